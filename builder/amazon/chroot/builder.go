@@ -54,6 +54,9 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateContext: b.config.ctx,
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
+				"ami_description",
+				"ami_name",
+				"tags",
 				"command_wrapper",
 				"mount_path",
 			},
@@ -97,7 +100,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	// Accumulate any errors
 	var errs *packer.MultiError
 	errs = packer.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(b.config.ctx)...)
-	errs = packer.MultiErrorAppend(errs, b.config.AMIConfig.Prepare(b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.AMIConfig.Prepare(&b.config.AccessConfig, b.config.ctx)...)
 
 	for _, mounts := range b.config.ChrootMounts {
 		if len(mounts) != 3 {
@@ -124,11 +127,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("The amazon-chroot builder only works on Linux environments.")
 	}
 
+	if err := b.config.AskMFACode(ui); err != nil {
+		return nil, err
+	}
+
 	config, err := b.config.Config()
 	if err != nil {
 		return nil, err
 	}
 
+	if err := b.config.AMIConfig.RenderNameAndTags(*b.config.ctx, config); err != nil {
+		return nil, err
+	}
+
 	ec2conn := ec2.New(config)
 
 	wrappedCommand := func(command string) (string, error) {
@@ -147,6 +158,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	// Build the steps
 	steps := []multistep.Step{
+		&awscommon.StepPreValidate{
+			DestAmiName:     b.config.AMIName,
+			ForceDeregister: b.config.AMIForceDeregister,
+		},
 		&StepInstanceInfo{},
 		&awscommon.StepSourceAMIInfo{
 			SourceAmi:          b.config.SourceAmi,
@@ -164,6 +179,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&StepChrootProvision{},
 		&StepEarlyCleanup{},
 		&StepSnapshot{},
+		&awscommon.StepDeregisterAMI{
+			AccessConfig:        &b.config.AccessConfig,
+			ForceDeregister:     b.config.AMIForceDeregister,
+			ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
+			AMIName:             b.config.AMIName,
+			Regions:             b.config.AMIRegions,
+		},
 		&StepRegisterAMI{},
 		&awscommon.StepAMIRegionCopy{
 			AccessConfig: &b.config.AccessConfig,
@@ -174,6 +196,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Description: b.config.AMIDescription,
 			Users:       b.config.AMIUsers,
 			Groups:      b.config.AMIGroups,
+			OrgARNs:     b.config.AMIOrgARNs,
+			OuARNs:      b.config.AMIOuARNs,
+			DeprecateAt: b.config.AMIDeprecateAt,
+			Ctx:         *b.config.ctx,
 		},
 		&awscommon.StepCreateTags{
 			Tags: b.config.AMITags,
@@ -187,7 +213,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 
 	b.runner.Run(state)
@@ -212,6 +240,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	return artifact, nil
 }
 
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (b *Builder) Cancel() {
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
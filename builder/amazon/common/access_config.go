@@ -4,20 +4,30 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"unicode"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/mitchellh/packer/packer"
 	"github.com/mitchellh/packer/template/interpolate"
 )
 
 // AccessConfig is for common configuration related to AWS access
 type AccessConfig struct {
-	AccessKey string `mapstructure:"access_key"`
-	SecretKey string `mapstructure:"secret_key"`
-	RawRegion string `mapstructure:"region"`
-	Token     string `mapstructure:"token"`
+	AccessKey            string `mapstructure:"access_key"`
+	SecretKey            string `mapstructure:"secret_key"`
+	RawRegion            string `mapstructure:"region"`
+	Token                string `mapstructure:"token"`
+	MFASerial            string `mapstructure:"mfa_serial"`
+	MFACode              string `mapstructure:"mfa_code"`
+	CustomEndpointEc2    string `mapstructure:"custom_endpoint_ec2"`
+	SkipRegionValidation bool   `mapstructure:"skip_region_validation"`
+
+	// session are the temporary credentials of the MFA session. A code
+	// can only be used once, so they're kept for the rest of the build.
+	session *credentials.Credentials
 }
 
 // Config returns a valid aws.Config object for access to AWS services, or
@@ -39,18 +49,55 @@ func (c *AccessConfig) Config() (*aws.Config, error) {
 		return nil, err
 	}
 
+	if c.MFASerial != "" {
+		if c.session == nil {
+			if c.MFACode == "" {
+				return nil, fmt.Errorf("mfa_code must be set to use mfa_serial")
+			}
+
+			value, err := GetSessionToken(&aws.Config{
+				Region:      region,
+				Credentials: creds,
+			}, c.MFASerial, c.MFACode)
+			if err != nil {
+				return nil, err
+			}
+
+			c.session = credentials.NewCredentials(&credentials.StaticProvider{Value: value})
+		}
+
+		creds = c.session
+	}
+
 	return &aws.Config{
 		Region:      region,
+		Endpoint:    c.CustomEndpointEc2,
 		Credentials: creds,
 		MaxRetries:  11,
 	}, nil
 }
 
+// AskMFACode asks for the code of the MFA device if mfa_serial is set but
+// mfa_code isn't, so that it doesn't have to be put in the template.
+func (c *AccessConfig) AskMFACode(ui packer.Ui) error {
+	if c.MFASerial == "" || c.MFACode != "" {
+		return nil
+	}
+
+	code, err := ui.Ask(fmt.Sprintf("Enter the MFA code for %s:", c.MFASerial))
+	if err != nil {
+		return fmt.Errorf("Error reading the MFA code: %s", err)
+	}
+
+	c.MFACode = strings.TrimSpace(code)
+	return nil
+}
+
 // Region returns the aws.Region object for access to AWS services, requesting
 // the region from the instance metadata if possible.
 func (c *AccessConfig) Region() (string, error) {
 	if c.RawRegion != "" {
-		if valid := ValidateRegion(c.RawRegion); valid == false {
+		if valid := ValidateRegion(c.RawRegion); valid == false && !c.SkipRegionValidation {
 			return "", fmt.Errorf("Not a valid region: %s", c.RawRegion)
 		}
 		return c.RawRegion, nil
@@ -67,12 +114,23 @@ func (c *AccessConfig) Region() (string, error) {
 
 func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 	var errs []error
-	if c.RawRegion != "" {
+	if c.RawRegion != "" && !c.SkipRegionValidation {
 		if valid := ValidateRegion(c.RawRegion); valid == false {
 			errs = append(errs, fmt.Errorf("Unknown region: %s", c.RawRegion))
 		}
 	}
 
+	if c.MFACode != "" && c.MFASerial == "" {
+		errs = append(errs, fmt.Errorf("mfa_serial must be set to use mfa_code"))
+	}
+
+	if c.CustomEndpointEc2 != "" {
+		if u, err := url.Parse(c.CustomEndpointEc2); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf(
+				"custom_endpoint_ec2 must be a URL: %s", c.CustomEndpointEc2))
+		}
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
@@ -1,7 +1,12 @@
 package common
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/mitchellh/packer/packer"
 )
 
 func testAccessConfig() *AccessConfig {
@@ -25,3 +30,98 @@ func TestAccessConfigPrepare_Region(t *testing.T) {
 		t.Fatalf("shouldn't have err: %s", err)
 	}
 }
+
+func TestAccessConfigPrepare_SkipRegionValidation(t *testing.T) {
+	c := testAccessConfig()
+	c.RawRegion = "us-east-12"
+	c.SkipRegionValidation = true
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	region, err := c.Region()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if region != "us-east-12" {
+		t.Fatalf("bad: %s", region)
+	}
+}
+
+func TestAccessConfigPrepare_MFA(t *testing.T) {
+	c := testAccessConfig()
+	c.MFACode = "123456"
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.MFASerial = "arn:aws:iam::123456789012:mfa/packer"
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestAccessConfigPrepare_CustomEndpointEc2(t *testing.T) {
+	c := testAccessConfig()
+	c.CustomEndpointEc2 = "ec2.example.com"
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.CustomEndpointEc2 = "https://ec2.example.com"
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestAccessConfigConfig_MFASession(t *testing.T) {
+	c := testAccessConfig()
+	c.RawRegion = "us-gov-west-1"
+	c.MFASerial = "arn:aws-us-gov:iam::123456789012:mfa/packer"
+	c.CustomEndpointEc2 = "https://ec2.example.com"
+
+	// The code is required to start a session
+	if _, err := c.Config(); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// A session that was started is used again
+	c.session = credentials.NewCredentials(&credentials.StaticProvider{Value: credentials.Value{
+		AccessKeyID:     "ASIAEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+	}})
+	config, err := c.Config()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.Credentials != c.session {
+		t.Fatal("should use the session credentials")
+	}
+	if config.Endpoint != "https://ec2.example.com" {
+		t.Fatalf("bad: %s", config.Endpoint)
+	}
+}
+
+func TestAccessConfigAskMFACode(t *testing.T) {
+	ui := &packer.BasicUi{
+		Reader: strings.NewReader(" 123456 \n"),
+		Writer: new(bytes.Buffer),
+	}
+
+	c := testAccessConfig()
+	if err := c.AskMFACode(ui); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.MFACode != "" {
+		t.Fatalf("shouldn't ask without mfa_serial: %s", c.MFACode)
+	}
+
+	c.MFASerial = "arn:aws:iam::123456789012:mfa/packer"
+	if err := c.AskMFACode(ui); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.MFACode != "123456" {
+		t.Fatalf("bad: %s", c.MFACode)
+	}
+}
@@ -2,29 +2,88 @@ package common
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/mitchellh/packer/template/interpolate"
 )
 
+// AMITemplateData is the data available when rendering the ami_name and
+// tags, which are rendered at the start of a build once the region and
+// account of the credentials are known.
+type AMITemplateData struct {
+	AccountID   string
+	BuildRegion string
+}
+
 // AMIConfig is for common configuration related to creating AMIs.
 type AMIConfig struct {
-	AMIName               string            `mapstructure:"ami_name"`
-	AMIDescription        string            `mapstructure:"ami_description"`
-	AMIVirtType           string            `mapstructure:"ami_virtualization_type"`
-	AMIUsers              []string          `mapstructure:"ami_users"`
-	AMIGroups             []string          `mapstructure:"ami_groups"`
-	AMIProductCodes       []string          `mapstructure:"ami_product_codes"`
-	AMIRegions            []string          `mapstructure:"ami_regions"`
-	AMITags               map[string]string `mapstructure:"tags"`
-	AMIEnhancedNetworking bool              `mapstructure:"enhanced_networking"`
+	AMIName                string            `mapstructure:"ami_name"`
+	AMIDescription         string            `mapstructure:"ami_description"`
+	AMIVirtType            string            `mapstructure:"ami_virtualization_type"`
+	AMIUsers               []string          `mapstructure:"ami_users"`
+	AMIGroups              []string          `mapstructure:"ami_groups"`
+	AMIOrgARNs             []string          `mapstructure:"ami_org_arns"`
+	AMIOuARNs              []string          `mapstructure:"ami_ou_arns"`
+	AMIProductCodes        []string          `mapstructure:"ami_product_codes"`
+	AMIRegions             []string          `mapstructure:"ami_regions"`
+	AMITags                map[string]string `mapstructure:"tags"`
+	AMIEnhancedNetworking  bool              `mapstructure:"enhanced_networking"`
+	AMIForceDeregister     bool              `mapstructure:"force_deregister"`
+	AMIForceDeleteSnapshot bool              `mapstructure:"force_delete_snapshot"`
+	AMIDeprecateAt         string            `mapstructure:"deprecate_at"`
 }
 
-func (c *AMIConfig) Prepare(ctx *interpolate.Context) []error {
+func (c *AMIConfig) Prepare(accessConfig *AccessConfig, ctx *interpolate.Context) []error {
 	var errs []error
 	if c.AMIName == "" {
 		errs = append(errs, fmt.Errorf("ami_name must be specified"))
 	}
 
+	if c.AMIForceDeleteSnapshot && !c.AMIForceDeregister {
+		errs = append(errs, fmt.Errorf(
+			"force_delete_snapshot requires force_deregister to be set"))
+	}
+
+	if err := interpolate.Validate(c.AMIDescription, ctx); err != nil {
+		errs = append(errs, fmt.Errorf("Error parsing ami_description: %s", err))
+	}
+
+	if err := interpolate.Validate(c.AMIName, ctx); err != nil {
+		errs = append(errs, fmt.Errorf("Error parsing ami_name: %s", err))
+	}
+
+	for k, v := range c.AMITags {
+		if err := interpolate.Validate(k, ctx); err != nil {
+			errs = append(errs, fmt.Errorf("Error parsing tag %s: %s", k, err))
+		}
+		if err := interpolate.Validate(v, ctx); err != nil {
+			errs = append(errs, fmt.Errorf("Error parsing tag %s: %s", k, err))
+		}
+	}
+
+	for _, arn := range c.AMIOrgARNs {
+		if !strings.HasPrefix(arn, "arn:") || !strings.Contains(arn, ":organization/") {
+			errs = append(errs, fmt.Errorf("Invalid organization ARN: %s", arn))
+		}
+	}
+
+	for _, arn := range c.AMIOuARNs {
+		if !strings.HasPrefix(arn, "arn:") || !strings.Contains(arn, ":ou/") {
+			errs = append(errs, fmt.Errorf("Invalid organizational unit ARN: %s", arn))
+		}
+	}
+
+	if c.AMIDeprecateAt != "" {
+		t, err := DeprecationTime(c.AMIDeprecateAt, time.Now())
+		if err != nil {
+			errs = append(errs, err)
+		} else if !t.After(time.Now()) {
+			errs = append(errs, fmt.Errorf("deprecate_at must be in the future"))
+		}
+	}
+
 	if len(c.AMIRegions) > 0 {
 		regionSet := make(map[string]struct{})
 		regions := make([]string, 0, len(c.AMIRegions))
@@ -39,11 +98,19 @@ func (c *AMIConfig) Prepare(ctx *interpolate.Context) []error {
 			regionSet[region] = struct{}{}
 
 			// Verify the region is real
-			if valid := ValidateRegion(region); valid == false {
+			if !accessConfig.SkipRegionValidation && !ValidateRegion(region) {
 				errs = append(errs, fmt.Errorf("Unknown region: %s", region))
 				continue
 			}
 
+			// AMIs can't be copied out of GovCloud or China, or into them
+			if accessConfig.RawRegion != "" && RegionPartition(region) != RegionPartition(accessConfig.RawRegion) {
+				errs = append(errs, fmt.Errorf(
+					"Can't copy the AMI from %s to %s, which is in another partition (%s)",
+					accessConfig.RawRegion, region, RegionPartition(region)))
+				continue
+			}
+
 			regions = append(regions, region)
 		}
 
@@ -56,3 +123,72 @@ func (c *AMIConfig) Prepare(ctx *interpolate.Context) []error {
 
 	return nil
 }
+
+// RenderNameAndTags renders the ami_name and tags with the region and
+// account of the build. The account is only looked up if it is used.
+func (c *AMIConfig) RenderNameAndTags(ctx interpolate.Context, config *aws.Config) error {
+	data := &AMITemplateData{BuildRegion: config.Region}
+	if c.usesAccountID() {
+		var err error
+		data.AccountID, err = AccountID(config)
+		if err != nil {
+			return err
+		}
+	}
+	ctx.Data = data
+
+	name, err := interpolate.Render(c.AMIName, &ctx)
+	if err != nil {
+		return fmt.Errorf("Error rendering ami_name: %s", err)
+	}
+	c.AMIName = name
+
+	if len(c.AMITags) > 0 {
+		tags := make(map[string]string, len(c.AMITags))
+		for k, v := range c.AMITags {
+			key, err := interpolate.Render(k, &ctx)
+			if err != nil {
+				return fmt.Errorf("Error rendering tag %s: %s", k, err)
+			}
+			value, err := interpolate.Render(v, &ctx)
+			if err != nil {
+				return fmt.Errorf("Error rendering tag %s: %s", k, err)
+			}
+			tags[key] = value
+		}
+		c.AMITags = tags
+	}
+
+	return nil
+}
+
+func (c *AMIConfig) usesAccountID() bool {
+	if strings.Contains(c.AMIName, ".AccountID") {
+		return true
+	}
+
+	for k, v := range c.AMITags {
+		if strings.Contains(k, ".AccountID") || strings.Contains(v, ".AccountID") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeprecationTime returns the time that the deprecate_at setting refers
+// to. The setting is either an RFC 3339 timestamp or a duration, such as
+// "8760h", that is added to now.
+func DeprecationTime(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(
+			"deprecate_at must be an RFC 3339 timestamp or a duration: %s", v)
+	}
+
+	return t, nil
+}
@@ -3,6 +3,10 @@ package common
 import (
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/mitchellh/packer/template/interpolate"
 )
 
 func testAMIConfig() *AMIConfig {
@@ -13,12 +17,12 @@ func testAMIConfig() *AMIConfig {
 
 func TestAMIConfigPrepare_name(t *testing.T) {
 	c := testAMIConfig()
-	if err := c.Prepare(nil); err != nil {
+	if err := c.Prepare(&AccessConfig{}, nil); err != nil {
 		t.Fatalf("shouldn't have err: %s", err)
 	}
 
 	c.AMIName = ""
-	if err := c.Prepare(nil); err == nil {
+	if err := c.Prepare(&AccessConfig{}, nil); err == nil {
 		t.Fatal("should have error")
 	}
 }
@@ -26,17 +30,17 @@ func TestAMIConfigPrepare_name(t *testing.T) {
 func TestAMIConfigPrepare_regions(t *testing.T) {
 	c := testAMIConfig()
 	c.AMIRegions = nil
-	if err := c.Prepare(nil); err != nil {
+	if err := c.Prepare(&AccessConfig{}, nil); err != nil {
 		t.Fatalf("shouldn't have err: %s", err)
 	}
 
 	c.AMIRegions = []string{"foo"}
-	if err := c.Prepare(nil); err == nil {
+	if err := c.Prepare(&AccessConfig{}, nil); err == nil {
 		t.Fatal("should have error")
 	}
 
 	c.AMIRegions = []string{"us-east-1", "us-west-1", "us-east-1"}
-	if err := c.Prepare(nil); err != nil {
+	if err := c.Prepare(&AccessConfig{}, nil); err != nil {
 		t.Fatalf("bad: %s", err)
 	}
 
@@ -45,3 +49,154 @@ func TestAMIConfigPrepare_regions(t *testing.T) {
 		t.Fatalf("bad: %#v", c.AMIRegions)
 	}
 }
+
+func TestAMIConfigPrepare_regionsSkipValidation(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIRegions = []string{"custom-region-1"}
+	if err := c.Prepare(&AccessConfig{SkipRegionValidation: true}, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	expected := []string{"custom-region-1"}
+	if !reflect.DeepEqual(c.AMIRegions, expected) {
+		t.Fatalf("bad: %#v", c.AMIRegions)
+	}
+}
+
+func TestAMIConfigPrepare_regionsPartition(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIRegions = []string{"us-gov-west-1"}
+	if err := c.Prepare(&AccessConfig{RawRegion: "us-east-1"}, nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.AMIRegions = []string{"cn-northwest-1"}
+	if err := c.Prepare(&AccessConfig{RawRegion: "cn-north-1"}, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestAMIConfigPrepare_forceDeleteSnapshot(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIForceDeleteSnapshot = true
+	if err := c.Prepare(&AccessConfig{}, nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.AMIForceDeregister = true
+	if err := c.Prepare(&AccessConfig{}, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestAMIConfigPrepare_description(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIDescription = "Built from {{ .SourceAMI }}"
+	if err := c.Prepare(&AccessConfig{}, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.AMIDescription = "{{ .SourceAMI"
+	if err := c.Prepare(&AccessConfig{}, nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestAMIConfigRenderNameAndTags(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIName = "base {{ .BuildRegion }}"
+	c.AMITags = map[string]string{"{{ upper \"region\" }}": "{{ .BuildRegion }}"}
+	if err := c.Prepare(&AccessConfig{}, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if c.usesAccountID() {
+		t.Fatal("should not use the account")
+	}
+
+	ctx := interpolate.Context{}
+	if err := c.RenderNameAndTags(ctx, &aws.Config{Region: "us-west-2"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.AMIName != "base us-west-2" {
+		t.Fatalf("bad: %s", c.AMIName)
+	}
+	if !reflect.DeepEqual(c.AMITags, map[string]string{"REGION": "us-west-2"}) {
+		t.Fatalf("bad: %#v", c.AMITags)
+	}
+
+	c.AMITags = map[string]string{"owner": "{{ .AccountID }}"}
+	if !c.usesAccountID() {
+		t.Fatal("should use the account")
+	}
+
+	c.AMITags = map[string]string{"owner": "{{ .AccountID"}
+	if err := c.Prepare(&AccessConfig{}, nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestAMIConfigPrepare_orgARNs(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIOrgARNs = []string{"arn:aws:organizations::123456789012:organization/o-abcdefghij"}
+	c.AMIOuARNs = []string{"arn:aws:organizations::123456789012:ou/o-abcdefghij/ou-ab12-cdefghij"}
+	if err := c.Prepare(&AccessConfig{}, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.AMIOrgARNs = []string{"o-abcdefghij"}
+	if err := c.Prepare(&AccessConfig{}, nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c = testAMIConfig()
+	c.AMIOuARNs = []string{"arn:aws:organizations::123456789012:organization/o-abcdefghij"}
+	if err := c.Prepare(&AccessConfig{}, nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestAMIConfigPrepare_deprecateAt(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIDeprecateAt = "8760h"
+	if err := c.Prepare(&AccessConfig{}, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.AMIDeprecateAt = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if err := c.Prepare(&AccessConfig{}, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.AMIDeprecateAt = "2006-01-02T15:04:05Z"
+	if err := c.Prepare(&AccessConfig{}, nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.AMIDeprecateAt = "next year"
+	if err := c.Prepare(&AccessConfig{}, nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestDeprecationTime(t *testing.T) {
+	now := time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		Input    string
+		Expected time.Time
+	}{
+		{"24h", time.Date(2015, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{"2016-01-01T00:00:00Z", time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		actual, err := DeprecationTime(tc.Input, now)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Input, err)
+		}
+
+		if !actual.Equal(tc.Expected) {
+			t.Fatalf("%s: bad: %s", tc.Input, actual)
+		}
+	}
+}
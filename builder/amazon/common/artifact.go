@@ -8,6 +8,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	packercommon "github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -57,6 +58,12 @@ func (a *Artifact) State(name string) interface{} {
 	switch name {
 	case "atlas.artifact.metadata":
 		return a.stateAtlasMetadata()
+	case packercommon.ArtifactStateData:
+		return a.stateArtifactData()
+	case packercommon.ArtifactStateImageId:
+		return a.stateImageId()
+	case packercommon.ArtifactStateRegions:
+		return a.stateArtifactData()
 	default:
 		return nil
 	}
@@ -104,3 +111,26 @@ func (a *Artifact) stateAtlasMetadata() interface{} {
 
 	return metadata
 }
+
+// stateImageId returns the ID of the AMI if it's only in a single region,
+// since otherwise there is no single ID.
+func (a *Artifact) stateImageId() interface{} {
+	if len(a.Amis) != 1 {
+		return nil
+	}
+
+	for _, imageId := range a.Amis {
+		return imageId
+	}
+
+	return nil
+}
+
+func (a *Artifact) stateArtifactData() interface{} {
+	data := make(map[string]string)
+	for region, imageId := range a.Amis {
+		data[region] = imageId
+	}
+
+	return data
+}
@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"testing"
 
+	packercommon "github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -46,6 +47,52 @@ func TestArtifactState_atlasMetadata(t *testing.T) {
 	}
 }
 
+func TestArtifactState_artifactData(t *testing.T) {
+	a := &Artifact{
+		Amis: map[string]string{
+			"east": "foo",
+			"west": "bar",
+		},
+	}
+
+	actual := a.State(packercommon.ArtifactStateData)
+	expected := map[string]string{
+		"east": "foo",
+		"west": "bar",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestArtifactState_regions(t *testing.T) {
+	a := &Artifact{
+		Amis: map[string]string{
+			"east": "foo",
+			"west": "bar",
+		},
+	}
+
+	actual := a.State("packer.artifact.regions")
+	expected := map[string]string{
+		"east": "foo",
+		"west": "bar",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// There's no single image ID in more than one region
+	if actual := a.State("packer.artifact.image_id"); actual != nil {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	delete(a.Amis, "west")
+	if actual := a.State("packer.artifact.image_id"); actual != "foo" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestArtifactString(t *testing.T) {
 	expected := `AMIs were created:
 
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// The version of aws-sdk-go that we build against predates sharing AMIs
+// with AWS Organizations and deprecating AMIs, so the inputs for those
+// requests are declared here. They're serialized by the SDK's EC2 query
+// protocol just like the generated types are.
+
+// ec2APIVersion is the version of the EC2 API that has the operations
+// and parameters below.
+const ec2APIVersion = "2016-11-15"
+
+type organizationLaunchPermission struct {
+	OrganizationARN       *string `locationName:"OrganizationArn" type:"string"`
+	OrganizationalUnitARN *string `locationName:"OrganizationalUnitArn" type:"string"`
+}
+
+type organizationLaunchPermissionModifications struct {
+	Add []*organizationLaunchPermission `locationNameList:"item" type:"list"`
+}
+
+type modifyImageLaunchPermissionInput struct {
+	ImageID          *string                                    `locationName:"ImageId" type:"string"`
+	LaunchPermission *organizationLaunchPermissionModifications `type:"structure"`
+}
+
+type enableImageDeprecationInput struct {
+	ImageID     *string    `locationName:"ImageId" type:"string"`
+	DeprecateAt *time.Time `type:"timestamp"`
+}
+
+type ec2RequestOutput struct {
+	Return *bool `locationName:"return" type:"boolean"`
+}
+
+// newEC2Request returns a request for the named EC2 operation.
+func newEC2Request(conn *ec2.EC2, name string, input interface{}) *aws.Request {
+	service := *conn.Service
+	service.APIVersion = ec2APIVersion
+
+	op := &aws.Operation{
+		Name:       name,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+
+	return aws.NewRequest(&service, op, input, &ec2RequestOutput{})
+}
+
+// shareImageWithOrganizationsRequest returns a request that adds launch
+// permissions for the AMI to the given organizations and organizational
+// units.
+func shareImageWithOrganizationsRequest(conn *ec2.EC2, ami string, orgARNs, ouARNs []string) *aws.Request {
+	permissions := make([]*organizationLaunchPermission, 0, len(orgARNs)+len(ouARNs))
+	for _, arn := range orgARNs {
+		permissions = append(permissions, &organizationLaunchPermission{
+			OrganizationARN: aws.String(arn),
+		})
+	}
+	for _, arn := range ouARNs {
+		permissions = append(permissions, &organizationLaunchPermission{
+			OrganizationalUnitARN: aws.String(arn),
+		})
+	}
+
+	return newEC2Request(conn, "ModifyImageAttribute", &modifyImageLaunchPermissionInput{
+		ImageID: aws.String(ami),
+		LaunchPermission: &organizationLaunchPermissionModifications{
+			Add: permissions,
+		},
+	})
+}
+
+// deprecateImageRequest returns a request that sets the time at which
+// the AMI is deprecated.
+func deprecateImageRequest(conn *ec2.EC2, ami string, t time.Time) *aws.Request {
+	return newEC2Request(conn, "EnableImageDeprecation", &enableImageDeprecationInput{
+		ImageID:     aws.String(ami),
+		DeprecateAt: &t,
+	})
+}
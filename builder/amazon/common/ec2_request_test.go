@@ -0,0 +1,69 @@
+package common
+
+import (
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func testEC2Conn() *ec2.EC2 {
+	return ec2.New(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("foo", "bar", ""),
+		Region:      "us-east-1",
+	})
+}
+
+func testEC2RequestBody(t *testing.T, req *aws.Request) url.Values {
+	if err := req.Build(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return values
+}
+
+func TestShareImageWithOrganizationsRequest(t *testing.T) {
+	req := shareImageWithOrganizationsRequest(
+		testEC2Conn(), "ami-1234", []string{"org"}, []string{"ou"})
+
+	expected := url.Values{
+		"Action":                                 {"ModifyImageAttribute"},
+		"Version":                                {ec2APIVersion},
+		"ImageId":                                {"ami-1234"},
+		"LaunchPermission.Add.1.OrganizationArn": {"org"},
+		"LaunchPermission.Add.2.OrganizationalUnitArn": {"ou"},
+	}
+	if actual := testEC2RequestBody(t, req); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestDeprecateImageRequest(t *testing.T) {
+	at := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	req := deprecateImageRequest(testEC2Conn(), "ami-1234", at)
+
+	expected := url.Values{
+		"Action":      {"EnableImageDeprecation"},
+		"Version":     {ec2APIVersion},
+		"ImageId":     {"ami-1234"},
+		"DeprecateAt": {"2016-01-01T00:00:00Z"},
+	}
+	if actual := testEC2RequestBody(t, req); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -1,11 +1,17 @@
 package common
 
+import (
+	"strings"
+)
+
 // IsValidRegion returns true if the supplied region is a valid AWS
 // region and false if it's not.
 func ValidateRegion(region string) bool {
-	var regions = [11]string{"us-east-1", "us-west-2", "us-west-1", "eu-west-1",
-		"eu-central-1", "ap-southeast-1", "ap-southeast-2", "ap-northeast-1",
-		"sa-east-1", "cn-north-1", "us-gov-west-1"}
+	var regions = [20]string{"us-east-1", "us-east-2", "us-west-2", "us-west-1",
+		"ca-central-1", "eu-west-1", "eu-west-2", "eu-west-3", "eu-central-1",
+		"eu-north-1", "ap-south-1", "ap-southeast-1", "ap-southeast-2",
+		"ap-northeast-1", "ap-northeast-2", "sa-east-1", "cn-north-1",
+		"cn-northwest-1", "us-gov-west-1", "us-gov-east-1"}
 
 	for _, valid := range regions {
 		if region == valid {
@@ -14,3 +20,18 @@ func ValidateRegion(region string) bool {
 	}
 	return false
 }
+
+// RegionPartition returns the partition of AWS that the region is in,
+// as used in ARNs: "aws-cn" for China, "aws-us-gov" for GovCloud and
+// "aws" for all other regions. AMIs can only be copied between regions
+// of the same partition.
+func RegionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	default:
+		return "aws"
+	}
+}
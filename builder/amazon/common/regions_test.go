@@ -0,0 +1,22 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestRegionPartition(t *testing.T) {
+	cases := map[string]string{
+		"us-east-1":      "aws",
+		"eu-west-2":      "aws",
+		"cn-north-1":     "aws-cn",
+		"cn-northwest-1": "aws-cn",
+		"us-gov-west-1":  "aws-us-gov",
+		"us-gov-east-1":  "aws-us-gov",
+	}
+
+	for region, expected := range cases {
+		if v := RegionPartition(region); v != expected {
+			t.Fatalf("%s: expected %s, got %s", region, expected, v)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// serviceConfig returns the config for AWS services other than EC2, which
+// don't use custom_endpoint_ec2.
+func serviceConfig(config *aws.Config) *aws.Config {
+	return &aws.Config{
+		Region:      config.Region,
+		Credentials: config.Credentials,
+		MaxRetries:  config.MaxRetries,
+	}
+}
+
+// AccountID returns the ID of the AWS account of the credentials, as
+// reported by GetCallerIdentity of STS.
+func AccountID(config *aws.Config) (string, error) {
+	stsconn := sts.New(serviceConfig(config))
+	resp, err := stsconn.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("Error getting the caller identity: %s", err)
+	}
+
+	return *resp.Account, nil
+}
+
+// GetSecretValue returns the string value of a secret of Secrets Manager,
+// by its name or ARN.
+func GetSecretValue(config *aws.Config, secretID string) (string, error) {
+	smconn := secretsmanager.New(serviceConfig(config))
+	resp, err := smconn.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretID: aws.String(secretID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error getting secret %s: %s", secretID, err)
+	}
+	if resp.SecretString == nil {
+		return "", fmt.Errorf("Secret %s has no string value", secretID)
+	}
+
+	return *resp.SecretString, nil
+}
+
+// GetSessionToken returns temporary credentials of a session that is
+// authenticated with the code of the MFA device with the given serial
+// number or ARN, as returned by GetSessionToken of STS.
+func GetSessionToken(config *aws.Config, serial, code string) (credentials.Value, error) {
+	stsconn := sts.New(serviceConfig(config))
+	resp, err := stsconn.GetSessionToken(&sts.GetSessionTokenInput{
+		SerialNumber: aws.String(serial),
+		TokenCode:    aws.String(code),
+	})
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("Error getting an MFA session token: %s", err)
+	}
+
+	return credentials.Value{
+		AccessKeyID:     *resp.Credentials.AccessKeyID,
+		SecretAccessKey: *resp.Credentials.SecretAccessKey,
+		SessionToken:    *resp.Credentials.SessionToken,
+	}, nil
+}
+
+// ECRAuthorizationToken returns the username and password to log in to
+// the Amazon ECR registry of the account of the credentials in the region,
+// as returned by GetAuthorizationToken of ECR.
+func ECRAuthorizationToken(config *aws.Config) (string, string, error) {
+	ecrconn := ecr.New(serviceConfig(config))
+	resp, err := ecrconn.GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return "", "", fmt.Errorf("Error getting an ECR authorization token: %s", err)
+	}
+	if len(resp.AuthorizationData) == 0 {
+		return "", "", fmt.Errorf("ECR returned no authorization token")
+	}
+
+	return decodeECRAuthorizationToken(*resp.AuthorizationData[0].AuthorizationToken)
+}
+
+// decodeECRAuthorizationToken splits the token, which is the base64
+// encoded username and password.
+func decodeECRAuthorizationToken(token string) (string, string, error) {
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", fmt.Errorf("Error decoding the ECR authorization token: %s", err)
+	}
+
+	parts := strings.SplitN(string(data), ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("The ECR authorization token isn't a username and password")
+	}
+
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestDecodeECRAuthorizationToken(t *testing.T) {
+	user, pass, err := decodeECRAuthorizationToken("QVdTOnNlY3JldA==")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if user != "AWS" || pass != "secret" {
+		t.Fatalf("bad: %s %s", user, pass)
+	}
+
+	// Not base64
+	if _, _, err := decodeECRAuthorizationToken("!"); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// No password
+	if _, _, err := decodeECRAuthorizationToken("QVdT"); err == nil {
+		t.Fatal("should have error")
+	}
+}
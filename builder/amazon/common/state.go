@@ -128,6 +128,59 @@ func SpotRequestStateRefreshFunc(conn *ec2.EC2, spotRequestId string) StateRefre
 	}
 }
 
+// SnapshotStateRefreshFunc returns a StateRefreshFunc that is used to
+// watch a snapshot for state changes.
+func SnapshotStateRefreshFunc(conn *ec2.EC2, snapshotId string) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		resp, err := conn.DescribeSnapshots(&ec2.DescribeSnapshotsInput{
+			SnapshotIDs: []*string{&snapshotId},
+		})
+		if err != nil {
+			if isTransientNetworkError(err) {
+				return nil, "", nil
+			}
+
+			log.Printf("Error on SnapshotStateRefresh: %s", err)
+			return nil, "", err
+		}
+
+		if len(resp.Snapshots) == 0 {
+			return nil, "", errors.New("No snapshots found.")
+		}
+
+		s := resp.Snapshots[0]
+		return s, *s.State, nil
+	}
+}
+
+// VolumeStateRefreshFunc returns a StateRefreshFunc that is used to watch
+// a volume for state changes.
+func VolumeStateRefreshFunc(conn *ec2.EC2, volumeId string) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		resp, err := conn.DescribeVolumes(&ec2.DescribeVolumesInput{
+			VolumeIDs: []*string{&volumeId},
+		})
+		if err != nil {
+			if ec2err, ok := err.(awserr.Error); ok && ec2err.Code() == "InvalidVolume.NotFound" {
+				// Set this to nil as if we didn't find anything.
+				return nil, "", nil
+			} else if isTransientNetworkError(err) {
+				return nil, "", nil
+			}
+
+			log.Printf("Error on VolumeStateRefresh: %s", err)
+			return nil, "", err
+		}
+
+		if len(resp.Volumes) == 0 {
+			return nil, "", nil
+		}
+
+		v := resp.Volumes[0]
+		return v, *v.State, nil
+	}
+}
+
 // WaitForState watches an object and waits for it to achieve a certain
 // state.
 func WaitForState(conf *StateChangeConf) (i interface{}, err error) {
@@ -86,6 +86,9 @@ func amiRegionCopy(state multistep.StateBag, config *AccessConfig, name string,
 	}
 	awsConfig.Region = target
 
+	// custom_endpoint_ec2 is the endpoint of the region of the build
+	awsConfig.Endpoint = ""
+
 	regionconn := ec2.New(awsConfig)
 	resp, err := regionconn.CopyImage(&ec2.CopyImageInput{
 		SourceRegion:  &source,
@@ -0,0 +1,112 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// StepDeregisterAMI deregisters the existing AMIs with the name of the new
+// AMI, in the region of the build and every region in ami_regions, if
+// force_deregister is set. It runs just before the new AMI is created so
+// that a build that fails earlier keeps the existing AMIs.
+type StepDeregisterAMI struct {
+	AccessConfig        *AccessConfig
+	ForceDeregister     bool
+	ForceDeleteSnapshot bool
+	AMIName             string
+	Regions             []string
+}
+
+func (s *StepDeregisterAMI) Run(state multistep.StateBag) multistep.StepAction {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !s.ForceDeregister {
+		return multistep.ActionContinue
+	}
+
+	regions := []string{ec2conn.Config.Region}
+	for _, region := range s.Regions {
+		if region != ec2conn.Config.Region {
+			regions = append(regions, region)
+		}
+	}
+
+	for _, region := range regions {
+		regionconn := ec2conn
+		if region != ec2conn.Config.Region {
+			awsConfig, err := s.AccessConfig.Config()
+			if err != nil {
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+			awsConfig.Region = region
+
+			// custom_endpoint_ec2 is the endpoint of the region of the build
+			awsConfig.Endpoint = ""
+
+			regionconn = ec2.New(awsConfig)
+		}
+
+		if err := s.deregister(ui, regionconn, region); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepDeregisterAMI) Cleanup(multistep.StateBag) {}
+
+// deregister deregisters the AMIs with the name of the new AMI in the
+// region of ec2conn, and deletes their snapshots if force_delete_snapshot
+// is set.
+func (s *StepDeregisterAMI) deregister(ui packer.Ui, ec2conn *ec2.EC2, region string) error {
+	resp, err := ec2conn.DescribeImages(&ec2.DescribeImagesInput{
+		Owners: []*string{aws.String("self")},
+		Filters: []*ec2.Filter{&ec2.Filter{
+			Name:   aws.String("name"),
+			Values: []*string{aws.String(s.AMIName)},
+		}}})
+	if err != nil {
+		return fmt.Errorf("Error querying AMI in region (%s): %s", region, err)
+	}
+
+	for _, image := range resp.Images {
+		ui.Say(fmt.Sprintf(
+			"Deregistering existing AMI: %s (%s)", *image.ImageID, region))
+		_, err := ec2conn.DeregisterImage(&ec2.DeregisterImageInput{
+			ImageID: image.ImageID,
+		})
+		if err != nil {
+			return fmt.Errorf("Error deregistering existing AMI: %s", err)
+		}
+
+		if !s.ForceDeleteSnapshot {
+			continue
+		}
+
+		for _, b := range image.BlockDeviceMappings {
+			if b.EBS == nil || b.EBS.SnapshotID == nil {
+				continue
+			}
+
+			ui.Say(fmt.Sprintf("Deleting snapshot: %s", *b.EBS.SnapshotID))
+			_, err := ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{
+				SnapshotID: b.EBS.SnapshotID,
+			})
+			if err != nil {
+				return fmt.Errorf("Error deleting existing snapshot: %s", err)
+			}
+		}
+	}
+
+	return nil
+}
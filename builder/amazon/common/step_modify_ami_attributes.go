@@ -2,18 +2,31 @@ package common
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/mitchellh/multistep"
 	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template/interpolate"
 )
 
+// amiDescriptionData is the data available when rendering the AMI
+// description.
+type amiDescriptionData struct {
+	BuildRegion string
+	SourceAMI   string
+}
+
 type StepModifyAMIAttributes struct {
 	Users        []string
 	Groups       []string
+	OrgARNs      []string
+	OuARNs       []string
 	ProductCodes []string
 	Description  string
+	DeprecateAt  string
+	Ctx          interpolate.Context
 }
 
 func (s *StepModifyAMIAttributes) Run(state multistep.StateBag) multistep.StepAction {
@@ -27,18 +40,49 @@ func (s *StepModifyAMIAttributes) Run(state multistep.StateBag) multistep.StepAc
 	valid = valid || (s.Users != nil && len(s.Users) > 0)
 	valid = valid || (s.Groups != nil && len(s.Groups) > 0)
 	valid = valid || (s.ProductCodes != nil && len(s.ProductCodes) > 0)
+	valid = valid || len(s.OrgARNs) > 0 || len(s.OuARNs) > 0
+	valid = valid || s.DeprecateAt != ""
 
 	if !valid {
 		return multistep.ActionContinue
 	}
 
+	description := s.Description
+	if description != "" {
+		data := &amiDescriptionData{BuildRegion: ec2conn.Config.Region}
+		if raw, ok := state.GetOk("source_image"); ok {
+			data.SourceAMI = *raw.(*ec2.Image).ImageID
+		}
+
+		s.Ctx.Data = data
+		var err error
+		description, err = interpolate.Render(description, &s.Ctx)
+		if err != nil {
+			err := fmt.Errorf("Error rendering ami_description: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	var deprecateAt time.Time
+	if s.DeprecateAt != "" {
+		var err error
+		deprecateAt, err = DeprecationTime(s.DeprecateAt, time.Now())
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	// Construct the modify image attribute requests we're going to make.
 	// We need to make each separately since the EC2 API only allows changing
 	// one type at a kind currently.
 	options := make(map[string]*ec2.ModifyImageAttributeInput)
-	if s.Description != "" {
+	if description != "" {
 		options["description"] = &ec2.ModifyImageAttributeInput{
-			Description: &ec2.AttributeValue{Value: &s.Description},
+			Description: &ec2.AttributeValue{Value: &description},
 		}
 	}
 
@@ -89,6 +133,28 @@ func (s *StepModifyAMIAttributes) Run(state multistep.StateBag) multistep.StepAc
 				return multistep.ActionHalt
 			}
 		}
+
+		if len(s.OrgARNs) > 0 || len(s.OuARNs) > 0 {
+			ui.Message("Modifying: organizations")
+			req := shareImageWithOrganizationsRequest(regionconn, ami, s.OrgARNs, s.OuARNs)
+			if err := req.Send(); err != nil {
+				err := fmt.Errorf("Error modify AMI attributes: %s", err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
+
+		if s.DeprecateAt != "" {
+			ui.Message(fmt.Sprintf("Deprecating at: %s", deprecateAt.UTC().Format(time.RFC3339)))
+			req := deprecateImageRequest(regionconn, ami, deprecateAt)
+			if err := req.Send(); err != nil {
+				err := fmt.Errorf("Error deprecating AMI: %s", err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
 	}
 
 	return multistep.ActionContinue
@@ -11,15 +11,22 @@ import (
 
 // StepPreValidate provides an opportunity to pre-validate any configuration for
 // the build before actually doing any time consuming work
-//
 type StepPreValidate struct {
-	DestAmiName string
+	DestAmiName     string
+	ForceDeregister bool
 }
 
 func (s *StepPreValidate) Run(state multistep.StateBag) multistep.StepAction {
 	ec2conn := state.Get("ec2").(*ec2.EC2)
 	ui := state.Get("ui").(packer.Ui)
 
+	// Existing AMIs are deregistered by StepDeregisterAMI instead, once
+	// the new AMI is about to be created.
+	if s.ForceDeregister {
+		ui.Say("Force deregister flag found, skipping prevalidating AMI name")
+		return multistep.ActionContinue
+	}
+
 	ui.Say("Prevalidating AMI Name...")
 	resp, err := ec2conn.DescribeImages(&ec2.DescribeImagesInput{
 		Filters: []*ec2.Filter{&ec2.Filter{
@@ -1,19 +1,21 @@
-package ebs
+package common
 
 import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/mitchellh/multistep"
-	awscommon "github.com/mitchellh/packer/builder/amazon/common"
 	"github.com/mitchellh/packer/packer"
 )
 
-type stepStopInstance struct {
+// StepStopInstance stops the source instance so that its volumes can be
+// captured consistently. Spot instances can't be stopped, so they are
+// left running.
+type StepStopInstance struct {
 	SpotPrice string
 }
 
-func (s *stepStopInstance) Run(state multistep.StateBag) multistep.StepAction {
+func (s *StepStopInstance) Run(state multistep.StateBag) multistep.StepAction {
 	ec2conn := state.Get("ec2").(*ec2.EC2)
 	instance := state.Get("instance").(*ec2.Instance)
 	ui := state.Get("ui").(packer.Ui)
@@ -37,13 +39,13 @@ func (s *stepStopInstance) Run(state multistep.StateBag) multistep.StepAction {
 
 	// Wait for the instance to actual stop
 	ui.Say("Waiting for the instance to stop...")
-	stateChange := awscommon.StateChangeConf{
+	stateChange := StateChangeConf{
 		Pending:   []string{"running", "stopping"},
 		Target:    "stopped",
-		Refresh:   awscommon.InstanceStateRefreshFunc(ec2conn, *instance.InstanceID),
+		Refresh:   InstanceStateRefreshFunc(ec2conn, *instance.InstanceID),
 		StepState: state,
 	}
-	_, err = awscommon.WaitForState(&stateChange)
+	_, err = WaitForState(&stateChange)
 	if err != nil {
 		err := fmt.Errorf("Error waiting for instance to stop: %s", err)
 		state.Put("error", err)
@@ -54,6 +56,6 @@ func (s *stepStopInstance) Run(state multistep.StateBag) multistep.StepAction {
 	return multistep.ActionContinue
 }
 
-func (s *stepStopInstance) Cleanup(multistep.StateBag) {
+func (s *StepStopInstance) Cleanup(multistep.StateBag) {
 	// No cleanup...
 }
@@ -2,6 +2,10 @@ package common
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
 	"text/template"
 )
 
@@ -33,6 +37,88 @@ func templateCleanAMIName(s string) string {
 	return string(newb[:])
 }
 
+// Clean up a resource name, such as an AMI name or a tag value, by
+// replacing the characters that AWS doesn't allow in names with "-" and
+// cutting it to the 128 characters that names can have.
+func templateCleanResourceName(s string) string {
+	allowed := []byte{'(', ')', '[', ']', ' ', '.', '/', '-', '\'', '@', '_'}
+	b := []byte(s)
+	if len(b) > 128 {
+		b = b[:128]
+	}
+	newb := make([]byte, len(b))
+	for i, c := range b {
+		if isalphanumeric(c) || bytes.IndexByte(allowed, c) != -1 {
+			newb[i] = c
+		} else {
+			newb[i] = '-'
+		}
+	}
+	return string(newb)
+}
+
+// secretRegion returns the region of a secret, which is part of its ARN,
+// or otherwise the region of the environment. An empty region is looked
+// up in the instance metadata.
+func secretRegion(id string) string {
+	if parts := strings.Split(id, ":"); len(parts) > 3 && parts[0] == "arn" {
+		return parts[3]
+	}
+
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// Look up the value of a secret in Secrets Manager with the credentials
+// of the environment. With a key, the secret is read as a JSON object and
+// the value of the key is returned.
+func templateAWSSecretsManager(id string, key ...string) (string, error) {
+	if len(key) > 1 {
+		return "", fmt.Errorf("aws_secretsmanager takes a secret and an optional key")
+	}
+
+	access := &AccessConfig{RawRegion: secretRegion(id)}
+	config, err := access.Config()
+	if err != nil {
+		return "", err
+	}
+
+	value, err := GetSecretValue(config, id)
+	if err != nil {
+		return "", err
+	}
+
+	if len(key) == 0 {
+		return value, nil
+	}
+
+	return secretKeyValue(id, value, key[0])
+}
+
+// secretKeyValue returns the value of a key of a secret that is a JSON
+// object, as Secrets Manager stores key/value secrets.
+func secretKeyValue(id, value, key string) (string, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		return "", fmt.Errorf("Secret %s is not a JSON object: %s", id, err)
+	}
+
+	v, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("Secret %s has no key %s", id, key)
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(v), nil
+}
+
 var TemplateFuncs = template.FuncMap{
-	"clean_ami_name": templateCleanAMIName,
+	"aws_secretsmanager":  templateAWSSecretsManager,
+	"clean_ami_name":      templateCleanAMIName,
+	"clean_resource_name": templateCleanResourceName,
 }
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"os"
 	"testing"
 )
 
@@ -14,3 +15,50 @@ func TestAMITemplatePrepare_clean(t *testing.T) {
 		t.Fatalf("template names do not match: expected %s got %s\n", expected, name)
 	}
 }
+
+func TestTemplateCleanResourceName(t *testing.T) {
+	origName := "AMZamz09()[]./-_'@:&^ $%,"
+	expected := "AMZamz09()[]./-_'@--- ---"
+
+	if name := templateCleanResourceName(origName); name != expected {
+		t.Fatalf("bad: %s", name)
+	}
+
+	long := make([]byte, 200)
+	for i := range long {
+		long[i] = 'a'
+	}
+	if name := templateCleanResourceName(string(long)); len(name) != 128 {
+		t.Fatalf("bad length: %d", len(name))
+	}
+}
+
+func TestSecretRegion(t *testing.T) {
+	arn := "arn:aws:secretsmanager:eu-west-1:123456789012:secret:packer-AbCdEf"
+	if v := secretRegion(arn); v != "eu-west-1" {
+		t.Fatalf("bad: %s", v)
+	}
+
+	defer os.Setenv("AWS_REGION", os.Getenv("AWS_REGION"))
+	os.Setenv("AWS_REGION", "us-east-2")
+	if v := secretRegion("packer"); v != "us-east-2" {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestSecretKeyValue(t *testing.T) {
+	secret := `{"username": "packer", "port": 5432}`
+
+	if v, err := secretKeyValue("db", secret, "username"); err != nil || v != "packer" {
+		t.Fatalf("bad: %s %s", v, err)
+	}
+	if v, err := secretKeyValue("db", secret, "port"); err != nil || v != "5432" {
+		t.Fatalf("bad: %s %s", v, err)
+	}
+	if _, err := secretKeyValue("db", secret, "password"); err == nil {
+		t.Fatal("should have error")
+	}
+	if _, err := secretKeyValue("db", "hunter2", "password"); err == nil {
+		t.Fatal("should have error")
+	}
+}
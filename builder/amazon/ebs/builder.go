@@ -42,6 +42,13 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	err := config.Decode(&b.config, &config.DecodeOpts{
 		Interpolate:        true,
 		InterpolateContext: b.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"ami_description",
+				"ami_name",
+				"tags",
+			},
+		},
 	}, raws...)
 	if err != nil {
 		return nil, err
@@ -51,7 +58,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	var errs *packer.MultiError
 	errs = packer.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.BlockDevices.Prepare(b.config.ctx)...)
-	errs = packer.MultiErrorAppend(errs, b.config.AMIConfig.Prepare(b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.AMIConfig.Prepare(&b.config.AccessConfig, b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(b.config.ctx)...)
 
 	if errs != nil && len(errs.Errors) > 0 {
@@ -63,11 +70,19 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	if err := b.config.AskMFACode(ui); err != nil {
+		return nil, err
+	}
+
 	config, err := b.config.Config()
 	if err != nil {
 		return nil, err
 	}
 
+	if err := b.config.AMIConfig.RenderNameAndTags(*b.config.ctx, config); err != nil {
+		return nil, err
+	}
+
 	ec2conn := ec2.New(config)
 
 	// Setup the state bag and initial state for the steps
@@ -80,7 +95,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	// Build the steps
 	steps := []multistep.Step{
 		&awscommon.StepPreValidate{
-			DestAmiName: b.config.AMIName,
+			DestAmiName:     b.config.AMIName,
+			ForceDeregister: b.config.AMIForceDeregister,
 		},
 		&awscommon.StepSourceAMIInfo{
 			SourceAmi:          b.config.SourceAmi,
@@ -126,9 +142,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 				b.config.RunConfig.Comm.SSHUsername),
 		},
 		&common.StepProvision{},
-		&stepStopInstance{SpotPrice: b.config.SpotPrice},
+		&awscommon.StepStopInstance{SpotPrice: b.config.SpotPrice},
 		// TODO(mitchellh): verify works with spots
 		&stepModifyInstance{},
+		&awscommon.StepDeregisterAMI{
+			AccessConfig:        &b.config.AccessConfig,
+			ForceDeregister:     b.config.AMIForceDeregister,
+			ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
+			AMIName:             b.config.AMIName,
+			Regions:             b.config.AMIRegions,
+		},
 		&stepCreateAMI{},
 		&awscommon.StepAMIRegionCopy{
 			AccessConfig: &b.config.AccessConfig,
@@ -139,6 +162,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Description: b.config.AMIDescription,
 			Users:       b.config.AMIUsers,
 			Groups:      b.config.AMIGroups,
+			OrgARNs:     b.config.AMIOrgARNs,
+			OuARNs:      b.config.AMIOuARNs,
+			DeprecateAt: b.config.AMIDeprecateAt,
+			Ctx:         *b.config.ctx,
 		},
 		&awscommon.StepCreateTags{
 			Tags: b.config.AMITags,
@@ -152,7 +179,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 
 	b.runner.Run(state)
@@ -177,6 +206,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	return artifact, nil
 }
 
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (b *Builder) Cancel() {
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
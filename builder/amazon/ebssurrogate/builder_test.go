@@ -0,0 +1,137 @@
+package ebssurrogate
+
+import (
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"access_key":    "foo",
+		"secret_key":    "bar",
+		"source_ami":    "foo",
+		"instance_type": "foo",
+		"region":        "us-east-1",
+		"ssh_username":  "root",
+		"ami_name":      "foo",
+		"launch_block_device_mappings": []map[string]interface{}{
+			{
+				"device_name": "/dev/xvdf",
+				"volume_size": 8,
+			},
+		},
+		"ami_root_device": map[string]interface{}{
+			"source_device_name": "/dev/xvdf",
+			"device_name":        "/dev/xvda",
+		},
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare(t *testing.T) {
+	var b Builder
+	warns, err := b.Prepare(testConfig())
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if b.config.AMIVirtType != "hvm" {
+		t.Fatalf("bad: %s", b.config.AMIVirtType)
+	}
+	if b.config.RootDevice.VolumeType != "standard" {
+		t.Fatalf("bad: %s", b.config.RootDevice.VolumeType)
+	}
+}
+
+func TestBuilderPrepare_RootDevice(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Required
+	delete(config, "ami_root_device")
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// The source device must be launched
+	config["ami_root_device"] = map[string]interface{}{
+		"source_device_name": "/dev/xvdg",
+		"device_name":        "/dev/xvda",
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// iops requires io1
+	config["ami_root_device"] = map[string]interface{}{
+		"source_device_name": "/dev/xvdf",
+		"device_name":        "/dev/xvda",
+		"iops":               1000,
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["ami_root_device"] = map[string]interface{}{
+		"source_device_name": "/dev/xvdf",
+		"device_name":        "/dev/xvda",
+		"volume_type":        "io1",
+		"iops":               1000,
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestBuilderPrepare_VirtType(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["ami_virtualization_type"] = "foo"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepRegisterAMI_blockDevices(t *testing.T) {
+	b := &Builder{}
+	config := testConfig()
+	config["ami_block_device_mappings"] = []map[string]interface{}{
+		{"device_name": "/dev/xvda", "volume_size": 100},
+		{"device_name": "/dev/xvdb", "virtual_name": "ephemeral0"},
+	}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	step := &StepRegisterAMI{
+		RootDevice:   b.config.RootDevice,
+		BlockDevices: b.config.BlockDevices.BuildAMIDevices(),
+	}
+	devices := step.blockDevices("snap-foo")
+	if len(devices) != 2 {
+		t.Fatalf("bad: %#v", devices)
+	}
+
+	root := devices[0]
+	if *root.DeviceName != "/dev/xvda" || *root.EBS.SnapshotID != "snap-foo" {
+		t.Fatalf("bad: %#v", root)
+	}
+	if *devices[1].DeviceName != "/dev/xvdb" {
+		t.Fatalf("bad: %#v", devices[1])
+	}
+}
@@ -0,0 +1,51 @@
+package ebssurrogate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+// RootBlockDevice describes the root device of the AMI, which is created
+// from the snapshot of a volume that was attached to the surrogate
+// instance with launch_block_device_mappings.
+type RootBlockDevice struct {
+	SourceDeviceName    string `mapstructure:"source_device_name"`
+	DeviceName          string `mapstructure:"device_name"`
+	DeleteOnTermination bool   `mapstructure:"delete_on_termination"`
+	IOPS                int64  `mapstructure:"iops"`
+	VolumeType          string `mapstructure:"volume_type"`
+	VolumeSize          int64  `mapstructure:"volume_size"`
+}
+
+func (c *RootBlockDevice) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.SourceDeviceName == "" {
+		errs = append(errs, errors.New(
+			"source_device_name for the ami_root_device must be specified"))
+	}
+
+	if c.DeviceName == "" {
+		errs = append(errs, errors.New(
+			"device_name for the ami_root_device must be specified"))
+	}
+
+	if c.VolumeType == "" {
+		c.VolumeType = "standard"
+	}
+
+	if c.VolumeType == "io1" {
+		if c.IOPS == 0 {
+			errs = append(errs, errors.New(
+				"iops for the ami_root_device must be specified with an io1 volume_type"))
+		}
+	} else if c.IOPS != 0 {
+		errs = append(errs, fmt.Errorf(
+			"iops for the ami_root_device can't be specified with a %s volume_type",
+			c.VolumeType))
+	}
+
+	return errs
+}
@@ -0,0 +1,141 @@
+package ebssurrogate
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mitchellh/multistep"
+	awscommon "github.com/mitchellh/packer/builder/amazon/common"
+	"github.com/mitchellh/packer/packer"
+)
+
+// StepRegisterAMI creates the AMI from the snapshot of the new root
+// volume.
+type StepRegisterAMI struct {
+	RootDevice   RootBlockDevice
+	BlockDevices []*ec2.BlockDeviceMapping
+
+	image *ec2.Image
+}
+
+func (s *StepRegisterAMI) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	image := state.Get("source_image").(*ec2.Image)
+	snapshotId := state.Get("snapshot_id").(string)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Registering the AMI...")
+	registerOpts := &ec2.RegisterImageInput{
+		Name:                &config.AMIName,
+		Architecture:        image.Architecture,
+		RootDeviceName:      aws.String(s.RootDevice.DeviceName),
+		VirtualizationType:  &config.AMIVirtType,
+		BlockDeviceMappings: s.blockDevices(snapshotId),
+	}
+
+	if config.AMIVirtType != "hvm" {
+		registerOpts.KernelID = image.KernelID
+		registerOpts.RAMDiskID = image.RAMDiskID
+	}
+
+	// Set SriovNetSupport to "simple". See http://goo.gl/icuXh5
+	if config.AMIEnhancedNetworking {
+		registerOpts.SRIOVNetSupport = aws.String("simple")
+	}
+
+	registerResp, err := ec2conn.RegisterImage(registerOpts)
+	if err != nil {
+		state.Put("error", fmt.Errorf("Error registering AMI: %s", err))
+		ui.Error(state.Get("error").(error).Error())
+		return multistep.ActionHalt
+	}
+
+	// Set the AMI ID in the state
+	ui.Say(fmt.Sprintf("AMI: %s", *registerResp.ImageID))
+	amis := make(map[string]string)
+	amis[ec2conn.Config.Region] = *registerResp.ImageID
+	state.Put("amis", amis)
+
+	// Wait for the image to become ready
+	stateChange := awscommon.StateChangeConf{
+		Pending:   []string{"pending"},
+		Target:    "available",
+		Refresh:   awscommon.AMIStateRefreshFunc(ec2conn, *registerResp.ImageID),
+		StepState: state,
+	}
+
+	ui.Say("Waiting for AMI to become ready...")
+	if _, err := awscommon.WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Error waiting for AMI: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	imagesResp, err := ec2conn.DescribeImages(&ec2.DescribeImagesInput{ImageIDs: []*string{registerResp.ImageID}})
+	if err != nil {
+		err := fmt.Errorf("Error searching for AMI: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.image = imagesResp.Images[0]
+
+	return multistep.ActionContinue
+}
+
+func (s *StepRegisterAMI) Cleanup(state multistep.StateBag) {
+	if s.image == nil {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deregistering the AMI because cancelation or error...")
+	deregisterOpts := &ec2.DeregisterImageInput{ImageID: s.image.ImageID}
+	if _, err := ec2conn.DeregisterImage(deregisterOpts); err != nil {
+		ui.Error(fmt.Sprintf("Error deregistering AMI, may still be around: %s", err))
+		return
+	}
+}
+
+// blockDevices returns the block device mappings of the AMI: the root
+// device from the snapshot, followed by the other mappings of the AMI
+// except any for the same device.
+func (s *StepRegisterAMI) blockDevices(snapshotId string) []*ec2.BlockDeviceMapping {
+	root := &ec2.BlockDeviceMapping{
+		DeviceName: aws.String(s.RootDevice.DeviceName),
+		EBS: &ec2.EBSBlockDevice{
+			SnapshotID:          aws.String(snapshotId),
+			VolumeType:          aws.String(s.RootDevice.VolumeType),
+			DeleteOnTermination: aws.Boolean(s.RootDevice.DeleteOnTermination),
+		},
+	}
+
+	if s.RootDevice.VolumeSize != 0 {
+		root.EBS.VolumeSize = aws.Long(s.RootDevice.VolumeSize)
+	}
+	if s.RootDevice.IOPS != 0 {
+		root.EBS.IOPS = aws.Long(s.RootDevice.IOPS)
+	}
+
+	result := []*ec2.BlockDeviceMapping{root}
+	for _, device := range s.BlockDevices {
+		if device.DeviceName != nil && *device.DeviceName == s.RootDevice.DeviceName {
+			continue
+		}
+
+		result = append(result, device)
+	}
+
+	return result
+}
@@ -0,0 +1,116 @@
+package ebssurrogate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mitchellh/multistep"
+	awscommon "github.com/mitchellh/packer/builder/amazon/common"
+	"github.com/mitchellh/packer/packer"
+)
+
+// StepSnapshotNewRootVolume creates a snapshot of the volume that was
+// built on the surrogate instance.
+//
+// Produces:
+//
+//	snapshot_id string - ID of the created snapshot
+type StepSnapshotNewRootVolume struct {
+	RootDevice RootBlockDevice
+
+	snapshotId string
+}
+
+func (s *StepSnapshotNewRootVolume) Run(state multistep.StateBag) multistep.StepAction {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+	instance := state.Get("instance").(*ec2.Instance)
+
+	volumeId, err := instanceVolumeId(ec2conn, instance, s.RootDevice.SourceDeviceName)
+	if err != nil {
+		err := fmt.Errorf("Error finding the new root volume: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Creating snapshot of the new root volume %s...", volumeId))
+	description := fmt.Sprintf("Packer: %s", time.Now().String())
+
+	createSnapResp, err := ec2conn.CreateSnapshot(&ec2.CreateSnapshotInput{
+		VolumeID:    &volumeId,
+		Description: &description,
+	})
+	if err != nil {
+		err := fmt.Errorf("Error creating snapshot: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Set the snapshot ID so we can delete it later
+	s.snapshotId = *createSnapResp.SnapshotID
+	ui.Message(fmt.Sprintf("Snapshot ID: %s", s.snapshotId))
+
+	// Wait for the snapshot to be ready
+	stateChange := awscommon.StateChangeConf{
+		Pending:   []string{"pending"},
+		StepState: state,
+		Target:    "completed",
+		Refresh:   awscommon.SnapshotStateRefreshFunc(ec2conn, s.snapshotId),
+	}
+
+	if _, err := awscommon.WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Error waiting for snapshot: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("snapshot_id", s.snapshotId)
+	return multistep.ActionContinue
+}
+
+func (s *StepSnapshotNewRootVolume) Cleanup(state multistep.StateBag) {
+	if s.snapshotId == "" {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+
+	if cancelled || halted {
+		ec2conn := state.Get("ec2").(*ec2.EC2)
+		ui := state.Get("ui").(packer.Ui)
+		ui.Say("Removing snapshot since we cancelled or halted...")
+		_, err := ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotID: &s.snapshotId})
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error: %s", err))
+		}
+	}
+}
+
+// instanceVolumeId returns the ID of the EBS volume that is attached to
+// the instance at the given device. The instance is described again,
+// since the mappings in the state are from the time it was launched.
+func instanceVolumeId(conn *ec2.EC2, instance *ec2.Instance, device string) (string, error) {
+	resp, err := conn.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIDs: []*string{instance.InstanceID},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Reservations) == 0 || len(resp.Reservations[0].Instances) == 0 {
+		return "", errors.New("the source instance wasn't found")
+	}
+
+	for _, m := range resp.Reservations[0].Instances[0].BlockDeviceMappings {
+		if m.DeviceName != nil && *m.DeviceName == device && m.EBS != nil {
+			return *m.EBS.VolumeID, nil
+		}
+	}
+
+	return "", fmt.Errorf("no EBS volume is attached at %s", device)
+}
@@ -0,0 +1,105 @@
+package ebsvolume
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mitchellh/packer/packer"
+)
+
+// Artifact is an artifact implementation that contains the built EBS
+// volumes and their snapshots.
+type Artifact struct {
+	// The region that the volumes are in.
+	Region string
+
+	// The volume and snapshot IDs by device name.
+	Volumes   map[string]string
+	Snapshots map[string]string
+
+	// BuilderId is the unique ID for the builder that created the volumes
+	BuilderIdValue string
+
+	// EC2 connection for performing API stuff.
+	Conn *ec2.EC2
+}
+
+func (a *Artifact) BuilderId() string {
+	return a.BuilderIdValue
+}
+
+func (*Artifact) Files() []string {
+	// We have no files
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	parts := make([]string, 0, len(a.Volumes)+len(a.Snapshots))
+	for _, id := range a.Volumes {
+		parts = append(parts, fmt.Sprintf("%s:%s", a.Region, id))
+	}
+	for _, id := range a.Snapshots {
+		parts = append(parts, fmt.Sprintf("%s:%s", a.Region, id))
+	}
+
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (a *Artifact) String() string {
+	devices := make([]string, 0, len(a.Volumes))
+	for device := range a.Volumes {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+
+	lines := make([]string, 0, len(devices))
+	for _, device := range devices {
+		line := fmt.Sprintf("%s: %s", device, a.Volumes[device])
+		if snapshotId, ok := a.Snapshots[device]; ok {
+			line += fmt.Sprintf(" (snapshot: %s)", snapshotId)
+		}
+
+		lines = append(lines, line)
+	}
+
+	return fmt.Sprintf("EBS volumes were created in %s:\n\n%s",
+		a.Region, strings.Join(lines, "\n"))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	errors := make([]error, 0)
+
+	for _, id := range a.Snapshots {
+		log.Printf("Deleting snapshot ID (%s) from region (%s)", id, a.Region)
+		input := &ec2.DeleteSnapshotInput{SnapshotID: &id}
+		if _, err := a.Conn.DeleteSnapshot(input); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	for _, id := range a.Volumes {
+		log.Printf("Deleting volume ID (%s) from region (%s)", id, a.Region)
+		input := &ec2.DeleteVolumeInput{VolumeID: &id}
+		if _, err := a.Conn.DeleteVolume(input); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if len(errors) > 0 {
+		if len(errors) == 1 {
+			return errors[0]
+		} else {
+			return &packer.MultiError{Errors: errors}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package ebsvolume
+
+import (
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestArtifactId(t *testing.T) {
+	a := &Artifact{
+		Region: "us-east-1",
+		Volumes: map[string]string{
+			"/dev/xvdg": "vol-bar",
+			"/dev/xvdf": "vol-foo",
+		},
+		Snapshots: map[string]string{
+			"/dev/xvdf": "snap-foo",
+		},
+	}
+
+	expected := "us-east-1:snap-foo,us-east-1:vol-bar,us-east-1:vol-foo"
+	if a.Id() != expected {
+		t.Fatalf("bad: %s", a.Id())
+	}
+}
+
+func TestArtifactString(t *testing.T) {
+	a := &Artifact{
+		Region: "us-east-1",
+		Volumes: map[string]string{
+			"/dev/xvdg": "vol-bar",
+			"/dev/xvdf": "vol-foo",
+		},
+		Snapshots: map[string]string{
+			"/dev/xvdf": "snap-foo",
+		},
+	}
+
+	expected := `EBS volumes were created in us-east-1:
+
+/dev/xvdf: vol-foo (snapshot: snap-foo)
+/dev/xvdg: vol-bar`
+	if a.String() != expected {
+		t.Fatalf("bad: %s", a.String())
+	}
+}
@@ -0,0 +1,63 @@
+package ebsvolume
+
+import (
+	"fmt"
+
+	awscommon "github.com/mitchellh/packer/builder/amazon/common"
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+// BlockDevice is an EBS volume that is attached to the source instance
+// and kept as part of the artifact after the instance is terminated.
+type BlockDevice struct {
+	awscommon.BlockDevice `mapstructure:",squash"`
+
+	Snapshot bool              `mapstructure:"snapshot"`
+	Tags     map[string]string `mapstructure:"tags"`
+}
+
+type BlockDevices []BlockDevice
+
+func (bds BlockDevices) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if len(bds) == 0 {
+		errs = append(errs, fmt.Errorf("at least one ebs_volumes must be specified"))
+	}
+
+	seen := make(map[string]struct{})
+	for i, bd := range bds {
+		if bd.DeviceName == "" {
+			errs = append(errs, fmt.Errorf("ebs_volumes[%d]: device_name must be specified", i))
+			continue
+		}
+		if _, ok := seen[bd.DeviceName]; ok {
+			errs = append(errs, fmt.Errorf(
+				"ebs_volumes[%d]: device_name %s is used more than once", i, bd.DeviceName))
+		}
+		seen[bd.DeviceName] = struct{}{}
+
+		if bd.NoDevice || bd.VirtualName != "" {
+			errs = append(errs, fmt.Errorf(
+				"ebs_volumes[%d]: only EBS volumes can be kept, so no_device and "+
+					"virtual_name can't be specified", i))
+		}
+	}
+
+	return errs
+}
+
+// LaunchDevices returns the block devices to launch the source instance
+// with. The volumes are never deleted on termination, since they are the
+// result of the build.
+func (bds BlockDevices) LaunchDevices() awscommon.BlockDevices {
+	result := awscommon.BlockDevices{
+		LaunchMappings: make([]awscommon.BlockDevice, len(bds)),
+	}
+	for i, bd := range bds {
+		result.LaunchMappings[i] = bd.BlockDevice
+		result.LaunchMappings[i].DeleteOnTermination = false
+	}
+
+	return result
+}
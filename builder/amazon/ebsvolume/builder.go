@@ -0,0 +1,196 @@
+// The ebsvolume package contains a packer.Builder implementation that
+// builds EBS volumes for Amazon EC2. The volumes are attached to an
+// instance that the provisioners run on, and are kept, optionally with
+// snapshots, as the artifact of the build instead of an AMI.
+package ebsvolume
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mitchellh/multistep"
+	awscommon "github.com/mitchellh/packer/builder/amazon/common"
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/helper/communicator"
+	"github.com/mitchellh/packer/helper/config"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+// The unique ID for this builder
+const BuilderId = "mitchellh.amazon.ebsvolume"
+
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	awscommon.AccessConfig `mapstructure:",squash"`
+	awscommon.RunConfig    `mapstructure:",squash"`
+
+	VolumeMappings BlockDevices `mapstructure:"ebs_volumes"`
+
+	ctx *interpolate.Context
+}
+
+type Builder struct {
+	config Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	b.config.ctx = &interpolate.Context{Funcs: awscommon.TemplateFuncs}
+	err := config.Decode(&b.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: b.config.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Accumulate any errors
+	var errs *packer.MultiError
+	errs = packer.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.VolumeMappings.Prepare(b.config.ctx)...)
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, errs
+	}
+
+	log.Println(common.ScrubConfig(b.config, b.config.AccessKey, b.config.SecretKey))
+	return nil, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	if err := b.config.AskMFACode(ui); err != nil {
+		return nil, err
+	}
+
+	config, err := b.config.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	ec2conn := ec2.New(config)
+
+	// Setup the state bag and initial state for the steps
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &b.config)
+	state.Put("ec2", ec2conn)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	snapshot := false
+	for _, mapping := range b.config.VolumeMappings {
+		snapshot = snapshot || mapping.Snapshot
+	}
+
+	// Build the steps
+	steps := []multistep.Step{
+		&awscommon.StepSourceAMIInfo{
+			SourceAmi: b.config.SourceAmi,
+		},
+		&awscommon.StepKeyPair{
+			Debug:          b.config.PackerDebug,
+			DebugKeyPath:   fmt.Sprintf("ec2_%s.pem", b.config.PackerBuildName),
+			KeyPairName:    b.config.TemporaryKeyPairName,
+			PrivateKeyFile: b.config.RunConfig.Comm.SSHPrivateKey,
+		},
+		&awscommon.StepSecurityGroup{
+			SecurityGroupIds: b.config.SecurityGroupIds,
+			CommConfig:       &b.config.RunConfig.Comm,
+			VpcId:            b.config.VpcId,
+		},
+		&stepCleanupVolumes{},
+		&awscommon.StepRunSourceInstance{
+			Debug:                    b.config.PackerDebug,
+			ExpectedRootDevice:       "ebs",
+			SpotPrice:                b.config.SpotPrice,
+			SpotPriceProduct:         b.config.SpotPriceAutoProduct,
+			InstanceType:             b.config.InstanceType,
+			UserData:                 b.config.UserData,
+			UserDataFile:             b.config.UserDataFile,
+			SourceAMI:                b.config.SourceAmi,
+			IamInstanceProfile:       b.config.IamInstanceProfile,
+			SubnetId:                 b.config.SubnetId,
+			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
+			AvailabilityZone:         b.config.AvailabilityZone,
+			BlockDevices:             b.config.VolumeMappings.LaunchDevices(),
+			Tags:                     b.config.RunTags,
+		},
+		&stepTagEBSVolumes{
+			VolumeMapping: b.config.VolumeMappings,
+		},
+		&awscommon.StepGetPassword{
+			Comm:    &b.config.RunConfig.Comm,
+			Timeout: b.config.WindowsPasswordTimeout,
+		},
+		&communicator.StepConnect{
+			Config: &b.config.RunConfig.Comm,
+			Host: awscommon.SSHHost(
+				ec2conn,
+				b.config.SSHPrivateIp),
+			SSHConfig: awscommon.SSHConfig(
+				b.config.RunConfig.Comm.SSHUsername),
+		},
+		&common.StepProvision{},
+	}
+
+	// Snapshots are taken of the stopped instance so that they are
+	// consistent.
+	if snapshot {
+		steps = append(steps,
+			&awscommon.StepStopInstance{SpotPrice: b.config.SpotPrice},
+			&stepSnapshotEBSVolumes{
+				VolumeMapping: b.config.VolumeMappings,
+			},
+		)
+	}
+
+	// Run!
+	if b.config.PackerDebug {
+		b.runner = &multistep.DebugRunner{
+			Steps:   steps,
+			PauseFn: common.MultistepDebugFn(ui),
+		}
+	} else {
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
+	}
+
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	// If there are no volumes, then just return
+	if _, ok := state.GetOk("ebs_volume_ids"); !ok {
+		return nil, nil
+	}
+
+	// Build the artifact and return it
+	artifact := &Artifact{
+		Region:         ec2conn.Config.Region,
+		Volumes:        state.Get("ebs_volume_ids").(map[string]string),
+		BuilderIdValue: BuilderId,
+		Conn:           ec2conn,
+	}
+	if raw, ok := state.GetOk("ebs_snapshot_ids"); ok {
+		artifact.Snapshots = raw.(map[string]string)
+	}
+
+	return artifact, nil
+}
+
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
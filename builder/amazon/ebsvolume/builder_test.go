@@ -0,0 +1,87 @@
+package ebsvolume
+
+import (
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"access_key":    "foo",
+		"secret_key":    "bar",
+		"source_ami":    "foo",
+		"instance_type": "foo",
+		"region":        "us-east-1",
+		"ssh_username":  "root",
+		"ebs_volumes": []map[string]interface{}{
+			{
+				"device_name":           "/dev/xvdf",
+				"volume_size":           8,
+				"delete_on_termination": true,
+				"snapshot":              true,
+				"tags": map[string]string{
+					"Name": "data",
+				},
+			},
+		},
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare(t *testing.T) {
+	var b Builder
+	warns, err := b.Prepare(testConfig())
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(b.config.VolumeMappings) != 1 {
+		t.Fatalf("bad: %#v", b.config.VolumeMappings)
+	}
+	v := b.config.VolumeMappings[0]
+	if !v.Snapshot || v.Tags["Name"] != "data" || v.VolumeSize != 8 {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	// The volumes are the artifact, so they must survive termination
+	launch := b.config.VolumeMappings.LaunchDevices()
+	if launch.LaunchMappings[0].DeleteOnTermination {
+		t.Fatal("should not delete on termination")
+	}
+}
+
+func TestBuilderPrepare_EBSVolumes(t *testing.T) {
+	cases := []interface{}{
+		[]map[string]interface{}{},
+		[]map[string]interface{}{
+			{"volume_size": 8},
+		},
+		[]map[string]interface{}{
+			{"device_name": "/dev/xvdf"},
+			{"device_name": "/dev/xvdf"},
+		},
+		[]map[string]interface{}{
+			{"device_name": "/dev/xvdf", "virtual_name": "ephemeral0"},
+		},
+	}
+
+	for _, tc := range cases {
+		var b Builder
+		config := testConfig()
+		config["ebs_volumes"] = tc
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error: %#v", tc)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package ebsvolume
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mitchellh/multistep"
+	awscommon "github.com/mitchellh/packer/builder/amazon/common"
+	"github.com/mitchellh/packer/packer"
+)
+
+// stepCleanupVolumes deletes the volumes of a failed build. It runs before
+// the source instance is launched, so that its cleanup runs after the
+// instance is terminated and the volumes are detached.
+type stepCleanupVolumes struct{}
+
+func (s *stepCleanupVolumes) Run(state multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (s *stepCleanupVolumes) Cleanup(state multistep.StateBag) {
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	raw, ok := state.GetOk("ebs_volume_ids")
+	if !ok {
+		return
+	}
+
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deleting the volumes because of cancellation or error...")
+	for _, id := range raw.(map[string]string) {
+		stateChange := awscommon.StateChangeConf{
+			Pending: []string{"in-use"},
+			Target:  "available",
+			Refresh: awscommon.VolumeStateRefreshFunc(ec2conn, id),
+		}
+		if _, err := awscommon.WaitForState(&stateChange); err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error waiting for volume %s to detach, may still be around: %s", id, err))
+			continue
+		}
+
+		if _, err := ec2conn.DeleteVolume(&ec2.DeleteVolumeInput{VolumeID: &id}); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting volume %s, may still be around: %s", id, err))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package ebsvolume
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mitchellh/multistep"
+	awscommon "github.com/mitchellh/packer/builder/amazon/common"
+	"github.com/mitchellh/packer/packer"
+)
+
+// stepSnapshotEBSVolumes creates a snapshot of each volume that asks for
+// one. The snapshots get the tags of their volume.
+//
+// Produces:
+//
+//	ebs_snapshot_ids map[string]string - The snapshot IDs by device name.
+type stepSnapshotEBSVolumes struct {
+	VolumeMapping BlockDevices
+
+	snapshotIds map[string]string
+}
+
+func (s *stepSnapshotEBSVolumes) Run(state multistep.StateBag) multistep.StepAction {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+	volumeIds := state.Get("ebs_volume_ids").(map[string]string)
+
+	s.snapshotIds = make(map[string]string)
+	for _, mapping := range s.VolumeMapping {
+		if !mapping.Snapshot {
+			continue
+		}
+
+		volumeId := volumeIds[mapping.DeviceName]
+		ui.Say(fmt.Sprintf("Creating snapshot of volume %s...", volumeId))
+		description := fmt.Sprintf("Packer: %s", time.Now().String())
+
+		createSnapResp, err := ec2conn.CreateSnapshot(&ec2.CreateSnapshotInput{
+			VolumeID:    &volumeId,
+			Description: &description,
+		})
+		if err != nil {
+			err := fmt.Errorf("Error creating snapshot: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		snapshotId := *createSnapResp.SnapshotID
+		s.snapshotIds[mapping.DeviceName] = snapshotId
+		ui.Message(fmt.Sprintf("Snapshot ID: %s", snapshotId))
+
+		if len(mapping.Tags) > 0 {
+			if err := createTags(ec2conn, snapshotId, mapping.Tags); err != nil {
+				err := fmt.Errorf("Error adding tags to snapshot (%s): %s", snapshotId, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
+	}
+
+	// Wait for all the snapshots at once, since they are taken in parallel
+	for _, snapshotId := range s.snapshotIds {
+		stateChange := awscommon.StateChangeConf{
+			Pending:   []string{"pending"},
+			StepState: state,
+			Target:    "completed",
+			Refresh:   awscommon.SnapshotStateRefreshFunc(ec2conn, snapshotId),
+		}
+
+		ui.Say(fmt.Sprintf("Waiting for snapshot %s to complete...", snapshotId))
+		if _, err := awscommon.WaitForState(&stateChange); err != nil {
+			err := fmt.Errorf("Error waiting for snapshot: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	state.Put("ebs_snapshot_ids", s.snapshotIds)
+	return multistep.ActionContinue
+}
+
+func (s *stepSnapshotEBSVolumes) Cleanup(state multistep.StateBag) {
+	if len(s.snapshotIds) == 0 {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+
+	if cancelled || halted {
+		ec2conn := state.Get("ec2").(*ec2.EC2)
+		ui := state.Get("ui").(packer.Ui)
+		ui.Say("Removing snapshots since we cancelled or halted...")
+		for _, id := range s.snapshotIds {
+			_, err := ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotID: &id})
+			if err != nil {
+				ui.Error(fmt.Sprintf("Error: %s", err))
+			}
+		}
+	}
+}
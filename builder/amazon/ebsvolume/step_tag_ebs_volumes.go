@@ -0,0 +1,95 @@
+package ebsvolume
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// stepTagEBSVolumes finds the volumes that were attached to the source
+// instance and tags them.
+//
+// Produces:
+//
+//	ebs_volume_ids map[string]string - The volume IDs by device name.
+type stepTagEBSVolumes struct {
+	VolumeMapping BlockDevices
+}
+
+func (s *stepTagEBSVolumes) Run(state multistep.StateBag) multistep.StepAction {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	instance := state.Get("instance").(*ec2.Instance)
+	ui := state.Get("ui").(packer.Ui)
+
+	volumeIds := make(map[string]string)
+	for _, m := range instance.BlockDeviceMappings {
+		if m.DeviceName != nil && m.EBS != nil && m.EBS.VolumeID != nil {
+			volumeIds[*m.DeviceName] = *m.EBS.VolumeID
+		}
+	}
+
+	// Record the volumes first, so that they are cleaned up even if
+	// tagging fails.
+	result := make(map[string]string)
+	for _, mapping := range s.VolumeMapping {
+		id, ok := volumeIds[mapping.DeviceName]
+		if !ok {
+			err := fmt.Errorf(
+				"Volume %s wasn't attached to the source instance", mapping.DeviceName)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		result[mapping.DeviceName] = id
+	}
+	state.Put("ebs_volume_ids", result)
+
+	for _, mapping := range s.VolumeMapping {
+		id := result[mapping.DeviceName]
+		ui.Message(fmt.Sprintf("Volume %s: %s", mapping.DeviceName, id))
+		if len(mapping.Tags) == 0 {
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Adding tags to volume (%s)...", id))
+		if err := createTags(ec2conn, id, mapping.Tags); err != nil {
+			err := fmt.Errorf("Error adding tags to volume (%s): %s", id, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepTagEBSVolumes) Cleanup(state multistep.StateBag) {
+	// No cleanup, stepCleanupVolumes removes the volumes on failure.
+}
+
+func createTags(conn *ec2.EC2, resource string, tags map[string]string) error {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ec2Tags := make([]*ec2.Tag, 0, len(keys))
+	for _, k := range keys {
+		ec2Tags = append(ec2Tags, &ec2.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(tags[k]),
+		})
+	}
+
+	_, err := conn.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{aws.String(resource)},
+		Tags:      ec2Tags,
+	})
+	return err
+}
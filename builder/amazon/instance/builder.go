@@ -56,6 +56,9 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateContext: b.config.ctx,
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
+				"ami_description",
+				"ami_name",
+				"tags",
 				"bundle_upload_command",
 				"bundle_vol_command",
 			},
@@ -116,7 +119,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	var errs *packer.MultiError
 	errs = packer.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.BlockDevices.Prepare(b.config.ctx)...)
-	errs = packer.MultiErrorAppend(errs, b.config.AMIConfig.Prepare(b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.AMIConfig.Prepare(&b.config.AccessConfig, b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(b.config.ctx)...)
 
 	if b.config.AccountId == "" {
@@ -152,11 +155,19 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	if err := b.config.AskMFACode(ui); err != nil {
+		return nil, err
+	}
+
 	config, err := b.config.Config()
 	if err != nil {
 		return nil, err
 	}
 
+	if err := b.config.AMIConfig.RenderNameAndTags(*b.config.ctx, config); err != nil {
+		return nil, err
+	}
+
 	ec2conn := ec2.New(config)
 
 	// Setup the state bag and initial state for the steps
@@ -168,6 +179,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	// Build the steps
 	steps := []multistep.Step{
+		&awscommon.StepPreValidate{
+			DestAmiName:     b.config.AMIName,
+			ForceDeregister: b.config.AMIForceDeregister,
+		},
 		&awscommon.StepSourceAMIInfo{
 			SourceAmi:          b.config.SourceAmi,
 			EnhancedNetworking: b.config.AMIEnhancedNetworking,
@@ -218,6 +233,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&StepUploadBundle{
 			Debug: b.config.PackerDebug,
 		},
+		&awscommon.StepDeregisterAMI{
+			AccessConfig:        &b.config.AccessConfig,
+			ForceDeregister:     b.config.AMIForceDeregister,
+			ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
+			AMIName:             b.config.AMIName,
+			Regions:             b.config.AMIRegions,
+		},
 		&StepRegisterAMI{},
 		&awscommon.StepAMIRegionCopy{
 			AccessConfig: &b.config.AccessConfig,
@@ -228,7 +250,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Description:  b.config.AMIDescription,
 			Users:        b.config.AMIUsers,
 			Groups:       b.config.AMIGroups,
+			OrgARNs:      b.config.AMIOrgARNs,
+			OuARNs:       b.config.AMIOuARNs,
 			ProductCodes: b.config.AMIProductCodes,
+			DeprecateAt:  b.config.AMIDeprecateAt,
+			Ctx:          *b.config.ctx,
 		},
 		&awscommon.StepCreateTags{
 			Tags: b.config.AMITags,
@@ -242,7 +268,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 
 	b.runner.Run(state)
@@ -267,6 +295,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	return artifact, nil
 }
 
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (b *Builder) Cancel() {
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/digitalocean/godo"
+	"github.com/mitchellh/packer/common"
 )
 
 type Artifact struct {
@@ -40,7 +41,20 @@ func (a *Artifact) String() string {
 }
 
 func (a *Artifact) State(name string) interface{} {
-	return nil
+	switch name {
+	case common.ArtifactStateData:
+		return map[string]string{
+			"SnapshotId":   a.Id(),
+			"SnapshotName": a.snapshotName,
+			"Region":       a.regionName,
+		}
+	case common.ArtifactStateImageId:
+		return a.Id()
+	case common.ArtifactStateRegions:
+		return map[string]string{a.regionName: a.Id()}
+	default:
+		return nil
+	}
 }
 
 func (a *Artifact) Destroy() error {
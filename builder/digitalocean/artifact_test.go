@@ -1,8 +1,10 @@
 package digitalocean
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -31,3 +33,16 @@ func TestArtifactString(t *testing.T) {
 		t.Fatalf("artifact string should match: %v", expected)
 	}
 }
+
+func TestArtifactState(t *testing.T) {
+	a := &Artifact{"packer-foobar", 42, "sfo1", nil}
+
+	if id, ok := common.ArtifactImageId(a); !ok || id != "42" {
+		t.Fatalf("bad: %#v", id)
+	}
+
+	regions, ok := common.ArtifactRegions(a)
+	if !ok || !reflect.DeepEqual(regions, map[string]string{"sfo1": "42"}) {
+		t.Fatalf("bad: %#v", regions)
+	}
+}
@@ -27,6 +27,7 @@ type Config struct {
 
 	PrivateNetworking bool          `mapstructure:"private_networking"`
 	SnapshotName      string        `mapstructure:"snapshot_name"`
+	ForceDelete       bool          `mapstructure:"force_delete"`
 	StateTimeout      time.Duration `mapstructure:"state_timeout"`
 	DropletName       string        `mapstructure:"droplet_name"`
 	UserData          string        `mapstructure:"user_data"`
@@ -0,0 +1,29 @@
+package digitalocean
+
+import (
+	"github.com/digitalocean/godo"
+)
+
+// listUserImages returns all of the images of the user. The API returns
+// them a page at a time, so every page is requested.
+func listUserImages(client *godo.Client) ([]godo.Image, error) {
+	var result []godo.Image
+	opt := &godo.ListOptions{PerPage: 200}
+	for {
+		images, resp, err := client.Images.ListUser(opt)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, images...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return result, nil
+		}
+
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = page + 1
+	}
+}
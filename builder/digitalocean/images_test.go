@@ -0,0 +1,38 @@
+package digitalocean
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestListUserImages(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprintf(w, `{"images": [{"id": 2, "name": "b"}], "links": {"pages": {`+
+				`"first": "%[1]s/v2/images?page=1", "prev": "%[1]s/v2/images?page=1"}}}`, ts.URL)
+			return
+		}
+
+		fmt.Fprintf(w, `{"images": [{"id": 1, "name": "a"}], "links": {"pages": {`+
+			`"next": "%[1]s/v2/images?page=2", "last": "%[1]s/v2/images?page=2"}}}`, ts.URL)
+	}))
+	defer ts.Close()
+
+	client := godo.NewClient(http.DefaultClient)
+	client.BaseURL, _ = url.Parse(ts.URL)
+
+	images, err := listUserImages(client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(images) != 2 || images[0].ID != 1 || images[1].ID != 2 {
+		t.Fatalf("bad: %#v", images)
+	}
+}
@@ -0,0 +1,51 @@
+package digitalocean
+
+import (
+	"fmt"
+
+	"github.com/digitalocean/godo"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// stepCheckExistingSnapshot verifies that no snapshot with the configured
+// name exists before the droplet is created. If force_delete is set, the
+// existing snapshots are deleted by stepDeleteExistingSnapshot instead,
+// once the droplet is ready to be snapshotted.
+type stepCheckExistingSnapshot struct{}
+
+func (s *stepCheckExistingSnapshot) Run(state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(Config)
+
+	if c.ForceDelete {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Checking snapshot does not exist: %s", c.SnapshotName))
+	images, err := listUserImages(client)
+	if err != nil {
+		err := fmt.Errorf("Error listing snapshots: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	for _, image := range images {
+		if image.Name == c.SnapshotName {
+			err := fmt.Errorf(
+				"Error: snapshot name conflicts with an existing snapshot: %d",
+				image.ID)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCheckExistingSnapshot) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
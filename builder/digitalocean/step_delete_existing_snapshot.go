@@ -0,0 +1,54 @@
+package digitalocean
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/digitalocean/godo"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// stepDeleteExistingSnapshot deletes the snapshots with the configured
+// name if force_delete is set. It runs just before the new snapshot is
+// taken so that a build that fails earlier keeps the existing ones.
+type stepDeleteExistingSnapshot struct{}
+
+func (s *stepDeleteExistingSnapshot) Run(state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(Config)
+
+	if !c.ForceDelete {
+		return multistep.ActionContinue
+	}
+
+	images, err := listUserImages(client)
+	if err != nil {
+		err := fmt.Errorf("Error listing snapshots: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	for _, image := range images {
+		if image.Name != c.SnapshotName {
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Deleting existing snapshot: %d", image.ID))
+		log.Printf("Deleting snapshot %d with name %s", image.ID, image.Name)
+		if _, err := client.Images.Delete(image.ID); err != nil {
+			err := fmt.Errorf("Error deleting existing snapshot: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepDeleteExistingSnapshot) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -8,10 +8,15 @@ import (
 // ExportArtifact is an Artifact implementation for when a container is
 // exported from docker into a single flat file.
 type ExportArtifact struct {
-	path string
+	path   string
+	format string
 }
 
-func (*ExportArtifact) BuilderId() string {
+func (a *ExportArtifact) BuilderId() string {
+	if a.format == ExportFormatOCI || a.format == ExportFormatOCIDir {
+		return BuilderIdOCI
+	}
+
 	return BuilderId
 }
 
@@ -24,6 +29,10 @@ func (*ExportArtifact) Id() string {
 }
 
 func (a *ExportArtifact) String() string {
+	if a.BuilderId() == BuilderIdOCI {
+		return fmt.Sprintf("Exported OCI image layout: %s", a.path)
+	}
+
 	return fmt.Sprintf("Exported Docker file: %s", a.path)
 }
 
@@ -32,5 +41,5 @@ func (a *ExportArtifact) State(name string) interface{} {
 }
 
 func (a *ExportArtifact) Destroy() error {
-	return os.Remove(a.path)
+	return os.RemoveAll(a.path)
 }
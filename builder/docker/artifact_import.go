@@ -2,6 +2,8 @@ package docker
 
 import (
 	"fmt"
+
+	"github.com/mitchellh/packer/common"
 )
 
 // ImportArtifact is an Artifact implementation for when a container is
@@ -28,8 +30,13 @@ func (a *ImportArtifact) String() string {
 	return fmt.Sprintf("Imported Docker image: %s", a.Id())
 }
 
-func (*ImportArtifact) State(name string) interface{} {
-	return nil
+func (a *ImportArtifact) State(name string) interface{} {
+	switch name {
+	case common.ArtifactStateImageId:
+		return a.IdValue
+	default:
+		return nil
+	}
 }
 
 func (a *ImportArtifact) Destroy() error {
@@ -2,6 +2,7 @@ package docker
 
 import (
 	"errors"
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 	"testing"
 )
@@ -31,6 +32,13 @@ func TestImportArtifactId(t *testing.T) {
 	}
 }
 
+func TestImportArtifactState(t *testing.T) {
+	a := &ImportArtifact{IdValue: "foo"}
+	if id, ok := common.ArtifactImageId(a); !ok || id != "foo" {
+		t.Fatalf("bad: %#v", id)
+	}
+}
+
 func TestImportArtifactDestroy(t *testing.T) {
 	d := new(MockDriver)
 	a := &ImportArtifact{
@@ -5,11 +5,13 @@ import (
 
 	"github.com/mitchellh/multistep"
 	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/helper/config"
 	"github.com/mitchellh/packer/packer"
 )
 
 const BuilderId = "packer.docker"
 const BuilderIdImport = "packer.post-processor.docker-import"
+const BuilderIdOCI = "packer.docker.oci"
 
 type Builder struct {
 	config *Config
@@ -42,6 +44,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&StepTempDir{},
 		&StepPull{},
 		&StepRun{},
+		&StepWaitForHealthy{},
 		&StepProvision{},
 	}
 
@@ -67,7 +70,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 
 	b.runner.Run(state)
@@ -91,12 +96,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Driver:         driver,
 		}
 	} else {
-		artifact = &ExportArtifact{path: b.config.ExportPath}
+		artifact = &ExportArtifact{
+			path:   b.config.ExportPath,
+			format: b.config.ExportFormat,
+		}
 	}
 
 	return artifact, nil
 }
 
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (b *Builder) Cancel() {
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
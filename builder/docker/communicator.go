@@ -25,6 +25,10 @@ type Communicator struct {
 	ContainerDir string
 	Version      *version.Version
 
+	// Windows is true if the container runs Windows, in which case
+	// commands are run with cmd.exe rather than /bin/sh.
+	Windows bool
+
 	lock sync.Mutex
 }
 
@@ -45,7 +49,8 @@ func (c *Communicator) Start(remote *packer.RemoteCmd) error {
 
 	var cmd *exec.Cmd
 	if c.canExec() {
-		cmd = exec.Command("docker", "exec", "-i", c.ContainerId, "/bin/sh")
+		cmd = exec.Command("docker", append(
+			[]string{"exec", "-i", c.ContainerId}, c.shell()...)...)
 	} else {
 		cmd = exec.Command("docker", "attach", c.ContainerId)
 	}
@@ -86,6 +91,10 @@ func (c *Communicator) Upload(dst string, src io.Reader, fi *os.FileInfo) error
 		Command: fmt.Sprintf("command cp %s/%s %s", c.ContainerDir,
 			filepath.Base(tempfile.Name()), dst),
 	}
+	if c.Windows {
+		cmd.Command = fmt.Sprintf("copy /Y \"%s/%s\" \"%s\"", c.ContainerDir,
+			filepath.Base(tempfile.Name()), dst)
+	}
 
 	if err := c.Start(cmd); err != nil {
 		return err
@@ -177,6 +186,10 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 		Command: fmt.Sprintf("set -e; mkdir -p %s; command cp -R %s/* %s",
 			containerDst, containerSrc, containerDst),
 	}
+	if c.Windows {
+		cmd.Command = fmt.Sprintf("xcopy /E /I /Y \"%s\" \"%s\"",
+			containerSrc, containerDst)
+	}
 	if err := c.Start(cmd); err != nil {
 		return err
 	}
@@ -203,6 +216,18 @@ func (c *Communicator) canExec() bool {
 	return execConstraint.Check(c.Version)
 }
 
+// shell returns the command line used to read commands from stdin
+// inside the container.
+func (c *Communicator) shell() []string {
+	if c.Windows {
+		// Delayed expansion is required so that !errorlevel! is
+		// evaluated after the command runs.
+		return []string{"cmd", "/V:ON"}
+	}
+
+	return []string{"/bin/sh"}
+}
+
 // Runs the given command and blocks until completion
 func (c *Communicator) run(cmd *exec.Cmd, remote *packer.RemoteCmd, stdin_w io.WriteCloser, outputFile *os.File, exitCodePath string) {
 	// For Docker, remote communication must be serialized since it
@@ -233,7 +258,11 @@ func (c *Communicator) run(cmd *exec.Cmd, remote *packer.RemoteCmd, stdin_w io.W
 	// is truly complete (because the file will have data), what the
 	// exit status is (because Docker loses it because of the pty, not
 	// Docker's fault), and get the output (Docker bug).
-	remoteCmd := fmt.Sprintf("(%s) >%s 2>&1; echo $? >%s",
+	format := "(%s) >%s 2>&1; echo $? >%s"
+	if c.Windows {
+		format = "(%s) >%s 2>&1 & echo !errorlevel! >%s"
+	}
+	remoteCmd := fmt.Sprintf(format,
 		remote.Command,
 		filepath.Join(c.ContainerDir, filepath.Base(outputFile.Name())),
 		filepath.Join(c.ContainerDir, filepath.Base(exitCodePath)))
@@ -3,6 +3,8 @@ package docker
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/mitchellh/packer/common"
@@ -11,15 +13,32 @@ import (
 	"github.com/mitchellh/packer/template/interpolate"
 )
 
+const (
+	// ExportFormatTar is a flat tar of the container's filesystem, as
+	// produced by `docker export`.
+	ExportFormatTar = "tar"
+
+	// ExportFormatOCI is an OCI image layout packed into a tar file.
+	ExportFormatOCI = "oci"
+
+	// ExportFormatOCIDir is an OCI image layout written to a directory.
+	ExportFormatOCIDir = "oci-dir"
+)
+
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	Commit     bool
-	ExportPath string `mapstructure:"export_path"`
-	Image      string
-	Pull       bool
-	RunCommand []string `mapstructure:"run_command"`
-	Volumes    map[string]string
+	Commit         bool
+	ExportFormat   string        `mapstructure:"export_format"`
+	ExportPath     string        `mapstructure:"export_path"`
+	HealthCommand  string        `mapstructure:"health_command"`
+	HealthTimeout  time.Duration `mapstructure:"health_timeout"`
+	Image          string
+	Platform       string
+	Pull           bool
+	RunCommand     []string `mapstructure:"run_command"`
+	Volumes        map[string]string
+	WaitForHealthy bool `mapstructure:"wait_for_healthy"`
 
 	Login         bool
 	LoginEmail    string `mapstructure:"login_email"`
@@ -27,6 +46,8 @@ type Config struct {
 	LoginPassword string `mapstructure:"login_password"`
 	LoginServer   string `mapstructure:"login_server"`
 
+	RegistryAuthConfig `mapstructure:",squash"`
+
 	ctx interpolate.Context
 }
 
@@ -47,12 +68,24 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		return nil, nil, err
 	}
 
+	var errs *packer.MultiError
+	if c.Platform != "" {
+		if err := validatePlatform(c.Platform); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+
 	// Defaults
 	if len(c.RunCommand) == 0 {
+		shell := "/bin/bash"
+		if c.Windows() {
+			shell = "cmd"
+		}
+
 		c.RunCommand = []string{
 			"-d", "-i", "-t",
 			"{{.Image}}",
-			"/bin/bash",
+			shell,
 		}
 	}
 
@@ -69,7 +102,15 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.Pull = true
 	}
 
-	var errs *packer.MultiError
+	if c.HealthTimeout == 0 {
+		c.HealthTimeout = 5 * time.Minute
+	}
+
+	if c.HealthTimeout < 0 {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("health_timeout must be positive"))
+	}
+
 	if c.Image == "" {
 		errs = packer.MultiErrorAppend(errs,
 			fmt.Errorf("image must be specified"))
@@ -80,7 +121,29 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 			fmt.Errorf("both commit and export_path cannot be set"))
 	}
 
-	if c.ExportPath != "" {
+	for _, err := range c.RegistryAuthConfig.Prepare(c.LoginServer) {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
+	if c.ExportFormat == "" {
+		c.ExportFormat = ExportFormatTar
+	}
+
+	switch c.ExportFormat {
+	case ExportFormatTar, ExportFormatOCI, ExportFormatOCIDir:
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"export_format must be one of %s, %s, or %s",
+			ExportFormatTar, ExportFormatOCI, ExportFormatOCIDir))
+	}
+
+	if c.ExportPath != "" && c.ExportFormat == ExportFormatOCIDir {
+		if fi, err := os.Stat(c.ExportPath); err == nil && !fi.IsDir() {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"export_path must be a directory for the %s format",
+				ExportFormatOCIDir))
+		}
+	} else if c.ExportPath != "" {
 		if fi, err := os.Stat(c.ExportPath); err == nil && fi.IsDir() {
 			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
 				"export_path must be a file, not a directory"))
@@ -93,3 +156,42 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 
 	return c, nil, nil
 }
+
+// Windows returns true if the container is built for a Windows platform.
+func (c *Config) Windows() bool {
+	return strings.HasPrefix(c.Platform, "windows/")
+}
+
+// HealthProbeCommand returns the command that runs health_command in the
+// container, or nil if it isn't set.
+func (c *Config) HealthProbeCommand() []string {
+	if c.HealthCommand == "" {
+		return nil
+	}
+
+	if c.Windows() {
+		return []string{"cmd", "/C", c.HealthCommand}
+	}
+
+	return []string{"/bin/sh", "-c", c.HealthCommand}
+}
+
+// ContainerDir returns the path inside the container where the temporary
+// directory used for file uploads is mounted.
+func (c *Config) ContainerDir() string {
+	if c.Windows() {
+		return "c:/packer-files"
+	}
+
+	return "/packer-files"
+}
+
+// loginCredentials returns the username and password to log in to
+// login_server with, from a registry login helper if one is enabled.
+func (c *Config) loginCredentials() (string, string, error) {
+	if c.RegistryAuthConfig.Enabled() {
+		return c.RegistryAuthConfig.Credentials(c.LoginServer)
+	}
+
+	return c.LoginUsername, c.LoginPassword, nil
+}
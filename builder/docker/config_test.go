@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 )
 
 func testConfig() map[string]interface{} {
@@ -115,3 +116,100 @@ func TestConfigPrepare_pull(t *testing.T) {
 		t.Fatal("should not pull")
 	}
 }
+
+func TestConfigPrepare_platform(t *testing.T) {
+	raw := testConfig()
+
+	// No platform
+	delete(raw, "platform")
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.Windows() {
+		t.Fatal("should not be windows")
+	}
+
+	// Good platforms
+	for _, p := range []string{"linux/amd64", "linux/arm64", "linux/arm/v7"} {
+		raw["platform"] = p
+		_, warns, errs = NewConfig(raw)
+		testConfigOk(t, warns, errs)
+	}
+
+	// Windows platform
+	raw["platform"] = "windows/amd64"
+	c, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if !c.Windows() {
+		t.Fatal("should be windows")
+	}
+	if c.RunCommand[len(c.RunCommand)-1] != "cmd" {
+		t.Fatalf("bad: %#v", c.RunCommand)
+	}
+	if c.ContainerDir() != "c:/packer-files" {
+		t.Fatalf("bad: %s", c.ContainerDir())
+	}
+
+	// Bad platforms
+	for _, p := range []string{"linux", "linux/", "darwin/amd64", "a/b/c/d"} {
+		raw["platform"] = p
+		_, warns, errs = NewConfig(raw)
+		testConfigErr(t, warns, errs)
+	}
+}
+
+func TestConfigPrepare_exportFormat(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	raw := testConfig()
+
+	// Default
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.ExportFormat != ExportFormatTar {
+		t.Fatalf("bad: %s", c.ExportFormat)
+	}
+
+	// Good
+	raw["export_format"] = ExportFormatOCI
+	_, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+
+	// Bad
+	raw["export_format"] = "bad"
+	_, warns, errs = NewConfig(raw)
+	testConfigErr(t, warns, errs)
+
+	// Directory layouts can be exported to a directory
+	raw["export_format"] = ExportFormatOCIDir
+	raw["export_path"] = td
+	_, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+}
+
+func TestConfigPrepare_healthTimeout(t *testing.T) {
+	raw := testConfig()
+
+	// Default
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.HealthTimeout != 5*time.Minute {
+		t.Fatalf("bad: %s", c.HealthTimeout)
+	}
+
+	// Good
+	raw["health_timeout"] = "30s"
+	c, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.HealthTimeout != 30*time.Second {
+		t.Fatalf("bad: %s", c.HealthTimeout)
+	}
+
+	// Bad
+	raw["health_timeout"] = "-30s"
+	_, warns, errs = NewConfig(raw)
+	testConfigErr(t, warns, errs)
+}
@@ -19,6 +19,19 @@ type Driver interface {
 	// Export exports the container with the given ID to the given writer.
 	Export(id string, dst io.Writer) error
 
+	// HealthProbe runs the command in the container with the given ID,
+	// returning an error if it doesn't exit successfully.
+	HealthProbe(id string, command []string) error
+
+	// HealthStatus returns the status of the HEALTHCHECK of the container
+	// with the given ID, such as "starting" or "healthy". It is empty if
+	// the container has no HEALTHCHECK.
+	HealthStatus(id string) (string, error)
+
+	// ImageConfig returns the container config of the image with the
+	// given ID or name, such as its Env and Cmd.
+	ImageConfig(id string) (*ociContainerConfig, error)
+
 	// Import imports a container from a tar file
 	Import(path, repo string) (string, error)
 
@@ -29,8 +42,9 @@ type Driver interface {
 	// Logout. This can only be called if Login succeeded.
 	Logout(repo string) error
 
-	// Pull should pull down the given image.
-	Pull(image string) error
+	// Pull should pull down the given image. If platform is not empty,
+	// the image for that platform is pulled.
+	Pull(image, platform string) error
 
 	// Push pushes an image to a Docker index/registry.
 	Push(name string) error
@@ -58,6 +72,7 @@ type Driver interface {
 // ContainerConfig is the configuration used to start a container.
 type ContainerConfig struct {
 	Image      string
+	Platform   string
 	RunCommand []string
 	Volumes    map[string]string
 }
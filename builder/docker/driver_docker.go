@@ -2,6 +2,7 @@ package docker
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -83,6 +84,59 @@ func (d *DockerDriver) Export(id string, dst io.Writer) error {
 	return nil
 }
 
+func (d *DockerDriver) HealthProbe(id string, command []string) error {
+	var stderr bytes.Buffer
+	args := append([]string{"exec", id}, command...)
+	cmd := exec.Command("docker", args...)
+	cmd.Stderr = &stderr
+
+	log.Printf("Running health probe: %v", command)
+	if err := cmd.Run(); err != nil {
+		err = fmt.Errorf("Health probe failed: %s\nStderr: %s",
+			err, stderr.String())
+		return err
+	}
+
+	return nil
+}
+
+func (d *DockerDriver) HealthStatus(id string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", "inspect", "-f",
+		"{{if .State.Health}}{{.State.Health.Status}}{{end}}", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		err = fmt.Errorf("Error inspecting container: %s\nStderr: %s",
+			err, stderr.String())
+		return "", err
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (d *DockerDriver) ImageConfig(id string) (*ociContainerConfig, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", "inspect", "--type", "image", "-f",
+		"{{json .Config}}", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		err = fmt.Errorf("Error inspecting image: %s\nStderr: %s",
+			err, stderr.String())
+		return nil, err
+	}
+
+	var config ociContainerConfig
+	if err := json.Unmarshal(stdout.Bytes(), &config); err != nil {
+		return nil, fmt.Errorf("Error parsing the config of image %s: %s", id, err)
+	}
+
+	return &config, nil
+}
+
 func (d *DockerDriver) Import(path string, repo string) (string, error) {
 	var stdout bytes.Buffer
 	cmd := exec.Command("docker", "import", "-", repo)
@@ -154,8 +208,14 @@ func (d *DockerDriver) Logout(repo string) error {
 	return err
 }
 
-func (d *DockerDriver) Pull(image string) error {
-	cmd := exec.Command("docker", "pull", image)
+func (d *DockerDriver) Pull(image, platform string) error {
+	args := []string{"pull"}
+	if platform != "" {
+		args = append(args, "--platform", platform)
+	}
+	args = append(args, image)
+
+	cmd := exec.Command("docker", args...)
 	return runAndStream(cmd, d.Ui)
 }
 
@@ -193,6 +253,9 @@ func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 
 	// Args that we're going to pass to Docker
 	args := []string{"run"}
+	if config.Platform != "" {
+		args = append(args, "--platform", config.Platform)
+	}
 	for host, guest := range config.Volumes {
 		args = append(args, "-v", fmt.Sprintf("%s:%s", host, guest))
 	}
@@ -17,6 +17,21 @@ type MockDriver struct {
 	DeleteImageId     string
 	DeleteImageErr    error
 
+	HealthProbeCalled  bool
+	HealthProbeID      string
+	HealthProbeCommand []string
+	HealthProbeErr     error
+
+	HealthStatusCalled bool
+	HealthStatusID     string
+	HealthStatusResult string
+	HealthStatusErr    error
+
+	ImageConfigCalled bool
+	ImageConfigId     string
+	ImageConfigResult *ociContainerConfig
+	ImageConfigErr    error
+
 	ImportCalled bool
 	ImportPath   string
 	ImportRepo   string
@@ -61,6 +76,7 @@ type MockDriver struct {
 	ExportID     string
 	PullCalled   bool
 	PullImage    string
+	PullPlatform string
 	StartCalled  bool
 	StartConfig  *ContainerConfig
 	StopCalled   bool
@@ -97,6 +113,25 @@ func (d *MockDriver) Export(id string, dst io.Writer) error {
 	return d.ExportError
 }
 
+func (d *MockDriver) HealthProbe(id string, command []string) error {
+	d.HealthProbeCalled = true
+	d.HealthProbeID = id
+	d.HealthProbeCommand = command
+	return d.HealthProbeErr
+}
+
+func (d *MockDriver) HealthStatus(id string) (string, error) {
+	d.HealthStatusCalled = true
+	d.HealthStatusID = id
+	return d.HealthStatusResult, d.HealthStatusErr
+}
+
+func (d *MockDriver) ImageConfig(id string) (*ociContainerConfig, error) {
+	d.ImageConfigCalled = true
+	d.ImageConfigId = id
+	return d.ImageConfigResult, d.ImageConfigErr
+}
+
 func (d *MockDriver) Import(path, repo string) (string, error) {
 	d.ImportCalled = true
 	d.ImportPath = path
@@ -119,9 +154,10 @@ func (d *MockDriver) Logout(r string) error {
 	return d.LogoutErr
 }
 
-func (d *MockDriver) Pull(image string) error {
+func (d *MockDriver) Pull(image, platform string) error {
 	d.PullCalled = true
 	d.PullImage = image
+	d.PullPlatform = platform
 	return d.PullError
 }
 
@@ -0,0 +1,285 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	ociMediaTypeConfig   = "application/vnd.oci.image.config.v1+json"
+	ociMediaTypeLayer    = "application/vnd.oci.image.layer.v1.tar+gzip"
+	ociMediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+)
+
+type ociDescriptor struct {
+	MediaType string       `json:"mediaType"`
+	Digest    string       `json:"digest"`
+	Size      int64        `json:"size"`
+	Platform  *ociPlatform `json:"platform,omitempty"`
+}
+
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// ociContainerConfig is the configuration of the containers that are run
+// from an image, such as its environment and command.
+type ociContainerConfig struct {
+	User         string              `json:"User,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Volumes      map[string]struct{} `json:"Volumes,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	StopSignal   string              `json:"StopSignal,omitempty"`
+}
+
+type ociImageConfig struct {
+	ociPlatform
+	Config  *ociContainerConfig `json:"config,omitempty"`
+	Created string              `json:"created"`
+	RootFS  struct {
+		Type    string   `json:"type"`
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// ociEpoch is the time of everything that is written to a layout, so
+// that the same container always results in the same digests.
+var ociEpoch = time.Unix(0, 0).UTC()
+
+// ociLayout is a destination for the files of an OCI image layout.
+type ociLayout interface {
+	WriteFile(path string, r io.Reader, size int64) error
+	Close() error
+}
+
+// ociDirLayout writes an OCI image layout into a directory.
+type ociDirLayout struct {
+	root string
+}
+
+func (l *ociDirLayout) WriteFile(path string, r io.Reader, size int64) error {
+	path = filepath.Join(l.root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func (l *ociDirLayout) Close() error {
+	return nil
+}
+
+// ociTarLayout writes an OCI image layout as a single tar file.
+type ociTarLayout struct {
+	tw   *tar.Writer
+	dirs map[string]bool
+}
+
+func newOCITarLayout(w io.Writer) *ociTarLayout {
+	return &ociTarLayout{
+		tw:   tar.NewWriter(w),
+		dirs: make(map[string]bool),
+	}
+}
+
+func (l *ociTarLayout) WriteFile(path string, r io.Reader, size int64) error {
+	// Write the parent directories first so that extracting the
+	// tar results in a proper layout.
+	if dir := filepath.ToSlash(filepath.Dir(path)); dir != "." {
+		var parts []string
+		for _, part := range strings.Split(dir, "/") {
+			parts = append(parts, part)
+			name := strings.Join(parts, "/") + "/"
+			if l.dirs[name] {
+				continue
+			}
+
+			err := l.tw.WriteHeader(&tar.Header{
+				Name:     name,
+				Mode:     0755,
+				ModTime:  ociEpoch,
+				Typeflag: tar.TypeDir,
+			})
+			if err != nil {
+				return err
+			}
+			l.dirs[name] = true
+		}
+	}
+
+	err := l.tw.WriteHeader(&tar.Header{
+		Name:     path,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  ociEpoch,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(l.tw, r)
+	return err
+}
+
+func (l *ociTarLayout) Close() error {
+	return l.tw.Close()
+}
+
+// ociPlatformFor returns the OCI platform for a platform string of the
+// form os/arch[/variant]. If the platform is empty, a Linux image for the
+// architecture of this machine is assumed.
+func ociPlatformFor(platform string) ociPlatform {
+	if platform == "" {
+		return ociPlatform{OS: "linux", Architecture: runtime.GOARCH}
+	}
+
+	parts := strings.Split(platform, "/")
+	result := ociPlatform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) > 2 {
+		result.Variant = parts[2]
+	}
+
+	return result
+}
+
+// writeOCILayout reads a flat filesystem tar, such as the output of
+// `docker export`, and writes it to the layout as a single layer image
+// that is run with the container config, such as that of the base image.
+// The layout is written by Packer rather than saved by the Docker daemon,
+// so it can be pushed or loaded with any OCI compatible tooling.
+func writeOCILayout(l ociLayout, rootfs io.Reader, platform string, container *ociContainerConfig, tempDir string) error {
+	// The layer has to be buffered to a temporary file in tempDir, since
+	// its digest must be known before it can be written to the layout.
+	tf, err := ioutil.TempFile(tempDir, "packer-oci")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tf.Name())
+	defer tf.Close()
+
+	diffHash := sha256.New()
+	layerHash := sha256.New()
+	gzipW := gzip.NewWriter(io.MultiWriter(tf, layerHash))
+	if _, err := io.Copy(io.MultiWriter(gzipW, diffHash), rootfs); err != nil {
+		return err
+	}
+	if err := gzipW.Close(); err != nil {
+		return err
+	}
+
+	layer := ociDescriptor{
+		MediaType: ociMediaTypeLayer,
+		Digest:    fmt.Sprintf("sha256:%x", layerHash.Sum(nil)),
+	}
+	if layer.Size, err = tf.Seek(0, 1); err != nil {
+		return err
+	}
+	if _, err := tf.Seek(0, 0); err != nil {
+		return err
+	}
+	if err := writeOCIBlob(l, layer.Digest, tf, layer.Size); err != nil {
+		return err
+	}
+
+	p := ociPlatformFor(platform)
+	config := ociImageConfig{
+		ociPlatform: p,
+		Config:      container,
+		Created:     ociEpoch.Format(time.RFC3339),
+	}
+	config.RootFS.Type = "layers"
+	config.RootFS.DiffIDs = []string{
+		fmt.Sprintf("sha256:%x", diffHash.Sum(nil)),
+	}
+	configDesc, err := writeOCIJSONBlob(l, ociMediaTypeConfig, config)
+	if err != nil {
+		return err
+	}
+
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociMediaTypeManifest,
+		Config:        configDesc,
+		Layers:        []ociDescriptor{layer},
+	}
+	manifestDesc, err := writeOCIJSONBlob(l, ociMediaTypeManifest, manifest)
+	if err != nil {
+		return err
+	}
+	manifestDesc.Platform = &p
+
+	index, err := json.Marshal(&ociIndex{
+		SchemaVersion: 2,
+		Manifests:     []ociDescriptor{manifestDesc},
+	})
+	if err != nil {
+		return err
+	}
+
+	layout := []byte(`{"imageLayoutVersion":"1.0.0"}`)
+	err = l.WriteFile("oci-layout", bytes.NewReader(layout), int64(len(layout)))
+	if err != nil {
+		return err
+	}
+
+	return l.WriteFile("index.json", bytes.NewReader(index), int64(len(index)))
+}
+
+func writeOCIBlob(l ociLayout, digest string, r io.Reader, size int64) error {
+	path := "blobs/" + strings.Replace(digest, ":", "/", 1)
+	return l.WriteFile(path, r, size)
+}
+
+func writeOCIJSONBlob(l ociLayout, mediaType string, v interface{}) (ociDescriptor, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+
+	sum := sha256.Sum256(raw)
+	desc := ociDescriptor{
+		MediaType: mediaType,
+		Digest:    fmt.Sprintf("sha256:%x", sum),
+		Size:      int64(len(raw)),
+	}
+
+	return desc, writeOCIBlob(l, desc.Digest, bytes.NewReader(raw), desc.Size)
+}
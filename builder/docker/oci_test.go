@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOCIPlatformFor(t *testing.T) {
+	p := ociPlatformFor("linux/arm/v7")
+	if p.OS != "linux" || p.Architecture != "arm" || p.Variant != "v7" {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	p = ociPlatformFor("")
+	if p.OS != "linux" || p.Architecture == "" {
+		t.Fatalf("bad: %#v", p)
+	}
+}
+
+func TestWriteOCILayout(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	layout := &ociDirLayout{root: td}
+	rootfs := bytes.NewReader([]byte("data!"))
+	container := &ociContainerConfig{
+		Env: []string{"PATH=/usr/bin:/bin"},
+		Cmd: []string{"/bin/sh"},
+	}
+	if err := writeOCILayout(layout, rootfs, "linux/amd64", container, ""); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(td, "oci-layout")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var index ociIndex
+	readOCIJSON(t, filepath.Join(td, "index.json"), &index)
+	if len(index.Manifests) != 1 {
+		t.Fatalf("bad: %#v", index)
+	}
+	if index.Manifests[0].Platform.Architecture != "amd64" {
+		t.Fatalf("bad: %#v", index.Manifests[0].Platform)
+	}
+
+	var manifest ociManifest
+	readOCIJSON(t, ociBlobPath(t, td, index.Manifests[0]), &manifest)
+	if len(manifest.Layers) != 1 {
+		t.Fatalf("bad: %#v", manifest)
+	}
+	ociBlobPath(t, td, manifest.Layers[0])
+
+	var config ociImageConfig
+	readOCIJSON(t, ociBlobPath(t, td, manifest.Config), &config)
+	expected := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("data!")))
+	if len(config.RootFS.DiffIDs) != 1 || config.RootFS.DiffIDs[0] != expected {
+		t.Fatalf("bad: %#v", config.RootFS)
+	}
+	if !reflect.DeepEqual(config.Config, container) {
+		t.Fatalf("bad: %#v", config.Config)
+	}
+}
+
+func TestWriteOCILayout_reproducible(t *testing.T) {
+	var digests []string
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		layout := newOCITarLayout(&buf)
+		rootfs := bytes.NewReader([]byte("data!"))
+		if err := writeOCILayout(layout, rootfs, "linux/amd64", nil, ""); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := layout.Close(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		digests = append(digests, fmt.Sprintf("%x", sha256.Sum256(buf.Bytes())))
+		time.Sleep(time.Second)
+	}
+
+	if digests[0] != digests[1] {
+		t.Fatalf("bad: %#v", digests)
+	}
+}
+
+// ociBlobPath verifies that the blob for the descriptor exists with the
+// proper digest and size, and returns its path.
+func ociBlobPath(t *testing.T, root string, desc ociDescriptor) string {
+	path := filepath.Join(
+		root, "blobs", strings.Replace(desc.Digest, ":", string(filepath.Separator), 1))
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if int64(len(raw)) != desc.Size {
+		t.Fatalf("bad size for %s: %d", desc.Digest, len(raw))
+	}
+	if fmt.Sprintf("sha256:%x", sha256.Sum256(raw)) != desc.Digest {
+		t.Fatalf("bad digest: %s", desc.Digest)
+	}
+
+	return path
+}
+
+func readOCIJSON(t *testing.T, path string, v interface{}) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
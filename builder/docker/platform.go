@@ -0,0 +1,38 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// platformOSes are the container operating systems that the builder knows
+// how to communicate with.
+var platformOSes = []string{"linux", "windows"}
+
+// validatePlatform verifies that the platform is of the form os/arch with
+// an optional variant, such as "linux/amd64" or "linux/arm/v7", and that
+// the OS is one we can provision.
+func validatePlatform(platform string) error {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf(
+			"platform must be of the form os/arch[/variant]: %s", platform)
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return fmt.Errorf(
+				"platform must be of the form os/arch[/variant]: %s", platform)
+		}
+	}
+
+	for _, os := range platformOSes {
+		if parts[0] == os {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(
+		"platform OS must be one of %s: %s",
+		strings.Join(platformOSes, ", "), parts[0])
+}
@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	awscommon "github.com/mitchellh/packer/builder/amazon/common"
+)
+
+// ecrServerRe matches the hostname of an Amazon ECR registry, capturing
+// its region.
+var ecrServerRe = regexp.MustCompile(
+	`^(?:https://)?\d{12}\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?/?$`)
+
+// RegistryAuthConfig is the configuration of the helpers that log in to
+// the registries of cloud providers without credentials for the registry
+// itself, so that nothing has to be put in ~/.docker/config.json first.
+// The credentials are passed to `docker login` for login_server.
+type RegistryAuthConfig struct {
+	// EcrLogin gets a token for Amazon ECR with GetAuthorizationToken,
+	// using the AWS credentials below, the environment, the shared
+	// credentials file or the IAM role of the instance.
+	EcrLogin     bool   `mapstructure:"ecr_login"`
+	AwsAccessKey string `mapstructure:"aws_access_key"`
+	AwsSecretKey string `mapstructure:"aws_secret_key"`
+	AwsToken     string `mapstructure:"aws_token"`
+
+	// GcrLogin logs in to Google Container Registry or Artifact Registry
+	// with the key file of GOOGLE_APPLICATION_CREDENTIALS, or else an
+	// access token of gcloud.
+	GcrLogin bool `mapstructure:"gcr_login"`
+
+	// AcrLogin logs in to Azure Container Registry as a service principal,
+	// which defaults to AZURE_CLIENT_ID and AZURE_CLIENT_SECRET.
+	AcrLogin          bool   `mapstructure:"acr_login"`
+	AzureClientID     string `mapstructure:"azure_client_id"`
+	AzureClientSecret string `mapstructure:"azure_client_secret"`
+}
+
+// Enabled returns true if one of the helpers is used.
+func (c *RegistryAuthConfig) Enabled() bool {
+	return c.EcrLogin || c.GcrLogin || c.AcrLogin
+}
+
+// Prepare validates the configuration for logging in to server.
+func (c *RegistryAuthConfig) Prepare(server string) []error {
+	var errs []error
+
+	helpers := 0
+	for _, enabled := range []bool{c.EcrLogin, c.GcrLogin, c.AcrLogin} {
+		if enabled {
+			helpers++
+		}
+	}
+	if helpers > 1 {
+		errs = append(errs, fmt.Errorf(
+			"only one of ecr_login, gcr_login and acr_login can be set"))
+	}
+
+	if c.Enabled() && server == "" {
+		errs = append(errs, fmt.Errorf(
+			"login_server must be set to use ecr_login, gcr_login or acr_login"))
+	}
+
+	if c.EcrLogin && server != "" && !ecrServerRe.MatchString(server) {
+		errs = append(errs, fmt.Errorf(
+			"login_server must be an ECR registry to use ecr_login, such as "+
+				"123456789012.dkr.ecr.us-east-1.amazonaws.com: %s", server))
+	}
+
+	if !c.EcrLogin && (c.AwsAccessKey != "" || c.AwsSecretKey != "" || c.AwsToken != "") {
+		errs = append(errs, fmt.Errorf(
+			"aws_access_key, aws_secret_key and aws_token can only be used with ecr_login"))
+	}
+
+	if !c.AcrLogin && (c.AzureClientID != "" || c.AzureClientSecret != "") {
+		errs = append(errs, fmt.Errorf(
+			"azure_client_id and azure_client_secret can only be used with acr_login"))
+	}
+
+	return errs
+}
+
+// Credentials returns the username and password to log in to server with
+// the helper that is enabled.
+func (c *RegistryAuthConfig) Credentials(server string) (string, string, error) {
+	switch {
+	case c.EcrLogin:
+		return c.ecrCredentials(server)
+	case c.GcrLogin:
+		return gcrCredentials()
+	case c.AcrLogin:
+		return c.acrCredentials()
+	}
+
+	return "", "", fmt.Errorf("no registry login helper is enabled")
+}
+
+func (c *RegistryAuthConfig) ecrCredentials(server string) (string, string, error) {
+	match := ecrServerRe.FindStringSubmatch(server)
+	if match == nil {
+		return "", "", fmt.Errorf("Not an ECR registry: %s", server)
+	}
+
+	access := &awscommon.AccessConfig{
+		AccessKey:            c.AwsAccessKey,
+		SecretKey:            c.AwsSecretKey,
+		Token:                c.AwsToken,
+		RawRegion:            match[1],
+		SkipRegionValidation: true,
+	}
+	config, err := access.Config()
+	if err != nil {
+		return "", "", err
+	}
+
+	return awscommon.ECRAuthorizationToken(config)
+}
+
+func gcrCredentials() (string, string, error) {
+	// A service account key is used as the password as it is
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		key, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("Error reading GOOGLE_APPLICATION_CREDENTIALS: %s", err)
+		}
+
+		return "_json_key", string(key), nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("gcloud", "auth", "print-access-token")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("Error getting an access token from gcloud: %s\n%s",
+			err, strings.TrimSpace(stderr.String()))
+	}
+
+	return "oauth2accesstoken", strings.TrimSpace(stdout.String()), nil
+}
+
+func (c *RegistryAuthConfig) acrCredentials() (string, string, error) {
+	id := c.AzureClientID
+	if id == "" {
+		id = os.Getenv("AZURE_CLIENT_ID")
+	}
+
+	secret := c.AzureClientSecret
+	if secret == "" {
+		secret = os.Getenv("AZURE_CLIENT_SECRET")
+	}
+
+	if id == "" || secret == "" {
+		return "", "", fmt.Errorf(
+			"acr_login needs azure_client_id and azure_client_secret, " +
+				"or AZURE_CLIENT_ID and AZURE_CLIENT_SECRET")
+	}
+
+	return id, secret, nil
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestRegistryAuthConfigPrepare(t *testing.T) {
+	var c RegistryAuthConfig
+	if errs := c.Prepare(""); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	// A login server is required
+	c.GcrLogin = true
+	if errs := c.Prepare(""); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+	if errs := c.Prepare("gcr.io"); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	// Only one helper can be used
+	c.AcrLogin = true
+	if errs := c.Prepare("gcr.io"); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
+
+func TestRegistryAuthConfigPrepare_ecr(t *testing.T) {
+	c := RegistryAuthConfig{EcrLogin: true}
+	if errs := c.Prepare("gcr.io"); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+
+	good := []string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com",
+		"https://123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn",
+	}
+	for _, server := range good {
+		if errs := c.Prepare(server); len(errs) > 0 {
+			t.Fatalf("%s: %#v", server, errs)
+		}
+	}
+
+	// AWS credentials are only for ECR
+	c = RegistryAuthConfig{AwsAccessKey: "foo"}
+	if errs := c.Prepare(""); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
+
+func TestRegistryAuthConfigCredentials_acr(t *testing.T) {
+	c := RegistryAuthConfig{
+		AcrLogin:          true,
+		AzureClientID:     "id",
+		AzureClientSecret: "secret",
+	}
+
+	user, pass, err := c.Credentials("foo.azurecr.io")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if user != "id" || pass != "secret" {
+		t.Fatalf("bad: %s %s", user, pass)
+	}
+}
+
+func TestRegistryAuthConfigCredentials_gcrKeyFile(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Write([]byte(`{"type": "service_account"}`))
+	tf.Close()
+
+	old := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", tf.Name())
+	defer os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", old)
+
+	c := RegistryAuthConfig{GcrLogin: true}
+	user, pass, err := c.Credentials("gcr.io")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if user != "_json_key" || pass != `{"type": "service_account"}` {
+		t.Fatalf("bad: %s %s", user, pass)
+	}
+}
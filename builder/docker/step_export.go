@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/mitchellh/multistep"
 	"github.com/mitchellh/packer/packer"
+	"io"
 	"os"
 )
 
@@ -17,6 +18,10 @@ func (s *StepExport) Run(state multistep.StateBag) multistep.StepAction {
 	containerId := state.Get("container_id").(string)
 	ui := state.Get("ui").(packer.Ui)
 
+	if config.ExportFormat != ExportFormatTar {
+		return s.runOCI(state)
+	}
+
 	// Open the file that we're going to write to
 	f, err := os.Create(config.ExportPath)
 	if err != nil {
@@ -40,4 +45,83 @@ func (s *StepExport) Run(state multistep.StateBag) multistep.StepAction {
 	return multistep.ActionContinue
 }
 
+// runOCI exports the container and converts it into an OCI image layout
+// itself, rather than committing and saving it with the Docker daemon.
+func (s *StepExport) runOCI(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	containerId := state.Get("container_id").(string)
+	ui := state.Get("ui").(packer.Ui)
+
+	// `docker export` only has the filesystem of the container, so the
+	// config of the base image is read from Docker as well.
+	ui.Say(fmt.Sprintf("Reading the config of the base image: %s", config.Image))
+	container, err := driver.ImageConfig(config.Image)
+	if err != nil {
+		err := fmt.Errorf("Error reading the config of the base image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	var layout ociLayout
+	var f *os.File
+	if config.ExportFormat == ExportFormatOCIDir {
+		if err := os.MkdirAll(config.ExportPath, 0755); err != nil {
+			err := fmt.Errorf("Error creating output directory: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		layout = &ociDirLayout{root: config.ExportPath}
+	} else {
+		f, err = os.Create(config.ExportPath)
+		if err != nil {
+			err := fmt.Errorf("Error creating output file: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		layout = newOCITarLayout(f)
+	}
+
+	// Stream the export straight into the layout writer
+	r, w := io.Pipe()
+	exportErrCh := make(chan error, 1)
+	go func() {
+		err := driver.Export(containerId, w)
+		w.CloseWithError(err)
+		exportErrCh <- err
+	}()
+
+	ui.Say("Exporting the container as an OCI image layout")
+	err = writeOCILayout(layout, r, config.Platform, container, config.PackerWorkDir)
+	r.CloseWithError(io.ErrClosedPipe)
+
+	// If writing the layout failed, the export fails too since the pipe
+	// is closed, so only report the export error if it's the cause.
+	if exportErr := <-exportErrCh; err == nil {
+		err = exportErr
+	}
+	if err == nil {
+		err = layout.Close()
+	}
+	if f != nil {
+		f.Close()
+	}
+
+	if err != nil {
+		os.RemoveAll(config.ExportPath)
+
+		err := fmt.Errorf("Error exporting OCI image layout: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
 func (s *StepExport) Cleanup(state multistep.StateBag) {}
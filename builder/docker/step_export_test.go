@@ -1,11 +1,14 @@
 package docker
 
 import (
+	"archive/tar"
 	"bytes"
 	"errors"
 	"github.com/mitchellh/multistep"
+	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -61,6 +64,129 @@ func TestStepExport(t *testing.T) {
 	}
 }
 
+func TestStepExport_oci(t *testing.T) {
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	// Create a tempfile for our output path
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	config := state.Get("config").(*Config)
+	config.ExportPath = tf.Name()
+	config.ExportFormat = ExportFormatOCI
+	config.PackerWorkDir = td
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExportReader = bytes.NewReader([]byte("data!"))
+	driver.ImageConfigResult = &ociContainerConfig{Cmd: []string{"/bin/sh"}}
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.ImageConfigId != config.Image {
+		t.Fatalf("bad: %#v", driver.ImageConfigId)
+	}
+
+	// the layer is buffered in the working directory and removed
+	if files, _ := ioutil.ReadDir(td); len(files) != 0 {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	// verify the layout files are in the tar
+	f, err := os.Open(tf.Name())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	names := make(map[string]bool)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		names[hdr.Name] = true
+	}
+
+	for _, n := range []string{"oci-layout", "index.json", "blobs/sha256/"} {
+		if !names[n] {
+			t.Fatalf("missing %s: %#v", n, names)
+		}
+	}
+}
+
+func TestStepExport_ociLayoutError(t *testing.T) {
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	// Create a tempfile for our output path
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	// The layer can't be buffered in a working directory that is gone
+	config := state.Get("config").(*Config)
+	config.ExportPath = tf.Name()
+	config.ExportFormat = ExportFormatOCI
+	config.PackerWorkDir = tf.Name() + "-missing"
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExportReader = bytes.NewReader([]byte("data!"))
+	driver.ImageConfigResult = new(ociContainerConfig)
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// the error is that of the layout rather than of the closed pipe
+	err = state.Get("error").(error)
+	if !strings.Contains(err.Error(), config.PackerWorkDir) {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestStepExport_ociImageConfigError(t *testing.T) {
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ExportFormat = ExportFormatOCI
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageConfigErr = errors.New("foo")
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.ExportCalled {
+		t.Fatal("should not export")
+	}
+}
+
 func TestStepExport_error(t *testing.T) {
 	state := testStepExportState(t)
 	step := new(StepExport)
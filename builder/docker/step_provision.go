@@ -8,6 +8,7 @@ import (
 type StepProvision struct{}
 
 func (s *StepProvision) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
 	containerId := state.Get("container_id").(string)
 	driver := state.Get("driver").(Driver)
 	tempDir := state.Get("temp_dir").(string)
@@ -24,8 +25,9 @@ func (s *StepProvision) Run(state multistep.StateBag) multistep.StepAction {
 	comm := &Communicator{
 		ContainerId:  containerId,
 		HostDir:      tempDir,
-		ContainerDir: "/packer-files",
+		ContainerDir: config.ContainerDir(),
 		Version:      version,
+		Windows:      config.Windows(),
 	}
 
 	prov := common.StepProvision{Comm: comm}
@@ -21,13 +21,21 @@ func (s *StepPull) Run(state multistep.StateBag) multistep.StepAction {
 
 	ui.Say(fmt.Sprintf("Pulling Docker image: %s", config.Image))
 
-	if config.Login {
+	if config.Login || config.RegistryAuthConfig.Enabled() {
 		ui.Message("Logging in...")
-		err := driver.Login(
+		username, password, err := config.loginCredentials()
+		if err != nil {
+			err := fmt.Errorf("Error getting the credentials of the registry: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		err = driver.Login(
 			config.LoginServer,
 			config.LoginEmail,
-			config.LoginUsername,
-			config.LoginPassword)
+			username,
+			password)
 		if err != nil {
 			err := fmt.Errorf("Error logging in: %s", err)
 			state.Put("error", err)
@@ -43,7 +51,7 @@ func (s *StepPull) Run(state multistep.StateBag) multistep.StepAction {
 		}()
 	}
 
-	if err := driver.Pull(config.Image); err != nil {
+	if err := driver.Pull(config.Image, config.Platform); err != nil {
 		err := fmt.Errorf("Error pulling Docker image: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
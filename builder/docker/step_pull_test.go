@@ -30,6 +30,28 @@ func TestStepPull(t *testing.T) {
 	if driver.PullImage != config.Image {
 		t.Fatalf("bad: %#v", driver.PullImage)
 	}
+	if driver.PullPlatform != "" {
+		t.Fatalf("bad: %#v", driver.PullPlatform)
+	}
+}
+
+func TestStepPull_platform(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Platform = "linux/arm64"
+	driver := state.Get("driver").(*MockDriver)
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.PullPlatform != "linux/arm64" {
+		t.Fatalf("bad: %#v", driver.PullPlatform)
+	}
 }
 
 func TestStepPull_error(t *testing.T) {
@@ -80,6 +102,39 @@ func TestStepPull_login(t *testing.T) {
 	}
 }
 
+func TestStepPull_registryAuth(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(*MockDriver)
+
+	config.LoginServer = "foo.azurecr.io"
+	config.AcrLogin = true
+	config.AzureClientID = "id"
+	config.AzureClientSecret = "secret"
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we logged in with the credentials of the helper
+	if !driver.LoginCalled {
+		t.Fatal("should've logged in")
+	}
+	if driver.LoginRepo != "foo.azurecr.io" {
+		t.Fatalf("bad: %#v", driver.LoginRepo)
+	}
+	if driver.LoginUsername != "id" || driver.LoginPassword != "secret" {
+		t.Fatalf("bad: %#v %#v", driver.LoginUsername, driver.LoginPassword)
+	}
+	if !driver.LogoutCalled {
+		t.Fatal("should've logged out")
+	}
+}
+
 func TestStepPull_noPull(t *testing.T) {
 	state := testState(t)
 	step := new(StepPull)
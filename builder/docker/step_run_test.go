@@ -37,6 +37,9 @@ func TestStepRun(t *testing.T) {
 	if driver.StartConfig.Image != config.Image {
 		t.Fatalf("bad: %#v", driver.StartConfig.Image)
 	}
+	if driver.StartConfig.Platform != config.Platform {
+		t.Fatalf("bad: %#v", driver.StartConfig.Platform)
+	}
 
 	// verify the ID is saved
 	idRaw, ok := state.GetOk("container_id")
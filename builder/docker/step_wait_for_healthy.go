@@ -0,0 +1,101 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// StepWaitForHealthy waits until the container is healthy before it is
+// provisioned, so that images whose entrypoint needs to warm up, such as
+// databases, can be provisioned reliably. The container is healthy once
+// its HEALTHCHECK reports so if wait_for_healthy is set, and once
+// health_command succeeds if that is set.
+type StepWaitForHealthy struct {
+	// interval is how often the health of the container is checked. It
+	// defaults to two seconds.
+	interval time.Duration
+}
+
+func (s *StepWaitForHealthy) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	if !config.WaitForHealthy && config.HealthCommand == "" {
+		return multistep.ActionContinue
+	}
+
+	containerId := state.Get("container_id").(string)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	interval := s.interval
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+
+	ui.Say("Waiting for the container to become healthy...")
+	timeout := time.After(config.HealthTimeout)
+	for {
+		healthy, err := s.healthy(config, driver, containerId)
+		if err != nil {
+			err := fmt.Errorf("Error checking the health of the container: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if healthy {
+			break
+		}
+
+		select {
+		case <-timeout:
+			err := errors.New("Timeout waiting for the container to become healthy.")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		case <-time.After(interval):
+		}
+
+		if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Message("The container is healthy.")
+	return multistep.ActionContinue
+}
+
+func (s *StepWaitForHealthy) Cleanup(state multistep.StateBag) {}
+
+// healthy reports whether the container passes all of the configured
+// health checks.
+func (s *StepWaitForHealthy) healthy(config *Config, driver Driver, id string) (bool, error) {
+	if config.WaitForHealthy {
+		status, err := driver.HealthStatus(id)
+		if err != nil {
+			return false, err
+		}
+		if status == "" {
+			return false, errors.New(
+				"the image has no HEALTHCHECK. Use health_command to check the\n" +
+					"health of the container instead.")
+		}
+
+		log.Printf("Container health status: %s", status)
+		if status != "healthy" {
+			return false, nil
+		}
+	}
+
+	if command := config.HealthProbeCommand(); command != nil {
+		if err := driver.HealthProbe(id, command); err != nil {
+			log.Printf("%s", err)
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
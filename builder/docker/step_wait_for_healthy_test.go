@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/multistep"
+)
+
+func testStepWaitForHealthyState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	return state
+}
+
+func TestStepWaitForHealthy_impl(t *testing.T) {
+	var _ multistep.Step = new(StepWaitForHealthy)
+}
+
+func TestStepWaitForHealthy_disabled(t *testing.T) {
+	state := testStepWaitForHealthyState(t)
+	step := new(StepWaitForHealthy)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.HealthStatusCalled || driver.HealthProbeCalled {
+		t.Fatal("should not have checked health")
+	}
+}
+
+func TestStepWaitForHealthy_healthcheck(t *testing.T) {
+	state := testStepWaitForHealthyState(t)
+	step := new(StepWaitForHealthy)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.WaitForHealthy = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.HealthStatusResult = "healthy"
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.HealthStatusID != "foo" {
+		t.Fatalf("bad: %#v", driver.HealthStatusID)
+	}
+	if driver.HealthProbeCalled {
+		t.Fatal("should not have run a probe")
+	}
+}
+
+func TestStepWaitForHealthy_noHealthcheck(t *testing.T) {
+	state := testStepWaitForHealthyState(t)
+	step := new(StepWaitForHealthy)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.WaitForHealthy = true
+
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepWaitForHealthy_probe(t *testing.T) {
+	state := testStepWaitForHealthyState(t)
+	step := new(StepWaitForHealthy)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.HealthCommand = "pg_isready"
+	driver := state.Get("driver").(*MockDriver)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	expected := []string{"/bin/sh", "-c", "pg_isready"}
+	if !reflect.DeepEqual(driver.HealthProbeCommand, expected) {
+		t.Fatalf("bad: %#v", driver.HealthProbeCommand)
+	}
+}
+
+func TestStepWaitForHealthy_timeout(t *testing.T) {
+	state := testStepWaitForHealthyState(t)
+	step := &StepWaitForHealthy{interval: time.Millisecond}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.HealthCommand = "pg_isready"
+	config.HealthTimeout = 10 * time.Millisecond
+	driver := state.Get("driver").(*MockDriver)
+	driver.HealthProbeErr = errors.New("foo")
+
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
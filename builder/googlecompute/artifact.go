@@ -3,6 +3,8 @@ package googlecompute
 import (
 	"fmt"
 	"log"
+
+	"github.com/mitchellh/packer/common"
 )
 
 // Artifact represents a GCE image as the result of a Packer build.
@@ -39,5 +41,14 @@ func (a *Artifact) String() string {
 }
 
 func (a *Artifact) State(name string) interface{} {
-	return nil
+	switch name {
+	case common.ArtifactStateData:
+		return map[string]string{
+			"ImageName": a.imageName,
+		}
+	case common.ArtifactStateImageId:
+		return a.imageName
+	default:
+		return nil
+	}
 }
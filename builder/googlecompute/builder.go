@@ -9,6 +9,7 @@ import (
 	"github.com/mitchellh/multistep"
 	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/helper/communicator"
+	"github.com/mitchellh/packer/helper/config"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -55,12 +56,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Debug:        b.config.PackerDebug,
 			DebugKeyPath: fmt.Sprintf("gce_%s.pem", b.config.PackerBuildName),
 		},
+		new(StepCreateFirewallRule),
 		&StepCreateInstance{
 			Debug: b.config.PackerDebug,
 		},
 		&StepInstanceInfo{
 			Debug: b.config.PackerDebug,
 		},
+		&StepCreateWindowsPassword{
+			Debug: b.config.PackerDebug,
+		},
 		&communicator.StepConnect{
 			Config:    &b.config.Comm,
 			Host:      commHost,
@@ -68,6 +73,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		new(common.StepProvision),
 		new(StepTeardownInstance),
+		new(StepDeleteExistingImage),
 		new(StepCreateImage),
 	}
 
@@ -78,7 +84,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 	b.runner.Run(state)
 
@@ -98,6 +106,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	return artifact, nil
 }
 
+// ConfigSchema returns the JSON Schema of the configuration.
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 // Cancel.
 func (b *Builder) Cancel() {
 	if b.runner != nil {
@@ -3,6 +3,8 @@ package googlecompute
 import (
 	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/mitchellh/packer/common"
@@ -23,23 +25,32 @@ type Config struct {
 	AccountFile string `mapstructure:"account_file"`
 	ProjectId   string `mapstructure:"project_id"`
 
-	DiskName             string            `mapstructure:"disk_name"`
-	DiskSizeGb           int64             `mapstructure:"disk_size"`
-	ImageName            string            `mapstructure:"image_name"`
-	ImageDescription     string            `mapstructure:"image_description"`
-	InstanceName         string            `mapstructure:"instance_name"`
-	MachineType          string            `mapstructure:"machine_type"`
-	Metadata             map[string]string `mapstructure:"metadata"`
-	Network              string            `mapstructure:"network"`
-	SourceImage          string            `mapstructure:"source_image"`
-	SourceImageProjectId string            `mapstructure:"source_image_project_id"`
-	RawStateTimeout      string            `mapstructure:"state_timeout"`
-	Tags                 []string          `mapstructure:"tags"`
-	Zone                 string            `mapstructure:"zone"`
+	AcceleratorCount          int64             `mapstructure:"accelerator_count"`
+	AcceleratorType           string            `mapstructure:"accelerator_type"`
+	DiskName                  string            `mapstructure:"disk_name"`
+	DiskSizeGb                int64             `mapstructure:"disk_size"`
+	EnableConfidentialCompute bool              `mapstructure:"enable_confidential_compute"`
+	EnableIntegrityMonitoring bool              `mapstructure:"enable_integrity_monitoring"`
+	EnableSecureBoot          bool              `mapstructure:"enable_secure_boot"`
+	EnableVtpm                bool              `mapstructure:"enable_vtpm"`
+	FirewallSourceRanges      []string          `mapstructure:"firewall_source_ranges"`
+	ForceDelete               bool              `mapstructure:"force_delete"`
+	ImageName                 string            `mapstructure:"image_name"`
+	ImageDescription          string            `mapstructure:"image_description"`
+	InstanceName              string            `mapstructure:"instance_name"`
+	MachineType               string            `mapstructure:"machine_type"`
+	Metadata                  map[string]string `mapstructure:"metadata"`
+	Network                   string            `mapstructure:"network"`
+	OnHostMaintenance         string            `mapstructure:"on_host_maintenance"`
+	SourceImage               string            `mapstructure:"source_image"`
+	SourceImageProjectId      string            `mapstructure:"source_image_project_id"`
+	StateTimeout              time.Duration     `mapstructure:"state_timeout"`
+	Tags                      []string          `mapstructure:"tags"`
+	WindowsPasswordTimeout    time.Duration     `mapstructure:"windows_password_timeout"`
+	Zone                      string            `mapstructure:"zone"`
 
 	account         accountFile
 	privateKeyBytes []byte
-	stateTimeout    time.Duration
 	ctx             *interpolate.Context
 }
 
@@ -86,15 +97,53 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.MachineType = "n1-standard-1"
 	}
 
-	if c.RawStateTimeout == "" {
-		c.RawStateTimeout = "5m"
+	// Instances with GPUs or confidential computing can't be live
+	// migrated, so they must be stopped for host maintenance.
+	if c.OnHostMaintenance == "" {
+		c.OnHostMaintenance = "MIGRATE"
+		if c.AcceleratorCount > 0 || c.EnableConfidentialCompute {
+			c.OnHostMaintenance = "TERMINATE"
+		}
+	}
+
+	if c.StateTimeout == 0 {
+		c.StateTimeout = 5 * time.Minute
+	}
+
+	if c.WindowsPasswordTimeout == 0 {
+		c.WindowsPasswordTimeout = 20 * time.Minute
 	}
 
 	if c.Comm.SSHUsername == "" {
 		c.Comm.SSHUsername = "root"
 	}
 
+	if c.Comm.Type == "winrm" && c.Comm.WinRMUser == "" {
+		c.Comm.WinRMUser = "packer_user"
+	}
+
+	// The startup script sets up WinRM over HTTPS with a certificate that
+	// is created on the instance, so it can't be verified.
+	if c.Comm.Type == "winrm" {
+		c.Comm.WinRMUseSSL = true
+		c.Comm.WinRMInsecure = true
+	}
+
+	if len(c.FirewallSourceRanges) == 0 {
+		c.FirewallSourceRanges = []string{"0.0.0.0/0"}
+	}
+
 	var errs *packer.MultiError
+	if es := c.Comm.Prepare(c.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	for _, r := range c.FirewallSourceRanges {
+		if _, _, err := net.ParseCIDR(r); err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("firewall_source_ranges: %s", err))
+		}
+	}
 
 	// Process required parameters.
 	if c.ProjectId == "" {
@@ -112,12 +161,39 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 			errs, errors.New("a zone must be specified"))
 	}
 
-	stateTimeout, err := time.ParseDuration(c.RawStateTimeout)
-	if err != nil {
+	if (c.AcceleratorCount > 0) != (c.AcceleratorType != "") {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("accelerator_type and accelerator_count must be set together"))
+	}
+
+	if c.AcceleratorCount < 0 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("accelerator_count must not be negative"))
+	}
+
+	switch c.OnHostMaintenance {
+	case "MIGRATE":
+		if c.AcceleratorCount > 0 || c.EnableConfidentialCompute {
+			errs = packer.MultiErrorAppend(errs, errors.New(
+				"on_host_maintenance must be TERMINATE with accelerators or confidential computing"))
+		}
+	case "TERMINATE":
+	default:
 		errs = packer.MultiErrorAppend(
-			errs, fmt.Errorf("Failed parsing state_timeout: %s", err))
+			errs, errors.New("on_host_maintenance must be MIGRATE or TERMINATE"))
+	}
+
+	// Integrity monitoring measures the boot against the vTPM
+	if c.EnableIntegrityMonitoring && !c.EnableVtpm {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("enable_integrity_monitoring requires enable_vtpm"))
+	}
+
+	if c.EnableConfidentialCompute && !strings.HasPrefix(c.MachineType, "n2d-") &&
+		!strings.HasPrefix(c.MachineType, "c2d-") {
+		errs = packer.MultiErrorAppend(errs, errors.New(
+			"enable_confidential_compute requires an AMD machine_type, such as n2d-standard-2"))
 	}
-	c.stateTimeout = stateTimeout
 
 	if c.AccountFile != "" {
 		if err := loadJSON(&c.account, c.AccountFile); err != nil {
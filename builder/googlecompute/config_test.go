@@ -1,8 +1,11 @@
 package googlecompute
 
 import (
+	"fmt"
 	"io/ioutil"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func testConfig(t *testing.T) map[string]interface{} {
@@ -56,6 +59,17 @@ func TestConfigPrepare(t *testing.T) {
 			true,
 		},
 
+		{
+			"firewall_source_ranges",
+			[]string{"203.0.113.0/24"},
+			false,
+		},
+		{
+			"firewall_source_ranges",
+			[]string{"203.0.113.1"},
+			true,
+		},
+
 		{
 			"private_key_file",
 			"/tmp/i/should/not/exist",
@@ -116,6 +130,17 @@ func TestConfigPrepare(t *testing.T) {
 			"5s",
 			false,
 		},
+
+		{
+			"windows_password_timeout",
+			"SO BAD",
+			true,
+		},
+		{
+			"windows_password_timeout",
+			"5s",
+			false,
+		},
 	}
 
 	for _, tc := range cases {
@@ -137,6 +162,91 @@ func TestConfigPrepare(t *testing.T) {
 	}
 }
 
+func TestConfigPrepare_instanceFeatures(t *testing.T) {
+	c := testConfigStruct(t)
+	if c.OnHostMaintenance != "MIGRATE" {
+		t.Fatalf("bad: %s", c.OnHostMaintenance)
+	}
+
+	// GPUs can't be live migrated
+	raw := testConfig(t)
+	raw["accelerator_type"] = "nvidia-tesla-t4"
+	raw["accelerator_count"] = 1
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.OnHostMaintenance != "TERMINATE" {
+		t.Fatalf("bad: %s", c.OnHostMaintenance)
+	}
+
+	cases := []map[string]interface{}{
+		{"accelerator_type": "nvidia-tesla-t4"},
+		{"accelerator_count": 1},
+		{"accelerator_type": "nvidia-tesla-t4", "accelerator_count": 1, "on_host_maintenance": "MIGRATE"},
+		{"on_host_maintenance": "RESTART"},
+		{"enable_integrity_monitoring": true},
+		{"enable_confidential_compute": true},
+	}
+	for _, tc := range cases {
+		raw := testConfig(t)
+		for k, v := range tc {
+			raw[k] = v
+		}
+
+		_, warns, errs := NewConfig(raw)
+		testConfigErr(t, warns, errs, fmt.Sprintf("%#v", tc))
+	}
+
+	raw = testConfig(t)
+	raw["enable_confidential_compute"] = true
+	raw["enable_secure_boot"] = true
+	raw["enable_vtpm"] = true
+	raw["enable_integrity_monitoring"] = true
+	raw["machine_type"] = "n2d-standard-2"
+	c, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.OnHostMaintenance != "TERMINATE" {
+		t.Fatalf("bad: %s", c.OnHostMaintenance)
+	}
+}
+
+func TestConfigPrepare_winrm(t *testing.T) {
+	raw := testConfig(t)
+	raw["communicator"] = "winrm"
+
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+
+	if c.Comm.WinRMUser != "packer_user" {
+		t.Fatalf("bad: %s", c.Comm.WinRMUser)
+	}
+	if c.Comm.WinRMPort != 5985 {
+		t.Fatalf("bad: %d", c.Comm.WinRMPort)
+	}
+	if c.WindowsPasswordTimeout != 20*time.Minute {
+		t.Fatalf("bad: %s", c.WindowsPasswordTimeout)
+	}
+
+	metadata := c.getInstanceMetadata("key")
+	if _, ok := metadata["windows-startup-script-cmd"]; !ok {
+		t.Fatalf("should have startup script: %#v", metadata)
+	}
+
+	tags := c.getInstanceTags()
+	if len(tags) != 1 || tags[0] != c.InstanceName {
+		t.Fatalf("bad: %#v", tags)
+	}
+
+	// A startup script in the template is kept
+	raw["metadata"] = map[string]string{"windows-startup-script-cmd": "foo"}
+	c, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+
+	metadata = c.getInstanceMetadata("key")
+	if metadata["windows-startup-script-cmd"] != "foo" {
+		t.Fatalf("bad: %#v", metadata)
+	}
+}
+
 func testAccountFile(t *testing.T) string {
 	tf, err := ioutil.TempFile("", "packer")
 	if err != nil {
@@ -154,3 +264,21 @@ func testAccountFile(t *testing.T) string {
 // This is just some dummy data that doesn't actually work (it was revoked
 // a long time ago).
 const testAccountContent = `{}`
+
+func TestConfigWinRM(t *testing.T) {
+	raw := testConfig(t)
+	raw["communicator"] = "winrm"
+
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+
+	if !c.Comm.WinRMUseSSL || !c.Comm.WinRMInsecure {
+		t.Fatalf("should use HTTPS: %#v", c.Comm)
+	}
+	if c.Comm.WinRMPort != 5986 {
+		t.Fatalf("bad: %d", c.Comm.WinRMPort)
+	}
+	if !reflect.DeepEqual(c.FirewallSourceRanges, []string{"0.0.0.0/0"}) {
+		t.Fatalf("bad: %#v", c.FirewallSourceRanges)
+	}
+}
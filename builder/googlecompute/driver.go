@@ -1,5 +1,10 @@
 package googlecompute
 
+import (
+	"crypto/rsa"
+	"time"
+)
+
 // Driver is the interface that has to be implemented to communicate
 // with GCE. The Driver interface exists mostly to allow a mock implementation
 // to be used to test the steps.
@@ -8,10 +13,23 @@ type Driver interface {
 	// occurs calling the API, this method returns false.
 	ImageExists(name string) bool
 
+	// CreateFirewallRule creates a firewall rule on the given network that
+	// allows inbound TCP traffic from the source ranges on the ports to
+	// instances with the tag.
+	CreateFirewallRule(name, network, tag string, ports, sourceRanges []string) (<-chan error, error)
+
 	// CreateImage creates an image from the given disk in Google Compute
 	// Engine.
 	CreateImage(name, description, zone, disk string) <-chan error
 
+	// CreateOrResetWindowsPassword creates or resets the password of a
+	// user on a Windows instance. The password is set on the config once
+	// the returned channel reports success.
+	CreateOrResetWindowsPassword(zone, name string, c *WindowsPasswordConfig) (<-chan error, error)
+
+	// DeleteFirewallRule deletes the firewall rule with the given name.
+	DeleteFirewallRule(name string) (<-chan error, error)
+
 	// DeleteImage deletes the image with the given name.
 	DeleteImage(name string) <-chan error
 
@@ -37,13 +55,43 @@ type Image struct {
 }
 
 type InstanceConfig struct {
-	Description string
-	DiskSizeGb  int64
-	Image       Image
-	MachineType string
-	Metadata    map[string]string
-	Name        string
-	Network     string
-	Tags        []string
-	Zone        string
+	AcceleratorCount          int64
+	AcceleratorType           string
+	Description               string
+	DiskSizeGb                int64
+	EnableConfidentialCompute bool
+	EnableIntegrityMonitoring bool
+	EnableSecureBoot          bool
+	EnableVtpm                bool
+	Image                     Image
+	MachineType               string
+	Metadata                  map[string]string
+	Name                      string
+	Network                   string
+	OnHostMaintenance         string
+	Tags                      []string
+	Zone                      string
+}
+
+// WindowsPasswordConfig is the request for a new Windows password that is
+// written to the "windows-keys" metadata of an instance. The password is
+// encrypted with the public half of Key and returned on the serial port.
+type WindowsPasswordConfig struct {
+	Key      *rsa.PrivateKey `json:"-"`
+	Password string          `json:"-"`
+	UserName string          `json:"userName"`
+	Modulus  string          `json:"modulus"`
+	Exponent string          `json:"exponent"`
+	Email    string          `json:"email"`
+	ExpireOn time.Time       `json:"expireOn"`
+}
+
+// windowsPasswordResponse is the reply of the agent on the instance.
+type windowsPasswordResponse struct {
+	UserName          string `json:"userName"`
+	PasswordFound     bool   `json:"passwordFound"`
+	EncryptedPassword string `json:"encryptedPassword"`
+	Modulus           string `json:"modulus"`
+	Exponent          string `json:"exponent"`
+	ErrorMessage      string `json:"errorMessage"`
 }
@@ -1,10 +1,18 @@
 package googlecompute
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/mitchellh/packer/packer"
@@ -94,6 +102,36 @@ func (d *driverGCE) ImageExists(name string) bool {
 	return err == nil
 }
 
+func (d *driverGCE) CreateFirewallRule(name, network, tag string, ports, sourceRanges []string) (<-chan error, error) {
+	n, err := d.service.Networks.Get(d.projectId, network).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	firewall := &compute.Firewall{
+		Description: "Firewall rule created by Packer",
+		Name:        name,
+		Network:     n.SelfLink,
+		Allowed: []*compute.FirewallAllowed{
+			&compute.FirewallAllowed{
+				IPProtocol: "tcp",
+				Ports:      ports,
+			},
+		},
+		SourceRanges: sourceRanges,
+		TargetTags:   []string{tag},
+	}
+
+	op, err := d.service.Firewalls.Insert(d.projectId, firewall).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 1)
+	go waitForState(errCh, "DONE", d.refreshGlobalOp(op))
+	return errCh, nil
+}
+
 func (d *driverGCE) CreateImage(name, description, zone, disk string) <-chan error {
 	image := &compute.Image{
 		Description: description,
@@ -113,6 +151,71 @@ func (d *driverGCE) CreateImage(name, description, zone, disk string) <-chan err
 	return errCh
 }
 
+func (d *driverGCE) CreateOrResetWindowsPassword(zone, name string, c *WindowsPasswordConfig) (<-chan error, error) {
+	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	c.Modulus = base64.StdEncoding.EncodeToString(c.Key.N.Bytes())
+	c.Exponent = base64.StdEncoding.EncodeToString(big.NewInt(int64(c.Key.E)).Bytes())
+	request, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// The agent on the instance watches the "windows-keys" metadata key
+	// for new requests, one JSON object per line.
+	metadata := instance.Metadata
+	if metadata == nil {
+		metadata = &compute.Metadata{}
+	}
+	found := false
+	for _, item := range metadata.Items {
+		if item.Key == "windows-keys" {
+			item.Value = fmt.Sprintf("%s\n%s", item.Value, request)
+			found = true
+			break
+		}
+	}
+	if !found {
+		metadata.Items = append(metadata.Items, &compute.MetadataItems{
+			Key:   "windows-keys",
+			Value: string(request),
+		})
+	}
+
+	op, err := d.service.Instances.SetMetadata(d.projectId, zone, name, metadata).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		opCh := make(chan error, 1)
+		waitForState(opCh, "DONE", d.refreshZoneOp(zone, op))
+		if err := <-opCh; err != nil {
+			errCh <- err
+			return
+		}
+
+		errCh <- d.waitForWindowsPassword(zone, name, c)
+	}()
+
+	return errCh, nil
+}
+
+func (d *driverGCE) DeleteFirewallRule(name string) (<-chan error, error) {
+	op, err := d.service.Firewalls.Delete(d.projectId, name).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 1)
+	go waitForState(errCh, "DONE", d.refreshGlobalOp(op))
+	return errCh, nil
+}
+
 func (d *driverGCE) DeleteImage(name string) <-chan error {
 	errCh := make(chan error, 1)
 	op, err := d.service.Images.Delete(d.projectId, name).Do()
@@ -250,11 +353,38 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 				},
 			},
 		},
+		Scheduling: &compute.Scheduling{
+			OnHostMaintenance: c.OnHostMaintenance,
+		},
 		Tags: &compute.Tags{
 			Items: c.Tags,
 		},
 	}
 
+	if c.AcceleratorCount > 0 {
+		instance.GuestAccelerators = []*compute.AcceleratorConfig{
+			&compute.AcceleratorConfig{
+				AcceleratorCount: c.AcceleratorCount,
+				AcceleratorType: fmt.Sprintf("projects/%s/zones/%s/acceleratorTypes/%s",
+					d.projectId, zone.Name, c.AcceleratorType),
+			},
+		}
+	}
+
+	if c.EnableSecureBoot || c.EnableVtpm || c.EnableIntegrityMonitoring {
+		instance.ShieldedInstanceConfig = &compute.ShieldedInstanceConfig{
+			EnableIntegrityMonitoring: c.EnableIntegrityMonitoring,
+			EnableSecureBoot:          c.EnableSecureBoot,
+			EnableVtpm:                c.EnableVtpm,
+		}
+	}
+
+	if c.EnableConfidentialCompute {
+		instance.ConfidentialInstanceConfig = &compute.ConfidentialInstanceConfig{
+			EnableConfidentialCompute: true,
+		}
+	}
+
 	d.ui.Message("Requesting instance creation...")
 	op, err := d.service.Instances.Insert(d.projectId, zone.Name, &instance).Do()
 	if err != nil {
@@ -272,6 +402,45 @@ func (d *driverGCE) WaitForInstance(state, zone, name string) <-chan error {
 	return errCh
 }
 
+// waitForWindowsPassword polls the serial port that the agent on the
+// instance writes its replies to until it answers the request in c.
+func (d *driverGCE) waitForWindowsPassword(zone, name string, c *WindowsPasswordConfig) error {
+	for {
+		out, err := d.service.Instances.GetSerialPortOutput(
+			d.projectId, zone, name).Port(4).Do()
+		if err != nil {
+			return err
+		}
+
+		for _, line := range strings.Split(out.Contents, "\n") {
+			var resp windowsPasswordResponse
+			if err := json.Unmarshal([]byte(line), &resp); err != nil {
+				continue
+			}
+			if resp.Modulus != c.Modulus {
+				continue
+			}
+
+			if resp.ErrorMessage != "" {
+				return errors.New(resp.ErrorMessage)
+			}
+			if resp.EncryptedPassword == "" {
+				continue
+			}
+
+			password, err := decryptWindowsPassword(resp.EncryptedPassword, c.Key)
+			if err != nil {
+				return fmt.Errorf("Error decrypting password: %s", err)
+			}
+
+			c.Password = password
+			return nil
+		}
+
+		time.Sleep(2 * time.Second)
+	}
+}
+
 func (d *driverGCE) getImage(img Image) (image *compute.Image, err error) {
 	projects := []string{img.ProjectId, "centos-cloud", "coreos-cloud", "debian-cloud", "google-containers", "opensuse-cloud", "rhel-cloud", "suse-cloud", "ubuntu-os-cloud", "windows-cloud"}
 	for _, project := range projects {
@@ -359,3 +528,17 @@ func waitForState(errCh chan<- error, target string, refresh stateRefreshFunc) {
 		time.Sleep(2 * time.Second)
 	}
 }
+
+func decryptWindowsPassword(encrypted string, key *rsa.PrivateKey) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, key, raw, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
@@ -6,12 +6,31 @@ type DriverMock struct {
 	ImageExistsName   string
 	ImageExistsResult bool
 
+	CreateFirewallRuleName         string
+	CreateFirewallRuleNetwork      string
+	CreateFirewallRuleTag          string
+	CreateFirewallRulePorts        []string
+	CreateFirewallRuleSourceRanges []string
+	CreateFirewallRuleErrCh        <-chan error
+	CreateFirewallRuleErr          error
+
 	CreateImageName  string
 	CreateImageDesc  string
 	CreateImageZone  string
 	CreateImageDisk  string
 	CreateImageErrCh <-chan error
 
+	CreateOrResetWindowsPasswordZone     string
+	CreateOrResetWindowsPasswordInstance string
+	CreateOrResetWindowsPasswordConfig   *WindowsPasswordConfig
+	CreateOrResetWindowsPasswordResult   string
+	CreateOrResetWindowsPasswordErrCh    <-chan error
+	CreateOrResetWindowsPasswordErr      error
+
+	DeleteFirewallRuleName  string
+	DeleteFirewallRuleErrCh <-chan error
+	DeleteFirewallRuleErr   error
+
 	DeleteImageName  string
 	DeleteImageErrCh <-chan error
 
@@ -45,6 +64,23 @@ func (d *DriverMock) ImageExists(name string) bool {
 	return d.ImageExistsResult
 }
 
+func (d *DriverMock) CreateFirewallRule(name, network, tag string, ports, sourceRanges []string) (<-chan error, error) {
+	d.CreateFirewallRuleName = name
+	d.CreateFirewallRuleNetwork = network
+	d.CreateFirewallRuleTag = tag
+	d.CreateFirewallRulePorts = ports
+	d.CreateFirewallRuleSourceRanges = sourceRanges
+
+	resultCh := d.CreateFirewallRuleErrCh
+	if resultCh == nil {
+		ch := make(chan error)
+		close(ch)
+		resultCh = ch
+	}
+
+	return resultCh, d.CreateFirewallRuleErr
+}
+
 func (d *DriverMock) CreateImage(name, description, zone, disk string) <-chan error {
 	d.CreateImageName = name
 	d.CreateImageDesc = description
@@ -61,6 +97,36 @@ func (d *DriverMock) CreateImage(name, description, zone, disk string) <-chan er
 	return resultCh
 }
 
+func (d *DriverMock) CreateOrResetWindowsPassword(zone, name string, c *WindowsPasswordConfig) (<-chan error, error) {
+	d.CreateOrResetWindowsPasswordZone = zone
+	d.CreateOrResetWindowsPasswordInstance = name
+	d.CreateOrResetWindowsPasswordConfig = c
+
+	c.Password = d.CreateOrResetWindowsPasswordResult
+
+	resultCh := d.CreateOrResetWindowsPasswordErrCh
+	if resultCh == nil {
+		ch := make(chan error)
+		close(ch)
+		resultCh = ch
+	}
+
+	return resultCh, d.CreateOrResetWindowsPasswordErr
+}
+
+func (d *DriverMock) DeleteFirewallRule(name string) (<-chan error, error) {
+	d.DeleteFirewallRuleName = name
+
+	resultCh := d.DeleteFirewallRuleErrCh
+	if resultCh == nil {
+		ch := make(chan error)
+		close(ch)
+		resultCh = ch
+	}
+
+	return resultCh, d.DeleteFirewallRuleErr
+}
+
 func (d *DriverMock) DeleteImage(name string) <-chan error {
 	d.DeleteImageName = name
 
@@ -8,7 +8,9 @@ import (
 )
 
 // StepCheckExistingImage represents a Packer build step that checks if the
-// target image already exists, and aborts immediately if so.
+// target image already exists, and aborts immediately if so. If force_delete
+// is set, the check is skipped and StepDeleteExistingImage deletes the
+// image instead.
 type StepCheckExistingImage int
 
 // Run executes the Packer build step that checks if the image already exists.
@@ -17,6 +19,10 @@ func (s *StepCheckExistingImage) Run(state multistep.StateBag) multistep.StepAct
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 
+	if config.ForceDelete {
+		return multistep.ActionContinue
+	}
+
 	ui.Say("Checking image does not exist...")
 	exists := driver.ImageExists(config.ImageName)
 	if exists {
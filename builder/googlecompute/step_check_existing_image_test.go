@@ -30,3 +30,24 @@ func TestStepCheckExistingImage(t *testing.T) {
 		t.Fatalf("bad: %#v", driver.ImageExistsName)
 	}
 }
+
+func TestStepCheckExistingImage_forceDelete(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckExistingImage)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ForceDelete = true
+	driver := state.Get("driver").(*DriverMock)
+	driver.ImageExistsResult = true
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// The image is only deleted once the new one is about to be created
+	if driver.DeleteImageName != "" {
+		t.Fatalf("bad: %#v", driver.DeleteImageName)
+	}
+}
@@ -0,0 +1,81 @@
+package googlecompute
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// StepCreateFirewallRule represents a Packer build step that opens the
+// WinRM port to the instance on the GCE network.
+type StepCreateFirewallRule struct {
+	name string
+}
+
+// Run executes the Packer build step that creates the firewall rule.
+func (s *StepCreateFirewallRule) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.Comm.Type != "winrm" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Creating firewall rule for WinRM...")
+	name := fmt.Sprintf("%s-winrm", config.InstanceName)
+	errCh, err := driver.CreateFirewallRule(
+		name, config.Network, config.InstanceName,
+		[]string{strconv.Itoa(config.Comm.WinRMPort)}, config.FirewallSourceRanges)
+	if err == nil {
+		select {
+		case err = <-errCh:
+		case <-time.After(config.StateTimeout):
+			err = errors.New("time out while waiting for firewall rule to create")
+		}
+	}
+
+	if err != nil {
+		err := fmt.Errorf("Error creating firewall rule: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	s.name = name
+	return multistep.ActionContinue
+}
+
+// Cleanup deletes the firewall rule.
+func (s *StepCreateFirewallRule) Cleanup(state multistep.StateBag) {
+	if s.name == "" {
+		return
+	}
+
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deleting firewall rule...")
+	errCh, err := driver.DeleteFirewallRule(s.name)
+	if err == nil {
+		select {
+		case err = <-errCh:
+		case <-time.After(config.StateTimeout):
+			err = errors.New("time out while waiting for firewall rule to delete")
+		}
+	}
+
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error deleting firewall rule. Please delete it manually.\n\n"+
+				"Name: %s\n"+
+				"Error: %s", s.name, err))
+	}
+
+	s.name = ""
+}
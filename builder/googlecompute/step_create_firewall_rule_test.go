@@ -0,0 +1,96 @@
+package googlecompute
+
+import (
+	"errors"
+	"github.com/mitchellh/multistep"
+	"reflect"
+	"testing"
+)
+
+func TestStepCreateFirewallRule_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCreateFirewallRule)
+}
+
+func TestStepCreateFirewallRule(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateFirewallRule)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Comm.Type = "winrm"
+	config.Comm.WinRMPort = 5985
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.CreateFirewallRuleNetwork != config.Network {
+		t.Fatalf("bad network: %#v", driver.CreateFirewallRuleNetwork)
+	}
+	if driver.CreateFirewallRuleTag != config.InstanceName {
+		t.Fatalf("bad tag: %#v", driver.CreateFirewallRuleTag)
+	}
+	ports := driver.CreateFirewallRulePorts
+	if len(ports) != 1 || ports[0] != "5985" {
+		t.Fatalf("bad ports: %#v", ports)
+	}
+	if !reflect.DeepEqual(driver.CreateFirewallRuleSourceRanges, config.FirewallSourceRanges) {
+		t.Fatalf("bad source ranges: %#v", driver.CreateFirewallRuleSourceRanges)
+	}
+
+	// cleanup
+	step.Cleanup(state)
+
+	if driver.DeleteFirewallRuleName != driver.CreateFirewallRuleName {
+		t.Fatal("should've deleted firewall rule")
+	}
+}
+
+func TestStepCreateFirewallRule_ssh(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateFirewallRule)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.CreateFirewallRuleName != "" {
+		t.Fatal("should NOT have created firewall rule")
+	}
+}
+
+func TestStepCreateFirewallRule_error(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateFirewallRule)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Comm.Type = "winrm"
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.CreateFirewallRuleErr = errors.New("error")
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Verify state
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+
+	// cleanup
+	step.Cleanup(state)
+
+	if driver.DeleteFirewallRuleName != "" {
+		t.Fatal("should NOT have deleted firewall rule")
+	}
+}
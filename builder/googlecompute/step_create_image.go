@@ -27,7 +27,7 @@ func (s *StepCreateImage) Run(state multistep.StateBag) multistep.StepAction {
 	var err error
 	select {
 	case err = <-errCh:
-	case <-time.After(config.stateTimeout):
+	case <-time.After(config.StateTimeout):
 		err = errors.New("time out while waiting for image to register")
 	}
 
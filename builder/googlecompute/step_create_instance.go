@@ -9,6 +9,19 @@ import (
 	"github.com/mitchellh/packer/packer"
 )
 
+const winrmMetaKey = "windows-startup-script-cmd"
+
+// winrmStartupScript enables WinRM with basic authentication over HTTPS,
+// with a self-signed certificate, and opens the port in the Windows
+// firewall.
+const winrmStartupScript = `winrm quickconfig -quiet & ` +
+	`powershell -NoProfile -Command "$cert = New-SelfSignedCertificate ` +
+	`-DnsName $env:COMPUTERNAME -CertStoreLocation Cert:\LocalMachine\My; ` +
+	`New-Item -Path WSMan:\localhost\Listener -Transport HTTPS -Address * ` +
+	`-CertificateThumbPrint $cert.Thumbprint -Force" & ` +
+	`winrm set winrm/config/service/auth @{Basic="true"} & ` +
+	`netsh advfirewall firewall add rule name="WinRM-HTTPS" dir=in action=allow protocol=TCP localport=%d`
+
 // StepCreateInstance represents a Packer build step that creates GCE instances.
 type StepCreateInstance struct {
 	Debug bool
@@ -38,9 +51,30 @@ func (config *Config) getInstanceMetadata(sshPublicKey string) map[string]string
 	}
 	instanceMetadata[sshMetaKey] = sshKeys
 
+	// Windows images don't listen for WinRM out of the box, so unless
+	// the template brings its own startup script, enable it on boot.
+	if config.Comm.Type == "winrm" {
+		if _, exists := instanceMetadata[winrmMetaKey]; !exists {
+			instanceMetadata[winrmMetaKey] = fmt.Sprintf(
+				winrmStartupScript, config.Comm.WinRMPort)
+		}
+	}
+
 	return instanceMetadata
 }
 
+func (config *Config) getInstanceTags() []string {
+	tags := config.Tags
+
+	// The instance is tagged with its name so that the firewall rule that
+	// allows WinRM only applies to it.
+	if config.Comm.Type == "winrm" {
+		tags = append(append([]string{}, tags...), config.InstanceName)
+	}
+
+	return tags
+}
+
 // Run executes the Packer build step that creates a GCE instance.
 func (s *StepCreateInstance) Run(state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
@@ -52,22 +86,29 @@ func (s *StepCreateInstance) Run(state multistep.StateBag) multistep.StepAction
 	name := config.InstanceName
 
 	errCh, err := driver.RunInstance(&InstanceConfig{
-		Description: "New instance created by Packer",
-		DiskSizeGb:  config.DiskSizeGb,
-		Image:       config.getImage(),
-		MachineType: config.MachineType,
-		Metadata:    config.getInstanceMetadata(sshPublicKey),
-		Name:        name,
-		Network:     config.Network,
-		Tags:        config.Tags,
-		Zone:        config.Zone,
+		AcceleratorCount:          config.AcceleratorCount,
+		AcceleratorType:           config.AcceleratorType,
+		Description:               "New instance created by Packer",
+		DiskSizeGb:                config.DiskSizeGb,
+		EnableConfidentialCompute: config.EnableConfidentialCompute,
+		EnableIntegrityMonitoring: config.EnableIntegrityMonitoring,
+		EnableSecureBoot:          config.EnableSecureBoot,
+		EnableVtpm:                config.EnableVtpm,
+		Image:                     config.getImage(),
+		MachineType:               config.MachineType,
+		Metadata:                  config.getInstanceMetadata(sshPublicKey),
+		Name:                      name,
+		Network:                   config.Network,
+		OnHostMaintenance:         config.OnHostMaintenance,
+		Tags:                      config.getInstanceTags(),
+		Zone:                      config.Zone,
 	})
 
 	if err == nil {
 		ui.Message("Waiting for creation operation to complete...")
 		select {
 		case err = <-errCh:
-		case <-time.After(config.stateTimeout):
+		case <-time.After(config.StateTimeout):
 			err = errors.New("time out while waiting for instance to create")
 		}
 	}
@@ -113,7 +154,7 @@ func (s *StepCreateInstance) Cleanup(state multistep.StateBag) {
 	if err == nil {
 		select {
 		case err = <-errCh:
-		case <-time.After(config.stateTimeout):
+		case <-time.After(config.StateTimeout):
 			err = errors.New("time out while waiting for instance to delete")
 		}
 	}
@@ -135,7 +176,7 @@ func (s *StepCreateInstance) Cleanup(state multistep.StateBag) {
 	if err == nil {
 		select {
 		case err = <-errCh:
-		case <-time.After(config.stateTimeout):
+		case <-time.After(config.StateTimeout):
 			err = errors.New("time out while waiting for disk to delete")
 		}
 	}
@@ -26,6 +26,10 @@ func TestStepCreateInstance(t *testing.T) {
 		t.Fatalf("bad action: %#v", action)
 	}
 
+	if c := driver.RunInstanceConfig; c.OnHostMaintenance != config.OnHostMaintenance {
+		t.Fatalf("bad: %#v", c)
+	}
+
 	// Verify state
 	nameRaw, ok := state.GetOk("instance_name")
 	if !ok {
@@ -115,7 +119,7 @@ func TestStepCreateInstance_errorTimeout(t *testing.T) {
 	state.Put("ssh_public_key", "key")
 
 	config := state.Get("config").(*Config)
-	config.stateTimeout = 1 * time.Microsecond
+	config.StateTimeout = 1 * time.Microsecond
 
 	driver := state.Get("driver").(*DriverMock)
 	driver.RunInstanceErrCh = errCh
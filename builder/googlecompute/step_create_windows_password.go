@@ -0,0 +1,90 @@
+package googlecompute
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// StepCreateWindowsPassword represents a Packer build step that sets the
+// password of the WinRM user on a Windows instance.
+type StepCreateWindowsPassword struct {
+	Debug bool
+}
+
+// Run executes the Packer build step that sets the Windows password.
+func (s *StepCreateWindowsPassword) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.Comm.Type != "winrm" {
+		return multistep.ActionContinue
+	}
+
+	// If we already have a password, skip it
+	if config.Comm.WinRMPassword != "" {
+		ui.Say("Skipping creating password since WinRM password set...")
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Creating password for Windows instance...")
+	ui.Message(
+		"The password is set by the agent on the instance once it has\n" +
+			"booted, which can take several minutes. Please wait.")
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		err := fmt.Errorf("Error creating temporary key: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	email := config.account.ClientEmail
+	if email == "" {
+		email = "packer@localhost"
+	}
+
+	data := &WindowsPasswordConfig{
+		Key:      priv,
+		UserName: config.Comm.WinRMUser,
+		Email:    email,
+		ExpireOn: time.Now().Add(config.WindowsPasswordTimeout).UTC(),
+	}
+
+	name := state.Get("instance_name").(string)
+	errCh, err := driver.CreateOrResetWindowsPassword(config.Zone, name, data)
+	if err == nil {
+		select {
+		case err = <-errCh:
+		case <-time.After(config.WindowsPasswordTimeout):
+			err = errors.New("time out while waiting for the password")
+		}
+	}
+
+	if err != nil {
+		err := fmt.Errorf("Error creating Windows password: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message("Password created!")
+	if s.Debug {
+		ui.Message(fmt.Sprintf(
+			"Password (since debug is enabled): %s", data.Password))
+	}
+
+	config.Comm.WinRMPassword = data.Password
+	state.Put("winrm_password", data.Password)
+	return multistep.ActionContinue
+}
+
+// Nothing to clean up. The password belongs to the instance.
+func (s *StepCreateWindowsPassword) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,133 @@
+package googlecompute
+
+import (
+	"errors"
+	"github.com/mitchellh/multistep"
+	"testing"
+	"time"
+)
+
+func TestStepCreateWindowsPassword_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCreateWindowsPassword)
+}
+
+func TestStepCreateWindowsPassword(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateWindowsPassword)
+	defer step.Cleanup(state)
+
+	state.Put("instance_name", "foo")
+
+	config := state.Get("config").(*Config)
+	config.Comm.Type = "winrm"
+	config.Comm.WinRMUser = "packer_user"
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.CreateOrResetWindowsPasswordResult = "secret"
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.CreateOrResetWindowsPasswordInstance != "foo" {
+		t.Fatalf("bad instance: %#v", driver.CreateOrResetWindowsPasswordInstance)
+	}
+	if driver.CreateOrResetWindowsPasswordZone != config.Zone {
+		t.Fatalf("bad zone: %#v", driver.CreateOrResetWindowsPasswordZone)
+	}
+	data := driver.CreateOrResetWindowsPasswordConfig
+	if data.UserName != "packer_user" {
+		t.Fatalf("bad user: %#v", data.UserName)
+	}
+	if data.Key == nil {
+		t.Fatal("should have key")
+	}
+
+	// Verify state
+	if config.Comm.WinRMPassword != "secret" {
+		t.Fatalf("bad password: %#v", config.Comm.WinRMPassword)
+	}
+	if state.Get("winrm_password").(string) != "secret" {
+		t.Fatal("should have password in state")
+	}
+}
+
+func TestStepCreateWindowsPassword_passwordSet(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateWindowsPassword)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Comm.Type = "winrm"
+	config.Comm.WinRMPassword = "foo"
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.CreateOrResetWindowsPasswordConfig != nil {
+		t.Fatal("should NOT have created password")
+	}
+}
+
+func TestStepCreateWindowsPassword_error(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateWindowsPassword)
+	defer step.Cleanup(state)
+
+	state.Put("instance_name", "foo")
+
+	config := state.Get("config").(*Config)
+	config.Comm.Type = "winrm"
+
+	errCh := make(chan error, 1)
+	errCh <- errors.New("error")
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.CreateOrResetWindowsPasswordErrCh = errCh
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Verify state
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+	if _, ok := state.GetOk("winrm_password"); ok {
+		t.Fatal("should NOT have password")
+	}
+}
+
+func TestStepCreateWindowsPassword_errorTimeout(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateWindowsPassword)
+	defer step.Cleanup(state)
+
+	state.Put("instance_name", "foo")
+
+	// The password never arrives, so the step has to time out.
+	errCh := make(chan error)
+
+	config := state.Get("config").(*Config)
+	config.Comm.Type = "winrm"
+	config.WindowsPasswordTimeout = 1 * time.Microsecond
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.CreateOrResetWindowsPasswordErrCh = errCh
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Verify state
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
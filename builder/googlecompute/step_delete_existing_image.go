@@ -0,0 +1,48 @@
+package googlecompute
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// StepDeleteExistingImage represents a Packer build step that deletes the
+// target image if it already exists and force_delete is set. It runs just
+// before the new image is created so that a build that fails earlier
+// keeps the existing image.
+type StepDeleteExistingImage int
+
+// Run executes the Packer build step that deletes the existing image.
+func (s *StepDeleteExistingImage) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !config.ForceDelete || !driver.ImageExists(config.ImageName) {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Deleting existing image: %s", config.ImageName))
+	errCh := driver.DeleteImage(config.ImageName)
+	var err error
+	select {
+	case err = <-errCh:
+	case <-time.After(config.StateTimeout):
+		err = errors.New("time out while waiting for image to delete")
+	}
+
+	if err != nil {
+		err := fmt.Errorf("Error deleting existing image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+// Cleanup.
+func (s *StepDeleteExistingImage) Cleanup(state multistep.StateBag) {}
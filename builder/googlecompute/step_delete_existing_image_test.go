@@ -0,0 +1,76 @@
+package googlecompute
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+)
+
+func TestStepDeleteExistingImage_impl(t *testing.T) {
+	var _ multistep.Step = new(StepDeleteExistingImage)
+}
+
+func TestStepDeleteExistingImage(t *testing.T) {
+	state := testState(t)
+	step := new(StepDeleteExistingImage)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ForceDelete = true
+	driver := state.Get("driver").(*DriverMock)
+	driver.ImageExistsResult = true
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Verify state
+	if driver.DeleteImageName != config.ImageName {
+		t.Fatalf("bad: %#v", driver.DeleteImageName)
+	}
+}
+
+func TestStepDeleteExistingImage_noForce(t *testing.T) {
+	state := testState(t)
+	step := new(StepDeleteExistingImage)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.ImageExistsResult = true
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Verify state
+	if driver.DeleteImageName != "" {
+		t.Fatalf("bad: %#v", driver.DeleteImageName)
+	}
+}
+
+func TestStepDeleteExistingImage_error(t *testing.T) {
+	state := testState(t)
+	step := new(StepDeleteExistingImage)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ForceDelete = true
+	driver := state.Get("driver").(*DriverMock)
+	driver.ImageExistsResult = true
+	errCh := make(chan error, 1)
+	errCh <- errors.New("error")
+	driver.DeleteImageErrCh = errCh
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Verify state
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/mitchellh/packer/template/interpolate"
 	"github.com/rackspace/gophercloud"
@@ -112,6 +113,29 @@ func (c *AccessConfig) computeV2Client() (*gophercloud.ServiceClient, error) {
 	})
 }
 
+// ImageV2Client returns a client for version 2 of the image service,
+// Glance. The endpoint of the client always ends in "v2/".
+func (c *AccessConfig) ImageV2Client() (*gophercloud.ServiceClient, error) {
+	url, err := c.osClient.EndpointLocator(gophercloud.EndpointOpts{
+		Type:         "image",
+		Region:       c.Region,
+		Availability: c.getEndpointType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url = strings.TrimSuffix(url, "/")
+	if !strings.HasSuffix(url, "/v2") {
+		url += "/v2"
+	}
+
+	return &gophercloud.ServiceClient{
+		ProviderClient: c.osClient,
+		Endpoint:       url + "/",
+	}, nil
+}
+
 func (c *AccessConfig) getEndpointType() gophercloud.Availability {
 	if c.EndpointType == "internal" || c.EndpointType == "internalURL" {
 		return gophercloud.AvailabilityInternal
@@ -2,7 +2,10 @@ package openstack
 
 import (
 	"errors"
+	"fmt"
+	"os"
 
+	"github.com/mitchellh/packer/common/uuid"
 	"github.com/mitchellh/packer/helper/communicator"
 	"github.com/mitchellh/packer/template/interpolate"
 )
@@ -10,8 +13,9 @@ import (
 // RunConfig contains configuration for running an instance from a source
 // image and details on how to access that launched image.
 type RunConfig struct {
-	Comm         communicator.Config `mapstructure:",squash"`
-	SSHInterface string              `mapstructure:"ssh_interface"`
+	Comm           communicator.Config `mapstructure:",squash"`
+	SSHKeyPairName string              `mapstructure:"ssh_keypair_name"`
+	SSHInterface   string              `mapstructure:"ssh_interface"`
 
 	SourceImage      string   `mapstructure:"source_image"`
 	Flavor           string   `mapstructure:"flavor"`
@@ -22,6 +26,11 @@ type RunConfig struct {
 	SecurityGroups   []string `mapstructure:"security_groups"`
 	Networks         []string `mapstructure:"networks"`
 
+	TemporaryKeyPairName string            `mapstructure:"temporary_key_pair_name"`
+	UserData             string            `mapstructure:"user_data"`
+	UserDataFile         string            `mapstructure:"user_data_file"`
+	Personality          map[string]string `mapstructure:"personality"`
+
 	// Not really used, but here for BC
 	OpenstackProvider string `mapstructure:"openstack_provider"`
 	UseFloatingIp     bool   `mapstructure:"use_floating_ip"`
@@ -37,6 +46,11 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		c.FloatingIpPool = "public"
 	}
 
+	if c.TemporaryKeyPairName == "" {
+		c.TemporaryKeyPairName = fmt.Sprintf(
+			"packer %s", uuid.TimeOrderedUUID())
+	}
+
 	// Validation
 	errs := c.Comm.Prepare(ctx)
 	if c.SourceImage == "" {
@@ -47,5 +61,25 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		errs = append(errs, errors.New("A flavor must be specified"))
 	}
 
+	if c.SSHKeyPairName != "" && c.Comm.SSHPrivateKey == "" {
+		errs = append(errs, errors.New(
+			"ssh_private_key_file must be specified with ssh_keypair_name"))
+	}
+
+	if c.UserData != "" && c.UserDataFile != "" {
+		errs = append(errs, errors.New("Only one of user_data or user_data_file can be specified."))
+	} else if c.UserDataFile != "" {
+		if _, err := os.Stat(c.UserDataFile); err != nil {
+			errs = append(errs, fmt.Errorf("user_data_file not found: %s", c.UserDataFile))
+		}
+	}
+
+	for dst, src := range c.Personality {
+		if _, err := os.Stat(src); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"personality file for %s not found: %s", dst, src))
+		}
+	}
+
 	return errs
 }
@@ -1,6 +1,7 @@
 package openstack
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -78,3 +79,85 @@ func TestRunConfigPrepare_SSHUsername(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 }
+
+func TestRunConfigPrepare_SSHKeyPairName(t *testing.T) {
+	c := testRunConfig()
+	c.SSHKeyPairName = "foo"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("should error without ssh_private_key_file")
+	}
+}
+
+func TestRunConfigPrepare_TemporaryKeyPairName(t *testing.T) {
+	c := testRunConfig()
+	c.TemporaryKeyPairName = ""
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	if c.TemporaryKeyPairName == "" {
+		t.Fatal("keypair name is empty")
+	}
+
+	c.TemporaryKeyPairName = "ssh-key-123"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	if c.TemporaryKeyPairName != "ssh-key-123" {
+		t.Fatal("keypair name does not match")
+	}
+}
+
+func TestRunConfigPrepare_UserData(t *testing.T) {
+	c := testRunConfig()
+	c.UserData = "foo"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.UserDataFile = "/i/dont/exist"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("should error with both user_data and user_data_file")
+	}
+}
+
+func TestRunConfigPrepare_UserDataFile(t *testing.T) {
+	c := testRunConfig()
+	c.UserDataFile = "/i/dont/exist"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("should error with a missing user_data_file")
+	}
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	c.UserDataFile = tf.Name()
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestRunConfigPrepare_Personality(t *testing.T) {
+	c := testRunConfig()
+	c.Personality = map[string]string{"/etc/foo": "/i/dont/exist"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("should error with a missing personality file")
+	}
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	c.Personality = map[string]string{"/etc/foo": tf.Name()}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+}
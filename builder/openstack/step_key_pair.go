@@ -2,22 +2,41 @@ package openstack
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"runtime"
 
 	"github.com/mitchellh/multistep"
-	"github.com/mitchellh/packer/common/uuid"
 	"github.com/mitchellh/packer/packer"
 	"github.com/rackspace/gophercloud/openstack/compute/v2/extensions/keypairs"
 )
 
 type StepKeyPair struct {
-	Debug        bool
-	DebugKeyPath string
-	keyName      string
+	Debug                bool
+	DebugKeyPath         string
+	KeyPairName          string
+	PrivateKeyFile       string
+	TemporaryKeyPairName string
+
+	keyName string
 }
 
 func (s *StepKeyPair) Run(state multistep.StateBag) multistep.StepAction {
+	if s.PrivateKeyFile != "" {
+		s.keyName = ""
+
+		privateKeyBytes, err := ioutil.ReadFile(s.PrivateKeyFile)
+		if err != nil {
+			state.Put("error", fmt.Errorf("Error loading configured private key file: %s", err))
+			return multistep.ActionHalt
+		}
+
+		state.Put("keyPair", s.KeyPairName)
+		state.Put("privateKey", string(privateKeyBytes))
+
+		return multistep.ActionContinue
+	}
+
 	config := state.Get("config").(Config)
 	ui := state.Get("ui").(packer.Ui)
 
@@ -29,8 +48,8 @@ func (s *StepKeyPair) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
-	ui.Say("Creating temporary keypair for this instance...")
-	keyName := fmt.Sprintf("packer %s", uuid.TimeOrderedUUID())
+	keyName := s.TemporaryKeyPairName
+	ui.Say(fmt.Sprintf("Creating temporary keypair: %s", keyName))
 	keypair, err := keypairs.Create(computeClient, keypairs.CreateOpts{
 		Name: keyName,
 	}).Extract()
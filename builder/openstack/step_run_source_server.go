@@ -2,6 +2,7 @@ package openstack
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 
 	"github.com/mitchellh/multistep"
@@ -16,6 +17,9 @@ type StepRunSourceServer struct {
 	SecurityGroups   []string
 	Networks         []string
 	AvailabilityZone string
+	UserData         string
+	UserDataFile     string
+	Personality      map[string]string
 
 	server *servers.Server
 }
@@ -39,6 +43,33 @@ func (s *StepRunSourceServer) Run(state multistep.StateBag) multistep.StepAction
 		networks[i].UUID = networkUuid
 	}
 
+	userData := []byte(s.UserData)
+	if s.UserDataFile != "" {
+		userData, err = ioutil.ReadFile(s.UserDataFile)
+		if err != nil {
+			err = fmt.Errorf("Error reading user data file: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	var personality servers.Personality
+	for dst, src := range s.Personality {
+		contents, err := ioutil.ReadFile(src)
+		if err != nil {
+			err = fmt.Errorf("Error reading personality file: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		personality = append(personality, &servers.File{
+			Path:     dst,
+			Contents: contents,
+		})
+	}
+
 	ui.Say("Launching server...")
 	s.server, err = servers.Create(computeClient, keypairs.CreateOptsExt{
 		CreateOptsBuilder: servers.CreateOpts{
@@ -48,6 +79,8 @@ func (s *StepRunSourceServer) Run(state multistep.StateBag) multistep.StepAction
 			SecurityGroups:   s.SecurityGroups,
 			Networks:         networks,
 			AvailabilityZone: s.AvailabilityZone,
+			UserData:         userData,
+			Personality:      personality,
 		},
 
 		KeyName: keyName,
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMacOSShutdownCommand is the shutdown_command of macOS guests,
+// which don't know the "-P" flag of Linux and have no Parallels Tools
+// that "prlctl stop" could ask to halt the guest.
+const DefaultMacOSShutdownCommand = "sudo shutdown -h now"
+
+// IsMacOSGuest returns true if the guest_os_type is one of the macOS
+// distributions of Parallels, such as "macosx".
+func IsMacOSGuest(guestOSType string) bool {
+	return strings.HasPrefix(strings.ToLower(guestOSType), "macos")
+}
+
+// PrepareMacOSGuest sets the defaults of the Parallels Tools and the
+// shutdown for macOS guests. The tools for macOS are installed from the
+// guest itself, so there is no ISO to attach or upload. This must be
+// called before ToolsConfig.Prepare, which defaults to uploading the ISO.
+func PrepareMacOSGuest(tools *ToolsConfig, shutdown *ShutdownConfig) []error {
+	var errs []error
+
+	if tools.ParallelsToolsMode == "" {
+		tools.ParallelsToolsMode = ParallelsToolsModeDisable
+	}
+
+	if tools.ParallelsToolsMode != ParallelsToolsModeDisable {
+		errs = append(errs, fmt.Errorf(
+			"parallels_tools_mode must be %q for macOS guests, as there is no "+
+				"Parallels Tools ISO to %s", ParallelsToolsModeDisable, tools.ParallelsToolsMode))
+	}
+
+	if shutdown.ShutdownCommand == "" {
+		shutdown.ShutdownCommand = DefaultMacOSShutdownCommand
+	}
+
+	return errs
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestIsMacOSGuest(t *testing.T) {
+	cases := map[string]bool{
+		"macosx": true,
+		"macos":  true,
+		"MacOSX": true,
+		"ubuntu": false,
+		"win-10": false,
+		"other":  false,
+		"":       false,
+	}
+
+	for guestOSType, expected := range cases {
+		if actual := IsMacOSGuest(guestOSType); actual != expected {
+			t.Errorf("%q: expected %t, got %t", guestOSType, expected, actual)
+		}
+	}
+}
+
+func TestPrepareMacOSGuest(t *testing.T) {
+	tools := &ToolsConfig{}
+	shutdown := &ShutdownConfig{}
+	errs := PrepareMacOSGuest(tools, shutdown)
+	if len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if tools.ParallelsToolsMode != ParallelsToolsModeDisable {
+		t.Fatalf("bad mode: %s", tools.ParallelsToolsMode)
+	}
+	if shutdown.ShutdownCommand != DefaultMacOSShutdownCommand {
+		t.Fatalf("bad shutdown command: %s", shutdown.ShutdownCommand)
+	}
+
+	// The tools config must still be valid without a flavor
+	if errs := tools.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	// A shutdown command of the template is kept
+	shutdown = &ShutdownConfig{ShutdownCommand: "foo"}
+	errs = PrepareMacOSGuest(&ToolsConfig{}, shutdown)
+	if len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if shutdown.ShutdownCommand != "foo" {
+		t.Fatalf("bad shutdown command: %s", shutdown.ShutdownCommand)
+	}
+
+	// There is no tools ISO to attach or upload
+	for _, mode := range []string{ParallelsToolsModeAttach, ParallelsToolsModeUpload} {
+		tools = &ToolsConfig{ParallelsToolsMode: mode}
+		errs = PrepareMacOSGuest(tools, &ShutdownConfig{})
+		if len(errs) == 0 {
+			t.Fatalf("%s: should have error", mode)
+		}
+	}
+}
@@ -1,9 +1,9 @@
 package common
 
 import (
-	"fmt"
 	"time"
 
+	"github.com/mitchellh/packer/helper/config"
 	"github.com/mitchellh/packer/template/interpolate"
 )
 
@@ -20,9 +20,9 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 	}
 
 	var err error
-	c.BootWait, err = time.ParseDuration(c.RawBootWait)
+	c.BootWait, err = config.ParseDuration("boot_wait", c.RawBootWait)
 	if err != nil {
-		return []error{fmt.Errorf("Failed parsing boot_wait: %s", err)}
+		return []error{err}
 	}
 
 	return nil
@@ -34,6 +34,7 @@ type Config struct {
 	parallelscommon.ToolsConfig         `mapstructure:",squash"`
 
 	BootCommand        []string `mapstructure:"boot_command"`
+	BootCommandFile    string   `mapstructure:"boot_command_file"`
 	DiskSize           uint     `mapstructure:"disk_size"`
 	GuestOSType        string   `mapstructure:"guest_os_type"`
 	HardDriveInterface string   `mapstructure:"hard_drive_interface"`
@@ -44,6 +45,7 @@ type Config struct {
 	ISOChecksum        string   `mapstructure:"iso_checksum"`
 	ISOChecksumType    string   `mapstructure:"iso_checksum_type"`
 	ISOUrls            []string `mapstructure:"iso_urls"`
+	KeepRegistered     bool     `mapstructure:"keep_registered"`
 	VMName             string   `mapstructure:"vm_name"`
 
 	RawSingleISOUrl string `mapstructure:"iso_url"`
@@ -72,6 +74,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 	// Accumulate any errors and warnings
 	var errs *packer.MultiError
+	if parallelscommon.IsMacOSGuest(b.config.GuestOSType) ||
+		parallelscommon.IsMacOSGuest(b.config.GuestOSDistribution) {
+		errs = packer.MultiErrorAppend(errs,
+			parallelscommon.PrepareMacOSGuest(&b.config.ToolsConfig, &b.config.ShutdownConfig)...)
+	}
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(
 		errs, b.config.OutputConfig.Prepare(&b.config.ctx, &b.config.PackerConfig)...)
@@ -83,6 +90,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, b.config.ToolsConfig.Prepare(&b.config.ctx)...)
 	warnings := make([]string, 0)
 
+	b.config.BootCommand, err = common.BootCommandFromFile(b.config.BootCommand, b.config.BootCommandFile)
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	if b.config.DiskSize == 0 {
 		b.config.DiskSize = 40000
 	}
@@ -221,7 +233,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Path:  b.config.OutputDir,
 		},
 		&common.StepCreateFloppy{
-			Files: b.config.FloppyFiles,
+			Files:   b.config.FloppyFiles,
+			WorkDir: b.config.PackerWorkDir,
 		},
 		new(stepHTTPServer),
 		new(stepCreateVM),
@@ -282,7 +295,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 
 	b.runner.Run(state)
@@ -304,6 +319,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	return parallelscommon.NewArtifact(b.config.OutputDir)
 }
 
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (b *Builder) Cancel() {
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
@@ -1,6 +1,7 @@
 package iso
 
 import (
+	parallelscommon "github.com/mitchellh/packer/builder/parallels/common"
 	"github.com/mitchellh/packer/packer"
 	"reflect"
 	"testing"
@@ -98,6 +99,36 @@ func TestBuilderPrepare_GuestOSType(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_GuestOSTypeMacOS(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["guest_os_type"] = "macosx"
+	delete(config, "parallels_tools_flavor")
+	delete(config, "shutdown_command")
+
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.ParallelsToolsMode != parallelscommon.ParallelsToolsModeDisable {
+		t.Fatalf("bad: %s", b.config.ParallelsToolsMode)
+	}
+	if b.config.ShutdownCommand != parallelscommon.DefaultMacOSShutdownCommand {
+		t.Fatalf("bad: %s", b.config.ShutdownCommand)
+	}
+
+	// There is no Parallels Tools ISO for macOS guests
+	config["parallels_tools_mode"] = "attach"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestBuilderPrepare_HardDriveInterface(t *testing.T) {
 	var b Builder
 	config := testConfig()
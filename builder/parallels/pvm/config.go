@@ -23,10 +23,13 @@ type Config struct {
 	parallelscommon.ShutdownConfig      `mapstructure:",squash"`
 	parallelscommon.ToolsConfig         `mapstructure:",squash"`
 
-	BootCommand []string `mapstructure:"boot_command"`
-	SourcePath  string   `mapstructure:"source_path"`
-	VMName      string   `mapstructure:"vm_name"`
-	ReassignMac bool     `mapstructure:"reassign_mac"`
+	BootCommand     []string `mapstructure:"boot_command"`
+	BootCommandFile string   `mapstructure:"boot_command_file"`
+	GuestOSType     string   `mapstructure:"guest_os_type"`
+	KeepRegistered  bool     `mapstructure:"keep_registered"`
+	SourcePath      string   `mapstructure:"source_path"`
+	VMName          string   `mapstructure:"vm_name"`
+	ReassignMac     bool     `mapstructure:"reassign_mac"`
 
 	ctx interpolate.Context
 }
@@ -53,6 +56,10 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 
 	// Prepare the errors
 	var errs *packer.MultiError
+	if parallelscommon.IsMacOSGuest(c.GuestOSType) {
+		errs = packer.MultiErrorAppend(errs,
+			parallelscommon.PrepareMacOSGuest(&c.ToolsConfig, &c.ShutdownConfig)...)
+	}
 	errs = packer.MultiErrorAppend(errs, c.FloppyConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.OutputConfig.Prepare(&c.ctx, &c.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, c.PrlctlConfig.Prepare(&c.ctx)...)
@@ -62,6 +69,11 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs = packer.MultiErrorAppend(errs, c.SSHConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ToolsConfig.Prepare(&c.ctx)...)
 
+	c.BootCommand, err = common.BootCommandFromFile(c.BootCommand, c.BootCommandFile)
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	if c.SourcePath == "" {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is required"))
 	} else {
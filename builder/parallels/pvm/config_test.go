@@ -4,6 +4,8 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+
+	parallelscommon "github.com/mitchellh/packer/builder/parallels/common"
 )
 
 func testConfig(t *testing.T) map[string]interface{} {
@@ -84,3 +86,27 @@ func TestNewConfig_shutdown_timeout(t *testing.T) {
 	_, warns, errs = NewConfig(c)
 	testConfigOk(t, warns, errs)
 }
+
+func TestNewConfig_guestOSTypeMacOS(t *testing.T) {
+	c := testConfig(t)
+	tf := getTempFile(t)
+	defer os.Remove(tf.Name())
+
+	c["source_path"] = tf.Name()
+	c["guest_os_type"] = "macosx"
+	delete(c, "parallels_tools_flavor")
+	delete(c, "shutdown_command")
+	config, warns, errs := NewConfig(c)
+	testConfigOk(t, warns, errs)
+	if config.ParallelsToolsMode != parallelscommon.ParallelsToolsModeDisable {
+		t.Fatalf("bad: %s", config.ParallelsToolsMode)
+	}
+	if config.ShutdownCommand != parallelscommon.DefaultMacOSShutdownCommand {
+		t.Fatalf("bad: %s", config.ShutdownCommand)
+	}
+
+	// There is no Parallels Tools ISO for macOS guests
+	c["parallels_tools_mode"] = "upload"
+	_, warns, errs = NewConfig(c)
+	testConfigErr(t, warns, errs)
+}
@@ -0,0 +1,124 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// accels are the accelerators that accelerator can be set to, besides
+// "auto".
+var accels = map[string]struct{}{
+	"none": struct{}{},
+	"hax":  struct{}{},
+	"hvf":  struct{}{},
+	"kvm":  struct{}{},
+	"tcg":  struct{}{},
+	"whpx": struct{}{},
+	"xen":  struct{}{},
+}
+
+// hostAccelerators are the hardware accelerators of each host OS that
+// "auto" tries, best first.
+var hostAccelerators = map[string][]string{
+	"darwin":  {"hvf"},
+	"linux":   {"kvm"},
+	"windows": {"whpx", "hax"},
+}
+
+// hostArchs map the architecture of the host, as in GOARCH, to the guest
+// architecture that its hardware accelerators run.
+var hostArchs = map[string]string{
+	"386":     "i386",
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"ppc64":   "ppc64",
+	"ppc64le": "ppc64",
+	"s390x":   "s390x",
+}
+
+// errAcceleratorUnsupported is returned by probeAccelerator for
+// accelerators that don't exist on the OS of the host.
+var errAcceleratorUnsupported = errors.New("not supported on this OS")
+
+// probeAccelerator returns nil if the hardware accelerator can be used on
+// this host, or why it can't. It is a variable so that tests can fake the
+// host.
+var probeAccelerator = probeHostAccelerator
+
+// prepareAccelerator picks the accelerator if it is "auto", which is the
+// default, and checks that it is one that Qemu knows.
+func (c *Config) prepareAccelerator() []error {
+	if c.Accelerator == "" {
+		c.Accelerator = "auto"
+	}
+
+	if c.Accelerator == "auto" {
+		c.Accelerator, c.acceleratorReason = detectAccelerator(
+			runtime.GOOS, runtime.GOARCH, efiArch(c.QemuBinary))
+		return nil
+	}
+
+	if _, ok := accels[c.Accelerator]; !ok {
+		return []error{errors.New(
+			"invalid accelerator, only 'auto', 'kvm', 'hvf', 'whpx', 'hax', 'tcg', 'xen', or 'none' are allowed")}
+	}
+
+	return nil
+}
+
+// detectAccelerator returns the best accelerator for guests of the
+// architecture on a host with the OS and architecture, and why it was
+// picked. Hardware accelerators only run guests of the architecture of
+// the host, so other guests are emulated by tcg.
+func detectAccelerator(goos, goarch, guestArch string) (string, string) {
+	hostArch, ok := hostArchs[goarch]
+	if !ok {
+		hostArch = goarch
+	}
+	if guestArch != hostArch && !(hostArch == "x86_64" && guestArch == "i386") {
+		return "tcg", fmt.Sprintf(
+			"%s guests are emulated, since the host is %s", guestArch, hostArch)
+	}
+
+	candidates := hostAccelerators[goos]
+	if len(candidates) == 0 {
+		return "tcg", fmt.Sprintf("there is no hardware accelerator for %s hosts", goos)
+	}
+
+	var reasons []string
+	for _, accel := range candidates {
+		err := probeAccelerator(accel)
+		if err == nil {
+			return accel, "it is the best one available on this host"
+		}
+
+		reasons = append(reasons, fmt.Sprintf("%s: %s", accel, err))
+	}
+
+	return "tcg", fmt.Sprintf(
+		"no hardware accelerator is available, so the VM will be slow (%s)",
+		strings.Join(reasons, "; "))
+}
+
+// fallBackAccelerator switches to tcg if the accelerator that "auto"
+// picked needs a newer version of Qemu. The accelerators that are set
+// explicitly are rejected by checkQemuVersion instead.
+func (c *Config) fallBackAccelerator(v qemuVersion) {
+	if c.acceleratorReason == "" {
+		return
+	}
+
+	min, ok := qemuVersionAccels[c.Accelerator]
+	if !ok || v.AtLeast(min) {
+		return
+	}
+
+	c.acceleratorReason = fmt.Sprintf(
+		"%s is available, but needs Qemu %s or newer, found %s", c.Accelerator, min, v)
+	c.Accelerator = "tcg"
+	if c.cpuModelDefault {
+		c.CPUModel = c.arch().CPU
+	}
+}
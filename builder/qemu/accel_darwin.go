@@ -0,0 +1,24 @@
+package qemu
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+func probeHostAccelerator(accel string) error {
+	if accel != "hvf" {
+		return errAcceleratorUnsupported
+	}
+
+	// The Hypervisor framework needs a Mac with VT-x or Apple silicon
+	out, err := exec.Command("sysctl", "-n", "kern.hv_support").Output()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(out)) != "1" {
+		return errors.New("the Hypervisor framework is not supported by this Mac")
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package qemu
+
+import (
+	"os"
+)
+
+func probeHostAccelerator(accel string) error {
+	if accel != "kvm" {
+		return errAcceleratorUnsupported
+	}
+
+	// KVM is used by opening /dev/kvm, which needs the kvm group
+	f, err := os.OpenFile("/dev/kvm", os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
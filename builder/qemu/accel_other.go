@@ -0,0 +1,7 @@
+// +build !linux,!darwin,!windows
+
+package qemu
+
+func probeHostAccelerator(accel string) error {
+	return errAcceleratorUnsupported
+}
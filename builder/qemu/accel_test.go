@@ -0,0 +1,126 @@
+package qemu
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testProbeAccelerator fakes a host where only the accelerators are
+// available, until the function that it returns is called.
+func testProbeAccelerator(available ...string) func() {
+	old := probeAccelerator
+	probeAccelerator = func(accel string) error {
+		for _, a := range available {
+			if a == accel {
+				return nil
+			}
+		}
+
+		return errors.New("not available")
+	}
+
+	return func() { probeAccelerator = old }
+}
+
+func TestDetectAccelerator(t *testing.T) {
+	restore := testProbeAccelerator("kvm", "hvf", "hax")
+	defer restore()
+
+	cases := []struct {
+		GOOS, GOARCH, Guest string
+		Expected            string
+	}{
+		{"linux", "amd64", "x86_64", "kvm"},
+		{"linux", "amd64", "i386", "kvm"},
+		{"linux", "amd64", "aarch64", "tcg"},
+		{"linux", "arm64", "aarch64", "kvm"},
+		{"linux", "ppc64le", "ppc64", "kvm"},
+		{"darwin", "arm64", "aarch64", "hvf"},
+		{"darwin", "amd64", "aarch64", "tcg"},
+		{"windows", "amd64", "x86_64", "hax"},
+		{"freebsd", "amd64", "x86_64", "tcg"},
+	}
+
+	for _, tc := range cases {
+		accel, reason := detectAccelerator(tc.GOOS, tc.GOARCH, tc.Guest)
+		if accel != tc.Expected {
+			t.Fatalf("%s/%s %s: expected %s, got %s", tc.GOOS, tc.GOARCH, tc.Guest, tc.Expected, accel)
+		}
+		if reason == "" {
+			t.Fatalf("%s/%s %s: no reason", tc.GOOS, tc.GOARCH, tc.Guest)
+		}
+	}
+
+	// Without KVM, the reason says why it can't be used
+	defer testProbeAccelerator()()
+	accel, reason := detectAccelerator("linux", "amd64", "x86_64")
+	if accel != "tcg" || !strings.Contains(reason, "kvm: not available") {
+		t.Fatalf("bad: %s %s", accel, reason)
+	}
+}
+
+func TestBuilderPrepare_Accelerator(t *testing.T) {
+	defer testProbeAccelerator()()
+
+	var b Builder
+	config := testConfig()
+
+	// auto is the default
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.Accelerator == "auto" || b.config.acceleratorReason == "" {
+		t.Fatalf("bad: %s %s", b.config.Accelerator, b.config.acceleratorReason)
+	}
+
+	for _, accel := range []string{"hvf", "whpx", "hax", "kvm", "tcg", "xen", "none"} {
+		config["accelerator"] = accel
+		b = Builder{}
+		if _, err := b.Prepare(config); err != nil {
+			t.Fatalf("%s: should not have error: %s", accel, err)
+		}
+		if b.config.Accelerator != accel || b.config.acceleratorReason != "" {
+			t.Fatalf("bad: %s %s", b.config.Accelerator, b.config.acceleratorReason)
+		}
+	}
+
+	config["accelerator"] = "vbox"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestConfigFallBackAccelerator(t *testing.T) {
+	// An accelerator that was picked falls back to tcg on old versions
+	c := &Config{
+		Accelerator:       "hvf",
+		CPUModel:          "host",
+		acceleratorReason: "it is the best one available on this host",
+		cpuModelDefault:   true,
+	}
+	c.fallBackAccelerator(qemuVersion{2, 11, 0})
+	if c.Accelerator != "tcg" || c.CPUModel != "qemu64" {
+		t.Fatalf("bad: %s %s", c.Accelerator, c.CPUModel)
+	}
+	if errs := c.checkQemuVersion(qemuVersion{2, 11, 0}); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	c = &Config{Accelerator: "hvf", acceleratorReason: "it is the best one available on this host"}
+	c.fallBackAccelerator(qemuVersion{2, 12, 0})
+	if c.Accelerator != "hvf" {
+		t.Fatalf("bad: %s", c.Accelerator)
+	}
+
+	// One that was set explicitly is rejected
+	c = &Config{Accelerator: "whpx"}
+	c.fallBackAccelerator(qemuVersion{2, 12, 0})
+	if c.Accelerator != "whpx" {
+		t.Fatalf("bad: %s", c.Accelerator)
+	}
+	if errs := c.checkQemuVersion(qemuVersion{2, 12, 0}); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
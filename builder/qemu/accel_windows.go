@@ -0,0 +1,54 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// whvCapabilityCodeHypervisorPresent is the WHV_CAPABILITY_CODE that
+// tells whether the Windows Hypervisor Platform is enabled.
+const whvCapabilityCodeHypervisorPresent = 0
+
+func probeHostAccelerator(accel string) error {
+	switch accel {
+	case "whpx":
+		dll, err := syscall.LoadDLL("WinHvPlatform.dll")
+		if err != nil {
+			return errors.New("the Windows Hypervisor Platform feature is not installed")
+		}
+		defer dll.Release()
+
+		proc, err := dll.FindProc("WHvGetCapability")
+		if err != nil {
+			return err
+		}
+
+		var present, written uint32
+		hr, _, _ := proc.Call(
+			whvCapabilityCodeHypervisorPresent,
+			uintptr(unsafe.Pointer(&present)),
+			unsafe.Sizeof(present),
+			uintptr(unsafe.Pointer(&written)))
+		if int32(hr) < 0 {
+			return fmt.Errorf("WHvGetCapability failed: 0x%08x", uint32(hr))
+		}
+		if present == 0 {
+			return errors.New("the Windows Hypervisor Platform is not enabled")
+		}
+
+		return nil
+	case "hax":
+		// HAXM is used through its device once its driver is installed
+		f, err := os.OpenFile(`\\.\HAX`, os.O_RDWR, 0)
+		if err != nil {
+			return errors.New("the HAXM driver is not installed")
+		}
+
+		return f.Close()
+	}
+
+	return errAcceleratorUnsupported
+}
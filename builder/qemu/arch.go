@@ -0,0 +1,103 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+)
+
+// qemuArch describes the defaults for VMs of a guest architecture.
+type qemuArch struct {
+	// MachineType is the default machine_type.
+	MachineType string
+
+	// CPU is the default cpu_model, or empty for Qemu's default. KVMCPU
+	// is the default instead when KVM or HVF runs the VM, which can pass
+	// the CPU of the host through.
+	CPU    string
+	KVMCPU string
+
+	// EFI is true if the machine can only boot EFI firmware, so efi_boot
+	// is the default.
+	EFI bool
+
+	// PC is true for PC machines, which have an IDE controller for the
+	// CD-ROM, a floppy drive, a display and a keyboard, and which follow
+	// the boot order of -boot. Other machines attach the CD-ROM to a
+	// virtio SCSI controller and leave the boot order to the firmware.
+	PC bool
+
+	// Devices are added to machines that aren't PCs so that they have a
+	// display and a keyboard for the boot command. Machines without them
+	// have no display at all.
+	Devices []string
+
+	// TPMDevice is the default tpm_device, or empty if the machine has no
+	// TPM device.
+	TPMDevice string
+}
+
+// qemuArchs are the defaults of the known guest architectures, named as
+// in qemu-system-ARCH.
+var qemuArchs = map[string]qemuArch{
+	"x86_64": {MachineType: "pc", CPU: "qemu64", KVMCPU: "host", PC: true, TPMDevice: "tpm-tis"},
+	"i386":   {MachineType: "pc", CPU: "qemu32", KVMCPU: "host", PC: true, TPMDevice: "tpm-tis"},
+	"aarch64": {
+		MachineType: "virt",
+		CPU:         "max",
+		EFI:         true,
+		Devices:     []string{"virtio-gpu-pci", "qemu-xhci", "usb-kbd", "usb-tablet"},
+		TPMDevice:   "tpm-tis-device",
+	},
+	"ppc64": {
+		MachineType: "pseries",
+		Devices:     []string{"VGA", "qemu-xhci", "usb-kbd", "usb-tablet"},
+		TPMDevice:   "tpm-spapr",
+	},
+	"s390x": {MachineType: "s390-ccw-virtio"},
+}
+
+// arch returns the defaults for the guest architecture of qemu_binary.
+// Architectures that aren't known are treated like ones that aren't PCs.
+func (c *Config) arch() qemuArch {
+	if arch, ok := qemuArchs[efiArch(c.QemuBinary)]; ok {
+		return arch
+	}
+
+	return qemuArch{}
+}
+
+// prepareArch fills in the defaults of the guest architecture and checks
+// that the configuration works with its machines.
+func (c *Config) prepareArch() []error {
+	var errs []error
+	arch := c.arch()
+
+	if c.MachineType == "" {
+		c.MachineType = arch.MachineType
+	}
+	if c.MachineType == "" {
+		errs = append(errs, fmt.Errorf(
+			"machine_type must be set for %s guests", efiArch(c.QemuBinary)))
+	}
+
+	if c.CPUModel == "" {
+		c.cpuModelDefault = true
+		c.CPUModel = arch.CPU
+		if (c.Accelerator == "kvm" || c.Accelerator == "hvf") && arch.KVMCPU != "" {
+			c.CPUModel = arch.KVMCPU
+		}
+	}
+
+	// Machines that can only boot EFI need firmware, which is found by
+	// efi_boot unless a single firmware image is given.
+	if arch.EFI && c.Firmware == "" {
+		c.EFIBoot = true
+	}
+
+	if !arch.PC && len(c.FloppyFiles) > 0 {
+		errs = append(errs, errors.New(
+			"floppy_files can only be used with x86 guests, which have a floppy drive"))
+	}
+
+	return errs
+}
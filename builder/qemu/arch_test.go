@@ -0,0 +1,95 @@
+package qemu
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestBuilderPrepare_Arch(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// x86 defaults with KVM
+	config["accelerator"] = "kvm"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.MachineType != "pc" || b.config.CPUModel != "host" || b.config.EFIBoot {
+		t.Fatalf("bad: %#v", b.config)
+	}
+
+	// Without KVM the CPU of the host isn't available
+	config["accelerator"] = "tcg"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.CPUModel != "qemu64" {
+		t.Fatalf("bad: %s", b.config.CPUModel)
+	}
+	delete(config, "accelerator")
+
+	// aarch64 boots EFI firmware on virt machines
+	code, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	code.Close()
+	defer os.Remove(code.Name())
+
+	old := efiFirmwarePaths
+	defer func() { efiFirmwarePaths = old }()
+	efiFirmwarePaths = map[string][]efiFirmware{
+		"aarch64": {{code.Name(), code.Name()}},
+	}
+
+	config["qemu_binary"] = "qemu-system-aarch64"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.MachineType != "virt" || b.config.CPUModel != "max" || !b.config.EFIBoot {
+		t.Fatalf("bad: %#v", b.config)
+	}
+	if b.config.EFIFirmwareCode != code.Name() {
+		t.Fatalf("bad: %s", b.config.EFIFirmwareCode)
+	}
+
+	// Settings win over the defaults
+	config["machine_type"] = "virt-6.2"
+	config["cpu_model"] = "cortex-a57"
+	config["firmware"] = code.Name()
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.MachineType != "virt-6.2" || b.config.CPUModel != "cortex-a57" || b.config.EFIBoot {
+		t.Fatalf("bad: %#v", b.config)
+	}
+
+	// Only PCs have a floppy drive
+	config["floppy_files"] = []string{code.Name()}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Unknown architectures need a machine type
+	config = testConfig()
+	config["qemu_binary"] = "qemu-system-mips"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["machine_type"] = "malta"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
@@ -3,14 +3,16 @@ package qemu
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 // Artifact is the result of running the Qemu builder, namely a set
 // of files associated with the resulting machine.
 type Artifact struct {
-	dir   string
-	f     []string
-	state map[string]interface{}
+	dir     string
+	f       []string
+	formats []string
+	state   map[string]interface{}
 }
 
 func (*Artifact) BuilderId() string {
@@ -26,6 +28,11 @@ func (*Artifact) Id() string {
 }
 
 func (a *Artifact) String() string {
+	if len(a.formats) > 1 {
+		return fmt.Sprintf("VM files in directory: %s (disk formats: %s)",
+			a.dir, strings.Join(a.formats, ", "))
+	}
+
 	return fmt.Sprintf("VM files in directory: %s", a.dir)
 }
 
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -20,13 +21,6 @@ import (
 
 const BuilderId = "transcend.qemu"
 
-var accels = map[string]struct{}{
-	"none": struct{}{},
-	"kvm":  struct{}{},
-	"tcg":  struct{}{},
-	"xen":  struct{}{},
-}
-
 var netDevice = map[string]bool{
 	"ne2k_pci":       true,
 	"i82551":         true,
@@ -80,33 +74,103 @@ type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 	Comm                communicator.Config `mapstructure:",squash"`
 
-	Accelerator     string     `mapstructure:"accelerator"`
-	BootCommand     []string   `mapstructure:"boot_command"`
-	DiskInterface   string     `mapstructure:"disk_interface"`
-	DiskSize        uint       `mapstructure:"disk_size"`
-	DiskCache       string     `mapstructure:"disk_cache"`
-	DiskDiscard     string     `mapstructure:"disk_discard"`
-	FloppyFiles     []string   `mapstructure:"floppy_files"`
-	Format          string     `mapstructure:"format"`
-	Headless        bool       `mapstructure:"headless"`
-	DiskImage       bool       `mapstructure:"disk_image"`
-	HTTPDir         string     `mapstructure:"http_directory"`
-	HTTPPortMin     uint       `mapstructure:"http_port_min"`
-	HTTPPortMax     uint       `mapstructure:"http_port_max"`
-	ISOChecksum     string     `mapstructure:"iso_checksum"`
-	ISOChecksumType string     `mapstructure:"iso_checksum_type"`
-	ISOUrls         []string   `mapstructure:"iso_urls"`
-	MachineType     string     `mapstructure:"machine_type"`
-	NetDevice       string     `mapstructure:"net_device"`
-	OutputDir       string     `mapstructure:"output_directory"`
-	QemuArgs        [][]string `mapstructure:"qemuargs"`
-	QemuBinary      string     `mapstructure:"qemu_binary"`
-	ShutdownCommand string     `mapstructure:"shutdown_command"`
-	SSHHostPortMin  uint       `mapstructure:"ssh_host_port_min"`
-	SSHHostPortMax  uint       `mapstructure:"ssh_host_port_max"`
-	VNCPortMin      uint       `mapstructure:"vnc_port_min"`
-	VNCPortMax      uint       `mapstructure:"vnc_port_max"`
-	VMName          string     `mapstructure:"vm_name"`
+	AdditionalDiskSize      []uint   `mapstructure:"additional_disk_size"`
+	AdditionalDiskInterface []string `mapstructure:"additional_disk_interface"`
+	AdditionalDiskCache     []string `mapstructure:"additional_disk_cache"`
+
+	AdditionalISOs []AdditionalISO `mapstructure:"additional_iso_urls"`
+
+	Ephemeral      bool `mapstructure:"ephemeral"`
+	StandaloneDisk bool `mapstructure:"standalone_disk"`
+	UseBackingFile bool `mapstructure:"use_backing_file"`
+
+	CDContent map[string]string `mapstructure:"cd_content"`
+	CDLabel   string            `mapstructure:"cd_label"`
+	MetaData  string            `mapstructure:"meta_data"`
+	UserData  string            `mapstructure:"user_data"`
+
+	PortForwards []PortForward `mapstructure:"host_port_forwards"`
+
+	SharedFolders []SharedFolder `mapstructure:"shared_folders"`
+
+	ResourceLimits ResourceLimits `mapstructure:"resource_limits"`
+
+	BridgeName   string `mapstructure:"bridge_name"`
+	MacAddress   string `mapstructure:"mac_address"`
+	NetLeaseFile string `mapstructure:"net_lease_file"`
+	NetMode      string `mapstructure:"net_mode"`
+	TapDevice    string `mapstructure:"tap_device"`
+
+	Accelerator     string       `mapstructure:"accelerator"`
+	BootCommand     []string     `mapstructure:"boot_command"`
+	BootCommandFile string       `mapstructure:"boot_command_file"`
+	Compression     bool         `mapstructure:"compression"`
+	CPUModel        string       `mapstructure:"cpu_model"`
+	CPUs            uint         `mapstructure:"cpus"`
+	Cores           uint         `mapstructure:"cores"`
+	Devices         []QemuDevice `mapstructure:"devices"`
+	DiskInterface   string       `mapstructure:"disk_interface"`
+	DiskSize        uint         `mapstructure:"disk_size"`
+	DiskCache       string       `mapstructure:"disk_cache"`
+	DiskDiscard     string       `mapstructure:"disk_discard"`
+	Display         string       `mapstructure:"display"`
+	FloppyFiles     []string     `mapstructure:"floppy_files"`
+	Formats         []string     `mapstructure:"format"`
+	Headless        bool         `mapstructure:"headless"`
+	DiskImage       bool         `mapstructure:"disk_image"`
+	Drives          []QemuDevice `mapstructure:"drives"`
+	EFIBoot         bool         `mapstructure:"efi_boot"`
+	EFIFirmwareCode string       `mapstructure:"efi_firmware_code"`
+	EFIFirmwareVars string       `mapstructure:"efi_firmware_vars"`
+	Firmware        string       `mapstructure:"firmware"`
+	HTTPDir         string       `mapstructure:"http_directory"`
+	HTTPPortMin     uint         `mapstructure:"http_port_min"`
+	HTTPPortMax     uint         `mapstructure:"http_port_max"`
+	HostIPv6        bool         `mapstructure:"host_ipv6"`
+	ISOChecksum     string       `mapstructure:"iso_checksum"`
+	ISOChecksumType string       `mapstructure:"iso_checksum_type"`
+	ISOSkipCache    bool         `mapstructure:"iso_skip_cache"`
+	ISOUrls         []string     `mapstructure:"iso_urls"`
+	KillOrphans     bool         `mapstructure:"kill_orphans"`
+	MachineType     string       `mapstructure:"machine_type"`
+	Memory          uint         `mapstructure:"memory"`
+	NetDevice       string       `mapstructure:"net_device"`
+	NetDevices      []QemuDevice `mapstructure:"net_devices"`
+	NetIPv6         bool         `mapstructure:"net_ipv6"`
+	NetIPv6DNS      string       `mapstructure:"net_ipv6_dns"`
+	NetIPv6Prefix   string       `mapstructure:"net_ipv6_prefix"`
+	OutputDir       string       `mapstructure:"output_directory"`
+	PidDirectory    string       `mapstructure:"pid_directory"`
+	QemuArgs        [][]string   `mapstructure:"qemuargs"`
+	QemuBinary      string       `mapstructure:"qemu_binary"`
+	PinSSHHostKey   bool         `mapstructure:"pin_ssh_host_key"`
+	SerialLogFile   string       `mapstructure:"serial_log_file"`
+	ShutdownCommand string       `mapstructure:"shutdown_command"`
+	SkipCompaction  bool         `mapstructure:"skip_compaction"`
+	SkipResizeDisk  bool         `mapstructure:"skip_resize_disk"`
+	Sockets         uint         `mapstructure:"sockets"`
+	SPICEPortMin    uint         `mapstructure:"spice_port_min"`
+	SPICEPortMax    uint         `mapstructure:"spice_port_max"`
+	SPICEPassword   string       `mapstructure:"spice_password"`
+	SSHHostPortMin  uint         `mapstructure:"ssh_host_port_min"`
+	SSHHostPortMax  uint         `mapstructure:"ssh_host_port_max"`
+	TPMDevice       string       `mapstructure:"tpm_device"`
+	TPMVersion      string       `mapstructure:"tpm_version"`
+	VNCBindAddress  string       `mapstructure:"vnc_bind_address"`
+	VNCPassword     string       `mapstructure:"vnc_password"`
+	VNCPortMin      uint         `mapstructure:"vnc_port_min"`
+	VNCPortMax      uint         `mapstructure:"vnc_port_max"`
+	VNCUsePassword  bool         `mapstructure:"vnc_use_password"`
+	VMName          string       `mapstructure:"vm_name"`
+
+	// BootCommandDriver is how the boot command is typed: "vnc" or "qmp".
+	BootCommandDriver string `mapstructure:"boot_command_driver"`
+
+	// These tune the I/O of the main disk: the aio and detect-zeroes
+	// options of its -drive, and whether an I/O thread of its own serves it.
+	DiskAIO          string `mapstructure:"disk_aio"`
+	DiskDetectZeroes string `mapstructure:"disk_detect_zeroes"`
+	DiskIOThread     bool   `mapstructure:"disk_iothread"`
 
 	// These are deprecated, but we keep them around for BC
 	// TODO(@mitchellh): remove
@@ -116,13 +180,30 @@ type Config struct {
 	// TODO(mitchellh): deprecate
 	RunOnce bool `mapstructure:"run_once"`
 
-	RawBootWait        string `mapstructure:"boot_wait"`
-	RawSingleISOUrl    string `mapstructure:"iso_url"`
-	RawShutdownTimeout string `mapstructure:"shutdown_timeout"`
+	RawBootWait     string        `mapstructure:"boot_wait"`
+	RawSingleISOUrl string        `mapstructure:"iso_url"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+
+	bootWait time.Duration ``
+	ctx      interpolate.Context
+
+	// acceleratorReason is why "auto" picked the accelerator, or empty if
+	// it was set explicitly. cpuModelDefault is true if cpu_model wasn't
+	// set, so it follows the accelerator.
+	acceleratorReason string
+	cpuModelDefault   bool
+
+	// format is the first of the formats, which the disks are built in.
+	// They are converted to the others once the VM has shut down.
+	format string
+
+	// diskSizeSet is true if disk_size was set rather than defaulted, for
+	// the options that keep the size of the disk image.
+	diskSizeSet bool
 
-	bootWait        time.Duration ``
-	shutdownTimeout time.Duration ``
-	ctx             interpolate.Context
+	// vncPasswordGenerated is true if vnc_use_password made up the
+	// password of this build, which the user has to be told.
+	vncPasswordGenerated bool
 }
 
 func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
@@ -139,6 +220,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		return nil, err
 	}
 
+	b.config.diskSizeSet = b.config.DiskSize != 0
 	if b.config.DiskSize == 0 {
 		b.config.DiskSize = 40000
 	}
@@ -147,12 +229,18 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.DiskCache = "writeback"
 	}
 
-	if b.config.DiskDiscard == "" {
-		b.config.DiskDiscard = "ignore"
+	if b.config.Memory == 0 {
+		b.config.Memory = 512
+	}
+
+	// Without a count of CPUs the VM gets one for each core of each
+	// socket, or a single CPU.
+	if b.config.CPUs == 0 {
+		b.config.CPUs = b.config.smpSockets() * b.config.smpCores()
 	}
 
-	if b.config.Accelerator == "" {
-		b.config.Accelerator = "kvm"
+	if b.config.DiskDiscard == "" {
+		b.config.DiskDiscard = "ignore"
 	}
 
 	if b.config.HTTPPortMin == 0 {
@@ -163,14 +251,14 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.HTTPPortMax = 9000
 	}
 
-	if b.config.MachineType == "" {
-		b.config.MachineType = "pc"
-	}
-
 	if b.config.OutputDir == "" {
 		b.config.OutputDir = fmt.Sprintf("output-%s", b.config.PackerBuildName)
 	}
 
+	if b.config.PidDirectory == "" {
+		b.config.PidDirectory = filepath.Join(os.TempDir(), "packer-qemu")
+	}
+
 	if b.config.QemuBinary == "" {
 		b.config.QemuBinary = "qemu-system-x86_64"
 	}
@@ -199,18 +287,15 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.VMName = fmt.Sprintf("packer-%s", b.config.PackerBuildName)
 	}
 
-	if b.config.Format == "" {
-		b.config.Format = "qcow2"
+	if len(b.config.Formats) == 0 {
+		b.config.Formats = []string{"qcow2"}
 	}
+	b.config.format = b.config.Formats[0]
 
 	if b.config.FloppyFiles == nil {
 		b.config.FloppyFiles = make([]string, 0)
 	}
 
-	if b.config.NetDevice == "" {
-		b.config.NetDevice = "virtio-net"
-	}
-
 	if b.config.DiskInterface == "" {
 		b.config.DiskInterface = "virtio"
 	}
@@ -226,18 +311,93 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	var errs *packer.MultiError
 	warnings := make([]string, 0)
 
+	b.config.BootCommand, err = common.BootCommandFromFile(b.config.BootCommand, b.config.BootCommandFile)
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	if es := b.config.Comm.Prepare(&b.config.ctx); len(es) > 0 {
 		errs = packer.MultiErrorAppend(errs, es...)
 	}
 
-	if !(b.config.Format == "qcow2" || b.config.Format == "raw") {
-		errs = packer.MultiErrorAppend(
-			errs, errors.New("invalid format, only 'qcow2' or 'raw' are allowed"))
+	if es := b.config.prepareDevices(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
 	}
 
-	if _, ok := accels[b.config.Accelerator]; !ok {
-		errs = packer.MultiErrorAppend(
-			errs, errors.New("invalid accelerator, only 'kvm', 'tcg', 'xen', or 'none' are allowed"))
+	if es := b.config.prepareAccelerator(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareArch(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareEFI(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareAdditionalDisks(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareBackingFile(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareCompaction(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareEphemeral(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareSkipResizeDisk(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareTPM(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareNetwork(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.preparePortForwards(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareSharedFolders(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareResourceLimits(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareDisplay(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareSerialLog(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareSeed(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareAdditionalISOs(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareISOSkipCache(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if es := b.config.prepareFormats(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
 	}
 
 	if _, ok := netDevice[b.config.NetDevice]; !ok {
@@ -245,6 +405,37 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, errors.New("unrecognized network device type"))
 	}
 
+	if !b.config.NetIPv6 && (b.config.NetIPv6Prefix != "" || b.config.NetIPv6DNS != "") {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("net_ipv6_prefix and net_ipv6_dns require net_ipv6"))
+	}
+
+	if b.config.NetIPv6Prefix != "" {
+		if ip, _, err := net.ParseCIDR(b.config.NetIPv6Prefix); err != nil || ip.To4() != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("net_ipv6_prefix is not an IPv6 prefix: %s", b.config.NetIPv6Prefix))
+		}
+	}
+
+	if b.config.NetIPv6DNS != "" {
+		if ip := net.ParseIP(b.config.NetIPv6DNS); ip == nil || ip.To4() != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("net_ipv6_dns is not an IPv6 address: %s", b.config.NetIPv6DNS))
+		}
+	}
+
+	if b.config.Memory < 128 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("memory must be at least 128 MB"))
+	}
+
+	if (b.config.Sockets != 0 || b.config.Cores != 0) &&
+		b.config.smpSockets()*b.config.smpCores() != b.config.CPUs {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("cpus must be the number of sockets times cores, %d",
+				b.config.smpSockets()*b.config.smpCores()))
+	}
+
 	if _, ok := diskInterface[b.config.DiskInterface]; !ok {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("unrecognized disk interface type"))
@@ -260,6 +451,10 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, errors.New("unrecognized disk cache type"))
 	}
 
+	if es := b.config.prepareDiskIO(); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
 	if b.config.HTTPPortMin > b.config.HTTPPortMax {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("http_port_min must be less than http_port_max"))
@@ -312,20 +507,13 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
-	b.config.bootWait, err = time.ParseDuration(b.config.RawBootWait)
+	b.config.bootWait, err = config.ParseDuration("boot_wait", b.config.RawBootWait)
 	if err != nil {
-		errs = packer.MultiErrorAppend(
-			errs, fmt.Errorf("Failed parsing boot_wait: %s", err))
-	}
-
-	if b.config.RawShutdownTimeout == "" {
-		b.config.RawShutdownTimeout = "5m"
+		errs = packer.MultiErrorAppend(errs, err)
 	}
 
-	b.config.shutdownTimeout, err = time.ParseDuration(b.config.RawShutdownTimeout)
-	if err != nil {
-		errs = packer.MultiErrorAppend(
-			errs, fmt.Errorf("Failed parsing shutdown_timeout: %s", err))
+	if b.config.ShutdownTimeout == 0 {
+		b.config.ShutdownTimeout = 5 * time.Minute
 	}
 
 	if b.config.SSHHostPortMin > b.config.SSHHostPortMax {
@@ -341,11 +529,54 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	if b.config.QemuArgs == nil {
 		b.config.QemuArgs = make([][]string, 0)
 	}
+	warnings = append(warnings, qemuArgsWarnings(&b.config)...)
 
 	if b.config.ISOChecksumType == "none" {
 		warnings = append(warnings,
 			"A checksum type of 'none' was specified. Since ISO files are so big,\n"+
 				"a checksum is highly recommended.")
+	} else if b.config.ISOSkipCache {
+		warnings = append(warnings,
+			"iso_skip_cache is set, so the ISO is read from its URL by Qemu and\n"+
+				"iso_checksum is not verified.")
+	}
+
+	if b.config.Ephemeral {
+		warnings = append(warnings,
+			"ephemeral is set, so the changes made by the build are thrown away\n"+
+				"and there is no artifact for post-processors.")
+	}
+
+	if b.config.UseBackingFile && !b.config.StandaloneDisk {
+		warnings = append(warnings,
+			"use_backing_file is set without standalone_disk, so the disk of the\n"+
+				"artifact only has the changes made by the build and can't be used\n"+
+				"without the source image that it references.")
+	}
+
+	if b.config.SSHKeyPath != "" {
+		warnings = append(warnings,
+			packer.DeprecatedOption("ssh_key_path", "ssh_private_key_file"))
+	}
+	if b.config.SSHWaitTimeout != 0 {
+		warnings = append(warnings,
+			packer.DeprecatedOption("ssh_wait_timeout", "ssh_timeout"))
+	}
+
+	displayArg := "-" + b.config.Display
+	if b.config.Headless && !qemuArgsHas(b.config.QemuArgs, displayArg) && b.config.displayExposed() {
+		restrict := fmt.Sprintf("Set \"%s\" in qemuargs to restrict it.", displayArg)
+		switch b.config.Display {
+		case "vnc":
+			restrict = "Set vnc_use_password or vnc_bind_address to restrict it."
+		case "spice":
+			restrict = "Set spice_password or vnc_bind_address to restrict it."
+		}
+		warnings = append(warnings, fmt.Sprintf(
+			"headless is set, so the VM can only be reached with %s, which\n"+
+				"listens on all interfaces without a password. Anyone who can\n"+
+				"reach this machine can control the VM while it builds.\n%s",
+			strings.ToUpper(b.config.Display), restrict))
 	}
 
 	if errs != nil && len(errs.Errors) > 0 {
@@ -357,11 +588,30 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
 	// Create the driver that we'll use to communicate with Qemu
-	driver, err := b.newDriver(b.config.QemuBinary)
+	driver, err := b.newDriver(b.config.QemuBinary, &b.config.ResourceLimits,
+		filepath.Join(b.config.OutputDir, "qemu.log"))
 	if err != nil {
 		return nil, fmt.Errorf("Failed creating Qemu driver: %s", err)
 	}
 
+	rawVersion, err := driver.Version()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the version of Qemu: %s", err)
+	}
+	version, err := parseQemuVersion(rawVersion)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the version of Qemu: %s", err)
+	}
+	b.config.fallBackAccelerator(version)
+	if errs := b.config.checkQemuVersion(version); len(errs) > 0 {
+		return nil, &packer.MultiError{Errors: errs}
+	}
+
+	if b.config.acceleratorReason != "" {
+		ui.Say(fmt.Sprintf("Using the %s accelerator, as %s",
+			b.config.Accelerator, b.config.acceleratorReason))
+	}
+
 	steprun := &stepRun{}
 	if !b.config.DiskImage {
 		steprun.BootDrive = "once=d"
@@ -371,36 +621,73 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		steprun.Message = "Starting VM, booting disk image"
 	}
 
+	// The address of the guest can only be looked up when it is attached
+	// to a network of the host. In user mode networking only the forwarded
+	// SSH port is reported.
+	reportAddress := &common.StepReportGuestAddress{SSHEndpoint: sshEndpoint}
+	if b.config.NetMode != "user" {
+		reportAddress.GuestIP = guestAddress
+	}
+
+	var isoStep multistep.Step = &common.StepDownload{
+		Checksum:     b.config.ISOChecksum,
+		ChecksumType: b.config.ISOChecksumType,
+		Description:  "ISO",
+		ResultKey:    "iso_path",
+		Url:          b.config.ISOUrls,
+	}
+	if b.config.ISOSkipCache {
+		isoStep = new(stepStreamISO)
+	}
+
 	steps := []multistep.Step{
-		&common.StepDownload{
-			Checksum:     b.config.ISOChecksum,
-			ChecksumType: b.config.ISOChecksumType,
-			Description:  "ISO",
-			ResultKey:    "iso_path",
-			Url:          b.config.ISOUrls,
-		},
+		new(stepCleanOrphans),
+		isoStep,
+	}
+	for i, iso := range b.config.AdditionalISOs {
+		steps = append(steps, &common.StepDownload{
+			Checksum:     iso.Checksum,
+			ChecksumType: iso.ChecksumType,
+			Description:  fmt.Sprintf("additional ISO %d", i+1),
+			ResultKey:    additionalISOKey(i),
+			Url:          iso.URLs,
+		})
+	}
+	steps = append(steps,
 		new(stepPrepareOutputDir),
 		&common.StepCreateFloppy{
-			Files: b.config.FloppyFiles,
+			Files:   b.config.FloppyFiles,
+			WorkDir: b.config.PackerWorkDir,
 		},
+		new(stepCreateSeedISO),
 		new(stepCreateDisk),
 		new(stepCopyDisk),
 		new(stepResizeDisk),
+		new(stepCopyEFIVars),
 		new(stepHTTPServer),
 		new(stepForwardSSH),
+		new(stepForwardPorts),
 		new(stepConfigureVNC),
+		new(stepStartTPM),
 		steprun,
+		new(stepMonitorUsage),
+		reportAddress,
 		&stepBootWait{},
 		&stepTypeBootCommand{},
-		&communicator.StepConnect{
-			Config:    &b.config.Comm,
-			Host:      commHost,
-			SSHConfig: sshConfig,
-			SSHPort:   commPort,
+		&stepBootFailureScreenshot{
+			Step: &communicator.StepConnect{
+				Config:    &b.config.Comm,
+				Host:      commHost,
+				SSHConfig: sshConfig,
+				SSHPort:   commPort,
+			},
 		},
 		new(common.StepProvision),
 		new(stepShutdown),
-	}
+		new(stepRebaseDisk),
+		new(stepCompactDisk),
+		new(stepConvertDisk),
+	)
 
 	// Setup the state bag
 	state := new(multistep.BasicStateBag)
@@ -408,6 +695,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	state.Put("config", &b.config)
 	state.Put("driver", driver)
 	state.Put("hook", hook)
+	state.Put("qemu_version", version)
 	state.Put("ui", ui)
 
 	// Run
@@ -417,7 +705,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 
 	b.runner.Run(state)
@@ -436,6 +726,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
+	// Nothing that the build wrote is kept, so there is no artifact
+	if b.config.Ephemeral {
+		ui.Say("Deleting output directory, as the build is ephemeral...")
+		if err := os.RemoveAll(b.config.OutputDir); err != nil {
+			return nil, fmt.Errorf("Error deleting output directory: %s", err)
+		}
+
+		return nil, nil
+	}
+
 	// Compile the artifact list
 	files := make([]string, 0, 5)
 	visit := func(path string, info os.FileInfo, err error) error {
@@ -451,19 +751,102 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 
 	artifact := &Artifact{
-		dir:   b.config.OutputDir,
-		f:     files,
-		state: make(map[string]interface{}),
+		dir:     b.config.OutputDir,
+		f:       files,
+		formats: b.config.Formats,
+		state:   make(map[string]interface{}),
 	}
 
 	artifact.state["diskName"] = state.Get("disk_filename").(string)
-	artifact.state["diskType"] = b.config.Format
-	artifact.state["diskSize"] = uint64(b.config.DiskSize)
+	imageFiles := []string{
+		filepath.Join(b.config.OutputDir, state.Get("disk_filename").(string)),
+	}
+	for i := range b.config.AdditionalDiskSize {
+		imageFiles = append(imageFiles,
+			filepath.Join(b.config.OutputDir, b.config.additionalDiskName(i)))
+	}
+	if converted, ok := state.GetOk("converted_disks"); ok {
+		imageFiles = append(imageFiles, converted.([]string)...)
+	}
+	artifact.state[common.ArtifactStateImageFiles] = imageFiles
+	artifact.state["diskType"] = b.config.format
+	diskSize := b.config.DiskSize
+	if size, ok := state.GetOk("disk_size"); ok {
+		diskSize = size.(uint)
+	}
+	artifact.state["diskSize"] = uint64(diskSize)
 	artifact.state["domainType"] = b.config.Accelerator
 
 	return artifact, nil
 }
 
+// qemuArgsWarnings looks for qemuargs that override the builder's defaults
+// in ways that are known to leave the VM unreachable or the artifact empty.
+func qemuArgsWarnings(c *Config) []string {
+	args := make(map[string][]string)
+	for _, row := range c.QemuArgs {
+		if len(row) > 0 {
+			args[row[0]] = append(args[row[0]], strings.Join(row[1:], ""))
+		}
+	}
+
+	var warnings []string
+	if netdevs, ok := args["-netdev"]; ok && c.Comm.Type == "ssh" && c.NetMode == "user" {
+		hostfwd := false
+		for _, v := range netdevs {
+			if strings.Contains(v, "hostfwd=") {
+				hostfwd = true
+				if !strings.Contains(v, ".SSHHostPort") &&
+					c.SSHHostPortMin != c.SSHHostPortMax {
+					warnings = append(warnings, fmt.Sprintf(
+						"The -netdev qemuarg forwards a fixed port, but the SSH port\n"+
+							"is chosen from %d-%d. Use {{ .SSHHostPort }} in hostfwd so\n"+
+							"Packer can connect to the VM.",
+						c.SSHHostPortMin, c.SSHHostPortMax))
+				}
+			}
+		}
+
+		if !hostfwd {
+			warnings = append(warnings,
+				"The -netdev qemuarg replaces the default network without a hostfwd\n"+
+					"for SSH, so Packer will not be able to connect to the VM. Add\n"+
+					"hostfwd=tcp::{{ .SSHHostPort }}-:22 to the -netdev value.")
+		}
+	}
+
+	if len(args["-m"]) > 1 {
+		warnings = append(warnings, fmt.Sprintf(
+			"The -m qemuarg is given %d times. Only the last one is used by Qemu.",
+			len(args["-m"])))
+	}
+
+	if drives, ok := args["-drive"]; ok {
+		found := false
+		for _, v := range drives {
+			if strings.Contains(v, ".OutputDir") ||
+				strings.Contains(v, c.OutputDir) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			warnings = append(warnings, fmt.Sprintf(
+				"None of the -drive qemuargs use a file in the output directory\n"+
+					"'%s'. The disk created by Packer won't be attached, and the\n"+
+					"artifact will not contain the installed system.",
+				c.OutputDir))
+		}
+	}
+
+	return warnings
+}
+
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (b *Builder) Cancel() {
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
@@ -471,7 +854,7 @@ func (b *Builder) Cancel() {
 	}
 }
 
-func (b *Builder) newDriver(qemuBinary string) (Driver, error) {
+func (b *Builder) newDriver(qemuBinary string, limits *ResourceLimits, logPath string) (Driver, error) {
 	qemuPath, err := exec.LookPath(qemuBinary)
 	if err != nil {
 		return nil, err
@@ -486,6 +869,10 @@ func (b *Builder) newDriver(qemuBinary string) (Driver, error) {
 	driver := &QemuDriver{
 		QemuPath:    qemuPath,
 		QemuImgPath: qemuImgPath,
+		LogPath:     logPath,
+	}
+	if limits.enabled() {
+		driver.Limits = limits
 	}
 
 	if err := driver.Verify(); err != nil {
@@ -494,3 +881,48 @@ func (b *Builder) newDriver(qemuBinary string) (Driver, error) {
 
 	return driver, nil
 }
+
+// smpSockets returns the number of CPU sockets of the VM, which is one
+// unless sockets is set.
+func (c *Config) smpSockets() uint {
+	if c.Sockets == 0 {
+		return 1
+	}
+
+	return c.Sockets
+}
+
+// smpCores returns the number of cores of each CPU socket of the VM,
+// which is one unless cores is set.
+func (c *Config) smpCores() uint {
+	if c.Cores == 0 {
+		return 1
+	}
+
+	return c.Cores
+}
+
+// smpArg returns the value of the -smp argument of the VM. The topology
+// is only given if it was set, so that Qemu picks it otherwise.
+func (c *Config) smpArg() string {
+	arg := fmt.Sprintf("cpus=%d", c.CPUs)
+	if c.Sockets != 0 {
+		arg += fmt.Sprintf(",sockets=%d", c.Sockets)
+	}
+	if c.Cores != 0 {
+		arg += fmt.Sprintf(",cores=%d", c.Cores)
+	}
+
+	return arg
+}
+
+// qemuArgsHas returns true if the user set the given argument in qemuargs.
+func qemuArgsHas(args [][]string, name string) bool {
+	for _, arg := range args {
+		if len(arg) > 0 && arg[0] == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -87,8 +88,8 @@ func TestBuilderPrepare_Defaults(t *testing.T) {
 		t.Errorf("bad vm name: %s", b.config.VMName)
 	}
 
-	if b.config.Format != "qcow2" {
-		t.Errorf("bad format: %s", b.config.Format)
+	if b.config.format != "qcow2" {
+		t.Errorf("bad format: %s", b.config.format)
 	}
 }
 
@@ -162,6 +163,132 @@ func TestBuilderPrepare_DiskSize(t *testing.T) {
 	if b.config.DiskSize != 60000 {
 		t.Fatalf("bad size: %d", b.config.DiskSize)
 	}
+
+	// Disk images are grown to the default too
+	delete(config, "disk_size")
+	config["disk_image"] = true
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.DiskSize != 40000 {
+		t.Fatalf("bad size: %d", b.config.DiskSize)
+	}
+}
+
+func TestBuilderPrepare_SkipResizeDisk(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Requires disk_image
+	config["skip_resize_disk"] = true
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["disk_image"] = true
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// The size of the image is kept, so it can't be set
+	config["disk_size"] = 60000
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Memory(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test the default
+	delete(config, "memory")
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Memory != 512 {
+		t.Fatalf("bad memory: %d", b.config.Memory)
+	}
+
+	// Test with a good one
+	config["memory"] = 2048
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Memory != 2048 {
+		t.Fatalf("bad memory: %d", b.config.Memory)
+	}
+
+	// Test with too little
+	config["memory"] = 64
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_SMP(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test the default
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.CPUs != 1 || b.config.smpArg() != "cpus=1" {
+		t.Fatalf("bad: %s", b.config.smpArg())
+	}
+
+	// The count of CPUs follows from the topology
+	config["sockets"] = 2
+	config["cores"] = 4
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if v := b.config.smpArg(); v != "cpus=8,sockets=2,cores=4" {
+		t.Fatalf("bad: %s", v)
+	}
+
+	// The count of CPUs must match the topology
+	config["cpus"] = 4
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	delete(config, "sockets")
+	delete(config, "cores")
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if v := b.config.smpArg(); v != "cpus=4" {
+		t.Fatalf("bad: %s", v)
+	}
 }
 
 func TestBuilderPrepare_HTTPPort(t *testing.T) {
@@ -512,7 +639,7 @@ func TestBuilderPrepare_sshKeyPath(t *testing.T) {
 	config["ssh_key_path"] = "/i/dont/exist"
 	b = Builder{}
 	warns, err = b.Prepare(config)
-	if len(warns) > 0 {
+	if len(warns) != 1 || !strings.Contains(warns[0], "ssh_private_key_file") {
 		t.Fatalf("bad: %#v", warns)
 	}
 	if err == nil {
@@ -534,7 +661,7 @@ func TestBuilderPrepare_sshKeyPath(t *testing.T) {
 	config["ssh_key_path"] = tf.Name()
 	b = Builder{}
 	warns, err = b.Prepare(config)
-	if len(warns) > 0 {
+	if len(warns) != 1 || !strings.Contains(warns[0], "ssh_private_key_file") {
 		t.Fatalf("bad: %#v", warns)
 	}
 	if err == nil {
@@ -548,7 +675,7 @@ func TestBuilderPrepare_sshKeyPath(t *testing.T) {
 	config["ssh_key_path"] = tf.Name()
 	b = Builder{}
 	warns, err = b.Prepare(config)
-	if len(warns) > 0 {
+	if len(warns) != 1 || !strings.Contains(warns[0], "ssh_private_key_file") {
 		t.Fatalf("bad: %#v", warns)
 	}
 	if err != nil {
@@ -606,11 +733,11 @@ func TestBuilderPrepare_SSHWaitTimeout(t *testing.T) {
 		t.Fatal("should have error")
 	}
 
-	// Test with a good one
+	// Test with a good one, which is deprecated
 	config["ssh_wait_timeout"] = "5s"
 	b = Builder{}
 	warns, err = b.Prepare(config)
-	if len(warns) > 0 {
+	if len(warns) != 1 || !strings.Contains(warns[0], "ssh_timeout") {
 		t.Fatalf("bad: %#v", warns)
 	}
 	if err != nil {
@@ -618,6 +745,34 @@ func TestBuilderPrepare_SSHWaitTimeout(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_Headless(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Headless without a VNC configuration is risky
+	config["headless"] = true
+	warns, err := b.Prepare(config)
+	if len(warns) != 1 || !strings.Contains(warns[0], "password") {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Setting -vnc is up to the user
+	config["qemuargs"] = [][]interface{}{
+		[]interface{}{"-vnc", "127.0.0.1:0"},
+	}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
 func TestBuilderPrepare_QemuArgs(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -658,3 +813,218 @@ func TestBuilderPrepare_QemuArgs(t *testing.T) {
 		t.Fatalf("bad: %#v", b.config.QemuArgs)
 	}
 }
+
+func TestBuilderPrepare_QemuArgsWarnings(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// -netdev without a hostfwd
+	config["qemuargs"] = [][]interface{}{
+		[]interface{}{"-netdev", "user,id=mynet0"},
+	}
+	warns, err := b.Prepare(config)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// -netdev forwarding the SSH host port
+	config["qemuargs"] = [][]interface{}{
+		[]interface{}{"-netdev", "user,id=mynet0,",
+			"hostfwd=tcp::{{ .SSHHostPort }}-:22"},
+	}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Duplicate -m
+	config["qemuargs"] = [][]interface{}{
+		[]interface{}{"-m", "1024M"},
+		[]interface{}{"-m", "2048M"},
+	}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// -drive outside of the output directory
+	config["qemuargs"] = [][]interface{}{
+		[]interface{}{"-drive", "file=/tmp/other.qcow2,if=virtio"},
+	}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["qemuargs"] = [][]interface{}{
+		[]interface{}{"-drive", "file={{ .OutputDir }}/{{ .Name }},if=virtio"},
+	}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_NetDevices(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test the default
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if len(b.config.NetDevices) != 1 || b.config.NetDevices[0].Type != "virtio-net" {
+		t.Fatalf("bad: %#v", b.config.NetDevices)
+	}
+
+	// Test with a good one
+	config["net_devices"] = []map[string]interface{}{
+		{"type": "e1000"},
+		{"type": "virtio-net", "options": map[string]string{"mac": "52:54:00:12:34:56"}},
+	}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if len(b.config.NetDevices) != 2 {
+		t.Fatalf("bad: %#v", b.config.NetDevices)
+	}
+
+	// Test with a bad type
+	config["net_devices"] = []map[string]interface{}{
+		{"type": "bad"},
+	}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with net_device also set
+	config["net_devices"] = []map[string]interface{}{
+		{"type": "e1000"},
+	}
+	config["net_device"] = "e1000"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_NetIPv6(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with a good prefix and DNS server
+	config["net_ipv6"] = true
+	config["net_ipv6_prefix"] = "fd00::/64"
+	config["net_ipv6_dns"] = "fd00::3"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test without net_ipv6
+	config["net_ipv6"] = false
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with an IPv4 prefix
+	config["net_ipv6"] = true
+	config["net_ipv6_prefix"] = "10.0.2.0/24"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a bad DNS server
+	config["net_ipv6_prefix"] = "fd00::/64"
+	config["net_ipv6_dns"] = "bad"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Drives(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with a good one
+	config["drives"] = []map[string]interface{}{
+		{"type": "scsi", "options": map[string]string{"file": "data.qcow2"}},
+	}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test without a file
+	config["drives"] = []map[string]interface{}{
+		{"type": "scsi"},
+	}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,97 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// QemuDevice is a structured device used to render -device and -drive
+// arguments. Options are rendered as comma separated key=value pairs
+// after the type.
+type QemuDevice struct {
+	Type    string            `mapstructure:"type"`
+	Options map[string]string `mapstructure:"options"`
+}
+
+// render returns the value of the argument, beginning with prefix, which
+// is usually the device type, followed by the options in sorted order so
+// that the command line is stable.
+func (d *QemuDevice) render(prefix string, extra ...string) string {
+	keys := make([]string, 0, len(d.Options))
+	for k := range d.Options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys)+len(extra)+1)
+	parts = append(parts, prefix)
+	parts = append(parts, extra...)
+	for _, k := range keys {
+		if v := d.Options[k]; v != "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", k, v))
+		} else {
+			parts = append(parts, k)
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// DeviceArg renders the device as the value of a -device argument.
+func (d *QemuDevice) DeviceArg(extra ...string) string {
+	return d.render(d.Type, extra...)
+}
+
+// DriveArg renders the device as the value of a -drive argument, using
+// the type as the drive interface.
+func (d *QemuDevice) DriveArg() string {
+	return d.render(fmt.Sprintf("if=%s", d.Type))
+}
+
+func (c *Config) prepareDevices() []error {
+	var errs []error
+
+	if c.NetDevice != "" && len(c.NetDevices) > 0 {
+		errs = append(errs,
+			errors.New("only one of net_device or net_devices may be specified"))
+	}
+
+	if c.NetDevice == "" {
+		c.NetDevice = "virtio-net"
+	}
+
+	if len(c.NetDevices) == 0 {
+		c.NetDevices = []QemuDevice{{Type: c.NetDevice}}
+	}
+
+	for i, d := range c.NetDevices {
+		if _, ok := netDevice[d.Type]; !ok {
+			errs = append(errs, fmt.Errorf(
+				"net_devices[%d]: unrecognized network device type: %s", i, d.Type))
+		}
+		if _, ok := d.Options["netdev"]; ok {
+			errs = append(errs, fmt.Errorf(
+				"net_devices[%d]: netdev is set by Packer and can't be an option", i))
+		}
+	}
+
+	for i, d := range c.Devices {
+		if d.Type == "" {
+			errs = append(errs, fmt.Errorf("devices[%d]: type must be specified", i))
+		}
+	}
+
+	for i, d := range c.Drives {
+		if _, ok := diskInterface[d.Type]; !ok && d.Type != "none" {
+			errs = append(errs, fmt.Errorf(
+				"drives[%d]: unrecognized disk interface type: %s", i, d.Type))
+		}
+		if d.Options["file"] == "" {
+			errs = append(errs, fmt.Errorf("drives[%d]: file option must be specified", i))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,40 @@
+package qemu
+
+import (
+	"testing"
+)
+
+func TestQemuDevice_DeviceArg(t *testing.T) {
+	d := &QemuDevice{
+		Type: "e1000",
+		Options: map[string]string{
+			"mac":       "52:54:00:12:34:56",
+			"bootindex": "1",
+		},
+	}
+
+	expected := "e1000,netdev=user.1,bootindex=1,mac=52:54:00:12:34:56"
+	if actual := d.DeviceArg("netdev=user.1"); actual != expected {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	d = &QemuDevice{Type: "virtio-scsi-pci"}
+	if actual := d.DeviceArg(); actual != "virtio-scsi-pci" {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
+func TestQemuDevice_DriveArg(t *testing.T) {
+	d := &QemuDevice{
+		Type: "scsi",
+		Options: map[string]string{
+			"file":     "data.qcow2",
+			"readonly": "",
+		},
+	}
+
+	expected := "if=scsi,file=data.qcow2,readonly"
+	if actual := d.DriveArg(); actual != expected {
+		t.Fatalf("bad: %s", actual)
+	}
+}
@@ -0,0 +1,139 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/packer/common"
+)
+
+// additionalDiskName returns the file name of the additional disk with
+// the given index, such as "packer-vm-1.qcow2" for the first.
+func (c *Config) additionalDiskName(i int) string {
+	return common.AdditionalDiskName(c.VMName, i, strings.ToLower(c.format))
+}
+
+// prepareAdditionalDisks validates the additional disks, filling in the
+// interface and cache mode of the main disk for those that have none.
+func (c *Config) prepareAdditionalDisks() []error {
+	var errs []error
+
+	n := len(c.AdditionalDiskSize)
+	if len(c.AdditionalDiskInterface) > n || len(c.AdditionalDiskCache) > n {
+		errs = append(errs, errors.New(
+			"additional_disk_interface and additional_disk_cache can't have more\n"+
+				"entries than additional_disk_size"))
+	}
+
+	for i, size := range c.AdditionalDiskSize {
+		if size == 0 {
+			errs = append(errs, fmt.Errorf(
+				"additional_disk_size[%d]: size must be greater than zero", i))
+		}
+	}
+
+	for len(c.AdditionalDiskInterface) < n {
+		c.AdditionalDiskInterface = append(c.AdditionalDiskInterface, c.DiskInterface)
+	}
+	for len(c.AdditionalDiskCache) < n {
+		c.AdditionalDiskCache = append(c.AdditionalDiskCache, c.DiskCache)
+	}
+
+	for i, v := range c.AdditionalDiskInterface {
+		if _, ok := diskInterface[v]; !ok {
+			errs = append(errs, fmt.Errorf(
+				"additional_disk_interface[%d]: unrecognized disk interface type: %s", i, v))
+		}
+	}
+	for i, v := range c.AdditionalDiskCache {
+		if _, ok := diskCache[v]; !ok {
+			errs = append(errs, fmt.Errorf(
+				"additional_disk_cache[%d]: unrecognized disk cache type: %s", i, v))
+		}
+	}
+
+	return errs
+}
+
+// prepareBackingFile validates the use of the disk image as the backing
+// file of the disk rather than copying it.
+func (c *Config) prepareBackingFile() []error {
+	var errs []error
+
+	if c.UseBackingFile {
+		if !c.DiskImage {
+			errs = append(errs, errors.New("use_backing_file requires disk_image"))
+		}
+		if c.format != "qcow2" {
+			errs = append(errs, errors.New("use_backing_file requires the qcow2 format"))
+		}
+	}
+
+	if c.StandaloneDisk && !c.UseBackingFile {
+		errs = append(errs, errors.New("standalone_disk requires use_backing_file"))
+	}
+
+	return errs
+}
+
+// prepareEphemeral validates running the VM on the disk image itself with
+// -snapshot, which throws away what is written to it.
+func (c *Config) prepareEphemeral() []error {
+	if !c.Ephemeral {
+		return nil
+	}
+
+	var errs []error
+
+	if !c.DiskImage {
+		errs = append(errs, errors.New("ephemeral requires disk_image"))
+	}
+	if c.UseBackingFile {
+		errs = append(errs, errors.New("ephemeral can't be used with use_backing_file"))
+	}
+	if c.diskSizeSet {
+		errs = append(errs, errors.New("ephemeral can't be used with disk_size, "+
+			"since the disk image isn't copied"))
+	}
+	if c.Compression {
+		errs = append(errs, errors.New("ephemeral can't be used with compression"))
+	}
+
+	return errs
+}
+
+// prepareSkipResizeDisk validates keeping the size of the disk image
+// instead of growing it to disk_size.
+func (c *Config) prepareSkipResizeDisk() []error {
+	if !c.SkipResizeDisk {
+		return nil
+	}
+
+	var errs []error
+
+	if !c.DiskImage {
+		errs = append(errs, errors.New("skip_resize_disk requires disk_image"))
+	}
+	if c.diskSizeSet {
+		errs = append(errs, errors.New("skip_resize_disk can't be used with disk_size"))
+	}
+
+	return errs
+}
+
+// prepareCompaction validates the compaction of the disks after the build.
+func (c *Config) prepareCompaction() []error {
+	var errs []error
+
+	if c.Compression {
+		if c.SkipCompaction {
+			errs = append(errs, errors.New("compression can't be used with skip_compaction"))
+		}
+		if c.format != "qcow2" {
+			errs = append(errs, errors.New("compression requires the qcow2 format"))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,88 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+var diskAIO = map[string]bool{
+	"threads":  true,
+	"native":   true,
+	"io_uring": true,
+}
+
+var diskDetectZeroes = map[string]bool{
+	"off":   true,
+	"on":    true,
+	"unmap": true,
+}
+
+// prepareDiskIO validates the options that tune the I/O of the main disk.
+func (c *Config) prepareDiskIO() []error {
+	var errs []error
+
+	if c.DiskAIO != "" {
+		if !diskAIO[c.DiskAIO] {
+			errs = append(errs, errors.New(
+				"disk_aio must be one of 'threads', 'native' or 'io_uring'"))
+		}
+
+		// Qemu refuses native AIO unless the page cache is bypassed
+		if c.DiskAIO == "native" && c.DiskCache != "none" && c.DiskCache != "directsync" {
+			errs = append(errs, errors.New(
+				"disk_aio 'native' requires disk_cache 'none' or 'directsync'"))
+		}
+
+		if c.DiskAIO == "io_uring" && runtime.GOOS != "linux" {
+			errs = append(errs, fmt.Errorf(
+				"disk_aio 'io_uring' is only supported on Linux hosts, not %s", runtime.GOOS))
+		}
+	}
+
+	if c.DiskDetectZeroes != "" {
+		if !diskDetectZeroes[c.DiskDetectZeroes] {
+			errs = append(errs, errors.New(
+				"disk_detect_zeroes must be one of 'off', 'on' or 'unmap'"))
+		}
+
+		if c.DiskDetectZeroes == "unmap" && c.DiskDiscard != "unmap" {
+			errs = append(errs, errors.New(
+				"disk_detect_zeroes 'unmap' requires disk_discard 'unmap'"))
+		}
+	}
+
+	if c.DiskIOThread && c.DiskInterface != "virtio" {
+		errs = append(errs, errors.New(
+			"disk_iothread requires disk_interface 'virtio'"))
+	}
+
+	return errs
+}
+
+// mainDriveArgs returns the arguments that attach the main disk at path.
+// With an I/O thread, the drive has no interface of its own and is
+// attached to a virtio-blk device that the thread serves.
+func (c *Config) mainDriveArgs(path, discard string) map[string][]string {
+	tuning := discard
+	if c.DiskAIO != "" {
+		tuning += ",aio=" + c.DiskAIO
+	}
+	if c.DiskDetectZeroes != "" {
+		tuning += ",detect-zeroes=" + c.DiskDetectZeroes
+	}
+
+	if !c.DiskIOThread {
+		return map[string][]string{
+			"-drive": {fmt.Sprintf("file=%s,if=%s,cache=%s%s",
+				path, c.DiskInterface, c.DiskCache, tuning)},
+		}
+	}
+
+	return map[string][]string{
+		"-object": {"iothread,id=iothread0"},
+		"-drive": {fmt.Sprintf("file=%s,if=none,id=drive0,cache=%s%s",
+			path, c.DiskCache, tuning)},
+		"-device": {"virtio-blk,drive=drive0,iothread=iothread0"},
+	}
+}
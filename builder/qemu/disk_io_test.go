@@ -0,0 +1,86 @@
+package qemu
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestBuilderPrepare_DiskIO(t *testing.T) {
+	cases := []struct {
+		Config map[string]interface{}
+		Err    bool
+	}{
+		{map[string]interface{}{}, false},
+
+		{map[string]interface{}{"disk_aio": "threads"}, false},
+		{map[string]interface{}{"disk_aio": "bogus"}, true},
+
+		// Native AIO bypasses the page cache
+		{map[string]interface{}{"disk_aio": "native"}, true},
+		{map[string]interface{}{"disk_aio": "native", "disk_cache": "none"}, false},
+		{map[string]interface{}{"disk_aio": "native", "disk_cache": "directsync"}, false},
+
+		{map[string]interface{}{"disk_aio": "io_uring"}, runtime.GOOS != "linux"},
+
+		{map[string]interface{}{"disk_detect_zeroes": "on"}, false},
+		{map[string]interface{}{"disk_detect_zeroes": "bogus"}, true},
+		{map[string]interface{}{"disk_detect_zeroes": "unmap"}, true},
+		{map[string]interface{}{"disk_detect_zeroes": "unmap", "disk_discard": "unmap"}, false},
+
+		{map[string]interface{}{"disk_iothread": true}, false},
+		{map[string]interface{}{"disk_iothread": true, "disk_interface": "ide"}, true},
+	}
+
+	for _, tc := range cases {
+		var b Builder
+		config := testConfig()
+		for k, v := range tc.Config {
+			config[k] = v
+		}
+
+		_, err := b.Prepare(config)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%#v: bad error: %s", tc.Config, err)
+		}
+	}
+}
+
+func TestConfigMainDriveArgs(t *testing.T) {
+	c := &Config{DiskInterface: "virtio", DiskCache: "writeback"}
+	expected := map[string][]string{
+		"-drive": {"file=foo.qcow2,if=virtio,cache=writeback,discard=ignore"},
+	}
+	if args := c.mainDriveArgs("foo.qcow2", ",discard=ignore"); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	c = &Config{
+		DiskInterface:    "virtio",
+		DiskCache:        "none",
+		DiskAIO:          "native",
+		DiskDetectZeroes: "unmap",
+	}
+	expected = map[string][]string{
+		"-drive": {"file=foo.qcow2,if=virtio,cache=none,discard=unmap,aio=native,detect-zeroes=unmap"},
+	}
+	if args := c.mainDriveArgs("foo.qcow2", ",discard=unmap"); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	// The drive is attached to a device that its I/O thread serves
+	c = &Config{
+		DiskInterface: "virtio",
+		DiskCache:     "none",
+		DiskAIO:       "io_uring",
+		DiskIOThread:  true,
+	}
+	expected = map[string][]string{
+		"-object": {"iothread,id=iothread0"},
+		"-drive":  {"file=foo.qcow2,if=none,id=drive0,cache=none,aio=io_uring"},
+		"-device": {"virtio-blk,drive=drive0,iothread=iothread0"},
+	}
+	if args := c.mainDriveArgs("foo.qcow2", ""); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
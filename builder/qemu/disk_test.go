@@ -0,0 +1,363 @@
+package qemu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestBuilderPrepare_AdditionalDisks(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// The interface and cache default to those of the main disk
+	config["additional_disk_size"] = []uint{1000, 2000}
+	config["additional_disk_interface"] = []string{"ide"}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	expected := []string{"ide", "virtio"}
+	if !reflect.DeepEqual(b.config.AdditionalDiskInterface, expected) {
+		t.Fatalf("bad: %#v", b.config.AdditionalDiskInterface)
+	}
+	expected = []string{"writeback", "writeback"}
+	if !reflect.DeepEqual(b.config.AdditionalDiskCache, expected) {
+		t.Fatalf("bad: %#v", b.config.AdditionalDiskCache)
+	}
+	if name := b.config.additionalDiskName(1); name != "packer-foo-2.qcow2" {
+		t.Fatalf("bad: %s", name)
+	}
+
+	// Bad interface
+	config["additional_disk_interface"] = []string{"bad"}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad cache
+	delete(config, "additional_disk_interface")
+	config["additional_disk_cache"] = []string{"bad"}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// More options than disks
+	config["additional_disk_cache"] = []string{"none", "none", "none"}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Zero size
+	delete(config, "additional_disk_cache")
+	config["additional_disk_size"] = []uint{0}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_UseBackingFile(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Requires disk_image
+	config["use_backing_file"] = true
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Without standalone_disk the artifact depends on the image
+	config["disk_image"] = true
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+
+	config["standalone_disk"] = true
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+
+	// Requires qcow2
+	config["format"] = "raw"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// standalone_disk requires use_backing_file
+	config["format"] = "qcow2"
+	config["use_backing_file"] = false
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepCopyDisk_backingFile(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	argsPath := filepath.Join(td, "args")
+	path := testFakeQemuImg(t, `echo "$@" > `+argsPath)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("driver", &QemuDriver{QemuImgPath: path})
+	state.Put("iso_path", "/images/base.qcow2")
+	state.Put("config", &Config{
+		DiskImage:      true,
+		format:         "qcow2",
+		OutputDir:      td,
+		UseBackingFile: true,
+		VMName:         "foo",
+	})
+
+	step := new(stepCopyDisk)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+
+	data, err := ioutil.ReadFile(argsPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := fmt.Sprintf(
+		"create -f qcow2 -b /images/base.qcow2 -F qcow2 %s\n", filepath.Join(td, "foo.qcow2"))
+	if string(data) != expected {
+		t.Fatalf("bad: %q", data)
+	}
+	if v := state.Get("disk_filename"); v != "foo.qcow2" {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestStepResizeDisk(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// The fake image is 2 GB and records how it is resized
+	argsPath := filepath.Join(td, "args")
+	path := testFakeQemuImg(t, `
+if [ "$1" = "info" ]; then
+	echo '{"virtual-size": 2147483648, "format": "qcow2"}'
+else
+	echo "$@" > `+argsPath+`
+fi`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	cases := []struct {
+		DiskSize uint
+		Skip     bool
+		Action   multistep.StepAction
+		Args     string
+		Size     uint
+	}{
+		// The size of the image is kept
+		{40000, true, multistep.ActionContinue, "", 2048},
+		{2048, false, multistep.ActionContinue, "", 2048},
+
+		// Images grow
+		{10000, false, multistep.ActionContinue,
+			fmt.Sprintf("resize -f qcow2 %s 10000M\n", filepath.Join(td, "foo.qcow2")), 10000},
+
+		// But don't shrink
+		{1024, false, multistep.ActionHalt, "", 0},
+	}
+
+	for _, tc := range cases {
+		os.Remove(argsPath)
+
+		state := new(multistep.BasicStateBag)
+		state.Put("ui", packer.TestUi(t))
+		state.Put("driver", &QemuDriver{QemuImgPath: path})
+		state.Put("config", &Config{
+			DiskImage:      true,
+			DiskSize:       tc.DiskSize,
+			SkipResizeDisk: tc.Skip,
+			format:         "qcow2",
+			OutputDir:      td,
+			VMName:         "foo",
+		})
+
+		step := new(stepResizeDisk)
+		if action := step.Run(state); action != tc.Action {
+			t.Fatalf("%d: bad action: %#v", tc.DiskSize, state.Get("error"))
+		}
+
+		data, _ := ioutil.ReadFile(argsPath)
+		if string(data) != tc.Args {
+			t.Fatalf("%d: bad: %q", tc.DiskSize, data)
+		}
+		if size, _ := state.GetOk("disk_size"); tc.Size != 0 && size != tc.Size {
+			t.Fatalf("%d: bad size: %#v", tc.DiskSize, size)
+		}
+	}
+}
+
+func TestBuilderPrepare_Ephemeral(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Requires disk_image
+	config["ephemeral"] = true
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["disk_image"] = true
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+
+	// The disk image isn't copied, so it can't be resized
+	config["disk_size"] = 20000
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	delete(config, "disk_size")
+	config["use_backing_file"] = true
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepCopyDisk_ephemeral(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("driver", &QemuDriver{QemuImgPath: "/nonexistent"})
+	state.Put("iso_path", "/images/base.qcow2")
+	state.Put("config", &Config{
+		DiskImage: true,
+		Ephemeral: true,
+		format:    "qcow2",
+		VMName:    "foo",
+	})
+
+	step := new(stepCopyDisk)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+	if _, ok := state.GetOk("disk_filename"); ok {
+		t.Fatal("should not copy the disk")
+	}
+}
+
+func TestBuilderPrepare_Compression(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["compression"] = true
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["format"] = "raw"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["format"] = "qcow2"
+	config["skip_compaction"] = true
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepCompactDisk(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	for _, name := range []string{"foo.qcow2", "foo-1.qcow2"} {
+		if err := ioutil.WriteFile(filepath.Join(td, name), []byte("zeros"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// The fake qemu-img records its arguments and writes the target
+	argsPath := filepath.Join(td, "args")
+	path := testFakeQemuImg(t, `echo "$@" >> `+argsPath+`
+eval "last=\${$#}"
+echo compact > "$last"`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("driver", &QemuDriver{QemuImgPath: path})
+	state.Put("disk_filename", "foo.qcow2")
+	state.Put("config", &Config{
+		AdditionalDiskSize: []uint{1024},
+		Compression:        true,
+		format:             "qcow2",
+		OutputDir:          td,
+		VMName:             "foo",
+	})
+
+	step := new(stepCompactDisk)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+
+	main := filepath.Join(td, "foo.qcow2")
+	additional := filepath.Join(td, "foo-1.qcow2")
+	expected := fmt.Sprintf("convert -p -O qcow2 -c %s %s.compact\n", main, main) +
+		fmt.Sprintf("convert -p -O qcow2 -c %s %s.compact\n", additional, additional)
+	data, _ := ioutil.ReadFile(argsPath)
+	if string(data) != expected {
+		t.Fatalf("bad: %q", data)
+	}
+
+	// The disks are replaced by the compacted ones
+	for _, p := range []string{main, additional} {
+		data, _ := ioutil.ReadFile(p)
+		if string(data) != "compact\n" {
+			t.Fatalf("%s: bad: %q", p, data)
+		}
+	}
+}
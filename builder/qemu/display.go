@@ -0,0 +1,280 @@
+package qemu
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// vncPasswordChars are the characters of generated VNC passwords.
+const vncPasswordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func (c *Config) prepareDisplay() []error {
+	if c.Display == "" {
+		c.Display = "vnc"
+	}
+
+	// The SPICE agent only runs once the guest is installed, so the boot
+	// command can't be typed over SPICE.
+	if c.BootCommandDriver == "" {
+		c.BootCommandDriver = "vnc"
+		if c.Display == "spice" {
+			c.BootCommandDriver = "qmp"
+		}
+	}
+
+	if c.SPICEPortMin == 0 {
+		c.SPICEPortMin = 5930
+	}
+
+	if c.SPICEPortMax == 0 {
+		c.SPICEPortMax = 6030
+	}
+
+	var errs []error
+	if c.Display != "vnc" && c.Display != "spice" {
+		errs = append(errs, fmt.Errorf("display must be 'vnc' or 'spice'"))
+	}
+
+	if c.SPICEPortMin > c.SPICEPortMax {
+		errs = append(errs, fmt.Errorf("spice_port_min must be less than spice_port_max"))
+	}
+
+	switch c.BootCommandDriver {
+	case "vnc":
+		if c.Display != "vnc" {
+			errs = append(errs, fmt.Errorf(
+				"boot_command_driver can only be 'vnc' with the vnc display"))
+		}
+	case "qmp":
+		if !qmpSupported {
+			errs = append(errs, fmt.Errorf(
+				"boot_command_driver 'qmp' is not supported on this platform"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("boot_command_driver must be 'vnc' or 'qmp'"))
+	}
+
+	if c.VNCBindAddress != "" && net.ParseIP(c.VNCBindAddress) == nil {
+		errs = append(errs, fmt.Errorf("vnc_bind_address must be an IP address"))
+	}
+
+	if c.SPICEPassword != "" {
+		if c.Display != "spice" {
+			errs = append(errs, fmt.Errorf("spice_password can only be used with the spice display"))
+		}
+
+		// The password is set through the QMP monitor once Qemu runs
+		if !qmpSupported {
+			errs = append(errs, fmt.Errorf("spice_password is not supported on this platform"))
+		}
+	}
+
+	if c.VNCUsePassword && c.Display != "vnc" {
+		errs = append(errs, fmt.Errorf("vnc_use_password can only be used with the vnc display"))
+	}
+
+	// Every build gets its own password, so that one build can't be
+	// controlled with the password of another.
+	if c.VNCUsePassword && c.VNCPassword == "" {
+		password, err := randomVNCPassword()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error generating VNC password: %s", err))
+		} else {
+			c.VNCPassword = password
+			c.vncPasswordGenerated = true
+		}
+	}
+
+	if c.VNCPassword != "" {
+		// The password is set through the QMP monitor once Qemu runs
+		if !qmpSupported {
+			errs = append(errs, fmt.Errorf("vnc_password is not supported on this platform"))
+		}
+
+		// VNC authentication ignores anything after eight characters
+		if len(c.VNCPassword) > 8 {
+			errs = append(errs, fmt.Errorf("vnc_password can be at most 8 characters"))
+		}
+	}
+
+	return errs
+}
+
+// randomVNCPassword returns a random password of the eight characters
+// that VNC authentication uses.
+func randomVNCPassword() (string, error) {
+	// Bytes past the last whole multiple of the characters are skipped
+	// so that every character is equally likely.
+	limit := 256 - 256%len(vncPasswordChars)
+
+	password := make([]byte, 0, 8)
+	buf := make([]byte, 16)
+	for len(password) < 8 {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+
+		for _, b := range buf {
+			if int(b) < limit && len(password) < 8 {
+				password = append(password, vncPasswordChars[int(b)%len(vncPasswordChars)])
+			}
+		}
+	}
+
+	return string(password), nil
+}
+
+// displayPortRange returns the range of host ports that the display
+// server of the VM can listen on.
+func (c *Config) displayPortRange() (uint, uint) {
+	if c.Display == "spice" {
+		return c.SPICEPortMin, c.SPICEPortMax
+	}
+
+	return c.VNCPortMin, c.VNCPortMax
+}
+
+// displayExposed returns true if the display of the VM can be reached by
+// anyone who can reach the host.
+func (c *Config) displayExposed() bool {
+	password := c.VNCPassword
+	if c.Display == "spice" {
+		password = c.SPICEPassword
+	}
+
+	if password != "" {
+		return false
+	}
+
+	ip := net.ParseIP(c.VNCBindAddress)
+	return ip == nil || ip.IsUnspecified()
+}
+
+// displayBindAddress returns the address that the VNC or SPICE server
+// listens on, which is vnc_bind_address, or all interfaces of the given
+// loopback address.
+func (c *Config) displayBindAddress(loopback string) string {
+	if c.VNCBindAddress != "" {
+		return c.VNCBindAddress
+	}
+
+	if loopback == "::1" {
+		return "::"
+	}
+
+	return "0.0.0.0"
+}
+
+// vncArg returns the value of the -vnc argument that starts a VNC server
+// on the port.
+func (c *Config) vncArg(loopback string, port uint) string {
+	addr := c.displayBindAddress(loopback)
+	arg := net.JoinHostPort(addr, strconv.Itoa(int(port-5900)))
+	if c.VNCPassword != "" {
+		arg += ",password=on"
+	}
+
+	return arg
+}
+
+// vncHost returns the address that the boot command connects to VNC on,
+// which is the loopback address unless VNC only listens on another.
+func (c *Config) vncHost(loopback string) string {
+	if ip := net.ParseIP(c.VNCBindAddress); ip != nil && !ip.IsUnspecified() {
+		return c.VNCBindAddress
+	}
+
+	return loopback
+}
+
+// spiceArg returns the value of the -spice argument that starts a SPICE
+// server on the port. With a password, SPICE refuses all clients until
+// the password is set.
+func (c *Config) spiceArg(loopback string, port uint) string {
+	arg := fmt.Sprintf("port=%d,addr=%s", port, c.displayBindAddress(loopback))
+	if c.SPICEPassword == "" {
+		arg += ",disable-ticketing=on"
+	}
+
+	return arg
+}
+
+// qcodes maps the X keysyms of the boot command to the names of keys in
+// QMP. Shifted characters map to the key that they are typed with.
+var qcodes = map[uint32]string{
+	0xFF08: "backspace",
+	0xFFFF: "delete",
+	0xFF0D: "ret",
+	0xFF1B: "esc",
+	0xFFBE: "f1",
+	0xFFBF: "f2",
+	0xFFC0: "f3",
+	0xFFC1: "f4",
+	0xFFC2: "f5",
+	0xFFC3: "f6",
+	0xFFC4: "f7",
+	0xFFC5: "f8",
+	0xFFC6: "f9",
+	0xFFC7: "f10",
+	0xFFC8: "f11",
+	0xFFC9: "f12",
+	0xFF09: "tab",
+	0xFF52: "up",
+	0xFF54: "down",
+	0xFF51: "left",
+	0xFF53: "right",
+	0xFF63: "insert",
+	0xFF50: "home",
+	0xFF57: "end",
+	0xFF55: "pgup",
+	0xFF56: "pgdn",
+	0xFFE1: "shift",
+	' ':    "spc",
+	'`':    "grave_accent",
+	'~':    "grave_accent",
+	'!':    "1",
+	'@':    "2",
+	'#':    "3",
+	'$':    "4",
+	'%':    "5",
+	'^':    "6",
+	'&':    "7",
+	'*':    "8",
+	'(':    "9",
+	')':    "0",
+	'-':    "minus",
+	'_':    "minus",
+	'=':    "equal",
+	'+':    "equal",
+	'[':    "bracket_left",
+	'{':    "bracket_left",
+	']':    "bracket_right",
+	'}':    "bracket_right",
+	'\\':   "backslash",
+	'|':    "backslash",
+	';':    "semicolon",
+	':':    "semicolon",
+	'\'':   "apostrophe",
+	'"':    "apostrophe",
+	',':    "comma",
+	'<':    "comma",
+	'.':    "dot",
+	'>':    "dot",
+	'/':    "slash",
+	'?':    "slash",
+}
+
+// qcode returns the name of the key in QMP that types the X keysym.
+func qcode(keysym uint32) (string, bool) {
+	switch {
+	case keysym >= 'a' && keysym <= 'z', keysym >= '0' && keysym <= '9':
+		return string(rune(keysym)), true
+	case keysym >= 'A' && keysym <= 'Z':
+		return string(rune(keysym - 'A' + 'a')), true
+	}
+
+	name, ok := qcodes[keysym]
+	return name, ok
+}
@@ -0,0 +1,298 @@
+package qemu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuilderPrepare_Display(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test the default
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.Display != "vnc" {
+		t.Fatalf("bad: %s", b.config.Display)
+	}
+	if min, max := b.config.displayPortRange(); min != 5900 || max != 6000 {
+		t.Fatalf("bad: %d %d", min, max)
+	}
+
+	config["display"] = "spice"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if min, max := b.config.displayPortRange(); min != 5930 || max != 6030 {
+		t.Fatalf("bad: %d %d", min, max)
+	}
+
+	// Headless warns about SPICE rather than VNC
+	config["headless"] = true
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) != 1 || !strings.Contains(warns[0], "spice_password") {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["display"] = "sdl"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["display"] = "spice"
+	config["spice_port_min"] = 6000
+	config["spice_port_max"] = 5900
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_BootCommandDriver(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test the default
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.BootCommandDriver != "vnc" {
+		t.Fatalf("bad: %s", b.config.BootCommandDriver)
+	}
+
+	// SPICE can only type through QMP
+	config["display"] = "spice"
+	b = Builder{}
+	_, err := b.Prepare(config)
+	if qmpSupported {
+		if err != nil {
+			t.Fatalf("should not have error: %s", err)
+		}
+		if b.config.BootCommandDriver != "qmp" {
+			t.Fatalf("bad: %s", b.config.BootCommandDriver)
+		}
+	}
+
+	config["boot_command_driver"] = "vnc"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	delete(config, "display")
+	config["boot_command_driver"] = "qmp"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if qmpSupported && err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !qmpSupported && err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["boot_command_driver"] = "serial"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestQcode(t *testing.T) {
+	cases := map[uint32]string{
+		'a':          "a",
+		'Q':          "q",
+		'7':          "7",
+		'&':          "7",
+		' ':          "spc",
+		'?':          "slash",
+		0xFF0D:       "ret",
+		KeyLeftShift: "shift",
+	}
+
+	for keysym, expected := range cases {
+		name, ok := qcode(keysym)
+		if !ok || name != expected {
+			t.Fatalf("%#x: bad: %s", keysym, name)
+		}
+	}
+
+	if _, ok := qcode(0xE9); ok {
+		t.Fatal("should not map")
+	}
+}
+
+func TestBuilderPrepare_VNC(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["headless"] = true
+	config["vnc_bind_address"] = "127.0.0.1"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["vnc_bind_address"] = "localhost"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// A password makes listening on all interfaces safe
+	delete(config, "vnc_bind_address")
+	config["vnc_password"] = "secret"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil && qmpSupported {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["vnc_password"] = "toolongpassword"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestConfigVNCArg(t *testing.T) {
+	cases := []struct {
+		BindAddress string
+		Password    string
+		Loopback    string
+		Arg         string
+		Host        string
+	}{
+		{"", "", "127.0.0.1", "0.0.0.0:5", "127.0.0.1"},
+		{"", "", "::1", "[::]:5", "::1"},
+		{"0.0.0.0", "", "127.0.0.1", "0.0.0.0:5", "127.0.0.1"},
+		{"192.168.0.2", "secret", "127.0.0.1", "192.168.0.2:5,password=on", "192.168.0.2"},
+	}
+
+	for _, tc := range cases {
+		c := &Config{VNCBindAddress: tc.BindAddress, VNCPassword: tc.Password}
+		if v := c.vncArg(tc.Loopback, 5905); v != tc.Arg {
+			t.Fatalf("bad: %s", v)
+		}
+		if v := c.vncHost(tc.Loopback); v != tc.Host {
+			t.Fatalf("bad: %s", v)
+		}
+	}
+}
+
+func TestConfigSPICEArg(t *testing.T) {
+	cases := []struct {
+		BindAddress string
+		Password    string
+		Loopback    string
+		Arg         string
+	}{
+		{"", "", "127.0.0.1", "port=5930,addr=0.0.0.0,disable-ticketing=on"},
+		{"", "", "::1", "port=5930,addr=::,disable-ticketing=on"},
+		{"127.0.0.1", "", "127.0.0.1", "port=5930,addr=127.0.0.1,disable-ticketing=on"},
+		{"192.168.0.2", "secret", "127.0.0.1", "port=5930,addr=192.168.0.2"},
+	}
+
+	for _, tc := range cases {
+		c := &Config{VNCBindAddress: tc.BindAddress, SPICEPassword: tc.Password}
+		if v := c.spiceArg(tc.Loopback, 5930); v != tc.Arg {
+			t.Fatalf("bad: %s", v)
+		}
+	}
+}
+
+func TestBuilderPrepare_SPICEPassword(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["display"] = "spice"
+	config["headless"] = true
+
+	// Listening on loopback doesn't warn
+	config["vnc_bind_address"] = "127.0.0.1"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil && qmpSupported {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Neither does a password
+	delete(config, "vnc_bind_address")
+	config["spice_password"] = "a long secret"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if qmpSupported && err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !qmpSupported && err == nil {
+		t.Fatal("should have error")
+	}
+
+	// The password is only for SPICE
+	delete(config, "display")
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_VNCUsePassword(t *testing.T) {
+	if !qmpSupported {
+		t.Skip("vnc_password is not supported on this platform")
+	}
+
+	var b Builder
+	config := testConfig()
+	config["vnc_use_password"] = true
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(b.config.VNCPassword) != 8 {
+		t.Fatalf("bad: %q", b.config.VNCPassword)
+	}
+
+	// Every build has its own password
+	var other Builder
+	if _, err := other.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if other.config.VNCPassword == b.config.VNCPassword {
+		t.Fatalf("passwords should differ: %q", b.config.VNCPassword)
+	}
+
+	// A given password is used as it is
+	config["vnc_password"] = "secret"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.VNCPassword != "secret" {
+		t.Fatalf("bad: %q", b.config.VNCPassword)
+	}
+
+	delete(config, "vnc_password")
+	config["display"] = "spice"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -3,12 +3,18 @@ package qemu
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/mitchellh/multistep"
 	"io"
+	"io/ioutil"
 	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -24,21 +30,54 @@ type Driver interface {
 	// Stop stops a running machine, forcefully.
 	Stop() error
 
+	// Powerdown asks the guest of a running machine to shut down, like
+	// pressing the power button of the machine does. It doesn't wait for
+	// the machine to stop.
+	Powerdown() error
+
 	// Qemu executes the given command via qemu-system-x86_64
 	Qemu(qemuArgs ...string) error
 
+	// SetVNCPassword sets the password of the VNC server of a running
+	// machine through the QMP monitor.
+	SetVNCPassword(password string) error
+
+	// SetSPICEPassword sets the password of the SPICE server of a running
+	// machine through the QMP monitor.
+	SetSPICEPassword(password string) error
+
+	// KeyEvent presses or releases a key of the keyboard of a running
+	// machine through the QMP monitor. The key is given as an X keysym,
+	// the same as for VNC.
+	KeyEvent(keysym uint32, down bool) error
+
+	// Screendump saves the display of a running machine to the file at
+	// path through the QMP monitor, as a PNG if png is true or else as a
+	// PPM, which older versions of Qemu only support.
+	Screendump(path string, png bool) error
+
 	// wait on shutdown of the VM with option to cancel
 	WaitForShutdown(<-chan struct{}) bool
 
 	// Qemu executes the given command via qemu-img
 	QemuImg(...string) error
 
+	// QemuImgProgress executes a long running qemu-img command, such as
+	// convert, with progress output enabled. The progress is reported as
+	// a percentage through the given function while the command runs.
+	// If cancelCh is closed, the command is killed.
+	QemuImgProgress(cancelCh <-chan struct{}, progress func(float64), args ...string) error
+
+	// QemuImgVirtualSize returns the virtual size of the disk image at
+	// path, in bytes, as reported by qemu-img info.
+	QemuImgVirtualSize(path string) (uint64, error)
+
 	// Verify checks to make sure that this driver should function
 	// properly. If there is any indication the driver can't function,
 	// this will return an error.
 	Verify() error
 
-	// Version reads the version of Qemu that is installed.
+	// Version reads the version of Qemu that is installed, such as 2.5.0.
 	Version() (string, error)
 }
 
@@ -46,17 +85,95 @@ type QemuDriver struct {
 	QemuPath    string
 	QemuImgPath string
 
+	// Limits are the resource limits that Qemu is run with, or nil to
+	// run it without limits.
+	Limits *ResourceLimits
+
+	// LogPath is a file that the stderr of Qemu is appended to, or empty
+	// to only log it to the Packer log.
+	LogPath string
+
 	vmCmd   *exec.Cmd
 	vmEndCh <-chan int
+	qmpPath string
 	lock    sync.Mutex
 }
 
+func (d *QemuDriver) Powerdown() error {
+	d.lock.Lock()
+	path := d.qmpPath
+	d.lock.Unlock()
+
+	if path == "" {
+		return errors.New("the QMP monitor of the VM is not available")
+	}
+
+	return qmpExecute(path, "system_powerdown")
+}
+
+func (d *QemuDriver) KeyEvent(keysym uint32, down bool) error {
+	d.lock.Lock()
+	path := d.qmpPath
+	d.lock.Unlock()
+
+	if path == "" {
+		return errors.New("the QMP monitor of the VM is not available")
+	}
+
+	args, err := qmpKeyEventArgs(keysym, down)
+	if err != nil {
+		return err
+	}
+
+	return qmpExecuteArgs(path, "input-send-event", args)
+}
+
+func (d *QemuDriver) SetVNCPassword(password string) error {
+	return d.setPassword("vnc", password)
+}
+
+func (d *QemuDriver) SetSPICEPassword(password string) error {
+	return d.setPassword("spice", password)
+}
+
+func (d *QemuDriver) setPassword(protocol string, password string) error {
+	d.lock.Lock()
+	path := d.qmpPath
+	d.lock.Unlock()
+
+	if path == "" {
+		return errors.New("the QMP monitor of the VM is not available")
+	}
+
+	return qmpExecuteArgs(path, "set_password", map[string]string{
+		"protocol": protocol,
+		"password": password,
+	})
+}
+
+func (d *QemuDriver) Screendump(path string, png bool) error {
+	d.lock.Lock()
+	qmpPath := d.qmpPath
+	d.lock.Unlock()
+
+	if qmpPath == "" {
+		return errors.New("the QMP monitor of the VM is not available")
+	}
+
+	args := map[string]string{"filename": path}
+	if png {
+		args["format"] = "png"
+	}
+
+	return qmpExecuteArgs(qmpPath, "screendump", args)
+}
+
 func (d *QemuDriver) Stop() error {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
 	if d.vmCmd != nil {
-		if err := d.vmCmd.Process.Kill(); err != nil {
+		if err := killProcessGroup(d.vmCmd.Process.Pid); err != nil {
 			return err
 		}
 	}
@@ -72,16 +189,63 @@ func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 		panic("Existing VM state found")
 	}
 
+	// Qemu is controlled through a QMP monitor on a Unix socket, which
+	// is kept out of the output directory so it isn't in the artifact.
+	var qmpDir, qmpPath string
+	if qmpSupported {
+		var err error
+		qmpDir, err = ioutil.TempDir("", "packer-qmp")
+		if err != nil {
+			return fmt.Errorf("Error creating QMP socket directory: %s", err)
+		}
+
+		qmpPath = filepath.Join(qmpDir, "qmp.sock")
+		qemuArgs = append(qemuArgs, "-qmp", fmt.Sprintf("unix:%s,server,nowait", qmpPath))
+	}
+
 	stdout_r, stdout_w := io.Pipe()
 	stderr_r, stderr_w := io.Pipe()
 
-	log.Printf("Executing %s: %#v", d.QemuPath, qemuArgs)
-	cmd := exec.Command(d.QemuPath, qemuArgs...)
+	name, args := d.QemuPath, qemuArgs
+	if d.Limits != nil {
+		if prefix := d.Limits.commandPrefix(); len(prefix) > 0 {
+			name = prefix[0]
+			args = append(append(prefix[1:], d.QemuPath), qemuArgs...)
+		}
+	}
+
+	// The end of stderr is kept to explain why Qemu failed to start, such
+	// as an invalid argument or no permission to use KVM.
+	stderr := &tailBuffer{Max: 4096}
+	stderrWriters := []io.Writer{stderr}
+	var logFile *os.File
+	if d.LogPath != "" {
+		f, err := os.OpenFile(d.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Printf("Error opening Qemu log file: %s", err)
+		} else {
+			logFile = f
+			stderrWriters = append(stderrWriters, f)
+		}
+	}
+	stderrWriters = append(stderrWriters, stderr_w)
+
+	log.Printf("Executing %s: %#v", name, args)
+	cmd := exec.Command(name, args...)
 	cmd.Stdout = stdout_w
-	cmd.Stderr = stderr_w
+	cmd.Stderr = io.MultiWriter(stderrWriters...)
+	setProcessGroup(cmd)
 
 	err := cmd.Start()
 	if err != nil {
+		if qmpDir != "" {
+			os.RemoveAll(qmpDir)
+		}
+
+		if logFile != nil {
+			logFile.Close()
+		}
+
 		err = fmt.Errorf("Error starting VM: %s", err)
 		return err
 	}
@@ -91,6 +255,24 @@ func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 
 	log.Printf("Started Qemu. Pid: %d", cmd.Process.Pid)
 
+	var cgroupDir string
+	if d.Limits != nil && d.Limits.Method == "cgroup" && d.Limits.cgroupLimits() {
+		cgroupDir, err = d.Limits.joinCgroup(cmd.Process.Pid)
+		if err != nil {
+			killProcessGroup(cmd.Process.Pid)
+			cmd.Wait()
+			if qmpDir != "" {
+				os.RemoveAll(qmpDir)
+			}
+
+			if logFile != nil {
+				logFile.Close()
+			}
+
+			return err
+		}
+	}
+
 	// Wait for Qemu to complete in the background, and mark when its done
 	endCh := make(chan int, 1)
 	go func() {
@@ -109,12 +291,25 @@ func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 			}
 		}
 
+		if qmpDir != "" {
+			os.RemoveAll(qmpDir)
+		}
+
+		if cgroupDir != "" {
+			os.Remove(cgroupDir)
+		}
+
+		if logFile != nil {
+			logFile.Close()
+		}
+
 		endCh <- exitCode
 
 		d.lock.Lock()
 		defer d.lock.Unlock()
 		d.vmCmd = nil
 		d.vmEndCh = nil
+		d.qmpPath = ""
 	}()
 
 	// Wait at least a couple seconds for an early fail from Qemu so
@@ -122,6 +317,10 @@ func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 	select {
 	case exit := <-endCh:
 		if exit != 0 {
+			if output := strings.TrimSpace(stderr.String()); output != "" {
+				return fmt.Errorf("Qemu failed to start: %s", output)
+			}
+
 			return fmt.Errorf("Qemu failed to start. Please run with logs to get more info.")
 		}
 	case <-time.After(2 * time.Second):
@@ -130,6 +329,7 @@ func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 	// Setup our state so we know we are running
 	d.vmCmd = cmd
 	d.vmEndCh = endCh
+	d.qmpPath = qmpPath
 
 	return nil
 }
@@ -173,7 +373,113 @@ func (d *QemuDriver) QemuImg(args ...string) error {
 	return err
 }
 
+func (d *QemuDriver) QemuImgVirtualSize(path string) (uint64, error) {
+	var stdout, stderr bytes.Buffer
+
+	log.Printf("Executing qemu-img: info --output=json %s", path)
+	cmd := exec.Command(d.QemuImgPath, "info", "--output=json", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			err = fmt.Errorf("QemuImg error: %s", strings.TrimSpace(stderr.String()))
+		}
+
+		return 0, err
+	}
+
+	var info struct {
+		VirtualSize uint64 `json:"virtual-size"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+		return 0, fmt.Errorf("Error parsing the output of qemu-img info: %s", err)
+	}
+
+	return info.VirtualSize, nil
+}
+
+func (d *QemuDriver) QemuImgProgress(cancelCh <-chan struct{}, progress func(float64), args ...string) error {
+	var stderr bytes.Buffer
+
+	// Progress output is enabled with -p, which must follow the command.
+	if len(args) > 0 {
+		args = append([]string{args[0], "-p"}, args[1:]...)
+	}
+
+	stdout_r, stdout_w := io.Pipe()
+
+	log.Printf("Executing qemu-img: %#v", args)
+	cmd := exec.Command(d.QemuImgPath, args...)
+	cmd.Stdout = stdout_w
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	readDoneCh := make(chan struct{})
+	go func() {
+		defer close(readDoneCh)
+
+		scanner := bufio.NewScanner(stdout_r)
+		scanner.Split(scanProgressLines)
+		for scanner.Scan() {
+			if p, ok := parseQemuImgProgress(scanner.Text()); ok {
+				progress(p)
+			}
+		}
+
+		// Drain anything left so that qemu-img never blocks writing
+		io.Copy(ioutil.Discard, stdout_r)
+	}()
+
+	// The progress is only done being reported once all the output
+	// is read, which happens after the command exits.
+	doneCh := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		stdout_w.Close()
+		<-readDoneCh
+		doneCh <- err
+	}()
+
+	var err error
+	select {
+	case err = <-doneCh:
+	case <-cancelCh:
+		log.Printf("Killing qemu-img: %#v", args)
+		cmd.Process.Kill()
+		<-doneCh
+		return errors.New("qemu-img was cancelled")
+	}
+
+	stderrString := strings.TrimSpace(stderr.String())
+	if _, ok := err.(*exec.ExitError); ok {
+		err = fmt.Errorf("QemuImg error: %s", stderrString)
+	}
+
+	log.Printf("stderr: %s", stderrString)
+
+	return err
+}
+
 func (d *QemuDriver) Verify() error {
+	if d.Limits == nil {
+		return nil
+	}
+
+	// The commands that apply the limits must exist, or Qemu can't start
+	prefix := d.Limits.commandPrefix()
+	for _, name := range []string{"systemd-run", "ionice"} {
+		for _, arg := range prefix {
+			if arg == name {
+				if _, err := exec.LookPath(name); err != nil {
+					return fmt.Errorf("%s is required by resource_limits: %s", name, err)
+				}
+				break
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -188,14 +494,82 @@ func (d *QemuDriver) Version() (string, error) {
 
 	versionOutput := strings.TrimSpace(stdout.String())
 	log.Printf("Qemu --version output: %s", versionOutput)
-	versionRe := regexp.MustCompile("qemu-kvm-[0-9]\\.[0-9]")
-	matches := versionRe.Split(versionOutput, 2)
-	if len(matches) == 0 {
+
+	// The version follows "version", as in "QEMU emulator version 2.5.0
+	// (Debian 1:2.5+dfsg-5ubuntu10)"
+	matches := qemuVersionOutputRe.FindStringSubmatch(versionOutput)
+	if matches == nil {
 		return "", fmt.Errorf("No version found: %s", versionOutput)
 	}
 
-	log.Printf("Qemu version: %s", matches[0])
-	return matches[0], nil
+	log.Printf("Qemu version: %s", matches[1])
+	return matches[1], nil
+}
+
+var qemuVersionOutputRe = regexp.MustCompile(`version ([0-9]+\.[0-9]+(?:\.[0-9]+)?)`)
+
+var qemuImgProgressRe = regexp.MustCompile(`\(([0-9.]+)/100%\)`)
+
+// parseQemuImgProgress parses the percentage out of a line of progress
+// output of qemu-img, such as "    (42.13/100%)".
+func parseQemuImgProgress(line string) (float64, bool) {
+	matches := qemuImgProgressRe.FindStringSubmatch(line)
+	if matches == nil {
+		return 0, false
+	}
+
+	p, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return p, true
+}
+
+// scanProgressLines is a bufio.SplitFunc like bufio.ScanLines, except
+// that a carriage return also ends a line, since that is how qemu-img
+// updates its progress in place.
+func scanProgressLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// tailBuffer is an io.Writer that keeps the last Max bytes written to it.
+type tailBuffer struct {
+	Max int
+
+	buf  []byte
+	lock sync.Mutex
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.Max {
+		b.buf = b.buf[len(b.buf)-b.Max:]
+	}
+
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return string(b.buf)
 }
 
 func logReader(name string, r io.Reader) {
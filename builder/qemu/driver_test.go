@@ -0,0 +1,175 @@
+package qemu
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQemuImgProgress(t *testing.T) {
+	cases := []struct {
+		Line    string
+		Percent float64
+		Ok      bool
+	}{
+		{"    (0.00/100%)", 0, true},
+		{"    (42.13/100%)", 42.13, true},
+		{"(100.00/100%)", 100, true},
+		{"", 0, false},
+		{"qemu-img: error", 0, false},
+	}
+
+	for _, tc := range cases {
+		p, ok := parseQemuImgProgress(tc.Line)
+		if ok != tc.Ok || p != tc.Percent {
+			t.Fatalf("%q: bad: %f %t", tc.Line, p, ok)
+		}
+	}
+}
+
+func TestScanProgressLines(t *testing.T) {
+	input := "    (0.00/100%)\r    (50.00/100%)\r    (100.00/100%)\r\nfoo"
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Split(scanProgressLines)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	expected := []string{
+		"    (0.00/100%)",
+		"    (50.00/100%)",
+		"    (100.00/100%)",
+		"",
+		"foo",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("bad: %#v", lines)
+	}
+}
+
+// testFakeQemuImg writes a script that behaves like qemu-img with the
+// given shell commands, returning its path.
+func testFakeQemuImg(t *testing.T, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := filepath.Join(td, "qemu-img")
+	err = ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return path
+}
+
+func TestQemuDriverQemuImgProgress(t *testing.T) {
+	path := testFakeQemuImg(t, `
+[ "$1" = "convert" ] && [ "$2" = "-p" ] || exit 1
+printf '    (0.00/100%%)\r    (50.00/100%%)\r    (100.00/100%%)\r\n'`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var progress []float64
+	d := &QemuDriver{QemuImgPath: path}
+	err := d.QemuImgProgress(nil, func(p float64) {
+		progress = append(progress, p)
+	}, "convert", "foo", "bar")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []float64{0, 50, 100}
+	if !reflect.DeepEqual(progress, expected) {
+		t.Fatalf("bad: %#v", progress)
+	}
+}
+
+func TestQemuDriverQemuImgProgress_error(t *testing.T) {
+	path := testFakeQemuImg(t, `echo "bad disk" >&2; exit 1`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	d := &QemuDriver{QemuImgPath: path}
+	err := d.QemuImgProgress(nil, func(float64) {}, "convert", "foo", "bar")
+	if err == nil || !strings.Contains(err.Error(), "bad disk") {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestQemuDriverQemuImgProgress_cancel(t *testing.T) {
+	path := testFakeQemuImg(t, `exec sleep 10`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	cancelCh := make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() { close(cancelCh) })
+
+	start := time.Now()
+	d := &QemuDriver{QemuImgPath: path}
+	err := d.QemuImgProgress(cancelCh, func(float64) {}, "convert", "foo", "bar")
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("should be killed")
+	}
+}
+
+func TestQemuDriverQemu_failedStart(t *testing.T) {
+	path := testFakeQemuImg(t, `echo "kvm: permission denied" >&2; exit 1`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	logPath := filepath.Join(filepath.Dir(path), "qemu.log")
+	d := &QemuDriver{QemuPath: path, LogPath: logPath}
+	err := d.Qemu("-enable-kvm")
+	if err == nil || !strings.Contains(err.Error(), "kvm: permission denied") {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	data, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(data) != "kvm: permission denied\n" {
+		t.Fatalf("bad: %q", data)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{Max: 5}
+	b.Write([]byte("abc"))
+	if v := b.String(); v != "abc" {
+		t.Fatalf("bad: %s", v)
+	}
+
+	b.Write([]byte("defg"))
+	if v := b.String(); v != "cdefg" {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestQemuDriverVersion(t *testing.T) {
+	path := testFakeQemuImg(t, `echo "QEMU emulator version 2.5.0 (Debian 1:2.5+dfsg-5ubuntu10.6), Copyright (c) 2003-2008 Fabrice Bellard"`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	d := &QemuDriver{QemuPath: path}
+	v, err := d.Version()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v != "2.5.0" {
+		t.Fatalf("bad: %s", v)
+	}
+}
@@ -0,0 +1,149 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// efiFirmware is a matching pair of OVMF firmware images: the read-only
+// code and the template for the writable variable store.
+type efiFirmware struct {
+	Code string
+	Vars string
+}
+
+// efiFirmwarePaths are the well-known locations of the firmware that the
+// OVMF and edk2 packages of common distributions and Homebrew install,
+// by guest architecture. They are probed in order.
+var efiFirmwarePaths = map[string][]efiFirmware{
+	"x86_64": {
+		// Debian and Ubuntu
+		{"/usr/share/OVMF/OVMF_CODE_4M.fd", "/usr/share/OVMF/OVMF_VARS_4M.fd"},
+		{"/usr/share/OVMF/OVMF_CODE.fd", "/usr/share/OVMF/OVMF_VARS.fd"},
+		// Fedora, RHEL and CentOS
+		{"/usr/share/edk2/ovmf/OVMF_CODE.fd", "/usr/share/edk2/ovmf/OVMF_VARS.fd"},
+		// Arch Linux
+		{"/usr/share/edk2/x64/OVMF_CODE.fd", "/usr/share/edk2/x64/OVMF_VARS.fd"},
+		{"/usr/share/edk2-ovmf/x64/OVMF_CODE.fd", "/usr/share/edk2-ovmf/x64/OVMF_VARS.fd"},
+		// openSUSE
+		{"/usr/share/qemu/ovmf-x86_64-code.bin", "/usr/share/qemu/ovmf-x86_64-vars.bin"},
+		// Homebrew and QEMU installed from source
+		{"/opt/homebrew/share/qemu/edk2-x86_64-code.fd", "/opt/homebrew/share/qemu/edk2-i386-vars.fd"},
+		{"/usr/local/share/qemu/edk2-x86_64-code.fd", "/usr/local/share/qemu/edk2-i386-vars.fd"},
+		{"/usr/share/qemu/edk2-x86_64-code.fd", "/usr/share/qemu/edk2-i386-vars.fd"},
+	},
+	"aarch64": {
+		// Debian and Ubuntu
+		{"/usr/share/AAVMF/AAVMF_CODE.fd", "/usr/share/AAVMF/AAVMF_VARS.fd"},
+		// Fedora, RHEL and CentOS
+		{"/usr/share/edk2/aarch64/QEMU_EFI-pflash.raw", "/usr/share/edk2/aarch64/vars-template-pflash.raw"},
+		// Arch Linux
+		{"/usr/share/edk2/aarch64/QEMU_CODE.fd", "/usr/share/edk2/aarch64/QEMU_VARS.fd"},
+		// openSUSE
+		{"/usr/share/qemu/aavmf-aarch64-code.bin", "/usr/share/qemu/aavmf-aarch64-vars.bin"},
+		// Homebrew and QEMU installed from source
+		{"/opt/homebrew/share/qemu/edk2-aarch64-code.fd", "/opt/homebrew/share/qemu/edk2-arm-vars.fd"},
+		{"/usr/local/share/qemu/edk2-aarch64-code.fd", "/usr/local/share/qemu/edk2-arm-vars.fd"},
+		{"/usr/share/qemu/edk2-aarch64-code.fd", "/usr/share/qemu/edk2-arm-vars.fd"},
+	},
+}
+
+// efiArch returns the guest architecture of the given Qemu binary, such
+// as "x86_64" for "qemu-system-x86_64".
+func efiArch(qemuBinary string) string {
+	name := strings.TrimSuffix(filepath.Base(qemuBinary), ".exe")
+	if idx := strings.LastIndex(name, "qemu-system-"); idx >= 0 {
+		return name[idx+len("qemu-system-"):]
+	}
+
+	// Plain "qemu" or "qemu-kvm" binaries run the host architecture
+	return "x86_64"
+}
+
+// findEFIFirmware returns the first pair of firmware images for the
+// architecture where both the code and the variable store exist.
+func findEFIFirmware(arch string) (efiFirmware, error) {
+	candidates, ok := efiFirmwarePaths[arch]
+	if !ok {
+		return efiFirmware{}, fmt.Errorf(
+			"Packer doesn't know where to find EFI firmware for %s guests.\n"+
+				"Set efi_firmware_code and efi_firmware_vars to the firmware\n"+
+				"images to use.", arch)
+	}
+
+	for _, f := range candidates {
+		if fileExists(f.Code) && fileExists(f.Vars) {
+			return f, nil
+		}
+	}
+
+	searched := make([]string, 0, len(candidates))
+	for _, f := range candidates {
+		searched = append(searched, fmt.Sprintf("  %s", f.Code))
+	}
+
+	return efiFirmware{}, fmt.Errorf(
+		"No EFI firmware for %s guests was found. Install the OVMF package of\n"+
+			"your distribution (named ovmf, edk2-ovmf or qemu-efi-aarch64), or set\n"+
+			"efi_firmware_code and efi_firmware_vars to the firmware images to\n"+
+			"use. These locations were searched:\n%s",
+		arch, strings.Join(searched, "\n"))
+}
+
+func (c *Config) prepareEFI() []error {
+	var errs []error
+
+	// A single firmware image, such as OVMF.fd, is loaded with -bios. It
+	// can't be used along with the pflash images of efi_boot.
+	if c.Firmware != "" {
+		if c.EFIBoot {
+			errs = append(errs, errors.New("firmware can't be used along with efi_boot"))
+		}
+		if _, err := os.Stat(c.Firmware); err != nil {
+			errs = append(errs, fmt.Errorf("firmware is invalid: %s", err))
+		}
+	}
+
+	if !c.EFIBoot {
+		if c.EFIFirmwareCode != "" || c.EFIFirmwareVars != "" {
+			errs = append(errs, errors.New(
+				"efi_firmware_code and efi_firmware_vars require efi_boot"))
+		}
+
+		return errs
+	}
+
+	if c.EFIFirmwareCode == "" && c.EFIFirmwareVars == "" {
+		f, err := findEFIFirmware(efiArch(c.QemuBinary))
+		if err != nil {
+			return append(errs, err)
+		}
+
+		c.EFIFirmwareCode = f.Code
+		c.EFIFirmwareVars = f.Vars
+		return errs
+	}
+
+	// The images of one pair must be used together, so a partial
+	// configuration isn't completed from the well-known locations.
+	if c.EFIFirmwareCode == "" || c.EFIFirmwareVars == "" {
+		return append(errs, errors.New(
+			"efi_firmware_code and efi_firmware_vars must be specified together"))
+	}
+
+	for _, path := range []string{c.EFIFirmwareCode, c.EFIFirmwareVars} {
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("EFI firmware is invalid: %s", err))
+		}
+	}
+
+	return errs
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
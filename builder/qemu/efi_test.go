@@ -0,0 +1,175 @@
+package qemu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEFIArch(t *testing.T) {
+	cases := map[string]string{
+		"qemu-system-x86_64":             "x86_64",
+		"/usr/bin/qemu-system-aarch64":   "aarch64",
+		`C:\qemu\qemu-system-x86_64.exe`: "x86_64",
+		"qemu-kvm":                       "x86_64",
+	}
+
+	for input, expected := range cases {
+		if actual := efiArch(input); actual != expected {
+			t.Fatalf("%s: bad: %s", input, actual)
+		}
+	}
+}
+
+func TestFindEFIFirmware(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	for _, name := range []string{"a_CODE.fd", "b_CODE.fd", "b_VARS.fd"} {
+		if err := ioutil.WriteFile(filepath.Join(td, name), nil, 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	old := efiFirmwarePaths
+	defer func() { efiFirmwarePaths = old }()
+	efiFirmwarePaths = map[string][]efiFirmware{
+		"x86_64": {
+			{filepath.Join(td, "a_CODE.fd"), filepath.Join(td, "a_VARS.fd")},
+			{filepath.Join(td, "b_CODE.fd"), filepath.Join(td, "b_VARS.fd")},
+		},
+	}
+
+	// Only complete pairs are used
+	f, err := findEFIFirmware("x86_64")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if f.Code != filepath.Join(td, "b_CODE.fd") || f.Vars != filepath.Join(td, "b_VARS.fd") {
+		t.Fatalf("bad: %#v", f)
+	}
+
+	// Unknown architectures
+	if _, err := findEFIFirmware("mips"); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Nothing found lists the searched locations
+	os.Remove(filepath.Join(td, "b_VARS.fd"))
+	_, err = findEFIFirmware("x86_64")
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.Error(), filepath.Join(td, "a_CODE.fd")) {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestBuilderPrepare_EFI(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Firmware without efi_boot
+	config["efi_firmware_code"] = "/foo"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Only one of the pair
+	config["efi_boot"] = true
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Both exist
+	code, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	code.Close()
+	defer os.Remove(code.Name())
+
+	vars, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	vars.Close()
+	defer os.Remove(vars.Name())
+
+	config["efi_firmware_code"] = code.Name()
+	config["efi_firmware_vars"] = vars.Name()
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Detected when neither is set
+	old := efiFirmwarePaths
+	defer func() { efiFirmwarePaths = old }()
+	efiFirmwarePaths = map[string][]efiFirmware{
+		"x86_64": {{code.Name(), vars.Name()}},
+	}
+
+	delete(config, "efi_firmware_code")
+	delete(config, "efi_firmware_vars")
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.EFIFirmwareCode != code.Name() || b.config.EFIFirmwareVars != vars.Name() {
+		t.Fatalf("bad: %s %s", b.config.EFIFirmwareCode, b.config.EFIFirmwareVars)
+	}
+}
+
+func TestBuilderPrepare_Firmware(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Doesn't exist
+	config["firmware"] = "/i/dont/exist"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Exists
+	f, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	config["firmware"] = f.Name()
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Along with efi_boot
+	config["efi_boot"] = true
+	config["efi_firmware_code"] = f.Name()
+	config["efi_firmware_vars"] = f.Name()
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
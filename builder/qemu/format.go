@@ -0,0 +1,61 @@
+package qemu
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// outputFormats are the formats of qemu-img that the disks can be
+// converted to, with the extensions of their files.
+var outputFormats = map[string]string{
+	"qcow2": "qcow2",
+	"raw":   "raw",
+	"vdi":   "vdi",
+	"vhdx":  "vhdx",
+	"vmdk":  "vmdk",
+	"vpc":   "vhd",
+}
+
+// prepareFormats validates the formats. The disks are built in the first
+// one, which must be "qcow2" or "raw", and converted to the others.
+func (c *Config) prepareFormats() []error {
+	var errs []error
+
+	if !(c.format == "qcow2" || c.format == "raw") {
+		errs = append(errs, fmt.Errorf(
+			"invalid format %q, the first format must be 'qcow2' or 'raw'", c.format))
+	}
+
+	valid := make([]string, 0, len(outputFormats))
+	for f := range outputFormats {
+		valid = append(valid, f)
+	}
+	sort.Strings(valid)
+
+	seen := make(map[string]bool)
+	for _, f := range c.Formats[1:] {
+		if _, ok := outputFormats[f]; !ok {
+			errs = append(errs, fmt.Errorf(
+				"invalid format %q, must be one of: %s", f, strings.Join(valid, ", ")))
+		}
+		if seen[f] || f == c.format {
+			errs = append(errs, fmt.Errorf("format %q is listed more than once", f))
+		}
+		seen[f] = true
+	}
+
+	if len(c.Formats) > 1 && c.Ephemeral {
+		errs = append(errs, fmt.Errorf(
+			"more than one format can't be used with ephemeral, as no disk is kept"))
+	}
+
+	return errs
+}
+
+// convertedDiskName returns the file name of the disk with the given name
+// once it is converted to format, such as "packer-vm.vmdk".
+func convertedDiskName(name, format string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + "." + outputFormats[format]
+}
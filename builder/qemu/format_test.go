@@ -0,0 +1,152 @@
+package qemu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestBuilderPrepare_Formats(t *testing.T) {
+	cases := []struct {
+		Formats interface{}
+		Format  string
+		Err     bool
+	}{
+		{nil, "qcow2", false},
+		{"raw", "raw", false},
+		{[]string{"qcow2", "vmdk", "vpc"}, "qcow2", false},
+		{[]string{"raw", "qcow2", "vdi", "vhdx"}, "raw", false},
+
+		// The disks are only built as qcow2 or raw
+		{[]string{"vmdk", "qcow2"}, "vmdk", true},
+
+		{[]string{"qcow2", "bogus"}, "qcow2", true},
+		{[]string{"qcow2", "vmdk", "vmdk"}, "qcow2", true},
+		{[]string{"qcow2", "qcow2"}, "qcow2", true},
+	}
+
+	for _, tc := range cases {
+		var b Builder
+		config := testConfig()
+		if tc.Formats != nil {
+			config["format"] = tc.Formats
+		}
+
+		_, err := b.Prepare(config)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%#v: bad error: %s", tc.Formats, err)
+		}
+		if b.config.format != tc.Format {
+			t.Fatalf("%#v: bad format: %s", tc.Formats, b.config.format)
+		}
+	}
+
+	// No disk is kept to convert
+	var b Builder
+	config := testConfig()
+	config["disk_image"] = true
+	config["ephemeral"] = true
+	config["format"] = []string{"qcow2", "vmdk"}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestConvertedDiskName(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Format   string
+		Expected string
+	}{
+		{"foo.qcow2", "vmdk", "foo.vmdk"},
+		{"foo-1.raw", "qcow2", "foo-1.qcow2"},
+		{"foo.qcow2", "vpc", "foo.vhd"},
+	}
+
+	for _, tc := range cases {
+		if actual := convertedDiskName(tc.Name, tc.Format); actual != tc.Expected {
+			t.Fatalf("%s to %s: bad: %s", tc.Name, tc.Format, actual)
+		}
+	}
+}
+
+func TestStepConvertDisk(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	for _, name := range []string{"foo.qcow2", "foo-1.qcow2"} {
+		if err := ioutil.WriteFile(filepath.Join(td, name), []byte("disk"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// The fake qemu-img records its arguments
+	argsPath := filepath.Join(td, "args")
+	path := testFakeQemuImg(t, `echo "$@" >> `+argsPath)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("driver", &QemuDriver{QemuImgPath: path})
+	state.Put("disk_filename", "foo.qcow2")
+	state.Put("config", &Config{
+		AdditionalDiskSize: []uint{1024},
+		Formats:            []string{"qcow2", "vmdk", "vpc"},
+		format:             "qcow2",
+		OutputDir:          td,
+		VMName:             "foo",
+	})
+
+	step := new(stepConvertDisk)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+
+	main := filepath.Join(td, "foo.qcow2")
+	additional := filepath.Join(td, "foo-1.qcow2")
+	expected := fmt.Sprintf("convert -p -O vmdk %s %s\n", main, filepath.Join(td, "foo.vmdk")) +
+		fmt.Sprintf("convert -p -O vmdk %s %s\n", additional, filepath.Join(td, "foo-1.vmdk")) +
+		fmt.Sprintf("convert -p -O vpc %s %s\n", main, filepath.Join(td, "foo.vhd")) +
+		fmt.Sprintf("convert -p -O vpc %s %s\n", additional, filepath.Join(td, "foo-1.vhd"))
+	data, _ := ioutil.ReadFile(argsPath)
+	if string(data) != expected {
+		t.Fatalf("bad: %q", data)
+	}
+
+	converted := []string{
+		filepath.Join(td, "foo.vmdk"),
+		filepath.Join(td, "foo-1.vmdk"),
+		filepath.Join(td, "foo.vhd"),
+		filepath.Join(td, "foo-1.vhd"),
+	}
+	if actual := state.Get("converted_disks"); !reflect.DeepEqual(actual, converted) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestStepConvertDisk_singleFormat(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("driver", &QemuDriver{QemuImgPath: "/nonexistent"})
+	state.Put("config", &Config{
+		Formats: []string{"qcow2"},
+		format:  "qcow2",
+	})
+
+	step := new(stepConvertDisk)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+	if _, ok := state.GetOk("converted_disks"); ok {
+		t.Fatal("should not convert the disks")
+	}
+}
@@ -0,0 +1,212 @@
+package qemu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/packer/common"
+)
+
+// AdditionalISO is an ISO that is downloaded like the installation ISO and
+// attached to the VM as another CD-ROM, such as the virtio-win drivers for
+// Windows installs.
+type AdditionalISO struct {
+	Checksum     string   `mapstructure:"checksum"`
+	ChecksumType string   `mapstructure:"checksum_type"`
+	Index        *int     `mapstructure:"index"`
+	Interface    string   `mapstructure:"interface"`
+	URL          string   `mapstructure:"url"`
+	URLs         []string `mapstructure:"urls"`
+}
+
+// cdrom is a CD-ROM of the VM.
+type cdrom struct {
+	Path string
+
+	// Interface is either "ide" or "scsi".
+	Interface string
+
+	// Index is the unit on the IDE bus or the SCSI ID, or nil to leave it
+	// to Qemu.
+	Index *int
+}
+
+// additionalISOKey returns the state key of the path of the additional
+// ISO with the given index.
+func additionalISOKey(i int) string {
+	return fmt.Sprintf("additional_iso_path_%d", i)
+}
+
+func (c *Config) prepareAdditionalISOs() []error {
+	var errs []error
+	arch := c.arch()
+
+	ideIndexes := make(map[int]bool)
+	if arch.PC && (!c.DiskImage || len(c.CDContent) > 0) {
+		// The first CD-ROM is attached with -cdrom, which is the master
+		// on the second IDE bus
+		ideIndexes[2] = true
+	}
+	scsiIndexes := make(map[int]bool)
+
+	for i := range c.AdditionalISOs {
+		iso := &c.AdditionalISOs[i]
+		name := fmt.Sprintf("additional_iso_urls[%d]", i)
+
+		if iso.URL == "" && len(iso.URLs) == 0 {
+			errs = append(errs, fmt.Errorf("%s: one of url or urls must be specified", name))
+		} else if iso.URL != "" && len(iso.URLs) > 0 {
+			errs = append(errs, fmt.Errorf("%s: only one of url or urls may be specified", name))
+		} else if iso.URL != "" {
+			iso.URLs = []string{iso.URL}
+		}
+
+		for j, url := range iso.URLs {
+			var err error
+			iso.URLs[j], err = common.DownloadableURL(url)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: failed to parse url %d: %s", name, j+1, err))
+			}
+		}
+
+		iso.ChecksumType = strings.ToLower(iso.ChecksumType)
+		if iso.ChecksumType == "" {
+			errs = append(errs, fmt.Errorf("%s: checksum_type must be specified", name))
+		} else if iso.ChecksumType != "none" {
+			if iso.Checksum == "" {
+				errs = append(errs, fmt.Errorf("%s: a checksum is required", name))
+			}
+			iso.Checksum = strings.ToLower(iso.Checksum)
+
+			if h := common.HashForType(iso.ChecksumType); h == nil {
+				errs = append(errs, fmt.Errorf(
+					"%s: unsupported checksum type: %s", name, iso.ChecksumType))
+			}
+		}
+
+		if iso.Interface == "" {
+			iso.Interface = isoInterface(arch)
+		}
+
+		var indexes map[int]bool
+		max := 3
+		switch iso.Interface {
+		case "ide":
+			if !arch.PC {
+				errs = append(errs, fmt.Errorf(
+					"%s: only x86 guests have an IDE interface, use scsi", name))
+			}
+			indexes = ideIndexes
+		case "scsi":
+			indexes = scsiIndexes
+			max = 255
+		default:
+			errs = append(errs, fmt.Errorf(
+				"%s: interface must be ide or scsi, not %q", name, iso.Interface))
+			continue
+		}
+
+		if iso.Index == nil {
+			continue
+		}
+		if *iso.Index < 0 || *iso.Index > max {
+			errs = append(errs, fmt.Errorf(
+				"%s: the index of %s CD-ROMs must be from 0 to %d", name, iso.Interface, max))
+		} else if indexes[*iso.Index] {
+			errs = append(errs, fmt.Errorf(
+				"%s: %s index %d is already used", name, iso.Interface, *iso.Index))
+		}
+		indexes[*iso.Index] = true
+	}
+
+	return errs
+}
+
+// cdromArgs returns the arguments that attach the CD-ROMs. The first IDE
+// CD-ROM without an index is attached with -cdrom, and SCSI CD-ROMs share
+// a virtio SCSI controller.
+func cdromArgs(cdroms []cdrom) map[string][]string {
+	args := make(map[string][]string)
+	scsi := false
+
+	for i, cd := range cdroms {
+		if cd.Interface == "ide" {
+			if i == 0 && cd.Index == nil {
+				args["-cdrom"] = []string{cd.Path}
+				continue
+			}
+
+			drive := fmt.Sprintf("file=%s,media=cdrom", driveFile(cd.Path))
+			if cd.Index != nil {
+				drive += fmt.Sprintf(",if=ide,index=%d", *cd.Index)
+			}
+			args["-drive"] = append(args["-drive"], drive)
+			continue
+		}
+
+		if !scsi {
+			args["-device"] = append(args["-device"], "virtio-scsi,id=scsi0")
+			scsi = true
+		}
+
+		id := fmt.Sprintf("cdrom%d", i)
+		device := fmt.Sprintf("scsi-cd,drive=%s,bus=scsi0.0", id)
+		if cd.Index != nil {
+			device += fmt.Sprintf(",scsi-id=%d", *cd.Index)
+		}
+		args["-drive"] = append(args["-drive"],
+			fmt.Sprintf("file=%s,if=none,id=%s,media=cdrom", driveFile(cd.Path), id))
+		args["-device"] = append(args["-device"], device)
+	}
+
+	return args
+}
+
+// driveFile escapes the path of a file or URL for the file option of
+// -drive, in which commas separate the options.
+func driveFile(path string) string {
+	return strings.Replace(path, ",", ",,", -1)
+}
+
+// isoInterface returns the interface of the CD-ROMs that Packer attaches
+// for the given guest architecture.
+func isoInterface(arch qemuArch) string {
+	if arch.PC {
+		return "ide"
+	}
+
+	return "scsi"
+}
+
+// streamedISOURL returns the URL that Qemu reads the ISO from with
+// iso_skip_cache, which is the first of iso_urls that is served over HTTP
+// or HTTPS, or an empty string if there is none.
+func (c *Config) streamedISOURL() string {
+	for _, u := range c.ISOUrls {
+		if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+			return u
+		}
+	}
+
+	return ""
+}
+
+// prepareISOSkipCache validates reading the ISO from its URL with Qemu
+// instead of downloading it.
+func (c *Config) prepareISOSkipCache() []error {
+	if !c.ISOSkipCache {
+		return nil
+	}
+
+	var errs []error
+
+	if c.DiskImage {
+		errs = append(errs, fmt.Errorf("iso_skip_cache can't be used with disk_image"))
+	}
+
+	if len(c.ISOUrls) > 0 && c.streamedISOURL() == "" {
+		errs = append(errs, fmt.Errorf("iso_skip_cache requires an http or https iso_url"))
+	}
+
+	return errs
+}
@@ -0,0 +1,199 @@
+package qemu
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestBuilderPrepare_AdditionalISOs(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["additional_iso_urls"] = []map[string]interface{}{
+		{
+			"url":           "http://example.com/virtio-win.iso",
+			"checksum":      "ABCD",
+			"checksum_type": "MD5",
+		},
+		{
+			"urls":          []string{"http://example.com/a.iso", "http://example.com/b.iso"},
+			"checksum_type": "none",
+			"interface":     "scsi",
+			"index":         3,
+		},
+	}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	isos := b.config.AdditionalISOs
+	if len(isos) != 2 {
+		t.Fatalf("bad: %#v", isos)
+	}
+	if !reflect.DeepEqual(isos[0].URLs, []string{"http://example.com/virtio-win.iso"}) {
+		t.Fatalf("bad: %#v", isos[0].URLs)
+	}
+	if isos[0].Checksum != "abcd" || isos[0].ChecksumType != "md5" || isos[0].Interface != "ide" || isos[0].Index != nil {
+		t.Fatalf("bad: %#v", isos[0])
+	}
+	if isos[1].Interface != "scsi" || isos[1].Index == nil || *isos[1].Index != 3 {
+		t.Fatalf("bad: %#v", isos[1])
+	}
+
+	cases := []map[string]interface{}{
+		// No URL
+		{"checksum_type": "none"},
+		// Both url and urls
+		{"url": "a.iso", "urls": []string{"b.iso"}, "checksum_type": "none"},
+		// No checksum
+		{"url": "a.iso", "checksum_type": "md5"},
+		// Unknown interface
+		{"url": "a.iso", "checksum_type": "none", "interface": "virtio"},
+		// The index of the installation ISO
+		{"url": "a.iso", "checksum_type": "none", "index": 2},
+		// Out of range
+		{"url": "a.iso", "checksum_type": "none", "index": 4},
+	}
+	for _, tc := range cases {
+		config["additional_iso_urls"] = []map[string]interface{}{tc}
+		b = Builder{}
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("%#v: should have error", tc)
+		}
+	}
+
+	// Indexes can't be shared
+	config["additional_iso_urls"] = []map[string]interface{}{
+		{"url": "a.iso", "checksum_type": "none", "index": 1},
+		{"url": "b.iso", "checksum_type": "none", "index": 3},
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["additional_iso_urls"].([]map[string]interface{})[1]["index"] = 1
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestCdromArgs(t *testing.T) {
+	index := 1
+
+	// The first IDE CD-ROM is attached with -cdrom
+	args := cdromArgs([]cdrom{
+		{Path: "a.iso", Interface: "ide"},
+		{Path: "b.iso", Interface: "ide"},
+		{Path: "c.iso", Interface: "ide", Index: &index},
+	})
+	expected := map[string][]string{
+		"-cdrom": {"a.iso"},
+		"-drive": {"file=b.iso,media=cdrom", "file=c.iso,media=cdrom,if=ide,index=1"},
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	// SCSI CD-ROMs share a controller
+	args = cdromArgs([]cdrom{
+		{Path: "a.iso", Interface: "scsi"},
+		{Path: "b.iso", Interface: "scsi", Index: &index},
+	})
+	expected = map[string][]string{
+		"-drive": {
+			"file=a.iso,if=none,id=cdrom0,media=cdrom",
+			"file=b.iso,if=none,id=cdrom1,media=cdrom",
+		},
+		"-device": {
+			"virtio-scsi,id=scsi0",
+			"scsi-cd,drive=cdrom0,bus=scsi0.0",
+			"scsi-cd,drive=cdrom1,bus=scsi0.0,scsi-id=1",
+		},
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
+
+func TestBuilderPrepare_ISOSkipCache(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["iso_skip_cache"] = true
+	config["iso_urls"] = []string{"file:///tmp/a.iso", "https://example.com/a.iso"}
+	delete(config, "iso_url")
+	warns, err := b.Prepare(config)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if u := b.config.streamedISOURL(); u != "https://example.com/a.iso" {
+		t.Fatalf("bad: %s", u)
+	}
+
+	// No HTTP URL
+	config["iso_urls"] = []string{"file:///tmp/a.iso"}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Disk images are always downloaded
+	config["iso_urls"] = []string{"https://example.com/a.img"}
+	config["disk_image"] = true
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestCdromArgs_escapesCommas(t *testing.T) {
+	args := cdromArgs([]cdrom{
+		{Path: "a.iso", Interface: "ide"},
+		{Path: "https://example.com/b.iso?a=1,2", Interface: "ide"},
+	})
+	expected := []string{"file=https://example.com/b.iso?a=1,,2,media=cdrom"}
+	if !reflect.DeepEqual(args["-drive"], expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
+
+func TestCheckRangeRequests(t *testing.T) {
+	ranges := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/a.iso" {
+			http.NotFound(w, r)
+			return
+		}
+		if ranges {
+			w.Header().Set("Accept-Ranges", "bytes")
+		}
+	}))
+	defer ts.Close()
+
+	if err := checkRangeRequests(ts.URL + "/a.iso"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := checkRangeRequests(ts.URL + "/b.iso"); err == nil {
+		t.Fatal("should have error")
+	}
+
+	ranges = false
+	if err := checkRangeRequests(ts.URL + "/a.iso"); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,253 @@
+package qemu
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/multistep"
+)
+
+// netModes are the valid values of net_mode. In user mode the VM is behind
+// Qemu's NAT and SSH is forwarded to it from the host. In bridge and tap
+// mode the VM gets an address on a host network and is connected to
+// directly.
+var netModes = map[string]bool{
+	"bridge": true,
+	"tap":    true,
+	"user":   true,
+}
+
+// arpTablePath is the host's ARP table, which is searched for the address
+// of the VM when there is no lease file.
+var arpTablePath = "/proc/net/arp"
+
+// prepareNetwork validates the network mode, generating a MAC address
+// for the VM if it needs one to be found on the network.
+func (c *Config) prepareNetwork() []error {
+	var errs []error
+
+	if c.NetMode == "" {
+		c.NetMode = "user"
+	}
+
+	if !netModes[c.NetMode] {
+		errs = append(errs, fmt.Errorf(
+			"net_mode must be 'user', 'bridge' or 'tap', not: %s", c.NetMode))
+		return errs
+	}
+
+	switch c.NetMode {
+	case "bridge":
+		if c.BridgeName == "" {
+			c.BridgeName = "virbr0"
+		}
+	case "tap":
+		if c.TapDevice == "" {
+			errs = append(errs, errors.New("tap_device must be set when net_mode is 'tap'"))
+		}
+	}
+
+	if c.NetMode == "user" {
+		if c.BridgeName != "" || c.TapDevice != "" || c.NetLeaseFile != "" {
+			errs = append(errs, errors.New(
+				"bridge_name, tap_device and net_lease_file require net_mode\n"+
+					"to be 'bridge' or 'tap'"))
+		}
+
+		return errs
+	}
+
+	if c.NetIPv6 {
+		errs = append(errs, errors.New("net_ipv6 requires net_mode to be 'user'"))
+	}
+
+	// The VM is found on the network by the MAC address of its first
+	// network device.
+	if mac, ok := c.NetDevices[0].Options["mac"]; ok {
+		c.MacAddress = mac
+	} else if c.MacAddress == "" {
+		mac, err := randomMAC()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		c.MacAddress = mac
+	}
+
+	if _, err := net.ParseMAC(c.MacAddress); err != nil {
+		errs = append(errs, fmt.Errorf("mac_address is invalid: %s", c.MacAddress))
+	}
+
+	return errs
+}
+
+// netInterface returns the name of the host interface that the VM is
+// attached to, if it isn't in user mode.
+func (c *Config) netInterface() string {
+	switch c.NetMode {
+	case "bridge":
+		return c.BridgeName
+	case "tap":
+		return c.TapDevice
+	default:
+		return ""
+	}
+}
+
+// netdevArg returns the value of the -netdev argument that the first
+// network device is attached to, with the given id.
+func (c *Config) netdevArg(id string) string {
+	switch c.NetMode {
+	case "bridge":
+		return fmt.Sprintf("bridge,id=%s,br=%s", id, c.BridgeName)
+	case "tap":
+		return fmt.Sprintf("tap,id=%s,ifname=%s,script=no,downscript=no", id, c.TapDevice)
+	default:
+		return fmt.Sprintf("user,id=%s", id)
+	}
+}
+
+// randomMAC returns a random MAC address in the range that Qemu uses for
+// its own, with locally administered addresses.
+func randomMAC() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Error generating a MAC address: %s", err)
+	}
+
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", b[0], b[1], b[2]), nil
+}
+
+// hostHTTPIP returns the address that the VM reaches the host on, which
+// is used for {{ .HTTPIP }}. In user mode this is the gateway of Qemu's
+// NAT. Otherwise it is the first IPv4 address of the host interface that
+// the VM is attached to, or an empty string if it has none.
+func hostHTTPIP(config *Config) string {
+	name := config.netInterface()
+	if name == "" {
+		return "10.0.2.2"
+	}
+
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return ""
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet.IP.String()
+		}
+	}
+
+	return ""
+}
+
+// guestAddress returns the address of the VM on the network it is attached
+// to, looked up by its MAC address in the lease file if there is one, and
+// in the ARP table of the host otherwise. Once it is found it is kept in
+// the "guestAddress" state so that later connections use the same one.
+func guestAddress(state multistep.StateBag) (string, error) {
+	if addr, ok := state.GetOk("guestAddress"); ok {
+		return addr.(string), nil
+	}
+
+	config := state.Get("config").(*Config)
+	mac, err := net.ParseMAC(config.MacAddress)
+	if err != nil {
+		return "", err
+	}
+
+	var addr string
+	if config.NetLeaseFile != "" {
+		addr, err = leaseFileAddress(config.NetLeaseFile, mac)
+	} else {
+		addr, err = arpTableAddress(arpTablePath, mac)
+	}
+	if err != nil {
+		return "", err
+	}
+	if addr == "" {
+		return "", fmt.Errorf("no address found yet for the VM with MAC address %s", mac)
+	}
+
+	state.Put("guestAddress", addr)
+	return addr, nil
+}
+
+// leaseFileAddress looks up the address leased to the MAC address in a
+// DHCP lease file. Both the leases of dnsmasq, one lease per line, and
+// the JSON status files that libvirt keeps for its networks are read.
+// The last matching lease wins since it is the most recent.
+func leaseFileAddress(path string, mac net.HardwareAddr) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var addr string
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		var leases []struct {
+			IPAddress  string `json:"ip-address"`
+			MACAddress string `json:"mac-address"`
+		}
+		if err := json.Unmarshal(data, &leases); err != nil {
+			return "", fmt.Errorf("Error parsing lease file %s: %s", path, err)
+		}
+		for _, l := range leases {
+			if sameMAC(l.MACAddress, mac) {
+				addr = l.IPAddress
+			}
+		}
+
+		return addr, nil
+	}
+
+	// dnsmasq: EXPIRY MAC IP HOSTNAME CLIENT-ID
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && sameMAC(fields[1], mac) {
+			addr = fields[2]
+		}
+	}
+
+	return addr, scanner.Err()
+}
+
+// arpTableAddress looks up the address of the MAC address in an ARP table
+// in the format of /proc/net/arp.
+func arpTableAddress(path string, mac net.HardwareAddr) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	// IP-ADDRESS HW-TYPE FLAGS HW-ADDRESS MASK DEVICE, after a header.
+	// Incomplete entries have a flags of 0x0.
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && fields[2] != "0x0" && sameMAC(fields[3], mac) {
+			return fields[0], nil
+		}
+	}
+
+	return "", nil
+}
+
+func sameMAC(v string, mac net.HardwareAddr) bool {
+	other, err := net.ParseMAC(v)
+	return err == nil && other.String() == mac.String()
+}
@@ -0,0 +1,167 @@
+package qemu
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+)
+
+func TestBuilderPrepare_NetMode(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Default
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.NetMode != "user" {
+		t.Fatalf("bad: %s", b.config.NetMode)
+	}
+	if b.config.MacAddress != "" {
+		t.Fatalf("bad: %s", b.config.MacAddress)
+	}
+
+	// Bridge defaults to virbr0 and generates a MAC address
+	config["net_mode"] = "bridge"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.BridgeName != "virbr0" {
+		t.Fatalf("bad: %s", b.config.BridgeName)
+	}
+	if _, err := net.ParseMAC(b.config.MacAddress); err != nil {
+		t.Fatalf("bad: %s", b.config.MacAddress)
+	}
+
+	// Tap requires a device
+	config["net_mode"] = "tap"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["tap_device"] = "tap0"
+	config["mac_address"] = "52:54:00:12:34:56"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.MacAddress != "52:54:00:12:34:56" {
+		t.Fatalf("bad: %s", b.config.MacAddress)
+	}
+
+	// Bad MAC address
+	config["mac_address"] = "nope"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Settings that only apply to bridge and tap
+	delete(config, "mac_address")
+	config["net_mode"] = "user"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad
+	delete(config, "tap_device")
+	config["net_mode"] = "bad"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestLeaseFileAddress(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	mac, _ := net.ParseMAC("52:54:00:12:34:56")
+	cases := map[string]string{
+		"dnsmasq": "1500000000 52:54:00:aa:bb:cc 192.168.122.10 other *\n" +
+			"1500000000 52:54:00:12:34:56 192.168.122.20 guest *\n",
+		"libvirt": `[
+  {
+    "ip-address": "192.168.122.20",
+    "mac-address": "52:54:00:12:34:56",
+    "hostname": "guest"
+  }
+]`,
+	}
+
+	for name, contents := range cases {
+		path := filepath.Join(td, name)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		addr, err := leaseFileAddress(path, mac)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if addr != "192.168.122.20" {
+			t.Fatalf("%s: bad: %s", name, addr)
+		}
+	}
+
+	// A lease file that doesn't exist yet has no leases
+	addr, err := leaseFileAddress(filepath.Join(td, "missing"), mac)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if addr != "" {
+		t.Fatalf("bad: %s", addr)
+	}
+}
+
+func TestGuestAddress_arpTable(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.WriteString(
+		"IP address       HW type     Flags       HW address            Mask     Device\n" +
+			"192.168.122.30   0x1         0x0         52:54:00:12:34:56     *        virbr0\n")
+	tf.Close()
+
+	old := arpTablePath
+	arpTablePath = tf.Name()
+	defer func() { arpTablePath = old }()
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{NetMode: "bridge", MacAddress: "52:54:00:12:34:56"})
+
+	// Incomplete entries are ignored
+	if _, err := guestAddress(state); err == nil {
+		t.Fatal("should have error")
+	}
+
+	ioutil.WriteFile(tf.Name(), []byte(
+		"IP address       HW type     Flags       HW address            Mask     Device\n"+
+			"192.168.122.30   0x1         0x2         52:54:00:12:34:56     *        virbr0\n"), 0644)
+	addr, err := guestAddress(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if addr != "192.168.122.30" {
+		t.Fatalf("bad: %s", addr)
+	}
+	if v := state.Get("guestAddress"); v != "192.168.122.30" {
+		t.Fatalf("bad: %#v", v)
+	}
+}
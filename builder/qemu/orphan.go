@@ -0,0 +1,87 @@
+package qemu
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// qemuOrphan is a Qemu process that was started by a Packer process
+// which is no longer running, usually because it crashed or was killed
+// before it could clean up.
+type qemuOrphan struct {
+	Pid     int
+	PidFile string
+}
+
+// pidFileName returns the name of the pidfile for a VM started by the
+// current Packer process. The Packer pid is part of the name so that
+// later builds can tell whether the owner of a pidfile is still alive.
+func pidFileName(vmName string) string {
+	return fmt.Sprintf("%d-%s.pid", os.Getpid(), vmName)
+}
+
+// findOrphans scans the pidfiles in dir for Qemu processes whose Packer
+// process is gone. Pidfiles of processes that already exited are stale
+// and are removed.
+func findOrphans(dir string) ([]qemuOrphan, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pid"))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []qemuOrphan
+	for _, path := range paths {
+		name := filepath.Base(path)
+		idx := strings.Index(name, "-")
+		if idx <= 0 {
+			continue
+		}
+
+		owner, err := strconv.Atoi(name[:idx])
+		if err != nil {
+			continue
+		}
+		if processAlive(owner) {
+			continue
+		}
+
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		pid, err := strconv.Atoi(string(bytes.TrimSpace(raw)))
+		if err != nil || !processAlive(pid) || !ownsPidFile(pid, path) {
+			os.Remove(path)
+			continue
+		}
+
+		result = append(result, qemuOrphan{Pid: pid, PidFile: path})
+	}
+
+	return result, nil
+}
+
+// ownsPidFile guards against pid reuse by checking that the command
+// line of the process refers to the pidfile. If the command line can't
+// be read, such as on systems without /proc, the process is assumed to
+// be the one that wrote the pidfile.
+func ownsPidFile(pid int, path string) bool {
+	cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return true
+	}
+
+	for _, arg := range bytes.Split(cmdline, []byte{0}) {
+		if string(arg) == path {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+package qemu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// deadPid returns the pid of a process that has already exited.
+func deadPid(t *testing.T) int {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return cmd.Process.Pid
+}
+
+func writePidFile(t *testing.T, path string, pid int) {
+	err := ioutil.WriteFile(path, []byte(fmt.Sprintf("%d\n", pid)), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestFindOrphans(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("requires /proc")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	owner := deadPid(t)
+
+	// A running "Qemu" whose command line refers to its pidfile
+	orphanPath := filepath.Join(td, fmt.Sprintf("%d-orphan.pid", owner))
+	cmd := exec.Command("sh", "-c", "sleep 30; :", "sh", orphanPath)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer cmd.Process.Kill()
+	writePidFile(t, orphanPath, cmd.Process.Pid)
+
+	// A pidfile whose Qemu already exited
+	stalePath := filepath.Join(td, fmt.Sprintf("%d-stale.pid", owner))
+	writePidFile(t, stalePath, deadPid(t))
+
+	// A pidfile whose pid was reused by an unrelated process
+	reusedPath := filepath.Join(td, fmt.Sprintf("%d-reused.pid", owner))
+	writePidFile(t, reusedPath, os.Getpid())
+
+	// A pidfile owned by a running Packer
+	ownedPath := filepath.Join(td, pidFileName("owned"))
+	writePidFile(t, ownedPath, cmd.Process.Pid)
+
+	orphans, err := findOrphans(td)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(orphans) != 1 {
+		t.Fatalf("bad: %#v", orphans)
+	}
+	if orphans[0].Pid != cmd.Process.Pid || orphans[0].PidFile != orphanPath {
+		t.Fatalf("bad: %#v", orphans[0])
+	}
+
+	for _, path := range []string{stalePath, reusedPath} {
+		if _, err := os.Stat(path); err == nil {
+			t.Fatalf("should remove: %s", path)
+		}
+	}
+	if _, err := os.Stat(ownedPath); err != nil {
+		t.Fatalf("should keep: %s", ownedPath)
+	}
+}
+
+func TestFindOrphans_noDir(t *testing.T) {
+	orphans, err := findOrphans(filepath.Join(os.TempDir(), "packer-i-dont-exist"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(orphans) != 0 {
+		t.Fatalf("bad: %#v", orphans)
+	}
+}
@@ -0,0 +1,122 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"regexp"
+)
+
+// PortForward forwards a port of the host to a port of the guest through
+// the user mode network of the first network device, as is done for SSH.
+type PortForward struct {
+	GuestPort uint   `mapstructure:"guest_port"`
+	HostPort  uint   `mapstructure:"host_port"`
+	Name      string `mapstructure:"name"`
+	Protocol  string `mapstructure:"protocol"`
+}
+
+var portForwardNameRe = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// BuildDataKey is the key of the host port in the build data that
+// provisioners get.
+func (f *PortForward) BuildDataKey() string {
+	return "HostPort_" + f.Name
+}
+
+// hostfwd returns the hostfwd option of the user mode network that
+// forwards the port on all addresses of the host, or all IPv6 addresses
+// if ipv6 is true.
+func (f *PortForward) hostfwd(ipv6 bool) string {
+	if ipv6 {
+		return fmt.Sprintf("%s:[::]:%d-:%d", f.Protocol, f.HostPort, f.GuestPort)
+	}
+
+	return fmt.Sprintf("%s::%d-:%d", f.Protocol, f.HostPort, f.GuestPort)
+}
+
+func (c *Config) preparePortForwards() []error {
+	var errs []error
+
+	if len(c.PortForwards) > 0 && c.NetMode != "user" {
+		errs = append(errs, errors.New(
+			"host_port_forwards require net_mode to be 'user', other modes\n"+
+				"connect to the guest directly"))
+	}
+
+	names := make(map[string]bool)
+	hostPorts := make(map[string]bool)
+	for i := range c.PortForwards {
+		f := &c.PortForwards[i]
+		name := fmt.Sprintf("host_port_forwards[%d]", i)
+
+		if f.Protocol == "" {
+			f.Protocol = "tcp"
+		}
+		if f.Protocol != "tcp" && f.Protocol != "udp" {
+			errs = append(errs, fmt.Errorf(
+				"%s: protocol must be 'tcp' or 'udp', not: %s", name, f.Protocol))
+		}
+
+		if f.GuestPort == 0 || f.GuestPort > 65535 {
+			errs = append(errs, fmt.Errorf(
+				"%s: guest_port must be a port from 1 to 65535", name))
+		}
+		if f.HostPort > 65535 {
+			errs = append(errs, fmt.Errorf(
+				"%s: host_port must be a port from 1 to 65535", name))
+		}
+
+		if f.Name == "" {
+			f.Name = fmt.Sprintf("%d", f.GuestPort)
+			if f.Protocol != "tcp" {
+				f.Name += "_" + f.Protocol
+			}
+		}
+		if !portForwardNameRe.MatchString(f.Name) {
+			errs = append(errs, fmt.Errorf(
+				"%s: name can only contain letters, digits and underscores: %s", name, f.Name))
+		}
+		if names[f.Name] {
+			errs = append(errs, fmt.Errorf("%s: name %s is already used", name, f.Name))
+		}
+		names[f.Name] = true
+
+		if f.HostPort != 0 {
+			key := fmt.Sprintf("%s/%d", f.Protocol, f.HostPort)
+			if hostPorts[key] {
+				errs = append(errs, fmt.Errorf(
+					"%s: host_port %d is already forwarded", name, f.HostPort))
+			}
+			hostPorts[key] = true
+		}
+	}
+
+	return errs
+}
+
+// findHostPort returns a random port between min and max that is free on
+// the host for the protocol and not in used.
+func findHostPort(protocol string, min, max uint, used map[uint]bool) (uint, error) {
+	for _, offset := range rand.Perm(int(max-min) + 1) {
+		port := min + uint(offset)
+		if used[port] {
+			continue
+		}
+
+		addr := fmt.Sprintf(":%d", port)
+		if protocol == "udp" {
+			if c, err := net.ListenPacket("udp", addr); err == nil {
+				c.Close()
+				return port, nil
+			}
+		} else if l, err := net.Listen("tcp", addr); err == nil {
+			l.Close()
+			return port, nil
+		}
+	}
+
+	return 0, fmt.Errorf(
+		"No free %s port was found between %d and %d.", protocol, min, max)
+}
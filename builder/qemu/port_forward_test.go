@@ -0,0 +1,108 @@
+package qemu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestBuilderPrepare_PortForwards(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["host_port_forwards"] = []map[string]interface{}{
+		{"guest_port": 5985, "name": "winrm"},
+		{"guest_port": 53, "protocol": "udp", "host_port": 5353},
+		{"guest_port": 80},
+	}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	expected := []PortForward{
+		{GuestPort: 5985, Name: "winrm", Protocol: "tcp"},
+		{GuestPort: 53, HostPort: 5353, Name: "53_udp", Protocol: "udp"},
+		{GuestPort: 80, Name: "80", Protocol: "tcp"},
+	}
+	if !reflect.DeepEqual(b.config.PortForwards, expected) {
+		t.Fatalf("bad: %#v", b.config.PortForwards)
+	}
+
+	cases := [][]map[string]interface{}{
+		// No guest port
+		{{"host_port": 8080}},
+		// Bad protocol
+		{{"guest_port": 80, "protocol": "sctp"}},
+		// Bad name
+		{{"guest_port": 80, "name": "web-server"}},
+		// Duplicate names
+		{{"guest_port": 80}, {"guest_port": 80}},
+		// Duplicate host ports
+		{{"guest_port": 80, "host_port": 8080}, {"guest_port": 81, "host_port": 8080}},
+	}
+	for _, tc := range cases {
+		config["host_port_forwards"] = tc
+		b = Builder{}
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("%#v: should have error", tc)
+		}
+	}
+
+	// Only user mode networking forwards ports
+	config["host_port_forwards"] = []map[string]interface{}{{"guest_port": 80}}
+	config["net_mode"] = "bridge"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestPortForwardHostfwd(t *testing.T) {
+	f := PortForward{GuestPort: 80, HostPort: 8080, Protocol: "tcp"}
+	if v := f.hostfwd(false); v != "tcp::8080-:80" {
+		t.Fatalf("bad: %s", v)
+	}
+	if v := f.hostfwd(true); v != "tcp:[::]:8080-:80" {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestStepForwardPorts(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("sshHostPort", uint(2222))
+	state.Put("config", &Config{
+		PortForwards: []PortForward{
+			{GuestPort: 5985, Name: "winrm", Protocol: "tcp"},
+			{GuestPort: 80, HostPort: 8080, Name: "80", Protocol: "tcp"},
+		},
+		SSHHostPortMin: 2222,
+		SSHHostPortMax: 2223,
+	})
+
+	step := new(stepForwardPorts)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+
+	// The SSH port is skipped
+	forwards := state.Get("portForwards").([]PortForward)
+	if forwards[0].HostPort != 2223 || forwards[1].HostPort != 8080 {
+		t.Fatalf("bad: %#v", forwards)
+	}
+
+	expected := packer.BuildData{
+		"SSHHostPort":    "2222",
+		"HostPort_winrm": "2223",
+		"HostPort_80":    "8080",
+	}
+	if data := state.Get("build_data"); !reflect.DeepEqual(data, expected) {
+		t.Fatalf("bad: %#v", data)
+	}
+}
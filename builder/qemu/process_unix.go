@@ -0,0 +1,41 @@
+// +build !windows
+
+package qemu
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// qmpSupported is whether Qemu is controlled through a QMP monitor on a
+// Unix socket.
+const qmpSupported = true
+
+// setProcessGroup starts the command in its own process group so that
+// Qemu and any helpers it spawns can be killed together.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the process group led by the given pid.
+func killProcessGroup(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil {
+		// The process may not be a group leader if it wasn't
+		// started by us, so fall back to killing just the process.
+		return syscall.Kill(pid, syscall.SIGKILL)
+	}
+
+	return nil
+}
+
+// processAlive reports whether a process with the given pid exists.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
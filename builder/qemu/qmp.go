@@ -0,0 +1,109 @@
+package qemu
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// qmpTimeout is how long a QMP command may take, including connecting.
+const qmpTimeout = 10 * time.Second
+
+// qmpResponse is a message from the QEMU Machine Protocol (QMP) monitor.
+// Events, which can arrive at any time, have Event set.
+type qmpResponse struct {
+	QMP    json.RawMessage `json:"QMP"`
+	Return json.RawMessage `json:"return"`
+	Error  *struct {
+		Class string `json:"class"`
+		Desc  string `json:"desc"`
+	} `json:"error"`
+	Event string `json:"event"`
+}
+
+// qmpCommand is a command to the QMP monitor, with optional arguments.
+type qmpCommand struct {
+	Execute   string      `json:"execute"`
+	Arguments interface{} `json:"arguments,omitempty"`
+}
+
+// qmpExecute connects to the QMP monitor listening on the Unix socket at
+// path and executes the command, such as "system_powerdown".
+func qmpExecute(path, command string) error {
+	return qmpExecuteArgs(path, command, nil)
+}
+
+// qmpExecuteArgs is like qmpExecute, for commands that take arguments.
+func qmpExecuteArgs(path, command string, arguments interface{}) error {
+	conn, err := net.DialTimeout("unix", path, qmpTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(qmpTimeout))
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+
+	// The monitor greets us, then commands can only be executed once
+	// capabilities are negotiated.
+	var greeting qmpResponse
+	if err := dec.Decode(&greeting); err != nil {
+		return fmt.Errorf("Error reading QMP greeting: %s", err)
+	}
+	if greeting.QMP == nil {
+		return fmt.Errorf("Unexpected QMP greeting")
+	}
+
+	commands := []qmpCommand{
+		{Execute: "qmp_capabilities"},
+		{Execute: command, Arguments: arguments},
+	}
+	for _, c := range commands {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+
+		for {
+			var resp qmpResponse
+			if err := dec.Decode(&resp); err != nil {
+				return fmt.Errorf("Error reading QMP response: %s", err)
+			}
+			if resp.Event != "" {
+				continue
+			}
+			if resp.Error != nil {
+				return fmt.Errorf("QMP command %s failed: %s", c.Execute, resp.Error.Desc)
+			}
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// qmpKeyEventArgs returns the arguments of input-send-event that press or
+// release the key that types the X keysym.
+func qmpKeyEventArgs(keysym uint32, down bool) (interface{}, error) {
+	name, ok := qcode(keysym)
+	if !ok {
+		return nil, fmt.Errorf("Key %#x can't be sent through QMP", keysym)
+	}
+
+	return map[string]interface{}{
+		"events": []interface{}{
+			map[string]interface{}{
+				"type": "key",
+				"data": map[string]interface{}{
+					"down": down,
+					"key": map[string]interface{}{
+						"type": "qcode",
+						"data": name,
+					},
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,112 @@
+package qemu
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// testQMPServer starts a fake QMP monitor on a Unix socket that answers
+// commands with the given responses, recording the commands it gets.
+func testQMPServer(t *testing.T, responses map[string]string) (string, <-chan []string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("QMP is not supported on Windows")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := filepath.Join(td, "qmp.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(td)
+		t.Fatalf("err: %s", err)
+	}
+
+	commandsCh := make(chan []string, 1)
+	go func() {
+		defer os.RemoveAll(td)
+		defer l.Close()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte(`{"QMP": {"version": {}, "capabilities": []}}` + "\n"))
+
+		var commands []string
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				break
+			}
+
+			var cmd map[string]string
+			json.Unmarshal(line, &cmd)
+			commands = append(commands, cmd["execute"])
+
+			resp, ok := responses[cmd["execute"]]
+			if !ok {
+				resp = `{"return": {}}`
+			}
+			conn.Write([]byte(resp + "\n"))
+		}
+
+		commandsCh <- commands
+	}()
+
+	return path, commandsCh
+}
+
+func TestQMPExecute(t *testing.T) {
+	path, commandsCh := testQMPServer(t, map[string]string{
+		"system_powerdown": `{"event": "POWERDOWN"}` + "\n" + `{"return": {}}`,
+	})
+
+	if err := qmpExecute(path, "system_powerdown"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"qmp_capabilities", "system_powerdown"}
+	if commands := <-commandsCh; !reflect.DeepEqual(commands, expected) {
+		t.Fatalf("bad: %#v", commands)
+	}
+}
+
+func TestQMPExecute_error(t *testing.T) {
+	path, _ := testQMPServer(t, map[string]string{
+		"system_powerdown": `{"error": {"class": "GenericError", "desc": "nope"}}`,
+	})
+
+	if err := qmpExecute(path, "system_powerdown"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestQMPExecuteArgs(t *testing.T) {
+	path, commandsCh := testQMPServer(t, nil)
+
+	args, err := qmpKeyEventArgs('A', true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := qmpExecuteArgs(path, "input-send-event", args); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"qmp_capabilities", "input-send-event"}
+	if commands := <-commandsCh; !reflect.DeepEqual(commands, expected) {
+		t.Fatalf("bad: %#v", commands)
+	}
+}
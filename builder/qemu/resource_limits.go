@@ -0,0 +1,168 @@
+package qemu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// ResourceLimits limits the CPU, memory and I/O that Qemu can use on the
+// host, so that builds sharing a host don't starve each other.
+type ResourceLimits struct {
+	CgroupPath  string `mapstructure:"cgroup_path"`
+	CPUShares   uint   `mapstructure:"cpu_shares"`
+	IONiceClass string `mapstructure:"ionice_class"`
+	IONiceLevel *int   `mapstructure:"ionice_level"`
+	MemoryMax   uint   `mapstructure:"memory_max"`
+	Method      string `mapstructure:"method"`
+}
+
+var ioniceClasses = map[string]string{
+	"realtime":    "1",
+	"best-effort": "2",
+	"idle":        "3",
+}
+
+// enabled returns true if any limit is set.
+func (l *ResourceLimits) enabled() bool {
+	return l.CPUShares != 0 || l.MemoryMax != 0 || l.IONiceClass != ""
+}
+
+// cgroupLimits returns true if a limit is set that is enforced by a
+// cgroup, rather than by the I/O priority of the process.
+func (l *ResourceLimits) cgroupLimits() bool {
+	return l.CPUShares != 0 || l.MemoryMax != 0
+}
+
+// cpuWeight converts cpu_shares to the weight of cgroup v2 the same way
+// systemd does, so that the default of 1024 shares is the default weight
+// of 100.
+func (l *ResourceLimits) cpuWeight() uint {
+	w := l.CPUShares * 100 / 1024
+	if w < 1 {
+		w = 1
+	} else if w > 10000 {
+		w = 10000
+	}
+
+	return w
+}
+
+// commandPrefix returns the command that Qemu is run with to apply the
+// limits, or nil if it is run directly. Both systemd-run --scope and
+// ionice exec the command, so the pid of the prefix is the pid of Qemu.
+func (l *ResourceLimits) commandPrefix() []string {
+	var prefix []string
+	if l.Method == "systemd-run" && l.cgroupLimits() {
+		prefix = append(prefix, "systemd-run", "--scope", "--quiet", "--collect")
+		if os.Geteuid() != 0 {
+			prefix = append(prefix, "--user")
+		}
+		if l.CPUShares != 0 {
+			prefix = append(prefix, "-p", fmt.Sprintf("CPUWeight=%d", l.cpuWeight()))
+		}
+		if l.MemoryMax != 0 {
+			prefix = append(prefix, "-p", fmt.Sprintf("MemoryMax=%dM", l.MemoryMax))
+		}
+	}
+
+	if l.IONiceClass != "" {
+		prefix = append(prefix, "ionice", "-c", ioniceClasses[l.IONiceClass])
+		if l.IONiceLevel != nil {
+			prefix = append(prefix, "-n", strconv.Itoa(*l.IONiceLevel))
+		}
+	}
+
+	return prefix
+}
+
+// joinCgroup creates a cgroup v2 group with the limits under cgroup_path
+// and moves the process with the given pid into it. The path of the group
+// is returned so that it can be removed once the process exits.
+func (l *ResourceLimits) joinCgroup(pid int) (string, error) {
+	dir := filepath.Join(l.CgroupPath, fmt.Sprintf("packer-qemu-%d", pid))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", fmt.Errorf("Error creating cgroup: %s", err)
+	}
+
+	files := [][2]string{}
+	if l.CPUShares != 0 {
+		files = append(files, [2]string{"cpu.weight", strconv.FormatUint(uint64(l.cpuWeight()), 10)})
+	}
+	if l.MemoryMax != 0 {
+		files = append(files, [2]string{"memory.max", strconv.FormatUint(uint64(l.MemoryMax)*1024*1024, 10)})
+	}
+	files = append(files, [2]string{"cgroup.procs", strconv.Itoa(pid)})
+
+	for _, f := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, f[0]), []byte(f[1]), 0644); err != nil {
+			os.Remove(dir)
+			return "", fmt.Errorf("Error writing %s of cgroup: %s", f[0], err)
+		}
+	}
+
+	return dir, nil
+}
+
+func (c *Config) prepareResourceLimits() []error {
+	l := &c.ResourceLimits
+	if !l.enabled() {
+		return nil
+	}
+
+	var errs []error
+	if runtime.GOOS != "linux" {
+		errs = append(errs, fmt.Errorf("resource_limits are only supported on Linux"))
+	}
+
+	if l.Method == "" {
+		l.Method = "systemd-run"
+	}
+
+	switch l.Method {
+	case "systemd-run":
+		if l.CgroupPath != "" {
+			errs = append(errs, fmt.Errorf("resource_limits: cgroup_path requires the cgroup method"))
+		}
+	case "cgroup":
+		if l.CgroupPath == "" {
+			l.CgroupPath = "/sys/fs/cgroup"
+		}
+		if _, err := os.Stat(filepath.Join(l.CgroupPath, "cgroup.controllers")); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"resource_limits: cgroup_path %s is not a cgroup v2 group: %s", l.CgroupPath, err))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("resource_limits: method must be 'systemd-run' or 'cgroup'"))
+	}
+
+	if l.CPUShares != 0 && (l.CPUShares < 2 || l.CPUShares > 262144) {
+		errs = append(errs, fmt.Errorf("resource_limits: cpu_shares must be between 2 and 262144"))
+	}
+
+	if l.MemoryMax != 0 && l.MemoryMax <= c.Memory {
+		errs = append(errs, fmt.Errorf(
+			"resource_limits: memory_max must be more than the memory of the VM, %d MB", c.Memory))
+	}
+
+	if l.IONiceClass != "" {
+		if _, ok := ioniceClasses[l.IONiceClass]; !ok {
+			errs = append(errs, fmt.Errorf(
+				"resource_limits: ionice_class must be 'realtime', 'best-effort' or 'idle'"))
+		}
+	}
+
+	if l.IONiceLevel != nil {
+		if l.IONiceClass == "" || l.IONiceClass == "idle" {
+			errs = append(errs, fmt.Errorf(
+				"resource_limits: ionice_level requires the realtime or best-effort ionice_class"))
+		} else if *l.IONiceLevel < 0 || *l.IONiceLevel > 7 {
+			errs = append(errs, fmt.Errorf("resource_limits: ionice_level must be between 0 and 7"))
+		}
+	}
+
+	return errs
+}
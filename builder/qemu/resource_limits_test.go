@@ -0,0 +1,144 @@
+package qemu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestBuilderPrepare_ResourceLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource_limits are only supported on Linux")
+	}
+
+	var b Builder
+	config := testConfig()
+	config["resource_limits"] = map[string]interface{}{
+		"cpu_shares":   512,
+		"memory_max":   1024,
+		"ionice_class": "best-effort",
+		"ionice_level": 7,
+	}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.ResourceLimits.Method != "systemd-run" {
+		t.Fatalf("bad: %s", b.config.ResourceLimits.Method)
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// A cgroup v2 group has a cgroup.controllers file
+	config["resource_limits"] = map[string]interface{}{
+		"cpu_shares":  512,
+		"method":      "cgroup",
+		"cgroup_path": td,
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(td, "cgroup.controllers"), nil, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	cases := []map[string]interface{}{
+		{"cpu_shares": 1},
+		{"memory_max": 512},
+		{"ionice_class": "low"},
+		{"ionice_class": "idle", "ionice_level": 0},
+		{"ionice_class": "best-effort", "ionice_level": 8},
+		{"cpu_shares": 512, "method": "nice"},
+		{"cpu_shares": 512, "cgroup_path": td},
+	}
+	for _, tc := range cases {
+		config["resource_limits"] = tc
+		b = Builder{}
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("%#v: should have error", tc)
+		}
+	}
+}
+
+func TestResourceLimitsCommandPrefix(t *testing.T) {
+	level := 3
+	l := ResourceLimits{
+		CPUShares:   2048,
+		MemoryMax:   1024,
+		IONiceClass: "best-effort",
+		IONiceLevel: &level,
+		Method:      "systemd-run",
+	}
+
+	expected := []string{"systemd-run", "--scope", "--quiet", "--collect"}
+	if os.Geteuid() != 0 {
+		expected = append(expected, "--user")
+	}
+	expected = append(expected,
+		"-p", "CPUWeight=200", "-p", "MemoryMax=1024M",
+		"ionice", "-c", "2", "-n", "3")
+	if v := l.commandPrefix(); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	// The cgroup method only needs ionice in front of Qemu
+	l.Method = "cgroup"
+	expected = []string{"ionice", "-c", "2", "-n", "3"}
+	if v := l.commandPrefix(); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	l.IONiceClass = ""
+	l.IONiceLevel = nil
+	if v := l.commandPrefix(); v != nil {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestResourceLimitsJoinCgroup(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	l := ResourceLimits{CPUShares: 1024, MemoryMax: 2048, CgroupPath: td}
+	dir, err := l.joinCgroup(42)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if dir != filepath.Join(td, "packer-qemu-42") {
+		t.Fatalf("bad: %s", dir)
+	}
+
+	expected := map[string]string{
+		"cpu.weight":   "100",
+		"memory.max":   "2147483648",
+		"cgroup.procs": "42",
+	}
+	for name, v := range expected {
+		raw, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if string(raw) != v {
+			t.Fatalf("%s: bad: %s", name, raw)
+		}
+	}
+}
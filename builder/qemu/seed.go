@@ -0,0 +1,111 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// seedISOTool is a tool that can create the seed ISO, with the arguments
+// that create OUTPUT with the volume label LABEL from the files in DIR.
+type seedISOTool struct {
+	Name string
+	Args []string
+}
+
+// seedISOTools are the tools that can create the seed ISO, in the order
+// they are looked for.
+var seedISOTools = []seedISOTool{
+	{"xorriso", []string{"-as", "mkisofs", "-quiet", "-J", "-r", "-V", "LABEL", "-o", "OUTPUT", "DIR"}},
+	{"genisoimage", []string{"-quiet", "-J", "-r", "-V", "LABEL", "-o", "OUTPUT", "DIR"}},
+	{"mkisofs", []string{"-quiet", "-J", "-r", "-V", "LABEL", "-o", "OUTPUT", "DIR"}},
+	{"hdiutil", []string{"makehybrid", "-iso", "-joliet", "-default-volume-name", "LABEL", "-o", "OUTPUT", "DIR"}},
+}
+
+// seedISOCommand returns the command that creates the ISO at output with
+// the given volume label from the files in dir, using the first of the
+// seedISOTools that is installed.
+func seedISOCommand(output, label, dir string) (*exec.Cmd, error) {
+	names := make([]string, 0, len(seedISOTools))
+	for _, tool := range seedISOTools {
+		names = append(names, tool.Name)
+
+		path, err := exec.LookPath(tool.Name)
+		if err != nil {
+			continue
+		}
+
+		args := make([]string, len(tool.Args))
+		for i, arg := range tool.Args {
+			switch arg {
+			case "LABEL":
+				arg = label
+			case "OUTPUT":
+				arg = output
+			case "DIR":
+				arg = dir
+			}
+			args[i] = arg
+		}
+
+		return exec.Command(path, args...), nil
+	}
+
+	return nil, fmt.Errorf(
+		"A tool to create the seed ISO wasn't found. Install one of: %s",
+		strings.Join(names, ", "))
+}
+
+// prepareSeed validates the contents of the seed ISO, adding user_data and
+// meta_data to them. A cloud-init NoCloud seed needs a meta-data file, so
+// one that names the instance after the VM is added if there is none.
+func (c *Config) prepareSeed() []error {
+	if len(c.CDContent) == 0 && c.UserData == "" && c.MetaData == "" {
+		if c.CDLabel != "" {
+			return []error{errors.New("cd_label requires cd_content, user_data or meta_data")}
+		}
+
+		return nil
+	}
+
+	var errs []error
+	if c.CDLabel == "" {
+		c.CDLabel = "cidata"
+	}
+
+	content := make(map[string]string, len(c.CDContent)+2)
+	for name, v := range c.CDContent {
+		clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(name)))
+		if name == "" || filepath.IsAbs(name) || strings.HasPrefix(name, "/") ||
+			clean == ".." || strings.HasPrefix(clean, "../") {
+			errs = append(errs, fmt.Errorf(
+				"cd_content: %q must be a path relative to the root of the ISO", name))
+			continue
+		}
+
+		content[clean] = v
+	}
+
+	for name, v := range map[string]string{"user-data": c.UserData, "meta-data": c.MetaData} {
+		if v == "" {
+			continue
+		}
+		if _, ok := content[name]; ok {
+			errs = append(errs, fmt.Errorf(
+				"%s can't be set along with %s in cd_content",
+				strings.Replace(name, "-", "_", 1), name))
+		}
+
+		content[name] = v
+	}
+
+	if _, ok := content["meta-data"]; !ok && c.CDLabel == "cidata" {
+		content["meta-data"] = fmt.Sprintf(
+			"instance-id: %s\nlocal-hostname: %s\n", c.VMName, c.VMName)
+	}
+
+	c.CDContent = content
+	return errs
+}
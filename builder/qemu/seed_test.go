@@ -0,0 +1,133 @@
+package qemu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestBuilderPrepare_Seed(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Nothing by default
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(b.config.CDContent) > 0 || b.config.CDLabel != "" {
+		t.Fatalf("bad: %#v %s", b.config.CDContent, b.config.CDLabel)
+	}
+
+	// user_data gets a default meta-data
+	config["user_data"] = "#cloud-config\n"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.CDLabel != "cidata" {
+		t.Fatalf("bad: %s", b.config.CDLabel)
+	}
+	if v := b.config.CDContent["user-data"]; v != "#cloud-config\n" {
+		t.Fatalf("bad: %q", v)
+	}
+	if v := b.config.CDContent["meta-data"]; !strings.Contains(v, "instance-id: packer-foo") {
+		t.Fatalf("bad: %q", v)
+	}
+
+	// Both user_data and user-data in cd_content
+	config["cd_content"] = map[string]string{"user-data": "x"}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Paths must stay within the ISO
+	delete(config, "user_data")
+	config["cd_content"] = map[string]string{"../user-data": "x"}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Other labels don't get a meta-data
+	config["cd_content"] = map[string]string{"openstack/latest/user_data": "x"}
+	config["cd_label"] = "config-2"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if _, ok := b.config.CDContent["meta-data"]; ok {
+		t.Fatalf("bad: %#v", b.config.CDContent)
+	}
+
+	// A label without contents
+	delete(config, "cd_content")
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepCreateSeedISO(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// The fake tool lists the files that would be on the ISO
+	tool := filepath.Join(td, "mkiso")
+	err = ioutil.WriteFile(tool, []byte("#!/bin/sh\ncd \"$3\" && find . -type f | sort > \"$2\"\necho \"$1\" >> \"$2\"\n"), 0755)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	old := seedISOTools
+	defer func() { seedISOTools = old }()
+	seedISOTools = []seedISOTool{{tool, []string{"LABEL", "OUTPUT", "DIR"}}}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("config", &Config{
+		CDContent: map[string]string{
+			"meta-data":          "instance-id: foo\n",
+			"user-data":          "#cloud-config\n",
+			"nested/config.json": "{}",
+		},
+		CDLabel: "cidata",
+	})
+
+	step := new(stepCreateSeedISO)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+
+	path := state.Get("seed_iso_path").(string)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := "./meta-data\n./nested/config.json\n./user-data\ncidata\n"
+	if string(data) != expected {
+		t.Fatalf("bad: %q", data)
+	}
+
+	step.Cleanup(state)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("seed ISO should be removed: %s", err)
+	}
+}
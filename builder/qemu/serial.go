@@ -0,0 +1,53 @@
+package qemu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// serialLogTailLines is how many lines of the serial console are shown
+// when a build fails.
+const serialLogTailLines = 20
+
+func (c *Config) prepareSerialLog() []error {
+	if c.SerialLogFile == "" {
+		return nil
+	}
+
+	var errs []error
+	if qemuArgsHas(c.QemuArgs, "-serial") {
+		errs = append(errs, fmt.Errorf("serial_log_file can't be used with a -serial qemuarg"))
+	}
+
+	path, err := filepath.Abs(c.SerialLogFile)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("serial_log_file is invalid: %s", err))
+	} else {
+		c.SerialLogFile = path
+	}
+
+	return errs
+}
+
+// serialArg returns the value of the -serial argument that writes the
+// serial console of the VM to the log file.
+func (c *Config) serialArg() string {
+	return "file:" + c.SerialLogFile
+}
+
+// tailFile returns the last n lines of the file at path.
+func tailFile(path string, n int) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
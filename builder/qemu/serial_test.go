@@ -0,0 +1,60 @@
+package qemu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuilderPrepare_SerialLogFile(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["serial_log_file"] = "serial.log"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !filepath.IsAbs(b.config.SerialLogFile) {
+		t.Fatalf("bad: %s", b.config.SerialLogFile)
+	}
+	if v := b.config.serialArg(); v != "file:"+b.config.SerialLogFile {
+		t.Fatalf("bad: %s", v)
+	}
+
+	// The serial console can't also be set with qemuargs
+	config["qemuargs"] = [][]string{{"-serial", "stdio"}}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestTailFile(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.WriteString("one\r\ntwo\nthree\nfour\n\n")
+	tf.Close()
+
+	tail, err := tailFile(tf.Name(), 2)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if tail != "three\nfour" {
+		t.Fatalf("bad: %q", tail)
+	}
+
+	tail, err = tailFile(tf.Name(), 10)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if tail != "one\r\ntwo\nthree\nfour" {
+		t.Fatalf("bad: %q", tail)
+	}
+}
@@ -0,0 +1,79 @@
+package qemu
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SharedFolder shares a directory of the host with the guest with VirtFS,
+// so that the guest can mount it over 9p by its mount tag.
+type SharedFolder struct {
+	HostPath      string `mapstructure:"host_path"`
+	MountTag      string `mapstructure:"mount_tag"`
+	ReadOnly      bool   `mapstructure:"read_only"`
+	SecurityModel string `mapstructure:"security_model"`
+}
+
+// Qemu limits mount tags to 31 bytes.
+const maxMountTagLen = 31
+
+var securityModels = map[string]struct{}{
+	"mapped-file":  {},
+	"mapped-xattr": {},
+	"none":         {},
+	"passthrough":  {},
+}
+
+// virtfsArg returns the value of the -virtfs argument that shares the
+// folder. Commas are doubled so that Qemu doesn't split the path on them.
+func (f *SharedFolder) virtfsArg(id string) string {
+	arg := fmt.Sprintf("local,id=%s,path=%s,mount_tag=%s,security_model=%s",
+		id, strings.Replace(f.HostPath, ",", ",,", -1), f.MountTag, f.SecurityModel)
+	if f.ReadOnly {
+		arg += ",readonly=on"
+	}
+
+	return arg
+}
+
+func (c *Config) prepareSharedFolders() []error {
+	var errs []error
+
+	tags := make(map[string]bool)
+	for i := range c.SharedFolders {
+		f := &c.SharedFolders[i]
+		name := fmt.Sprintf("shared_folders[%d]", i)
+
+		if f.HostPath == "" {
+			errs = append(errs, fmt.Errorf("%s: host_path must be specified", name))
+		} else if fi, err := os.Stat(f.HostPath); err != nil {
+			errs = append(errs, fmt.Errorf("%s: host_path is invalid: %s", name, err))
+		} else if !fi.IsDir() {
+			errs = append(errs, fmt.Errorf("%s: host_path must be a directory", name))
+		}
+
+		switch {
+		case f.MountTag == "":
+			errs = append(errs, fmt.Errorf("%s: mount_tag must be specified", name))
+		case len(f.MountTag) > maxMountTagLen || strings.ContainsAny(f.MountTag, ", "):
+			errs = append(errs, fmt.Errorf(
+				"%s: mount_tag must be at most %d characters without commas or spaces",
+				name, maxMountTagLen))
+		case tags[f.MountTag]:
+			errs = append(errs, fmt.Errorf("%s: mount_tag %s is already used", name, f.MountTag))
+		}
+		tags[f.MountTag] = true
+
+		if f.SecurityModel == "" {
+			f.SecurityModel = "none"
+		}
+		if _, ok := securityModels[f.SecurityModel]; !ok {
+			errs = append(errs, fmt.Errorf(
+				"%s: security_model must be 'passthrough', 'mapped-xattr', "+
+					"'mapped-file' or 'none'", name))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,77 @@
+package qemu
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestBuilderPrepare_SharedFolders(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	var b Builder
+	config := testConfig()
+	config["shared_folders"] = []map[string]interface{}{
+		{"host_path": td, "mount_tag": "assets", "read_only": true},
+	}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if f := b.config.SharedFolders[0]; f.SecurityModel != "none" || !f.ReadOnly {
+		t.Fatalf("bad: %#v", f)
+	}
+
+	cases := [][]map[string]interface{}{
+		// No host path
+		{{"mount_tag": "assets"}},
+		// Host path isn't a directory
+		{{"host_path": tf.Name(), "mount_tag": "assets"}},
+		// No mount tag
+		{{"host_path": td}},
+		// Mount tag is too long
+		{{"host_path": td, "mount_tag": "abcdefghijklmnopqrstuvwxyz0123456789"}},
+		// Duplicate mount tags
+		{{"host_path": td, "mount_tag": "assets"}, {"host_path": td, "mount_tag": "assets"}},
+		// Bad security model
+		{{"host_path": td, "mount_tag": "assets", "security_model": "mapped"}},
+	}
+	for _, tc := range cases {
+		config["shared_folders"] = tc
+		b = Builder{}
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("%#v: should have error", tc)
+		}
+	}
+}
+
+func TestSharedFolderVirtfsArg(t *testing.T) {
+	f := SharedFolder{
+		HostPath:      "/srv/a,b",
+		MountTag:      "assets",
+		SecurityModel: "mapped-xattr",
+	}
+	expected := "local,id=fsdev0,path=/srv/a,,b,mount_tag=assets,security_model=mapped-xattr"
+	if v := f.virtfsArg("fsdev0"); v != expected {
+		t.Fatalf("bad: %s", v)
+	}
+
+	f.ReadOnly = true
+	if v := f.virtfsArg("fsdev0"); v != expected+",readonly=on" {
+		t.Fatalf("bad: %s", v)
+	}
+}
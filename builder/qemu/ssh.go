@@ -1,21 +1,61 @@
 package qemu
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sync"
+
 	"github.com/mitchellh/multistep"
 	commonssh "github.com/mitchellh/packer/common/ssh"
 	"github.com/mitchellh/packer/communicator/ssh"
+	"github.com/mitchellh/packer/packer"
 	gossh "golang.org/x/crypto/ssh"
 )
 
 func commHost(state multistep.StateBag) (string, error) {
+	config := state.Get("config").(*Config)
+	if config.NetMode != "user" {
+		return guestAddress(state)
+	}
+
+	if addr, ok := state.GetOk("hostAddress"); ok {
+		return addr.(string), nil
+	}
+
 	return "127.0.0.1", nil
 }
 
 func commPort(state multistep.StateBag) (int, error) {
+	config := state.Get("config").(*Config)
+	if config.NetMode != "user" {
+		return config.Comm.Port(), nil
+	}
+
 	sshHostPort := state.Get("sshHostPort").(uint)
 	return int(sshHostPort), nil
 }
 
+// sshEndpoint returns the host and port that SSH of the guest is forwarded
+// to, or a port of zero if the guest isn't in user mode networking and is
+// connected to directly.
+func sshEndpoint(state multistep.StateBag) (string, int, error) {
+	config := state.Get("config").(*Config)
+	if config.NetMode != "user" {
+		return "", 0, nil
+	}
+
+	host, err := commHost(state)
+	if err != nil {
+		return "", 0, err
+	}
+
+	port, err := commPort(state)
+	return host, port, err
+}
+
 func sshConfig(state multistep.StateBag) (*gossh.ClientConfig, error) {
 	config := state.Get("config").(*Config)
 
@@ -34,8 +74,59 @@ func sshConfig(state multistep.StateBag) (*gossh.ClientConfig, error) {
 		auth = append(auth, gossh.PublicKeys(signer))
 	}
 
-	return &gossh.ClientConfig{
+	clientConfig := &gossh.ClientConfig{
 		User: config.Comm.SSHUsername,
 		Auth: auth,
-	}, nil
+	}
+
+	if config.PinSSHHostKey {
+		pin, ok := state.Get("ssh_host_key_pin").(*hostKeyPin)
+		if !ok {
+			pin = &hostKeyPin{ui: state.Get("ui").(packer.Ui)}
+			state.Put("ssh_host_key_pin", pin)
+		}
+
+		clientConfig.HostKeyCallback = pin.check
+	}
+
+	return clientConfig, nil
+}
+
+// hostKeyPin pins the host key of the guest to the key of the first SSH
+// connection of the build. Later connections, such as those after the
+// guest reboots, are refused if the key changed, which happens if the
+// forwarded port reached another VM or something listens in between.
+type hostKeyPin struct {
+	ui packer.Ui
+
+	l   sync.Mutex
+	key []byte
+}
+
+func (p *hostKeyPin) check(hostname string, remote net.Addr, key gossh.PublicKey) error {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if p.key == nil {
+		p.key = key.Marshal()
+		p.ui.Message(fmt.Sprintf("Pinned the SSH host key of the guest: %s %s",
+			key.Type(), hostKeyFingerprint(p.key)))
+		return nil
+	}
+
+	if !bytes.Equal(p.key, key.Marshal()) {
+		return fmt.Errorf(
+			"The SSH host key of the guest at %s changed during the build. "+
+				"Expected %s, got %s %s",
+			hostname, hostKeyFingerprint(p.key), key.Type(), hostKeyFingerprint(key.Marshal()))
+	}
+
+	return nil
+}
+
+// hostKeyFingerprint returns the SHA256 fingerprint of a host key in the
+// format of OpenSSH.
+func hostKeyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
 }
@@ -0,0 +1,72 @@
+package qemu
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func testHostKey(t *testing.T) gossh.PublicKey {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	key, err := gossh.NewPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return key
+}
+
+func TestHostKeyPin(t *testing.T) {
+	pin := &hostKeyPin{ui: packer.TestUi(t)}
+	key := testHostKey(t)
+
+	// The first key is pinned
+	if err := pin.check("127.0.0.1:2222", nil, key); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := pin.check("127.0.0.1:2222", nil, key); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Any other key is refused
+	if err := pin.check("127.0.0.1:2222", nil, testHostKey(t)); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestSSHConfig_pinHostKey(t *testing.T) {
+	config := &Config{}
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("config", config)
+
+	c, err := sshConfig(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.HostKeyCallback != nil {
+		t.Fatal("shouldn't pin the host key")
+	}
+
+	config.PinSSHHostKey = true
+	c, err = sshConfig(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.HostKeyCallback == nil {
+		t.Fatal("should pin the host key")
+	}
+
+	// The pin is kept for the connections after the first
+	if _, ok := state.GetOk("ssh_host_key_pin"); !ok {
+		t.Fatal("should keep the pin in the state")
+	}
+}
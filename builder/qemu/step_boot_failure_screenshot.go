@@ -0,0 +1,77 @@
+package qemu
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step runs the step that connects to the guest, and if it fails,
+// such as when SSH or WinRM isn't up before the timeout, saves a
+// screenshot of the display of the VM next to the output directory, which
+// is deleted when the build fails. The path of the screenshot is added to
+// the error, so that it can be seen what a headless installer was stuck on.
+//
+// Uses:
+//   config       *config
+//   driver       Driver
+//   qemu_version qemuVersion
+//   ui           packer.Ui
+//
+// Produces:
+//   boot_failure_screenshot string - The path of the screenshot.
+type stepBootFailureScreenshot struct {
+	Step multistep.Step
+}
+
+func (s *stepBootFailureScreenshot) Run(state multistep.StateBag) multistep.StepAction {
+	action := s.Step.Run(state)
+	if action != multistep.ActionHalt {
+		return action
+	}
+
+	rawErr, ok := state.GetOk("error")
+	if !ok {
+		return action
+	}
+	if _, ok := state.GetOk(multistep.StateCancelled); ok {
+		return action
+	}
+
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	// Older versions of Qemu only save PPM files
+	png := true
+	if v, ok := state.GetOk("qemu_version"); ok {
+		png = v.(qemuVersion).AtLeast(qemuVersionScreendumpPNG)
+	}
+	path := filepath.Clean(config.OutputDir) + "-boot-failure.png"
+	if !png {
+		path = filepath.Clean(config.OutputDir) + "-boot-failure.ppm"
+	}
+
+	path, err := filepath.Abs(path)
+	if err == nil {
+		err = driver.Screendump(path, png)
+	}
+	if err != nil {
+		// Machines without a display, such as s390x, have nothing to save
+		log.Printf("Error saving a screenshot of the VM: %s", err)
+		return action
+	}
+
+	ui.Message(fmt.Sprintf("A screenshot of the VM was saved to %s", path))
+	state.Put("boot_failure_screenshot", path)
+	state.Put("error", fmt.Errorf(
+		"%s\nA screenshot of the VM was saved to %s", rawErr, path))
+	return action
+}
+
+func (s *stepBootFailureScreenshot) Cleanup(state multistep.StateBag) {
+	s.Step.Cleanup(state)
+}
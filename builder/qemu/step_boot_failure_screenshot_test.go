@@ -0,0 +1,77 @@
+package qemu
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// testHaltStep is a step that fails with the error.
+type testHaltStep struct {
+	err error
+}
+
+func (s *testHaltStep) Run(state multistep.StateBag) multistep.StepAction {
+	state.Put("error", s.err)
+	return multistep.ActionHalt
+}
+
+func (s *testHaltStep) Cleanup(multistep.StateBag) {}
+
+func TestStepBootFailureScreenshot(t *testing.T) {
+	path, commandsCh := testQMPServer(t, nil)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{OutputDir: "output"})
+	state.Put("driver", &QemuDriver{qmpPath: path})
+	state.Put("qemu_version", qemuVersion{2, 5, 0})
+	state.Put("ui", packer.TestUi(t))
+
+	step := &stepBootFailureScreenshot{
+		Step: &testHaltStep{err: errors.New("Timeout waiting for SSH.")},
+	}
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	expected := []string{"qmp_capabilities", "screendump"}
+	if commands := <-commandsCh; !reflect.DeepEqual(commands, expected) {
+		t.Fatalf("bad: %#v", commands)
+	}
+
+	screenshot := state.Get("boot_failure_screenshot").(string)
+	if filepath.Base(screenshot) != "output-boot-failure.ppm" || !filepath.IsAbs(screenshot) {
+		t.Fatalf("bad: %s", screenshot)
+	}
+
+	err := state.Get("error").(error).Error()
+	if !strings.HasPrefix(err, "Timeout waiting for SSH.") || !strings.Contains(err, screenshot) {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestStepBootFailureScreenshot_noMonitor(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{OutputDir: "output"})
+	state.Put("driver", new(QemuDriver))
+	state.Put("ui", packer.TestUi(t))
+
+	step := &stepBootFailureScreenshot{
+		Step: &testHaltStep{err: errors.New("Timeout waiting for SSH.")},
+	}
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if _, ok := state.GetOk("boot_failure_screenshot"); ok {
+		t.Fatal("should not have a screenshot")
+	}
+	if err := state.Get("error").(error).Error(); err != "Timeout waiting for SSH." {
+		t.Fatalf("bad: %s", err)
+	}
+}
@@ -0,0 +1,51 @@
+package qemu
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step looks for Qemu processes left behind by previous builds that
+// crashed, and kills them if kill_orphans is set. Orphans often still
+// hold the VNC and SSH ports that a new build wants to use.
+type stepCleanOrphans struct{}
+
+func (s *stepCleanOrphans) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	orphans, err := findOrphans(config.PidDirectory)
+	if err != nil {
+		err := fmt.Errorf("Error looking for orphaned Qemu processes: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	for _, o := range orphans {
+		if !config.KillOrphans {
+			ui.Message(fmt.Sprintf(
+				"WARNING: Qemu process %d from a previous build is still running.\n"+
+					"It may hold ports this build needs. Set kill_orphans to true to\n"+
+					"have Packer kill it.", o.Pid))
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Killing orphaned Qemu process %d...", o.Pid))
+		if err := killProcessGroup(o.Pid); err != nil {
+			err := fmt.Errorf("Error killing orphaned Qemu process: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		os.Remove(o.PidFile)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCleanOrphans) Cleanup(state multistep.StateBag) {}
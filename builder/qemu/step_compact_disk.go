@@ -0,0 +1,92 @@
+package qemu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step compacts the disks once the VM has shut down by converting
+// them to new images with `qemu-img convert`, which leaves out the blocks
+// that are unallocated or only hold zeros. The qcow2 clusters are also
+// compressed if compression is set.
+type stepCompactDisk struct{}
+
+func (s *stepCompactDisk) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.SkipCompaction || config.Ephemeral {
+		return multistep.ActionContinue
+	}
+
+	var paths []string
+
+	// Converting an overlay would copy its backing file into it, which
+	// standalone_disk is for.
+	if !config.UseBackingFile || config.StandaloneDisk {
+		paths = append(paths,
+			filepath.Join(config.OutputDir, state.Get("disk_filename").(string)))
+	}
+	for i := range config.AdditionalDiskSize {
+		paths = append(paths, filepath.Join(config.OutputDir, config.additionalDiskName(i)))
+	}
+
+	for _, path := range paths {
+		ui.Say(fmt.Sprintf("Compacting hard drive %s...", filepath.Base(path)))
+		if err := compactDisk(state, config, path); err != nil {
+			if _, ok := state.GetOk(multistep.StateCancelled); ok {
+				return multistep.ActionHalt
+			}
+
+			err := fmt.Errorf("Error compacting hard drive: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCompactDisk) Cleanup(state multistep.StateBag) {}
+
+// compactDisk converts the disk at path to a compacted copy, which then
+// replaces it.
+func compactDisk(state multistep.StateBag, config *Config, path string) error {
+	ui := state.Get("ui").(packer.Ui)
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".compact"
+	args := []string{"convert", "-O", config.format}
+	if config.Compression {
+		args = append(args, "-c")
+	}
+	args = append(args, path, tmpPath)
+
+	if err := qemuImgWithProgress(state, fi.Size(), args...); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	newFi, err := os.Stat(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	ui.Message(fmt.Sprintf("Compacted from %d MB to %d MB",
+		fi.Size()/(1024*1024), newFi.Size()/(1024*1024)))
+	return nil
+}
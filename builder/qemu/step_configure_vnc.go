@@ -9,7 +9,7 @@ import (
 	"net"
 )
 
-// This step configures the VM to enable the VNC server.
+// This step configures the VM to enable the VNC or SPICE server.
 //
 // Uses:
 //   config *config
@@ -17,32 +17,42 @@ import (
 //
 // Produces:
 //   vnc_port uint - The port that VNC is configured to listen on.
+//   spice_port uint - The port that SPICE is configured to listen on.
 type stepConfigureVNC struct{}
 
 func (stepConfigureVNC) Run(state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
 	ui := state.Get("ui").(packer.Ui)
 
-	// Find an open VNC port. Note that this can still fail later on
+	// Find an open display port. Note that this can still fail later on
 	// because we have to release the port at some point. But this does its
 	// best.
-	msg := fmt.Sprintf("Looking for available port between %d and %d", config.VNCPortMin, config.VNCPortMax)
+	portMin, portMax := config.displayPortRange()
+	msg := fmt.Sprintf("Looking for available port between %d and %d", portMin, portMax)
 	ui.Say(msg)
 	log.Printf(msg)
-	var vncPort uint
-	portRange := int(config.VNCPortMax - config.VNCPortMin)
+	var port uint
+	portRange := int(portMax - portMin)
 	for {
-		vncPort = uint(rand.Intn(portRange)) + config.VNCPortMin
-		log.Printf("Trying port: %d", vncPort)
-		l, err := net.Listen("tcp", fmt.Sprintf(":%d", vncPort))
+		port = uint(rand.Intn(portRange)) + portMin
+		log.Printf("Trying port: %d", port)
+		l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 		if err == nil {
 			defer l.Close()
 			break
 		}
 	}
 
-	ui.Say(fmt.Sprintf("Found available VNC port: %d", vncPort))
-	state.Put("vnc_port", vncPort)
+	if config.Display == "spice" {
+		ui.Say(fmt.Sprintf("Found available SPICE port: %d", port))
+		state.Put("spice_port", port)
+	} else {
+		ui.Say(fmt.Sprintf("Found available VNC port: %d", port))
+		if config.vncPasswordGenerated {
+			ui.Message(fmt.Sprintf("The VNC password of this build is %s", config.VNCPassword))
+		}
+		state.Put("vnc_port", port)
+	}
 
 	return multistep.ActionContinue
 }
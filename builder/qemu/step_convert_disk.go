@@ -0,0 +1,79 @@
+package qemu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step converts the disks to the formats that follow the first one
+// once the VM has shut down, with `qemu-img convert`. The converted disks
+// are written next to the disks, which are kept as well.
+//
+// Uses:
+//   config        *Config
+//   disk_filename string
+//   ui            packer.Ui
+//
+// Produces:
+//   converted_disks []string - The paths of the converted disks.
+type stepConvertDisk struct{}
+
+func (s *stepConvertDisk) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if len(config.Formats) < 2 || config.Ephemeral {
+		return multistep.ActionContinue
+	}
+
+	names := []string{state.Get("disk_filename").(string)}
+	for i := range config.AdditionalDiskSize {
+		names = append(names, config.additionalDiskName(i))
+	}
+
+	var converted []string
+	for _, format := range config.Formats[1:] {
+		for _, name := range names {
+			src := filepath.Join(config.OutputDir, name)
+			dst := filepath.Join(config.OutputDir, convertedDiskName(name, format))
+
+			ui.Say(fmt.Sprintf("Converting hard drive %s to %s...", name, format))
+			if err := convertDisk(state, format, src, dst); err != nil {
+				if _, ok := state.GetOk(multistep.StateCancelled); ok {
+					return multistep.ActionHalt
+				}
+
+				err := fmt.Errorf("Error converting hard drive to %s: %s", format, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+
+			converted = append(converted, dst)
+		}
+	}
+
+	state.Put("converted_disks", converted)
+	return multistep.ActionContinue
+}
+
+func (s *stepConvertDisk) Cleanup(state multistep.StateBag) {}
+
+// convertDisk converts the disk at src to a disk in format at dst.
+func convertDisk(state multistep.StateBag, format, src, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if err := qemuImgWithProgress(state, fi.Size(), "convert", "-O", format, src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	return nil
+}
@@ -2,39 +2,80 @@ package qemu
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mitchellh/multistep"
 	"github.com/mitchellh/packer/packer"
 )
 
 // This step copies the virtual disk that will be used as the
-// hard drive for the virtual machine.
+// hard drive for the virtual machine. With use_backing_file, a qcow2
+// overlay backed by the disk image is created instead, which is much
+// faster for large images since nothing is copied. With ephemeral the VM
+// runs on the disk image itself, so there is nothing to copy.
 type stepCopyDisk struct{}
 
 func (s *stepCopyDisk) Run(state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
-	driver := state.Get("driver").(Driver)
 	isoPath := state.Get("iso_path").(string)
 	ui := state.Get("ui").(packer.Ui)
 	path := filepath.Join(config.OutputDir, fmt.Sprintf("%s.%s", config.VMName,
-		strings.ToLower(config.Format)))
-	name := config.VMName + "." + strings.ToLower(config.Format)
+		strings.ToLower(config.format)))
+	name := config.VMName + "." + strings.ToLower(config.format)
 
 	command := []string{
 		"convert",
-		"-f", config.Format,
+		"-f", config.format,
 		isoPath,
 		path,
 	}
 
-	if config.DiskImage == false {
+	if config.DiskImage == false || config.Ephemeral {
+		return multistep.ActionContinue
+	}
+
+	if config.UseBackingFile {
+		// The backing file is referenced by its absolute path so that the
+		// overlay doesn't depend on the working directory.
+		backingPath, err := filepath.Abs(isoPath)
+		if err != nil {
+			err := fmt.Errorf("Error creating hard drive: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		driver := state.Get("driver").(Driver)
+		ui.Say("Creating hard drive backed by the disk image...")
+		err = driver.QemuImg(
+			"create", "-f", "qcow2", "-b", backingPath, "-F", config.format, path)
+		if err != nil {
+			err := fmt.Errorf("Error creating hard drive: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		state.Put("disk_filename", name)
 		return multistep.ActionContinue
 	}
 
+	// The size of the source is used to show the throughput of the copy
+	var size int64
+	if fi, err := os.Stat(isoPath); err == nil {
+		size = fi.Size()
+	}
+
 	ui.Say("Copying hard drive...")
-	if err := driver.QemuImg(command...); err != nil {
+	if err := qemuImgWithProgress(state, size, command...); err != nil {
+		if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			return multistep.ActionHalt
+		}
+
 		err := fmt.Errorf("Error creating hard drive: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -47,3 +88,54 @@ func (s *stepCopyDisk) Run(state multistep.StateBag) multistep.StepAction {
 }
 
 func (s *stepCopyDisk) Cleanup(state multistep.StateBag) {}
+
+// qemuImgWithProgress runs a long running qemu-img command, showing its
+// progress and throughput in the UI, and kills it if the build is
+// cancelled. The size is the number of bytes the command reads, or 0 if
+// it isn't known, in which case no throughput is shown.
+func qemuImgWithProgress(state multistep.StateBag, size int64, args ...string) error {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	var l sync.Mutex
+	var percent float64
+	progress := func(p float64) {
+		l.Lock()
+		defer l.Unlock()
+		percent = p
+	}
+
+	cancelCh := make(chan struct{})
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- driver.QemuImgProgress(cancelCh, progress, args...)
+	}()
+
+	start := time.Now()
+	progressTicker := time.NewTicker(5 * time.Second)
+	defer progressTicker.Stop()
+
+	for {
+		select {
+		case err := <-doneCh:
+			return err
+		case <-progressTicker.C:
+			l.Lock()
+			p := percent
+			l.Unlock()
+
+			message := fmt.Sprintf("Progress: %d%%", int(p))
+			if elapsed := time.Since(start).Seconds(); size > 0 && elapsed > 0 {
+				rate := float64(size) * p / 100 / elapsed
+				message += fmt.Sprintf(" (%.1f MB/s)", rate/(1024*1024))
+			}
+			ui.Message(message)
+		case <-time.After(1 * time.Second):
+			if _, ok := state.GetOk(multistep.StateCancelled); ok {
+				ui.Say("Interrupt received. Cancelling qemu-img...")
+				close(cancelCh)
+				return <-doneCh
+			}
+		}
+	}
+}
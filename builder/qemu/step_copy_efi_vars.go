@@ -0,0 +1,71 @@
+package qemu
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step copies the EFI variable store template into the output
+// directory, since the firmware writes to it while the VM runs. The copy
+// is part of the artifact, so that the VM can be booted with the boot
+// entries that the installer created.
+//
+// Produces:
+//   efi_vars_path string - The path to the writable variable store.
+type stepCopyEFIVars struct{}
+
+func (s *stepCopyEFIVars) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !config.EFIBoot {
+		return multistep.ActionContinue
+	}
+
+	path := config.efiVarsPath()
+	ui.Say("Copying EFI variable store...")
+	if err := copyFile(config.EFIFirmwareVars, path); err != nil {
+		err := fmt.Errorf("Error copying EFI variable store: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("efi_vars_path", path)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCopyEFIVars) Cleanup(state multistep.StateBag) {}
+
+// efiVarsPath returns the path of the EFI variable store of the VM in the
+// output directory.
+func (c *Config) efiVarsPath() string {
+	return filepath.Join(c.OutputDir, fmt.Sprintf("%s_VARS.fd", c.VMName))
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// The template is usually read-only, but the copy must not be
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
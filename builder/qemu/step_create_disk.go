@@ -16,29 +16,41 @@ func (s *stepCreateDisk) Run(state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
-	name := config.VMName + "." + strings.ToLower(config.Format)
+	name := config.VMName + "." + strings.ToLower(config.format)
 	path := filepath.Join(config.OutputDir, name)
 
 	command := []string{
 		"create",
-		"-f", config.Format,
+		"-f", config.format,
 		path,
 		fmt.Sprintf("%vM", config.DiskSize),
 	}
 
-	if config.DiskImage == true {
-		return multistep.ActionContinue
-	}
+	if config.DiskImage == false {
+		ui.Say("Creating hard drive...")
+		if err := driver.QemuImg(command...); err != nil {
+			err := fmt.Errorf("Error creating hard drive: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
 
-	ui.Say("Creating hard drive...")
-	if err := driver.QemuImg(command...); err != nil {
-		err := fmt.Errorf("Error creating hard drive: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+		state.Put("disk_filename", name)
 	}
 
-	state.Put("disk_filename", name)
+	// The additional disks are created even for disk images, since they
+	// are empty either way.
+	for i, size := range config.AdditionalDiskSize {
+		path := filepath.Join(config.OutputDir, config.additionalDiskName(i))
+		ui.Say(fmt.Sprintf("Creating additional hard drive %d...", i+1))
+		err := driver.QemuImg("create", "-f", config.format, path, fmt.Sprintf("%vM", size))
+		if err != nil {
+			err := fmt.Errorf("Error creating additional hard drive: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
 
 	return multistep.ActionContinue
 }
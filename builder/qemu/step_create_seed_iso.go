@@ -0,0 +1,98 @@
+package qemu
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// stepCreateSeedISO creates an ISO with the files of cd_content, such as
+// a cloud-init NoCloud seed, so that it can be attached to the VM as a
+// second CD-ROM.
+//
+// Produces:
+//   seed_iso_path string - The path to the seed ISO.
+type stepCreateSeedISO struct {
+	workDir string
+}
+
+func (s *stepCreateSeedISO) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if len(config.CDContent) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Creating seed ISO...")
+	dir, err := ioutil.TempDir(config.PackerWorkDir, "packer-seed")
+	if err != nil {
+		err := fmt.Errorf("Error creating seed ISO: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.workDir = dir
+
+	names := make([]string, 0, len(config.CDContent))
+	for name := range config.CDContent {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The files are within their own directory so that only they end up
+	// on the ISO.
+	contentDir := filepath.Join(dir, "content")
+	for _, name := range names {
+		path := filepath.Join(contentDir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(path, []byte(config.CDContent[name]), 0644)
+		}
+		if err != nil {
+			err := fmt.Errorf("Error creating seed ISO: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	isoPath := filepath.Join(dir, "seed.iso")
+	cmd, err := seedISOCommand(isoPath, config.CDLabel, contentDir)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	log.Printf("Executing: %s", strings.Join(cmd.Args, " "))
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		err := fmt.Errorf("Error creating seed ISO: %s\n%s",
+			err, strings.TrimSpace(output.String()))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf(
+		"Created seed ISO labeled %s with: %s", config.CDLabel, strings.Join(names, ", ")))
+	state.Put("seed_iso_path", isoPath)
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateSeedISO) Cleanup(state multistep.StateBag) {
+	if s.workDir != "" {
+		os.RemoveAll(s.workDir)
+	}
+}
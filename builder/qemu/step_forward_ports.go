@@ -0,0 +1,69 @@
+package qemu
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step picks the host ports of host_port_forwards that aren't set,
+// from the range of the SSH port, and records the forwarded host ports in
+// the build data so that provisioners can use them.
+//
+// Uses:
+//   sshHostPort uint
+//
+// Produces:
+//   build_data packer.BuildData - The forwarded host ports.
+//   portForwards []PortForward - The forwards with their host ports.
+type stepForwardPorts struct{}
+
+func (s *stepForwardPorts) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	sshHostPort := state.Get("sshHostPort").(uint)
+	ui := state.Get("ui").(packer.Ui)
+
+	data := packer.BuildData{}
+	used := make(map[uint]bool)
+	if sshHostPort != 0 {
+		data["SSHHostPort"] = fmt.Sprintf("%d", sshHostPort)
+		used[sshHostPort] = true
+	}
+	for _, f := range config.PortForwards {
+		if f.HostPort != 0 {
+			used[f.HostPort] = true
+		}
+	}
+
+	forwards := make([]PortForward, len(config.PortForwards))
+	for i, f := range config.PortForwards {
+		if f.HostPort == 0 {
+			log.Printf("Looking for available %s port between %d and %d",
+				f.Protocol, config.SSHHostPortMin, config.SSHHostPortMax)
+			port, err := findHostPort(
+				f.Protocol, config.SSHHostPortMin, config.SSHHostPortMax, used)
+			if err != nil {
+				err := fmt.Errorf("Error forwarding guest port %d: %s", f.GuestPort, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+
+			f.HostPort = port
+			used[port] = true
+		}
+
+		ui.Say(fmt.Sprintf("Forwarding host port %d to guest port %d/%s (%s).",
+			f.HostPort, f.GuestPort, f.Protocol, f.Name))
+		data[f.BuildDataKey()] = fmt.Sprintf("%d", f.HostPort)
+		forwards[i] = f
+	}
+
+	state.Put("build_data", data)
+	state.Put("portForwards", forwards)
+	return multistep.ActionContinue
+}
+
+func (s *stepForwardPorts) Cleanup(state multistep.StateBag) {}
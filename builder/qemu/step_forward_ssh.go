@@ -5,23 +5,48 @@ import (
 	"log"
 	"math/rand"
 	"net"
+	"strings"
 
 	"github.com/mitchellh/multistep"
 	"github.com/mitchellh/packer/packer"
 )
 
 // This step adds a NAT port forwarding definition so that SSH is available
-// on the guest machine.
+// on the guest machine. Nothing is forwarded if the guest isn't in user
+// mode networking, since it is connected to directly.
 //
 // Uses:
 //
 // Produces:
+//   hostAddress string - The loopback address of the host that the SSH
+//     and VNC ports are forwarded on.
+//   sshHostPort uint - The host port that SSH is forwarded to, or zero if
+//     SSH isn't forwarded.
 type stepForwardSSH struct{}
 
 func (s *stepForwardSSH) Run(state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
 	ui := state.Get("ui").(packer.Ui)
 
+	hostAddress := hostLoopback(config.HostIPv6)
+	if hostAddress == "::1" && !config.HostIPv6 {
+		ui.Message("The host has no IPv4 loopback, forwarding ports over IPv6.")
+	}
+	state.Put("hostAddress", hostAddress)
+
+	if config.NetMode != "user" {
+		ui.Say(fmt.Sprintf(
+			"Attaching the VM to %s with MAC address %s, SSH will not be forwarded.",
+			config.netInterface(), config.MacAddress))
+		if hostHTTPIP(config) == "" {
+			ui.Message(fmt.Sprintf(
+				"WARNING: %s has no IPv4 address, so {{ .HTTPIP }} will be empty.",
+				config.netInterface()))
+		}
+		state.Put("sshHostPort", uint(0))
+		return multistep.ActionContinue
+	}
+
 	log.Printf("Looking for available SSH port between %d and %d", config.SSHHostPortMin, config.SSHHostPortMax)
 	var sshHostPort uint
 	var offset uint = 0
@@ -50,3 +75,30 @@ func (s *stepForwardSSH) Run(state multistep.StateBag) multistep.StepAction {
 }
 
 func (s *stepForwardSSH) Cleanup(state multistep.StateBag) {}
+
+// hostLoopback returns the loopback address of the host that ports are
+// forwarded on. IPv4 is used unless IPv6 is preferred or the host has no
+// IPv4, as is the case on some CI runners.
+func hostLoopback(preferIPv6 bool) string {
+	if preferIPv6 {
+		return "::1"
+	}
+
+	if l, err := net.Listen("tcp4", "127.0.0.1:0"); err == nil {
+		l.Close()
+		return "127.0.0.1"
+	}
+
+	if l, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+		l.Close()
+		return "::1"
+	}
+
+	return "127.0.0.1"
+}
+
+// hostIsIPv6 reports whether ports are forwarded on the IPv6 loopback.
+func hostIsIPv6(state multistep.StateBag) bool {
+	addr, ok := state.GetOk("hostAddress")
+	return ok && strings.Contains(addr.(string), ":")
+}
@@ -0,0 +1,145 @@
+package qemu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step samples the CPU and memory that the Qemu process uses and
+// the space that the disks take while the VM runs, and shows a summary
+// of them at the end of the build so that cpus, memory and disk_size can
+// be sized to what the build needs.
+//
+// Uses:
+//   config        *config
+//   disk_filename string
+//   pid_path      string
+//   ui            packer.Ui
+type stepMonitorUsage struct {
+	// Interval is the time between samples. Defaults to
+	// usageSampleInterval.
+	Interval time.Duration
+
+	config   *Config
+	pidPath  string
+	diskName string
+
+	summary usageSummary
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+func (s *stepMonitorUsage) Run(state multistep.StateBag) multistep.StepAction {
+	s.config = state.Get("config").(*Config)
+	if v, ok := state.GetOk("pid_path"); ok {
+		s.pidPath = v.(string)
+	}
+	if v, ok := state.GetOk("disk_filename"); ok {
+		s.diskName = v.(string)
+	}
+
+	interval := s.Interval
+	if interval == 0 {
+		interval = usageSampleInterval
+	}
+
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+	go func() {
+		defer close(s.doneCh)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.sample(true)
+
+			select {
+			case <-ticker.C:
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+
+	return multistep.ActionContinue
+}
+
+func (s *stepMonitorUsage) Cleanup(state multistep.StateBag) {
+	if s.stopCh == nil {
+		return
+	}
+
+	close(s.stopCh)
+	<-s.doneCh
+	s.stopCh = nil
+
+	// The disks are sampled once more as they were compacted since
+	s.sample(false)
+
+	ui := state.Get("ui").(packer.Ui)
+	lines := s.summary.Lines(s.config.CPUs, s.config.Memory)
+	if len(lines) == 0 {
+		return
+	}
+
+	ui.Say("Resources used by the VM:")
+	for _, line := range lines {
+		ui.Message(line)
+	}
+}
+
+// sample samples the Qemu process if process is true, and the disks.
+func (s *stepMonitorUsage) sample(process bool) {
+	if process && s.pidPath != "" {
+		if usage, err := s.sampleProcess(); err != nil {
+			log.Printf("Error sampling the Qemu process: %s", err)
+		} else {
+			s.summary.AddProcess(time.Now(), usage)
+		}
+	}
+
+	// The image of an ephemeral VM is only read and the writes go to a
+	// temporary file of Qemu
+	if s.config.Ephemeral || s.diskName == "" {
+		return
+	}
+
+	paths := []string{filepath.Join(s.config.OutputDir, s.diskName)}
+	for i := range s.config.AdditionalDiskSize {
+		paths = append(paths, filepath.Join(s.config.OutputDir, s.config.additionalDiskName(i)))
+	}
+
+	var total int64
+	for _, path := range paths {
+		size, err := diskSpace(path)
+		if err != nil {
+			log.Printf("Error sampling the size of %s: %s", path, err)
+			return
+		}
+		total += size
+	}
+	s.summary.AddDisk(total)
+}
+
+func (s *stepMonitorUsage) sampleProcess() (processUsage, error) {
+	data, err := ioutil.ReadFile(s.pidPath)
+	if err != nil {
+		return processUsage{}, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return processUsage{}, fmt.Errorf("Invalid pidfile %s: %s", s.pidPath, err)
+	}
+
+	return sampleProcess(pid)
+}
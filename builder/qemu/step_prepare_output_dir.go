@@ -2,6 +2,7 @@ package qemu
 
 import (
 	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 	"log"
 	"os"
@@ -13,13 +14,14 @@ type stepPrepareOutputDir struct{}
 func (stepPrepareOutputDir) Run(state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
 	ui := state.Get("ui").(packer.Ui)
+	outputDir := common.LongPath(config.OutputDir)
 
-	if _, err := os.Stat(config.OutputDir); err == nil && config.PackerForce {
+	if _, err := os.Stat(outputDir); err == nil && config.PackerForce {
 		ui.Say("Deleting previous output directory...")
-		os.RemoveAll(config.OutputDir)
+		os.RemoveAll(outputDir)
 	}
 
-	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
@@ -36,8 +38,9 @@ func (stepPrepareOutputDir) Cleanup(state multistep.StateBag) {
 		ui := state.Get("ui").(packer.Ui)
 
 		ui.Say("Deleting output directory...")
+		outputDir := common.LongPath(config.OutputDir)
 		for i := 0; i < 5; i++ {
-			err := os.RemoveAll(config.OutputDir)
+			err := os.RemoveAll(outputDir)
 			if err == nil {
 				break
 			}
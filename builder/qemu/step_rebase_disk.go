@@ -0,0 +1,51 @@
+package qemu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step makes the disk standalone once the VM has shut down, when it
+// is an overlay backed by the disk image, by rebasing it onto no backing
+// file. The contents of the disk image are copied into it.
+type stepRebaseDisk struct{}
+
+func (s *stepRebaseDisk) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !config.UseBackingFile || !config.StandaloneDisk {
+		return multistep.ActionContinue
+	}
+
+	path := filepath.Join(config.OutputDir, fmt.Sprintf("%s.%s", config.VMName,
+		strings.ToLower(config.format)))
+
+	// The whole disk image is read, so its size is used to show the
+	// throughput.
+	var size int64
+	if fi, err := os.Stat(state.Get("iso_path").(string)); err == nil {
+		size = fi.Size()
+	}
+
+	ui.Say("Making the hard drive standalone...")
+	if err := qemuImgWithProgress(state, size, "rebase", "-b", "", path); err != nil {
+		if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			return multistep.ActionHalt
+		}
+
+		err := fmt.Errorf("Error making the hard drive standalone: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRebaseDisk) Cleanup(state multistep.StateBag) {}
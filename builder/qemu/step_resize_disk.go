@@ -8,8 +8,13 @@ import (
 	"strings"
 )
 
-// This step resizes the virtual disk that will be used as the
-// hard drive for the virtual machine.
+// This step grows the disk image, which is copied or used as a backing
+// file, to disk_size, so that the VM has room for what is installed.
+// Images that are already that large, or all images with
+// skip_resize_disk, are left alone, since shrinking them would lose data.
+//
+// Produces:
+//   disk_size uint - The size of the disk in megabytes.
 type stepResizeDisk struct{}
 
 func (s *stepResizeDisk) Run(state multistep.StateBag) multistep.StepAction {
@@ -17,26 +22,52 @@ func (s *stepResizeDisk) Run(state multistep.StateBag) multistep.StepAction {
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 	path := filepath.Join(config.OutputDir, fmt.Sprintf("%s.%s", config.VMName,
-		strings.ToLower(config.Format)))
+		strings.ToLower(config.format)))
+
+	if config.DiskImage == false || config.Ephemeral {
+		return multistep.ActionContinue
+	}
+
+	size, err := driver.QemuImgVirtualSize(path)
+	if err != nil {
+		err := fmt.Errorf("Error reading the size of the hard drive: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Round up, so that an image of a partial megabyte isn't shrunk
+	current := uint((size + 1024*1024 - 1) / (1024 * 1024))
+	if config.SkipResizeDisk || config.DiskSize == current {
+		state.Put("disk_size", current)
+		return multistep.ActionContinue
+	}
+	if config.DiskSize < current {
+		err := fmt.Errorf(
+			"The disk image is %d MB, which is larger than the disk_size of %d MB.\n"+
+				"Disks can't be shrunk. Increase disk_size, or remove it and set\n"+
+				"skip_resize_disk to keep the size of the image.", current, config.DiskSize)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
 
 	command := []string{
 		"resize",
+		"-f", config.format,
 		path,
 		fmt.Sprintf("%vM", config.DiskSize),
 	}
 
-	if config.DiskImage == false {
-		return multistep.ActionContinue
-	}
-
-	ui.Say("Resizing hard drive...")
+	ui.Say(fmt.Sprintf("Resizing hard drive from %d MB to %d MB...", current, config.DiskSize))
 	if err := driver.QemuImg(command...); err != nil {
-		err := fmt.Errorf("Error creating hard drive: %s", err)
+		err := fmt.Errorf("Error resizing hard drive: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
+	state.Put("disk_size", config.DiskSize)
 	return multistep.ActionContinue
 }
 
@@ -3,6 +3,7 @@ package qemu
 import (
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -15,22 +16,36 @@ import (
 type stepRun struct {
 	BootDrive string
 	Message   string
+
+	pidPath string
 }
 
 type qemuArgsTemplateData struct {
-	HTTPIP    string
-	HTTPPort  uint
-	HTTPDir   string
-	OutputDir string
-	Name      string
+	HTTPIP      string
+	HTTPPort    uint
+	HTTPDir     string
+	OutputDir   string
+	Name        string
+	SSHHostPort uint
 }
 
 func (s *stepRun) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 
 	ui.Say(s.Message)
 
+	// Record the pid of Qemu so that it can be found again if this
+	// process dies before it can clean up.
+	if err := os.MkdirAll(config.PidDirectory, 0755); err != nil {
+		err := fmt.Errorf("Error creating pid directory: %s", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.pidPath = filepath.Join(config.PidDirectory, pidFileName(config.VMName))
+	state.Put("pid_path", s.pidPath)
+
 	command, err := getCommandArgs(s.BootDrive, state)
 	if err != nil {
 		err := fmt.Errorf("Error processing QemuArggs: %s", err)
@@ -44,6 +59,28 @@ func (s *stepRun) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
+	if config.SerialLogFile != "" {
+		ui.Message(fmt.Sprintf("The serial console of the VM is logged to %s", config.SerialLogFile))
+	}
+
+	// VNC refuses all clients until the password is set
+	if config.Display == "vnc" && config.VNCPassword != "" {
+		if err := driver.SetVNCPassword(config.VNCPassword); err != nil {
+			err := fmt.Errorf("Error setting VNC password: %s", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	// So does SPICE
+	if config.Display == "spice" && config.SPICEPassword != "" {
+		if err := driver.SetSPICEPassword(config.SPICEPassword); err != nil {
+			err := fmt.Errorf("Error setting SPICE password: %s", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	return multistep.ActionContinue
 }
 
@@ -54,45 +91,192 @@ func (s *stepRun) Cleanup(state multistep.StateBag) {
 	if err := driver.Stop(); err != nil {
 		ui.Error(fmt.Sprintf("Error shutting down VM: %s", err))
 	}
+
+	// The end of the serial console usually shows why a headless VM
+	// didn't boot or install.
+	config := state.Get("config").(*Config)
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if config.SerialLogFile != "" && (cancelled || halted) {
+		if tail, err := tailFile(config.SerialLogFile, serialLogTailLines); err == nil && tail != "" {
+			ui.Message(fmt.Sprintf(
+				"The last lines of the serial console, from %s:\n%s", config.SerialLogFile, tail))
+		}
+	}
+
+	if s.pidPath != "" {
+		os.Remove(s.pidPath)
+	}
 }
 
 func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error) {
 	config := state.Get("config").(*Config)
 	isoPath := state.Get("iso_path").(string)
-	vncPort := state.Get("vnc_port").(uint)
 	sshHostPort := state.Get("sshHostPort").(uint)
 	ui := state.Get("ui").(packer.Ui)
 
-	vnc := fmt.Sprintf("0.0.0.0:%d", vncPort-5900)
+	hostfwd := fmt.Sprintf("tcp::%v-:22", sshHostPort)
+	if hostIsIPv6(state) {
+		hostfwd = fmt.Sprintf("tcp:[::]:%v-:22", sshHostPort)
+	}
 	vmName := config.VMName
 	imgPath := filepath.Join(config.OutputDir,
-		fmt.Sprintf("%s.%s", vmName, strings.ToLower(config.Format)))
+		fmt.Sprintf("%s.%s", vmName, strings.ToLower(config.format)))
+
+	arch := config.arch()
+	defaultArgs := make(map[string][]string)
+
+	// An ephemeral VM runs on the disk image itself, which Qemu only reads
+	// with -snapshot. What is written goes to a temporary file instead.
+	if config.Ephemeral {
+		imgPath = isoPath
+		defaultArgs["-snapshot"] = nil
+	}
 
-	defaultArgs := make(map[string]string)
+	// Options that only newer versions of Qemu support are left out for
+	// older ones
+	discard := ",discard=" + config.DiskDiscard
+	if version, ok := state.GetOk("qemu_version"); ok {
+		discard = config.driveDiscard(version.(qemuVersion))
+	}
 
+	// Machines without a display device, such as s390x, can only be
+	// used through their serial console. Others use the default display
+	// of Qemu, which isn't SDL everywhere.
 	if config.Headless == true {
 		ui.Message("WARNING: The VM will be started in headless mode, as configured.\n" +
 			"In headless mode, errors during the boot sequence or OS setup\n" +
 			"won't be easily visible. Use at your own discretion.")
-	} else {
-		defaultArgs["-display"] = "sdl"
+	} else if arch.PC {
+		defaultArgs["-display"] = []string{"sdl"}
+	} else if len(arch.Devices) == 0 {
+		defaultArgs["-display"] = []string{"none"}
+	}
+
+	defaultArgs["-name"] = []string{vmName}
+	defaultArgs["-machine"] = []string{fmt.Sprintf("type=%s", config.MachineType)}
+	if config.CPUModel != "" {
+		defaultArgs["-cpu"] = []string{config.CPUModel}
+	}
+
+	// Each network device gets its own user mode network. Only the first
+	// forwards the SSH port, or is attached to the bridge or tap device
+	// if the network mode isn't user.
+	for i, d := range config.NetDevices {
+		id := fmt.Sprintf("user.%d", i)
+		netdev := fmt.Sprintf("user,id=%s", id)
+		extra := []string{"netdev=" + id}
+		if i == 0 && config.NetMode != "user" {
+			netdev = config.netdevArg(id)
+			if _, ok := d.Options["mac"]; !ok {
+				extra = append(extra, "mac="+config.MacAddress)
+			}
+		} else if config.NetIPv6 {
+			netdev += ",ipv6=on"
+			if config.NetIPv6Prefix != "" {
+				netdev += ",ipv6-net=" + config.NetIPv6Prefix
+			}
+			if config.NetIPv6DNS != "" {
+				netdev += ",ipv6-dns=" + config.NetIPv6DNS
+			}
+		}
+		if i == 0 && config.NetMode == "user" {
+			netdev += ",hostfwd=" + hostfwd
+			if forwards, ok := state.GetOk("portForwards"); ok {
+				for _, f := range forwards.([]PortForward) {
+					netdev += ",hostfwd=" + f.hostfwd(hostIsIPv6(state))
+				}
+			}
+		}
+
+		defaultArgs["-netdev"] = append(defaultArgs["-netdev"], netdev)
+		defaultArgs["-device"] = append(defaultArgs["-device"], d.DeviceArg(extra...))
+	}
+	for _, d := range config.Devices {
+		defaultArgs["-device"] = append(defaultArgs["-device"], d.DeviceArg())
+	}
+	if !arch.PC {
+		defaultArgs["-device"] = append(defaultArgs["-device"], arch.Devices...)
+	}
+
+	for key, values := range config.mainDriveArgs(imgPath, discard) {
+		defaultArgs[key] = append(defaultArgs[key], values...)
+	}
+	for i := range config.AdditionalDiskSize {
+		path := filepath.Join(config.OutputDir, config.additionalDiskName(i))
+		defaultArgs["-drive"] = append(defaultArgs["-drive"], fmt.Sprintf(
+			"file=%s,if=%s,cache=%s%s", path,
+			config.AdditionalDiskInterface[i], config.AdditionalDiskCache[i], discard))
+	}
+	for _, d := range config.Drives {
+		defaultArgs["-drive"] = append(defaultArgs["-drive"], d.DriveArg())
+	}
+	if varsPath, ok := state.GetOk("efi_vars_path"); ok {
+		defaultArgs["-drive"] = append(defaultArgs["-drive"],
+			fmt.Sprintf("if=pflash,format=raw,readonly=on,file=%s", config.EFIFirmwareCode),
+			fmt.Sprintf("if=pflash,format=raw,file=%s", varsPath.(string)))
+	}
+
+	for i, f := range config.SharedFolders {
+		defaultArgs["-virtfs"] = append(defaultArgs["-virtfs"],
+			f.virtfsArg(fmt.Sprintf("fsdev%d", i)))
+	}
+
+	if socket, ok := state.GetOk("tpm_socket"); ok {
+		for key, values := range config.tpmArgs(socket.(string)) {
+			defaultArgs[key] = append(defaultArgs[key], values...)
+		}
+	}
+
+	if config.SerialLogFile != "" {
+		defaultArgs["-serial"] = []string{config.serialArg()}
+	}
+
+	if config.Firmware != "" {
+		defaultArgs["-bios"] = []string{config.Firmware}
 	}
 
-	defaultArgs["-name"] = vmName
-	defaultArgs["-machine"] = fmt.Sprintf("type=%s", config.MachineType)
-	defaultArgs["-netdev"] = fmt.Sprintf("user,id=user.0,hostfwd=tcp::%v-:22", sshHostPort)
-	defaultArgs["-device"] = fmt.Sprintf("%s,netdev=user.0", config.NetDevice)
-	defaultArgs["-drive"] = fmt.Sprintf("file=%s,if=%s,cache=%s,discard=%s", imgPath, config.DiskInterface, config.DiskCache, config.DiskDiscard)
+	var cdroms []cdrom
 	if !config.DiskImage {
-		defaultArgs["-cdrom"] = isoPath
+		cdroms = append(cdroms, cdrom{Path: isoPath, Interface: isoInterface(arch)})
+	}
+	if seedPath, ok := state.GetOk("seed_iso_path"); ok {
+		cdroms = append(cdroms, cdrom{Path: seedPath.(string), Interface: isoInterface(arch)})
+	}
+	for i, iso := range config.AdditionalISOs {
+		cdroms = append(cdroms, cdrom{
+			Path:      state.Get(additionalISOKey(i)).(string),
+			Interface: iso.Interface,
+			Index:     iso.Index,
+		})
+	}
+	for key, values := range cdromArgs(cdroms) {
+		defaultArgs[key] = append(defaultArgs[key], values...)
+	}
+
+	// Only PC firmware follows the boot order of -boot. The firmware of
+	// other machines boots the first disk that has a bootloader.
+	if arch.PC {
+		defaultArgs["-boot"] = []string{bootDrive}
+	}
+	defaultArgs["-m"] = []string{fmt.Sprintf("%dM", config.Memory)}
+	defaultArgs["-smp"] = []string{config.smpArg()}
+	loopback := "127.0.0.1"
+	if hostIsIPv6(state) {
+		loopback = "::1"
+	}
+	if config.Display == "spice" {
+		defaultArgs["-spice"] = []string{config.spiceArg(loopback, state.Get("spice_port").(uint))}
+		if config.Headless {
+			defaultArgs["-display"] = []string{"none"}
+		}
+	} else {
+		defaultArgs["-vnc"] = []string{config.vncArg(loopback, state.Get("vnc_port").(uint))}
 	}
-	defaultArgs["-boot"] = bootDrive
-	defaultArgs["-m"] = "512M"
-	defaultArgs["-vnc"] = vnc
 
 	// Append the accelerator to the machine type if it is specified
 	if config.Accelerator != "none" {
-		defaultArgs["-machine"] += fmt.Sprintf(",accel=%s", config.Accelerator)
+		defaultArgs["-machine"][0] += fmt.Sprintf(",accel=%s", config.Accelerator)
 	} else {
 		ui.Message("WARNING: The VM will be started with no hardware acceleration.\n" +
 			"The installation may take considerably longer to finish.\n")
@@ -100,11 +284,15 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 
 	// Determine if we have a floppy disk to attach
 	if floppyPathRaw, ok := state.GetOk("floppy_path"); ok {
-		defaultArgs["-fda"] = floppyPathRaw.(string)
+		defaultArgs["-fda"] = []string{floppyPathRaw.(string)}
 	} else {
 		log.Println("Qemu Builder has no floppy files, not attaching a floppy.")
 	}
 
+	if pidPath, ok := state.GetOk("pid_path"); ok {
+		defaultArgs["-pidfile"] = []string{pidPath.(string)}
+	}
+
 	inArgs := make(map[string][]string)
 	if len(config.QemuArgs) > 0 {
 		ui.Say("Overriding defaults Qemu arguments with QemuArgs...")
@@ -112,11 +300,12 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 		httpPort := state.Get("http_port").(uint)
 		ctx := config.ctx
 		ctx.Data = qemuArgsTemplateData{
-			"10.0.2.2",
+			hostHTTPIP(config),
 			httpPort,
 			config.HTTPDir,
 			config.OutputDir,
 			config.VMName,
+			sshHostPort,
 		}
 		newQemuArgs, err := processArgs(config.QemuArgs, &ctx)
 		if err != nil {
@@ -141,9 +330,7 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 	// get any remaining missing default args from the default settings
 	for key := range defaultArgs {
 		if _, ok := inArgs[key]; !ok {
-			arg := make([]string, 1)
-			arg[0] = defaultArgs[key]
-			inArgs[key] = arg
+			inArgs[key] = defaultArgs[key]
 		}
 	}
 
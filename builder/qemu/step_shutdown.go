@@ -10,7 +10,9 @@ import (
 )
 
 // This step shuts down the machine. It first attempts to do so gracefully,
-// but ultimately forcefully shuts it down if that fails.
+// but ultimately forcefully shuts it down if that fails. Without a
+// shutdown_command, the guest is asked to shut down through ACPI, as if
+// its power button were pressed, so that it can flush its disks.
 //
 // Uses:
 //   communicator packer.Communicator
@@ -43,10 +45,10 @@ func (s *stepShutdown) Run(state multistep.StateBag) multistep.StepAction {
 		cancelCh := make(chan struct{}, 1)
 		go func() {
 			defer close(cancelCh)
-			<-time.After(config.shutdownTimeout)
+			<-time.After(config.ShutdownTimeout)
 		}()
 
-		log.Printf("Waiting max %s for shutdown to complete", config.shutdownTimeout)
+		log.Printf("Waiting max %s for shutdown to complete", config.ShutdownTimeout)
 		if ok := driver.WaitForShutdown(cancelCh); !ok {
 			err := errors.New("Timeout while waiting for machine to shut down.")
 			state.Put("error", err)
@@ -54,12 +56,29 @@ func (s *stepShutdown) Run(state multistep.StateBag) multistep.StepAction {
 			return multistep.ActionHalt
 		}
 	} else {
-		ui.Say("Halting the virtual machine...")
-		if err := driver.Stop(); err != nil {
-			err := fmt.Errorf("Error stopping VM: %s", err)
-			state.Put("error", err)
-			ui.Error(err.Error())
-			return multistep.ActionHalt
+		ui.Say("Gracefully halting virtual machine through ACPI...")
+		graceful := false
+		if err := driver.Powerdown(); err != nil {
+			log.Printf("Error sending ACPI power down: %s", err)
+		} else {
+			cancelCh := make(chan struct{}, 1)
+			go func() {
+				defer close(cancelCh)
+				<-time.After(config.ShutdownTimeout)
+			}()
+
+			log.Printf("Waiting max %s for shutdown to complete", config.ShutdownTimeout)
+			graceful = driver.WaitForShutdown(cancelCh)
+		}
+
+		if !graceful {
+			ui.Say("The VM didn't shut down gracefully, halting it...")
+			if err := driver.Stop(); err != nil {
+				err := fmt.Errorf("Error stopping VM: %s", err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
 		}
 	}
 
@@ -0,0 +1,131 @@
+package qemu
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step starts swtpm to emulate the TPM of the VM. Its state is kept
+// in a temporary directory for the build only.
+//
+// Uses:
+//   config *config
+//   ui     packer.Ui
+//
+// Produces:
+//   tpm_socket string - The path to the socket that swtpm listens on.
+type stepStartTPM struct {
+	// SwtpmPath is the path to swtpm. Defaults to swtpm on the PATH.
+	SwtpmPath string
+
+	// Timeout is how long swtpm has to create its socket. Defaults to
+	// 10 seconds.
+	Timeout time.Duration
+
+	dir    string
+	cmd    *exec.Cmd
+	doneCh chan struct{}
+}
+
+func (s *stepStartTPM) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !config.tpmEnabled() {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Starting swtpm to emulate TPM %s...", config.TPMVersion))
+	socket, err := s.start(config)
+	if err != nil {
+		err := fmt.Errorf("Error starting swtpm: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("tpm_socket", socket)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepStartTPM) start(config *Config) (string, error) {
+	path := s.SwtpmPath
+	if path == "" {
+		var err error
+		if path, err = exec.LookPath("swtpm"); err != nil {
+			return "", fmt.Errorf("swtpm was not found: %s", err)
+		}
+	}
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	// The path of a Unix socket is limited to about 100 characters, which
+	// the output directory could exceed.
+	var err error
+	s.dir, err = ioutil.TempDir("", "packer-swtpm")
+	if err != nil {
+		return "", err
+	}
+	socket := filepath.Join(s.dir, "swtpm.sock")
+
+	var stderr bytes.Buffer
+	args := config.swtpmArgs(s.dir, socket)
+	log.Printf("Executing %s: %#v", path, args)
+	s.cmd = exec.Command(path, args...)
+	s.cmd.Stderr = &stderr
+	if err := s.cmd.Start(); err != nil {
+		return "", err
+	}
+
+	s.doneCh = make(chan struct{})
+	go func() {
+		s.cmd.Wait()
+		close(s.doneCh)
+	}()
+
+	deadline := time.After(timeout)
+	for {
+		if _, err := os.Stat(socket); err == nil {
+			return socket, nil
+		}
+
+		select {
+		case <-s.doneCh:
+			return "", fmt.Errorf("swtpm exited: %s", strings.TrimSpace(stderr.String()))
+		case <-deadline:
+			return "", fmt.Errorf("swtpm didn't create its socket within %s", timeout)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (s *stepStartTPM) Cleanup(state multistep.StateBag) {
+	// swtpm exits by itself when Qemu disconnects, but not if Qemu never
+	// connected.
+	if s.cmd != nil {
+		select {
+		case <-s.doneCh:
+		default:
+			s.cmd.Process.Kill()
+			<-s.doneCh
+		}
+	}
+
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
@@ -0,0 +1,61 @@
+package qemu
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step takes the place of downloading the ISO with iso_skip_cache.
+// Qemu reads the ISO from its URL with its curl block driver while the
+// VM runs, which needs a server that supports range requests.
+//
+// Uses:
+//   config *config
+//   ui     packer.Ui
+//
+// Produces:
+//   iso_path string - The URL of the ISO.
+type stepStreamISO struct{}
+
+func (s *stepStreamISO) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	url := config.streamedISOURL()
+	ui.Say(fmt.Sprintf("Reading the ISO from %s without downloading it...", url))
+	if err := checkRangeRequests(url); err != nil {
+		err := fmt.Errorf("Error reading the ISO from its URL: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("iso_path", url)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepStreamISO) Cleanup(state multistep.StateBag) {}
+
+// checkRangeRequests checks that the server of the URL can be reached and
+// serves parts of the file, which Qemu reads it with.
+func checkRangeRequests(url string) error {
+	resp, err := http.Head(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HEAD %s returned %s", url, resp.Status)
+	}
+
+	if resp.Header.Get("Accept-Ranges") != "bytes" {
+		return fmt.Errorf("the server of %s doesn't support range requests", url)
+	}
+
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/mitchellh/go-vnc"
 	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 	"github.com/mitchellh/packer/template/interpolate"
 )
@@ -21,13 +22,35 @@ type bootCommandTemplateData struct {
 	HTTPIP   string
 	HTTPPort uint
 	Name     string
+
+	isoPath string
+}
+
+// ISOLabel returns the volume label of the ISO that the VM boots, for
+// kernel arguments such as inst.stage2=hd:LABEL={{ .ISOLabel }}. The ISO
+// is only read if the boot command uses it.
+func (d *bootCommandTemplateData) ISOLabel() (string, error) {
+	if d.isoPath == "" {
+		return "", fmt.Errorf("ISOLabel can't be used, as no ISO is attached")
+	}
+
+	return common.ISOLabel(d.isoPath)
 }
 
-// This step "types" the boot command into the VM over VNC.
+// keyEventSender presses and releases keys of the VM, given as X keysyms.
+// It is implemented by both a VNC client and the driver.
+type keyEventSender interface {
+	KeyEvent(keysym uint32, down bool) error
+}
+
+// This step "types" the boot command into the VM over VNC, or through
+// the QMP monitor if boot_command_driver is "qmp".
 //
 // Uses:
 //   config *config
+//   driver Driver
 //   http_port int
+//   iso_path string
 //   ui     packer.Ui
 //   vnc_port uint
 //
@@ -39,38 +62,64 @@ func (s *stepTypeBootCommand) Run(state multistep.StateBag) multistep.StepAction
 	config := state.Get("config").(*Config)
 	httpPort := state.Get("http_port").(uint)
 	ui := state.Get("ui").(packer.Ui)
-	vncPort := state.Get("vnc_port").(uint)
-
-	// Connect to VNC
-	ui.Say("Connecting to VM via VNC")
-	nc, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", vncPort))
-	if err != nil {
-		err := fmt.Errorf("Error connecting to VNC: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
-	}
-	defer nc.Close()
-
-	c, err := vnc.Client(nc, &vnc.ClientConfig{Exclusive: true})
-	if err != nil {
-		err := fmt.Errorf("Error handshaking with VNC: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+
+	var keys keyEventSender
+	via := "VNC"
+	if config.BootCommandDriver == "qmp" {
+		// The keys are sent to the emulated keyboard, which doesn't
+		// need a connection to the display of the VM.
+		keys = state.Get("driver").(Driver)
+		via = "QMP"
+	} else {
+		vncPort := state.Get("vnc_port").(uint)
+
+		// Connect to VNC
+		ui.Say("Connecting to VM via VNC")
+		loopback := "127.0.0.1"
+		if addr, ok := state.GetOk("hostAddress"); ok {
+			loopback = addr.(string)
+		}
+		vncHost := config.vncHost(loopback)
+		nc, err := net.Dial("tcp", net.JoinHostPort(vncHost, fmt.Sprint(vncPort)))
+		if err != nil {
+			err := fmt.Errorf("Error connecting to VNC: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		defer nc.Close()
+
+		vncConfig := &vnc.ClientConfig{Exclusive: true}
+		if config.VNCPassword != "" {
+			vncConfig.Auth = []vnc.ClientAuth{&vnc.PasswordAuth{Password: config.VNCPassword}}
+		}
+		c, err := vnc.Client(nc, vncConfig)
+		if err != nil {
+			err := fmt.Errorf("Error handshaking with VNC: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		defer c.Close()
+
+		log.Printf("Connected to VNC desktop: %s", c.DesktopName)
+		keys = c
 	}
-	defer c.Close()
 
-	log.Printf("Connected to VNC desktop: %s", c.DesktopName)
+	var isoPath string
+	if !config.DiskImage {
+		isoPath = state.Get("iso_path").(string)
+	}
 
 	ctx := config.ctx
 	ctx.Data = &bootCommandTemplateData{
-		"10.0.2.2",
+		hostHTTPIP(config),
 		httpPort,
 		config.VMName,
+		isoPath,
 	}
 
-	ui.Say("Typing the boot command over VNC...")
+	ui.Say(fmt.Sprintf("Typing the boot command over %s...", via))
 	for _, command := range config.BootCommand {
 		command, err := interpolate.Render(command, &ctx)
 		if err != nil {
@@ -86,7 +135,12 @@ func (s *stepTypeBootCommand) Run(state multistep.StateBag) multistep.StepAction
 			return multistep.ActionHalt
 		}
 
-		vncSendString(c, command)
+		if err := sendBootString(keys, command); err != nil {
+			err := fmt.Errorf("Error typing boot command: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
 	}
 
 	return multistep.ActionContinue
@@ -94,7 +148,7 @@ func (s *stepTypeBootCommand) Run(state multistep.StateBag) multistep.StepAction
 
 func (*stepTypeBootCommand) Cleanup(multistep.StateBag) {}
 
-func vncSendString(c *vnc.ClientConn, original string) {
+func sendBootString(c keyEventSender, original string) error {
 	// Scancodes reference: https://github.com/qemu/qemu/blob/master/ui/vnc_keysym.h
 	special := make(map[string]uint32)
 	special["<bs>"] = 0xFF08
@@ -173,17 +227,27 @@ func vncSendString(c *vnc.ClientConn, original string) {
 		}
 
 		if keyShift {
-			c.KeyEvent(KeyLeftShift, true)
+			if err := c.KeyEvent(KeyLeftShift, true); err != nil {
+				return err
+			}
 		}
 
-		c.KeyEvent(keyCode, true)
-		c.KeyEvent(keyCode, false)
+		if err := c.KeyEvent(keyCode, true); err != nil {
+			return err
+		}
+		if err := c.KeyEvent(keyCode, false); err != nil {
+			return err
+		}
 
 		if keyShift {
-			c.KeyEvent(KeyLeftShift, false)
+			if err := c.KeyEvent(KeyLeftShift, false); err != nil {
+				return err
+			}
 		}
 
 		// qemu is picky, so no matter what, wait a small period
 		time.Sleep(100 * time.Millisecond)
 	}
+
+	return nil
 }
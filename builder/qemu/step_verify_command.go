@@ -0,0 +1,42 @@
+package qemu
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step runs the command that verifies the artifact in the VM.
+//
+// Uses:
+//   communicator packer.Communicator
+//   ui           packer.Ui
+type stepVerifyCommand struct {
+	Command string
+}
+
+func (s *stepVerifyCommand) Run(state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packer.Communicator)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say(fmt.Sprintf("Running the verify command: %s", s.Command))
+	cmd := &packer.RemoteCmd{Command: s.Command}
+	if err := cmd.StartWithUi(comm, ui); err != nil {
+		err := fmt.Errorf("Error running the verify command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if cmd.ExitStatus != 0 {
+		err := fmt.Errorf("The verify command exited with status %d", cmd.ExitStatus)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepVerifyCommand) Cleanup(multistep.StateBag) {}
@@ -0,0 +1,82 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// tpmDevices are the TPM devices of Qemu that tpm_device can be.
+var tpmDevices = map[string]bool{
+	"tpm-crb":        true,
+	"tpm-spapr":      true,
+	"tpm-tis":        true,
+	"tpm-tis-device": true,
+}
+
+// tpmEnabled returns true if the VM has a TPM that is emulated by swtpm.
+func (c *Config) tpmEnabled() bool {
+	return c.TPMDevice != "" || c.TPMVersion != ""
+}
+
+// prepareTPM validates the TPM, which is added if either tpm_device or
+// tpm_version is set. The other defaults to the TPM device of the guest
+// architecture and TPM 2.0.
+func (c *Config) prepareTPM() []error {
+	if !c.tpmEnabled() {
+		return nil
+	}
+
+	var errs []error
+
+	if c.TPMDevice == "" {
+		c.TPMDevice = c.arch().TPMDevice
+		if c.TPMDevice == "" {
+			errs = append(errs, fmt.Errorf(
+				"tpm_device must be set for %s guests", efiArch(c.QemuBinary)))
+		}
+	} else if !tpmDevices[c.TPMDevice] {
+		errs = append(errs, fmt.Errorf("unrecognized TPM device: %s", c.TPMDevice))
+	}
+
+	if c.TPMVersion == "" {
+		c.TPMVersion = "2.0"
+	}
+	if c.TPMVersion != "1.2" && c.TPMVersion != "2.0" {
+		errs = append(errs, errors.New("tpm_version must be '1.2' or '2.0'"))
+	}
+
+	// Qemu talks to swtpm over a Unix socket
+	if runtime.GOOS == "windows" {
+		errs = append(errs, errors.New("a TPM is not supported on Windows"))
+	}
+
+	return errs
+}
+
+// swtpmArgs returns the arguments of swtpm to emulate the TPM with its
+// state in dir and its control channel on the socket. swtpm exits once
+// Qemu disconnects from the socket.
+func (c *Config) swtpmArgs(dir, socket string) []string {
+	args := []string{
+		"socket",
+		"--tpmstate", "dir=" + dir,
+		"--ctrl", "type=unixio,path=" + socket,
+		"--terminate",
+	}
+	if c.TPMVersion == "2.0" {
+		args = append(args, "--tpm2")
+	}
+
+	return args
+}
+
+// tpmArgs returns the arguments of Qemu that attach the TPM whose swtpm
+// listens on the socket.
+func (c *Config) tpmArgs(socket string) map[string][]string {
+	return map[string][]string{
+		"-chardev": {"socket,id=chrtpm,path=" + socket},
+		"-tpmdev":  {"emulator,id=tpm0,chardev=chrtpm"},
+		"-device":  {c.TPMDevice + ",tpmdev=tpm0"},
+	}
+}
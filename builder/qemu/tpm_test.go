@@ -0,0 +1,154 @@
+package qemu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestBuilderPrepare_TPM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("a TPM is not supported on Windows")
+	}
+
+	var b Builder
+	config := testConfig()
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.tpmEnabled() {
+		t.Fatal("should not have a TPM")
+	}
+
+	// The device of the architecture and TPM 2.0 are the defaults
+	config["tpm_version"] = "2.0"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.TPMDevice != "tpm-tis" {
+		t.Fatalf("bad: %s", b.config.TPMDevice)
+	}
+
+	delete(config, "tpm_version")
+	config["tpm_device"] = "tpm-crb"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.TPMVersion != "2.0" {
+		t.Fatalf("bad: %s", b.config.TPMVersion)
+	}
+
+	config["tpm_device"] = "tpm-foo"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["tpm_device"] = "tpm-tis"
+	config["tpm_version"] = "3.0"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestConfigSwtpmArgs(t *testing.T) {
+	c := &Config{TPMDevice: "tpm-tis", TPMVersion: "2.0"}
+
+	expected := []string{
+		"socket",
+		"--tpmstate", "dir=/tmp/tpm",
+		"--ctrl", "type=unixio,path=/tmp/tpm/swtpm.sock",
+		"--terminate",
+		"--tpm2",
+	}
+	if args := c.swtpmArgs("/tmp/tpm", "/tmp/tpm/swtpm.sock"); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	c.TPMVersion = "1.2"
+	if args := c.swtpmArgs("/tmp/tpm", "/tmp/tpm/swtpm.sock"); !reflect.DeepEqual(args, expected[:len(expected)-1]) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	qemuArgs := c.tpmArgs("/tmp/tpm/swtpm.sock")
+	if v := qemuArgs["-device"]; !reflect.DeepEqual(v, []string{"tpm-tis,tpmdev=tpm0"}) {
+		t.Fatalf("bad: %#v", v)
+	}
+	if v := qemuArgs["-chardev"]; !reflect.DeepEqual(v, []string{"socket,id=chrtpm,path=/tmp/tpm/swtpm.sock"}) {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func testFakeSwtpm(t *testing.T, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := filepath.Join(td, "swtpm")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return path
+}
+
+func TestStepStartTPM(t *testing.T) {
+	// The fake swtpm creates the socket of --ctrl and waits
+	path := testFakeSwtpm(t, `
+while [ $# -gt 0 ]; do
+	case "$1" in type=unixio,path=*) touch "${1#type=unixio,path=}" ;; esac
+	shift
+done
+exec sleep 60`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("config", &Config{TPMDevice: "tpm-tis", TPMVersion: "2.0"})
+
+	step := &stepStartTPM{SwtpmPath: path}
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+
+	socket := state.Get("tpm_socket").(string)
+	if _, err := os.Stat(socket); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// swtpm is stopped and its state removed
+	step.Cleanup(state)
+	if _, err := os.Stat(filepath.Dir(socket)); !os.IsNotExist(err) {
+		t.Fatalf("should remove the state: %s", err)
+	}
+}
+
+func TestStepStartTPM_exited(t *testing.T) {
+	path := testFakeSwtpm(t, `echo "no such device" >&2; exit 1`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("config", &Config{TPMDevice: "tpm-tis", TPMVersion: "2.0"})
+
+	step := &stepStartTPM{SwtpmPath: path, Timeout: 5 * time.Second}
+	defer step.Cleanup(state)
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
@@ -0,0 +1,161 @@
+package qemu
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// usageSampleInterval is how often the resources that the VM uses are
+// sampled during the build.
+const usageSampleInterval = 10 * time.Second
+
+// clockTicks is the unit of the CPU times in /proc/<pid>/stat, which is
+// 1/100 of a second on all architectures that Linux runs Qemu on.
+const clockTicks = 100
+
+// processUsage is a sample of the resources used by the Qemu process.
+type processUsage struct {
+	// CPUTime is the user and system CPU time since Qemu started.
+	CPUTime time.Duration
+
+	// RSS is the resident memory in bytes.
+	RSS uint64
+}
+
+// usageSummary collects the samples of the resources used by the VM
+// during the build, to show how much of what it was given it used.
+type usageSummary struct {
+	// The first and last process samples, and when they were taken.
+	first, last       processUsage
+	firstAt, lastAt   time.Time
+	processSamples    int
+	peakCPU           float64
+	peakRSS           uint64
+	peakDisk, endDisk int64
+	diskSamples       int
+}
+
+// AddProcess adds a sample of the Qemu process taken at the time.
+func (u *usageSummary) AddProcess(at time.Time, p processUsage) {
+	if u.processSamples == 0 {
+		u.first, u.firstAt = p, at
+	} else if wall := at.Sub(u.lastAt); wall > 0 {
+		cpu := float64(p.CPUTime-u.last.CPUTime) / float64(wall)
+		if cpu > u.peakCPU {
+			u.peakCPU = cpu
+		}
+	}
+
+	if p.RSS > u.peakRSS {
+		u.peakRSS = p.RSS
+	}
+	u.last, u.lastAt = p, at
+	u.processSamples++
+}
+
+// AddDisk adds a sample of the space used by the disks of the VM.
+func (u *usageSummary) AddDisk(size int64) {
+	if size > u.peakDisk {
+		u.peakDisk = size
+	}
+	u.endDisk = size
+	u.diskSamples++
+}
+
+// Lines returns the summary for a VM with the given number of CPUs and
+// megabytes of memory, one line for each resource that was sampled.
+func (u *usageSummary) Lines(cpus, memory uint) []string {
+	var lines []string
+
+	if u.processSamples > 1 {
+		wall := u.lastAt.Sub(u.firstAt)
+		cpuTime := u.last.CPUTime - u.first.CPUTime
+		average := float64(cpuTime) / float64(wall)
+		lines = append(lines, fmt.Sprintf(
+			"CPU: %.0f%% on average and %.0f%% at peak of %d CPUs (%s of CPU time)",
+			100*average/float64(cpus), 100*u.peakCPU/float64(cpus), cpus,
+			cpuTime-cpuTime%time.Second))
+	}
+
+	if u.processSamples > 0 {
+		lines = append(lines, fmt.Sprintf(
+			"Memory: %s resident at peak of %d MB", formatBytes(int64(u.peakRSS)), memory))
+	}
+
+	if u.diskSamples > 0 {
+		lines = append(lines, fmt.Sprintf(
+			"Disk: %s at peak, %s at the end", formatBytes(u.peakDisk), formatBytes(u.endDisk)))
+	}
+
+	return lines
+}
+
+// formatBytes formats a number of bytes in the largest unit that it has
+// at least one of.
+func formatBytes(n int64) string {
+	units := []string{"bytes", "KB", "MB", "GB", "TB"}
+	value := float64(n)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+
+	if i == 0 {
+		return fmt.Sprintf("%d %s", n, units[i])
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
+
+// parseProcStat parses the CPU time out of the contents of
+// /proc/<pid>/stat.
+func parseProcStat(data string) (time.Duration, error) {
+	// The name of the command is in parentheses and can have spaces
+	end := strings.LastIndex(data, ")")
+	if end < 0 {
+		return 0, fmt.Errorf("Invalid stat: %q", data)
+	}
+
+	// utime and stime are the 14th and 15th fields, counting the pid and
+	// the name of the command as the first two
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("Invalid stat: %q", data)
+	}
+
+	var ticks uint64
+	for _, f := range fields[11:13] {
+		t, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid stat: %q", data)
+		}
+		ticks += t
+	}
+
+	return time.Duration(ticks) * time.Second / clockTicks, nil
+}
+
+// parseProcStatus parses the resident memory in bytes out of the
+// contents of /proc/<pid>/status.
+func parseProcStatus(data string) (uint64, error) {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[0] != "VmRSS:" || fields[2] != "kB" {
+			continue
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid VmRSS: %s", fields[1])
+		}
+
+		return kb * 1024, nil
+	}
+
+	return 0, fmt.Errorf("No VmRSS in status")
+}
@@ -0,0 +1,43 @@
+package qemu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"syscall"
+)
+
+// sampleProcess samples the resources used by the process with the pid.
+func sampleProcess(pid int) (processUsage, error) {
+	var usage processUsage
+
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return usage, err
+	}
+	if usage.CPUTime, err = parseProcStat(string(stat)); err != nil {
+		return usage, err
+	}
+
+	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return usage, err
+	}
+	usage.RSS, err = parseProcStatus(string(status))
+	return usage, err
+}
+
+// diskSpace returns the space that the file takes on the disk of the
+// host, which is less than its size for sparse files such as raw disks.
+func diskSpace(path string) (int64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return st.Blocks * 512, nil
+	}
+
+	return fi.Size(), nil
+}
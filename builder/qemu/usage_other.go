@@ -0,0 +1,24 @@
+// +build !linux
+
+package qemu
+
+import (
+	"errors"
+	"os"
+)
+
+// sampleProcess samples the resources used by the process with the pid,
+// which is only supported on Linux.
+func sampleProcess(pid int) (processUsage, error) {
+	return processUsage{}, errors.New("sampling processes is only supported on Linux")
+}
+
+// diskSpace returns the size of the file.
+func diskSpace(path string) (int64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return fi.Size(), nil
+}
@@ -0,0 +1,83 @@
+package qemu
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	// The name of the command has a space and parentheses of its own
+	stat := "1234 (qemu (x86 64)) S 1 1234 1234 0 -1 4194560 100 0 0 0 " +
+		"250 50 0 0 20 0 3 0 100 1000000 2000 18446744073709551615"
+
+	cpu, err := parseProcStat(stat)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if cpu != 3*time.Second {
+		t.Fatalf("bad: %s", cpu)
+	}
+
+	for _, bad := range []string{"", "1234 (qemu) S 1 2", "1234 (qemu) S 1 1234 1234 0 -1 4194560 100 0 0 0 x 50"} {
+		if _, err := parseProcStat(bad); err == nil {
+			t.Fatalf("should error: %q", bad)
+		}
+	}
+}
+
+func TestParseProcStatus(t *testing.T) {
+	status := "Name:\tqemu-system-x86\nVmPeak:\t 2000000 kB\nVmHWM:\t  600000 kB\nVmRSS:\t  524288 kB\n"
+
+	rss, err := parseProcStatus(status)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if rss != 512*1024*1024 {
+		t.Fatalf("bad: %d", rss)
+	}
+
+	if _, err := parseProcStatus("Name:\tqemu\n"); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestUsageSummary(t *testing.T) {
+	var u usageSummary
+	if lines := u.Lines(2, 1024); len(lines) != 0 {
+		t.Fatalf("bad: %#v", lines)
+	}
+
+	start := time.Now()
+	u.AddProcess(start, processUsage{CPUTime: 0, RSS: 100 * 1024 * 1024})
+	u.AddProcess(start.Add(10*time.Second), processUsage{CPUTime: 15 * time.Second, RSS: 300 * 1024 * 1024})
+	u.AddProcess(start.Add(20*time.Second), processUsage{CPUTime: 20 * time.Second, RSS: 200 * 1024 * 1024})
+	u.AddDisk(3 * 1024 * 1024 * 1024)
+	u.AddDisk(1024 * 1024 * 1024)
+
+	expected := []string{
+		"CPU: 50% on average and 75% at peak of 2 CPUs (20s of CPU time)",
+		"Memory: 300.0 MB resident at peak of 1024 MB",
+		"Disk: 3.0 GB at peak, 1.0 GB at the end",
+	}
+	if lines := u.Lines(2, 1024); !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("bad: %#v", lines)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:                       "0 bytes",
+		1023:                    "1023 bytes",
+		1536:                    "1.5 KB",
+		10 * 1024 * 1024:        "10.0 MB",
+		5 << 40:                 "5.0 TB",
+		2048 * (int64(1) << 40): "2048.0 TB",
+	}
+
+	for n, expected := range cases {
+		if actual := formatBytes(n); actual != expected {
+			t.Fatalf("%d: expected %q, got %q", n, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/helper/communicator"
+	"github.com/mitchellh/packer/packer"
+)
+
+// PrepareVerify checks that the VM that boots the artifact can be reached.
+func (b *Builder) PrepareVerify() error {
+	if b.config.Comm.Type == "none" {
+		return errors.New("the artifact can only be verified with a communicator")
+	}
+
+	if b.config.Ephemeral {
+		return errors.New("ephemeral builds have no artifact to verify")
+	}
+
+	return nil
+}
+
+// VerifyArtifact boots the disks of the artifact with -snapshot, so that
+// nothing is written to them, and runs the command over the communicator.
+func (b *Builder) VerifyArtifact(ui packer.Ui, artifact packer.Artifact, command string, timeout time.Duration) error {
+	diskName, ok := artifact.State("diskName").(string)
+	if !ok {
+		return errors.New("the artifact has no disk")
+	}
+
+	driver, err := b.newDriver(b.config.QemuBinary, &b.config.ResourceLimits,
+		filepath.Join(b.config.OutputDir, "qemu.log"))
+	if err != nil {
+		return fmt.Errorf("Failed creating Qemu driver: %s", err)
+	}
+
+	rawVersion, err := driver.Version()
+	if err != nil {
+		return fmt.Errorf("Error reading the version of Qemu: %s", err)
+	}
+	version, err := parseQemuVersion(rawVersion)
+	if err != nil {
+		return fmt.Errorf("Error reading the version of Qemu: %s", err)
+	}
+
+	// The VM boots the disk of the artifact like an ephemeral build does
+	// its disk image.
+	config := b.config
+	config.DiskImage = true
+	config.Ephemeral = true
+
+	steps := []multistep.Step{
+		new(stepHTTPServer),
+		new(stepForwardSSH),
+		new(stepForwardPorts),
+		new(stepConfigureVNC),
+		new(stepStartTPM),
+		&stepRun{
+			BootDrive: "c",
+			Message:   "Starting VM, booting the disk of the artifact",
+		},
+		&communicator.StepConnect{
+			Config:    &config.Comm,
+			Host:      commHost,
+			SSHConfig: sshConfig,
+			SSHPort:   commPort,
+		},
+		&stepVerifyCommand{Command: command},
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &config)
+	state.Put("driver", driver)
+	state.Put("iso_path", filepath.Join(config.OutputDir, diskName))
+	state.Put("qemu_version", version)
+	state.Put("ui", ui)
+	if config.EFIBoot {
+		state.Put("efi_vars_path", config.efiVarsPath())
+	}
+
+	b.runner = &multistep.BasicRunner{
+		Steps: common.TraceSteps(config.PackerBuildName, steps),
+	}
+
+	timer := time.AfterFunc(timeout, func() {
+		log.Printf("Verification timed out after %s, cancelling", timeout)
+		state.Put("error", fmt.Errorf("The verification timed out after %s", timeout))
+		b.runner.Cancel()
+	})
+	b.runner.Run(state)
+	timer.Stop()
+
+	if rawErr, ok := state.GetOk("error"); ok {
+		return rawErr.(error)
+	}
+
+	if _, ok := state.GetOk(multistep.StateCancelled); ok {
+		return errors.New("Verification was cancelled.")
+	}
+
+	if _, ok := state.GetOk(multistep.StateHalted); ok {
+		return errors.New("Verification was halted.")
+	}
+
+	return nil
+}
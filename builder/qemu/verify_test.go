@@ -0,0 +1,44 @@
+package qemu
+
+import (
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestBuilder_ImplementsArtifactVerifier(t *testing.T) {
+	var _ packer.ArtifactVerifier = new(Builder)
+}
+
+func TestBuilderPrepareVerify(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := b.PrepareVerify(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The command is run over the communicator
+	config["communicator"] = "none"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := b.PrepareVerify(); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Ephemeral builds have no artifact
+	delete(config, "communicator")
+	config["disk_image"] = true
+	config["ephemeral"] = true
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := b.PrepareVerify(); err == nil {
+		t.Fatal("should have error")
+	}
+}
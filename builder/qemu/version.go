@@ -0,0 +1,141 @@
+package qemu
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// qemuVersion is the version of the Qemu binary that the VM is run with.
+// Arguments that Qemu only supports since some version are chosen by it,
+// and options that need a newer Qemu are rejected before the VM is
+// started rather than with a cryptic error of Qemu.
+type qemuVersion struct {
+	Major int
+	Minor int
+	Micro int
+}
+
+// minQemuVersion is the oldest version of Qemu that is supported, which
+// has the -display and -machine accel options that are always used.
+var minQemuVersion = qemuVersion{1, 0, 0}
+
+// Versions of Qemu that added the features that are adjusted for.
+var (
+	// The discard option of -drive.
+	qemuVersionDiscard = qemuVersion{1, 5, 0}
+
+	// The detect-zeroes option of -drive, and the iothread option of
+	// virtio-blk devices.
+	qemuVersionDetectZeroes = qemuVersion{2, 1, 0}
+	qemuVersionIOThread     = qemuVersion{2, 1, 0}
+
+	// The io_uring mode of the aio option of -drive.
+	qemuVersionIOURing = qemuVersion{5, 0, 0}
+
+	// The IPv6 options of user mode networks.
+	qemuVersionNetIPv6 = qemuVersion{2, 6, 0}
+
+	// The emulator backend of -tpmdev, which swtpm is attached with.
+	qemuVersionTPMEmulator = qemuVersion{2, 11, 0}
+
+	// The format argument of screendump, which saves PPM files before.
+	qemuVersionScreendumpPNG = qemuVersion{7, 1, 0}
+)
+
+// qemuVersionAccels are the versions of Qemu that added the accelerators
+// of macOS and Windows.
+var qemuVersionAccels = map[string]qemuVersion{
+	"hax":  {2, 9, 0},
+	"hvf":  {2, 12, 0},
+	"whpx": {3, 0, 0},
+}
+
+var qemuVersionRe = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// parseQemuVersion parses a version such as 2.5.0 out of s, which can be
+// the output of qemu -version.
+func parseQemuVersion(s string) (qemuVersion, error) {
+	match := qemuVersionRe.FindStringSubmatch(s)
+	if match == nil {
+		return qemuVersion{}, fmt.Errorf("No version found: %s", s)
+	}
+
+	var v qemuVersion
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		v.Micro, _ = strconv.Atoi(match[3])
+	}
+
+	return v, nil
+}
+
+func (v qemuVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Micro)
+}
+
+// AtLeast returns true if v is the same as or newer than other.
+func (v qemuVersion) AtLeast(other qemuVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+
+	return v.Micro >= other.Micro
+}
+
+// checkQemuVersion returns errors for the options that the version of
+// Qemu doesn't support.
+func (c *Config) checkQemuVersion(v qemuVersion) []error {
+	if !v.AtLeast(minQemuVersion) {
+		return []error{fmt.Errorf(
+			"Qemu %s is not supported, Qemu %s or newer is required", v, minQemuVersion)}
+	}
+
+	var errs []error
+
+	if min, ok := qemuVersionAccels[c.Accelerator]; ok && !v.AtLeast(min) {
+		errs = append(errs, fmt.Errorf(
+			"the %s accelerator requires Qemu %s or newer, found %s", c.Accelerator, min, v))
+	}
+
+	if c.NetIPv6 && !v.AtLeast(qemuVersionNetIPv6) {
+		errs = append(errs, fmt.Errorf(
+			"net_ipv6 requires Qemu %s or newer, found %s", qemuVersionNetIPv6, v))
+	}
+
+	if c.TPMDevice != "" && !v.AtLeast(qemuVersionTPMEmulator) {
+		errs = append(errs, fmt.Errorf(
+			"tpm_device requires Qemu %s or newer, found %s", qemuVersionTPMEmulator, v))
+	}
+
+	if c.DiskDetectZeroes != "" && !v.AtLeast(qemuVersionDetectZeroes) {
+		errs = append(errs, fmt.Errorf(
+			"disk_detect_zeroes requires Qemu %s or newer, found %s", qemuVersionDetectZeroes, v))
+	}
+
+	if c.DiskAIO == "io_uring" && !v.AtLeast(qemuVersionIOURing) {
+		errs = append(errs, fmt.Errorf(
+			"disk_aio 'io_uring' requires Qemu %s or newer, found %s", qemuVersionIOURing, v))
+	}
+
+	if c.DiskIOThread && !v.AtLeast(qemuVersionIOThread) {
+		errs = append(errs, fmt.Errorf(
+			"disk_iothread requires Qemu %s or newer, found %s", qemuVersionIOThread, v))
+	}
+
+	return errs
+}
+
+// driveDiscard returns the discard option for -drive, which is left out
+// if Qemu doesn't support it.
+func (c *Config) driveDiscard(v qemuVersion) string {
+	if !v.AtLeast(qemuVersionDiscard) {
+		return ""
+	}
+
+	return ",discard=" + c.DiskDiscard
+}
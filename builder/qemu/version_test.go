@@ -0,0 +1,90 @@
+package qemu
+
+import (
+	"testing"
+)
+
+func TestParseQemuVersion(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Expected qemuVersion
+	}{
+		{"2.5.0", qemuVersion{2, 5, 0}},
+		{"1.0", qemuVersion{1, 0, 0}},
+		{"2.11.1", qemuVersion{2, 11, 1}},
+	}
+
+	for _, tc := range cases {
+		v, err := parseQemuVersion(tc.Input)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Input, err)
+		}
+		if v != tc.Expected {
+			t.Fatalf("%s: bad: %s", tc.Input, v)
+		}
+	}
+
+	if _, err := parseQemuVersion("unknown"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestQemuVersionAtLeast(t *testing.T) {
+	v := qemuVersion{2, 6, 1}
+
+	for _, other := range []qemuVersion{{1, 7, 0}, {2, 5, 3}, {2, 6, 0}, {2, 6, 1}} {
+		if !v.AtLeast(other) {
+			t.Fatalf("should be at least %s", other)
+		}
+	}
+
+	for _, other := range []qemuVersion{{3, 0, 0}, {2, 7, 0}, {2, 6, 2}} {
+		if v.AtLeast(other) {
+			t.Fatalf("should not be at least %s", other)
+		}
+	}
+}
+
+func TestConfigCheckQemuVersion(t *testing.T) {
+	c := &Config{NetIPv6: true, TPMDevice: "tpm-tis"}
+
+	if errs := c.checkQemuVersion(qemuVersion{2, 11, 0}); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	if errs := c.checkQemuVersion(qemuVersion{2, 5, 0}); len(errs) != 2 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	if errs := (&Config{}).checkQemuVersion(qemuVersion{0, 15, 1}); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestConfigCheckQemuVersion_diskIO(t *testing.T) {
+	c := &Config{DiskAIO: "io_uring", DiskDetectZeroes: "on", DiskIOThread: true}
+
+	if errs := c.checkQemuVersion(qemuVersion{5, 0, 0}); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	if errs := c.checkQemuVersion(qemuVersion{4, 2, 1}); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	if errs := c.checkQemuVersion(qemuVersion{2, 0, 0}); len(errs) != 3 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestConfigDriveDiscard(t *testing.T) {
+	c := &Config{DiskDiscard: "unmap"}
+
+	if d := c.driveDiscard(qemuVersion{2, 5, 0}); d != ",discard=unmap" {
+		t.Fatalf("bad: %s", d)
+	}
+
+	if d := c.driveDiscard(qemuVersion{1, 4, 2}); d != "" {
+		t.Fatalf("bad: %s", d)
+	}
+}
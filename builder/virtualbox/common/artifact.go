@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -57,6 +58,13 @@ func (a *artifact) String() string {
 }
 
 func (a *artifact) State(name string) interface{} {
+	// An OVA has no separate disk images, so it is left to Files()
+	if name == common.ArtifactStateImageFiles {
+		if images := common.DiskImages(a.f); len(images) > 0 {
+			return images
+		}
+	}
+
 	return nil
 }
 
@@ -19,12 +19,20 @@ type Driver interface {
 	// Create a SATA controller.
 	CreateSATAController(vm string, controller string) error
 
+	// Create an NVMe controller.
+	CreateNVMeController(vm string, controller string) error
+
 	// Create a SCSI controller.
 	CreateSCSIController(vm string, controller string) error
 
 	// Delete a VM by name
 	Delete(string) error
 
+	// GuestIP returns the IPv4 address of the first network adapter of the
+	// VM as reported by the guest additions, or an empty string if they
+	// haven't reported one.
+	GuestIP(string) (string, error)
+
 	// Import a VM
 	Import(string, string, []string) error
 
@@ -36,6 +36,18 @@ func (d *VBox42Driver) CreateSATAController(vmName string, name string) error {
 	return d.VBoxManage(command...)
 }
 
+func (d *VBox42Driver) CreateNVMeController(vmName string, name string) error {
+	command := []string{
+		"storagectl", vmName,
+		"--name", name,
+		"--add", "pcie",
+		"--controller", "NVMe",
+		"--portcount", "1",
+	}
+
+	return d.VBoxManage(command...)
+}
+
 func (d *VBox42Driver) CreateSCSIController(vmName string, name string) error {
 
 	command := []string{
@@ -52,6 +64,19 @@ func (d *VBox42Driver) Delete(name string) error {
 	return d.VBoxManage("unregistervm", name, "--delete")
 }
 
+func (d *VBox42Driver) GuestIP(name string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(d.VBoxManagePath,
+		"guestproperty", "get", name, "/VirtualBox/GuestInfo/Net/0/V4/IP")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	return parseGuestProperty(stdout.String()), nil
+}
+
 func (d *VBox42Driver) Iso() (string, error) {
 	var stdout bytes.Buffer
 
@@ -217,3 +242,14 @@ func (d *VBox42Driver) Version() (string, error) {
 	log.Printf("VirtualBox version: %s", matches[0][1])
 	return matches[0][1], nil
 }
+
+// parseGuestProperty parses the output of VBoxManage guestproperty get,
+// which is "Value: VALUE", or "No value set!" if the property isn't set.
+func parseGuestProperty(output string) string {
+	output = strings.TrimSpace(output)
+	if !strings.HasPrefix(output, "Value: ") {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(output, "Value: "))
+}
@@ -7,3 +7,17 @@ import (
 func TestVBox42Driver_impl(t *testing.T) {
 	var _ Driver = new(VBox42Driver)
 }
+
+func TestParseGuestProperty(t *testing.T) {
+	cases := map[string]string{
+		"Value: 10.0.2.15\n": "10.0.2.15",
+		"No value set!\n":    "",
+		"":                   "",
+	}
+
+	for input, expected := range cases {
+		if v := parseGuestProperty(input); v != expected {
+			t.Fatalf("%q: bad: %s", input, v)
+		}
+	}
+}
@@ -9,6 +9,10 @@ type DriverMock struct {
 	CreateSATAControllerController string
 	CreateSATAControllerErr        error
 
+	CreateNVMeControllerVM         string
+	CreateNVMeControllerController string
+	CreateNVMeControllerErr        error
+
 	CreateSCSIControllerVM         string
 	CreateSCSIControllerController string
 	CreateSCSIControllerErr        error
@@ -17,6 +21,10 @@ type DriverMock struct {
 	DeleteName   string
 	DeleteErr    error
 
+	GuestIPName   string
+	GuestIPResult string
+	GuestIPErr    error
+
 	ImportCalled bool
 	ImportName   string
 	ImportPath   string
@@ -53,6 +61,12 @@ func (d *DriverMock) CreateSATAController(vm string, controller string) error {
 	return d.CreateSATAControllerErr
 }
 
+func (d *DriverMock) CreateNVMeController(vm string, controller string) error {
+	d.CreateNVMeControllerVM = vm
+	d.CreateNVMeControllerController = controller
+	return d.CreateNVMeControllerErr
+}
+
 func (d *DriverMock) CreateSCSIController(vm string, controller string) error {
 	d.CreateSCSIControllerVM = vm
 	d.CreateSCSIControllerController = vm
@@ -65,6 +79,14 @@ func (d *DriverMock) Delete(name string) error {
 	return d.DeleteErr
 }
 
+func (d *DriverMock) GuestIP(name string) (string, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.GuestIPName = name
+	return d.GuestIPResult, d.GuestIPErr
+}
+
 func (d *DriverMock) Import(name string, path string, flags []string) error {
 	d.ImportCalled = true
 	d.ImportName = name
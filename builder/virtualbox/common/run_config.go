@@ -2,9 +2,9 @@ package common
 
 import (
 	"errors"
-	"fmt"
 	"time"
 
+	"github.com/mitchellh/packer/helper/config"
 	"github.com/mitchellh/packer/template/interpolate"
 )
 
@@ -34,9 +34,9 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 
 	var errs []error
 	var err error
-	c.BootWait, err = time.ParseDuration(c.RawBootWait)
+	c.BootWait, err = config.ParseDuration("boot_wait", c.RawBootWait)
 	if err != nil {
-		errs = append(errs, fmt.Errorf("Failed parsing boot_wait: %s", err))
+		errs = append(errs, err)
 	}
 
 	if c.HTTPPortMin > c.HTTPPortMax {
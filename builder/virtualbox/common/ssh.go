@@ -12,8 +12,23 @@ func CommHost(state multistep.StateBag) (string, error) {
 }
 
 func SSHPort(state multistep.StateBag) (int, error) {
-	sshHostPort := state.Get("sshHostPort").(uint)
-	return int(sshHostPort), nil
+	sshHostPort := state.Get("sshHostPort").(int)
+	return sshHostPort, nil
+}
+
+// SSHEndpoint returns the host and port that SSH of the guest is forwarded
+// to, for common.StepReportGuestAddress.
+func SSHEndpoint(state multistep.StateBag) (string, int, error) {
+	port, err := SSHPort(state)
+	return "127.0.0.1", port, err
+}
+
+// GuestIP returns the address of the guest reported by the guest
+// additions, for common.StepReportGuestAddress.
+func GuestIP(state multistep.StateBag) (string, error) {
+	driver := state.Get("driver").(Driver)
+	vmName := state.Get("vmName").(string)
+	return driver.GuestIP(vmName)
 }
 
 func SSHConfigFunc(config SSHConfig) func(multistep.StateBag) (*gossh.ClientConfig, error) {
@@ -20,6 +20,11 @@ var additionsVersionMap = map[string]string{
 	"4.1.23": "4.1.22",
 }
 
+// DefaultGuestAdditionsMirror is where the guest additions are downloaded
+// from unless guest_additions_mirror is set. Mirrors have the same layout,
+// with the ISO and SHA256SUMS in a directory for each version.
+const DefaultGuestAdditionsMirror = "http://download.virtualbox.org/virtualbox"
+
 type guestAdditionsUrlTemplate struct {
 	Version string
 }
@@ -33,6 +38,7 @@ type StepDownloadGuestAdditions struct {
 	GuestAdditionsMode   string
 	GuestAdditionsURL    string
 	GuestAdditionsSHA256 string
+	GuestAdditionsMirror string
 	Ctx                  interpolate.Context
 }
 
@@ -83,14 +89,15 @@ func (s *StepDownloadGuestAdditions) Run(state multistep.StateBag) multistep.Ste
 	} else {
 		url, err = driver.Iso()
 
+		// The ISO that comes with VirtualBox is only verified against
+		// guest_additions_sha256, as there are no checksums for it
 		if err == nil {
-			checksumType = "none"
+			if s.GuestAdditionsSHA256 == "" {
+				checksumType = "none"
+			}
 		} else {
 			ui.Error(err.Error())
-			url = fmt.Sprintf(
-				"http://download.virtualbox.org/virtualbox/%s/%s",
-				version,
-				additionsName)
+			url = fmt.Sprintf("%s/%s/%s", s.mirror(), version, additionsName)
 		}
 	}
 	if url == "" {
@@ -135,12 +142,18 @@ func (s *StepDownloadGuestAdditions) Run(state multistep.StateBag) multistep.Ste
 
 func (s *StepDownloadGuestAdditions) Cleanup(state multistep.StateBag) {}
 
+func (s *StepDownloadGuestAdditions) mirror() string {
+	if s.GuestAdditionsMirror != "" {
+		return s.GuestAdditionsMirror
+	}
+
+	return DefaultGuestAdditionsMirror
+}
+
 func (s *StepDownloadGuestAdditions) downloadAdditionsSHA256(state multistep.StateBag, additionsVersion string, additionsName string) (string, multistep.StepAction) {
 	// First things first, we get the list of checksums for the files available
 	// for this version.
-	checksumsUrl := fmt.Sprintf(
-		"http://download.virtualbox.org/virtualbox/%s/SHA256SUMS",
-		additionsVersion)
+	checksumsUrl := fmt.Sprintf("%s/%s/SHA256SUMS", s.mirror(), additionsVersion)
 
 	checksumsFile, err := ioutil.TempFile("", "packer")
 	if err != nil {
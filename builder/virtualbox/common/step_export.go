@@ -10,7 +10,8 @@ import (
 	"time"
 )
 
-// This step cleans up forwarded ports and exports the VM to an OVF.
+// This step cleans up forwarded ports and exports the VM to an OVF, unless
+// SkipExport is set.
 //
 // Uses:
 //
@@ -21,6 +22,7 @@ type StepExport struct {
 	OutputDir      string
 	ExportOpts     []string
 	SkipNatMapping bool
+	SkipExport     bool
 }
 
 func (s *StepExport) Run(state multistep.StateBag) multistep.StepAction {
@@ -47,6 +49,11 @@ func (s *StepExport) Run(state multistep.StateBag) multistep.StepAction {
 		}
 	}
 
+	if s.SkipExport {
+		ui.Say("Skipping export of virtual machine...")
+		return multistep.ActionContinue
+	}
+
 	// Export the VM to an OVF
 	outputPath := filepath.Join(s.OutputDir, vmName+"."+s.Format)
 
@@ -41,3 +41,33 @@ func TestStepExport(t *testing.T) {
 		t.Fatal("bad")
 	}
 }
+
+func TestStepExport_skipExport(t *testing.T) {
+	state := testState(t)
+	step := &StepExport{SkipExport: true}
+
+	state.Put("vmName", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test output state
+	if _, ok := state.GetOk("exportPath"); ok {
+		t.Fatal("should NOT set exportPath")
+	}
+
+	// The forwarding rule should still be removed
+	if len(driver.VBoxManageCalls) != 1 {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+	if driver.VBoxManageCalls[0][0] != "modifyvm" {
+		t.Fatal("bad")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -21,20 +22,21 @@ type StepOutputDir struct {
 
 func (s *StepOutputDir) Run(state multistep.StateBag) multistep.StepAction {
 	ui := state.Get("ui").(packer.Ui)
+	path := common.LongPath(s.Path)
 
-	if _, err := os.Stat(s.Path); err == nil && s.Force {
+	if _, err := os.Stat(path); err == nil && s.Force {
 		ui.Say("Deleting previous output directory...")
-		os.RemoveAll(s.Path)
+		os.RemoveAll(path)
 	}
 
 	// Create the directory
-	if err := os.MkdirAll(s.Path, 0755); err != nil {
+	if err := os.MkdirAll(path, 0755); err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
 
 	// Make sure we can write in the directory
-	f, err := os.Create(filepath.Join(s.Path, "_packer_perm_check"))
+	f, err := os.Create(filepath.Join(path, "_packer_perm_check"))
 	if err != nil {
 		err = fmt.Errorf("Couldn't write to output directory: %s", err)
 		state.Put("error", err)
@@ -54,8 +56,9 @@ func (s *StepOutputDir) Cleanup(state multistep.StateBag) {
 		ui := state.Get("ui").(packer.Ui)
 
 		ui.Say("Deleting output directory...")
+		path := common.LongPath(s.Path)
 		for i := 0; i < 5; i++ {
-			err := os.RemoveAll(s.Path)
+			err := os.RemoveAll(path)
 			if err == nil {
 				break
 			}
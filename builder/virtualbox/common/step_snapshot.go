@@ -0,0 +1,49 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step takes a snapshot of the VM once it is provisioned, so that a
+// VM that is kept registered can be restored to that state. Nothing is
+// done if Name is empty.
+//
+// Uses:
+//   driver Driver
+//   ui     packer.Ui
+//   vmName string
+//
+// Produces:
+//   <nothing>
+type StepSnapshot struct {
+	Name string
+}
+
+func (s *StepSnapshot) Run(state multistep.StateBag) multistep.StepAction {
+	if s.Name == "" {
+		return multistep.ActionContinue
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+
+	ui.Say(fmt.Sprintf("Taking snapshot of virtual machine: %s", s.Name))
+	command := []string{
+		"snapshot", vmName, "take", s.Name,
+		"--description", "The provisioned state of the VM, taken by Packer",
+	}
+	if err := driver.VBoxManage(command...); err != nil {
+		err := fmt.Errorf("Error taking snapshot: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepSnapshot) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+)
+
+func TestStepSnapshot_impl(t *testing.T) {
+	var _ multistep.Step = new(StepSnapshot)
+}
+
+func TestStepSnapshot(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "foo")
+	step := &StepSnapshot{Name: "provisioned"}
+
+	driver := state.Get("driver").(*DriverMock)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	if len(driver.VBoxManageCalls) != 1 {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+	call := driver.VBoxManageCalls[0]
+	if call[0] != "snapshot" || call[1] != "foo" || call[2] != "take" || call[3] != "provisioned" {
+		t.Fatalf("bad: %#v", call)
+	}
+}
+
+func TestStepSnapshot_noName(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "foo")
+	step := new(StepSnapshot)
+
+	driver := state.Get("driver").(*DriverMock)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if len(driver.VBoxManageCalls) != 0 {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+}
+
+func TestStepSnapshot_error(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "foo")
+	step := &StepSnapshot{Name: "provisioned"}
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.VBoxManageErrs = []error{errors.New("locked")}
+
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
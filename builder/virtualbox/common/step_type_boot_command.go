@@ -9,6 +9,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 	"github.com/mitchellh/packer/template/interpolate"
 )
@@ -19,6 +20,19 @@ type bootCommandTemplateData struct {
 	HTTPIP   string
 	HTTPPort uint
 	Name     string
+
+	isoPath string
+}
+
+// ISOLabel returns the volume label of the ISO that the VM boots, for
+// kernel arguments such as inst.stage2=hd:LABEL={{ .ISOLabel }}. The ISO
+// is only read if the boot command uses it.
+func (d *bootCommandTemplateData) ISOLabel() (string, error) {
+	if d.isoPath == "" {
+		return "", fmt.Errorf("ISOLabel can't be used, as no ISO is attached")
+	}
+
+	return common.ISOLabel(d.isoPath)
 }
 
 // This step "types" the boot command into the VM over VNC.
@@ -26,6 +40,7 @@ type bootCommandTemplateData struct {
 // Uses:
 //   driver Driver
 //   http_port int
+//   iso_path string
 //   ui     packer.Ui
 //   vmName string
 //
@@ -43,10 +58,17 @@ func (s *StepTypeBootCommand) Run(state multistep.StateBag) multistep.StepAction
 	ui := state.Get("ui").(packer.Ui)
 	vmName := state.Get("vmName").(string)
 
+	// Only the VirtualBox ISO builder has an ISO
+	var isoPath string
+	if v, ok := state.GetOk("iso_path"); ok {
+		isoPath = v.(string)
+	}
+
 	s.Ctx.Data = &bootCommandTemplateData{
 		"10.0.2.2",
 		httpPort,
 		s.VMName,
+		isoPath,
 	}
 
 	ui.Say("Typing the boot command...")
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+func TestStepTypeBootCommand_impl(t *testing.T) {
+	var _ multistep.Step = new(StepTypeBootCommand)
+}
+
+func TestStepTypeBootCommand_userVariables(t *testing.T) {
+	state := testState(t)
+	state.Put("http_port", uint(8080))
+	state.Put("vmName", "foo")
+
+	step := &StepTypeBootCommand{
+		BootCommand: []string{"{{user `a`}}{{user `b`}}"},
+		VMName:      "foo",
+		Ctx: interpolate.Context{
+			UserVariables: map[string]string{"a": "a", "b": "b"},
+		},
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	driver := state.Get("driver").(*DriverMock)
+	var codes []string
+	for _, call := range driver.VBoxManageCalls {
+		codes = append(codes, call[3])
+	}
+
+	expected := append(scancodes("a"), scancodes("b")...)
+	if !reflect.DeepEqual(codes, expected) {
+		t.Fatalf("bad: %#v", codes)
+	}
+}
+
+func TestStepTypeBootCommand_isoLabel(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// An ISO with just the primary volume descriptor
+	iso := make([]byte, 16*2048+2048)
+	copy(iso[16*2048:], "\x01CD001")
+	copy(iso[16*2048+40:], "LABEL")
+	isoPath := filepath.Join(td, "test.iso")
+	if err := ioutil.WriteFile(isoPath, iso, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state := testState(t)
+	state.Put("http_port", uint(8080))
+	state.Put("iso_path", isoPath)
+	state.Put("vmName", "foo")
+
+	step := &StepTypeBootCommand{
+		BootCommand: []string{"{{ .ISOLabel }}"},
+		VMName:      "foo",
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+
+	driver := state.Get("driver").(*DriverMock)
+	var codes []string
+	for _, call := range driver.VBoxManageCalls {
+		codes = append(codes, call[3])
+	}
+	if !reflect.DeepEqual(codes, scancodes("LABEL")) {
+		t.Fatalf("bad: %#v", codes)
+	}
+
+	// Without an ISO, there is no label
+	state = testState(t)
+	state.Put("http_port", uint(8080))
+	state.Put("vmName", "foo")
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"net/url"
 	"strings"
 	"time"
 
@@ -38,7 +39,9 @@ type Config struct {
 	vboxcommon.VBoxVersionConfig    `mapstructure:",squash"`
 
 	BootCommand          []string `mapstructure:"boot_command"`
+	BootCommandFile      string   `mapstructure:"boot_command_file"`
 	DiskSize             uint     `mapstructure:"disk_size"`
+	GuestAdditionsMirror string   `mapstructure:"guest_additions_mirror"`
 	GuestAdditionsMode   string   `mapstructure:"guest_additions_mode"`
 	GuestAdditionsPath   string   `mapstructure:"guest_additions_path"`
 	GuestAdditionsURL    string   `mapstructure:"guest_additions_url"`
@@ -49,6 +52,11 @@ type Config struct {
 	ISOChecksumType      string   `mapstructure:"iso_checksum_type"`
 	ISOInterface         string   `mapstructure:"iso_interface"`
 	ISOUrls              []string `mapstructure:"iso_urls"`
+	KeepRegistered       bool     `mapstructure:"keep_registered"`
+	NestedVirt           bool     `mapstructure:"nested_virt"`
+	SkipExport           bool     `mapstructure:"skip_export"`
+	SnapshotName         string   `mapstructure:"snapshot_name"`
+	USBController        string   `mapstructure:"usb_controller"`
 	VMName               string   `mapstructure:"vm_name"`
 
 	RawSingleISOUrl string `mapstructure:"iso_url"`
@@ -88,6 +96,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, b.config.VBoxVersionConfig.Prepare(&b.config.ctx)...)
 	warnings := make([]string, 0)
 
+	b.config.BootCommand, err = common.BootCommandFromFile(b.config.BootCommand, b.config.BootCommandFile)
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	if b.config.DiskSize == 0 {
 		b.config.DiskSize = 40000
 	}
@@ -116,9 +129,19 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.VMName = fmt.Sprintf("packer-%s-{{timestamp}}", b.config.PackerBuildName)
 	}
 
-	if b.config.HardDriveInterface != "ide" && b.config.HardDriveInterface != "sata" && b.config.HardDriveInterface != "scsi" {
+	switch b.config.HardDriveInterface {
+	case "ide", "sata", "scsi", "pcie":
+	default:
 		errs = packer.MultiErrorAppend(
-			errs, errors.New("hard_drive_interface can only be ide, sata, or scsi"))
+			errs, errors.New("hard_drive_interface can only be ide, sata, scsi, or pcie"))
+	}
+
+	b.config.USBController = strings.ToLower(b.config.USBController)
+	switch b.config.USBController {
+	case "", "ohci", "ehci", "xhci":
+	default:
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("usb_controller can only be ohci, ehci, or xhci"))
 	}
 
 	if b.config.ISOChecksumType == "" {
@@ -184,10 +207,23 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			fmt.Errorf("guest_additions_mode is invalid. Must be one of: %v", validModes))
 	}
 
+	if b.config.SnapshotName != "" && !b.config.KeepRegistered {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("snapshot_name requires keep_registered, since the VM is deleted otherwise"))
+	}
+
 	if b.config.GuestAdditionsSHA256 != "" {
 		b.config.GuestAdditionsSHA256 = strings.ToLower(b.config.GuestAdditionsSHA256)
 	}
 
+	if b.config.GuestAdditionsMirror != "" {
+		b.config.GuestAdditionsMirror = strings.TrimRight(b.config.GuestAdditionsMirror, "/")
+		if u, err := url.Parse(b.config.GuestAdditionsMirror); err != nil || u.Scheme == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"guest_additions_mirror must be a URL, such as https://mirror.example.com/virtualbox"))
+		}
+	}
+
 	// Warnings
 	if b.config.ISOChecksumType == "none" {
 		warnings = append(warnings,
@@ -223,6 +259,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			GuestAdditionsMode:   b.config.GuestAdditionsMode,
 			GuestAdditionsURL:    b.config.GuestAdditionsURL,
 			GuestAdditionsSHA256: b.config.GuestAdditionsSHA256,
+			GuestAdditionsMirror: b.config.GuestAdditionsMirror,
 			Ctx:                  b.config.ctx,
 		},
 		&common.StepDownload{
@@ -238,7 +275,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Path:  b.config.OutputDir,
 		},
 		&common.StepCreateFloppy{
-			Files: b.config.FloppyFiles,
+			Files:   b.config.FloppyFiles,
+			WorkDir: b.config.PackerWorkDir,
 		},
 		&vboxcommon.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
@@ -267,6 +305,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			BootWait: b.config.BootWait,
 			Headless: b.config.Headless,
 		},
+		&common.StepReportGuestAddress{
+			SSHEndpoint: vboxcommon.SSHEndpoint,
+			GuestIP:     vboxcommon.GuestIP,
+		},
 		&vboxcommon.StepTypeBootCommand{
 			BootCommand: b.config.BootCommand,
 			VMName:      b.config.VMName,
@@ -296,11 +338,15 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Commands: b.config.VBoxManagePost,
 			Ctx:      b.config.ctx,
 		},
+		&vboxcommon.StepSnapshot{
+			Name: b.config.SnapshotName,
+		},
 		&vboxcommon.StepExport{
 			Format:         b.config.Format,
 			OutputDir:      b.config.OutputDir,
 			ExportOpts:     b.config.ExportOpts.ExportOpts,
 			SkipNatMapping: b.config.SSHSkipNatMapping,
+			SkipExport:     b.config.SkipExport,
 		},
 	}
 
@@ -319,7 +365,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 
 	b.runner.Run(state)
@@ -341,6 +389,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	return vboxcommon.NewArtifact(b.config.OutputDir)
 }
 
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (b *Builder) Cancel() {
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
@@ -3,6 +3,8 @@ package iso
 import (
 	"github.com/mitchellh/packer/builder/virtualbox/common"
 	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"testing"
 )
@@ -55,6 +57,41 @@ func TestBuilderPrepare_Defaults(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_BootCommandFile(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Write([]byte("# comment\n<esc><wait>\n<enter>\n"))
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	config["boot_command_file"] = tf.Name()
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"<esc><wait>", "<enter>"}
+	if !reflect.DeepEqual(b.config.BootCommand, expected) {
+		t.Fatalf("bad: %#v", b.config.BootCommand)
+	}
+
+	// Both can't be set
+	config["boot_command"] = []string{"<enter>"}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestBuilderPrepare_DiskSize(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -87,6 +124,30 @@ func TestBuilderPrepare_DiskSize(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_GuestAdditionsMirror(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["guest_additions_mirror"] = "https://mirror.example.com/virtualbox/"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.GuestAdditionsMirror != "https://mirror.example.com/virtualbox" {
+		t.Fatalf("bad: %s", b.config.GuestAdditionsMirror)
+	}
+
+	config["guest_additions_mirror"] = "mirror.example.com"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestBuilderPrepare_GuestAdditionsMode(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -259,6 +320,17 @@ func TestBuilderPrepare_HardDriveInterface(t *testing.T) {
 	if err != nil {
 		t.Fatalf("should not have error: %s", err)
 	}
+
+	// Test with NVMe
+	config["hard_drive_interface"] = "pcie"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
 }
 
 func TestBuilderPrepare_HTTPPort(t *testing.T) {
@@ -511,3 +583,58 @@ func TestBuilderPrepare_ISOUrl(t *testing.T) {
 		t.Fatalf("bad: %#v", b.config.ISOUrls)
 	}
 }
+
+func TestBuilderPrepare_USBController(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with a bad
+	config["usb_controller"] = "fake"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a good
+	config["usb_controller"] = "XHCI"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.USBController != "xhci" {
+		t.Fatalf("bad: %s", b.config.USBController)
+	}
+}
+
+func TestBuilderPrepare_SnapshotName(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// The VM must be kept to have a snapshot
+	config["snapshot_name"] = "provisioned"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["keep_registered"] = true
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
@@ -72,6 +72,15 @@ func (s *stepCreateDisk) Run(state multistep.StateBag) multistep.StepAction {
 		}
 	}
 
+	if config.HardDriveInterface == "pcie" {
+		if err := driver.CreateNVMeController(vmName, "NVMe Controller"); err != nil {
+			err := fmt.Errorf("Error creating disk controller: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	// Attach the disk to the controller
 	controllerName := "IDE Controller"
 	if config.HardDriveInterface == "sata" {
@@ -82,6 +91,10 @@ func (s *stepCreateDisk) Run(state multistep.StateBag) multistep.StepAction {
 		controllerName = "SCSI Controller"
 	}
 
+	if config.HardDriveInterface == "pcie" {
+		controllerName = "NVMe Controller"
+	}
+
 	command = []string{
 		"storageattach", vmName,
 		"--storagectl", controllerName,
@@ -35,6 +35,22 @@ func (s *stepCreateVM) Run(state multistep.StateBag) multistep.StepAction {
 	commands[2] = []string{"modifyvm", name, "--cpus", "1"}
 	commands[3] = []string{"modifyvm", name, "--memory", "512"}
 
+	// USB controllers build on each other, so the faster ones are
+	// enabled together with the slower ones.
+	switch config.USBController {
+	case "ohci":
+		commands = append(commands, []string{"modifyvm", name, "--usb", "on"})
+	case "ehci":
+		commands = append(commands, []string{
+			"modifyvm", name, "--usb", "on", "--usbehci", "on"})
+	case "xhci":
+		commands = append(commands, []string{"modifyvm", name, "--usbxhci", "on"})
+	}
+
+	if config.NestedVirt {
+		commands = append(commands, []string{"modifyvm", name, "--nested-hw-virt", "on"})
+	}
+
 	ui.Say("Creating virtual machine...")
 	for _, command := range commands {
 		err := driver.VBoxManage(command...)
@@ -62,9 +78,17 @@ func (s *stepCreateVM) Cleanup(state multistep.StateBag) {
 		return
 	}
 
+	config := state.Get("config").(*Config)
 	driver := state.Get("driver").(vboxcommon.Driver)
 	ui := state.Get("ui").(packer.Ui)
 
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if config.KeepRegistered && !cancelled && !halted {
+		ui.Say("Keeping virtual machine registered (keep_registered = true)")
+		return
+	}
+
 	ui.Say("Unregistering and deleting virtual machine...")
 	var err error = nil
 	for i := 0; i < 5; i++ {
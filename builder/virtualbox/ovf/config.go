@@ -2,6 +2,7 @@ package ovf
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 
@@ -27,7 +28,11 @@ type Config struct {
 	vboxcommon.VBoxVersionConfig    `mapstructure:",squash"`
 
 	BootCommand          []string `mapstructure:"boot_command"`
+	BootCommandFile      string   `mapstructure:"boot_command_file"`
+	Checksum             string   `mapstructure:"checksum"`
+	ChecksumType         string   `mapstructure:"checksum_type"`
 	SourcePath           string   `mapstructure:"source_path"`
+	GuestAdditionsMirror string   `mapstructure:"guest_additions_mirror"`
 	GuestAdditionsMode   string   `mapstructure:"guest_additions_mode"`
 	GuestAdditionsPath   string   `mapstructure:"guest_additions_path"`
 	GuestAdditionsURL    string   `mapstructure:"guest_additions_url"`
@@ -35,6 +40,9 @@ type Config struct {
 	VMName               string   `mapstructure:"vm_name"`
 	ImportOpts           string   `mapstructure:"import_opts"`
 	ImportFlags          []string `mapstructure:"import_flags"`
+	KeepRegistered       bool     `mapstructure:"keep_registered"`
+	SkipExport           bool     `mapstructure:"skip_export"`
+	SnapshotName         string   `mapstructure:"snapshot_name"`
 
 	ctx interpolate.Context
 }
@@ -82,12 +90,51 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs = packer.MultiErrorAppend(errs, c.VBoxManagePostConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VBoxVersionConfig.Prepare(&c.ctx)...)
 
+	c.BootCommand, err = common.BootCommandFromFile(c.BootCommand, c.BootCommandFile)
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
+	remoteSource := false
 	if c.SourcePath == "" {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is required"))
 	} else {
-		if _, err := os.Stat(c.SourcePath); err != nil {
+		c.SourcePath, err = common.DownloadableURL(c.SourcePath)
+		if err != nil {
 			errs = packer.MultiErrorAppend(errs,
 				fmt.Errorf("source_path is invalid: %s", err))
+		} else if u, _ := url.Parse(c.SourcePath); u.Scheme == "file" {
+			// Local files are imported in place, so they must exist now.
+			if _, err := os.Stat(common.LongPath(common.FileURLPath(u))); err != nil {
+				errs = packer.MultiErrorAppend(errs,
+					fmt.Errorf("source_path is invalid: %s", err))
+			}
+		} else {
+			remoteSource = true
+		}
+	}
+
+	if c.ChecksumType == "" {
+		if c.Checksum != "" {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("checksum_type must be specified with checksum"))
+		}
+
+		c.ChecksumType = "none"
+	}
+
+	c.ChecksumType = strings.ToLower(c.ChecksumType)
+	if c.ChecksumType != "none" {
+		if c.Checksum == "" {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("checksum is required when checksum_type is %s", c.ChecksumType))
+		} else {
+			c.Checksum = strings.ToLower(c.Checksum)
+		}
+
+		if h := common.HashForType(c.ChecksumType); h == nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Unsupported checksum type: %s", c.ChecksumType))
 		}
 	}
 
@@ -110,12 +157,30 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 			fmt.Errorf("guest_additions_mode is invalid. Must be one of: %v", validModes))
 	}
 
+	if c.SnapshotName != "" && !c.KeepRegistered {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("snapshot_name requires keep_registered, since the VM is deleted otherwise"))
+	}
+
 	if c.GuestAdditionsSHA256 != "" {
 		c.GuestAdditionsSHA256 = strings.ToLower(c.GuestAdditionsSHA256)
 	}
 
+	if c.GuestAdditionsMirror != "" {
+		c.GuestAdditionsMirror = strings.TrimRight(c.GuestAdditionsMirror, "/")
+		if u, err := url.Parse(c.GuestAdditionsMirror); err != nil || u.Scheme == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"guest_additions_mirror must be a URL, such as https://mirror.example.com/virtualbox"))
+		}
+	}
+
 	// Warnings
 	var warnings []string
+	if remoteSource && c.ChecksumType == "none" {
+		warnings = append(warnings,
+			"No checksum was specified for a remote source_path. Without a checksum,\n"+
+				"Packer can't verify the download and will fetch it again on every build.")
+	}
 	if c.ShutdownCommand == "" {
 		warnings = append(warnings,
 			"A shutdown_command was not specified. Without a shutdown command, Packer\n"+
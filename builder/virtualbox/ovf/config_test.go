@@ -83,3 +83,83 @@ func TestNewConfig_shutdown_timeout(t *testing.T) {
 	_, warns, errs = NewConfig(c)
 	testConfigOk(t, warns, errs)
 }
+
+func TestNewConfig_sourcePathURL(t *testing.T) {
+	c := testConfig(t)
+	c["source_path"] = "http://example.com/source.ova"
+	c["checksum"] = "ABC123"
+	c["checksum_type"] = "MD5"
+	config, warns, errs := NewConfig(c)
+	testConfigOk(t, warns, errs)
+
+	if config.Checksum != "abc123" {
+		t.Fatalf("bad: %s", config.Checksum)
+	}
+	if config.ChecksumType != "md5" {
+		t.Fatalf("bad: %s", config.ChecksumType)
+	}
+
+	// Without a checksum, it should warn
+	delete(c, "checksum")
+	delete(c, "checksum_type")
+	_, warns, errs = NewConfig(c)
+	if len(warns) == 0 {
+		t.Fatal("should have warnings")
+	}
+	if errs != nil {
+		t.Fatalf("bad: %s", errs)
+	}
+}
+
+func TestNewConfig_checksum(t *testing.T) {
+	tf := getTempFile(t)
+	defer os.Remove(tf.Name())
+
+	// Defaults to none
+	c := testConfig(t)
+	c["source_path"] = tf.Name()
+	config, warns, errs := NewConfig(c)
+	testConfigOk(t, warns, errs)
+	if config.ChecksumType != "none" {
+		t.Fatalf("bad: %s", config.ChecksumType)
+	}
+
+	// Checksum without a type
+	c["checksum"] = "abc123"
+	_, warns, errs = NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	// Type without a checksum
+	delete(c, "checksum")
+	c["checksum_type"] = "sha256"
+	_, warns, errs = NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	// Unsupported type
+	c["checksum"] = "abc123"
+	c["checksum_type"] = "crc32"
+	_, warns, errs = NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	// Good
+	c["checksum_type"] = "sha256"
+	_, warns, errs = NewConfig(c)
+	testConfigOk(t, warns, errs)
+}
+
+func TestNewConfig_snapshotName(t *testing.T) {
+	tf := getTempFile(t)
+	defer os.Remove(tf.Name())
+
+	// Bad
+	c := testConfig(t)
+	c["source_path"] = tf.Name()
+	c["snapshot_name"] = "provisioned"
+	_, warns, errs := NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	// Good
+	c["keep_registered"] = true
+	_, warns, errs = NewConfig(c)
+	testConfigOk(t, warns, errs)
+}
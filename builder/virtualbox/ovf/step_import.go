@@ -8,10 +8,13 @@ import (
 )
 
 // This step imports an OVF VM into VirtualBox.
+//
+// Uses:
+//   source_path string - The local path to the OVF or OVA file
 type StepImport struct {
-	Name        string
-	SourcePath  string
-	ImportFlags []string
+	Name           string
+	ImportFlags    []string
+	KeepRegistered bool
 
 	vmName string
 }
@@ -19,9 +22,10 @@ type StepImport struct {
 func (s *StepImport) Run(state multistep.StateBag) multistep.StepAction {
 	driver := state.Get("driver").(vboxcommon.Driver)
 	ui := state.Get("ui").(packer.Ui)
+	sourcePath := state.Get("source_path").(string)
 
-	ui.Say(fmt.Sprintf("Importing VM: %s", s.SourcePath))
-	if err := driver.Import(s.Name, s.SourcePath, s.ImportFlags); err != nil {
+	ui.Say(fmt.Sprintf("Importing VM: %s", sourcePath))
+	if err := driver.Import(s.Name, sourcePath, s.ImportFlags); err != nil {
 		err := fmt.Errorf("Error importing VM: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -41,6 +45,13 @@ func (s *StepImport) Cleanup(state multistep.StateBag) {
 	driver := state.Get("driver").(vboxcommon.Driver)
 	ui := state.Get("ui").(packer.Ui)
 
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if s.KeepRegistered && !cancelled && !halted {
+		ui.Say("Keeping virtual machine registered (keep_registered = true)")
+		return
+	}
+
 	ui.Say("Unregistering and deleting imported VM...")
 	if err := driver.Delete(s.vmName); err != nil {
 		ui.Error(fmt.Sprintf("Error deleting VM: %s", err))
@@ -14,7 +14,7 @@ func TestStepImport(t *testing.T) {
 	state := testState(t)
 	step := new(StepImport)
 	step.Name = "bar"
-	step.SourcePath = "foo"
+	state.Put("source_path", "foo")
 
 	driver := state.Get("driver").(*vboxcommon.DriverMock)
 
@@ -33,7 +33,7 @@ func TestStepImport(t *testing.T) {
 	if driver.ImportName != step.Name {
 		t.Fatalf("bad: %#v", driver.ImportName)
 	}
-	if driver.ImportPath != step.SourcePath {
+	if driver.ImportPath != "foo" {
 		t.Fatalf("bad: %#v", driver.ImportPath)
 	}
 
@@ -53,3 +53,26 @@ func TestStepImport(t *testing.T) {
 		t.Fatalf("bad: %#v", driver.DeleteName)
 	}
 }
+
+func TestStepImport_keepRegistered(t *testing.T) {
+	state := testState(t)
+	step := &StepImport{Name: "bar", KeepRegistered: true}
+	state.Put("source_path", "foo")
+
+	driver := state.Get("driver").(*vboxcommon.DriverMock)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	step.Cleanup(state)
+	if driver.DeleteCalled {
+		t.Fatal("delete should not be called")
+	}
+
+	state.Put(multistep.StateCancelled, true)
+	step.Cleanup(state)
+	if !driver.DeleteCalled {
+		t.Fatal("delete should be called")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -57,6 +58,12 @@ func (a *localArtifact) String() string {
 }
 
 func (a *localArtifact) State(name string) interface{} {
+	if name == common.ArtifactStateImageFiles {
+		if images := common.DiskImages(a.f); len(images) > 0 {
+			return images
+		}
+	}
+
 	return nil
 }
 
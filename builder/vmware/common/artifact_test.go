@@ -4,8 +4,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -41,3 +43,28 @@ func TestNewLocalArtifact(t *testing.T) {
 		t.Fatalf("should length 1: %d", len(a.Files()))
 	}
 }
+
+func TestLocalArtifact_imageFiles(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	for _, name := range []string{"disk-1.vmdk", "disk.vmdk", "packer.vmx"} {
+		err = ioutil.WriteFile(filepath.Join(td, name), []byte("foo"), 0644)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	a, err := NewLocalArtifact(td)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{filepath.Join(td, "disk.vmdk"), filepath.Join(td, "disk-1.vmdk")}
+	if files := common.ArtifactImageFiles(a); !reflect.DeepEqual(files, expected) {
+		t.Fatalf("bad: %#v", files)
+	}
+}
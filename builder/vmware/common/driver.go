@@ -17,11 +17,14 @@ import (
 type Driver interface {
 	// Clone clones the VMX and the disk to the destination path. The
 	// destination is a path to the VMX file. The disk will be copied
-	// to that same directory.
-	Clone(dst string, src string) error
-
-	// CompactDisk compacts a virtual disk.
-	CompactDisk(string) error
+	// to that same directory, unless the clone is linked, in which case
+	// the new disk only records the changes from the disk of the source.
+	Clone(dst string, src string, linked bool) error
+
+	// CompactDisk compacts a virtual disk. If defrag is true, the disk is
+	// defragmented before it is shrunk, which takes longer but usually
+	// results in a smaller disk.
+	CompactDisk(path string, defrag bool) error
 
 	// CreateDisk creates a virtual disk with the given size.
 	CreateDisk(string, string, string) error
@@ -21,14 +21,16 @@ type Fusion5Driver struct {
 	SSHConfig *SSHConfig
 }
 
-func (d *Fusion5Driver) Clone(dst, src string) error {
+func (d *Fusion5Driver) Clone(dst, src string, linked bool) error {
 	return errors.New("Cloning is not supported with Fusion 5. Please use Fusion 6+.")
 }
 
-func (d *Fusion5Driver) CompactDisk(diskPath string) error {
-	defragCmd := exec.Command(d.vdiskManagerPath(), "-d", diskPath)
-	if _, _, err := runAndLog(defragCmd); err != nil {
-		return err
+func (d *Fusion5Driver) CompactDisk(diskPath string, defrag bool) error {
+	if defrag {
+		defragCmd := exec.Command(d.vdiskManagerPath(), "-d", diskPath)
+		if _, _, err := runAndLog(defragCmd); err != nil {
+			return err
+		}
 	}
 
 	shrinkCmd := exec.Command(d.vdiskManagerPath(), "-k", diskPath)
@@ -18,11 +18,16 @@ type Fusion6Driver struct {
 	Fusion5Driver
 }
 
-func (d *Fusion6Driver) Clone(dst, src string) error {
+func (d *Fusion6Driver) Clone(dst, src string, linked bool) error {
+	cloneType := "full"
+	if linked {
+		cloneType = "linked"
+	}
+
 	cmd := exec.Command(d.vmrunPath(),
 		"-T", "fusion",
 		"clone", src, dst,
-		"full")
+		cloneType)
 	if _, _, err := runAndLog(cmd); err != nil {
 		if strings.Contains(err.Error(), "parameters was invalid") {
 			return fmt.Errorf(
@@ -12,10 +12,12 @@ type DriverMock struct {
 	CloneCalled bool
 	CloneDst    string
 	CloneSrc    string
+	CloneLinked bool
 	CloneErr    error
 
 	CompactDiskCalled bool
 	CompactDiskPath   string
+	CompactDiskDefrag bool
 	CompactDiskErr    error
 
 	CreateDiskCalled bool
@@ -62,16 +64,18 @@ type DriverMock struct {
 	VerifyErr    error
 }
 
-func (d *DriverMock) Clone(dst string, src string) error {
+func (d *DriverMock) Clone(dst string, src string, linked bool) error {
 	d.CloneCalled = true
 	d.CloneDst = dst
 	d.CloneSrc = src
+	d.CloneLinked = linked
 	return d.CloneErr
 }
 
-func (d *DriverMock) CompactDisk(path string) error {
+func (d *DriverMock) CompactDisk(path string, defrag bool) error {
 	d.CompactDiskCalled = true
 	d.CompactDiskPath = path
+	d.CompactDiskDefrag = defrag
 	return d.CompactDiskErr
 }
 
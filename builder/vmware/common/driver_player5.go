@@ -23,18 +23,20 @@ type Player5Driver struct {
 	SSHConfig *SSHConfig
 }
 
-func (d *Player5Driver) Clone(dst, src string) error {
+func (d *Player5Driver) Clone(dst, src string, linked bool) error {
 	return errors.New("Cloning is not supported with VMWare Player version 5. Please use VMWare Player version 6, or greater.")
 }
 
-func (d *Player5Driver) CompactDisk(diskPath string) error {
+func (d *Player5Driver) CompactDisk(diskPath string, defrag bool) error {
 	if d.QemuImgPath != "" {
 		return d.qemuCompactDisk(diskPath)
 	}
 
-	defragCmd := exec.Command(d.VdiskManagerPath, "-d", diskPath)
-	if _, _, err := runAndLog(defragCmd); err != nil {
-		return err
+	if defrag {
+		defragCmd := exec.Command(d.VdiskManagerPath, "-d", diskPath)
+		if _, _, err := runAndLog(defragCmd); err != nil {
+			return err
+		}
 	}
 
 	shrinkCmd := exec.Command(d.VdiskManagerPath, "-k", diskPath)
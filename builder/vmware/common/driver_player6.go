@@ -13,13 +13,18 @@ type Player6Driver struct {
 	Player5Driver
 }
 
-func (d *Player6Driver) Clone(dst, src string) error {
+func (d *Player6Driver) Clone(dst, src string, linked bool) error {
+	cloneType := "full"
+	if linked {
+		cloneType = "linked"
+	}
+
 	// TODO(rasa) check if running player+, not just player
 
 	cmd := exec.Command(d.Player5Driver.VmrunPath,
 		"-T", "ws",
 		"clone", src, dst,
-		"full")
+		cloneType)
 
 	if _, _, err := runAndLog(cmd); err != nil {
 		return err
@@ -13,11 +13,16 @@ type Workstation10Driver struct {
 	Workstation9Driver
 }
 
-func (d *Workstation10Driver) Clone(dst, src string) error {
+func (d *Workstation10Driver) Clone(dst, src string, linked bool) error {
+	cloneType := "full"
+	if linked {
+		cloneType = "linked"
+	}
+
 	cmd := exec.Command(d.Workstation9Driver.VmrunPath,
 		"-T", "ws",
 		"clone", src, dst,
-		"full")
+		cloneType)
 
 	if _, _, err := runAndLog(cmd); err != nil {
 		return err
@@ -22,14 +22,16 @@ type Workstation9Driver struct {
 	SSHConfig *SSHConfig
 }
 
-func (d *Workstation9Driver) Clone(dst, src string) error {
+func (d *Workstation9Driver) Clone(dst, src string, linked bool) error {
 	return errors.New("Cloning is not supported with VMware WS version 9. Please use VMware WS version 10, or greater.")
 }
 
-func (d *Workstation9Driver) CompactDisk(diskPath string) error {
-	defragCmd := exec.Command(d.VdiskManagerPath, "-d", diskPath)
-	if _, _, err := runAndLog(defragCmd); err != nil {
-		return err
+func (d *Workstation9Driver) CompactDisk(diskPath string, defrag bool) error {
+	if defrag {
+		defragCmd := exec.Command(d.VdiskManagerPath, "-d", diskPath)
+		if _, _, err := runAndLog(defragCmd); err != nil {
+			return err
+		}
 	}
 
 	shrinkCmd := exec.Command(d.VdiskManagerPath, "-k", diskPath)
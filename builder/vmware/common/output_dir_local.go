@@ -3,6 +3,8 @@ package common
 import (
 	"os"
 	"path/filepath"
+
+	"github.com/mitchellh/packer/common"
 )
 
 // LocalOutputDir is an OutputDir implementation where the directory
@@ -12,7 +14,7 @@ type LocalOutputDir struct {
 }
 
 func (d *LocalOutputDir) DirExists() (bool, error) {
-	_, err := os.Stat(d.dir)
+	_, err := os.Stat(common.LongPath(d.dir))
 	return err == nil, nil
 }
 
@@ -33,15 +35,15 @@ func (d *LocalOutputDir) ListFiles() ([]string, error) {
 }
 
 func (d *LocalOutputDir) MkdirAll() error {
-	return os.MkdirAll(d.dir, 0755)
+	return os.MkdirAll(common.LongPath(d.dir), 0755)
 }
 
 func (d *LocalOutputDir) Remove(path string) error {
-	return os.Remove(path)
+	return os.Remove(common.LongPath(path))
 }
 
 func (d *LocalOutputDir) RemoveAll() error {
-	return os.RemoveAll(d.dir)
+	return os.RemoveAll(common.LongPath(d.dir))
 }
 
 func (d *LocalOutputDir) SetOutputDir(path string) {
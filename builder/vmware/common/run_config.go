@@ -3,8 +3,10 @@ package common
 import (
 	"errors"
 	"fmt"
+	"net"
 	"time"
 
+	"github.com/mitchellh/packer/helper/config"
 	"github.com/mitchellh/packer/template/interpolate"
 )
 
@@ -16,8 +18,10 @@ type RunConfig struct {
 	HTTPPortMin uint   `mapstructure:"http_port_min"`
 	HTTPPortMax uint   `mapstructure:"http_port_max"`
 
-	VNCPortMin uint `mapstructure:"vnc_port_min"`
-	VNCPortMax uint `mapstructure:"vnc_port_max"`
+	VNCBindAddress string `mapstructure:"vnc_bind_address"`
+	VNCPassword    string `mapstructure:"vnc_password"`
+	VNCPortMin     uint   `mapstructure:"vnc_port_min"`
+	VNCPortMax     uint   `mapstructure:"vnc_port_max"`
 
 	BootWait time.Duration ``
 }
@@ -46,10 +50,9 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 	var errs []error
 	var err error
 	if c.RawBootWait != "" {
-		c.BootWait, err = time.ParseDuration(c.RawBootWait)
+		c.BootWait, err = config.ParseDuration("boot_wait", c.RawBootWait)
 		if err != nil {
-			errs = append(
-				errs, fmt.Errorf("Failed parsing boot_wait: %s", err))
+			errs = append(errs, err)
 		}
 	}
 
@@ -63,5 +66,14 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 			errs, fmt.Errorf("vnc_port_min must be less than vnc_port_max"))
 	}
 
+	if c.VNCBindAddress != "" && net.ParseIP(c.VNCBindAddress) == nil {
+		errs = append(errs, errors.New("vnc_bind_address must be an IP address"))
+	}
+
+	// VNC authentication ignores anything after eight characters
+	if len(c.VNCPassword) > 8 {
+		errs = append(errs, errors.New("vnc_password can be at most 8 characters"))
+	}
+
 	return errs
 }
@@ -34,3 +34,24 @@ func TestRunConfigPrepare(t *testing.T) {
 		t.Fatalf("bad: %#v", errs)
 	}
 }
+
+func TestRunConfigPrepare_VNC(t *testing.T) {
+	c := new(RunConfig)
+	c.VNCBindAddress = "127.0.0.1"
+	c.VNCPassword = "secret"
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	c = new(RunConfig)
+	c.VNCBindAddress = "localhost"
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) == 0 {
+		t.Fatal("should error")
+	}
+
+	c = new(RunConfig)
+	c.VNCPassword = "toolongpassword"
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) == 0 {
+		t.Fatal("should error")
+	}
+}
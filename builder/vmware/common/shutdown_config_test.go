@@ -24,17 +24,19 @@ func TestShutdownConfigPrepare_ShutdownTimeout(t *testing.T) {
 	var c *ShutdownConfig
 	var errs []error
 
-	// Test with a bad value
+	// Test with the default
 	c = testShutdownConfig()
-	c.RawShutdownTimeout = "this is not good"
 	errs = c.Prepare(testConfigTemplate(t))
-	if len(errs) == 0 {
-		t.Fatalf("should have error")
+	if len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.ShutdownTimeout != 5*time.Minute {
+		t.Fatalf("bad: %s", c.ShutdownTimeout)
 	}
 
 	// Test with a good one
 	c = testShutdownConfig()
-	c.RawShutdownTimeout = "5s"
+	c.ShutdownTimeout = 5 * time.Second
 	errs = c.Prepare(testConfigTemplate(t))
 	if len(errs) > 0 {
 		t.Fatalf("err: %#v", errs)
@@ -42,6 +42,15 @@ func (s StepCleanVMX) Run(state multistep.StateBag) multistep.StepAction {
 	}
 	vmxData["floppy0.present"] = "FALSE"
 
+	// Delete the VNC server of the build, which would otherwise keep
+	// listening with its password in every VM made from the artifact
+	for k, _ := range vmxData {
+		if strings.HasPrefix(k, "remotedisplay.vnc.") {
+			log.Printf("Deleting key: %s", k)
+			delete(vmxData, k)
+		}
+	}
+
 	devRe := regexp.MustCompile(`^ide\d:\d\.`)
 	for k, v := range vmxData {
 		ide := devRe.FindString(k)
@@ -128,6 +128,57 @@ func TestStepCleanVMX_isoPath(t *testing.T) {
 	}
 }
 
+func TestStepCleanVMX_vnc(t *testing.T) {
+	state := testState(t)
+	step := new(StepCleanVMX)
+
+	vmxPath := testVMXFile(t)
+	defer os.Remove(vmxPath)
+	if err := ioutil.WriteFile(vmxPath, []byte(testVMXVNC), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state.Put("vmx_path", vmxPath)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test the resulting data
+	vmxContents, err := ioutil.ReadFile(vmxPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	vmxData := ParseVMX(string(vmxContents))
+
+	cases := []struct {
+		Key   string
+		Value string
+	}{
+		{"remotedisplay.vnc.enabled", ""},
+		{"remotedisplay.vnc.port", ""},
+		{"remotedisplay.vnc.ip", ""},
+		{"remotedisplay.vnc.password", ""},
+		{"foo", "bar"},
+	}
+
+	for _, tc := range cases {
+		if tc.Value == "" {
+			if _, ok := vmxData[tc.Key]; ok {
+				t.Fatalf("should not have key: %s", tc.Key)
+			}
+		} else {
+			if vmxData[tc.Key] != tc.Value {
+				t.Fatalf("bad: %s %#v", tc.Key, vmxData[tc.Key])
+			}
+		}
+	}
+}
+
 const testVMXFloppyPath = `
 floppy0.present = "TRUE"
 floppy0.filetype = "file"
@@ -139,3 +190,11 @@ ide0:0.filename = "foo"
 ide0:1.filename = "bar"
 foo = "bar"
 `
+
+const testVMXVNC = `
+remotedisplay.vnc.enabled = "TRUE"
+remotedisplay.vnc.port = "5900"
+remotedisplay.vnc.ip = "127.0.0.1"
+remotedisplay.vnc.password = "secret"
+foo = "bar"
+`
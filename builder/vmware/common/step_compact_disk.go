@@ -8,7 +8,8 @@ import (
 )
 
 // This step compacts the virtual disk for the VM unless the "skip_compaction"
-// boolean is true.
+// boolean is true. The disk is defragmented first unless "skip_defrag" is
+// true.
 //
 // Uses:
 //   driver Driver
@@ -18,7 +19,8 @@ import (
 // Produces:
 //   <nothing>
 type StepCompactDisk struct {
-	Skip bool
+	Skip       bool
+	SkipDefrag bool
 }
 
 func (s StepCompactDisk) Run(state multistep.StateBag) multistep.StepAction {
@@ -32,7 +34,7 @@ func (s StepCompactDisk) Run(state multistep.StateBag) multistep.StepAction {
 	}
 
 	ui.Say("Compacting the disk image")
-	if err := driver.CompactDisk(full_disk_path); err != nil {
+	if err := driver.CompactDisk(full_disk_path, !s.SkipDefrag); err != nil {
 		state.Put("error", fmt.Errorf("Error compacting disk: %s", err))
 		return multistep.ActionHalt
 	}
@@ -41,7 +43,7 @@ func (s StepCompactDisk) Run(state multistep.StateBag) multistep.StepAction {
 		if moreDisks := state.Get("additional_disk_paths").([]string); len(moreDisks) > 0 {
 			for i, path := range moreDisks {
 				ui.Say(fmt.Sprintf("Compacting additional disk image %d",i+1))
-				if err := driver.CompactDisk(path); err != nil {
+				if err := driver.CompactDisk(path, !s.SkipDefrag); err != nil {
 					state.Put("error", fmt.Errorf("Error compacting additional disk %d: %s", i+1, err))
 					return multistep.ActionHalt
 				}
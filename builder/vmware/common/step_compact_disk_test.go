@@ -33,6 +33,35 @@ func TestStepCompactDisk(t *testing.T) {
 	if driver.CompactDiskPath != "foo" {
 		t.Fatal("should call with right path")
 	}
+	if !driver.CompactDiskDefrag {
+		t.Fatal("should defrag")
+	}
+}
+
+func TestStepCompactDisk_skipDefrag(t *testing.T) {
+	state := testState(t)
+	step := new(StepCompactDisk)
+	step.SkipDefrag = true
+
+	state.Put("full_disk_path", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test the driver
+	if !driver.CompactDiskCalled {
+		t.Fatal("should've called")
+	}
+	if driver.CompactDiskDefrag {
+		t.Fatal("should not defrag")
+	}
 }
 
 func TestStepCompactDisk_skip(t *testing.T) {
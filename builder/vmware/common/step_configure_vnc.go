@@ -21,8 +21,10 @@ import (
 // Produces:
 //   vnc_port uint - The port that VNC is configured to listen on.
 type StepConfigureVNC struct {
-	VNCPortMin uint
-	VNCPortMax uint
+	VNCBindAddress string
+	VNCPassword    string
+	VNCPortMin     uint
+	VNCPortMax     uint
 }
 
 type VNCAddressFinder interface {
@@ -92,6 +94,19 @@ func (s *StepConfigureVNC) Run(state multistep.StateBag) multistep.StepAction {
 	vmxData := ParseVMX(string(vmxBytes))
 	vmxData["remotedisplay.vnc.enabled"] = "TRUE"
 	vmxData["remotedisplay.vnc.port"] = fmt.Sprintf("%d", vncPort)
+	if s.VNCBindAddress != "" {
+		vmxData["remotedisplay.vnc.ip"] = s.VNCBindAddress
+
+		// A local VM is only reachable on the address it listens on
+		_, remote := driver.(VNCAddressFinder)
+		ip := net.ParseIP(s.VNCBindAddress)
+		if !remote && ip != nil && !ip.IsUnspecified() {
+			vncIp = s.VNCBindAddress
+		}
+	}
+	if s.VNCPassword != "" {
+		vmxData["remotedisplay.vnc.password"] = s.VNCPassword
+	}
 
 	if err := WriteVMX(vmxPath, vmxData); err != nil {
 		err := fmt.Errorf("Error writing VMX data: %s", err)
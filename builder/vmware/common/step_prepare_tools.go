@@ -5,11 +5,15 @@ import (
 	"os"
 
 	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/common"
 )
 
 type StepPrepareTools struct {
 	RemoteType        string
 	ToolsUploadFlavor string
+	ToolsMirror       string
+	ToolsChecksum     string
+	ToolsChecksumType string
 }
 
 func (c *StepPrepareTools) Run(state multistep.StateBag) multistep.StepAction {
@@ -23,6 +27,13 @@ func (c *StepPrepareTools) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionContinue
 	}
 
+	// The tools are downloaded from the mirror, which StepDownload also
+	// verifies the checksum of
+	if c.ToolsMirror != "" {
+		return c.download(state,
+			fmt.Sprintf("%s/%s.iso", c.ToolsMirror, c.ToolsUploadFlavor))
+	}
+
 	path := driver.ToolsIsoPath(c.ToolsUploadFlavor)
 	if _, err := os.Stat(path); err != nil {
 		state.Put("error", fmt.Errorf(
@@ -33,8 +44,37 @@ func (c *StepPrepareTools) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
+	if c.ToolsChecksum != "" {
+		url, err := common.DownloadableURL(path)
+		if err != nil {
+			state.Put("error", fmt.Errorf("Error preparing VMware tools path: %s", err))
+			return multistep.ActionHalt
+		}
+
+		return c.download(state, url)
+	}
+
 	state.Put("tools_upload_source", path)
 	return multistep.ActionContinue
 }
 
 func (c *StepPrepareTools) Cleanup(multistep.StateBag) {}
+
+// download downloads the tools from url, or only verifies their checksum
+// if it is a local file.
+func (c *StepPrepareTools) download(state multistep.StateBag, url string) multistep.StepAction {
+	checksumType := c.ToolsChecksumType
+	if checksumType == "" {
+		checksumType = "none"
+	}
+
+	downStep := &common.StepDownload{
+		Checksum:     c.ToolsChecksum,
+		ChecksumType: checksumType,
+		Description:  "VMware tools",
+		ResultKey:    "tools_upload_source",
+		Url:          []string{url},
+	}
+
+	return downStep.Run(state)
+}
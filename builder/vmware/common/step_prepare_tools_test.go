@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
 )
 
 func TestStepPrepareTools_impl(t *testing.T) {
@@ -113,3 +114,51 @@ func TestStepPrepareTools_nonExist(t *testing.T) {
 		t.Fatal("should NOT have tools_upload_source")
 	}
 }
+
+func TestStepPrepareTools_checksum(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Write([]byte("tools"))
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	cacheDir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	state := testState(t)
+	state.Put("cache", &packer.FileCache{CacheDir: cacheDir})
+	step := &StepPrepareTools{
+		ToolsUploadFlavor: "foo",
+		ToolsChecksum:     "b3c2b5ea8c8ce4cbc3dba2dbdc0e8b2e5d0f0ba2ac1d2b3a5fd43fe0d9b8e4b7",
+		ToolsChecksumType: "sha256",
+	}
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.ToolsIsoPathResult = tf.Name()
+
+	// The checksum of the local tools doesn't match
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("tools_upload_source"); ok {
+		t.Fatal("should NOT have tools_upload_source")
+	}
+
+	// sha1 of "tools"
+	step.ToolsChecksum = "0284c6ac58cb47bb52e427007beee58e0132bf71"
+	step.ToolsChecksumType = "sha1"
+	state = testState(t)
+	state.Put("cache", &packer.FileCache{CacheDir: cacheDir})
+	state.Get("driver").(*DriverMock).ToolsIsoPathResult = tf.Name()
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v %s", action, state.Get("error"))
+	}
+	if path := state.Get("tools_upload_source"); path != tf.Name() {
+		t.Fatalf("bad: %#v", path)
+	}
+}
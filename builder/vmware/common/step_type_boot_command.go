@@ -36,6 +36,7 @@ type bootCommandTemplateData struct {
 type StepTypeBootCommand struct {
 	BootCommand []string
 	VMName      string
+	VNCPassword string
 	Ctx         interpolate.Context
 }
 
@@ -57,7 +58,11 @@ func (s *StepTypeBootCommand) Run(state multistep.StateBag) multistep.StepAction
 	}
 	defer nc.Close()
 
-	c, err := vnc.Client(nc, &vnc.ClientConfig{Exclusive: true})
+	vncConfig := &vnc.ClientConfig{Exclusive: true}
+	if s.VNCPassword != "" {
+		vncConfig.Auth = []vnc.ClientAuth{&vnc.PasswordAuth{Password: s.VNCPassword}}
+	}
+	c, err := vnc.Client(nc, vncConfig)
 	if err != nil {
 		err := fmt.Errorf("Error handshaking with VNC: %s", err)
 		state.Put("error", err)
@@ -1,12 +1,24 @@
 package common
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/template/interpolate"
 )
 
 type ToolsConfig struct {
 	ToolsUploadFlavor string `mapstructure:"tools_upload_flavor"`
 	ToolsUploadPath   string `mapstructure:"tools_upload_path"`
+
+	// ToolsMirror is the URL of a directory with the ISOs of the tools,
+	// named after their flavor like in the installation of VMware. The
+	// ISO is downloaded from it instead of taken from the installation.
+	ToolsMirror       string `mapstructure:"tools_mirror"`
+	ToolsChecksum     string `mapstructure:"tools_checksum"`
+	ToolsChecksumType string `mapstructure:"tools_checksum_type"`
 }
 
 func (c *ToolsConfig) Prepare(ctx *interpolate.Context) []error {
@@ -14,5 +26,37 @@ func (c *ToolsConfig) Prepare(ctx *interpolate.Context) []error {
 		c.ToolsUploadPath = "{{ .Flavor }}.iso"
 	}
 
-	return nil
+	var errs []error
+
+	if c.ToolsMirror != "" {
+		c.ToolsMirror = strings.TrimRight(c.ToolsMirror, "/")
+		if u, err := url.Parse(c.ToolsMirror); err != nil || u.Scheme == "" {
+			errs = append(errs, fmt.Errorf(
+				"tools_mirror must be a URL, such as https://mirror.example.com/vmware-tools"))
+		}
+	}
+
+	if c.ToolsChecksum != "" {
+		c.ToolsChecksum = strings.ToLower(c.ToolsChecksum)
+		if c.ToolsChecksumType == "" {
+			c.ToolsChecksumType = "sha256"
+		}
+	}
+
+	if c.ToolsChecksumType != "" {
+		c.ToolsChecksumType = strings.ToLower(c.ToolsChecksumType)
+		if c.ToolsChecksum == "" {
+			errs = append(errs, fmt.Errorf("tools_checksum_type requires tools_checksum"))
+		} else if common.HashForType(c.ToolsChecksumType) == nil {
+			errs = append(errs, fmt.Errorf(
+				"Unsupported tools_checksum_type: %s", c.ToolsChecksumType))
+		}
+	}
+
+	if c.ToolsUploadFlavor == "" && (c.ToolsMirror != "" || c.ToolsChecksum != "") {
+		errs = append(errs, fmt.Errorf(
+			"tools_mirror and tools_checksum require tools_upload_flavor"))
+	}
+
+	return errs
 }
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestToolsConfigPrepare(t *testing.T) {
+	c := &ToolsConfig{}
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.ToolsUploadPath != "{{ .Flavor }}.iso" {
+		t.Fatalf("bad: %s", c.ToolsUploadPath)
+	}
+
+	c = &ToolsConfig{
+		ToolsUploadFlavor: "linux",
+		ToolsMirror:       "https://mirror.example.com/tools/",
+		ToolsChecksum:     "ABCD",
+	}
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.ToolsMirror != "https://mirror.example.com/tools" {
+		t.Fatalf("bad: %s", c.ToolsMirror)
+	}
+	if c.ToolsChecksum != "abcd" || c.ToolsChecksumType != "sha256" {
+		t.Fatalf("bad: %#v", c)
+	}
+
+	cases := []*ToolsConfig{
+		// Not a URL
+		{ToolsUploadFlavor: "linux", ToolsMirror: "mirror"},
+		// Unknown checksum type
+		{ToolsUploadFlavor: "linux", ToolsChecksum: "abcd", ToolsChecksumType: "crc32"},
+		// Checksum type without a checksum
+		{ToolsUploadFlavor: "linux", ToolsChecksumType: "sha1"},
+		// No flavor
+		{ToolsMirror: "https://mirror.example.com/tools"},
+	}
+	for _, tc := range cases {
+		if errs := tc.Prepare(testConfigTemplate(t)); len(errs) == 0 {
+			t.Fatalf("%#v: should have error", tc)
+		}
+	}
+}
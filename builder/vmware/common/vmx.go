@@ -10,6 +10,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/mitchellh/packer/common"
 )
 
 // ParseVMX parses the keys and values from a VMX file and returns
@@ -55,7 +57,7 @@ func EncodeVMX(contents map[string]string) string {
 // map and writes it out.
 func WriteVMX(path string, data map[string]string) (err error) {
 	log.Printf("Writing VMX to: %s", path)
-	f, err := os.Create(path)
+	f, err := os.Create(common.LongPath(path))
 	if err != nil {
 		return
 	}
@@ -72,7 +74,7 @@ func WriteVMX(path string, data map[string]string) (err error) {
 
 // ReadVMX takes a path to a VMX file and reads it into a k/v mapping.
 func ReadVMX(path string) (map[string]string, error) {
-	data, err := ioutil.ReadFile(path)
+	data, err := ioutil.ReadFile(common.LongPath(path))
 	if err != nil {
 		return nil, err
 	}
@@ -45,10 +45,13 @@ type Config struct {
 	ISOChecksum        string   `mapstructure:"iso_checksum"`
 	ISOChecksumType    string   `mapstructure:"iso_checksum_type"`
 	ISOUrls            []string `mapstructure:"iso_urls"`
+	KeepRegistered     bool     `mapstructure:"keep_registered"`
 	Version            string   `mapstructure:"version"`
 	VMName             string   `mapstructure:"vm_name"`
 	BootCommand        []string `mapstructure:"boot_command"`
+	BootCommandFile    string   `mapstructure:"boot_command_file"`
 	SkipCompaction     bool     `mapstructure:"skip_compaction"`
+	SkipDefrag         bool     `mapstructure:"skip_defrag"`
 	VMXTemplatePath    string   `mapstructure:"vmx_template_path"`
 
 	RemoteType           string `mapstructure:"remote_type"`
@@ -91,6 +94,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, b.config.VMXConfig.Prepare(&b.config.ctx)...)
 	warnings := make([]string, 0)
 
+	b.config.BootCommand, err = common.BootCommandFromFile(b.config.BootCommand, b.config.BootCommandFile)
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	if b.config.DiskName == "" {
 		b.config.DiskName = "disk"
 	}
@@ -206,6 +214,12 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 				"a checksum is highly recommended.")
 	}
 
+	if b.config.KeepRegistered && b.config.RemoteType == "" {
+		warnings = append(warnings,
+			"keep_registered only applies to remote builds, since local VMware\n"+
+				"builds don't register the virtual machine. It will be ignored.")
+	}
+
 	if b.config.ShutdownCommand == "" {
 		warnings = append(warnings,
 			"A shutdown_command was not specified. Without a shutdown command, Packer\n"+
@@ -251,6 +265,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&vmwcommon.StepPrepareTools{
 			RemoteType:        b.config.RemoteType,
 			ToolsUploadFlavor: b.config.ToolsUploadFlavor,
+			ToolsMirror:       b.config.ToolsMirror,
+			ToolsChecksum:     b.config.ToolsChecksum,
+			ToolsChecksumType: b.config.ToolsChecksumType,
 		},
 		&common.StepDownload{
 			Checksum:     b.config.ISOChecksum,
@@ -263,7 +280,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Force: b.config.PackerForce,
 		},
 		&common.StepCreateFloppy{
-			Files: b.config.FloppyFiles,
+			Files:   b.config.FloppyFiles,
+			WorkDir: b.config.PackerWorkDir,
 		},
 		&stepRemoteUpload{
 			Key:     "floppy_path",
@@ -285,10 +303,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			HTTPPortMax: b.config.HTTPPortMax,
 		},
 		&vmwcommon.StepConfigureVNC{
-			VNCPortMin: b.config.VNCPortMin,
-			VNCPortMax: b.config.VNCPortMax,
+			VNCBindAddress: b.config.VNCBindAddress,
+			VNCPassword:    b.config.VNCPassword,
+			VNCPortMin:     b.config.VNCPortMin,
+			VNCPortMax:     b.config.VNCPortMax,
+		},
+		&StepRegister{
+			KeepRegistered: b.config.KeepRegistered,
 		},
-		&StepRegister{},
 		&vmwcommon.StepRun{
 			BootWait:           b.config.BootWait,
 			DurationBeforeStop: 5 * time.Second,
@@ -297,6 +319,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&vmwcommon.StepTypeBootCommand{
 			BootCommand: b.config.BootCommand,
 			VMName:      b.config.VMName,
+			VNCPassword: b.config.VNCPassword,
 			Ctx:         b.config.ctx,
 		},
 		&communicator.StepConnect{
@@ -325,7 +348,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			RemoteType: b.config.RemoteType,
 		},
 		&vmwcommon.StepCompactDisk{
-			Skip: b.config.SkipCompaction,
+			Skip:       b.config.SkipCompaction,
+			SkipDefrag: b.config.SkipDefrag,
 		},
 	}
 
@@ -336,7 +360,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 
 	b.runner.Run(state)
@@ -374,6 +400,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}, nil
 }
 
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (b *Builder) Cancel() {
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
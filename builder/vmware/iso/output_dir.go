@@ -3,6 +3,8 @@ package iso
 import (
 	"os"
 	"path/filepath"
+
+	"github.com/mitchellh/packer/common"
 )
 
 // OutputDir is an interface type that abstracts the creation and handling
@@ -25,7 +27,7 @@ type localOutputDir struct {
 }
 
 func (d *localOutputDir) DirExists() (bool, error) {
-	_, err := os.Stat(d.dir)
+	_, err := os.Stat(common.LongPath(d.dir))
 	return err == nil, nil
 }
 
@@ -46,15 +48,15 @@ func (d *localOutputDir) ListFiles() ([]string, error) {
 }
 
 func (d *localOutputDir) MkdirAll() error {
-	return os.MkdirAll(d.dir, 0755)
+	return os.MkdirAll(common.LongPath(d.dir), 0755)
 }
 
 func (d *localOutputDir) Remove(path string) error {
-	return os.Remove(path)
+	return os.Remove(common.LongPath(path))
 }
 
 func (d *localOutputDir) RemoveAll() error {
-	return os.RemoveAll(d.dir)
+	return os.RemoveAll(common.LongPath(d.dir))
 }
 
 func (d *localOutputDir) SetOutputDir(path string) {
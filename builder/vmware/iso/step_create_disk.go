@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/mitchellh/multistep"
 	vmwcommon "github.com/mitchellh/packer/builder/vmware/common"
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 	"path/filepath"
 )
@@ -41,7 +42,7 @@ func (stepCreateDisk) Run(state multistep.StateBag) multistep.StepAction {
 
 		ui.Say("Creating additional hard drives...")
 		for i, additionalsize := range config.AdditionalDiskSize {
-			additionalpath := filepath.Join(config.OutputDir, fmt.Sprintf("%s-%d.vmdk", config.DiskName, i+1))
+			additionalpath := filepath.Join(config.OutputDir, common.AdditionalDiskName(config.DiskName, i, "vmdk"))
 			size := fmt.Sprintf("%dM", uint64(additionalsize))
 
 			if err := driver.CreateDisk(additionalpath, size, config.DiskTypeId); err != nil {
@@ -8,7 +8,12 @@ import (
 	"github.com/mitchellh/packer/packer"
 )
 
+// This step registers the VM with a remote host, and unregisters it
+// again during cleanup unless KeepRegistered is set and the build
+// succeeded.
 type StepRegister struct {
+	KeepRegistered bool
+
 	registeredPath string
 }
 
@@ -40,6 +45,13 @@ func (s *StepRegister) Cleanup(state multistep.StateBag) {
 	driver := state.Get("driver").(vmwcommon.Driver)
 	ui := state.Get("ui").(packer.Ui)
 
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if s.KeepRegistered && !cancelled && !halted {
+		ui.Say("Keeping virtual machine registered (keep_registered = true)")
+		return
+	}
+
 	if remoteDriver, ok := driver.(RemoteDriver); ok {
 		ui.Say("Unregistering virtual machine...")
 		if err := remoteDriver.Unregister(s.registeredPath); err != nil {
@@ -63,3 +63,30 @@ func TestStepRegister_remoteDriver(t *testing.T) {
 		t.Fatal("should unregister proper path")
 	}
 }
+
+func TestStepRegister_keepRegistered(t *testing.T) {
+	state := testState(t)
+	step := &StepRegister{KeepRegistered: true}
+
+	driver := new(RemoteDriverMock)
+	state.Put("driver", driver)
+	state.Put("vmx_path", "foo")
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// A successful build keeps the VM registered
+	step.Cleanup(state)
+	if driver.UnregisterCalled {
+		t.Fatal("unregister should not be called")
+	}
+
+	// A halted build does not
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+	if !driver.UnregisterCalled {
+		t.Fatal("unregister should be called")
+	}
+}
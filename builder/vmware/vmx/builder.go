@@ -10,6 +10,7 @@ import (
 	vmwcommon "github.com/mitchellh/packer/builder/vmware/common"
 	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/helper/communicator"
+	"github.com/mitchellh/packer/helper/config"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -45,6 +46,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	// Set up the state.
 	state := new(multistep.BasicStateBag)
+	state.Put("cache", cache)
 	state.Put("config", b.config)
 	state.Put("dir", dir)
 	state.Put("driver", driver)
@@ -56,14 +58,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&vmwcommon.StepPrepareTools{
 			RemoteType:        b.config.RemoteType,
 			ToolsUploadFlavor: b.config.ToolsUploadFlavor,
+			ToolsMirror:       b.config.ToolsMirror,
+			ToolsChecksum:     b.config.ToolsChecksum,
+			ToolsChecksumType: b.config.ToolsChecksumType,
 		},
 		&vmwcommon.StepOutputDir{
 			Force: b.config.PackerForce,
 		},
 		&common.StepCreateFloppy{
-			Files: b.config.FloppyFiles,
+			Files:   b.config.FloppyFiles,
+			WorkDir: b.config.PackerWorkDir,
 		},
 		&StepCloneVMX{
+			Linked:    b.config.Linked,
 			OutputDir: b.config.OutputDir,
 			Path:      b.config.SourcePath,
 			VMName:    b.config.VMName,
@@ -78,8 +85,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			HTTPPortMax: b.config.HTTPPortMax,
 		},
 		&vmwcommon.StepConfigureVNC{
-			VNCPortMin: b.config.VNCPortMin,
-			VNCPortMax: b.config.VNCPortMax,
+			VNCBindAddress: b.config.VNCBindAddress,
+			VNCPassword:    b.config.VNCPassword,
+			VNCPortMin:     b.config.VNCPortMin,
+			VNCPortMax:     b.config.VNCPortMax,
 		},
 		&vmwcommon.StepRun{
 			BootWait:           b.config.BootWait,
@@ -89,6 +98,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&vmwcommon.StepTypeBootCommand{
 			BootCommand: b.config.BootCommand,
 			VMName:      b.config.VMName,
+			VNCPassword: b.config.VNCPassword,
 			Ctx:         b.config.ctx,
 		},
 		&communicator.StepConnect{
@@ -114,7 +124,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&vmwcommon.StepCleanVMX{},
 		&vmwcommon.StepCompactDisk{
-			Skip: b.config.SkipCompaction,
+			Skip:       b.config.SkipCompaction || b.config.Linked,
+			SkipDefrag: b.config.SkipDefrag,
 		},
 	}
 
@@ -125,7 +136,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PauseFn: common.MultistepDebugFn(ui),
 		}
 	} else {
-		b.runner = &multistep.BasicRunner{Steps: steps}
+		b.runner = &multistep.BasicRunner{
+			Steps: common.TraceSteps(b.config.PackerBuildName, steps),
+		}
 	}
 	b.runner.Run(state)
 
@@ -146,6 +159,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	return vmwcommon.NewLocalArtifact(b.config.OutputDir)
 }
 
+// ConfigSchema returns the JSON Schema of the configuration.
+func (b *Builder) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 // Cancel.
 func (b *Builder) Cancel() {
 	if b.runner != nil {
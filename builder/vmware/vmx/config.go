@@ -22,12 +22,15 @@ type Config struct {
 	vmwcommon.ToolsConfig    `mapstructure:",squash"`
 	vmwcommon.VMXConfig      `mapstructure:",squash"`
 
-	BootCommand    []string `mapstructure:"boot_command"`
-	FloppyFiles    []string `mapstructure:"floppy_files"`
-	RemoteType     string   `mapstructure:"remote_type"`
-	SkipCompaction bool     `mapstructure:"skip_compaction"`
-	SourcePath     string   `mapstructure:"source_path"`
-	VMName         string   `mapstructure:"vm_name"`
+	BootCommand     []string `mapstructure:"boot_command"`
+	BootCommandFile string   `mapstructure:"boot_command_file"`
+	FloppyFiles     []string `mapstructure:"floppy_files"`
+	Linked          bool     `mapstructure:"linked"`
+	RemoteType      string   `mapstructure:"remote_type"`
+	SkipCompaction  bool     `mapstructure:"skip_compaction"`
+	SkipDefrag      bool     `mapstructure:"skip_defrag"`
+	SourcePath      string   `mapstructure:"source_path"`
+	VMName          string   `mapstructure:"vm_name"`
 
 	ctx interpolate.Context
 }
@@ -62,10 +65,15 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs = packer.MultiErrorAppend(errs, c.ToolsConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VMXConfig.Prepare(&c.ctx)...)
 
+	c.BootCommand, err = common.BootCommandFromFile(c.BootCommand, c.BootCommandFile)
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	if c.SourcePath == "" {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is blank, but is required"))
 	} else {
-		if _, err := os.Stat(c.SourcePath); err != nil {
+		if _, err := os.Stat(common.LongPath(c.SourcePath)); err != nil {
 			errs = packer.MultiErrorAppend(errs,
 				fmt.Errorf("source_path is invalid: %s", err))
 		}
@@ -79,6 +87,12 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 				"will forcibly halt the virtual machine, which may result in data loss.")
 	}
 
+	if c.Linked && !c.SkipCompaction {
+		warnings = append(warnings,
+			"The disk of a linked clone can't be compacted, so compaction will be\n"+
+				"skipped. Set skip_compaction to true to remove this warning.")
+	}
+
 	// Check for any errors.
 	if errs != nil && len(errs.Errors) > 0 {
 		return nil, warnings, errs
@@ -57,3 +57,28 @@ func TestNewConfig_sourcePath(t *testing.T) {
 	_, warns, errs = NewConfig(c)
 	testConfigOk(t, warns, errs)
 }
+
+func TestNewConfig_linked(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	// Linked clones warn that compaction is skipped
+	c := testConfig(t)
+	c["source_path"] = tf.Name()
+	c["linked"] = true
+	_, warns, errs := NewConfig(c)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if errs != nil {
+		t.Fatalf("bad: %s", errs)
+	}
+
+	c["skip_compaction"] = true
+	_, warns, errs = NewConfig(c)
+	testConfigOk(t, warns, errs)
+}
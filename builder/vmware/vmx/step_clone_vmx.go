@@ -12,6 +12,7 @@ import (
 
 // StepCloneVMX takes a VMX file and clones the VM into the output directory.
 type StepCloneVMX struct {
+	Linked    bool
 	OutputDir string
 	Path      string
 	VMName    string
@@ -26,7 +27,7 @@ func (s *StepCloneVMX) Run(state multistep.StateBag) multistep.StepAction {
 	ui.Say("Cloning source VM...")
 	log.Printf("Cloning from: %s", s.Path)
 	log.Printf("Cloning to: %s", vmxPath)
-	if err := driver.Clone(vmxPath, s.Path); err != nil {
+	if err := driver.Clone(vmxPath, s.Path, s.Linked); err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
@@ -54,6 +54,9 @@ func TestStepCloneVMX(t *testing.T) {
 	if !driver.CloneCalled {
 		t.Fatal("should call clone")
 	}
+	if driver.CloneLinked {
+		t.Fatal("should not be a linked clone")
+	}
 
 	// Test that we have our paths
 	if vmxPath, ok := state.GetOk("vmx_path"); !ok {
@@ -3,9 +3,14 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-checkpoint"
+	"github.com/mitchellh/osext"
 	"github.com/mitchellh/packer/command"
 )
 
@@ -15,16 +20,24 @@ func init() {
 
 var checkpointResult chan *checkpoint.CheckResponse
 
+// checkpointConfig is the config that runCheckpoint was called with,
+// which has the plugins to check. It is nil if checkpoint is disabled.
+var checkpointConfig *config
+
 // runCheckpoint runs a HashiCorp Checkpoint request. You can read about
 // Checkpoint here: https://github.com/hashicorp/go-checkpoint.
 func runCheckpoint(c *config) {
-	// If the user doesn't want checkpoint at all, then return.
-	if c.DisableCheckpoint {
+	// If the user doesn't want checkpoint at all, then return. The
+	// CHECKPOINT_DISABLE environment variable is honored as well so that
+	// Packer can be run offline without editing the config.
+	if c.DisableCheckpoint || os.Getenv("CHECKPOINT_DISABLE") != "" {
 		log.Printf("[INFO] Checkpoint disabled. Not running.")
 		checkpointResult <- nil
 		return
 	}
 
+	checkpointConfig = c
+
 	configDir, err := ConfigDir()
 	if err != nil {
 		log.Printf("[ERR] Checkpoint setup error: %s", err)
@@ -74,8 +87,118 @@ func commandVersionCheck() (command.VersionCheckInfo, error) {
 	}
 
 	return command.VersionCheckInfo{
+		Checked:  true,
 		Outdated: info.Outdated,
 		Latest:   info.CurrentVersion,
 		Alerts:   alerts,
 	}, nil
 }
+
+// pluginCheckTimeout bounds how long the plugin version checks may take
+// altogether.
+var pluginCheckTimeout = 10 * time.Second
+
+// commandPluginVersionCheck implements command.PluginCheckFunc and looks
+// up the latest versions of the installed plugins that report their
+// version. Plugins are known to Checkpoint by the name of their binary,
+// such as "packer-builder-foo". The plugins that ship with Packer, next
+// to the executable, are versioned with Packer itself and are skipped.
+func commandPluginVersionCheck() []command.PluginVersionInfo {
+	c := checkpointConfig
+	if c == nil {
+		return nil
+	}
+
+	configDir, err := ConfigDir()
+	if err != nil {
+		log.Printf("[ERR] Checkpoint setup error: %s", err)
+		return nil
+	}
+
+	var exeDir string
+	if exePath, err := osext.Executable(); err != nil {
+		log.Printf("[ERR] Error loading exe directory: %s", err)
+	} else {
+		exeDir = filepath.Dir(exePath)
+	}
+
+	plugins := c.Plugins()
+	for k, v := range c.Communicators {
+		plugins["communicator."+k] = v
+	}
+	for k, v := range plugins {
+		if exeDir != "" && filepath.Dir(v) == exeDir {
+			delete(plugins, k)
+		}
+	}
+
+	resultCh := make(chan *command.PluginVersionInfo, len(plugins))
+	for name, path := range plugins {
+		go func(name, path string) {
+			resultCh <- checkPlugin(c, configDir, name, path)
+		}(name, path)
+	}
+
+	var result []command.PluginVersionInfo
+	timeout := time.After(pluginCheckTimeout)
+CHECKS:
+	for i := 0; i < len(plugins); i++ {
+		select {
+		case info := <-resultCh:
+			if info != nil {
+				result = append(result, *info)
+			}
+		case <-timeout:
+			log.Printf("[ERR] Timeout checking plugin versions")
+			break CHECKS
+		}
+	}
+
+	sort.Sort(pluginVersionInfoSlice(result))
+	return result
+}
+
+// checkPlugin looks up the latest version of a single plugin. It returns
+// nil if the plugin doesn't report its version or the check failed.
+func checkPlugin(c *config, configDir, name, path string) *command.PluginVersionInfo {
+	client := c.pluginClient(path)
+	version, err := client.Version()
+	client.Kill()
+	if err != nil {
+		log.Printf("[INFO] Plugin %s doesn't report its version: %s", name, err)
+		return nil
+	}
+	if version == "" {
+		return nil
+	}
+
+	product := "packer-" + strings.Replace(name, ".", "-", 1)
+	signaturePath := filepath.Join(configDir, "checkpoint_signature")
+	if c.DisableCheckpointSignature {
+		signaturePath = ""
+	}
+
+	resp, err := checkpoint.Check(&checkpoint.CheckParams{
+		Product:       product,
+		Version:       version,
+		SignatureFile: signaturePath,
+		CacheFile:     filepath.Join(configDir, "checkpoint_cache_"+product),
+	})
+	if err != nil {
+		log.Printf("[ERR] Checkpoint error for %s: %s", product, err)
+		return nil
+	}
+
+	return &command.PluginVersionInfo{
+		Name:     name,
+		Version:  version,
+		Outdated: resp.Outdated,
+		Latest:   resp.CurrentVersion,
+	}
+}
+
+type pluginVersionInfoSlice []command.PluginVersionInfo
+
+func (s pluginVersionInfoSlice) Len() int           { return len(s) }
+func (s pluginVersionInfoSlice) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s pluginVersionInfoSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
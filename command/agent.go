@@ -0,0 +1,378 @@
+package command
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/mitchellh/osext"
+	"github.com/mitchellh/packer/packer"
+)
+
+// agentVarFileEntry is the name of the file with the user variables of a
+// remote build within its working directory.
+const agentVarFileEntry = ".packer-remote-vars"
+
+type AgentCommand struct {
+	Meta
+}
+
+func (c *AgentCommand) Run(args []string) int {
+	var stdio bool
+	var address, certFile, keyFile string
+
+	f := c.Meta.FlagSet("agent", FlagSetNone)
+	f.Usage = func() { c.Ui.Error(c.Help()) }
+	f.BoolVar(&stdio, "stdio", false, "stdio")
+	f.StringVar(&address, "address", ":8443", "address")
+	f.StringVar(&certFile, "tls-cert", "", "tls-cert")
+	f.StringVar(&keyFile, "tls-key", "", "tls-key")
+	if err := f.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(f.Args()) != 0 {
+		f.Usage()
+		return 1
+	}
+
+	if stdio {
+		return c.runStdio()
+	}
+
+	if certFile == "" || keyFile == "" {
+		c.Ui.Error("The -tls-cert and -tls-key options must be specified.")
+		return 1
+	}
+
+	token := os.Getenv(remoteTokenEnvVar)
+	if token == "" {
+		c.Ui.Error(fmt.Sprintf(
+			"The %s environment variable must be set to the token\n"+
+				"that clients authenticate with.", remoteTokenEnvVar))
+		return 1
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/build", &agentHandler{
+		CacheDir: c.cacheDir(),
+		Token:    token,
+	})
+
+	c.Ui.Say(fmt.Sprintf("Packer agent listening on: %s", address))
+	if err := http.ListenAndServeTLS(address, certFile, keyFile, mux); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error serving: %s", err))
+		return 1
+	}
+
+	return 0
+}
+
+// runStdio runs the remote build that is read from stdin, writing the
+// output of it to stdout. The build is cancelled if stdin is closed.
+func (c *AgentCommand) runStdio() int {
+	ui := &packer.MachineReadableUi{Writer: os.Stdout}
+
+	r := bufio.NewReader(os.Stdin)
+	req, dir, err := readAgentRequest(r)
+	if err != nil {
+		ui.Error(err.Error())
+		ui.Machine("exit-code", "1")
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cancelCh := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, r)
+		close(cancelCh)
+	}()
+
+	return runAgentBuild(req, dir, c.cacheDir(), os.Stdout, cancelCh)
+}
+
+// cacheDir returns the cache directory of the agent. Remote builds share
+// it so that they don't download the same files each time.
+func (c *AgentCommand) cacheDir() string {
+	if cache, ok := c.Meta.Cache.(*packer.FileCache); ok {
+		return cache.CacheDir
+	}
+
+	return ""
+}
+
+func (*AgentCommand) Help() string {
+	helpText := `
+Usage: packer agent [options]
+
+  Runs builds for Packer clients on this machine, which start them with
+  "packer build -remote". The template and its supporting files are sent
+  by the client, and the output of the builds is streamed back to it.
+
+  By default the agent serves HTTPS. Clients authenticate with the token
+  in the PACKER_AGENT_TOKEN environment variable, which must be set for
+  both the agent and its clients.
+
+  Clients that connect over SSH run "packer agent -stdio" on this machine
+  themselves, so no agent needs to be running for them.
+
+Options:
+
+  -address=addr            The address to serve HTTPS on. Defaults to ":8443".
+
+  -stdio                   Run a single build that is read from stdin
+
+  -tls-cert=path           The TLS certificate to serve HTTPS with
+
+  -tls-key=path            The private key of the TLS certificate
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*AgentCommand) Synopsis() string {
+	return "run builds for remote Packer clients"
+}
+
+// agentHandler is the HTTP handler that runs remote builds. The build is
+// cancelled if the client closes the connection.
+type agentHandler struct {
+	CacheDir string
+	Token    string
+}
+
+func (h *agentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	auth := []byte(r.Header.Get("Authorization"))
+	if subtle.ConstantTimeCompare(auth, []byte("Bearer "+h.Token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	req, dir, err := readAgentRequest(bufio.NewReader(r.Body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	cancelCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	if cn, ok := w.(http.CloseNotifier); ok {
+		closeCh := cn.CloseNotify()
+		go func() {
+			select {
+			case <-closeCh:
+				close(cancelCh)
+			case <-doneCh:
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	output := io.Writer(w)
+	if f, ok := w.(http.Flusher); ok {
+		output = &flushWriter{Writer: w, Flusher: f}
+	}
+
+	runAgentBuild(req, dir, h.CacheDir, output, cancelCh)
+}
+
+// flushWriter flushes every write so the output of a build is streamed.
+type flushWriter struct {
+	io.Writer
+	http.Flusher
+}
+
+func (w *flushWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.Flush()
+	return n, err
+}
+
+// readAgentRequest reads a remote build request, extracting the archive
+// that follows it into a new temporary directory. The caller is
+// responsible for removing the directory.
+func readAgentRequest(r *bufio.Reader) (*remoteBuildRequest, string, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, "", fmt.Errorf("Error reading remote build request: %s", err)
+	}
+
+	var req remoteBuildRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return nil, "", fmt.Errorf("Error decoding remote build request: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "packer-agent")
+	if err != nil {
+		return nil, "", fmt.Errorf("Error creating build directory: %s", err)
+	}
+
+	if err := extractArchive(io.LimitReader(r, req.ArchiveSize), dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, "", fmt.Errorf("Error extracting archive: %s", err)
+	}
+
+	if len(req.Vars) > 0 {
+		vars, err := json.Marshal(req.Vars)
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(dir, agentVarFileEntry), vars, 0600)
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, "", fmt.Errorf("Error writing variables: %s", err)
+		}
+	}
+
+	return &req, dir, nil
+}
+
+// extractArchive extracts the gzipped tar archive in r into dir.
+func extractArchive(r io.Reader, dir string) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." ||
+			strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+
+			f, err := os.OpenFile(
+				path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, hdr.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// agentBuildArgs returns the arguments to "packer build" that run the
+// remote build.
+func agentBuildArgs(req *remoteBuildRequest) []string {
+	args := []string{"build", "-machine-readable"}
+	if req.Force {
+		args = append(args, "-force")
+	}
+	if !req.Parallel {
+		args = append(args, "-parallel=false")
+	}
+	if req.ParallelPostProcessors > 0 {
+		args = append(args, fmt.Sprintf(
+			"-parallel-post-processors=%d", req.ParallelPostProcessors))
+	}
+	if len(req.Except) > 0 {
+		args = append(args, "-except="+strings.Join(req.Except, ","))
+	}
+	if len(req.Only) > 0 {
+		args = append(args, "-only="+strings.Join(req.Only, ","))
+	}
+	if len(req.Vars) > 0 {
+		args = append(args, "-var-file="+agentVarFileEntry)
+	}
+
+	return append(args, archiveTemplateEntry)
+}
+
+// runAgentBuild runs the remote build whose files are in dir, writing
+// the machine-readable output of it to w. It returns the exit code of
+// the build, which is also the last message written.
+func runAgentBuild(req *remoteBuildRequest, dir, cacheDir string, w io.Writer, cancelCh <-chan struct{}) int {
+	ui := &packer.MachineReadableUi{Writer: w}
+
+	exePath, err := osext.Executable()
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error finding Packer: %s", err))
+		ui.Machine("exit-code", "1")
+		return 1
+	}
+
+	cmd := exec.Command(exePath, agentBuildArgs(req)...)
+	cmd.Dir = dir
+	if cacheDir != "" {
+		cmd.Env = append(os.Environ(), "PACKER_CACHE_DIR="+cacheDir)
+	}
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		ui.Error(fmt.Sprintf("Error starting build: %s", err))
+		ui.Machine("exit-code", "1")
+		return 1
+	}
+
+	// Interrupt the build when it's cancelled, like Ctrl-C would
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		select {
+		case <-cancelCh:
+			if err := cmd.Process.Signal(os.Interrupt); err != nil {
+				cmd.Process.Kill()
+			}
+		case <-doneCh:
+		}
+	}()
+
+	code := 0
+	if err := cmd.Wait(); err != nil {
+		code = 1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				code = status.ExitStatus()
+			}
+		}
+	}
+
+	ui.Machine("exit-code", strconv.Itoa(code))
+	return code
+}
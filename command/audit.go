@@ -0,0 +1,266 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/packer/template"
+)
+
+type AuditCommand struct {
+	Meta
+}
+
+func (c *AuditCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("audit", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		return 1
+	}
+
+	total := 0
+	for _, path := range args {
+		tpl, err := template.ParseFile(path)
+		if err != nil {
+			c.Ui.Machine("error-category", ErrorCategoryParse)
+			c.Ui.Error(fmt.Sprintf("Failed to parse template %s: %s", path, err))
+			return ExitCodeParseError
+		}
+
+		findings := auditTemplate(tpl)
+		total += len(findings)
+		if len(findings) == 0 {
+			c.Ui.Say(fmt.Sprintf("%s: no insecure settings found.", path))
+			continue
+		}
+
+		c.Ui.Say(fmt.Sprintf("%s: %d insecure setting(s) found:\n", path, len(findings)))
+		for _, f := range findings {
+			c.Ui.Machine("audit-finding", path, f.Component, f.Problem, f.Suggestion)
+			c.Ui.Say(fmt.Sprintf("  %s: %s", f.Component, f.Problem))
+			c.Ui.Say(fmt.Sprintf("    %s\n", f.Suggestion))
+		}
+	}
+
+	if total > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func (*AuditCommand) Help() string {
+	helpText := `
+Usage: packer audit TEMPLATE...
+
+  Checks templates for insecure settings, such as weak SSH passwords, VNC
+  servers without a password that can be reached from the network,
+  unencrypted WinRM and public AMIs, and suggests the settings to use
+  instead. Only the settings written in the templates are checked, not
+  the values of user variables.
+
+  Exits with a non-zero exit status if anything was found, or 2 if a
+  template can't be parsed.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*AuditCommand) Synopsis() string {
+	return "check templates for insecure settings"
+}
+
+// auditFinding is an insecure setting of a builder in a template.
+type auditFinding struct {
+	// Component is the builder, such as "builder 'qemu'".
+	Component string
+
+	Problem    string
+	Suggestion string
+}
+
+// weakPasswords are passwords of default images and examples, which are
+// flagged whatever their length.
+var weakPasswords = map[string]bool{
+	"admin":     true,
+	"changeme":  true,
+	"packer":    true,
+	"password":  true,
+	"password1": true,
+	"root":      true,
+	"toor":      true,
+	"ubuntu":    true,
+	"vagrant":   true,
+}
+
+// minPasswordLength is the length below which a password is weak.
+const minPasswordLength = 12
+
+// auditTemplate returns the insecure settings of the builders of the
+// template, sorted by the name of the builder.
+func auditTemplate(tpl *template.Template) []auditFinding {
+	names := make([]string, 0, len(tpl.Builders))
+	for n := range tpl.Builders {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var findings []auditFinding
+	for _, n := range names {
+		b := tpl.Builders[n]
+		component := fmt.Sprintf("builder '%s'", n)
+		for _, f := range auditBuilder(b.Type, b.Config) {
+			f.Component = component
+			findings = append(findings, f)
+		}
+	}
+
+	return findings
+}
+
+func auditBuilder(typ string, config map[string]interface{}) []auditFinding {
+	var findings []auditFinding
+
+	comm := configString(config, "communicator")
+	switch comm {
+	case "", "ssh":
+		password := configString(config, "ssh_password")
+		if password != "" && configString(config, "ssh_private_key_file") == "" && weakPassword(password) {
+			findings = append(findings, auditFinding{
+				Problem: "ssh_password is a weak password, which anyone who can reach the " +
+					"SSH port of the machine during the build can guess.",
+				Suggestion: "Use ssh_private_key_file, or a long random password passed " +
+					"in with -var.",
+			})
+		}
+
+		if typ == "qemu" && !configBool(config, "pin_ssh_host_key") {
+			findings = append(findings, auditFinding{
+				Problem: "The SSH host key of the guest isn't verified, so another " +
+					"machine could take its place on the forwarded port.",
+				Suggestion: "Set pin_ssh_host_key to trust the first host key of the " +
+					"guest and reject others for the rest of the build.",
+			})
+		}
+	case "winrm":
+		findings = append(findings, auditFinding{
+			Problem: "WinRM is used over unencrypted HTTP, so winrm_password and " +
+				"everything that is provisioned can be read on the network.",
+			Suggestion: "Use the ssh communicator with OpenSSH on the guest, or only " +
+				"build on a network that you trust.",
+		})
+
+		if password := configString(config, "winrm_password"); password != "" && weakPassword(password) {
+			findings = append(findings, auditFinding{
+				Problem:    "winrm_password is a weak password.",
+				Suggestion: "Use a long random password passed in with -var.",
+			})
+		}
+	}
+
+	switch typ {
+	case "qemu":
+		if configString(config, "display") != "spice" &&
+			!loopbackAddress(configString(config, "vnc_bind_address")) &&
+			!configBool(config, "vnc_use_password") &&
+			configString(config, "vnc_password") == "" {
+			findings = append(findings, auditFinding{
+				Problem: "The VNC server of the VM listens on all interfaces without a " +
+					"password, so anyone on the network can control the VM.",
+				Suggestion: "Set vnc_use_password to protect it with a random password, " +
+					"or set vnc_password, or set vnc_bind_address to \"127.0.0.1\".",
+			})
+		}
+	case "vmware-iso", "vmware-vmx":
+		if !loopbackAddress(configString(config, "vnc_bind_address")) &&
+			configString(config, "vnc_password") == "" {
+			findings = append(findings, auditFinding{
+				Problem: "The VNC server of the VM listens on all interfaces without a " +
+					"password, so anyone on the network can control the VM.",
+				Suggestion: "Set vnc_password, or set vnc_bind_address to \"127.0.0.1\".",
+			})
+		}
+	}
+
+	if strings.HasPrefix(typ, "amazon-") {
+		for _, g := range configStrings(config, "ami_groups") {
+			if g == "all" {
+				findings = append(findings, auditFinding{
+					Problem: "ami_groups contains \"all\", which makes the AMI and its " +
+						"snapshots public.",
+					Suggestion: "Share the AMI with ami_users, ami_org_arns or ami_ou_arns " +
+						"instead.",
+				})
+				break
+			}
+		}
+	}
+
+	return findings
+}
+
+// weakPassword returns true if the password is short or well known.
+// Passwords that are templates, such as user variables, can't be
+// judged and aren't weak.
+func weakPassword(password string) bool {
+	if isTemplate(password) {
+		return false
+	}
+
+	return len(password) < minPasswordLength || weakPasswords[strings.ToLower(password)]
+}
+
+// loopbackAddress returns true if addr is a loopback IP address, which
+// only the host can connect to, or a template that can't be judged.
+func loopbackAddress(addr string) bool {
+	if isTemplate(addr) {
+		return true
+	}
+
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsLoopback()
+}
+
+func configString(config map[string]interface{}, key string) string {
+	s, _ := config[key].(string)
+	return s
+}
+
+// configBool returns the boolean value of key, which can be a string if
+// it is set with a user variable.
+func configBool(config map[string]interface{}, key string) bool {
+	switch v := config[key].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true" || isTemplate(v)
+	}
+
+	return false
+}
+
+func configStrings(config map[string]interface{}, key string) []string {
+	raw, _ := config[key].([]interface{})
+	result := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
+// isTemplate returns true if the value is a template, such as a user
+// variable, whose value isn't known until the build.
+func isTemplate(v string) bool {
+	return strings.Contains(v, "{{")
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/packer/template"
+)
+
+func TestAudit(t *testing.T) {
+	c := &AuditCommand{Meta: testMeta(t)}
+	args := []string{filepath.Join(testFixture("audit-secure"), "template.json")}
+	if code := c.Run(args); code != ExitCodeOK {
+		fatalCommand(t, c.Meta)
+	}
+
+	args = append(args, filepath.Join(testFixture("audit"), "template.json"))
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestAudit_badSyntax(t *testing.T) {
+	c := &AuditCommand{Meta: testMeta(t)}
+	args := []string{filepath.Join(testFixture("validate-bad-syntax"), "template.json")}
+	if code := c.Run(args); code != ExitCodeParseError {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestAuditTemplate(t *testing.T) {
+	tpl, err := template.ParseFile(filepath.Join(testFixture("audit"), "template.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var components []string
+	for _, f := range auditTemplate(tpl) {
+		components = append(components, f.Component)
+	}
+
+	// Weak SSH password, unpinned host key and open VNC of qemu;
+	// plaintext WinRM and a public AMI of aws
+	expected := []string{
+		"builder 'aws'", "builder 'aws'",
+		"builder 'qemu'", "builder 'qemu'", "builder 'qemu'",
+	}
+	if !reflect.DeepEqual(components, expected) {
+		t.Fatalf("bad: %#v", auditTemplate(tpl))
+	}
+}
+
+func TestAuditBuilder_qemuVNC(t *testing.T) {
+	cases := []struct {
+		Config   map[string]interface{}
+		Expected bool
+	}{
+		{map[string]interface{}{}, true},
+		{map[string]interface{}{"vnc_use_password": true}, false},
+		{map[string]interface{}{"vnc_password": "s3cr3t"}, false},
+		{map[string]interface{}{"vnc_password": ""}, true},
+		{map[string]interface{}{"vnc_bind_address": "127.0.0.1"}, false},
+		{map[string]interface{}{"display": "spice"}, false},
+	}
+
+	for _, tc := range cases {
+		tc.Config["pin_ssh_host_key"] = true
+
+		findings := auditBuilder("qemu", tc.Config)
+		if (len(findings) > 0) != tc.Expected {
+			t.Fatalf("%#v: bad: %#v", tc.Config, findings)
+		}
+	}
+}
+
+func TestWeakPassword(t *testing.T) {
+	cases := map[string]bool{
+		"vagrant":                 true,
+		"Passw0rd":                true,
+		"Password":                true,
+		"correct horse battery":   false,
+		"{{user `ssh_password`}}": false,
+	}
+
+	for password, expected := range cases {
+		if weakPassword(password) != expected {
+			t.Fatalf("%s: should be %t", password, expected)
+		}
+	}
+}
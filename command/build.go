@@ -10,6 +10,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/mitchellh/packer/helper/tracing"
 	"github.com/mitchellh/packer/packer"
 	"github.com/mitchellh/packer/template"
 )
@@ -19,13 +20,20 @@ type BuildCommand struct {
 }
 
 func (c BuildCommand) Run(args []string) int {
-	var cfgColor, cfgDebug, cfgForce, cfgParallel bool
+	var cfgColor, cfgDebug, cfgForce, cfgKeepWorkDir, cfgParallel bool
+	var cfgCaptureOutput, cfgManifest, cfgRemote string
+	var cfgPPParallelism int
 	flags := c.Meta.FlagSet("build", FlagSetBuildFilter|FlagSetVars)
 	flags.Usage = func() { c.Ui.Say(c.Help()) }
 	flags.BoolVar(&cfgColor, "color", true, "")
 	flags.BoolVar(&cfgDebug, "debug", false, "")
 	flags.BoolVar(&cfgForce, "force", false, "")
+	flags.BoolVar(&cfgKeepWorkDir, "keep-workdir", false, "")
 	flags.BoolVar(&cfgParallel, "parallel", true, "")
+	flags.StringVar(&cfgCaptureOutput, "capture-output", "", "")
+	flags.IntVar(&cfgPPParallelism, "parallel-post-processors", 1, "")
+	flags.StringVar(&cfgManifest, "manifest", "", "")
+	flags.StringVar(&cfgRemote, "remote", "", "")
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
@@ -36,22 +44,94 @@ func (c BuildCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Fetch the template if it is hosted elsewhere
+	tplPath := args[0]
+	if isTemplateSource(tplPath) {
+		if cfgRemote != "" {
+			c.Ui.Error("The -remote option can't be used with a template source.")
+			return 1
+		}
+
+		// The build runs in the directory of the source
+		paths := []*string{&cfgCaptureOutput, &cfgManifest}
+		for i := range c.Meta.flagVarFiles {
+			paths = append(paths, &c.Meta.flagVarFiles[i])
+		}
+		if err := absPaths(paths...); err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		path, cleanup, err := fetchTemplate(c.Ui, tplPath)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to fetch template: %s", err))
+			return 1
+		}
+		defer cleanup()
+		tplPath = path
+	}
+
 	// Parse the template
-	tpl, err := template.ParseFile(args[0])
+	tpl, err := template.ParseFile(tplPath)
 	if err != nil {
+		c.Ui.Machine("error-category", ErrorCategoryParse)
 		c.Ui.Error(fmt.Sprintf("Failed to parse template: %s", err))
+		return ExitCodeParseError
+	}
+
+	// Remote builds are validated and run by the agent
+	if cfgRemote != "" {
+		if cfgDebug || cfgKeepWorkDir || cfgCaptureOutput != "" || cfgManifest != "" {
+			c.Ui.Error("The -debug, -keep-workdir, -capture-output and -manifest\n" +
+				"options can't be used with -remote.")
+			return 1
+		}
+
+		vars, err := c.Meta.Vars(tpl)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		return c.runRemote(cfgRemote, args[0], tpl, &remoteBuildRequest{
+			Except:                 c.Meta.flagBuildExcept,
+			Only:                   c.Meta.flagBuildOnly,
+			Vars:                   vars,
+			Force:                  cfgForce,
+			Parallel:               cfgParallel,
+			ParallelPostProcessors: cfgPPParallelism,
+		})
+	}
+
+	// Set up tracing before the plugins start so they trace into it too
+	closeTrace, err := tracing.Setup()
+	if err != nil {
+		c.Ui.Error(err.Error())
 		return 1
 	}
+	defer closeTrace()
 
 	// Get the core
 	core, err := c.Meta.Core(tpl)
 	if err != nil {
+		c.Ui.Machine("error-category", ErrorCategoryValidation)
 		c.Ui.Error(err.Error())
-		return 1
+		return ExitCodeValidationError
 	}
 
 	// Get the builds we care about
 	buildNames := c.Meta.BuildNames(core)
+
+	// Capture the inputs of the run before anything is built
+	var manifest *packer.Manifest
+	if cfgManifest != "" {
+		key := []byte(os.Getenv("PACKER_MANIFEST_KEY"))
+		manifest, err = core.Manifest(buildNames, key)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to create manifest: %s", err))
+			return 1
+		}
+	}
 	builds := make([]packer.Build, 0, len(buildNames))
 	for _, n := range buildNames {
 		b, err := core.Build(n)
@@ -103,19 +183,18 @@ func (c BuildCommand) Run(args []string) int {
 		log.Printf("Preparing build: %s", b.Name())
 		b.SetDebug(cfgDebug)
 		b.SetForce(cfgForce)
+		b.SetCaptureOutput(cfgCaptureOutput)
+		b.SetPostProcessorParallelism(cfgPPParallelism)
+		b.SetKeepWorkDir(cfgKeepWorkDir)
 
 		warnings, err := b.Prepare()
 		if err != nil {
+			c.Ui.Machine("error-category", ErrorCategoryValidation)
 			c.Ui.Error(err.Error())
-			return 1
+			return ExitCodeValidationError
 		}
 		if len(warnings) > 0 {
-			ui := buildUis[b.Name()]
-			ui.Say(fmt.Sprintf("Warnings for build '%s':\n", b.Name()))
-			for _, warning := range warnings {
-				ui.Say(fmt.Sprintf("* %s", warning))
-			}
-			ui.Say("")
+			displayWarnings(buildUis[b.Name()], c.Ui, b.Name(), warnings)
 		}
 	}
 
@@ -130,6 +209,7 @@ func (c BuildCommand) Run(args []string) int {
 
 		// Handle interrupts for this build
 		sigCh := make(chan os.Signal, 1)
+		cancelCh := make(chan struct{})
 		signal.Notify(sigCh, os.Interrupt)
 		defer signal.Stop(sigCh)
 		go func(b packer.Build) {
@@ -137,6 +217,7 @@ func (c BuildCommand) Run(args []string) int {
 			interruptWg.Add(1)
 			defer interruptWg.Done()
 			interrupted = true
+			close(cancelCh)
 
 			log.Printf("Stopping build: %s", b.Name())
 			b.Cancel()
@@ -148,8 +229,23 @@ func (c BuildCommand) Run(args []string) int {
 			defer wg.Done()
 
 			name := b.Name()
-			log.Printf("Starting build run: %s", name)
 			ui := buildUis[name]
+
+			// Wait for a free build slot on this host, if they're limited
+			if slots := c.CoreConfig.BuildSlots; slots != nil {
+				slot, err := slots.Acquire(ui, cancelCh)
+				if err == packer.ErrBuildSlotCancelled {
+					return
+				}
+				if err != nil {
+					ui.Error(fmt.Sprintf("Build '%s' errored: %s", name, err))
+					errors[name] = err
+					return
+				}
+				defer slot.Release()
+			}
+
+			log.Printf("Starting build run: %s", name)
 			runArtifacts, err := b.Run(ui, c.Cache)
 
 			if err != nil {
@@ -190,6 +286,20 @@ func (c BuildCommand) Run(args []string) int {
 		return 1
 	}
 
+	if manifest != nil {
+		for name, err := range errors {
+			manifest.SetBuildResult(name, nil, err)
+		}
+		for name, buildArtifacts := range artifacts {
+			manifest.SetBuildResult(name, buildArtifacts, nil)
+		}
+
+		if err := manifest.WriteFile(cfgManifest); err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to write manifest: %s", err))
+			return 1
+		}
+	}
+
 	if len(errors) > 0 {
 		c.Ui.Machine("error-count", strconv.FormatInt(int64(len(errors)), 10))
 
@@ -202,13 +312,20 @@ func (c BuildCommand) Run(args []string) int {
 			}
 
 			ui.Machine("error", err.Error())
+			ui.Machine("error-category", packer.BuildErrorStage(err))
 
 			c.Ui.Error(fmt.Sprintf("--> %s: %s", name, err))
 		}
 	}
 
+	// Artifacts are shown even if the rest of the output is quiet
+	artifactUi := c.Ui
+	if quietUi, ok := c.Ui.(*packer.QuietUi); ok {
+		artifactUi = quietUi.Ui
+	}
+
 	if len(artifacts) > 0 {
-		c.Ui.Say("\n==> Builds finished. The artifacts of successful builds are:")
+		artifactUi.Say("\n==> Builds finished. The artifacts of successful builds are:")
 		for name, buildArtifacts := range artifacts {
 			// Create a UI for the machine readable stuff to be targetted
 			ui := &packer.TargettedUi{
@@ -248,16 +365,17 @@ func (c BuildCommand) Run(args []string) int {
 				}
 
 				ui.Machine("artifact", iStr, "end")
-				c.Ui.Say(message.String())
+				artifactUi.Say(message.String())
 			}
 		}
 	} else {
-		c.Ui.Say("\n==> Builds finished but no artifacts were created.")
+		artifactUi.Say("\n==> Builds finished but no artifacts were created.")
 	}
 
 	if len(errors) > 0 {
-		// If any errors occurred, exit with a non-zero exit status
-		return 1
+		// If any errors occurred, exit with a status that tells what
+		// kind of failure it was
+		return buildErrorExitCode(errors)
 	}
 
 	return 0
@@ -270,16 +388,41 @@ Usage: packer build [options] TEMPLATE
   Will execute multiple builds in parallel as defined in the template.
   The various artifacts created by the template will be outputted.
 
+  TEMPLATE can also be fetched from a URL, such as
+  https://example.com/templates/web.json, or from git, such as
+  git::https://example.com/templates.git//web.json?ref=v1.0. Relative
+  paths in it are relative to the root of the repository or URL.
+
 Options:
 
+  -capture-output=path       Write the output of each provisioner to files in this directory
   -debug                     Debug mode enabled for builds
   -force                     Force a build to continue if artifacts exist, deletes existing artifacts
+  -keep-workdir              Keep the working directory of each build for debugging
   -machine-readable          Machine-readable output
+  -manifest=path             Write a manifest of the inputs and artifacts of the builds to this file
+  -no-color                  Disable color output
+  -quiet                     Only show errors and artifacts
+  -remote=address            Run the builds on the Packer agent at this address
   -except=foo,bar,baz        Build all builds other than these
   -only=foo,bar,baz          Only build the given builds by name
   -parallel=false            Disable parallelization (on by default)
+  -parallel-post-processors=n  Run up to n post-processor sequences of a build at once
   -var 'key=value'           Variable for templates, can be used multiple times.
-  -var-file=path             JSON file containing user variables.
+  -var-file=path             JSON or YAML file containing user variables.
+
+Exit codes:
+
+  0  All builds completed successfully
+  1  Usage error, interrupted, or other failure
+  2  The template couldn't be parsed
+  3  The template or the configuration of a build is invalid
+  4  A builder failed
+  5  A provisioner failed
+  6  A post-processor failed
+
+  If builds failed in different stages, the lowest of codes 4 through 6
+  is used.
 `
 
 	return strings.TrimSpace(helpText)
@@ -0,0 +1,64 @@
+package command
+
+import (
+	"github.com/mitchellh/packer/packer"
+)
+
+// These are the exit codes of the commands, which tell apart the kinds
+// of failures so that scripts can act on them. Usage errors and other
+// failures that aren't one of these exit with ExitCodeError.
+const (
+	ExitCodeOK                 = 0
+	ExitCodeError              = 1
+	ExitCodeParseError         = 2
+	ExitCodeValidationError    = 3
+	ExitCodeBuilderError       = 4
+	ExitCodeProvisionerError   = 5
+	ExitCodePostProcessorError = 6
+	ExitCodeVerifyError        = 7
+)
+
+// These are the categories of errors reported in machine-readable
+// output. The category of a build error is the stage of the build that
+// failed.
+const (
+	ErrorCategoryParse      = "parse"
+	ErrorCategoryValidation = "validation"
+)
+
+// buildStageExitCodes are the exit codes of the stages of a build, in
+// the order that the stages run.
+var buildStageExitCodes = []struct {
+	Stage string
+	Code  int
+}{
+	{packer.BuildStageBuilder, ExitCodeBuilderError},
+	{packer.BuildStageProvisioner, ExitCodeProvisionerError},
+	{packer.BuildStageVerify, ExitCodeVerifyError},
+	{packer.BuildStagePostProcessor, ExitCodePostProcessorError},
+}
+
+// buildErrorExitCode returns the exit code for builds that failed with
+// the given errors. If builds failed in different stages, the earliest
+// stage decides the exit code.
+func buildErrorExitCode(errs map[string]error) int {
+	earliest := -1
+	for _, err := range errs {
+		stage := packer.BuildErrorStage(err)
+		for i, s := range buildStageExitCodes {
+			if s.Stage == stage && (earliest == -1 || i < earliest) {
+				earliest = i
+			}
+		}
+	}
+
+	if earliest == -1 {
+		if len(errs) > 0 {
+			return ExitCodeBuilderError
+		}
+
+		return ExitCodeOK
+	}
+
+	return buildStageExitCodes[earliest].Code
+}
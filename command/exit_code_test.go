@@ -0,0 +1,36 @@
+package command
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestBuildErrorExitCode(t *testing.T) {
+	builderErr := errors.New("builder")
+	provErr := &packer.BuildError{Stage: packer.BuildStageProvisioner, Err: builderErr}
+	ppErr := &packer.BuildError{Stage: packer.BuildStagePostProcessor, Err: builderErr}
+	verifyErr := &packer.BuildError{Stage: packer.BuildStageVerify, Err: builderErr}
+
+	cases := []struct {
+		Errors map[string]error
+		Code   int
+	}{
+		{map[string]error{}, ExitCodeOK},
+		{map[string]error{"a": builderErr}, ExitCodeBuilderError},
+		{map[string]error{"a": provErr}, ExitCodeProvisionerError},
+		{map[string]error{"a": ppErr}, ExitCodePostProcessorError},
+		{map[string]error{"a": ppErr, "b": provErr}, ExitCodeProvisionerError},
+		{map[string]error{"a": ppErr, "b": builderErr}, ExitCodeBuilderError},
+		{map[string]error{"a": verifyErr}, ExitCodeVerifyError},
+		{map[string]error{"a": ppErr, "b": verifyErr}, ExitCodeVerifyError},
+		{map[string]error{"a": verifyErr, "b": provErr}, ExitCodeProvisionerError},
+	}
+
+	for _, tc := range cases {
+		if code := buildErrorExitCode(tc.Errors); code != tc.Code {
+			t.Fatalf("%#v: bad: %d", tc.Errors, code)
+		}
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/mitchellh/packer/helper/flag-kv"
 	"github.com/mitchellh/packer/helper/flag-slice"
@@ -32,16 +35,27 @@ type Meta struct {
 	// These are set by command-line flags
 	flagBuildExcept []string
 	flagBuildOnly   []string
+	flagVarFiles    []string
 	flagVars        map[string]string
 }
 
+// autoVarFileExts are the extensions of the variable files that are
+// loaded from the directory of the template without a -var-file flag,
+// such as "prod.auto.pkrvars.yaml".
+var autoVarFileExts = []string{".json", ".yaml", ".yml"}
+
 // Core returns the core for the given template given the configured
 // CoreConfig and user variables on this Meta.
 func (m *Meta) Core(tpl *template.Template) (*packer.Core, error) {
 	// Copy the config so we don't modify it
 	config := *m.CoreConfig
 	config.Template = tpl
-	config.Variables = m.flagVars
+
+	vars, err := m.Vars(tpl)
+	if err != nil {
+		return nil, err
+	}
+	config.Variables = vars
 
 	// Init the core
 	core, err := packer.NewCore(&config)
@@ -52,6 +66,58 @@ func (m *Meta) Core(tpl *template.Template) (*packer.Core, error) {
 	return core, nil
 }
 
+// Vars returns the user variables for the given template. They are
+// layered so that later layers override earlier ones:
+//
+//   1. The *.auto.pkrvars files in the directory of the template, in
+//      lexical order of their names.
+//   2. The -var-file flags, in the order they are given.
+//   3. The -var flags, no matter where they are given.
+func (m *Meta) Vars(tpl *template.Template) (map[string]string, error) {
+	var paths []string
+	if tpl.Path != "" {
+		auto, err := autoVarFiles(filepath.Dir(tpl.Path))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, auto...)
+	}
+	paths = append(paths, m.flagVarFiles...)
+
+	result := make(map[string]string)
+	for _, path := range paths {
+		vars, err := kvflag.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range vars {
+			result[k] = v
+		}
+	}
+
+	for k, v := range m.flagVars {
+		result[k] = v
+	}
+
+	return result, nil
+}
+
+// autoVarFiles returns the variable files in dir that are loaded
+// automatically, sorted by name.
+func autoVarFiles(dir string) ([]string, error) {
+	var result []string
+	for _, ext := range autoVarFileExts {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.auto.pkrvars"+ext))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, matches...)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
 // BuildNames returns the list of builds that are in the given core
 // that we care about taking into account the only and except flags.
 func (m *Meta) BuildNames(c *packer.Core) []string {
@@ -117,7 +183,7 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 	// FlagSetVars tells us what variables to use
 	if fs&FlagSetVars != 0 {
 		f.Var((*kvflag.Flag)(&m.flagVars), "var", "")
-		f.Var((*kvflag.FlagJSON)(&m.flagVars), "var-file", "")
+		f.Var((*sliceflag.StringFlag)(&m.flagVarFiles), "var-file", "")
 	}
 
 	// Create an io.Writer that writes to our Ui properly for errors.
@@ -142,3 +208,22 @@ func (m *Meta) ValidateFlags() error {
 	// TODO
 	return nil
 }
+
+// displayWarnings shows the warnings of a build as a prominent list and
+// emits them as "warning" machine-readable messages targetted at the
+// build, so that they aren't lost when Packer is run by another tool.
+func displayWarnings(ui packer.Ui, machineUi packer.Ui, name string, warnings []string) {
+	ui.Say(fmt.Sprintf("Warnings for build '%s':\n", name))
+	for _, warning := range warnings {
+		ui.Say(fmt.Sprintf("* %s", strings.Replace(warning, "\n", "\n  ", -1)))
+	}
+	ui.Say("")
+
+	targetted := &packer.TargettedUi{
+		Target: name,
+		Ui:     machineUi,
+	}
+	for _, warning := range warnings {
+		targetted.Machine("warning", warning)
+	}
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/packer/template"
+)
+
+func TestMetaVars(t *testing.T) {
+	tpl, err := template.ParseFile(filepath.Join(testFixture("vars"), "template.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Args     []string
+		Expected map[string]string
+	}{
+		{
+			nil,
+			map[string]string{"auto": "b", "file": "b", "flag": "a"},
+		},
+
+		{
+			[]string{
+				"-var-file", filepath.Join(testFixture("vars"), "override.yml"),
+			},
+			map[string]string{"auto": "b", "file": "override", "flag": "override"},
+		},
+
+		{
+			[]string{
+				"-var", "flag=flag",
+				"-var-file", filepath.Join(testFixture("vars"), "override.yml"),
+			},
+			map[string]string{"auto": "b", "file": "override", "flag": "flag"},
+		},
+	}
+
+	for _, tc := range cases {
+		m := testMeta(t)
+		if err := m.FlagSet("test", FlagSetVars).Parse(tc.Args); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		actual, err := m.Vars(tpl)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("bad: %#v\n\n%#v", tc.Args, actual)
+		}
+	}
+}
+
+func TestMetaVars_badFile(t *testing.T) {
+	tpl, err := template.ParseFile(filepath.Join(testFixture("vars"), "template.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m := testMeta(t)
+	m.flagVarFiles = []string{filepath.Join(testFixture("vars"), "missing.json")}
+	if _, err := m.Vars(tpl); err == nil {
+		t.Fatal("should error")
+	}
+}
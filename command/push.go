@@ -117,32 +117,10 @@ func (c *PushCommand) Run(args []string) int {
 		archiveTemplateEntry: args[0],
 	}
 
-	// Determine the path we're archiving. This logic is a bit complicated
-	// as there are three possibilities:
-	//
-	//   1.) BaseDir is an absolute path, just use that.
-	//
-	//   2.) BaseDir is empty, so we use the directory of the template.
-	//
-	//   3.) BaseDir is relative, so we use the path relative to the directory
-	//       of the template.
-	//
-	path := push.BaseDir
-	if path == "" || !filepath.IsAbs(path) {
-		tplPath, err := filepath.Abs(args[0])
-		if err != nil {
-			c.Ui.Error(fmt.Sprintf("Error determining path to archive: %s", err))
-			return 1
-		}
-		tplPath = filepath.Dir(tplPath)
-		if path != "" {
-			tplPath = filepath.Join(tplPath, path)
-		}
-		path, err = filepath.Abs(tplPath)
-		if err != nil {
-			c.Ui.Error(fmt.Sprintf("Error determining path to archive: %s", err))
-			return 1
-		}
+	path, err := archiveDir(args[0], push.BaseDir)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error determining path to archive: %s", err))
+		return 1
 	}
 
 	// Find the Atlas post-processors, if possible
@@ -267,7 +245,7 @@ Options:
 
   -var 'key=value'         Variable for templates, can be used multiple times.
 
-  -var-file=path           JSON file containing user variables.
+  -var-file=path           JSON or YAML file containing user variables.
 `
 
 	return strings.TrimSpace(helpText)
@@ -331,6 +309,23 @@ func (c *PushCommand) upload(
 	return doneCh, errCh, nil
 }
 
+// archiveDir returns the directory that is archived along with the
+// template at tplPath. An absolute baseDir is used as is. Otherwise the
+// directory is baseDir relative to the directory of the template, which
+// is the directory of the template itself if baseDir is empty.
+func archiveDir(tplPath, baseDir string) (string, error) {
+	if baseDir != "" && filepath.IsAbs(baseDir) {
+		return baseDir, nil
+	}
+
+	tplPath, err := filepath.Abs(tplPath)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(filepath.Join(filepath.Dir(tplPath), baseDir))
+}
+
 type uploadOpts struct {
 	URL      string
 	Slug     string
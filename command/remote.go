@@ -0,0 +1,304 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/atlas-go/archive"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template"
+)
+
+// A remote build is a build that runs on a Packer agent ("packer agent")
+// rather than on this machine. The client sends a request made of a line
+// of JSON describing the build followed by an archive of the template and
+// its supporting files. The agent runs the build and streams back the
+// machine-readable output of it, ending with an "exit-code" message.
+
+// remoteTokenEnvVar is the environment variable with the token that
+// clients use to authenticate to agents serving HTTPS.
+const remoteTokenEnvVar = "PACKER_AGENT_TOKEN"
+
+// remoteBuildRequest describes a remote build.
+type remoteBuildRequest struct {
+	// ArchiveSize is the size of the archive that follows the request.
+	ArchiveSize int64
+
+	Except                 []string
+	Only                   []string
+	Vars                   map[string]string
+	Force                  bool
+	Parallel               bool
+	ParallelPostProcessors int
+}
+
+// runRemote runs the builds of the template at tplPath on the agent at
+// the given address, showing the output of them on the UI. It returns
+// the exit code of the remote build.
+func (c BuildCommand) runRemote(address, tplPath string, tpl *template.Template, req *remoteBuildRequest) int {
+	u, err := url.Parse(address)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error parsing remote address: %s", err))
+		return 1
+	}
+
+	// The files shipped along with the template are the same as those
+	// that would be pushed.
+	var opts archive.ArchiveOpts
+	opts.Include = tpl.Push.Include
+	opts.Exclude = tpl.Push.Exclude
+	opts.VCS = tpl.Push.VCS
+	opts.Extra = map[string]string{
+		archiveTemplateEntry: tplPath,
+	}
+
+	path, err := archiveDir(tplPath, tpl.Push.BaseDir)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error determining path to archive: %s", err))
+		return 1
+	}
+
+	a, err := archive.CreateArchive(path, &opts)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error archiving: %s", err))
+		return 1
+	}
+	defer a.Close()
+
+	body, err := remoteRequestBody(req, a)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error encoding remote build request: %s", err))
+		return 1
+	}
+
+	c.Ui.Say(fmt.Sprintf("Running builds on the remote agent: %s", u.Host))
+	conn, err := dialRemote(u, body)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error starting remote build: %s", err))
+		return 1
+	}
+	defer conn.Close()
+
+	// Handle interrupts by cancelling the remote build, which still
+	// shows its output while it cleans up.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	cancelCh := make(chan struct{})
+	go func() {
+		if _, ok := <-sigCh; ok {
+			close(cancelCh)
+			c.Ui.Error("Interrupt received. Cancelling the remote build...")
+			conn.Cancel()
+		}
+	}()
+
+	code, ok := showRemoteOutput(c.Ui, conn)
+	select {
+	case <-cancelCh:
+		c.Ui.Say("Cancelled remote build after being interrupted.")
+		return 1
+	default:
+	}
+
+	if !ok {
+		c.Ui.Error("The connection to the remote agent was lost.")
+		return 1
+	}
+
+	return code
+}
+
+// remoteRequestBody returns the body of a remote build request, which is
+// the request followed by the archive.
+func remoteRequestBody(req *remoteBuildRequest, a *archive.Archive) (io.Reader, error) {
+	req.ArchiveSize = a.Size
+	header, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.MultiReader(bytes.NewReader(header), strings.NewReader("\n"), a), nil
+}
+
+// showRemoteOutput shows the machine-readable output of a remote build
+// on the UI. It returns the exit code of the remote build and whether
+// the output contained it at all.
+func showRemoteOutput(ui packer.Ui, r io.Reader) (int, bool) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return 0, false
+		}
+
+		parts := strings.SplitN(strings.TrimRight(line, "\n"), ",", 4)
+		if len(parts) < 3 {
+			continue
+		}
+
+		target, t := parts[1], parts[2]
+		var args []string
+		if len(parts) == 4 {
+			args = strings.Split(parts[3], ",")
+		}
+		for i, v := range args {
+			v = strings.Replace(v, "\\n", "\n", -1)
+			v = strings.Replace(v, "\\r", "\r", -1)
+			args[i] = strings.Replace(v, "%!(PACKER_COMMA)", ",", -1)
+		}
+
+		switch {
+		case t == "exit-code" && target == "" && len(args) == 1:
+			code, err := strconv.Atoi(args[0])
+			if err != nil {
+				return 1, true
+			}
+
+			return code, true
+		case t == "ui" && len(args) == 2:
+			switch args[0] {
+			case "say":
+				ui.Say(args[1])
+			case "message":
+				ui.Message(args[1])
+			case "error":
+				ui.Error(args[1])
+			}
+		case target != "":
+			ui.Machine(fmt.Sprintf("%s,%s", target, t), args...)
+		default:
+			ui.Machine(t, args...)
+		}
+	}
+}
+
+// remoteConn is a connection to an agent running a remote build. Reading
+// from it returns the output of the build.
+type remoteConn interface {
+	io.ReadCloser
+
+	// Cancel asks the agent to cancel the build.
+	Cancel()
+}
+
+// dialRemote sends the remote build request in body to the agent at the
+// given URL.
+//
+// Agents are reached either over SSH, in which case "packer agent -stdio"
+// is run on the remote host, or over HTTPS.
+func dialRemote(u *url.URL, body io.Reader) (remoteConn, error) {
+	switch u.Scheme {
+	case "ssh":
+		return dialRemoteSSH(u, body)
+	case "https":
+		return dialRemoteHTTPS(u, body)
+	default:
+		return nil, fmt.Errorf("unsupported remote address scheme: %s", u.Scheme)
+	}
+}
+
+// remoteSSHConn is a connection to an agent over SSH. The agent cancels
+// the build once its stdin is closed, so stdin is held open until then.
+type remoteSSHConn struct {
+	io.ReadCloser
+
+	cmd   *exec.Cmd
+	stdin io.Closer
+}
+
+func (c *remoteSSHConn) Cancel() {
+	c.stdin.Close()
+}
+
+func (c *remoteSSHConn) Close() error {
+	c.stdin.Close()
+	c.ReadCloser.Close()
+	return c.cmd.Wait()
+}
+
+func dialRemoteSSH(u *url.URL, body io.Reader) (remoteConn, error) {
+	host := u.Host
+	args := []string{"-T"}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		host = h
+		args = append(args, "-p", port)
+	}
+	if u.User != nil {
+		host = u.User.Username() + "@" + host
+	}
+
+	// The path of the URL is the path to Packer on the remote host
+	packerPath := "packer"
+	if u.Path != "" && u.Path != "/" {
+		packerPath = u.Path
+	}
+	args = append(args, host, packerPath, "agent", "-stdio")
+
+	// SSH is started in its own process group so that interrupts are
+	// handled by cancelling the build rather than killing SSH.
+	cmd := exec.Command("ssh", args...)
+	cmd.Stderr = os.Stderr
+	setProcessGroup(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go io.Copy(stdin, body)
+
+	return &remoteSSHConn{ReadCloser: stdout, cmd: cmd, stdin: stdin}, nil
+}
+
+// remoteHTTPSConn is a connection to an agent over HTTPS. The agent
+// cancels the build if the connection is closed.
+type remoteHTTPSConn struct {
+	io.ReadCloser
+}
+
+func (c *remoteHTTPSConn) Cancel() {
+	c.Close()
+}
+
+func dialRemoteHTTPS(u *url.URL, body io.Reader) (remoteConn, error) {
+	req, err := http.NewRequest("POST", strings.TrimRight(u.String(), "/")+"/build", body)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv(remoteTokenEnvVar); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf(
+			"agent responded with %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	return &remoteHTTPSConn{ReadCloser: resp.Body}, nil
+}
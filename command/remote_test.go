@@ -0,0 +1,189 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/atlas-go/archive"
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestRemoteRequestBody(t *testing.T) {
+	tplPath := filepath.Join(testFixture("remote"), "template.json")
+	a, err := archive.CreateArchive(testFixture("remote"), &archive.ArchiveOpts{
+		Extra: map[string]string{archiveTemplateEntry: tplPath},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer a.Close()
+
+	body, err := remoteRequestBody(&remoteBuildRequest{
+		Only: []string{"foo"},
+		Vars: map[string]string{"bar": "baz"},
+	}, a)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	req, dir, err := readAgentRequest(bufio.NewReader(body))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if !reflect.DeepEqual(req.Only, []string{"foo"}) {
+		t.Fatalf("bad: %#v", req)
+	}
+
+	for _, name := range []string{archiveTemplateEntry, "script.sh", agentVarFileEntry} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+	}
+
+	vars, err := ioutil.ReadFile(filepath.Join(dir, agentVarFileEntry))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(vars) != `{"bar":"baz"}` {
+		t.Fatalf("bad: %s", vars)
+	}
+}
+
+func TestAgentBuildArgs(t *testing.T) {
+	args := agentBuildArgs(&remoteBuildRequest{
+		Except:                 []string{"foo", "bar"},
+		Vars:                   map[string]string{"baz": "qux"},
+		Force:                  true,
+		ParallelPostProcessors: 2,
+	})
+
+	expected := []string{
+		"build",
+		"-machine-readable",
+		"-force",
+		"-parallel=false",
+		"-parallel-post-processors=2",
+		"-except=foo,bar",
+		"-var-file=" + agentVarFileEntry,
+		archiveTemplateEntry,
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
+
+func TestShowRemoteOutput(t *testing.T) {
+	output := strings.Join([]string{
+		"1,,ui,say,==> foo: Building%!(PACKER_COMMA) please wait",
+		"1,,ui,error,bad\\nthings",
+		"1,foo,artifact-count,1",
+		"1,,exit-code,4",
+		"1,,ui,say,ignored",
+	}, "\n") + "\n"
+
+	ui := new(remoteTestUi)
+	code, ok := showRemoteOutput(ui, strings.NewReader(output))
+	if !ok || code != 4 {
+		t.Fatalf("bad: %d %t", code, ok)
+	}
+
+	expected := []string{
+		"say: ==> foo: Building, please wait",
+		"error: bad\nthings",
+		"machine: foo,artifact-count [1]",
+	}
+	if !reflect.DeepEqual(ui.Lines, expected) {
+		t.Fatalf("bad: %#v", ui.Lines)
+	}
+}
+
+func TestShowRemoteOutput_lost(t *testing.T) {
+	_, ok := showRemoteOutput(new(remoteTestUi), strings.NewReader("1,,ui,say,foo\n"))
+	if ok {
+		t.Fatal("should not have an exit code")
+	}
+}
+
+func TestAgentHandler_auth(t *testing.T) {
+	server := httptest.NewServer(&agentHandler{Token: "foo"})
+	defer server.Close()
+
+	req, err := http.NewRequest("POST", server.URL, strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer bar")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("bad: %d", resp.StatusCode)
+	}
+}
+
+func TestExtractArchive_invalidPath(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	f, err := os.Create(filepath.Join(td, "evil"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+
+	empty := filepath.Join(td, "empty")
+	if err := os.Mkdir(empty, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	a, err := archive.CreateArchive(empty, &archive.ArchiveOpts{
+		Extra: map[string]string{"../evil": f.Name()},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer a.Close()
+
+	if err := extractArchive(a, filepath.Join(td, "out")); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+// remoteTestUi is a UI that records what is shown on it.
+type remoteTestUi struct {
+	packer.BasicUi
+	Lines []string
+}
+
+func (u *remoteTestUi) Say(message string) {
+	u.Lines = append(u.Lines, "say: "+message)
+}
+
+func (u *remoteTestUi) Message(message string) {
+	u.Lines = append(u.Lines, "message: "+message)
+}
+
+func (u *remoteTestUi) Error(message string) {
+	u.Lines = append(u.Lines, "error: "+message)
+}
+
+func (u *remoteTestUi) Machine(t string, args ...string) {
+	u.Lines = append(u.Lines, fmt.Sprintf("machine: %s %v", t, args))
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template"
+)
+
+type SchemaCommand struct {
+	Meta
+}
+
+func (c *SchemaCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("schema", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 0 {
+		flags.Usage()
+		return 1
+	}
+
+	keys := make([]string, 0, len(c.CoreConfig.Plugins))
+	for k := range c.CoreConfig.Plugins {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Ask each of the installed builders and provisioners for the schema
+	// of its configuration. Components that fail to load are still
+	// listed, so that templates using them validate.
+	components := c.CoreConfig.Components
+	builders := make(map[string]map[string]interface{})
+	provisioners := make(map[string]map[string]interface{})
+	for _, k := range keys {
+		parts := strings.SplitN(k, ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		var component interface{}
+		var err error
+		switch parts[0] {
+		case "builder":
+			builders[parts[1]] = nil
+			if components.Builder != nil {
+				component, err = components.Builder(parts[1])
+			}
+		case "provisioner":
+			provisioners[parts[1]] = nil
+			if components.Provisioner != nil {
+				component, err = components.Provisioner(parts[1])
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error loading %s %s: %s", parts[0], parts[1], err))
+			continue
+		}
+
+		s, ok := component.(packer.ConfigSchemer)
+		if !ok {
+			continue
+		}
+
+		switch parts[0] {
+		case "builder":
+			builders[parts[1]] = s.ConfigSchema()
+		case "provisioner":
+			provisioners[parts[1]] = s.ConfigSchema()
+		}
+	}
+
+	raw, err := json.MarshalIndent(template.Schema(builders, provisioners), "", "  ")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error encoding schema: %s", err))
+		return 1
+	}
+
+	c.Ui.Say(string(raw))
+	return 0
+}
+
+func (*SchemaCommand) Help() string {
+	helpText := `
+Usage: packer schema
+
+  Outputs a JSON Schema that describes the format of templates, including
+  the configuration of the installed builders and provisioners. It can be
+  used by editors for autocompletion or to validate templates with other
+  tools.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*SchemaCommand) Synopsis() string {
+	return "outputs a JSON Schema for templates"
+}
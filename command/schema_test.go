@@ -0,0 +1,40 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestSchemaCommand_implements(t *testing.T) {
+	var _ cli.Command = &SchemaCommand{}
+}
+
+func TestSchemaCommand(t *testing.T) {
+	c := &SchemaCommand{
+		Meta: testMeta(t),
+	}
+	c.CoreConfig.Plugins = map[string]string{
+		"builder.test":           "/bin/packer-builder-test",
+		"post-processor.ignored": "/bin/packer-post-processor-ignored",
+	}
+
+	if code := c.Run(nil); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	out := c.Ui.(*packer.BasicUi).Writer.(*bytes.Buffer)
+	var schema map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatalf("err: %s\n\n%s", err, out.String())
+	}
+
+	builders := schema["properties"].(map[string]interface{})["builders"].(map[string]interface{})
+	oneOf := builders["items"].(map[string]interface{})["oneOf"].([]interface{})
+	if len(oneOf) != 1 {
+		t.Fatalf("bad: %#v", oneOf)
+	}
+}
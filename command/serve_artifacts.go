@@ -0,0 +1,312 @@
+package command
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+type ServeArtifactsCommand struct {
+	Meta
+}
+
+func (c *ServeArtifactsCommand) Run(args []string) int {
+	var address string
+	var duration time.Duration
+	var public bool
+
+	f := c.Meta.FlagSet("serve-artifacts", FlagSetNone)
+	f.Usage = func() { c.Ui.Error(c.Help()) }
+	f.StringVar(&address, "address", "127.0.0.1:8080", "address")
+	f.DurationVar(&duration, "duration", time.Hour, "duration")
+	f.BoolVar(&public, "public", false, "public")
+	if err := f.Parse(args); err != nil {
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) != 1 {
+		f.Usage()
+		return 1
+	}
+
+	// The artifacts are served without any authentication, so only
+	// serve them beyond this machine when asked to.
+	if !public && !isLoopbackAddress(address) {
+		c.Ui.Error(fmt.Sprintf(
+			"The address %q isn't a loopback address. The artifacts are served\n"+
+				"without authentication, so -public must be set to serve them\n"+
+				"on other interfaces.", address))
+		return 1
+	}
+
+	m, err := packer.ReadManifest(args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to read manifest: %s", err))
+		return 1
+	}
+
+	c.Ui.Say("Computing the checksums of the artifact files...")
+	server, err := newArtifactServer(m)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error listening: %s", err))
+		return 1
+	}
+	defer ln.Close()
+
+	c.Ui.Machine("serve-address", ln.Addr().String())
+	c.Ui.Say(fmt.Sprintf(
+		"Serving %d artifact file(s) on http://%s/", len(server.paths), ln.Addr()))
+	c.Ui.Message("The index is at /index.json and the checksums are at /SHA256SUMS.")
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- http.Serve(ln, server)
+	}()
+
+	var timeoutCh <-chan time.Time
+	if duration > 0 {
+		c.Ui.Message(fmt.Sprintf("The artifacts will be served for %s.", duration))
+		timeoutCh = time.After(duration)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	select {
+	case err := <-errCh:
+		c.Ui.Error(fmt.Sprintf("Error serving: %s", err))
+		return 1
+	case <-sigCh:
+		c.Ui.Say("Interrupted, no longer serving artifacts.")
+	case <-timeoutCh:
+		c.Ui.Say("No longer serving artifacts.")
+	}
+
+	return 0
+}
+
+func (*ServeArtifactsCommand) Help() string {
+	helpText := `
+Usage: packer serve-artifacts [options] MANIFEST
+
+  Serves the files of the artifacts recorded in a manifest over HTTP, so
+  that jobs on other machines can fetch them. Manifests are written by
+  "packer build -manifest". Relative paths of files in the manifest are
+  relative to the current directory.
+
+  An index of the artifacts and their files, with the size and SHA256
+  checksum of each file, is served as JSON at /index.json. The checksums
+  are also served at /SHA256SUMS in the format of sha256sum.
+
+Options:
+
+  -address=addr            The address to serve on. Defaults to
+                           "127.0.0.1:8080".
+
+  -public                  Allow serving on an address other than a
+                           loopback address, such as ":8080". The files
+                           are served without authentication.
+
+  -duration=1h             How long to serve the artifacts for. Defaults
+                           to an hour. Zero serves them until interrupted.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*ServeArtifactsCommand) Synopsis() string {
+	return "serve the artifacts of a build over HTTP"
+}
+
+// artifactIndex is the JSON index of the artifacts that are served.
+type artifactIndex struct {
+	Builds []*artifactIndexBuild `json:"builds"`
+}
+
+type artifactIndexBuild struct {
+	Name      string                   `json:"name"`
+	Type      string                   `json:"type"`
+	Artifacts []*artifactIndexArtifact `json:"artifacts"`
+	Error     string                   `json:"error,omitempty"`
+}
+
+type artifactIndexArtifact struct {
+	BuilderId string               `json:"builder_id"`
+	Id        string               `json:"id"`
+	Files     []*artifactIndexFile `json:"files"`
+}
+
+type artifactIndexFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	URL    string `json:"url"`
+}
+
+// artifactServer is the HTTP handler that serves artifact files. Files
+// are served at /files/BUILD/ARTIFACT/FILE/NAME, where ARTIFACT and FILE
+// are the indexes of the artifact within the build and of the file
+// within the artifact.
+type artifactServer struct {
+	index []byte
+	sums  []byte
+
+	// paths maps the URL path of each file to its path on disk
+	paths map[string]string
+}
+
+// newArtifactServer returns a server for the artifacts in the manifest,
+// computing the checksums of their files.
+func newArtifactServer(m *packer.Manifest) (*artifactServer, error) {
+	server := &artifactServer{paths: make(map[string]string)}
+
+	var index artifactIndex
+	var sums bytes.Buffer
+	for _, b := range m.Builds {
+		ib := &artifactIndexBuild{
+			Name:      b.Name,
+			Type:      b.Type,
+			Artifacts: make([]*artifactIndexArtifact, 0, len(b.Artifacts)),
+			Error:     b.Error,
+		}
+		index.Builds = append(index.Builds, ib)
+
+		for ai, a := range b.Artifacts {
+			ia := &artifactIndexArtifact{
+				BuilderId: a.BuilderId,
+				Id:        a.Id,
+				Files:     make([]*artifactIndexFile, 0, len(a.Files)),
+			}
+			ib.Artifacts = append(ib.Artifacts, ia)
+
+			for fi, file := range a.Files {
+				info, err := os.Stat(file)
+				if err != nil {
+					return nil, fmt.Errorf("Error reading artifact file: %s", err)
+				}
+				if !info.Mode().IsRegular() {
+					return nil, fmt.Errorf("Artifact file is not a regular file: %s", file)
+				}
+
+				sum, err := artifactFileSHA256(file)
+				if err != nil {
+					return nil, fmt.Errorf("Error reading artifact file: %s", err)
+				}
+
+				name := filepath.Base(file)
+				urlPath := path.Join(
+					"/files", b.Name, strconv.Itoa(ai), strconv.Itoa(fi), name)
+				server.paths[urlPath] = file
+
+				ia.Files = append(ia.Files, &artifactIndexFile{
+					Name:   name,
+					Size:   info.Size(),
+					SHA256: sum,
+					URL:    (&url.URL{Path: urlPath}).String(),
+				})
+				fmt.Fprintf(&sums, "%s  %s\n", sum, strings.TrimPrefix(urlPath, "/"))
+			}
+		}
+	}
+
+	raw, err := json.MarshalIndent(&index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	server.index = append(raw, '\n')
+	server.sums = sums.Bytes()
+	return server, nil
+}
+
+func (s *artifactServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/", "/index.json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.index)
+		return
+	case "/SHA256SUMS":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(s.sums)
+		return
+	}
+
+	file, ok := s.paths[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// isLoopbackAddress returns true if the host of the address is a
+// loopback address, so that only this machine can connect to it.
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func artifactFileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,169 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func testArtifactServer(t *testing.T) (*httptest.Server, string) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	file := filepath.Join(td, "disk.img")
+	if err := ioutil.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	server, err := newArtifactServer(&packer.Manifest{
+		Builds: []*packer.ManifestBuild{
+			&packer.ManifestBuild{
+				Name: "foo",
+				Type: "test",
+				Artifacts: []*packer.ManifestArtifact{
+					&packer.ManifestArtifact{BuilderId: "bid", Id: "id", Files: []string{file}},
+				},
+			},
+			&packer.ManifestBuild{Name: "bar", Type: "test", Error: "failed"},
+		},
+	})
+	if err != nil {
+		os.RemoveAll(td)
+		t.Fatalf("err: %s", err)
+	}
+
+	return httptest.NewServer(server), td
+}
+
+func testArtifactServerGet(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+// The SHA256 checksum of "hello"
+const testArtifactSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestArtifactServer(t *testing.T) {
+	ts, td := testArtifactServer(t)
+	defer os.RemoveAll(td)
+	defer ts.Close()
+
+	code, body := testArtifactServerGet(t, ts.URL+"/index.json")
+	if code != http.StatusOK {
+		t.Fatalf("bad: %d", code)
+	}
+
+	var index artifactIndex
+	if err := json.Unmarshal([]byte(body), &index); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(index.Builds) != 2 || index.Builds[1].Error != "failed" {
+		t.Fatalf("bad: %#v", index.Builds)
+	}
+
+	files := index.Builds[0].Artifacts[0].Files
+	if len(files) != 1 {
+		t.Fatalf("bad: %#v", files)
+	}
+	expected := &artifactIndexFile{
+		Name:   "disk.img",
+		Size:   5,
+		SHA256: testArtifactSHA256,
+		URL:    "/files/foo/0/0/disk.img",
+	}
+	if *files[0] != *expected {
+		t.Fatalf("bad: %#v", files[0])
+	}
+
+	code, body = testArtifactServerGet(t, ts.URL+files[0].URL)
+	if code != http.StatusOK || body != "hello" {
+		t.Fatalf("bad: %d %q", code, body)
+	}
+
+	code, body = testArtifactServerGet(t, ts.URL+"/SHA256SUMS")
+	if code != http.StatusOK || body != fmt.Sprintf("%s  files/foo/0/0/disk.img\n", testArtifactSHA256) {
+		t.Fatalf("bad: %q", body)
+	}
+
+	code, _ = testArtifactServerGet(t, ts.URL+"/files/foo/0/1/disk.img")
+	if code != http.StatusNotFound {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestNewArtifactServer_missingFile(t *testing.T) {
+	_, err := newArtifactServer(&packer.Manifest{
+		Builds: []*packer.ManifestBuild{
+			&packer.ManifestBuild{
+				Name: "foo",
+				Artifacts: []*packer.ManifestArtifact{
+					&packer.ManifestArtifact{Files: []string{"/i/dont/exist"}},
+				},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestServeArtifacts_noManifest(t *testing.T) {
+	c := &ServeArtifactsCommand{Meta: testMeta(t)}
+	if code := c.Run([]string{"/i/dont/exist"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestServeArtifacts_publicAddress(t *testing.T) {
+	c := &ServeArtifactsCommand{Meta: testMeta(t)}
+	if code := c.Run([]string{"-address", ":8080", "/i/dont/exist"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+
+	out := c.Ui.(*packer.BasicUi).ErrorWriter.(*bytes.Buffer).String()
+	if !strings.Contains(out, "-public") {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestIsLoopbackAddress(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1:8080": true,
+		"127.0.0.2:0":    true,
+		"[::1]:8080":     true,
+		"localhost:8080": true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"[::]:8080":      false,
+		"10.0.0.1:8080":  false,
+		"example.com:80": false,
+		"127.0.0.1":      false,
+	}
+
+	for address, expected := range cases {
+		if actual := isLoopbackAddress(address); actual != expected {
+			t.Fatalf("%s: expected %t, got %t", address, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+// Templates can be given as a source rather than a local path, so that
+// canonical templates can be built without a checkout of them:
+//
+//   git::URL[//PATH][?ref=REF]  - PATH within the repository at URL, checked
+//                                 out at REF. PATH defaults to template.json.
+//   https://HOST/DIR/TEMPLATE   - A template, along with the files that it
+//                                 references within DIR.
+//   https://HOST/ARCHIVE.tar.gz[//PATH]
+//                               - PATH within a gzipped tar archive. PATH
+//                                 defaults to template.json.
+//
+// The source is fetched into a temporary directory, which is the working
+// directory of the build. Relative paths in the template are then relative
+// to the root of the source, the same as they are relative to the working
+// directory for local templates, without changing the template.
+
+// templateSourceDefaultName is the template within a repository or
+// archive if the source doesn't give one.
+const templateSourceDefaultName = "template.json"
+
+// templateFileKeys are the configuration keys whose values are paths of
+// local files that are part of the template, such as scripts.
+var templateFileKeys = map[string]bool{
+	"boot_command_file": true,
+	"floppy_files":      true,
+	"hiera_config_path": true,
+	"inventory_file":    true,
+	"manifest_file":     true,
+	"playbook_file":     true,
+	"script":            true,
+	"scripts":           true,
+	"source_path":       true,
+	"user_data_file":    true,
+	"vmx_template_path": true,
+}
+
+// templateDirKeys are the configuration keys whose values are paths of
+// local directories that are part of the template. They can't be fetched
+// from plain HTTP sources since the directories can't be listed.
+var templateDirKeys = map[string]bool{
+	"data_bags_path":    true,
+	"environments_path": true,
+	"http_directory":    true,
+	"manifest_dir":      true,
+	"module_paths":      true,
+	"playbook_dir":      true,
+	"roles_path":        true,
+}
+
+// isTemplateSource reports whether the template argument is a source to
+// fetch rather than a local path.
+func isTemplateSource(src string) bool {
+	return strings.HasPrefix(src, "git::") ||
+		strings.HasPrefix(src, "http://") ||
+		strings.HasPrefix(src, "https://")
+}
+
+// fetchTemplate fetches the template source into a temporary directory
+// and changes the working directory to it. It returns the path of the
+// template within it and a function that changes the working directory
+// back and removes the directory. Relative paths of the command line have
+// to be made absolute with absPaths first.
+func fetchTemplate(ui packer.Ui, src string) (string, func(), error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "packer-template")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+
+	var tplPath string
+	var fetch func(string) error
+	switch {
+	case strings.HasPrefix(src, "git::"):
+		tplPath, err = fetchTemplateGit(ui, strings.TrimPrefix(src, "git::"), dir)
+	case strings.Contains(src, ".tar.gz") || strings.Contains(src, ".tgz"):
+		tplPath, err = fetchTemplateArchive(ui, src, dir)
+	default:
+		tplPath, fetch, err = fetchTemplateHTTP(ui, src, dir)
+	}
+	if err == nil && fetch != nil {
+		err = fetchTemplateFiles(tplPath, dir, fetch)
+	}
+	if err == nil {
+		err = os.Chdir(dir)
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return tplPath, cleanup, nil
+}
+
+// absPaths makes the relative paths absolute within the current working
+// directory, such as those of the flags of a command before the working
+// directory changes to the source of the template.
+func absPaths(paths ...*string) error {
+	for _, p := range paths {
+		if *p == "" || filepath.IsAbs(*p) {
+			continue
+		}
+
+		abs, err := filepath.Abs(*p)
+		if err != nil {
+			return err
+		}
+		*p = abs
+	}
+
+	return nil
+}
+
+// splitTemplateSource splits the path within the source, after a "//"
+// that isn't part of the scheme, from its address.
+func splitTemplateSource(src string) (string, string) {
+	start := 0
+	if i := strings.Index(src, "://"); i >= 0 {
+		start = i + 3
+	}
+
+	if i := strings.Index(src[start:], "//"); i >= 0 {
+		return src[:start+i], src[start+i+2:]
+	}
+
+	return src, ""
+}
+
+// templateSourcePath returns the path of the template within dir, making
+// sure that it doesn't leave it.
+func templateSourcePath(dir, sub string) (string, error) {
+	if sub == "" {
+		sub = templateSourceDefaultName
+	}
+
+	name := filepath.Clean(filepath.FromSlash(sub))
+	if filepath.IsAbs(name) || name == ".." ||
+		strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid template path in source: %s", sub)
+	}
+
+	return filepath.Join(dir, name), nil
+}
+
+func fetchTemplateGit(ui packer.Ui, src, dir string) (string, error) {
+	var ref string
+	if i := strings.LastIndex(src, "?"); i >= 0 {
+		q, err := url.ParseQuery(src[i+1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid git source query: %s", err)
+		}
+		ref = q.Get("ref")
+		src = src[:i]
+	}
+
+	repo, sub := splitTemplateSource(src)
+
+	// The ref comes before the "--" that ends the options of checkout,
+	// so make sure that git can't read it as an option.
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid git ref in source: %s", ref)
+	}
+
+	ui.Say(fmt.Sprintf("Cloning template repository: %s", repo))
+	if err := runGit("", "clone", "--quiet", "--", repo, dir); err != nil {
+		return "", err
+	}
+	if ref != "" {
+		ui.Message(fmt.Sprintf("Checking out %s", ref))
+		if err := runGit(dir, "checkout", "--quiet", ref, "--"); err != nil {
+			return "", err
+		}
+	}
+
+	return templateSourcePath(dir, sub)
+}
+
+func runGit(dir string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %s\n%s",
+			args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+func fetchTemplateArchive(ui packer.Ui, src, dir string) (string, error) {
+	addr, sub := splitTemplateSource(src)
+	ui.Say(fmt.Sprintf("Downloading template archive: %s", addr))
+
+	resp, err := httpGet(addr)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := extractArchive(resp.Body, dir); err != nil {
+		return "", fmt.Errorf("Error extracting template archive: %s", err)
+	}
+
+	return templateSourcePath(dir, sub)
+}
+
+// fetchTemplateHTTP downloads a single template. It also returns a
+// function that downloads the files that it references, given their
+// path relative to the directory of the template.
+func fetchTemplateHTTP(ui packer.Ui, src, dir string) (string, func(string) error, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", nil, err
+	}
+
+	tplPath := filepath.Join(dir, path.Base(u.Path))
+	ui.Say(fmt.Sprintf("Downloading template: %s", src))
+	if err := downloadFile(src, tplPath); err != nil {
+		return "", nil, err
+	}
+
+	root := *u
+	root.Path = path.Dir(u.Path)
+	root.RawQuery = ""
+	fetch := func(rel string) error {
+		file := root
+		file.Path = path.Join(root.Path, filepath.ToSlash(rel))
+		ui.Message(fmt.Sprintf("Downloading %s", rel))
+		return downloadFile(file.String(), filepath.Join(dir, rel))
+	}
+
+	return tplPath, fetch, nil
+}
+
+func httpGet(addr string) (*http.Response, error) {
+	resp, err := http.Get(addr)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error downloading %s: %s", addr, resp.Status)
+	}
+
+	return resp, nil
+}
+
+func downloadFile(addr, dst string) error {
+	resp, err := httpGet(addr)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, resp.Body)
+	return err
+}
+
+// fetchTemplateFiles fetches the files and directories that the template
+// at tplPath references by relative paths into dir, with fetch.
+func fetchTemplateFiles(tplPath, dir string, fetch func(string) error) error {
+	data, err := ioutil.ReadFile(tplPath)
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		// Leave it to the template parser to report the error
+		return nil
+	}
+
+	tplDir, err := filepath.Rel(dir, filepath.Dir(tplPath))
+	if err != nil {
+		return err
+	}
+
+	for _, c := range templateSourceConfigs(raw) {
+		err := walkTemplateConfig(c, func(key, v string) error {
+			rel, relToTemplate := templateSourceRelPath(v)
+			if rel == "" {
+				return nil
+			}
+			if relToTemplate {
+				rel = filepath.Join(tplDir, rel)
+			}
+
+			if templateDirKeys[key] {
+				return fmt.Errorf(
+					"%s can't be fetched over HTTP since directories can't be listed.\n"+
+						"Use a git source or an archive for this template.", key)
+			}
+
+			return fetch(rel)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// templateSourceRelPath returns the relative path of a file in the
+// template, and whether it is relative to {{template_dir}}. It returns an
+// empty path for values that aren't relative paths, such as absolute
+// paths, URLs and other interpolations.
+func templateSourceRelPath(v string) (string, bool) {
+	relToTemplate := false
+	for _, prefix := range []string{"{{template_dir}}/", "{{ template_dir }}/"} {
+		if strings.HasPrefix(v, prefix) {
+			v = strings.TrimPrefix(v, prefix)
+			relToTemplate = true
+			break
+		}
+	}
+
+	if v == "" || strings.Contains(v, "{{") || strings.Contains(v, "://") ||
+		filepath.IsAbs(v) || strings.HasPrefix(v, "/") {
+		return "", false
+	}
+
+	// Paths that leave the source would be fetched outside of it
+	rel := filepath.Clean(filepath.FromSlash(v))
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return rel, relToTemplate
+}
+
+// templateSourceConfigs returns the configurations of the builders,
+// provisioners (with their overrides) and post-processors of a raw
+// template.
+func templateSourceConfigs(raw map[string]interface{}) []map[string]interface{} {
+	var result []map[string]interface{}
+	add := func(v interface{}) {
+		if m, ok := v.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+
+	builders, _ := raw["builders"].([]interface{})
+	for _, b := range builders {
+		add(b)
+	}
+
+	provisioners, _ := raw["provisioners"].([]interface{})
+	for _, p := range provisioners {
+		add(p)
+		if m, ok := p.(map[string]interface{}); ok {
+			overrides, _ := m["override"].(map[string]interface{})
+			for _, o := range overrides {
+				add(o)
+			}
+		}
+	}
+
+	pps, _ := raw["post-processors"].([]interface{})
+	for _, pp := range pps {
+		if seq, ok := pp.([]interface{}); ok {
+			for _, p := range seq {
+				add(p)
+			}
+		} else {
+			add(pp)
+		}
+	}
+
+	return result
+}
+
+// walkTemplateConfig calls fn with each path of a local file or directory
+// in the configuration, stopping at the first error.
+func walkTemplateConfig(c map[string]interface{}, fn func(string, string) error) error {
+	for k, v := range c {
+		isPath := templateFileKeys[k] || templateDirKeys[k]
+
+		// The source of the file provisioner is a local file unless it
+		// downloads from the machine.
+		if k == "source" && c["type"] == "file" && c["direction"] != "download" {
+			isPath = true
+		}
+		if !isPath {
+			continue
+		}
+
+		switch v := v.(type) {
+		case string:
+			if err := fn(k, v); err != nil {
+				return err
+			}
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					if err := fn(k, s); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package command
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestSplitTemplateSource(t *testing.T) {
+	cases := []struct {
+		Input, Addr, Path string
+	}{
+		{"https://example.com/t.tar.gz", "https://example.com/t.tar.gz", ""},
+		{"https://example.com/t.tar.gz//web/t.json", "https://example.com/t.tar.gz", "web/t.json"},
+		{"git@example.com:t.git//t.json", "git@example.com:t.git", "t.json"},
+	}
+
+	for _, tc := range cases {
+		addr, path := splitTemplateSource(tc.Input)
+		if addr != tc.Addr || path != tc.Path {
+			t.Fatalf("%s: bad: %s %s", tc.Input, addr, path)
+		}
+	}
+}
+
+func TestTemplateSourcePath(t *testing.T) {
+	if _, err := templateSourcePath("/tmp", "../t.json"); err == nil {
+		t.Fatal("should error")
+	}
+
+	path, err := templateSourcePath("/tmp", "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if path != filepath.Join("/tmp", "template.json") {
+		t.Fatalf("bad: %s", path)
+	}
+}
+
+func TestTemplateSourceRelPath(t *testing.T) {
+	cases := []struct {
+		Input         string
+		Path          string
+		RelToTemplate bool
+	}{
+		{"scripts/a.sh", filepath.Join("scripts", "a.sh"), false},
+		{"{{template_dir}}/scripts/a.sh", filepath.Join("scripts", "a.sh"), true},
+		{"{{ template_dir }}/a.sh", "a.sh", true},
+		{"../a.sh", "", false},
+		{"{{template_dir}}/../a.sh", "", false},
+		{"{{template_dir}}/scripts/../../a.sh", "", false},
+		{"{{template_dir}}//etc/passwd", "", false},
+		{"/etc/passwd", "", false},
+		{"{{user `script`}}", "", false},
+		{"https://example.com/a.sh", "", false},
+	}
+
+	for _, tc := range cases {
+		path, relToTemplate := templateSourceRelPath(tc.Input)
+		if path != tc.Path || relToTemplate != tc.RelToTemplate {
+			t.Fatalf("%s: bad: %s %t", tc.Input, path, relToTemplate)
+		}
+	}
+}
+
+func TestFetchTemplate_http(t *testing.T) {
+	files := map[string]string{
+		"/t/template.json": `{
+			"builders": [{"type": "file", "disk_size": 10000000}],
+			"provisioners": [
+				{"type": "shell", "scripts": ["scripts/a.sh", "{{template_dir}}/scripts/b.sh"]},
+				{"type": "shell", "script": "/abs/c.sh"}
+			]
+		}`,
+		"/t/scripts/a.sh": "a",
+		"/t/scripts/b.sh": "b",
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer ts.Close()
+
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tplPath, cleanup, err := fetchTemplate(packer.TestUi(t), ts.URL+"/t/template.json")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer cleanup()
+
+	dir, err := filepath.EvalSymlinks(filepath.Dir(tplPath))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, name := range []string{"a.sh", "b.sh"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "scripts", name))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if string(data) != name[:1] {
+			t.Fatalf("bad: %s", data)
+		}
+	}
+
+	// The template is left alone, and the build runs in its directory
+	data, err := ioutil.ReadFile(tplPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(data) != files["/t/template.json"] {
+		t.Fatalf("bad: %s", data)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if wd != dir {
+		t.Fatalf("bad: %s", wd)
+	}
+
+	// Directories can't be fetched over plain HTTP
+	files["/t/template.json"] = `{"builders": [{"type": "qemu", "http_directory": "http"}]}`
+	if _, _, err := fetchTemplate(packer.TestUi(t), ts.URL+"/t/template.json"); err == nil {
+		t.Fatal("should error")
+	}
+
+	// Missing files are errors
+	files["/t/template.json"] = `{"provisioners": [{"type": "shell", "script": "missing.sh"}]}`
+	if _, _, err := fetchTemplate(packer.TestUi(t), ts.URL+"/t/template.json"); err == nil {
+		t.Fatal("should error")
+	}
+
+	// The working directory is changed back
+	cleanup()
+	if v, _ := os.Getwd(); v != old {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestAbsPaths(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	a, b, c := "foo.json", "/abs/bar.json", ""
+	if err := absPaths(&a, &b, &c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if a != filepath.Join(wd, "foo.json") || b != "/abs/bar.json" || c != "" {
+		t.Fatalf("bad: %s %s %s", a, b, c)
+	}
+}
@@ -28,11 +28,34 @@ func (c *ValidateCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Fetch the template if it is hosted elsewhere
+	tplPath := args[0]
+	if isTemplateSource(tplPath) {
+		// The template is validated in the directory of the source
+		var paths []*string
+		for i := range c.Meta.flagVarFiles {
+			paths = append(paths, &c.Meta.flagVarFiles[i])
+		}
+		if err := absPaths(paths...); err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		path, cleanup, err := fetchTemplate(c.Ui, tplPath)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to fetch template: %s", err))
+			return 1
+		}
+		defer cleanup()
+		tplPath = path
+	}
+
 	// Parse the template
-	tpl, err := template.ParseFile(args[0])
+	tpl, err := template.ParseFile(tplPath)
 	if err != nil {
+		c.Ui.Machine("error-category", ErrorCategoryParse)
 		c.Ui.Error(fmt.Sprintf("Failed to parse template: %s", err))
-		return 1
+		return ExitCodeParseError
 	}
 
 	// If we're only checking syntax, then we're done already
@@ -44,8 +67,9 @@ func (c *ValidateCommand) Run(args []string) int {
 	// Get the core
 	core, err := c.Meta.Core(tpl)
 	if err != nil {
+		c.Ui.Machine("error-category", ErrorCategoryValidation)
 		c.Ui.Error(err.Error())
-		return 1
+		return ExitCodeValidationError
 	}
 
 	errs := make([]error, 0)
@@ -57,10 +81,11 @@ func (c *ValidateCommand) Run(args []string) int {
 	for _, n := range buildNames {
 		b, err := core.Build(n)
 		if err != nil {
+			c.Ui.Machine("error-category", ErrorCategoryValidation)
 			c.Ui.Error(fmt.Sprintf(
 				"Failed to initialize build '%s': %s",
 				n, err))
-			return 1
+			return ExitCodeValidationError
 		}
 
 		builds = append(builds, b)
@@ -79,6 +104,7 @@ func (c *ValidateCommand) Run(args []string) int {
 	}
 
 	if len(errs) > 0 {
+		c.Ui.Machine("error-category", ErrorCategoryValidation)
 		c.Ui.Error("Template validation failed. Errors are shown below.\n")
 		for i, err := range errs {
 			c.Ui.Error(err.Error())
@@ -88,7 +114,7 @@ func (c *ValidateCommand) Run(args []string) int {
 			}
 		}
 
-		return 1
+		return ExitCodeValidationError
 	}
 
 	if len(warnings) > 0 {
@@ -96,10 +122,9 @@ func (c *ValidateCommand) Run(args []string) int {
 		c.Ui.Say("These are ONLY WARNINGS, and Packer will attempt to build the")
 		c.Ui.Say("template despite them, but they should be paid attention to.\n")
 
-		for build, warns := range warnings {
-			c.Ui.Say(fmt.Sprintf("Warnings for build '%s':\n", build))
-			for _, warning := range warns {
-				c.Ui.Say(fmt.Sprintf("* %s", warning))
+		for _, b := range builds {
+			if warns, ok := warnings[b.Name()]; ok {
+				displayWarnings(c.Ui, c.Ui, b.Name(), warns)
 			}
 		}
 
@@ -118,8 +143,8 @@ Usage: packer validate [options] TEMPLATE
   checking the configuration with the various builders, provisioners, etc.
 
   If it is not valid, the errors will be shown and the command will exit
-  with a non-zero exit status. If it is valid, it will exit with a zero
-  exit status.
+  with a non-zero exit status: 2 if the template can't be parsed, and 3
+  if it is invalid. If it is valid, it will exit with a zero exit status.
 
 Options:
 
@@ -127,7 +152,7 @@ Options:
   -except=foo,bar,baz    Validate all builds other than these
   -only=foo,bar,baz      Validate only these builds
   -var 'key=value'       Variable for templates, can be used multiple times.
-  -var-file=path         JSON file containing user variables.
+  -var-file=path         JSON or YAML file containing user variables.
 `
 
 	return strings.TrimSpace(helpText)
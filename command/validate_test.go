@@ -0,0 +1,30 @@
+package command
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	c := &ValidateCommand{Meta: testMeta(t)}
+	args := []string{filepath.Join(testFixture("validate"), "template.json")}
+	if code := c.Run(args); code != ExitCodeOK {
+		fatalCommand(t, c.Meta)
+	}
+}
+
+func TestValidate_badSyntax(t *testing.T) {
+	c := &ValidateCommand{Meta: testMeta(t)}
+	args := []string{filepath.Join(testFixture("validate-bad-syntax"), "template.json")}
+	if code := c.Run(args); code != ExitCodeParseError {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestValidate_invalid(t *testing.T) {
+	c := &ValidateCommand{Meta: testMeta(t)}
+	args := []string{filepath.Join(testFixture("validate-invalid"), "template.json")}
+	if code := c.Run(args); code != ExitCodeValidationError {
+		t.Fatalf("bad: %d", code)
+	}
+}
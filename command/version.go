@@ -3,6 +3,9 @@ package command
 import (
 	"bytes"
 	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-version"
 )
 
 // VersionCommand is a Command implementation prints the version.
@@ -13,6 +16,7 @@ type VersionCommand struct {
 	Version           string
 	VersionPrerelease string
 	CheckFunc         VersionCheckFunc
+	PluginCheckFunc   PluginCheckFunc
 }
 
 // VersionCheckFunc is the callback called by the Version command to
@@ -23,16 +27,60 @@ type VersionCheckFunc func() (VersionCheckInfo, error)
 // and tells the Version command information about the latest version
 // of Packer.
 type VersionCheckInfo struct {
+	// Checked is false if no check was made, such as when the check
+	// is disabled or Packer is offline.
+	Checked bool
+
 	Outdated bool
 	Latest   string
 	Alerts   []string
 }
 
+// PluginCheckFunc is the callback called by the Version command with
+// -check to look up the latest versions of the installed plugins that
+// report their version.
+type PluginCheckFunc func() []PluginVersionInfo
+
+// PluginVersionInfo tells the Version command the installed and latest
+// version of a plugin.
+type PluginVersionInfo struct {
+	Name     string
+	Version  string
+	Outdated bool
+	Latest   string
+}
+
 func (c *VersionCommand) Help() string {
-	return ""
+	helpText := `
+Usage: packer version [options]
+
+  Prints the Packer version. Unless checkpoint is disabled, the latest
+  version of Packer is looked up as well.
+
+Options:
+
+  -check                 Exit with a non-zero status if a newer version of
+                         Packer or of an installed plugin is available, or
+                         if the latest version couldn't be determined.
+  -min-version=VERSION   Exit with a non-zero status if this version of
+                         Packer is older than VERSION. This doesn't require
+                         network access.
+`
+
+	return strings.TrimSpace(helpText)
 }
 
 func (c *VersionCommand) Run(args []string) int {
+	var check bool
+	var minVersion string
+	flags := c.Meta.FlagSet("version", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.BoolVar(&check, "check", false, "check")
+	flags.StringVar(&minVersion, "min-version", "", "min-version")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
 	c.Ui.Machine("version", c.Version)
 	c.Ui.Machine("version-prelease", c.VersionPrerelease)
 	c.Ui.Machine("version-commit", c.Revision)
@@ -49,6 +97,14 @@ func (c *VersionCommand) Run(args []string) int {
 
 	c.Ui.Say(versionString.String())
 
+	exitCode := 0
+	if minVersion != "" {
+		if err := c.checkMinVersion(minVersion); err != nil {
+			c.Ui.Error(err.Error())
+			exitCode = 1
+		}
+	}
+
 	// If we have a version check function, then let's check for
 	// the latest version as well.
 	if c.CheckFunc != nil {
@@ -61,15 +117,74 @@ func (c *VersionCommand) Run(args []string) int {
 			c.Ui.Error(fmt.Sprintf(
 				"Error checking latest version: %s", err))
 		}
+		for _, alert := range info.Alerts {
+			c.Ui.Say(alert)
+		}
 		if info.Outdated {
 			c.Ui.Say(fmt.Sprintf(
 				"Your version of Packer is out of date! The latest version\n"+
 					"is %s. You can update by downloading from www.packer.io",
 				info.Latest))
 		}
+
+		// Plugins have to be started to learn their version, so they're
+		// only checked when asked to.
+		var plugins []PluginVersionInfo
+		if check && info.Checked && c.PluginCheckFunc != nil {
+			plugins = c.PluginCheckFunc()
+		}
+
+		pluginsOutdated := false
+		for _, p := range plugins {
+			c.Ui.Machine("plugin-version", p.Name, p.Version)
+			if p.Outdated {
+				pluginsOutdated = true
+				c.Ui.Say(fmt.Sprintf(
+					"The %s plugin is out of date! Version %s is installed,\n"+
+						"and the latest version is %s.",
+					p.Name, p.Version, p.Latest))
+			}
+		}
+
+		if check && (err != nil || !info.Checked) {
+			c.Ui.Error("The latest version of Packer couldn't be determined.\n" +
+				"The version check may be disabled or Packer may be offline.")
+			exitCode = 1
+		} else if check && (info.Outdated || pluginsOutdated) {
+			exitCode = 1
+		}
+	} else if check {
+		c.Ui.Error("Version checking is not available.")
+		exitCode = 1
+	}
+
+	return exitCode
+}
+
+// checkMinVersion returns an error if the running version is older
+// than the given version.
+func (c *VersionCommand) checkMinVersion(raw string) error {
+	min, err := version.NewVersion(raw)
+	if err != nil {
+		return fmt.Errorf("Invalid min-version %q: %s", raw, err)
+	}
+
+	current := c.Version
+	if c.VersionPrerelease != "" {
+		current += "-" + c.VersionPrerelease
+	}
+	v, err := version.NewVersion(current)
+	if err != nil {
+		return fmt.Errorf("Invalid Packer version %q: %s", current, err)
+	}
+
+	if v.LessThan(min) {
+		return fmt.Errorf(
+			"Packer v%s is older than the required minimum version %s.",
+			current, raw)
 	}
 
-	return 0
+	return nil
 }
 
 func (c *VersionCommand) Synopsis() string {
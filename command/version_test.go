@@ -9,3 +9,86 @@ import (
 func TestVersionCommand_implements(t *testing.T) {
 	var _ cli.Command = &VersionCommand{}
 }
+
+func TestVersionCommand_minVersion(t *testing.T) {
+	c := &VersionCommand{
+		Meta:    testMeta(t),
+		Version: "0.8.0",
+	}
+
+	if code := c.Run([]string{"-min-version=0.7.5"}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	if code := c.Run([]string{"-min-version=0.9.0"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+
+	if code := c.Run([]string{"-min-version=bad"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestVersionCommand_check(t *testing.T) {
+	var info VersionCheckInfo
+	var plugins []PluginVersionInfo
+	pluginsChecked := false
+	c := &VersionCommand{
+		Meta:    testMeta(t),
+		Version: "0.8.0",
+		CheckFunc: func() (VersionCheckInfo, error) {
+			return info, nil
+		},
+		PluginCheckFunc: func() []PluginVersionInfo {
+			pluginsChecked = true
+			return plugins
+		},
+	}
+
+	// Not checked, such as when offline
+	if code := c.Run([]string{"-check"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+
+	// Without -check the result doesn't matter
+	if code := c.Run(nil); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	// Up to date
+	info = VersionCheckInfo{Checked: true, Latest: "0.8.0"}
+	if code := c.Run([]string{"-check"}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	// Outdated
+	info = VersionCheckInfo{Checked: true, Outdated: true, Latest: "0.9.0"}
+	if code := c.Run([]string{"-check"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+
+	// Up to date plugin
+	info = VersionCheckInfo{Checked: true, Latest: "0.8.0"}
+	plugins = []PluginVersionInfo{
+		{Name: "builder.foo", Version: "1.0.0", Latest: "1.0.0"},
+	}
+	if code := c.Run([]string{"-check"}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	// Outdated plugin
+	plugins[0].Outdated = true
+	plugins[0].Latest = "1.1.0"
+	if code := c.Run([]string{"-check"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+
+	// Plugins are only checked with -check
+	pluginsChecked = false
+	if code := c.Run(nil); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+	if pluginsChecked {
+		t.Fatal("plugins should not be checked")
+	}
+}
@@ -20,6 +20,18 @@ const OutputPrefix = "o:"
 
 func init() {
 	Commands = map[string]cli.CommandFactory{
+		"agent": func() (cli.Command, error) {
+			return &command.AgentCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"audit": func() (cli.Command, error) {
+			return &command.AuditCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"build": func() (cli.Command, error) {
 			return &command.BuildCommand{
 				Meta: *CommandMeta,
@@ -44,6 +56,18 @@ func init() {
 			}, nil
 		},
 
+		"schema": func() (cli.Command, error) {
+			return &command.SchemaCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"serve-artifacts": func() (cli.Command, error) {
+			return &command.ServeArtifactsCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"validate": func() (cli.Command, error) {
 			return &command.ValidateCommand{
 				Meta: *CommandMeta,
@@ -57,6 +81,7 @@ func init() {
 				Version:           Version,
 				VersionPrerelease: VersionPrerelease,
 				CheckFunc:         commandVersionCheck,
+				PluginCheckFunc:   commandPluginVersionCheck,
 			}, nil
 		},
 	}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/mitchellh/packer/packer"
+)
+
+// ArtifactStateData is the state key that artifacts can respond to with a
+// map[string]string of builder-specific attributes (such as the AMI ID for
+// each region) that should be made available to post-processor templates.
+const ArtifactStateData = "packer.artifact.data"
+
+// ArtifactData is the structured view of an artifact that is exposed to
+// post-processor configuration templates as `{{.Artifact}}`.
+type ArtifactData struct {
+	BuilderId string
+	Id        string
+	Files     []string
+	Data      map[string]string
+}
+
+// NewArtifactData builds the template data for the given artifact.
+// Artifacts that don't expose any extra attributes get an empty Data map
+// so that templates can safely index into it.
+func NewArtifactData(a packer.Artifact) *ArtifactData {
+	data := make(map[string]string)
+	if raw := a.State(ArtifactStateData); raw != nil {
+		// Artifacts from plugins come over RPC as a generic map, so decode
+		// the state rather than asserting on its type.
+		if err := mapstructure.Decode(raw, &data); err != nil {
+			data = make(map[string]string)
+		}
+	}
+
+	return &ArtifactData{
+		BuilderId: a.BuilderId(),
+		Id:        a.Id(),
+		Files:     a.Files(),
+		Data:      data,
+	}
+}
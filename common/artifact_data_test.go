@@ -0,0 +1,52 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestNewArtifactData(t *testing.T) {
+	a := &packer.MockArtifact{
+		IdValue:    "foo",
+		FilesValue: []string{"bar"},
+		StateValues: map[string]interface{}{
+			ArtifactStateData: map[string]string{"us-east-1": "ami-1234"},
+		},
+	}
+
+	d := NewArtifactData(a)
+	if d.BuilderId != "bid" {
+		t.Fatalf("bad: %#v", d.BuilderId)
+	}
+	if d.Id != "foo" {
+		t.Fatalf("bad: %#v", d.Id)
+	}
+	if !reflect.DeepEqual(d.Files, []string{"bar"}) {
+		t.Fatalf("bad: %#v", d.Files)
+	}
+	if d.Data["us-east-1"] != "ami-1234" {
+		t.Fatalf("bad: %#v", d.Data)
+	}
+}
+
+func TestNewArtifactData_noState(t *testing.T) {
+	d := NewArtifactData(new(packer.MockArtifact))
+	if d.Data == nil || len(d.Data) != 0 {
+		t.Fatalf("bad: %#v", d.Data)
+	}
+}
+
+func TestNewArtifactData_rpc(t *testing.T) {
+	a := testRPCArtifact(t, &packer.MockArtifact{
+		StateValues: map[string]interface{}{
+			ArtifactStateData: map[string]string{"us-east-1": "ami-1234"},
+		},
+	})
+
+	d := NewArtifactData(a)
+	if !reflect.DeepEqual(d.Data, map[string]string{"us-east-1": "ami-1234"}) {
+		t.Fatalf("bad: %#v", d.Data)
+	}
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/mitchellh/packer/packer"
+)
+
+// These are the state keys that artifacts can respond to with well-known
+// information, so that post-processors can work with the artifacts of any
+// builder rather than parsing IDs or asserting on builder-specific state.
+// Use the accessors below to read them: artifact state that comes over
+// plugin RPC loses its concrete types, so it has to be decoded.
+const (
+	// ArtifactStateImageId is the ID of the image that was created, such
+	// as an AMI ID or a Docker image ID, as a string.
+	ArtifactStateImageId = "packer.artifact.image_id"
+
+	// ArtifactStateImageFiles are the files of the artifact that are the
+	// images themselves, such as disk images, as a []string. Files() can
+	// also contain supporting files, such as machine definitions.
+	ArtifactStateImageFiles = "packer.artifact.image_files"
+
+	// ArtifactStateRegions maps each region that the image is in to the ID
+	// of the image in that region, as a map[string]string.
+	ArtifactStateRegions = "packer.artifact.regions"
+
+	// ArtifactStateChecksums maps files of the artifact to their checksum,
+	// in the form "type:value" such as "sha256:...", as a map[string]string.
+	ArtifactStateChecksums = "packer.artifact.checksums"
+)
+
+// ArtifactImageId returns the ID of the image of the artifact, and whether
+// the artifact has one.
+func ArtifactImageId(a packer.Artifact) (string, bool) {
+	id, ok := a.State(ArtifactStateImageId).(string)
+	return id, ok && id != ""
+}
+
+// ArtifactImageFiles returns the files of the artifact that are images. If
+// the artifact doesn't say which they are, all of its files are returned.
+func ArtifactImageFiles(a packer.Artifact) []string {
+	var files []string
+	raw := a.State(ArtifactStateImageFiles)
+	if raw == nil || mapstructure.Decode(raw, &files) != nil {
+		return a.Files()
+	}
+
+	return files
+}
+
+// ArtifactRegions returns the ID of the image of the artifact in each
+// region, and whether the artifact has any.
+func ArtifactRegions(a packer.Artifact) (map[string]string, bool) {
+	return artifactStringMap(a, ArtifactStateRegions)
+}
+
+// ArtifactChecksums returns the checksums of the files of the artifact,
+// and whether the artifact has any.
+func ArtifactChecksums(a packer.Artifact) (map[string]string, bool) {
+	return artifactStringMap(a, ArtifactStateChecksums)
+}
+
+func artifactStringMap(a packer.Artifact, name string) (map[string]string, bool) {
+	raw := a.State(name)
+	if raw == nil {
+		return nil, false
+	}
+
+	var result map[string]string
+	if err := mapstructure.Decode(raw, &result); err != nil || len(result) == 0 {
+		return nil, false
+	}
+
+	return result, true
+}
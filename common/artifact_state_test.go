@@ -0,0 +1,170 @@
+package common
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/packer/rpc"
+)
+
+// testRPCArtifact serves the artifact over plugin RPC and returns the
+// client side of it, the way post-processors see the artifacts of
+// builders.
+func testRPCArtifact(t *testing.T, a packer.Artifact) packer.Artifact {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer l.Close()
+
+	connCh := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(connCh)
+			return
+		}
+		connCh <- conn
+	}()
+
+	clientConn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	serverConn, ok := <-connCh
+	if !ok {
+		t.Fatal("failed to accept connection")
+	}
+
+	server := rpc.NewServer(serverConn)
+	server.RegisterArtifact(a)
+	go server.Serve()
+
+	client, err := rpc.NewClient(clientConn)
+	if err != nil {
+		server.Close()
+		t.Fatalf("err: %s", err)
+	}
+
+	return client.Artifact()
+}
+
+func TestArtifactImageId(t *testing.T) {
+	a := &packer.MockArtifact{
+		StateValues: map[string]interface{}{
+			ArtifactStateImageId: "ami-1234",
+		},
+	}
+
+	id, ok := ArtifactImageId(a)
+	if !ok || id != "ami-1234" {
+		t.Fatalf("bad: %#v %#v", id, ok)
+	}
+
+	if _, ok := ArtifactImageId(new(packer.MockArtifact)); ok {
+		t.Fatal("should not have an image ID")
+	}
+}
+
+func TestArtifactImageFiles(t *testing.T) {
+	a := &packer.MockArtifact{
+		FilesValue: []string{"disk.qcow2", "machine.xml"},
+		StateValues: map[string]interface{}{
+			ArtifactStateImageFiles: []string{"disk.qcow2"},
+		},
+	}
+
+	files := ArtifactImageFiles(a)
+	if !reflect.DeepEqual(files, []string{"disk.qcow2"}) {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	// Without the state, all files are images
+	a.StateValues = nil
+	files = ArtifactImageFiles(a)
+	if !reflect.DeepEqual(files, []string{"disk.qcow2", "machine.xml"}) {
+		t.Fatalf("bad: %#v", files)
+	}
+}
+
+func TestArtifactRegions(t *testing.T) {
+	expected := map[string]string{"us-east-1": "ami-1234"}
+	a := &packer.MockArtifact{
+		StateValues: map[string]interface{}{
+			ArtifactStateRegions: expected,
+		},
+	}
+
+	regions, ok := ArtifactRegions(a)
+	if !ok || !reflect.DeepEqual(regions, expected) {
+		t.Fatalf("bad: %#v %#v", regions, ok)
+	}
+
+	// Changing the result doesn't change the artifact
+	regions["us-west-1"] = "ami-5678"
+	if len(expected) != 1 {
+		t.Fatalf("bad: %#v", expected)
+	}
+
+	if _, ok := ArtifactRegions(new(packer.MockArtifact)); ok {
+		t.Fatal("should not have regions")
+	}
+}
+
+func TestArtifactChecksums(t *testing.T) {
+	expected := map[string]string{"disk.qcow2": "sha256:abcd"}
+	a := &packer.MockArtifact{
+		StateValues: map[string]interface{}{
+			ArtifactStateChecksums: expected,
+		},
+	}
+
+	checksums, ok := ArtifactChecksums(a)
+	if !ok || !reflect.DeepEqual(checksums, expected) {
+		t.Fatalf("bad: %#v %#v", checksums, ok)
+	}
+
+	if _, ok := ArtifactChecksums(new(packer.MockArtifact)); ok {
+		t.Fatal("should not have checksums")
+	}
+}
+
+func TestArtifactState_rpc(t *testing.T) {
+	a := testRPCArtifact(t, &packer.MockArtifact{
+		FilesValue: []string{"disk.qcow2", "machine.xml"},
+		StateValues: map[string]interface{}{
+			ArtifactStateImageId:    "ami-1234",
+			ArtifactStateImageFiles: []string{"disk.qcow2"},
+			ArtifactStateRegions: map[string]string{
+				"us-east-1": "ami-1234",
+			},
+			ArtifactStateChecksums: map[string]string{
+				"disk.qcow2": "sha256:abcd",
+			},
+		},
+	})
+
+	if id, ok := ArtifactImageId(a); !ok || id != "ami-1234" {
+		t.Fatalf("bad: %#v %#v", id, ok)
+	}
+
+	files := ArtifactImageFiles(a)
+	if !reflect.DeepEqual(files, []string{"disk.qcow2"}) {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	regions, ok := ArtifactRegions(a)
+	expected := map[string]string{"us-east-1": "ami-1234"}
+	if !ok || !reflect.DeepEqual(regions, expected) {
+		t.Fatalf("bad: %#v %#v", regions, ok)
+	}
+
+	checksums, ok := ArtifactChecksums(a)
+	expected = map[string]string{"disk.qcow2": "sha256:abcd"}
+	if !ok || !reflect.DeepEqual(checksums, expected) {
+		t.Fatalf("bad: %#v %#v", checksums, ok)
+	}
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/packer/common/yaml"
+)
+
+// BootCommandFromFile returns the boot command of a VM builder, which is
+// either given inline with boot_command or read from boot_command_file.
+// Only one of them may be set. The entries that are read are returned
+// verbatim, so they are interpolated when they are typed just like inline
+// entries.
+//
+// Files with a .yml, .yaml or .json extension must contain a list of
+// strings. Any other file has one entry per line, where blank lines and
+// lines that start with "#" are ignored.
+func BootCommandFromFile(command []string, path string) ([]string, error) {
+	if path == "" {
+		return command, nil
+	}
+	if len(command) > 0 {
+		return nil, errors.New(
+			"Only one of boot_command or boot_command_file may be specified.")
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading boot_command_file: %s", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml", ".json":
+		result, err := bootCommandList(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing boot_command_file: %s", err)
+		}
+		return result, nil
+	}
+
+	result := make([]string, 0)
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		result = append(result, line)
+	}
+
+	return result, nil
+}
+
+func bootCommandList(data string) ([]string, error) {
+	decoded, err := yaml.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if decoded == nil {
+		return []string{}, nil
+	}
+
+	list, ok := decoded.([]interface{})
+	if !ok {
+		return nil, errors.New("expected a list of boot command entries")
+	}
+
+	result := make([]string, 0, len(list))
+	for i, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("entry %d must be a string, got: %v", i+1, v)
+		}
+
+		result = append(result, s)
+	}
+
+	return result, nil
+}
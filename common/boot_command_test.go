@@ -0,0 +1,124 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testBootCommandFile(t *testing.T, name, contents string) string {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := filepath.Join(td, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return path
+}
+
+func TestBootCommandFromFile_none(t *testing.T) {
+	command := []string{"<enter>"}
+	result, err := BootCommandFromFile(command, "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(result, command) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestBootCommandFromFile_both(t *testing.T) {
+	path := testBootCommandFile(t, "boot.txt", "<enter>\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	if _, err := BootCommandFromFile([]string{"<enter>"}, path); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBootCommandFromFile_missing(t *testing.T) {
+	if _, err := BootCommandFromFile(nil, "/i/dont/exist"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBootCommandFromFile_text(t *testing.T) {
+	path := testBootCommandFile(t, "boot.txt", `# Wait for the boot menu
+<wait5><esc>
+
+  linux ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg
+<enter>`+"\r\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	result, err := BootCommandFromFile(nil, path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"<wait5><esc>",
+		"  linux ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg",
+		"<enter>",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestBootCommandFromFile_yaml(t *testing.T) {
+	path := testBootCommandFile(t, "boot.yml", `# Wait for the boot menu
+- <wait5><esc>
+- "linux ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg"
+- <enter> # start the install
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	result, err := BootCommandFromFile(nil, path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"<wait5><esc>",
+		"linux ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg",
+		"<enter>",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestBootCommandFromFile_yamlBad(t *testing.T) {
+	cases := []string{
+		"foo: bar\n",
+		"- <enter>\n- 42\n",
+	}
+
+	for _, tc := range cases {
+		path := testBootCommandFile(t, "boot.yaml", tc)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		if _, err := BootCommandFromFile(nil, path); err == nil {
+			t.Fatalf("should have error: %s", tc)
+		}
+	}
+}
+
+func TestBootCommandFromFile_json(t *testing.T) {
+	path := testBootCommandFile(t, "boot.json", `["<esc>", "<enter>"]`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	result, err := BootCommandFromFile(nil, path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(result, []string{"<esc>", "<enter>"}) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
@@ -66,15 +66,16 @@ func DownloadableURL(original string) (string, error) {
 			}
 
 			// For Windows absolute file paths, remove leading / prior to processing
-			// since net/url turns "C:/" into "/C:/"
-			if len(url.Path) > 0 && url.Path[0] == '/' {
+			// since net/url turns "C:/" into "/C:/". UNC paths, which begin
+			// with two slashes, are left alone.
+			if len(url.Path) > 0 && url.Path[0] == '/' && !strings.HasPrefix(url.Path, "//") {
 				url.Path = url.Path[1:len(url.Path)]
 			}
 		}
 
 		// Only do the filepath transformations if the file appears
 		// to actually exist.
-		if _, err := os.Stat(url.Path); err == nil {
+		if _, err := os.Stat(LongPath(url.Path)); err == nil {
 			url.Path, err = filepath.Abs(url.Path)
 			if err != nil {
 				return "", err
@@ -93,6 +94,17 @@ func DownloadableURL(original string) (string, error) {
 			// users are likely to do this but the URL should actually only
 			// contain forward slashes.
 			url.Path = strings.Replace(url.Path, `\`, `/`, -1)
+
+			// UNC paths put the server in the host of the URL, so that
+			// "\\server\share\file.iso" becomes "file://server/share/file.iso".
+			if url.Host == "" && strings.HasPrefix(url.Path, "//") {
+				parts := strings.SplitN(url.Path[2:], "/", 2)
+				url.Host = parts[0]
+				url.Path = ""
+				if len(parts) > 1 {
+					url.Path = "/" + parts[1]
+				}
+			}
 		}
 	}
 
@@ -158,3 +158,20 @@ func TestScrubConfig(t *testing.T) {
 		t.Fatalf("got %s, expected %s", conf, expect)
 	}
 }
+
+func TestDownloadableURL_UNCPaths(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("UNC paths are only supported on Windows")
+	}
+
+	for _, path := range []string{`\\server\share\foo.iso`, "//server/share/foo.iso"} {
+		u, err := DownloadableURL(path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if u != "file://server/share/foo.iso" {
+			t.Fatalf("%s: bad: %s", path, u)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// qcow2Magic is the magic at the start of every qcow2 image.
+var qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+
+// FindDiskImage returns the disk image in the files of an artifact, and
+// whether it is a "qcow2" or "raw" image. Files that aren't qcow2 are
+// only considered raw images if they have a .raw or .img extension,
+// unless the artifact has just one file.
+func FindDiskImage(files []string) (string, string, error) {
+	for _, path := range files {
+		isQcow2, err := hasMagic(path, qcow2Magic)
+		if err != nil {
+			return "", "", err
+		}
+		if isQcow2 {
+			return path, "qcow2", nil
+		}
+
+		switch filepath.Ext(path) {
+		case ".raw", ".img":
+			return path, "raw", nil
+		}
+	}
+
+	if len(files) == 1 {
+		return files[0], "raw", nil
+	}
+
+	return "", "", fmt.Errorf("No qcow2 or raw disk image found in artifact")
+}
+
+func hasMagic(path string, magic []byte) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, len(magic))
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return false, nil
+	}
+
+	return bytes.Equal(buf, magic), nil
+}
+
+// AdditionalDiskName returns the file name of the additional disk with
+// index i of a VM whose main disk is named base, such as "disk-1.vmdk"
+// for the first additional disk of "disk". Builders name additional disks
+// this way so that DiskImages lists the disks of any artifact in order.
+func AdditionalDiskName(base string, i int, ext string) string {
+	return fmt.Sprintf("%s-%d.%s", base, i+1, ext)
+}
+
+var diskImageExts = map[string]bool{
+	".img":   true,
+	".qcow2": true,
+	".raw":   true,
+	".vdi":   true,
+	".vhd":   true,
+	".vhdx":  true,
+	".vmdk":  true,
+}
+
+// vmdkExtentRe matches the extents of split and preallocated VMDK disks,
+// which are part of the disk of the descriptor they belong to.
+var vmdkExtentRe = regexp.MustCompile(`-(s|f)[0-9]{3}\.vmdk$|-flat\.vmdk$`)
+
+// DiskImages returns the disk images in the files of an artifact, in the
+// order of the disks of the VM: the main disk first, then the additional
+// disks by their number, such as "disk.vmdk", "disk-1.vmdk", "disk-2.vmdk"
+// or "vm-disk001.vmdk", "vm-disk002.vmdk" for a VirtualBox export.
+func DiskImages(files []string) []string {
+	var images []string
+	for _, f := range files {
+		if diskImageExts[strings.ToLower(filepath.Ext(f))] && !vmdkExtentRe.MatchString(f) {
+			images = append(images, f)
+		}
+	}
+
+	sort.Sort(byDiskOrder(images))
+	return images
+}
+
+// byDiskOrder sorts disks by their names without the extension, comparing
+// numbers in the names by their value, so that "disk" comes before
+// "disk-1" and "disk-2" before "disk-10".
+type byDiskOrder []string
+
+func (s byDiskOrder) Len() int      { return len(s) }
+func (s byDiskOrder) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byDiskOrder) Less(i, j int) bool {
+	a := strings.TrimSuffix(s[i], filepath.Ext(s[i]))
+	b := strings.TrimSuffix(s[j], filepath.Ext(s[j]))
+	for a != "" && b != "" {
+		ra, rb := leadingRun(a), leadingRun(b)
+		if ra != rb {
+			na, errA := strconv.ParseUint(ra, 10, 64)
+			nb, errB := strconv.ParseUint(rb, 10, 64)
+			if errA == nil && errB == nil && na != nb {
+				return na < nb
+			}
+
+			return ra < rb
+		}
+
+		a, b = a[len(ra):], b[len(rb):]
+	}
+
+	return len(a) < len(b)
+}
+
+// leadingRun returns the leading run of digits or of other characters.
+func leadingRun(s string) string {
+	digit := s[0] >= '0' && s[0] <= '9'
+	for i := 1; i < len(s); i++ {
+		if (s[i] >= '0' && s[i] <= '9') != digit {
+			return s[:i]
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,88 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindDiskImage(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	qcow2 := filepath.Join(td, "packer-qemu")
+	ioutil.WriteFile(qcow2, append(qcow2Magic, 0, 0, 0, 3), 0644)
+	raw := filepath.Join(td, "disk.raw")
+	ioutil.WriteFile(raw, []byte("disk"), 0644)
+	other := filepath.Join(td, "packer.ovf")
+	ioutil.WriteFile(other, []byte("<xml>"), 0644)
+
+	cases := []struct {
+		Files  []string
+		Path   string
+		Format string
+	}{
+		{[]string{other, qcow2}, qcow2, "qcow2"},
+		{[]string{other, raw}, raw, "raw"},
+		{[]string{other}, other, "raw"},
+	}
+
+	for _, tc := range cases {
+		path, format, err := FindDiskImage(tc.Files)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if path != tc.Path || format != tc.Format {
+			t.Fatalf("bad: %s %s", path, format)
+		}
+	}
+
+	if _, _, err := FindDiskImage([]string{other, other}); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestAdditionalDiskName(t *testing.T) {
+	if v := AdditionalDiskName("disk", 0, "vmdk"); v != "disk-1.vmdk" {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestDiskImages(t *testing.T) {
+	cases := []struct {
+		Files    []string
+		Expected []string
+	}{
+		{
+			[]string{"out/disk-1.vmdk", "out/disk-10.vmdk", "out/disk-2.vmdk", "out/disk.vmdk", "out/vm.vmx"},
+			[]string{"out/disk.vmdk", "out/disk-1.vmdk", "out/disk-2.vmdk", "out/disk-10.vmdk"},
+		},
+		{
+			[]string{"out/disk-1-s001.vmdk", "out/disk-1.vmdk", "out/disk-s001.vmdk", "out/disk.vmdk"},
+			[]string{"out/disk.vmdk", "out/disk-1.vmdk"},
+		},
+		{
+			[]string{"out/vm.ovf", "out/vm-disk002.vmdk", "out/vm-disk001.vmdk"},
+			[]string{"out/vm-disk001.vmdk", "out/vm-disk002.vmdk"},
+		},
+		{
+			[]string{"out/packer-2016-1.qcow2", "out/packer-2016.qcow2"},
+			[]string{"out/packer-2016.qcow2", "out/packer-2016-1.qcow2"},
+		},
+		{
+			[]string{"out/vm.ova"},
+			nil,
+		},
+	}
+
+	for _, tc := range cases {
+		if images := DiskImages(tc.Files); !reflect.DeepEqual(images, tc.Expected) {
+			t.Fatalf("bad: %#v", images)
+		}
+	}
+}
@@ -15,7 +15,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"runtime"
 )
 
 // DownloadConfig is the configuration given to instantiate a new
@@ -115,12 +114,7 @@ func (d *DownloadClient) Get() (string, error) {
 	// Files when we don't copy the file are special cased.
 	var finalPath string
 	if url.Scheme == "file" && !d.config.CopyFile {
-		finalPath = url.Path
-
-		// Remove forward slash on absolute Windows file URLs before processing
-		if runtime.GOOS == "windows" && finalPath[0] == '/' {
-			finalPath = finalPath[1:len(finalPath)]
-		}
+		finalPath = FileURLPath(url)
 	} else {
 		finalPath = d.config.TargetPath
 
@@ -131,7 +125,7 @@ func (d *DownloadClient) Get() (string, error) {
 		}
 
 		// Otherwise, download using the downloader.
-		f, err := os.Create(finalPath)
+		f, err := os.Create(LongPath(finalPath))
 		if err != nil {
 			return "", err
 		}
@@ -171,7 +165,7 @@ func (d *DownloadClient) VerifyChecksum(path string) (bool, error) {
 		return false, errors.New("Checksum or Hash isn't set on download.")
 	}
 
-	f, err := os.Open(path)
+	f, err := os.Open(LongPath(path))
 	if err != nil {
 		return false, err
 	}
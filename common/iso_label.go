@@ -0,0 +1,103 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	// isoDescriptorOffset is where the primary volume descriptor of an
+	// ISO 9660 image starts, after the 16 sectors of the system area.
+	isoDescriptorOffset = 16 * 2048
+
+	// The volume identifier is the label, padded with spaces.
+	isoLabelOffset = 40
+	isoLabelSize   = 32
+)
+
+// ISOLabel returns the volume label of the ISO 9660 image at path, such
+// as "Ubuntu-Server 22.04.3 LTS amd64", so that boot commands don't have
+// to hardcode labels that change between releases. The path can also be
+// an HTTP or HTTPS URL, of which only the volume descriptor is requested.
+func ISOLabel(path string) (string, error) {
+	var descriptor []byte
+	var err error
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		descriptor, err = isoDescriptorHTTP(path)
+	} else {
+		descriptor, err = isoDescriptorFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error reading the label of %s: %s", path, err)
+	}
+
+	label, err := parseISOLabel(descriptor)
+	if err != nil {
+		return "", fmt.Errorf("Error reading the label of %s: %s", path, err)
+	}
+
+	return label, nil
+}
+
+func isoDescriptorFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	descriptor := make([]byte, isoLabelOffset+isoLabelSize)
+	if _, err := f.ReadAt(descriptor, isoDescriptorOffset); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("not an ISO 9660 image")
+		}
+		return nil, err
+	}
+
+	return descriptor, nil
+}
+
+func isoDescriptorHTTP(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d",
+		isoDescriptorOffset, isoDescriptorOffset+isoLabelOffset+isoLabelSize-1))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return ioutil.ReadAll(io.LimitReader(resp.Body, isoLabelOffset+isoLabelSize))
+	case http.StatusOK:
+		// The server ignored the range, so the start is skipped
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, isoDescriptorOffset); err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(io.LimitReader(resp.Body, isoLabelOffset+isoLabelSize))
+	default:
+		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+}
+
+// parseISOLabel returns the volume identifier of a primary volume
+// descriptor, which has the type 1 and the identifier "CD001".
+func parseISOLabel(descriptor []byte) (string, error) {
+	if len(descriptor) < isoLabelOffset+isoLabelSize ||
+		descriptor[0] != 1 || !bytes.Equal(descriptor[1:6], []byte("CD001")) {
+		return "", fmt.Errorf("not an ISO 9660 image")
+	}
+
+	label := descriptor[isoLabelOffset : isoLabelOffset+isoLabelSize]
+	return strings.TrimRight(string(label), " \x00"), nil
+}
@@ -0,0 +1,81 @@
+package common
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testISO returns an image with a primary volume descriptor that has the
+// given label.
+func testISO(label string) []byte {
+	descriptor := make([]byte, 2048)
+	descriptor[0] = 1
+	copy(descriptor[1:], "CD001")
+	copy(descriptor[isoLabelOffset:], label+string(bytes.Repeat([]byte(" "), isoLabelSize-len(label))))
+
+	return append(make([]byte, isoDescriptorOffset), descriptor...)
+}
+
+func TestISOLabel(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "test.iso")
+	if err := ioutil.WriteFile(path, testISO("Ubuntu-Server 22.04.3 LTS amd64"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	label, err := ISOLabel(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if label != "Ubuntu-Server 22.04.3 LTS amd64" {
+		t.Fatalf("bad: %q", label)
+	}
+
+	// Files that are too short or aren't ISOs have no label
+	for _, data := range [][]byte{[]byte("foo"), make([]byte, 64*1024)} {
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err := ISOLabel(path); err == nil {
+			t.Fatal("should error")
+		}
+	}
+
+	if _, err := ISOLabel(filepath.Join(td, "missing.iso")); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestISOLabel_http(t *testing.T) {
+	iso := testISO("RHEL-9-2-0-BaseOS-x86_64")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/norange.iso" {
+			w.Write(iso)
+			return
+		}
+
+		http.ServeContent(w, r, "test.iso", time.Time{}, bytes.NewReader(iso))
+	}))
+	defer ts.Close()
+
+	for _, name := range []string{"test.iso", "norange.iso"} {
+		label, err := ISOLabel(ts.URL + "/" + name)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if label != "RHEL-9-2-0-BaseOS-x86_64" {
+			t.Fatalf("%s: bad: %q", name, label)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package common
 
+import "fmt"
+
 // PackerConfig is a struct that contains the configuration keys that
 // are sent by packer, properly tagged already so mapstructure can load
 // them. Embed this structure into your configuration class to get it.
@@ -8,5 +10,19 @@ type PackerConfig struct {
 	PackerBuilderType string            `mapstructure:"packer_builder_type"`
 	PackerDebug       bool              `mapstructure:"packer_debug"`
 	PackerForce       bool              `mapstructure:"packer_force"`
+	PackerGuestOS     string            `mapstructure:"packer_guest_os"`
 	PackerUserVars    map[string]string `mapstructure:"packer_user_variables"`
+	PackerWorkDir     string            `mapstructure:"packer_work_dir"`
+}
+
+// RequireUnixGuest returns an error if the machine of the build runs
+// Windows, for the named components that only work on Unix.
+func (c *PackerConfig) RequireUnixGuest(name string) error {
+	if c.PackerGuestOS == "windows" {
+		return fmt.Errorf(
+			"The %s can't be used with builds of Windows machines, which "+
+				"connect with WinRM or are for a windows/ platform", name)
+	}
+
+	return nil
 }
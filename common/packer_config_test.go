@@ -0,0 +1,17 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestPackerConfigRequireUnixGuest(t *testing.T) {
+	c := &PackerConfig{}
+	if err := c.RequireUnixGuest("shell provisioner"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.PackerGuestOS = "windows"
+	if err := c.RequireUnixGuest("shell provisioner"); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// windowsMaxPath is the length at which Windows starts refusing paths
+// that aren't in the extended-length form. It is a bit less than
+// MAX_PATH (260), since directories must leave room for an 8.3 file
+// name to be created inside them.
+const windowsMaxPath = 248
+
+// FileURLPath returns the local path of a file URL, such as one returned
+// by DownloadableURL. On Windows, URLs with a host or a path beginning
+// with two slashes are turned back into UNC paths, such as
+// \\server\share\file.iso.
+func FileURLPath(u *url.URL) string {
+	return fileURLPath(u, runtime.GOOS == "windows")
+}
+
+func fileURLPath(u *url.URL, windows bool) string {
+	if !windows {
+		return u.Path
+	}
+
+	path := u.Path
+	if u.Host != "" && u.Host != "localhost" {
+		// file://server/share/file.iso
+		path = "//" + u.Host + path
+	} else if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		// file:///C:/file.iso
+		path = path[1:]
+	}
+
+	return strings.Replace(path, "/", `\`, -1)
+}
+
+// LongPath returns a form of path that can be used with the os package
+// even if it is longer than Windows allows for regular paths. Long paths
+// are made absolute and given the extended-length prefix \\?\, or
+// \\?\UNC\ for paths on a network share. On other platforms, and for
+// paths that are short enough, the path is returned unchanged.
+//
+// The result should only be used to access the file from Packer itself,
+// since many external programs don't support extended-length paths.
+func LongPath(path string) string {
+	if runtime.GOOS != "windows" {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	if long := windowsLongPath(abs); long != abs {
+		return long
+	}
+
+	return path
+}
+
+func windowsLongPath(abs string) string {
+	if len(abs) < windowsMaxPath || strings.HasPrefix(abs, `\\?\`) {
+		return abs
+	}
+
+	switch {
+	case strings.HasPrefix(abs, `\\`):
+		return `\\?\UNC\` + abs[2:]
+	case len(abs) > 2 && abs[1] == ':' && abs[2] == '\\':
+		return `\\?\` + abs
+	default:
+		return abs
+	}
+}
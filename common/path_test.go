@@ -0,0 +1,65 @@
+package common
+
+import (
+	"net/url"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFileURLPath(t *testing.T) {
+	cases := []struct {
+		URL     string
+		Windows bool
+		Path    string
+	}{
+		{"file:///foo/bar.iso", false, "/foo/bar.iso"},
+		{"file:///C:/foo/bar.iso", true, `C:\foo\bar.iso`},
+		{"file://server/share/bar.iso", true, `\\server\share\bar.iso`},
+		{"file:////server/share/bar.iso", true, `\\server\share\bar.iso`},
+		{"file://localhost/C:/foo/bar.iso", true, `C:\foo\bar.iso`},
+	}
+
+	for _, tc := range cases {
+		u, err := url.Parse(tc.URL)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if p := fileURLPath(u, tc.Windows); p != tc.Path {
+			t.Fatalf("%s: bad: %s", tc.URL, p)
+		}
+	}
+}
+
+func TestLongPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("path is modified on Windows")
+	}
+
+	long := "/" + strings.Repeat("a", 300)
+	if p := LongPath(long); p != long {
+		t.Fatalf("bad: %s", p)
+	}
+}
+
+func TestWindowsLongPath(t *testing.T) {
+	long := strings.Repeat(`\abcdefghij`, 30)
+
+	cases := []struct {
+		Input    string
+		Expected string
+	}{
+		{`C:\foo\bar.iso`, `C:\foo\bar.iso`},
+		{`\\server\share\bar.iso`, `\\server\share\bar.iso`},
+		{`C:` + long, `\\?\C:` + long},
+		{`\\server\share` + long, `\\?\UNC\server\share` + long},
+		{`\\?\C:` + long, `\\?\C:` + long},
+	}
+
+	for _, tc := range cases {
+		if p := windowsLongPath(tc.Input); p != tc.Expected {
+			t.Fatalf("%s: bad: %s", tc.Input, p)
+		}
+	}
+}
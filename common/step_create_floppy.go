@@ -20,6 +20,10 @@ import (
 type StepCreateFloppy struct {
 	Files []string
 
+	// WorkDir is the directory the floppy image is created in. If it is
+	// empty, the default directory for temporary files is used.
+	WorkDir string
+
 	floppyPath string
 
 	FilesAdded map[string]bool
@@ -37,7 +41,7 @@ func (s *StepCreateFloppy) Run(state multistep.StateBag) multistep.StepAction {
 	ui.Say("Creating floppy disk...")
 
 	// Create a temporary file to be our floppy drive
-	floppyF, err := ioutil.TempFile("", "packer")
+	floppyF, err := ioutil.TempFile(s.WorkDir, "packer")
 	if err != nil {
 		state.Put("error",
 			fmt.Errorf("Error creating temporary file for floppy: %s", err))
@@ -124,7 +128,7 @@ func (s *StepCreateFloppy) addFilespec(dir fs.Directory, src string) error {
 		return s.addFiles(dir, matches)
 	}
 
-	finfo, err := os.Stat(src)
+	finfo, err := os.Stat(LongPath(src))
 	if err != nil {
 		return err
 	}
@@ -150,12 +154,13 @@ func (s *StepCreateFloppy) addFiles(dir fs.Directory, files []string) error {
 func (s *StepCreateFloppy) addDirectory(dir fs.Directory, src string) error {
 	log.Printf("Adding directory to floppy: %s", src)
 
+	root := LongPath(src)
 	walkFn := func(path string, finfo os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if path == src {
+		if path == root {
 			return nil
 		}
 
@@ -166,13 +171,13 @@ func (s *StepCreateFloppy) addDirectory(dir fs.Directory, src string) error {
 		return s.addSingleFile(dir, path)
 	}
 
-	return filepath.Walk(src, walkFn)
+	return filepath.Walk(root, walkFn)
 }
 
 func (s *StepCreateFloppy) addSingleFile(dir fs.Directory, src string) error {
 	log.Printf("Adding file to floppy: %s", src)
 
-	inputF, err := os.Open(src)
+	inputF, err := os.Open(LongPath(src))
 	if err != nil {
 		return err
 	}
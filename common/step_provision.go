@@ -2,6 +2,7 @@ package common
 
 import (
 	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/helper/tracing"
 	"github.com/mitchellh/packer/packer"
 	"log"
 	"time"
@@ -10,8 +11,10 @@ import (
 // StepProvision runs the provisioners.
 //
 // Uses:
+//   build_data   packer.BuildData - Optional, given to the provisioners.
 //   communicator packer.Communicator
 //   hook         packer.Hook
+//   trace_span   *tracing.Span - Optional, traces the communicator.
 //   ui           packer.Ui
 //
 // Produces:
@@ -25,16 +28,24 @@ func (s *StepProvision) Run(state multistep.StateBag) multistep.StepAction {
 	if comm == nil {
 		comm = state.Get("communicator").(packer.Communicator)
 	}
+	if span, ok := state.GetOk("trace_span"); ok {
+		comm = TraceCommunicator(comm, span.(*tracing.Span))
+	}
 
 	hook := state.Get("hook").(packer.Hook)
 	ui := state.Get("ui").(packer.Ui)
 
+	var data interface{}
+	if buildData, ok := state.GetOk("build_data"); ok {
+		data = buildData.(packer.BuildData)
+	}
+
 	// Run the provisioner in a goroutine so we can continually check
 	// for cancellations...
 	log.Println("Running the provision hook")
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- hook.Run(packer.HookProvision, ui, comm, nil)
+		errCh <- hook.Run(packer.HookProvision, ui, comm, data)
 	}()
 
 	for {
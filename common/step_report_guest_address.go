@@ -0,0 +1,94 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// StepReportGuestAddress tells the user where the guest can be reached
+// once it is running, so that it can be inspected during long installs.
+// The SSH endpoint is reported right away, and the IP address of the
+// guest as soon as GuestIP finds it, which usually takes until the guest
+// tools of the installed system are running. Both are also given as
+// machine-readable "guest-ssh-endpoint" and "guest-ip" events.
+//
+// Uses:
+//   ui packer.Ui
+//
+// Produces:
+//   <nothing>
+type StepReportGuestAddress struct {
+	// SSHEndpoint returns the host and port that SSH of the guest is
+	// reached on, or a port of zero if there is none. Optional.
+	SSHEndpoint func(multistep.StateBag) (string, int, error)
+
+	// GuestIP returns the IP address of the guest, or an empty string if
+	// it can't be found yet. It is called until it returns an address or
+	// the step is cleaned up. Optional.
+	GuestIP func(multistep.StateBag) (string, error)
+
+	// PollInterval is how often GuestIP is called. Defaults to 5 seconds.
+	PollInterval time.Duration
+
+	doneCh chan struct{}
+}
+
+func (s *StepReportGuestAddress) Run(state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+
+	if s.SSHEndpoint != nil {
+		host, port, err := s.SSHEndpoint(state)
+		if err != nil {
+			log.Printf("Error finding the SSH endpoint of the guest: %s", err)
+		} else if port != 0 {
+			endpoint := net.JoinHostPort(host, strconv.Itoa(port))
+			ui.Say(fmt.Sprintf("SSH of the guest is reachable at %s", endpoint))
+			ui.Machine("guest-ssh-endpoint", host, strconv.Itoa(port))
+		}
+	}
+
+	if s.GuestIP != nil {
+		interval := s.PollInterval
+		if interval == 0 {
+			interval = 5 * time.Second
+		}
+
+		s.doneCh = make(chan struct{})
+		go s.pollGuestIP(state, ui, interval, s.doneCh)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepReportGuestAddress) Cleanup(state multistep.StateBag) {
+	if s.doneCh != nil {
+		close(s.doneCh)
+		s.doneCh = nil
+	}
+}
+
+func (s *StepReportGuestAddress) pollGuestIP(
+	state multistep.StateBag, ui packer.Ui, interval time.Duration, doneCh <-chan struct{}) {
+	for {
+		ip, err := s.GuestIP(state)
+		if err != nil {
+			log.Printf("Error finding the IP address of the guest: %s", err)
+		} else if ip != "" {
+			ui.Message(fmt.Sprintf("The guest has IP address %s", ip))
+			ui.Machine("guest-ip", ip)
+			return
+		}
+
+		select {
+		case <-doneCh:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
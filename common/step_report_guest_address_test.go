@@ -0,0 +1,98 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// syncBuffer is a bytes.Buffer that can be written from a goroutine
+// while the test reads it.
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestStepReportGuestAddress_Impl(t *testing.T) {
+	var _ multistep.Step = new(StepReportGuestAddress)
+}
+
+func TestStepReportGuestAddress(t *testing.T) {
+	buf := new(syncBuffer)
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", &packer.MachineReadableUi{Writer: buf})
+
+	var lock sync.Mutex
+	calls := 0
+	step := &StepReportGuestAddress{
+		SSHEndpoint: func(multistep.StateBag) (string, int, error) {
+			return "127.0.0.1", 2222, nil
+		},
+		GuestIP: func(multistep.StateBag) (string, error) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			// The guest tools only report an address after a while
+			calls++
+			if calls < 3 {
+				return "", nil
+			}
+			return "10.0.2.15", nil
+		},
+		PollInterval: 10 * time.Millisecond,
+	}
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	defer step.Cleanup(state)
+
+	if !strings.Contains(buf.String(), ",guest-ssh-endpoint,127.0.0.1,2222\n") {
+		t.Fatalf("bad: %s", buf.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), ",guest-ip,10.0.2.15\n") {
+		if time.Now().After(deadline) {
+			t.Fatalf("guest IP not reported: %s", buf.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStepReportGuestAddress_noEndpoint(t *testing.T) {
+	buf := new(syncBuffer)
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", &packer.MachineReadableUi{Writer: buf})
+
+	step := &StepReportGuestAddress{
+		SSHEndpoint: func(multistep.StateBag) (string, int, error) {
+			return "", 0, nil
+		},
+	}
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	step.Cleanup(state)
+
+	if buf.String() != "" {
+		t.Fatalf("bad: %s", buf.String())
+	}
+}
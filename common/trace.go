@@ -0,0 +1,136 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/helper/tracing"
+	"github.com/mitchellh/packer/packer"
+)
+
+// TraceSteps wraps the steps of a build so that the time that each of them
+// spends running and cleaning up is traced if tracing is enabled. While a
+// step runs, its span is in the state as "trace_span" so that the work it
+// does, such as with the communicator, can be traced within it.
+func TraceSteps(build string, steps []multistep.Step) []multistep.Step {
+	tracer := tracing.Default()
+	if tracer == nil {
+		return steps
+	}
+
+	result := make([]multistep.Step, len(steps))
+	for i, step := range steps {
+		result[i] = &tracedStep{
+			Step:   step,
+			Build:  build,
+			Name:   reflect.Indirect(reflect.ValueOf(step)).Type().Name(),
+			Tracer: tracer,
+		}
+	}
+
+	return result
+}
+
+type tracedStep struct {
+	multistep.Step
+
+	Build  string
+	Name   string
+	Tracer *tracing.Tracer
+}
+
+func (s *tracedStep) Run(state multistep.StateBag) multistep.StepAction {
+	span := s.Tracer.StartInBuild("step "+s.Name, s.Build)
+	defer span.Finish()
+
+	state.Put("trace_span", span)
+	action := s.Step.Run(state)
+
+	if action == multistep.ActionHalt {
+		span.SetAttr("action", "halt")
+		if err, ok := state.GetOk("error"); ok {
+			span.SetError(err.(error))
+		}
+	} else {
+		span.SetAttr("action", "continue")
+	}
+
+	return action
+}
+
+func (s *tracedStep) Cleanup(state multistep.StateBag) {
+	span := s.Tracer.StartInBuild("cleanup "+s.Name, s.Build)
+	defer span.Finish()
+
+	s.Step.Cleanup(state)
+}
+
+// TraceCommunicator wraps a communicator so that the commands it runs
+// and the files it transfers are traced within the given span. If the
+// span is nil, the communicator is returned as is.
+func TraceCommunicator(comm packer.Communicator, span *tracing.Span) packer.Communicator {
+	if span == nil {
+		return comm
+	}
+
+	return &tracedCommunicator{Communicator: comm, Span: span}
+}
+
+type tracedCommunicator struct {
+	packer.Communicator
+
+	Span *tracing.Span
+}
+
+func (c *tracedCommunicator) Start(cmd *packer.RemoteCmd) error {
+	span := c.Span.Child("command")
+	span.SetAttr("command", cmd.Command)
+
+	if err := c.Communicator.Start(cmd); err != nil {
+		span.SetError(err)
+		span.Finish()
+		return err
+	}
+
+	go func() {
+		cmd.Wait()
+		span.SetAttr("exit_status", fmt.Sprintf("%d", cmd.ExitStatus))
+		span.Finish()
+	}()
+
+	return nil
+}
+
+func (c *tracedCommunicator) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
+	span := c.Span.Child("upload")
+	defer span.Finish()
+
+	span.SetAttr("destination", dst)
+	err := c.Communicator.Upload(dst, r, fi)
+	span.SetError(err)
+	return err
+}
+
+func (c *tracedCommunicator) UploadDir(dst string, src string, exclude []string) error {
+	span := c.Span.Child("upload directory")
+	defer span.Finish()
+
+	span.SetAttr("source", src)
+	span.SetAttr("destination", dst)
+	err := c.Communicator.UploadDir(dst, src, exclude)
+	span.SetError(err)
+	return err
+}
+
+func (c *tracedCommunicator) Download(src string, w io.Writer) error {
+	span := c.Span.Child("download")
+	defer span.Finish()
+
+	span.SetAttr("source", src)
+	err := c.Communicator.Download(src, w)
+	span.SetError(err)
+	return err
+}
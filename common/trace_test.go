@@ -0,0 +1,90 @@
+package common
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/helper/tracing"
+	"github.com/mitchellh/packer/packer"
+)
+
+type testTraceStep struct {
+	Action multistep.StepAction
+	Span   *tracing.Span
+}
+
+func (s *testTraceStep) Run(state multistep.StateBag) multistep.StepAction {
+	s.Span = state.Get("trace_span").(*tracing.Span)
+	if s.Action == multistep.ActionHalt {
+		state.Put("error", errors.New("failed"))
+	}
+
+	return s.Action
+}
+
+func (s *testTraceStep) Cleanup(multistep.StateBag) {}
+
+func TestTraceSteps_disabled(t *testing.T) {
+	steps := []multistep.Step{new(testTraceStep)}
+	if result := TraceSteps("foo", steps); result[0] != steps[0] {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestTracedStep(t *testing.T) {
+	tracer := &tracing.Tracer{TraceID: "0123456789abcdef0123456789abcdef"}
+	inner := &testTraceStep{Action: multistep.ActionHalt}
+	step := &tracedStep{
+		Step:   inner,
+		Build:  "foo",
+		Name:   "testTraceStep",
+		Tracer: tracer,
+	}
+
+	state := new(multistep.BasicStateBag)
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad: %#v", action)
+	}
+
+	span := inner.Span
+	if span == nil || span.Name != "step testTraceStep" {
+		t.Fatalf("bad: %#v", span)
+	}
+	if span.ParentID != tracing.BuildSpanID(tracer.TraceID, "foo") {
+		t.Fatalf("bad: %s", span.ParentID)
+	}
+	if !span.Failed || span.Attrs["error"] != "failed" || span.Attrs["action"] != "halt" {
+		t.Fatalf("bad: %#v", span.Attrs)
+	}
+	if span.End.IsZero() {
+		t.Fatal("span should be finished")
+	}
+}
+
+func TestTraceCommunicator(t *testing.T) {
+	comm := new(packer.MockCommunicator)
+	if TraceCommunicator(comm, nil) != comm {
+		t.Fatal("should not be wrapped")
+	}
+
+	tracer := &tracing.Tracer{TraceID: "0123456789abcdef0123456789abcdef"}
+	traced := TraceCommunicator(comm, tracer.StartBuild("foo"))
+
+	cmd := &packer.RemoteCmd{Command: "echo foo"}
+	if err := traced.Start(cmd); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd.Wait()
+	if !comm.StartCalled || comm.StartCmd.Command != "echo foo" {
+		t.Fatalf("bad: %#v", comm)
+	}
+
+	if err := traced.Upload("/foo", strings.NewReader("bar"), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if comm.UploadPath != "/foo" || comm.UploadData != "bar" {
+		t.Fatalf("bad: %#v", comm)
+	}
+}
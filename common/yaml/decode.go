@@ -0,0 +1,52 @@
+package yaml
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Decode decodes a YAML document, such as a variable or configuration
+// file. Mappings decode to map[string]interface{} rather than the
+// map[interface{}]interface{} of yaml.v2, so that the result can be used
+// like that of encoding/json.
+func Decode(data string) (interface{}, error) {
+	var result interface{}
+	if err := yaml.Unmarshal([]byte(data), &result); err != nil {
+		return nil, err
+	}
+
+	return stringKeys(result)
+}
+
+// stringKeys converts the mappings within v to map[string]interface{}.
+func stringKeys(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("the key %v must be a string, quote it", k)
+			}
+
+			var err error
+			if result[key], err = stringKeys(item); err != nil {
+				return nil, err
+			}
+		}
+
+		return result, nil
+	case []interface{}:
+		for i, item := range v {
+			var err error
+			if v[i], err = stringKeys(item); err != nil {
+				return nil, err
+			}
+		}
+
+		return v, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,112 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Output interface{}
+	}{
+		{
+			"",
+			nil,
+		},
+
+		{
+			"foo",
+			"foo",
+		},
+
+		{
+			"# comment\n---\nfoo: bar # trailing\nnum: 42\npi: 3.14\nenabled: true\nnone: ~\nurl: http://example.com/#frag\n",
+			map[string]interface{}{
+				"foo":     "bar",
+				"num":     42,
+				"pi":      3.14,
+				"enabled": true,
+				"none":    nil,
+				"url":     "http://example.com/#frag",
+			},
+		},
+
+		{
+			"quoted: \"a # b\\n\"\nsingle: 'it''s'\n\"key: x\": 1\n",
+			map[string]interface{}{
+				"quoted": "a # b\n",
+				"single": "it's",
+				"key: x": 1,
+			},
+		},
+
+		{
+			"list:\n  - a\n  - b\nsame:\n- c\n- d\nflow: [1, \"two\"]\nempty: {}\n",
+			map[string]interface{}{
+				"list":  []interface{}{"a", "b"},
+				"same":  []interface{}{"c", "d"},
+				"flow":  []interface{}{1, "two"},
+				"empty": map[string]interface{}{},
+			},
+		},
+
+		{
+			"- name: a\n  size: 1\n- name: b\n  tags:\n    - x\n- - nested\n",
+			[]interface{}{
+				map[string]interface{}{"name": "a", "size": 1},
+				map[string]interface{}{"name": "b", "tags": []interface{}{"x"}},
+				[]interface{}{"nested"},
+			},
+		},
+
+		{
+			"outer:\n  inner:\n    key: value\n  other: 1\n",
+			map[string]interface{}{
+				"outer": map[string]interface{}{
+					"inner": map[string]interface{}{"key": "value"},
+					"other": 1,
+				},
+			},
+		},
+
+		{
+			"script: |\n  #!/bin/sh\n  echo hi\n\n  exit 0\n\nstrip: |-\n  one\n  two\nfolded: >\n  one\n  two\n\n  three\nnext: 1\n",
+			map[string]interface{}{
+				"script": "#!/bin/sh\necho hi\n\nexit 0\n",
+				"strip":  "one\ntwo",
+				"folded": "one two\nthree\n",
+				"next":   1,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		actual, err := Decode(tc.Input)
+		if err != nil {
+			t.Fatalf("Input: %q\n\nerr: %s", tc.Input, err)
+		}
+
+		if !reflect.DeepEqual(actual, tc.Output) {
+			t.Fatalf("Input: %q\n\nGot: %#v", tc.Input, actual)
+		}
+	}
+}
+
+func TestDecode_errors(t *testing.T) {
+	cases := []string{
+		"foo: 1\n  bar: 2\n",
+		"\tfoo: 1\n",
+		"foo: [1,\n",
+		"foo: \"bar\n",
+		"foo: |x\n  bar\n",
+		"1: foo\n",
+		"yes: foo\n",
+	}
+
+	for _, tc := range cases {
+		if _, err := Decode(tc); err == nil {
+			t.Fatalf("Input: %q\n\nshould error", tc)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"github.com/mitchellh/packer/helper/ratelimit"
 	"github.com/mitchellh/packer/packer"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
@@ -14,9 +15,15 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 )
 
+// uploadResumeAttempts is the number of times an interrupted upload
+// is resumed before giving up.
+const uploadResumeAttempts = 5
+
 type comm struct {
 	client  *ssh.Client
 	config  *Config
@@ -36,6 +43,15 @@ type Config struct {
 
 	// Pty, if true, will request a pty from the remote end.
 	Pty bool
+
+	// UploadBandwidth is the maximum rate, in bytes per second, at which
+	// files are uploaded. Zero means no limit.
+	UploadBandwidth int64
+
+	// UploadResume, if true, will resume file uploads that fail part way
+	// through rather than failing. This requires a POSIX shell on the
+	// remote end.
+	UploadResume bool
 }
 
 // Creates a new packer.Communicator implementation over SSH. This takes
@@ -134,7 +150,96 @@ func (c *comm) Upload(path string, input io.Reader, fi *os.FileInfo) error {
 		return scpUploadFile(target_file, input, w, stdoutR, fi)
 	}
 
-	return c.scpSession("scp -vt "+target_dir, scpFunc)
+	err := c.scpSession("scp -vt "+target_dir, scpFunc)
+	if err == nil || !c.config.UploadResume {
+		return err
+	}
+
+	// Resuming requires that we can go back to where the remote
+	// file left off, so it only works with regular files.
+	rs, ok := input.(io.ReadSeeker)
+	if !ok || fi == nil || !(*fi).Mode().IsRegular() {
+		return err
+	}
+
+	for i := 0; i < uploadResumeAttempts && err != nil; i++ {
+		log.Printf("upload of '%s' failed, resuming: %s", path, err)
+		err = c.resumeUpload(path, rs, (*fi).Size())
+	}
+
+	return err
+}
+
+// resumeUpload appends the rest of src to a partially uploaded file.
+func (c *comm) resumeUpload(path string, src io.ReadSeeker, size int64) error {
+	offset, err := c.remoteSize(path)
+	if err != nil {
+		return err
+	}
+
+	command := fmt.Sprintf("cat >> %s", shellQuote(path))
+	if offset > size {
+		offset = 0
+		command = fmt.Sprintf("cat > %s", shellQuote(path))
+	}
+
+	if _, err := src.Seek(offset, 0); err != nil {
+		return err
+	}
+
+	log.Printf("resuming upload of '%s' at byte %d of %d", path, offset, size)
+	var stderr bytes.Buffer
+	cmd := &packer.RemoteCmd{
+		Command: command,
+		Stdin: ratelimit.NewReader(
+			io.LimitReader(src, size-offset), c.config.UploadBandwidth),
+		Stderr: &stderr,
+	}
+	if err := c.Start(cmd); err != nil {
+		return err
+	}
+
+	cmd.Wait()
+	if cmd.ExitStatus != 0 {
+		return fmt.Errorf("resumed upload exited with status %d: %s",
+			cmd.ExitStatus, stderr.String())
+	}
+
+	if offset, err = c.remoteSize(path); err != nil {
+		return err
+	}
+	if offset != size {
+		return fmt.Errorf(
+			"resumed upload is incomplete: %d of %d bytes", offset, size)
+	}
+
+	return nil
+}
+
+// remoteSize returns the size of the file at path on the remote end, or
+// zero if it doesn't exist.
+func (c *comm) remoteSize(path string) (int64, error) {
+	var stdout bytes.Buffer
+	cmd := &packer.RemoteCmd{
+		Command: fmt.Sprintf("wc -c < %s 2>/dev/null || echo 0", shellQuote(path)),
+		Stdout:  &stdout,
+	}
+	if err := c.Start(cmd); err != nil {
+		return 0, err
+	}
+
+	cmd.Wait()
+	if cmd.ExitStatus != 0 {
+		return 0, fmt.Errorf(
+			"error reading size of '%s': exit status %d", path, cmd.ExitStatus)
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(stdout.String()), 10, 64)
+}
+
+// shellQuote quotes s for use as a single argument to a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
 }
 
 func (c *comm) UploadDir(dst string, src string, excl []string) error {
@@ -292,6 +397,7 @@ func (c *comm) scpSession(scpCommand string, f func(io.Writer, *bufio.Reader) er
 	if err != nil {
 		return err
 	}
+	limitedW := ratelimit.NewWriter(stdinW, c.config.UploadBandwidth)
 
 	// We only want to close once, so we nil w after we close it,
 	// and only close in the defer if it hasn't been closed already.
@@ -323,7 +429,7 @@ func (c *comm) scpSession(scpCommand string, f func(io.Writer, *bufio.Reader) er
 	// EOF errors if they occur because it usually means that SCP prematurely
 	// ended on the other side.
 	log.Println("Started SCP session, beginning transfers...")
-	if err := f(stdinW, stdoutR); err != nil && err != io.EOF {
+	if err := f(limitedW, stdoutR); err != nil && err != io.EOF {
 		return err
 	}
 
@@ -164,3 +164,17 @@ func TestStart(t *testing.T) {
 
 	client.Start(&cmd)
 }
+
+func TestShellQuote(t *testing.T) {
+	cases := map[string]string{
+		"/tmp/foo":     "'/tmp/foo'",
+		"/tmp/foo bar": "'/tmp/foo bar'",
+		"/tmp/it's":    `'/tmp/it'\''s'`,
+	}
+
+	for input, expected := range cases {
+		if actual := shellQuote(input); actual != expected {
+			t.Fatalf("bad: %s", actual)
+		}
+	}
+}
@@ -1,14 +1,14 @@
 package winrm
 
 import (
-	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/masterzen/winrm/winrm"
 	"github.com/mitchellh/packer/packer"
-	"github.com/packer-community/winrmcp/winrmcp"
 
 	// This import is a bit strange, but it's needed so `make updatedeps`
 	// can see and download it
@@ -25,13 +25,13 @@ type Communicator struct {
 // New creates a new communicator implementation over WinRM.
 func New(config *Config) (*Communicator, error) {
 	endpoint := &winrm.Endpoint{
-		Host: config.Host,
-		Port: config.Port,
+		Host:     config.Host,
+		Port:     config.Port,
+		HTTPS:    config.HTTPS,
+		Insecure: config.Insecure,
 
 		/*
 			TODO
-			HTTPS:    connInfo.HTTPS,
-			Insecure: connInfo.Insecure,
 			CACert:   connInfo.CACert,
 		*/
 	}
@@ -94,36 +94,59 @@ func runCommand(shell *winrm.Shell, cmd *winrm.Command, rc *packer.RemoteCmd) {
 
 // Upload implementation of communicator.Communicator interface
 func (c *Communicator) Upload(path string, input io.Reader, _ *os.FileInfo) error {
-	wcp, err := c.newCopyClient()
-	if err != nil {
-		return err
-	}
 	log.Printf("Uploading file to '%s'", path)
-	return wcp.Write(path, input)
+	return c.upload(path, input)
 }
 
 // UploadDir implementation of communicator.Communicator interface
 func (c *Communicator) UploadDir(dst string, src string, exclude []string) error {
 	log.Printf("Uploading dir '%s' to '%s'", src, dst)
-	wcp, err := c.newCopyClient()
-	if err != nil {
-		return err
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != src && excluded(info.Name(), exclude) {
+			log.Printf("Excluding '%s' from the upload", path)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		target := dst + "\\" + strings.Replace(rel, "/", "\\", -1)
+		log.Printf("Uploading file to '%s'", target)
+		return c.upload(target, f)
+	})
+}
+
+// excluded returns true if the name of a file or directory matches one of
+// the patterns of exclude.
+func excluded(name string, exclude []string) bool {
+	for _, pattern := range exclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
 	}
-	return wcp.Copy(src, dst)
+
+	return false
 }
 
 func (c *Communicator) Download(src string, dst io.Writer) error {
 	panic("download not implemented")
 }
-
-func (c *Communicator) newCopyClient() (*winrmcp.Winrmcp, error) {
-	addr := fmt.Sprintf("%s:%d", c.endpoint.Host, c.endpoint.Port)
-	return winrmcp.New(addr, &winrmcp.Config{
-		Auth: winrmcp.Auth{
-			User:     c.config.Username,
-			Password: c.config.Password,
-		},
-		OperationTimeout:      c.config.Timeout,
-		MaxOperationsPerShell: 15, // lowest common denominator
-	})
-}
@@ -3,6 +3,13 @@ package winrm
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,6 +33,15 @@ func newMockWinRMServer(t *testing.T) *winrmtest.Remote {
 			return 0
 		})
 
+	// Uploads print the SHA256 of the file that was written, which is
+	// "something" in the tests.
+	wrm.CommandFunc(
+		winrmtest.MatchPattern(`^powershell.exe -NoProfile -NonInteractive -EncodedCommand .*$`),
+		func(out, err io.Writer) int {
+			out.Write([]byte("3fc9b689459d738f8c88a3a48aa9e33542016b7a4052e001aaa536fca74813cb\r\n"))
+			return 0
+		})
+
 	wrm.CommandFunc(
 		winrmtest.MatchPattern(`^powershell.exe -EncodedCommand .*$`),
 		func(out, err io.Writer) int {
@@ -92,3 +108,62 @@ func TestUpload(t *testing.T) {
 		t.Fatalf("error uploading file: %s", err)
 	}
 }
+
+func TestUploadDir(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	for _, name := range []string{"foo.txt", "it's.txt", "sub/bar.txt", ".git/config", "sub/.git"} {
+		path := filepath.Join(td, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte("something"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// Record the destination of each upload
+	var uploaded []string
+	pathRe := regexp.MustCompile(`GetFullPath\('((?:[^']|'')*)'\)`)
+	wrm := winrmtest.NewRemote()
+	defer wrm.Close()
+	wrm.CommandFunc(
+		func(cmd string) bool {
+			match := pathRe.FindStringSubmatch(decodePowershellCommand(t, cmd))
+			if match == nil {
+				t.Errorf("bad: %s", cmd)
+				return false
+			}
+			uploaded = append(uploaded, strings.Replace(match[1], "''", "'", -1))
+			return true
+		},
+		func(out, err io.Writer) int {
+			out.Write([]byte("3fc9b689459d738f8c88a3a48aa9e33542016b7a4052e001aaa536fca74813cb\r\n"))
+			return 0
+		})
+
+	c, err := New(&Config{
+		Host:     wrm.Host,
+		Port:     wrm.Port,
+		Username: "user",
+		Password: "pass",
+		Timeout:  30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("error creating communicator: %s", err)
+	}
+
+	if err := c.UploadDir(`C:\Temp`, td, []string{".git"}); err != nil {
+		t.Fatalf("error uploading dir: %s", err)
+	}
+
+	expected := []string{`C:\Temp\foo.txt`, `C:\Temp\it's.txt`, `C:\Temp\sub\bar.txt`}
+	sort.Strings(uploaded)
+	if !reflect.DeepEqual(uploaded, expected) {
+		t.Fatalf("bad: %#v", uploaded)
+	}
+}
@@ -11,4 +11,13 @@ type Config struct {
 	Username string
 	Password string
 	Timeout  time.Duration
+
+	// HTTPS connects over HTTPS, and Insecure skips the verification of
+	// the certificate of the machine.
+	HTTPS    bool
+	Insecure bool
+
+	// UploadBandwidth is the maximum rate, in bytes per second, at which
+	// files are uploaded. Zero means no limit.
+	UploadBandwidth int64
 }
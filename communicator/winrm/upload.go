@@ -0,0 +1,199 @@
+package winrm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/mitchellh/packer/helper/ratelimit"
+)
+
+// uploadChunkSize is the amount of compressed data sent in each write to
+// the standard input of the remote command. Each chunk is base64 encoded
+// and sent in a single WinRM message, so it must stay well below the
+// default MaxEnvelopeSizekb of 150KB on older Windows versions.
+const uploadChunkSize = 64 * 1024
+
+// uploadScript is the PowerShell script that receives an upload. It reads
+// base64 encoded lines of gzip data from standard input, decompresses them
+// into the destination and prints the SHA256 of the written file so that
+// it can be verified.
+const uploadScript = `$ErrorActionPreference = 'Stop'
+$ProgressPreference = 'SilentlyContinue'
+$dst = [System.IO.Path]::GetFullPath(%s)
+$dir = [System.IO.Path]::GetDirectoryName($dst)
+if (-not (Test-Path $dir)) { New-Item -ItemType Directory -Force -Path $dir | Out-Null }
+$tmp = [System.IO.Path]::GetTempFileName()
+$f = [System.IO.File]::Create($tmp)
+while (($line = [Console]::In.ReadLine()) -ne $null) {
+  if ($line.Length -eq 0) { continue }
+  $b = [Convert]::FromBase64String($line)
+  $f.Write($b, 0, $b.Length)
+}
+$f.Close()
+$f = [System.IO.File]::OpenRead($tmp)
+$gz = New-Object System.IO.Compression.GZipStream($f, [System.IO.Compression.CompressionMode]::Decompress)
+$out = [System.IO.File]::Create($dst)
+$gz.CopyTo($out)
+$out.Close()
+$gz.Close()
+Remove-Item $tmp
+$f = [System.IO.File]::OpenRead($dst)
+$sha = [System.Security.Cryptography.SHA256]::Create()
+$hash = [BitConverter]::ToString($sha.ComputeHash($f)).Replace('-', '').ToLower()
+$f.Close()
+Write-Output $hash
+`
+
+// upload streams the contents of input to the file at path on the remote
+// machine. The data is compressed and sent over the standard input of a
+// single remote command, and the SHA256 of the resulting file is compared
+// with the data that was read.
+func (c *Communicator) upload(path string, input io.Reader) error {
+	shell, err := c.client.CreateShell()
+	if err != nil {
+		return err
+	}
+	defer shell.Close()
+
+	path = strings.Replace(path, "/", "\\", -1)
+	cmd, err := shell.Execute(powershellCommand(fmt.Sprintf(uploadScript, psQuote(path))))
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	outDone := make(chan struct{})
+	errDone := make(chan struct{})
+	go func() {
+		defer close(outDone)
+		io.Copy(&stdout, cmd.Stdout)
+	}()
+	go func() {
+		defer close(errDone)
+		io.Copy(&stderr, cmd.Stderr)
+	}()
+
+	hash := sha256.New()
+	n, err := writeCompressed(
+		ratelimit.NewWriter(cmd.Stdin, c.config.UploadBandwidth),
+		io.TeeReader(input, hash))
+	if cerr := cmd.Stdin.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("Error uploading %s: %s", path, err)
+	}
+
+	cmd.Wait()
+	<-outDone
+	<-errDone
+	if code := cmd.ExitCode(); code != 0 {
+		return fmt.Errorf(
+			"Error uploading %s, exit code %d: %s",
+			path, code, strings.TrimSpace(stderr.String()))
+	}
+
+	expected := fmt.Sprintf("%x", hash.Sum(nil))
+	actual := strings.TrimSpace(stdout.String())
+	if actual != expected {
+		return fmt.Errorf(
+			"Checksum mismatch uploading %s: expected %s, got %q",
+			path, expected, actual)
+	}
+
+	log.Printf("Uploaded %d bytes to '%s', sha256 %s", n, path, expected)
+	return nil
+}
+
+// writeCompressed gzips everything read from r and writes it to w as
+// base64 encoded lines of at most uploadChunkSize bytes of data each. It
+// returns the number of uncompressed bytes that were read.
+func writeCompressed(w io.Writer, r io.Reader) (int64, error) {
+	cw := &chunkWriter{
+		w:    w,
+		buf:  make([]byte, 0, uploadChunkSize),
+		size: uploadChunkSize,
+	}
+
+	gzipW := gzip.NewWriter(cw)
+	n, err := io.Copy(gzipW, r)
+	if err != nil {
+		return n, err
+	}
+	if err := gzipW.Close(); err != nil {
+		return n, err
+	}
+
+	return n, cw.Flush()
+}
+
+// chunkWriter buffers writes and writes them to the underlying writer as
+// base64 encoded lines.
+type chunkWriter struct {
+	w    io.Writer
+	buf  []byte
+	size int
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		free := c.size - len(c.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+
+		c.buf = append(c.buf, p[:free]...)
+		p = p[free:]
+		n += free
+
+		if len(c.buf) == c.size {
+			if err := c.Flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Flush writes any buffered data as a line.
+func (c *chunkWriter) Flush() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+
+	line := base64.StdEncoding.EncodeToString(c.buf) + "\r\n"
+	c.buf = c.buf[:0]
+	_, err := io.WriteString(c.w, line)
+	return err
+}
+
+// psQuote returns s as a single-quoted PowerShell string, in which only
+// quotes are special, so that paths with "$" or "`" are used literally.
+func psQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// powershellCommand returns the command line that runs the given script
+// with PowerShell. The script is passed encoded so that it doesn't have
+// to be quoted.
+func powershellCommand(script string) string {
+	encoded := utf16.Encode([]rune(script))
+	raw := make([]byte, len(encoded)*2)
+	for i, v := range encoded {
+		binary.LittleEndian.PutUint16(raw[i*2:], v)
+	}
+
+	return fmt.Sprintf(
+		"powershell.exe -NoProfile -NonInteractive -EncodedCommand %s",
+		base64.StdEncoding.EncodeToString(raw))
+}
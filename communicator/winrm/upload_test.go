@@ -0,0 +1,116 @@
+package winrm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestWriteCompressed(t *testing.T) {
+	data := bytes.Repeat([]byte("packer"), uploadChunkSize)
+
+	var buf bytes.Buffer
+	n, err := writeCompressed(&buf, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n != int64(len(data)) {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// Decode each line and make sure the chunks are within the limit
+	var compressed bytes.Buffer
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\r\n") {
+		chunk, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if len(chunk) > uploadChunkSize {
+			t.Fatalf("chunk too large: %d", len(chunk))
+		}
+
+		compressed.Write(chunk)
+	}
+
+	gzipR, err := gzip.NewReader(&compressed)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	actual, err := ioutil.ReadAll(gzipR)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(actual, data) {
+		t.Fatal("decompressed data doesn't match")
+	}
+}
+
+func TestChunkWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &chunkWriter{w: &buf, size: 4}
+
+	if _, err := w.Write([]byte("abcdefghij")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := "YWJjZA==\r\nZWZnaA==\r\naWo=\r\n"
+	if buf.String() != expected {
+		t.Fatalf("bad: %q", buf.String())
+	}
+}
+
+func TestPowershellCommand(t *testing.T) {
+	cmd := powershellCommand("echo foo")
+	if actual := decodePowershellCommand(t, cmd); actual != "echo foo" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestPSQuote(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Expected string
+	}{
+		{`C:\Temp\foo.txt`, `'C:\Temp\foo.txt'`},
+		{`C:\Temp\it's.txt`, `'C:\Temp\it''s.txt'`},
+		{`C:\Temp\$env:foo`, `'C:\Temp\$env:foo'`},
+		{"C:\\Temp\\`\"foo\".txt", "'C:\\Temp\\`\"foo\".txt'"},
+	}
+
+	for _, tc := range cases {
+		if actual := psQuote(tc.Input); actual != tc.Expected {
+			t.Fatalf("%s: bad: %s", tc.Input, actual)
+		}
+	}
+}
+
+// decodePowershellCommand returns the script of a command line made by
+// powershellCommand.
+func decodePowershellCommand(t *testing.T, cmd string) string {
+	prefix := "powershell.exe -NoProfile -NonInteractive -EncodedCommand "
+	if !strings.HasPrefix(cmd, prefix) {
+		t.Errorf("bad: %s", cmd)
+		return ""
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(cmd[len(prefix):])
+	if err != nil {
+		t.Errorf("err: %s", err)
+		return ""
+	}
+
+	decoded := make([]uint16, len(raw)/2)
+	for i := range decoded {
+		decoded[i] = binary.LittleEndian.Uint16(raw[i*2:])
+	}
+
+	return string(utf16.Decode(decoded))
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"io"
 	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/mitchellh/osext"
@@ -20,7 +22,13 @@ type config struct {
 	PluginMinPort              uint
 	PluginMaxPort              uint
 
+	// BuildLockDir and MaxConcurrentBuilds limit the number of builds
+	// that run at the same time on this host, across Packer processes.
+	BuildLockDir        string `json:"build_lock_dir"`
+	MaxConcurrentBuilds int    `json:"max_concurrent_builds"`
+
 	Builders       map[string]string
+	Communicators  map[string]string
 	PostProcessors map[string]string `json:"post-processors"`
 	Provisioners   map[string]string
 }
@@ -127,6 +135,53 @@ func (c *config) LoadProvisioner(name string) (packer.Provisioner, error) {
 	return c.pluginClient(bin).Provisioner()
 }
 
+// BuildSlots returns the build slots that limit the number of concurrent
+// builds, or nil if they aren't limited.
+func (c *config) BuildSlots() *packer.BuildSlots {
+	if c.MaxConcurrentBuilds <= 0 {
+		return nil
+	}
+
+	dir := c.BuildLockDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "packer-build-locks")
+	}
+
+	return &packer.BuildSlots{Dir: dir, Max: c.MaxConcurrentBuilds}
+}
+
+// Plugins returns the paths of the discovered plugins, keyed by the kind
+// and name of the component they implement, such as "builder.amazon-ebs".
+func (c *config) Plugins() map[string]string {
+	result := make(map[string]string)
+	for k, v := range c.Builders {
+		result["builder."+k] = v
+	}
+	for k, v := range c.PostProcessors {
+		result["post-processor."+k] = v
+	}
+	for k, v := range c.Provisioners {
+		result["provisioner."+k] = v
+	}
+
+	return result
+}
+
+// CommunicatorPlugins returns the communicator plugins in the form of the
+// PACKER_COMMUNICATOR_PLUGINS environment variable, which is a list of
+// NAME=PATH pairs separated like the PATH. Communicator plugins are
+// started by the builders rather than by Packer itself, so this is how
+// they learn what was discovered.
+func (c *config) CommunicatorPlugins() string {
+	pairs := make([]string, 0, len(c.Communicators))
+	for k, v := range c.Communicators {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, string(os.PathListSeparator))
+}
+
 func (c *config) discover(path string) error {
 	var err error
 
@@ -143,6 +198,12 @@ func (c *config) discover(path string) error {
 		return err
 	}
 
+	err = c.discoverSingle(
+		filepath.Join(path, "packer-communicator-*"), &c.Communicators)
+	if err != nil {
+		return err
+	}
+
 	err = c.discoverSingle(
 		filepath.Join(path, "packer-post-processor-*"), &c.PostProcessors)
 	if err != nil {
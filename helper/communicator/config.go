@@ -14,6 +14,10 @@ import (
 type Config struct {
 	Type string `mapstructure:"communicator"`
 
+	// File uploads
+	UploadBandwidthLimit int  `mapstructure:"upload_bandwidth_limit"`
+	UploadResume         bool `mapstructure:"upload_resume"`
+
 	// SSH
 	SSHHost       string        `mapstructure:"ssh_host"`
 	SSHPort       int           `mapstructure:"ssh_port"`
@@ -29,6 +33,18 @@ type Config struct {
 	WinRMHost     string        `mapstructure:"winrm_host"`
 	WinRMPort     int           `mapstructure:"winrm_port"`
 	WinRMTimeout  time.Duration `mapstructure:"winrm_timeout"`
+	WinRMUseSSL   bool          `mapstructure:"winrm_use_ssl"`
+	WinRMInsecure bool          `mapstructure:"winrm_insecure"`
+
+	// Plugins
+	CommunicatorConfig  map[string]interface{} `mapstructure:"communicator_config"`
+	CommunicatorTimeout time.Duration          `mapstructure:"communicator_timeout"`
+}
+
+// UploadBandwidth returns the upload bandwidth limit in bytes per
+// second, or zero if there is no limit.
+func (c *Config) UploadBandwidth() int64 {
+	return int64(c.UploadBandwidthLimit) * 1024
 }
 
 // Port returns the port that will be used for access based on config.
@@ -49,6 +65,17 @@ func (c *Config) Prepare(ctx *interpolate.Context) []error {
 	}
 
 	var errs []error
+	if c.UploadBandwidthLimit < 0 {
+		errs = append(errs, errors.New(
+			"upload_bandwidth_limit must be zero or a positive number"))
+	}
+
+	// Only SSH uploads can pick up where they left off
+	if c.UploadResume && c.Type != "ssh" {
+		errs = append(errs, fmt.Errorf(
+			"upload_resume is only supported by the ssh communicator, not %s", c.Type))
+	}
+
 	switch c.Type {
 	case "ssh":
 		if es := c.prepareSSH(ctx); len(es) > 0 {
@@ -58,6 +85,11 @@ func (c *Config) Prepare(ctx *interpolate.Context) []error {
 		if es := c.prepareWinRM(ctx); len(es) > 0 {
 			errs = append(errs, es...)
 		}
+	case "none":
+	default:
+		if es := c.preparePlugin(ctx); len(es) > 0 {
+			errs = append(errs, es...)
+		}
 	}
 
 	return errs
@@ -94,6 +126,9 @@ func (c *Config) prepareSSH(ctx *interpolate.Context) []error {
 func (c *Config) prepareWinRM(ctx *interpolate.Context) []error {
 	if c.WinRMPort == 0 {
 		c.WinRMPort = 5985
+		if c.WinRMUseSSL {
+			c.WinRMPort = 5986
+		}
 	}
 
 	if c.WinRMTimeout == 0 {
@@ -105,5 +140,23 @@ func (c *Config) prepareWinRM(ctx *interpolate.Context) []error {
 		errs = append(errs, errors.New("winrm_username must be specified."))
 	}
 
+	if c.WinRMInsecure && !c.WinRMUseSSL {
+		errs = append(errs, errors.New("winrm_insecure requires winrm_use_ssl"))
+	}
+
+	return errs
+}
+
+func (c *Config) preparePlugin(ctx *interpolate.Context) []error {
+	if c.CommunicatorTimeout == 0 {
+		c.CommunicatorTimeout = 5 * time.Minute
+	}
+
+	var errs []error
+	if _, err := pluginPath(c.Type); err != nil {
+		errs = append(errs, fmt.Errorf(
+			"unknown communicator type %s: %s", c.Type, err))
+	}
+
 	return errs
 }
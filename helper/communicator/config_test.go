@@ -1,7 +1,11 @@
 package communicator
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/mitchellh/packer/template/interpolate"
 )
@@ -30,6 +34,124 @@ func TestConfig_none(t *testing.T) {
 	}
 }
 
+func TestConfig_plugin(t *testing.T) {
+	c := &Config{Type: "i-should-not-exist"}
+	if err := c.Prepare(testContext(t)); len(err) != 1 {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "packer-communicator-foo")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", td)
+
+	c = &Config{Type: "foo"}
+	if err := c.Prepare(testContext(t)); len(err) > 0 {
+		t.Fatalf("bad: %#v", err)
+	}
+	if c.CommunicatorTimeout != 5*time.Minute {
+		t.Fatalf("bad: %s", c.CommunicatorTimeout)
+	}
+}
+
+func TestConfig_pluginDiscovered(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// Discovered plugins are found even if they aren't on the PATH
+	path := filepath.Join(td, "packer-communicator-bar")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	oldPlugins := os.Getenv("PACKER_COMMUNICATOR_PLUGINS")
+	defer os.Setenv("PACKER_COMMUNICATOR_PLUGINS", oldPlugins)
+	os.Setenv("PACKER_COMMUNICATOR_PLUGINS", "foo=/nope"+string(os.PathListSeparator)+"bar="+path)
+
+	actual, err := pluginPath("bar")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual != path {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	if _, err := pluginPath("foo"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestConfig_uploadBandwidthLimit(t *testing.T) {
+	c := testConfig()
+	c.UploadBandwidthLimit = 512
+	if err := c.Prepare(testContext(t)); len(err) > 0 {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	if c.UploadBandwidth() != 512*1024 {
+		t.Fatalf("bad: %d", c.UploadBandwidth())
+	}
+
+	c = testConfig()
+	c.UploadBandwidthLimit = -1
+	if err := c.Prepare(testContext(t)); len(err) == 0 {
+		t.Fatal("should have error")
+	}
+}
+
+func TestConfig_uploadResume(t *testing.T) {
+	c := testConfig()
+	c.UploadResume = true
+	if err := c.Prepare(testContext(t)); len(err) > 0 {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	c = &Config{
+		Type:         "winrm",
+		WinRMUser:    "admin",
+		UploadResume: true,
+	}
+	if err := c.Prepare(testContext(t)); len(err) == 0 {
+		t.Fatal("should have error")
+	}
+}
+
 func testContext(t *testing.T) *interpolate.Context {
 	return nil
 }
+
+func TestConfig_winrmSSL(t *testing.T) {
+	c := &Config{Type: "winrm", WinRMUser: "admin"}
+	if err := c.Prepare(testContext(t)); len(err) > 0 {
+		t.Fatalf("bad: %#v", err)
+	}
+	if c.WinRMPort != 5985 {
+		t.Fatalf("bad: %d", c.WinRMPort)
+	}
+
+	c = &Config{Type: "winrm", WinRMUser: "admin", WinRMUseSSL: true}
+	if err := c.Prepare(testContext(t)); len(err) > 0 {
+		t.Fatalf("bad: %#v", err)
+	}
+	if c.WinRMPort != 5986 {
+		t.Fatalf("bad: %d", c.WinRMPort)
+	}
+
+	c = &Config{Type: "winrm", WinRMUser: "admin", WinRMInsecure: true}
+	if err := c.Prepare(testContext(t)); len(err) != 1 {
+		t.Fatalf("bad: %#v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package communicator
 
 import (
-	"fmt"
 	"log"
 
 	"github.com/mitchellh/multistep"
@@ -53,8 +52,11 @@ func (s *StepConnect) Run(state multistep.StateBag) multistep.StepAction {
 
 	step, ok := typeMap[s.Config.Type]
 	if !ok {
-		state.Put("error", fmt.Errorf("unknown communicator type: %s", s.Config.Type))
-		return multistep.ActionHalt
+		// Any other type is provided by a communicator plugin
+		step = &StepConnectPlugin{
+			Config: s.Config,
+			Host:   s.Host,
+		}
 	}
 
 	if step == nil {
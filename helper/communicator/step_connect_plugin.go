@@ -0,0 +1,198 @@
+package communicator
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/packer/plugin"
+)
+
+// pluginPrefix is the prefix of the binary name of communicator plugins.
+// A communicator type of "foo" is provided by "packer-communicator-foo".
+const pluginPrefix = "packer-communicator-"
+
+// StepConnectPlugin is a multistep Step implementation that connects
+// using a communicator provided by an external plugin. The plugin is
+// given the "communicator_config" settings and is then asked to connect
+// to the host until it succeeds or the timeout is reached.
+//
+// Uses:
+//   ui packer.Ui
+//
+// Produces:
+//   communicator packer.Communicator
+type StepConnectPlugin struct {
+	// All the fields below are documented on StepConnect
+	Config *Config
+	Host   func(multistep.StateBag) (string, error)
+
+	client *plugin.Client
+}
+
+func (s *StepConnectPlugin) Run(state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+
+	provider, err := s.provider()
+	if err != nil {
+		err := fmt.Errorf("Error loading communicator plugin %s: %s", s.Config.Type, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if err := provider.Prepare(s.Config.CommunicatorConfig); err != nil {
+		err := fmt.Errorf("Error configuring communicator %s: %s", s.Config.Type, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	var comm packer.Communicator
+
+	cancel := make(chan struct{})
+	waitDone := make(chan bool, 1)
+	go func() {
+		ui.Say(fmt.Sprintf("Waiting for communicator %s to connect...", s.Config.Type))
+		comm, err = s.waitForConnect(state, provider, cancel)
+		waitDone <- true
+	}()
+
+	log.Printf("Waiting for %s, up to timeout: %s", s.Config.Type, s.Config.CommunicatorTimeout)
+	timeout := time.After(s.Config.CommunicatorTimeout)
+WaitLoop:
+	for {
+		// Wait for either the connection to be made, a timeout to occur,
+		// or an interrupt to come through.
+		select {
+		case <-waitDone:
+			if err != nil {
+				err := fmt.Errorf("Error waiting for %s: %s", s.Config.Type, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+
+			ui.Say(fmt.Sprintf("Connected with communicator %s!", s.Config.Type))
+			state.Put("communicator", comm)
+			break WaitLoop
+		case <-timeout:
+			err := fmt.Errorf("Timeout waiting for %s.", s.Config.Type)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			close(cancel)
+			return multistep.ActionHalt
+		case <-time.After(1 * time.Second):
+			if _, ok := state.GetOk(multistep.StateCancelled); ok {
+				// The step sequence was cancelled, so cancel waiting
+				// and just start the halting process.
+				close(cancel)
+				log.Printf("Interrupt detected, quitting waiting for %s.", s.Config.Type)
+				return multistep.ActionHalt
+			}
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepConnectPlugin) Cleanup(multistep.StateBag) {
+	if s.client != nil {
+		s.client.Kill()
+		s.client = nil
+	}
+}
+
+// provider starts the plugin process and returns the communicator
+// provider it serves.
+func (s *StepConnectPlugin) provider() (packer.CommunicatorProvider, error) {
+	path, err := pluginPath(s.Config.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	// Builders are usually plugins themselves, so use the same port
+	// range that Packer gave us, if any.
+	config := &plugin.ClientConfig{
+		Cmd:     exec.Command(path),
+		MinPort: envUint("PACKER_PLUGIN_MIN_PORT"),
+		MaxPort: envUint("PACKER_PLUGIN_MAX_PORT"),
+	}
+
+	log.Printf("[INFO] Loading communicator plugin: %s", path)
+	s.client = plugin.NewClient(config)
+	return s.client.CommunicatorProvider()
+}
+
+func (s *StepConnectPlugin) waitForConnect(
+	state multistep.StateBag,
+	provider packer.CommunicatorProvider,
+	cancel <-chan struct{}) (packer.Communicator, error) {
+	first := true
+	for {
+		// Don't wait the first time through, but wait between attempts
+		if !first {
+			select {
+			case <-cancel:
+				log.Printf("[INFO] %s wait cancelled. Exiting loop.", s.Config.Type)
+				return nil, errors.New("wait cancelled")
+			case <-time.After(5 * time.Second):
+			}
+		}
+		first = false
+
+		host, err := s.Host(state)
+		if err != nil {
+			log.Printf("[DEBUG] Error getting %s host: %s", s.Config.Type, err)
+			continue
+		}
+
+		log.Printf("[INFO] Attempting %s connection to %s...", s.Config.Type, host)
+		comm, err := provider.Connect(host)
+		if err != nil {
+			log.Printf("[DEBUG] %s connection err: %s", s.Config.Type, err)
+			continue
+		}
+
+		return comm, nil
+	}
+}
+
+// pluginPath finds the plugin binary for the given communicator type.
+// Packer discovers communicator plugins in the same directories and
+// config file as its other plugins, and passes them to the builders in
+// the PACKER_COMMUNICATOR_PLUGINS environment variable. Plugins that it
+// didn't discover are looked up on the PATH.
+func pluginPath(name string) (string, error) {
+	bin := pluginPrefix + name
+	for _, pair := range filepath.SplitList(os.Getenv("PACKER_COMMUNICATOR_PLUGINS")) {
+		if i := strings.Index(pair, "="); i >= 0 && pair[:i] == name {
+			bin = pair[i+1:]
+			break
+		}
+	}
+
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		return "", fmt.Errorf("plugin %s not found", bin)
+	}
+
+	return path, nil
+}
+
+func envUint(key string) uint {
+	v, err := strconv.ParseUint(os.Getenv(key), 10, 32)
+	if err != nil {
+		return 0
+	}
+
+	return uint(v)
+}
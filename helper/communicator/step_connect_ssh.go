@@ -133,6 +133,9 @@ func (s *StepConnectSSH) waitForSSH(state multistep.StateBag, cancel <-chan stru
 			Connection: connFunc,
 			SSHConfig:  sshConfig,
 			Pty:        s.Config.SSHPty,
+
+			UploadBandwidth: s.Config.UploadBandwidth(),
+			UploadResume:    s.Config.UploadResume,
 		}
 
 		log.Println("[INFO] Attempting SSH connection...")
@@ -28,6 +28,28 @@ func TestStepConnect_none(t *testing.T) {
 	}
 }
 
+func TestStepConnect_pluginNotFound(t *testing.T) {
+	state := testState(t)
+
+	step := &StepConnect{
+		Config: &Config{
+			Type: "i-should-not-exist",
+		},
+		Host: func(multistep.StateBag) (string, error) {
+			return "127.0.0.1", nil
+		},
+	}
+	defer step.Cleanup(state)
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
+
 func testState(t *testing.T) multistep.StateBag {
 	state := new(multistep.BasicStateBag)
 	state.Put("hook", &packer.MockHook{})
@@ -121,6 +121,10 @@ func (s *StepConnectWinRM) waitForWinRM(state multistep.StateBag, cancel <-chan
 			Username: user,
 			Password: password,
 			Timeout:  s.Config.WinRMTimeout,
+			HTTPS:    s.Config.WinRMUseSSL,
+			Insecure: s.Config.WinRMInsecure,
+
+			UploadBandwidth: s.Config.UploadBandwidth(),
 		})
 		if err != nil {
 			log.Printf("[ERROR] WinRM connection err: %s", err)
@@ -2,6 +2,7 @@ package config
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -88,3 +89,63 @@ func TestDecode(t *testing.T) {
 		}
 	}
 }
+
+func TestDecode_duration(t *testing.T) {
+	type Target struct {
+		Timeout time.Duration `mapstructure:"timeout"`
+	}
+
+	cases := []struct {
+		Input  interface{}
+		Output time.Duration
+		Err    bool
+	}{
+		{"90s", 90 * time.Second, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"0s", 0, false},
+		{0, 0, false},
+		{"", 0, false},
+		{"5", 0, true},
+		{300, 0, true},
+		{float64(1.5), 0, true},
+		{"forever", 0, true},
+	}
+
+	for _, tc := range cases {
+		var result Target
+		err := Decode(&result, nil, map[string]interface{}{
+			"timeout": tc.Input,
+		})
+		if (err != nil) != tc.Err {
+			t.Fatalf("%#v: err: %s", tc.Input, err)
+		}
+		if err != nil {
+			if !strings.Contains(err.Error(), "timeout") {
+				t.Fatalf("%#v: error should name the key: %s", tc.Input, err)
+			}
+			continue
+		}
+
+		if result.Timeout != tc.Output {
+			t.Fatalf("%#v: bad: %s", tc.Input, result.Timeout)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	d, err := ParseDuration("boot_wait", "1m30s")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d != 90*time.Second {
+		t.Fatalf("bad: %s", d)
+	}
+
+	_, err = ParseDuration("boot_wait", "10")
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.HasPrefix(err.Error(), "boot_wait: ") {
+		t.Fatalf("error should name the key: %s", err)
+	}
+}
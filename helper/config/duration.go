@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// durationExamples is shown in errors to say how durations are written.
+const durationExamples = `such as "90s", "10m" or "1h30m"`
+
+// ParseDuration parses the duration given for the configuration key
+// name, such as "90s", "10m" or "1h30m". The error names the key.
+//
+// time.Duration fields are parsed this way automatically by Decode, so
+// this is only needed for fields that are strings, such as those where
+// an empty string and "0s" mean different things.
+func ParseDuration(name, raw string) (time.Duration, error) {
+	d, err := parseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", name, err)
+	}
+
+	return d, nil
+}
+
+func parseDuration(raw string) (time.Duration, error) {
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"invalid duration %q, durations are a number and a unit, %s",
+			raw, durationExamples)
+	}
+
+	return d, nil
+}
+
+// durationHook decodes time.Duration fields from strings such as "90s".
+// An empty string is zero, the same as a missing key, so that a variable
+// that isn't set leaves the default. Decoding numbers would silently
+// treat them as nanoseconds, so those are errors unless they are zero.
+// mapstructure adds the name of the key to the error.
+func durationHook(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+	if t != durationType || f == durationType {
+		return v, nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		if v.(string) == "" {
+			return time.Duration(0), nil
+		}
+
+		return parseDuration(v.(string))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if reflect.ValueOf(v).Convert(reflect.TypeOf(float64(0))).Float() != 0 {
+			return nil, fmt.Errorf(
+				"durations need a unit, %s, got %v", durationExamples, v)
+		}
+	}
+
+	return v, nil
+}
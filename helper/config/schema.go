@@ -0,0 +1,117 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Schema returns a JSON Schema describing the configuration keys of the
+// given configuration struct, as they are read by Decode. The keys are
+// taken from the mapstructure tags of the fields. Squashed structs are
+// flattened into the result and the internal "packer_" keys are left out.
+//
+// Decode is weakly typed and runs after user variables are interpolated,
+// so keys that aren't strings also accept strings, such as "{{user `n`}}"
+// for a number, or a comma-separated list for a slice.
+func Schema(v interface{}) map[string]interface{} {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	properties := make(map[string]interface{})
+	schemaFields(t, properties)
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func schemaFields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// Unexported fields can't be set by mapstructure
+			continue
+		}
+
+		tag := f.Tag.Get("mapstructure")
+		name := f.Name
+		squash := false
+		if tag != "" {
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				if opt == "squash" {
+					squash = true
+				}
+			}
+		}
+
+		if name == "-" {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if squash && ft.Kind() == reflect.Struct {
+			schemaFields(ft, properties)
+			continue
+		}
+
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "packer_") {
+			continue
+		}
+
+		properties[name] = schemaType(ft)
+	}
+}
+
+func schemaType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// Durations are decoded from strings such as "5m"
+	if t == durationType {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": []interface{}{"boolean", "string"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": []interface{}{"integer", "string"}}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": []interface{}{"number", "string"}}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  []interface{}{"array", "string"},
+			"items": schemaType(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaType(t.Elem()),
+		}
+	case reflect.Struct:
+		return Schema(reflect.New(t).Interface())
+	default:
+		// Anything goes for interfaces
+		return map[string]interface{}{}
+	}
+}
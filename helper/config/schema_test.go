@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	type Inner struct {
+		Port int `mapstructure:"port"`
+	}
+
+	type Squashed struct {
+		BuildName string `mapstructure:"packer_build_name"`
+		User      string `mapstructure:"user"`
+	}
+
+	type Target struct {
+		Squashed `mapstructure:",squash"`
+
+		Name     string
+		Enabled  bool              `mapstructure:"enabled"`
+		Ratio    float64           `mapstructure:"ratio"`
+		Timeout  time.Duration     `mapstructure:"timeout"`
+		Tags     []string          `mapstructure:"tags"`
+		Metadata map[string]string `mapstructure:"metadata"`
+		Inner    *Inner            `mapstructure:"inner"`
+		Ignored  string            `mapstructure:"-"`
+		Raw      interface{}       `mapstructure:"raw"`
+
+		internal string
+	}
+
+	expected := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"user":    map[string]interface{}{"type": "string"},
+			"name":    map[string]interface{}{"type": "string"},
+			"enabled": map[string]interface{}{"type": []interface{}{"boolean", "string"}},
+			"ratio":   map[string]interface{}{"type": []interface{}{"number", "string"}},
+			"timeout": map[string]interface{}{"type": "string"},
+			"tags": map[string]interface{}{
+				"type":  []interface{}{"array", "string"},
+				"items": map[string]interface{}{"type": "string"},
+			},
+			"metadata": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"inner": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"port": map[string]interface{}{"type": []interface{}{"integer", "string"}},
+				},
+				"additionalProperties": false,
+			},
+			"raw": map[string]interface{}{},
+		},
+		"additionalProperties": false,
+	}
+
+	actual := Schema(new(Target))
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
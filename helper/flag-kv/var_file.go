@@ -0,0 +1,64 @@
+package kvflag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/packer/common/yaml"
+)
+
+// ReadFile reads the user variables of a variable file. Files ending in
+// ".yml" or ".yaml" are YAML, and all others are JSON. Both hold a single
+// mapping of variable names to their values.
+func ReadFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		err = decodeYAML(string(data), result)
+	default:
+		err = json.Unmarshal(data, &result)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Error reading variables in '%s': %s", path, err)
+	}
+
+	return result, nil
+}
+
+func decodeYAML(data string, result map[string]string) error {
+	decoded, err := yaml.Decode(data)
+	if err != nil {
+		return err
+	}
+	if decoded == nil {
+		return nil
+	}
+
+	m, ok := decoded.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected a mapping of variables")
+	}
+
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			result[k] = v
+		case bool, int, int64, uint64:
+			result[k] = fmt.Sprint(v)
+		default:
+			// Floats would lose their formatting, so "1.10" must be quoted
+			return fmt.Errorf("the value of '%s' must be a string: %v", k, v)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package kvflag
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Output map[string]string
+		Error  bool
+	}{
+		{
+			"basic.json",
+			map[string]string{"key": "value"},
+			false,
+		},
+
+		{
+			"basic.yaml",
+			map[string]string{"key": "value"},
+			false,
+		},
+
+		{
+			"scalars.yml",
+			map[string]string{"string": "1.10", "int": "3", "bool": "true"},
+			false,
+		},
+
+		{
+			"float.yaml",
+			nil,
+			true,
+		},
+
+		{
+			"list.yaml",
+			nil,
+			true,
+		},
+
+		{
+			"missing.json",
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		actual, err := ReadFile(filepath.Join("./test-fixtures", tc.Input))
+		if (err != nil) != tc.Error {
+			t.Fatalf("bad error. Input: %#v\n\n%s", tc.Input, err)
+		}
+
+		if !reflect.DeepEqual(actual, tc.Output) {
+			t.Fatalf("bad: %#v", actual)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package ratelimit provides readers and writers that cap the rate at
+// which data passes through them. Communicators use these to limit the
+// bandwidth used by file uploads.
+package ratelimit
+
+import (
+	"io"
+	"time"
+)
+
+// limiter keeps track of how much data has passed through and sleeps
+// whenever the transfer gets ahead of the configured rate.
+type limiter struct {
+	rate  int64
+	start time.Time
+	count int64
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newLimiter(rate int64) *limiter {
+	return &limiter{rate: rate, now: time.Now, sleep: time.Sleep}
+}
+
+// chunk returns the largest slice of p that should be transferred at
+// once, so that the rate stays smooth rather than bursting.
+func (l *limiter) chunk(p []byte) []byte {
+	max := l.rate / 10
+	if max < 1 {
+		max = 1
+	}
+	if int64(len(p)) > max {
+		p = p[:max]
+	}
+
+	return p
+}
+
+func (l *limiter) wait(n int) {
+	if l.start.IsZero() {
+		l.start = l.now()
+	}
+
+	// The expected time is computed in seconds as a float, since
+	// multiplying the count by time.Second overflows after ~9.2GB.
+	l.count += int64(n)
+	expected := time.Duration(float64(l.count) / float64(l.rate) * float64(time.Second))
+	if elapsed := l.now().Sub(l.start); elapsed < expected {
+		l.sleep(expected - elapsed)
+	}
+}
+
+type reader struct {
+	r io.Reader
+	l *limiter
+}
+
+// NewReader returns a reader that reads from r at no more than rate
+// bytes per second. If rate is zero or less, r is returned as is.
+func NewReader(r io.Reader, rate int64) io.Reader {
+	if rate <= 0 {
+		return r
+	}
+
+	return &reader{r: r, l: newLimiter(rate)}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(r.l.chunk(p))
+	r.l.wait(n)
+	return n, err
+}
+
+type writer struct {
+	w io.Writer
+	l *limiter
+}
+
+// NewWriter returns a writer that writes to w at no more than rate bytes
+// per second. If rate is zero or less, w is returned as is.
+func NewWriter(w io.Writer, rate int64) io.Writer {
+	if rate <= 0 {
+		return w
+	}
+
+	return &writer{w: w, l: newLimiter(rate)}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n, err := w.w.Write(w.l.chunk(p))
+		w.l.wait(n)
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
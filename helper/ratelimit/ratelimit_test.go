@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// testClock replaces the clock of the limiter with a fake one that only
+// advances when sleeping, and returns the total time slept.
+func testClock(l *limiter) *time.Duration {
+	var slept time.Duration
+	now := time.Now()
+	l.now = func() time.Time { return now.Add(slept) }
+	l.sleep = func(d time.Duration) { slept += d }
+	return &slept
+}
+
+func TestNewReader_noLimit(t *testing.T) {
+	r := bytes.NewReader([]byte("foo"))
+	if NewReader(r, 0) != io.Reader(r) {
+		t.Fatal("should return the reader")
+	}
+}
+
+func TestReader(t *testing.T) {
+	r := NewReader(bytes.NewReader(make([]byte, 1000)), 100).(*reader)
+	slept := testClock(r.l)
+
+	n, err := io.Copy(ioutil.Discard, r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n != 1000 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// 1000 bytes at 100 bytes per second should take about 10 seconds
+	if *slept < 9*time.Second || *slept > 10*time.Second {
+		t.Fatalf("bad: %s", *slept)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, 100).(*writer)
+	slept := testClock(w.l)
+
+	n, err := w.Write(make([]byte, 500))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n != 500 || buf.Len() != 500 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	if *slept < 4*time.Second || *slept > 5*time.Second {
+		t.Fatalf("bad: %s", *slept)
+	}
+}
+
+func TestLimiter_large(t *testing.T) {
+	l := newLimiter(100 * 1024 * 1024)
+	slept := testClock(l)
+
+	// 20GB is past the point where the count in nanoseconds overflows
+	for i := 0; i < 20; i++ {
+		l.wait(1024 * 1024 * 1024)
+	}
+
+	// 20GB at 100MB per second should take about 205 seconds
+	if *slept < 204*time.Second || *slept > 205*time.Second {
+		t.Fatalf("bad: %s", *slept)
+	}
+}
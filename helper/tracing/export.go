@@ -0,0 +1,281 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// traceFile is the trace file, in the JSON array form of the trace event
+// format. Packer and its plugins all append their events to it, each with
+// a single write, and Close ends the array once they are done.
+type traceFile struct {
+	Path string
+}
+
+func createTraceFile(path string) (*traceFile, error) {
+	if err := ioutil.WriteFile(path, []byte("[\n"), 0644); err != nil {
+		return nil, err
+	}
+
+	return &traceFile{Path: path}, nil
+}
+
+// Close ends the trace with an instant event, which has no comma after
+// it, and closes the array.
+func (f *traceFile) Close() {
+	event := traceEvent{
+		Name:  "end",
+		Phase: "i",
+		Scope: "g",
+		Pid:   os.Getpid(),
+		Time:  micros(time.Now()),
+	}
+	b, err := json.Marshal(event)
+	if err == nil {
+		err = appendFile(f.Path, append(b, []byte("\n]\n")...))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error completing trace file: %s\n", err)
+	}
+}
+
+// traceEvent is an event of the trace event format.
+type traceEvent struct {
+	Name     string            `json:"name"`
+	Category string            `json:"cat,omitempty"`
+	Phase    string            `json:"ph"`
+	Scope    string            `json:"s,omitempty"`
+	Time     int64             `json:"ts"`
+	Duration int64             `json:"dur,omitempty"`
+	Pid      int               `json:"pid"`
+	Tid      uint32            `json:"tid"`
+	Args     map[string]string `json:"args,omitempty"`
+}
+
+// fileExporter appends the spans of this process to the trace file as
+// complete events. Spans of each lane are put in a thread of their own,
+// which is named after the lane.
+type fileExporter struct {
+	Path string
+
+	named map[string]bool
+}
+
+func (e *fileExporter) Export(s *Span) error {
+	if e.named == nil {
+		e.named = make(map[string]bool)
+	}
+
+	pid := os.Getpid()
+	tid := laneID(s.Lane)
+
+	var events []traceEvent
+	if !e.named[""] {
+		events = append(events, traceEvent{
+			Name:  "process_name",
+			Phase: "M",
+			Pid:   pid,
+			Args:  map[string]string{"name": processName()},
+		})
+		e.named[""] = true
+	}
+	if !e.named[s.Lane] && s.Lane != "" {
+		events = append(events, traceEvent{
+			Name:  "thread_name",
+			Phase: "M",
+			Pid:   pid,
+			Tid:   tid,
+			Args:  map[string]string{"name": s.Lane},
+		})
+		e.named[s.Lane] = true
+	}
+
+	args := map[string]string{
+		"span_id":   s.ID,
+		"parent_id": s.ParentID,
+	}
+	for k, v := range s.Attrs {
+		args[k] = v
+	}
+
+	events = append(events, traceEvent{
+		Name:     s.Name,
+		Category: strings.SplitN(s.Name, " ", 2)[0],
+		Phase:    "X",
+		Time:     micros(s.Start),
+		Duration: micros(s.End) - micros(s.Start),
+		Pid:      pid,
+		Tid:      tid,
+		Args:     args,
+	})
+
+	var buf bytes.Buffer
+	for _, event := range events {
+		b, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteString(",\n")
+	}
+
+	return appendFile(e.Path, buf.Bytes())
+}
+
+// otlpExporter sends spans to an OTLP/HTTP endpoint as JSON.
+type otlpExporter struct {
+	URL string
+
+	client *http.Client
+}
+
+func newOTLPExporter(endpoint string) *otlpExporter {
+	url := strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+
+	return &otlpExporter{
+		URL:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       *otlpStatus     `json:"status,omitempty"`
+}
+
+func (e *otlpExporter) Export(s *Span) error {
+	attrs := map[string]string{"packer.build": s.Lane}
+	for k, v := range s.Attrs {
+		attrs[k] = v
+	}
+
+	span := otlpSpan{
+		TraceID:      s.TraceID,
+		SpanID:       s.ID,
+		ParentSpanID: s.ParentID,
+		Name:         s.Name,
+		Kind:         1,
+		Start:        fmt.Sprintf("%d", s.Start.UnixNano()),
+		End:          fmt.Sprintf("%d", s.End.UnixNano()),
+		Attributes:   otlpAttributes(attrs),
+	}
+	if s.Failed {
+		span.Status = &otlpStatus{Code: 2, Message: s.Attrs["error"]}
+	}
+
+	body := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]string{
+						"service.name": "packer",
+						"process.pid":  fmt.Sprintf("%d", os.Getpid()),
+					}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": "packer"},
+						"spans": []otlpSpan{span},
+					},
+				},
+			},
+		},
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with status %s", e.URL, resp.Status)
+	}
+
+	return nil
+}
+
+func otlpAttributes(m map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]otlpAttribute, len(keys))
+	for i, k := range keys {
+		attrs[i].Key = k
+		attrs[i].Value.StringValue = m[k]
+	}
+
+	return attrs
+}
+
+func appendFile(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func laneID(lane string) uint32 {
+	if lane == "" {
+		return 0
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(lane))
+	return h.Sum32()
+}
+
+func micros(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+func processName() string {
+	args := append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...)
+	return strings.Join(args, " ")
+}
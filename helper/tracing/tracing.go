@@ -0,0 +1,265 @@
+// Package tracing records spans of the time that builds spend in their
+// steps, provisioners and communicators, so that it can be seen whether
+// the time goes to downloads, boot waits or provisioning. Spans are
+// written to a trace file in the trace event format, which Perfetto and
+// chrome://tracing open, or sent to an OTLP endpoint, or both.
+//
+// Tracing is set up by the build command with Setup, which puts the trace
+// ID in the environment so that plugins, which inherit it, add their spans
+// to the same trace. All methods can be called on nil Tracers and Spans, so
+// that code doesn't need to check whether tracing is enabled.
+package tracing
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvFile is the path of the trace file to write.
+	EnvFile = "PACKER_TRACE"
+
+	// EnvEndpoint is the URL of the OTLP/HTTP endpoint to send spans to,
+	// such as "http://localhost:4318".
+	EnvEndpoint = "PACKER_TRACE_OTLP_ENDPOINT"
+
+	// EnvTraceID is the ID of the trace, which Setup generates.
+	EnvTraceID = "PACKER_TRACE_ID"
+)
+
+// exporter writes finished spans somewhere.
+type exporter interface {
+	Export(*Span) error
+}
+
+// Tracer starts the spans of a trace and exports them when they finish.
+type Tracer struct {
+	TraceID string
+
+	exporters []exporter
+	lock      sync.Mutex
+}
+
+var (
+	defaultTracer *Tracer
+	defaultOnce   sync.Once
+)
+
+// Default returns the tracer that is configured by the environment, or
+// nil if tracing isn't enabled.
+func Default() *Tracer {
+	defaultOnce.Do(func() {
+		t, err := fromEnv()
+		if err != nil {
+			log.Printf("[WARN] Tracing is disabled: %s", err)
+			return
+		}
+
+		defaultTracer = t
+	})
+
+	return defaultTracer
+}
+
+// Setup enables tracing for this run of Packer and the plugins that it
+// starts afterwards if a trace file or an OTLP endpoint is configured. It
+// returns a function that completes the trace file, which must be called
+// once everything has finished.
+func Setup() (func(), error) {
+	path := os.Getenv(EnvFile)
+	if path == "" && os.Getenv(EnvEndpoint) == "" {
+		return func() {}, nil
+	}
+
+	if os.Getenv(EnvTraceID) == "" {
+		id, err := randomID(16)
+		if err != nil {
+			return nil, err
+		}
+		os.Setenv(EnvTraceID, id)
+	}
+
+	closeFn := func() {}
+	if path != "" {
+		f, err := createTraceFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating trace file: %s", err)
+		}
+		closeFn = f.Close
+	}
+
+	log.Printf("Tracing is enabled, trace ID: %s", os.Getenv(EnvTraceID))
+	return closeFn, nil
+}
+
+func fromEnv() (*Tracer, error) {
+	traceID := os.Getenv(EnvTraceID)
+	if traceID == "" {
+		return nil, nil
+	}
+	if b, err := hex.DecodeString(traceID); err != nil || len(b) != 16 {
+		return nil, fmt.Errorf("%s must be 32 hexadecimal digits", EnvTraceID)
+	}
+
+	t := &Tracer{TraceID: traceID}
+	if path := os.Getenv(EnvFile); path != "" {
+		t.exporters = append(t.exporters, &fileExporter{Path: path})
+	}
+	if endpoint := os.Getenv(EnvEndpoint); endpoint != "" {
+		t.exporters = append(t.exporters, newOTLPExporter(endpoint))
+	}
+
+	return t, nil
+}
+
+// Start starts a span with the given parent, which is empty for spans at
+// the root of the trace. Spans of the same lane, such as of one build, are
+// shown together.
+func (t *Tracer) Start(name, parentID, lane string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	id, err := randomID(8)
+	if err != nil {
+		log.Printf("[WARN] Error starting span %s: %s", name, err)
+		return nil
+	}
+
+	return t.start(name, id, parentID, lane)
+}
+
+// StartBuild starts the span of the build with the given name. Its ID
+// is derived from the trace ID and the name, so that plugins can add
+// their spans to it with StartInBuild.
+func (t *Tracer) StartBuild(name string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	return t.start("build "+name, BuildSpanID(t.TraceID, name), "", name)
+}
+
+// StartInBuild starts a span within the span of the build with the given
+// name.
+func (t *Tracer) StartInBuild(name, build string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	return t.Start(name, BuildSpanID(t.TraceID, build), build)
+}
+
+func (t *Tracer) start(name, id, parentID, lane string) *Span {
+	return &Span{
+		Name:     name,
+		TraceID:  t.TraceID,
+		ID:       id,
+		ParentID: parentID,
+		Lane:     lane,
+		Start:    time.Now(),
+		Attrs:    make(map[string]string),
+		tracer:   t,
+	}
+}
+
+func (t *Tracer) export(s *Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Exporters that fail are dropped, so that a missing collector
+	// doesn't slow down every step of the build.
+	exporters := t.exporters[:0]
+	for _, e := range t.exporters {
+		if err := e.Export(s); err != nil {
+			log.Printf("[WARN] Error exporting spans, no longer exporting to it: %s", err)
+			continue
+		}
+		exporters = append(exporters, e)
+	}
+	t.exporters = exporters
+}
+
+// BuildSpanID returns the ID of the span of the build with the given name
+// in the trace.
+func BuildSpanID(traceID, build string) string {
+	sum := sha256.Sum256([]byte(traceID + "/" + build))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Span is an operation of the build that took some time.
+type Span struct {
+	Name     string
+	TraceID  string
+	ID       string
+	ParentID string
+	Lane     string
+	Start    time.Time
+	End      time.Time
+	Attrs    map[string]string
+	Failed   bool
+
+	tracer *Tracer
+	lock   sync.Mutex
+}
+
+// Child starts a span within this one.
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+
+	return s.tracer.Start(name, s.ID, s.Lane)
+}
+
+// SetAttr sets an attribute of the span, such as the command that a
+// communicator ran.
+func (s *Span) SetAttr(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Attrs[key] = value
+}
+
+// SetError marks the span as failed with the given error, if any.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Failed = true
+	s.Attrs["error"] = err.Error()
+}
+
+// Finish ends the span and exports it.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.End = time.Now()
+	s.lock.Unlock()
+
+	s.tracer.export(s)
+}
+
+func randomID(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,198 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testTraceID = "0123456789abcdef0123456789abcdef"
+
+func testEnv(t *testing.T, env map[string]string) func() {
+	old := make(map[string]string)
+	for _, k := range []string{EnvFile, EnvEndpoint, EnvTraceID} {
+		old[k] = os.Getenv(k)
+		os.Setenv(k, env[k])
+	}
+
+	return func() {
+		for k, v := range old {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+func TestNilSpan(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.StartBuild("foo")
+	if span != nil {
+		t.Fatalf("bad: %#v", span)
+	}
+
+	// None of these should panic
+	span.Child("bar").Finish()
+	span.SetAttr("foo", "bar")
+	span.SetError(errors.New("foo"))
+	span.Finish()
+}
+
+func TestBuildSpanID(t *testing.T) {
+	a := BuildSpanID(testTraceID, "foo")
+	if len(a) != 16 {
+		t.Fatalf("bad: %s", a)
+	}
+	if BuildSpanID(testTraceID, "foo") != a {
+		t.Fatal("should be the same")
+	}
+	if BuildSpanID(testTraceID, "bar") == a {
+		t.Fatal("should be different")
+	}
+
+	tracer := &Tracer{TraceID: testTraceID}
+	build := tracer.StartBuild("foo")
+	if build.ID != a {
+		t.Fatalf("bad: %s", build.ID)
+	}
+	if span := tracer.StartInBuild("step", "foo"); span.ParentID != a {
+		t.Fatalf("bad: %s", span.ParentID)
+	}
+	if child := build.Child("step"); child.ParentID != a || child.Lane != "foo" {
+		t.Fatalf("bad: %#v", child)
+	}
+}
+
+func TestSetup_disabled(t *testing.T) {
+	defer testEnv(t, nil)()
+
+	closeFn, err := Setup()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	closeFn()
+
+	if v := os.Getenv(EnvTraceID); v != "" {
+		t.Fatalf("bad: %s", v)
+	}
+	if tracer, err := fromEnv(); err != nil || tracer != nil {
+		t.Fatalf("bad: %#v %s", tracer, err)
+	}
+}
+
+func TestSetup_file(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "trace.json")
+	defer testEnv(t, map[string]string{EnvFile: path})()
+
+	closeFn, err := Setup()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tracer, err := fromEnv()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if tracer == nil || tracer.TraceID != os.Getenv(EnvTraceID) {
+		t.Fatalf("bad: %#v", tracer)
+	}
+
+	build := tracer.StartBuild("foo")
+	step := build.Child("step StepFoo")
+	step.SetError(errors.New("bad"))
+	step.Finish()
+	build.Finish()
+	closeFn()
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var events []traceEvent
+	if err := json.Unmarshal(contents, &events); err != nil {
+		t.Fatalf("invalid trace file: %s\n\n%s", err, contents)
+	}
+
+	var names []string
+	for _, e := range events {
+		if e.Phase == "X" {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) != 2 || names[0] != "step StepFoo" || names[1] != "build foo" {
+		t.Fatalf("bad: %#v", names)
+	}
+
+	for _, e := range events {
+		if e.Name == "step StepFoo" {
+			if e.Args["error"] != "bad" || e.Args["parent_id"] != build.ID {
+				t.Fatalf("bad: %#v", e)
+			}
+		}
+		if e.Name == "thread_name" && e.Args["name"] != "foo" {
+			t.Fatalf("bad: %#v", e)
+		}
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			w.WriteHeader(404)
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer ts.Close()
+
+	defer testEnv(t, map[string]string{
+		EnvEndpoint: ts.URL,
+		EnvTraceID:  testTraceID,
+	})()
+
+	tracer, err := fromEnv()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	span := tracer.StartBuild("foo")
+	span.SetAttr("builder", "qemu")
+	span.Finish()
+
+	if len(tracer.exporters) != 1 {
+		t.Fatal("exporter should still be enabled")
+	}
+
+	spans := body["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	exported := spans[0].(map[string]interface{})
+	if exported["traceId"] != testTraceID || exported["spanId"] != span.ID || exported["name"] != "build foo" {
+		t.Fatalf("bad: %#v", exported)
+	}
+
+	// Failing exporters are dropped
+	ts.Close()
+	tracer.StartBuild("bar").Finish()
+	if len(tracer.exporters) != 0 {
+		t.Fatal("exporter should be disabled")
+	}
+}
+
+func TestFromEnv_badTraceID(t *testing.T) {
+	defer testEnv(t, map[string]string{EnvTraceID: "foo"})()
+
+	if _, err := fromEnv(); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -137,6 +137,8 @@ func wrappedMain() int {
 	// Determine if we're in machine-readable mode by mucking around with
 	// the arguments...
 	args, machineReadable := extractMachineReadable(os.Args[1:])
+	args, quiet := extractFlag(args, "-quiet")
+	args, noColor := extractFlag(args, "-no-color")
 
 	defer plugin.CleanupClients()
 
@@ -153,12 +155,31 @@ func wrappedMain() int {
 
 		// Set this so that we don't get colored output in our machine-
 		// readable UI.
+		noColor = true
+	}
+
+	// Colors are disabled through the environment so that plugins, which
+	// inherit it, honor it as well.
+	if noColor {
 		if err := os.Setenv("PACKER_NO_COLOR", "1"); err != nil {
 			fmt.Fprintf(os.Stderr, "Packer failed to initialize UI: %s\n", err)
 			return 1
 		}
 	}
 
+	// Communicator plugins are started by the builders, which find the
+	// ones that were discovered here through the environment.
+	if err := os.Setenv("PACKER_COMMUNICATOR_PLUGINS", config.CommunicatorPlugins()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting up communicator plugins: %s\n", err)
+		return 1
+	}
+
+	// In quiet mode only errors, artifacts, and machine-readable output
+	// are shown.
+	if quiet {
+		ui = &packer.QuietUi{Ui: ui}
+	}
+
 	// Create the CLI meta
 	CommandMeta = &command.Meta{
 		CoreConfig: &packer.CoreConfig{
@@ -168,6 +189,9 @@ func wrappedMain() int {
 				PostProcessor: config.LoadPostProcessor,
 				Provisioner:   config.LoadProvisioner,
 			},
+			Version:    versionString(),
+			Plugins:    config.Plugins(),
+			BuildSlots: config.BuildSlots(),
 		},
 		Cache: cache,
 		Ui:    ui,
@@ -196,8 +220,14 @@ func wrappedMain() int {
 // flag and returns whether or not it is on. It modifies the args
 // to remove this flag.
 func extractMachineReadable(args []string) ([]string, bool) {
+	return extractFlag(args, "-machine-readable")
+}
+
+// extractFlag checks the args for the given boolean flag and returns
+// whether or not it is set. It modifies the args to remove this flag.
+func extractFlag(args []string, flag string) ([]string, bool) {
 	for i, arg := range args {
-		if arg == "-machine-readable" {
+		if arg == flag {
 			// We found it. Slice it out.
 			result := make([]string, len(args)-1)
 			copy(result, args[:i])
@@ -33,3 +33,21 @@ func TestExtractMachineReadable(t *testing.T) {
 		t.Fatal("should be mr")
 	}
 }
+
+func TestExtractFlag(t *testing.T) {
+	args := []string{"build", "-quiet", "-no-color", "template.json"}
+	result, quiet := extractFlag(args, "-quiet")
+	if !quiet {
+		t.Fatal("should be quiet")
+	}
+
+	result, noColor := extractFlag(result, "-no-color")
+	if !noColor {
+		t.Fatal("should be no-color")
+	}
+
+	expected := []string{"build", "template.json"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
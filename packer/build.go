@@ -1,9 +1,17 @@
 package packer
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+
+	"github.com/mitchellh/packer/helper/tracing"
+	"github.com/mitchellh/packer/template"
 )
 
 const (
@@ -24,12 +32,24 @@ const (
 	// force build is enabled.
 	ForceConfigKey = "packer_force"
 
+	// This is the key in configurations that is set to "windows" if the
+	// machine of the build runs Windows, going by the communicator and
+	// platform of the builder, so that provisioners that only work on
+	// Unix can refuse it. It is empty if the OS isn't known.
+	GuestOSConfigKey = "packer_guest_os"
+
 	// TemplatePathKey is the path to the template that configured this build
 	TemplatePathKey = "packer_template_path"
 
 	// This key contains a map[string]string of the user variables for
 	// template processing.
 	UserVariablesConfigKey = "packer_user_variables"
+
+	// This is the key in configurations that is set to the working
+	// directory of the build. Components should create their temporary
+	// files in it, so that builds running in parallel don't collide and
+	// nothing is left behind after the build.
+	WorkDirConfigKey = "packer_work_dir"
 )
 
 // A Build represents a single job within Packer that is responsible for
@@ -67,6 +87,22 @@ type Build interface {
 	// When SetForce is set to true, existing artifacts from the build are
 	// deleted prior to the build.
 	SetForce(bool)
+
+	// SetCaptureOutput sets the directory that the output of each
+	// provisioner is written to. The files are listed in the
+	// "provisioner_output" state of the builder's artifact. If the
+	// directory is empty, output isn't captured.
+	SetCaptureOutput(string)
+
+	// SetPostProcessorParallelism sets the number of post-processor
+	// sequences that run at the same time. The post-processors within a
+	// sequence always run one after another. Values below one are
+	// treated as one, which runs the sequences one at a time.
+	SetPostProcessorParallelism(int)
+
+	// SetKeepWorkDir will keep the working directory of the build after
+	// it has run rather than deleting it, which is useful for debugging.
+	SetKeepWorkDir(bool)
 }
 
 // A build struct represents a single build job, the result of which should
@@ -83,11 +119,16 @@ type coreBuild struct {
 	provisioners   []coreBuildProvisioner
 	templatePath   string
 	variables      map[string]string
+	verify         *template.Verify
 
+	captureDir    string
 	debug         bool
 	force         bool
+	keepWorkDir   bool
 	l             sync.Mutex
+	ppParallelism int
 	prepareCalled bool
+	workDir       string
 }
 
 // Keeps track of the post-processor and the configuration of the
@@ -102,8 +143,9 @@ type coreBuildPostProcessor struct {
 // Keeps track of the provisioner and the configuration of the provisioner
 // within the build.
 type coreBuildProvisioner struct {
-	provisioner Provisioner
-	config      []interface{}
+	provisioner     Provisioner
+	provisionerType string
+	config          []interface{}
 }
 
 // Returns the name of the build.
@@ -124,13 +166,22 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 
 	b.prepareCalled = true
 
+	// The working directory is only created once the build runs, so
+	// that validating a template doesn't leave directories behind.
+	b.workDir, err = newWorkDirPath()
+	if err != nil {
+		return
+	}
+
 	packerConfig := map[string]interface{}{
 		BuildNameConfigKey:     b.name,
 		BuilderTypeConfigKey:   b.builderType,
 		DebugConfigKey:         b.debug,
 		ForceConfigKey:         b.force,
+		GuestOSConfigKey:       guestOS(b.builderConfig),
 		TemplatePathKey:        b.templatePath,
 		UserVariablesConfigKey: b.variables,
+		WorkDirConfigKey:       b.workDir,
 	}
 
 	// Prepare the builder
@@ -140,13 +191,33 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 		return
 	}
 
+	// Only some builders can boot their artifacts to verify them
+	if b.verify != nil {
+		verifier, ok := b.builder.(ArtifactVerifier)
+		if !ok {
+			err = fmt.Errorf("The %s builder can't verify its artifacts", b.builderType)
+			return
+		}
+
+		if err = verifier.PrepareVerify(); err != nil {
+			err = fmt.Errorf("verify: %s", err)
+			return
+		}
+	}
+
 	// Prepare the provisioners
 	for _, coreProv := range b.provisioners {
 		configs := make([]interface{}, len(coreProv.config), len(coreProv.config)+1)
 		copy(configs, coreProv.config)
 		configs = append(configs, packerConfig)
 
-		if err = coreProv.provisioner.Prepare(configs...); err != nil {
+		var provWarn []string
+		provWarn, err = SplitWarnings(coreProv.provisioner.Prepare(configs...))
+		for _, w := range provWarn {
+			warn = append(warn, fmt.Sprintf(
+				"provisioner '%s': %s", coreProv.provisionerType, w))
+		}
+		if err != nil {
 			return
 		}
 	}
@@ -154,7 +225,13 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 	// Prepare the post-processors
 	for _, ppSeq := range b.postProcessors {
 		for _, corePP := range ppSeq {
-			err = corePP.processor.Configure(corePP.config, packerConfig)
+			var ppWarn []string
+			ppWarn, err = SplitWarnings(
+				corePP.processor.Configure(corePP.config, packerConfig))
+			for _, w := range ppWarn {
+				warn = append(warn, fmt.Sprintf(
+					"post-processor '%s': %s", corePP.processorType, w))
+			}
 			if err != nil {
 				return
 			}
@@ -164,12 +241,52 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 	return
 }
 
+// guestOS returns "windows" if the configuration of the builder connects
+// with WinRM or is for a Windows platform, such as the "windows/amd64" of
+// the Docker builder, or else an empty string.
+func guestOS(raw interface{}) string {
+	config, ok := raw.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	if v, ok := config["communicator"].(string); ok && v == "winrm" {
+		return "windows"
+	}
+	if v, ok := config["platform"].(string); ok && strings.HasPrefix(v, "windows/") {
+		return "windows"
+	}
+
+	return ""
+}
+
 // Runs the actual build. Prepare must be called prior to running this.
 func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 	if !b.prepareCalled {
 		panic("Prepare must be called first")
 	}
 
+	tracer := tracing.Default()
+	span := tracer.StartBuild(b.name)
+	span.SetAttr("builder", b.builderType)
+	defer span.Finish()
+
+	log.Printf("Build '%s' working directory: %s", b.name, b.workDir)
+	if err := os.MkdirAll(b.workDir, 0700); err != nil {
+		return nil, fmt.Errorf("Error creating working directory: %s", err)
+	}
+	defer func() {
+		if b.keepWorkDir {
+			originalUi.Say(fmt.Sprintf(
+				"Keeping working directory of build '%s': %s", b.name, b.workDir))
+			return
+		}
+
+		if err := os.RemoveAll(b.workDir); err != nil {
+			log.Printf("Error removing working directory %s: %s", b.workDir, err)
+		}
+	}()
+
 	// Copy the hooks
 	hooks := make(map[string][]Hook)
 	for hookName, hookList := range b.hooks {
@@ -178,19 +295,43 @@ func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 	}
 
 	// Add a hook for the provisioners if we have provisioners
+	var capturedFiles []string
+	var provHook *ProvisionHook
 	if len(b.provisioners) > 0 {
 		provisioners := make([]Provisioner, len(b.provisioners))
 		for i, p := range b.provisioners {
 			provisioners[i] = p.provisioner
+
+			// If we're capturing output, each provisioner writes to its
+			// own files, numbered in the order that they run.
+			if b.captureDir != "" {
+				path := filepath.Join(b.captureDir, b.name,
+					fmt.Sprintf("%02d-%s", i+1, p.provisionerType))
+				provisioners[i] = &CapturedProvisioner{
+					Path:        path,
+					Provisioner: p.provisioner,
+				}
+
+				capturedFiles = append(capturedFiles,
+					path+".stdout", path+".stderr")
+			}
+
+			if tracer != nil {
+				provisioners[i] = &TracedProvisioner{
+					Build:       b.name,
+					Type:        p.provisionerType,
+					Tracer:      tracer,
+					Provisioner: provisioners[i],
+				}
+			}
 		}
 
 		if _, ok := hooks[HookProvision]; !ok {
 			hooks[HookProvision] = make([]Hook, 0, 1)
 		}
 
-		hooks[HookProvision] = append(hooks[HookProvision], &ProvisionHook{
-			Provisioners: provisioners,
-		})
+		provHook = &ProvisionHook{Provisioners: provisioners}
+		hooks[HookProvision] = append(hooks[HookProvision], provHook)
 	}
 
 	hook := &DispatchHook{Mapping: hooks}
@@ -205,7 +346,15 @@ func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 	log.Printf("Running builder: %s", b.builderType)
 	builderArtifact, err := b.builder.Run(builderUi, hook, cache)
 	if err != nil {
-		return nil, err
+		// The builder fails if a provisioner does, but the hook knows
+		// which one it was since it always runs here, in the core.
+		stage := BuildStageBuilder
+		if provHook != nil && provHook.failed() {
+			stage = BuildStageProvisioner
+		}
+
+		span.SetError(err)
+		return nil, &BuildError{Stage: stage, Err: err}
 	}
 
 	// If there was no result, don't worry about running post-processors
@@ -214,62 +363,51 @@ func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 		return nil, nil
 	}
 
-	errors := make([]error, 0)
-	keepOriginalArtifact := len(b.postProcessors) == 0
-
-	// Run the post-processors
-PostProcessorRunSeqLoop:
-	for _, ppSeq := range b.postProcessors {
-		priorArtifact := builderArtifact
-		for i, corePP := range ppSeq {
-			ppUi := &TargettedUi{
-				Target: fmt.Sprintf("%s (%s)", b.Name(), corePP.processorType),
-				Ui:     originalUi,
-			}
+	// A broken artifact must not be post-processed, which may publish it
+	if b.verify != nil {
+		if err := b.verifyArtifact(builderUi, builderArtifact); err != nil {
+			span.SetError(err)
+			return nil, &BuildError{Stage: BuildStageVerify, Err: err}
+		}
+	}
 
-			builderUi.Say(fmt.Sprintf("Running post-processor: %s", corePP.processorType))
-			artifact, keep, err := corePP.processor.PostProcess(ppUi, priorArtifact)
-			if err != nil {
-				errors = append(errors, fmt.Errorf("Post-processor failed: %s", err))
-				continue PostProcessorRunSeqLoop
-			}
+	if len(capturedFiles) > 0 {
+		builderArtifact = &capturedArtifact{
+			Artifact: builderArtifact,
+			files:    capturedFiles,
+		}
+	}
 
-			if artifact == nil {
-				log.Println("Nil artifact, halting post-processor chain.")
-				continue PostProcessorRunSeqLoop
-			}
+	errors := make([]error, 0)
+	keepOriginalArtifact := len(b.postProcessors) == 0
 
-			keep = keep || corePP.keepInputArtifact
-			if i == 0 {
-				// This is the first post-processor. We handle deleting
-				// previous artifacts a bit different because multiple
-				// post-processors may be using the original and need it.
-				if !keepOriginalArtifact && keep {
-					log.Printf(
-						"Flagging to keep original artifact from post-processor '%s'",
-						corePP.processorType)
-					keepOriginalArtifact = true
-				}
-			} else {
-				// We have a prior artifact. If we want to keep it, we append
-				// it to the results list. Otherwise, we destroy it.
-				if keep {
-					artifacts = append(artifacts, priorArtifact)
-				} else {
-					log.Printf("Deleting prior artifact from post-processor '%s'", corePP.processorType)
-					if err := priorArtifact.Destroy(); err != nil {
-						errors = append(errors, fmt.Errorf("Failed cleaning up prior artifact: %s", err))
-					}
-				}
-			}
+	// Run the post-processor sequences, up to ppParallelism at a time.
+	// The results are kept in the order of the sequences so that the
+	// artifacts are listed in the same order no matter how they ran.
+	parallelism := b.ppParallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
 
-			priorArtifact = artifact
-		}
+	results := make([]postProcessorSeqResult, len(b.postProcessors))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, ppSeq := range b.postProcessors {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ppSeq []coreBuildPostProcessor) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = b.runPostProcessorSeq(ppSeq, builderArtifact, originalUi, builderUi)
+		}(i, ppSeq)
+	}
+	wg.Wait()
 
-		// Add on the last artifact to the results
-		if priorArtifact != nil {
-			artifacts = append(artifacts, priorArtifact)
-		}
+	for _, result := range results {
+		artifacts = append(artifacts, result.artifacts...)
+		errors = append(errors, result.errors...)
+		keepOriginalArtifact = keepOriginalArtifact || result.keepOriginal
 	}
 
 	if keepOriginalArtifact {
@@ -284,12 +422,117 @@ PostProcessorRunSeqLoop:
 	}
 
 	if len(errors) > 0 {
-		err = &MultiError{errors}
+		err = &BuildError{
+			Stage: BuildStagePostProcessor,
+			Err:   &MultiError{errors},
+		}
+		span.SetError(err)
 	}
 
 	return artifacts, err
 }
 
+// verifyArtifact verifies the artifact of the builder with the command
+// of the template. The builder was checked to be an ArtifactVerifier
+// when the build was prepared.
+func (b *coreBuild) verifyArtifact(ui Ui, artifact Artifact) error {
+	timeout := b.verify.Timeout
+	if timeout == 0 {
+		timeout = DefaultVerifyTimeout
+	}
+
+	ui.Say(fmt.Sprintf("Verifying the artifact with: %s", b.verify.Command))
+	span := tracing.Default().StartInBuild("verify", b.name)
+	err := b.builder.(ArtifactVerifier).VerifyArtifact(ui, artifact, b.verify.Command, timeout)
+	span.SetError(err)
+	span.Finish()
+	if err != nil {
+		return fmt.Errorf(
+			"Error verifying the artifact: %s\n"+
+				"The artifact wasn't post-processed, and is kept to debug it: %s",
+			err, artifact.String())
+	}
+
+	ui.Say("The artifact was verified.")
+	return nil
+}
+
+// postProcessorSeqResult is the result of running one sequence of
+// post-processors.
+type postProcessorSeqResult struct {
+	artifacts    []Artifact
+	errors       []error
+	keepOriginal bool
+}
+
+// runPostProcessorSeq runs a sequence of post-processors on the artifact
+// of the builder, each one on the artifact of the one before it.
+func (b *coreBuild) runPostProcessorSeq(ppSeq []coreBuildPostProcessor, builderArtifact Artifact, originalUi Ui, builderUi Ui) postProcessorSeqResult {
+	var result postProcessorSeqResult
+	b.runPostProcessors(ppSeq, 0, builderArtifact, originalUi, builderUi, &result)
+	return result
+}
+
+// runPostProcessors runs the post-processors of the sequence from index i
+// on the prior artifact. A post-processor can produce several artifacts,
+// in which case the rest of the sequence runs on each of them in turn.
+func (b *coreBuild) runPostProcessors(ppSeq []coreBuildPostProcessor, i int, priorArtifact Artifact, originalUi Ui, builderUi Ui, result *postProcessorSeqResult) {
+	// Add on the last artifact to the results
+	if i == len(ppSeq) {
+		result.artifacts = append(result.artifacts, priorArtifact)
+		return
+	}
+
+	corePP := ppSeq[i]
+	ppUi := &TargettedUi{
+		Target: fmt.Sprintf("%s (%s)", b.Name(), corePP.processorType),
+		Ui:     originalUi,
+	}
+
+	builderUi.Say(fmt.Sprintf("Running post-processor: %s", corePP.processorType))
+	span := tracing.Default().StartInBuild("post-processor "+corePP.processorType, b.name)
+	artifacts, keep, err := PostProcessArtifacts(corePP.processor, ppUi, priorArtifact)
+	span.SetError(err)
+	span.Finish()
+	if err != nil {
+		result.errors = append(result.errors, fmt.Errorf("Post-processor failed: %s", err))
+		return
+	}
+
+	if len(artifacts) == 0 {
+		log.Println("Nil artifact, halting post-processor chain.")
+		return
+	}
+
+	keep = keep || corePP.keepInputArtifact
+	if i == 0 {
+		// This is the first post-processor. We handle deleting
+		// previous artifacts a bit different because multiple
+		// post-processors may be using the original and need it.
+		if keep {
+			log.Printf(
+				"Flagging to keep original artifact from post-processor '%s'",
+				corePP.processorType)
+			result.keepOriginal = true
+		}
+	} else {
+		// We have a prior artifact. If we want to keep it, we append
+		// it to the results list. Otherwise, we destroy it.
+		if keep {
+			result.artifacts = append(result.artifacts, priorArtifact)
+		} else {
+			log.Printf("Deleting prior artifact from post-processor '%s'", corePP.processorType)
+			if err := priorArtifact.Destroy(); err != nil {
+				result.errors = append(result.errors, fmt.Errorf("Failed cleaning up prior artifact: %s", err))
+			}
+		}
+	}
+
+	for _, artifact := range artifacts {
+		b.runPostProcessors(ppSeq, i+1, artifact, originalUi, builderUi, result)
+	}
+}
+
 func (b *coreBuild) SetDebug(val bool) {
 	if b.prepareCalled {
 		panic("prepare has already been called")
@@ -306,7 +549,58 @@ func (b *coreBuild) SetForce(val bool) {
 	b.force = val
 }
 
+func (b *coreBuild) SetCaptureOutput(dir string) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.captureDir = dir
+}
+
+func (b *coreBuild) SetPostProcessorParallelism(n int) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.ppParallelism = n
+}
+
+func (b *coreBuild) SetKeepWorkDir(val bool) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.keepWorkDir = val
+}
+
 // Cancels the build if it is running.
 func (b *coreBuild) Cancel() {
 	b.builder.Cancel()
 }
+
+// capturedArtifact is an Artifact that adds the files that provisioner
+// output was captured to as the "provisioner_output" state.
+type capturedArtifact struct {
+	Artifact
+
+	files []string
+}
+
+func (a *capturedArtifact) State(name string) interface{} {
+	if name == "provisioner_output" {
+		return a.files
+	}
+
+	return a.Artifact.State(name)
+}
+
+// newWorkDirPath returns a unique path for the working directory of a
+// build within the directory for temporary files.
+func newWorkDirPath() (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("Error generating working directory name: %s", err)
+	}
+
+	return filepath.Join(os.TempDir(), "packer-build-"+hex.EncodeToString(suffix)), nil
+}
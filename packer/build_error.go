@@ -0,0 +1,33 @@
+package packer
+
+// These are the stages of a build that an error can come from.
+const (
+	BuildStageBuilder       = "builder"
+	BuildStageProvisioner   = "provisioner"
+	BuildStageVerify        = "verify"
+	BuildStagePostProcessor = "post-processor"
+)
+
+// BuildError is an error returned from running a Build that records the
+// stage of the build that failed, so that failures of the builder, the
+// provisioners, the verification and the post-processors can be told
+// apart.
+type BuildError struct {
+	Stage string
+	Err   error
+}
+
+func (e *BuildError) Error() string {
+	return e.Err.Error()
+}
+
+// BuildErrorStage returns the stage of the build that the error came
+// from. Errors that don't record a stage are considered to have come
+// from the builder.
+func BuildErrorStage(err error) string {
+	if err, ok := err.(*BuildError); ok {
+		return err.Stage
+	}
+
+	return BuildStageBuilder
+}
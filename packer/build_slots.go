@@ -0,0 +1,94 @@
+package packer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrBuildSlotCancelled is returned by BuildSlots.Acquire if waiting for a
+// build slot was cancelled.
+var ErrBuildSlotCancelled = errors.New("cancelled while waiting for a build slot")
+
+// buildSlotPollInterval is how often a free build slot is looked for.
+var buildSlotPollInterval = time.Second
+
+// BuildSlots limits the number of builds that run at the same time on a
+// host, across all of the Packer processes that share the lock directory.
+// This lets independent Packer invocations on a shared host, such as a CI
+// runner, queue instead of oversubscribing its CPU, memory or KVM.
+//
+// Each slot is a lock file in the directory. The locks are released by the
+// operating system if Packer exits without releasing them.
+type BuildSlots struct {
+	// Dir is the directory with the lock files.
+	Dir string
+
+	// Max is the maximum number of builds that run at the same time.
+	Max int
+}
+
+// BuildSlot is a build slot that is held by a running build.
+type BuildSlot struct {
+	f *os.File
+}
+
+// Acquire waits until a build slot is free and takes it. The UI is told
+// if the build has to wait. Waiting stops with ErrBuildSlotCancelled once
+// cancelCh is closed.
+func (s *BuildSlots) Acquire(ui Ui, cancelCh <-chan struct{}) (*BuildSlot, error) {
+	if err := os.MkdirAll(s.Dir, 0777); err != nil {
+		return nil, fmt.Errorf("Error creating build lock directory: %s", err)
+	}
+
+	waiting := false
+	for {
+		for i := 0; i < s.Max; i++ {
+			slot, err := s.tryAcquire(i)
+			if err != nil {
+				return nil, err
+			}
+			if slot != nil {
+				return slot, nil
+			}
+		}
+
+		if !waiting {
+			waiting = true
+			ui.Say(fmt.Sprintf(
+				"Waiting for a build slot, %d build(s) are already running on this host...",
+				s.Max))
+		}
+
+		select {
+		case <-cancelCh:
+			return nil, ErrBuildSlotCancelled
+		case <-time.After(buildSlotPollInterval):
+		}
+	}
+}
+
+// tryAcquire takes the given slot if it's free, returning nil otherwise.
+func (s *BuildSlots) tryAcquire(i int) (*BuildSlot, error) {
+	path := filepath.Join(s.Dir, fmt.Sprintf("slot-%d.lock", i))
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening build lock: %s", err)
+	}
+
+	ok, err := tryLockFile(f)
+	if err != nil || !ok {
+		f.Close()
+		return nil, err
+	}
+
+	return &BuildSlot{f: f}, nil
+}
+
+// Release frees the build slot.
+func (s *BuildSlot) Release() error {
+	defer s.f.Close()
+	return unlockFile(s.f)
+}
@@ -0,0 +1,93 @@
+package packer
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestBuildSlots(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("build slots are not supported on Windows")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	old := buildSlotPollInterval
+	buildSlotPollInterval = time.Millisecond
+	defer func() { buildSlotPollInterval = old }()
+
+	slots := &BuildSlots{Dir: td, Max: 2}
+	ui := testUi()
+
+	first, err := slots.Acquire(ui, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	second, err := slots.Acquire(ui, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// All of the slots are taken, so the next build waits
+	acquiredCh := make(chan *BuildSlot, 1)
+	go func() {
+		slot, err := slots.Acquire(ui, nil)
+		if err != nil {
+			t.Errorf("err: %s", err)
+		}
+		acquiredCh <- slot
+	}()
+
+	select {
+	case <-acquiredCh:
+		t.Fatal("should wait for a slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	select {
+	case slot := <-acquiredCh:
+		slot.Release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("should've gotten a slot")
+	}
+
+	second.Release()
+}
+
+func TestBuildSlots_cancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("build slots are not supported on Windows")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	slots := &BuildSlots{Dir: td, Max: 1}
+	ui := testUi()
+
+	slot, err := slots.Acquire(ui, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer slot.Release()
+
+	cancelCh := make(chan struct{})
+	close(cancelCh)
+	if _, err := slots.Acquire(ui, cancelCh); err != ErrBuildSlotCancelled {
+		t.Fatalf("bad: %#v", err)
+	}
+}
@@ -0,0 +1,16 @@
+// +build windows
+
+package packer
+
+import (
+	"errors"
+	"os"
+)
+
+func tryLockFile(*os.File) (bool, error) {
+	return false, errors.New("build slots are not supported on Windows")
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -1,8 +1,17 @@
 package packer
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/mitchellh/packer/template"
 )
 
 func testBuild() *coreBuild {
@@ -15,7 +24,7 @@ func testBuild() *coreBuild {
 			"foo": []Hook{&MockHook{}},
 		},
 		provisioners: []coreBuildProvisioner{
-			coreBuildProvisioner{&MockProvisioner{}, []interface{}{42}},
+			coreBuildProvisioner{&MockProvisioner{}, "mock", []interface{}{42}},
 		},
 		postProcessors: [][]coreBuildPostProcessor{
 			[]coreBuildPostProcessor{
@@ -32,6 +41,7 @@ func testDefaultPackerConfig() map[string]interface{} {
 		BuilderTypeConfigKey:   "foo",
 		DebugConfigKey:         false,
 		ForceConfigKey:         false,
+		GuestOSConfigKey:       "",
 		TemplatePathKey:        "",
 		UserVariablesConfigKey: make(map[string]string),
 	}
@@ -50,6 +60,7 @@ func TestBuild_Prepare(t *testing.T) {
 	builder := build.builder.(*MockBuilder)
 
 	build.Prepare()
+	packerConfig[WorkDirConfigKey] = build.workDir
 	if !builder.PrepareCalled {
 		t.Fatal("should be called")
 	}
@@ -76,6 +87,33 @@ func TestBuild_Prepare(t *testing.T) {
 	}
 }
 
+func TestBuild_Prepare_guestOS(t *testing.T) {
+	cases := []struct {
+		Config   interface{}
+		Expected string
+	}{
+		{42, ""},
+		{map[string]interface{}{"communicator": "ssh"}, ""},
+		{map[string]interface{}{"communicator": "winrm"}, "windows"},
+		{map[string]interface{}{"platform": "linux/amd64"}, ""},
+		{map[string]interface{}{"platform": "windows/amd64"}, "windows"},
+	}
+
+	for _, tc := range cases {
+		build := testBuild()
+		build.builderConfig = tc.Config
+		if _, err := build.Prepare(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		prov := build.provisioners[0].provisioner.(*MockProvisioner)
+		packerConfig := prov.PrepConfigs[len(prov.PrepConfigs)-1].(map[string]interface{})
+		if packerConfig[GuestOSConfigKey] != tc.Expected {
+			t.Fatalf("%#v: bad: %#v", tc.Config, packerConfig[GuestOSConfigKey])
+		}
+	}
+}
+
 func TestBuild_Prepare_Twice(t *testing.T) {
 	build := testBuild()
 	warn, err := build.Prepare()
@@ -116,6 +154,27 @@ func TestBuildPrepare_BuilderWarniings(t *testing.T) {
 	}
 }
 
+func TestBuildPrepare_ProvisionerWarnings(t *testing.T) {
+	build := testBuild()
+	prov := build.provisioners[0].provisioner.(*MockProvisioner)
+	prov.PrepError = Warnings{"foo"}
+	pp := build.postProcessors[0][0].processor.(*MockPostProcessor)
+	pp.ConfigureError = Warnings{"bar"}
+
+	warn, err := build.Prepare()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"provisioner 'mock': foo",
+		"post-processor 'testPP': bar",
+	}
+	if !reflect.DeepEqual(warn, expected) {
+		t.Fatalf("bad: %#v", warn)
+	}
+}
+
 func TestBuild_Prepare_Debug(t *testing.T) {
 	packerConfig := testDefaultPackerConfig()
 	packerConfig[DebugConfigKey] = true
@@ -125,6 +184,7 @@ func TestBuild_Prepare_Debug(t *testing.T) {
 
 	build.SetDebug(true)
 	build.Prepare()
+	packerConfig[WorkDirConfigKey] = build.workDir
 	if !builder.PrepareCalled {
 		t.Fatalf("should be called")
 	}
@@ -164,6 +224,7 @@ func TestBuildPrepare_variables_default(t *testing.T) {
 		t.Fatal("prepare should be called")
 	}
 
+	packerConfig[WorkDirConfigKey] = build.workDir
 	if !reflect.DeepEqual(builder.PrepareConfig[1], packerConfig) {
 		t.Fatalf("prepare bad: %#v", builder.PrepareConfig[1])
 	}
@@ -215,6 +276,192 @@ func TestBuild_Run(t *testing.T) {
 	}
 }
 
+func TestBuild_Run_WorkDir(t *testing.T) {
+	cache := &TestCache{}
+	ui := testUi()
+
+	build := testBuild()
+	build.Prepare()
+	workDir := build.workDir
+	if workDir == "" {
+		t.Fatal("should have a working directory")
+	}
+	if _, err := os.Stat(workDir); err == nil {
+		t.Fatal("working directory should not exist before the build runs")
+	}
+
+	prov := build.provisioners[0].provisioner.(*MockProvisioner)
+	prov.ProvFunc = func() error {
+		_, err := os.Stat(workDir)
+		return err
+	}
+
+	if _, err := build.Run(ui, cache); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(workDir); err == nil {
+		t.Fatal("working directory should be removed")
+	}
+
+	// With keep-workdir the directory is kept
+	build = testBuild()
+	build.SetKeepWorkDir(true)
+	build.Prepare()
+	defer os.RemoveAll(build.workDir)
+
+	if _, err := build.Run(ui, cache); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(build.workDir); err != nil {
+		t.Fatalf("working directory should be kept: %s", err)
+	}
+
+	// Each build gets its own directory
+	other := testBuild()
+	other.Prepare()
+	if other.workDir == build.workDir {
+		t.Fatalf("working directories should differ: %s", other.workDir)
+	}
+}
+
+func TestBuild_Run_ErrorStage(t *testing.T) {
+	cache := &TestCache{}
+	ui := testUi()
+
+	// Builder failure
+	build := testBuild()
+	build.builder.(*MockBuilder).RunErrResult = true
+	build.Prepare()
+	_, err := build.Run(ui, cache)
+	if stage := BuildErrorStage(err); err == nil || stage != BuildStageBuilder {
+		t.Fatalf("bad: %s %#v", stage, err)
+	}
+
+	// Provisioner failure
+	build = testBuild()
+	build.provisioners[0].provisioner.(*MockProvisioner).ProvFunc = func() error {
+		return errors.New("failed")
+	}
+	build.Prepare()
+	_, err = build.Run(ui, cache)
+	if stage := BuildErrorStage(err); err == nil || stage != BuildStageProvisioner {
+		t.Fatalf("bad: %s %#v", stage, err)
+	}
+
+	// Verification failure
+	build = testBuild()
+	build.verify = &template.Verify{Command: "true"}
+	build.builder.(*MockBuilder).VerifyErrResult = true
+	build.Prepare()
+	_, err = build.Run(ui, cache)
+	if stage := BuildErrorStage(err); err == nil || stage != BuildStageVerify {
+		t.Fatalf("bad: %s %#v", stage, err)
+	}
+	if build.postProcessors[0][0].processor.(*MockPostProcessor).PostProcessCalled {
+		t.Fatal("should not post-process")
+	}
+
+	// Post-processor failure
+	build = testBuild()
+	build.postProcessors[0][0].processor.(*MockPostProcessor).Error = errors.New("failed")
+	build.Prepare()
+	_, err = build.Run(ui, cache)
+	if stage := BuildErrorStage(err); err == nil || stage != BuildStagePostProcessor {
+		t.Fatalf("bad: %s %#v", stage, err)
+	}
+}
+
+func TestBuild_Prepare_Verify(t *testing.T) {
+	build := testBuild()
+	build.verify = &template.Verify{Command: "true"}
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !build.builder.(*MockBuilder).PrepareVerifyCalled {
+		t.Fatal("should be called")
+	}
+
+	// Builders that can't verify their artifacts
+	build = testBuild()
+	build.builder = struct{ Builder }{new(MockBuilder)}
+	build.verify = &template.Verify{Command: "true"}
+	if _, err := build.Prepare(); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuild_Run_Verify(t *testing.T) {
+	cache := &TestCache{}
+	ui := testUi()
+
+	build := testBuild()
+	build.verify = &template.Verify{Command: "systemctl is-system-running"}
+	build.Prepare()
+	if _, err := build.Run(ui, cache); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	builder := build.builder.(*MockBuilder)
+	if !builder.VerifyCalled {
+		t.Fatal("should be called")
+	}
+	if builder.VerifiedArtifactId != "b" {
+		t.Fatalf("bad: %#v", builder.VerifiedArtifactId)
+	}
+	if builder.VerifyCommand != "systemctl is-system-running" {
+		t.Fatalf("bad: %s", builder.VerifyCommand)
+	}
+	if builder.VerifyTimeout != DefaultVerifyTimeout {
+		t.Fatalf("bad: %s", builder.VerifyTimeout)
+	}
+
+	// Without verify, the artifact isn't verified
+	build = testBuild()
+	build.Prepare()
+	if _, err := build.Run(ui, cache); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if build.builder.(*MockBuilder).VerifyCalled {
+		t.Fatal("should not be called")
+	}
+}
+
+func TestBuild_Run_CaptureOutput(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	build := testBuild()
+	build.postProcessors = [][]coreBuildPostProcessor{}
+	build.SetCaptureOutput(td)
+	build.Prepare()
+	artifacts, err := build.Run(testUi(), &TestCache{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Run the provisioners
+	builder := build.builder.(*MockBuilder)
+	if err := builder.RunHook.Run(HookProvision, testUi(), nil, 42); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := filepath.Join(td, "test", "01-mock")
+	expected := []string{path + ".stdout", path + ".stderr"}
+	actual := artifacts[0].State("provisioner_output")
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	for _, f := range expected {
+		if _, err := os.Stat(f); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+}
+
 func TestBuild_Run_Artifacts(t *testing.T) {
 	cache := &TestCache{}
 	ui := testUi()
@@ -351,6 +598,115 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	}
 }
 
+func TestBuild_Run_MultiPostProcessor(t *testing.T) {
+	cache := &TestCache{}
+	ui := testUi()
+
+	// Each artifact of the multi post-processor goes through the rest of
+	// the sequence on its own.
+	next := &MockPostProcessor{ArtifactId: "pp"}
+	build := testBuild()
+	build.postProcessors = [][]coreBuildPostProcessor{
+		[]coreBuildPostProcessor{
+			coreBuildPostProcessor{
+				&MockMultiPostProcessor{ArtifactIds: []string{"vhd", "vmdk"}}, "multi", make(map[string]interface{}), false,
+			},
+			coreBuildPostProcessor{next, "pp", make(map[string]interface{}), true},
+		},
+	}
+
+	build.Prepare()
+	artifacts, err := build.Run(ui, cache)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expectedIds := []string{"vhd", "pp", "vmdk", "pp"}
+	artifactIds := make([]string, len(artifacts))
+	for i, artifact := range artifacts {
+		artifactIds[i] = artifact.Id()
+	}
+
+	if !reflect.DeepEqual(artifactIds, expectedIds) {
+		t.Fatalf("unexpected ids: %#v", artifactIds)
+	}
+
+	// No artifacts stop the sequence
+	next.PostProcessCalled = false
+	build = testBuild()
+	build.postProcessors = [][]coreBuildPostProcessor{
+		[]coreBuildPostProcessor{
+			coreBuildPostProcessor{&MockMultiPostProcessor{}, "multi", make(map[string]interface{}), false},
+			coreBuildPostProcessor{next, "pp", make(map[string]interface{}), false},
+		},
+	}
+
+	build.Prepare()
+	if _, err := build.Run(ui, cache); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if next.PostProcessCalled {
+		t.Fatal("should not run the rest of the sequence")
+	}
+}
+
+// barrierPostProcessor is a post-processor that waits until all the
+// post-processors sharing its WaitGroup are running.
+type barrierPostProcessor struct {
+	MockPostProcessor
+
+	wg *sync.WaitGroup
+}
+
+func (p *barrierPostProcessor) PostProcess(ui Ui, a Artifact) (Artifact, bool, error) {
+	p.wg.Done()
+	doneCh := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(doneCh)
+	}()
+
+	select {
+	case <-doneCh:
+	case <-time.After(5 * time.Second):
+		return nil, false, fmt.Errorf("post-processors didn't run in parallel")
+	}
+
+	return p.MockPostProcessor.PostProcess(ui, a)
+}
+
+func TestBuild_Run_PostProcessorParallelism(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	build := testBuild()
+	build.postProcessors = nil
+	for _, id := range []string{"pp1", "pp2", "pp3"} {
+		pp := &barrierPostProcessor{MockPostProcessor{ArtifactId: id}, &wg}
+		build.postProcessors = append(build.postProcessors, []coreBuildPostProcessor{
+			coreBuildPostProcessor{pp, "pp", make(map[string]interface{}), false},
+		})
+	}
+	build.SetPostProcessorParallelism(3)
+
+	build.Prepare()
+	artifacts, err := build.Run(testUi(), &TestCache{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The artifacts are in the order of the sequences
+	expectedIds := []string{"pp1", "pp2", "pp3"}
+	artifactIds := make([]string, len(artifacts))
+	for i, artifact := range artifacts {
+		artifactIds[i] = artifact.Id()
+	}
+
+	if !reflect.DeepEqual(artifactIds, expectedIds) {
+		t.Fatalf("unexpected ids: %#v", artifactIds)
+	}
+}
+
 func TestBuild_RunBeforePrepare(t *testing.T) {
 	defer func() {
 		p := recover()
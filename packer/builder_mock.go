@@ -2,6 +2,7 @@ package packer
 
 import (
 	"errors"
+	"time"
 )
 
 // MockBuilder is an implementation of Builder that can be used for tests.
@@ -12,6 +13,8 @@ type MockBuilder struct {
 	PrepareWarnings []string
 	RunErrResult    bool
 	RunNilResult    bool
+	SchemaResult    map[string]interface{}
+	VerifyErrResult bool
 
 	PrepareCalled bool
 	PrepareConfig []interface{}
@@ -20,6 +23,12 @@ type MockBuilder struct {
 	RunHook       Hook
 	RunUi         Ui
 	CancelCalled  bool
+
+	PrepareVerifyCalled bool
+	VerifyCalled        bool
+	VerifiedArtifactId  string
+	VerifyCommand       string
+	VerifyTimeout       time.Duration
 }
 
 func (tb *MockBuilder) Prepare(config ...interface{}) ([]string, error) {
@@ -56,3 +65,25 @@ func (tb *MockBuilder) Run(ui Ui, h Hook, c Cache) (Artifact, error) {
 func (tb *MockBuilder) Cancel() {
 	tb.CancelCalled = true
 }
+
+func (tb *MockBuilder) ConfigSchema() map[string]interface{} {
+	return tb.SchemaResult
+}
+
+func (tb *MockBuilder) PrepareVerify() error {
+	tb.PrepareVerifyCalled = true
+	return nil
+}
+
+func (tb *MockBuilder) VerifyArtifact(ui Ui, a Artifact, command string, timeout time.Duration) error {
+	tb.VerifyCalled = true
+	tb.VerifiedArtifactId = a.Id()
+	tb.VerifyCommand = command
+	tb.VerifyTimeout = timeout
+
+	if tb.VerifyErrResult {
+		return errors.New("verify failed")
+	}
+
+	return nil
+}
@@ -77,6 +77,20 @@ type Communicator interface {
 	Download(string, io.Writer) error
 }
 
+// A CommunicatorProvider creates Communicators for a transport that isn't
+// built into Packer. Communicator plugins implement this so that builders
+// can use them by setting "communicator" to the name of the plugin.
+type CommunicatorProvider interface {
+	// Prepare is called with the "communicator_config" of the builder
+	// and should return an error if it isn't valid.
+	Prepare(...interface{}) error
+
+	// Connect returns a Communicator for the machine at the given host.
+	// It may be called more than once while waiting for the machine to
+	// become available.
+	Connect(host string) (Communicator, error)
+}
+
 // StartWithUi runs the remote command and streams the output to any
 // configured Writers for stdout/stderr, while also writing each line
 // as it comes to a Ui.
@@ -0,0 +1,35 @@
+package packer
+
+// MockCommunicatorProvider is a CommunicatorProvider implementation that
+// can be used for tests.
+type MockCommunicatorProvider struct {
+	PrepareCalled  bool
+	PrepareConfigs []interface{}
+	PrepareError   error
+
+	ConnectCalled bool
+	ConnectHost   string
+	ConnectComm   Communicator
+	ConnectError  error
+}
+
+func (p *MockCommunicatorProvider) Prepare(configs ...interface{}) error {
+	p.PrepareCalled = true
+	p.PrepareConfigs = configs
+	return p.PrepareError
+}
+
+func (p *MockCommunicatorProvider) Connect(host string) (Communicator, error) {
+	p.ConnectCalled = true
+	p.ConnectHost = host
+	if p.ConnectError != nil {
+		return nil, p.ConnectError
+	}
+
+	comm := p.ConnectComm
+	if comm == nil {
+		comm = new(MockCommunicator)
+	}
+
+	return comm, nil
+}
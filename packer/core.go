@@ -2,9 +2,12 @@ package packer
 
 import (
 	"fmt"
+	"log"
 	"sort"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-version"
 	"github.com/mitchellh/packer/template"
 	"github.com/mitchellh/packer/template/interpolate"
 )
@@ -17,6 +20,8 @@ type Core struct {
 	components ComponentFinder
 	variables  map[string]string
 	builds     map[string]*template.Builder
+	version    string
+	plugins    map[string]string
 }
 
 // CoreConfig is the structure for initializing a new Core. Once a CoreConfig
@@ -25,6 +30,18 @@ type CoreConfig struct {
 	Components ComponentFinder
 	Template   *template.Template
 	Variables  map[string]string
+
+	// Version is the version of Packer, which is recorded in manifests.
+	Version string
+
+	// Plugins maps components, such as "builder.amazon-ebs", to the path
+	// of the plugin binary that implements them. This is only used to
+	// record the plugins in manifests.
+	Plugins map[string]string
+
+	// BuildSlots, if set, limits the number of builds that run at the
+	// same time on this host.
+	BuildSlots *BuildSlots
 }
 
 // The function type used to lookup Builder implementations.
@@ -55,6 +72,8 @@ func NewCore(c *CoreConfig) (*Core, error) {
 		Template:   c.Template,
 		components: c.Components,
 		variables:  c.Variables,
+		version:    c.Version,
+		plugins:    c.Plugins,
 	}
 	if err := result.validate(); err != nil {
 		return nil, err
@@ -150,8 +169,9 @@ func (c *Core) Build(n string) (Build, error) {
 		}
 
 		provisioners = append(provisioners, coreBuildProvisioner{
-			provisioner: provisioner,
-			config:      config,
+			provisioner:     provisioner,
+			provisionerType: rawP.Type,
+			config:          config,
 		})
 	}
 
@@ -193,20 +213,73 @@ func (c *Core) Build(n string) (Build, error) {
 		postProcessors = append(postProcessors, current)
 	}
 
+	// The verify command is interpolated like the names of the builds
+	var verify *template.Verify
+	if configBuilder.Verify != nil {
+		v := *configBuilder.Verify
+		v.Command, err = interpolate.Render(v.Command, c.Context())
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error interpolating the verify command of '%s': %s", n, err)
+		}
+
+		verify = &v
+	}
+
+	// The communicator settings of the template are defaults of the builder
+	builderConfig := c.builderConfig(n, builder, configBuilder.Config)
+
 	// TODO hooks one day
 
 	return &coreBuild{
 		name:           n,
 		builder:        builder,
-		builderConfig:  configBuilder.Config,
+		builderConfig:  builderConfig,
 		builderType:    configBuilder.Type,
 		postProcessors: postProcessors,
 		provisioners:   provisioners,
 		templatePath:   c.Template.Path,
 		variables:      c.variables,
+		verify:         verify,
 	}, nil
 }
 
+// builderConfig returns the configuration of a builder with the settings
+// of the communicator block of the template merged in. The keys set by the
+// builder itself win. Only the keys in the config schema of the builder
+// are merged, so that builders without a communicator, such as
+// amazon-chroot, still accept the template. Builders without a schema
+// get none of them, since it isn't known which keys they accept.
+func (c *Core) builderConfig(
+	name string, builder Builder, config map[string]interface{}) map[string]interface{} {
+	if len(c.Template.Communicator) == 0 {
+		return config
+	}
+
+	var known map[string]interface{}
+	if s, ok := builder.(ConfigSchemer); ok {
+		if schema := s.ConfigSchema(); schema != nil {
+			known, _ = schema["properties"].(map[string]interface{})
+		}
+	}
+	if known == nil {
+		log.Printf("Build '%s' has no config schema, not using the communicator block", name)
+		return config
+	}
+
+	result := make(map[string]interface{}, len(config)+len(c.Template.Communicator))
+	for k, v := range c.Template.Communicator {
+		if _, ok := known[k]; ok {
+			result[k] = v
+		}
+	}
+	for k, v := range config {
+		result[k] = v
+	}
+
+	return result
+}
+
 // Context returns an interpolation context.
 func (c *Core) Context() *interpolate.Context {
 	return &interpolate.Context{
@@ -220,6 +293,13 @@ func (c *Core) Context() *interpolate.Context {
 // This will automatically call template.validate() in addition to doing
 // richer semantic checks around variables and so on.
 func (c *Core) validate() error {
+	// Templates written for a newer version of Packer may use features
+	// that this one doesn't know, so check the version before anything
+	// else to explain why the template doesn't work.
+	if err := c.validateMinVersion(); err != nil {
+		return err
+	}
+
 	// First validate the template in general, we can't do anything else
 	// unless the template itself is valid.
 	if err := c.Template.Validate(); err != nil {
@@ -244,6 +324,48 @@ func (c *Core) validate() error {
 	return err
 }
 
+// validateMinVersion returns an error if this version of Packer is older
+// than the min_packer_version of the template.
+func (c *Core) validateMinVersion() error {
+	if c.Template.MinVersion == "" {
+		return nil
+	}
+
+	min, err := version.NewVersion(c.Template.MinVersion)
+	if err != nil {
+		return fmt.Errorf("min_packer_version is invalid: %s", err)
+	}
+
+	// Without a version, such as when Packer is used as a library, there
+	// is nothing to compare with.
+	if c.version == "" {
+		return nil
+	}
+
+	// The version may have a pre-release marker and commit, such as
+	// "0.8.0.dev (abc123)". Pre-releases count as the version they lead
+	// up to, so that templates can be written against them.
+	raw := c.version
+	if idx := strings.IndexFunc(raw, func(r rune) bool {
+		return r != '.' && (r < '0' || r > '9')
+	}); idx >= 0 {
+		raw = raw[:idx]
+	}
+	current, err := version.NewVersion(strings.TrimRight(raw, "."))
+	if err != nil {
+		return fmt.Errorf("Invalid Packer version %q: %s", c.version, err)
+	}
+
+	if current.LessThan(min) {
+		return fmt.Errorf(
+			"This template requires Packer %s or newer, but this is Packer %s.\n"+
+				"Upgrade Packer to build or validate it.",
+			c.Template.MinVersion, c.version)
+	}
+
+	return nil
+}
+
 func (c *Core) init() error {
 	if c.variables == nil {
 		c.variables = make(map[string]string)
@@ -142,6 +142,69 @@ func TestCoreBuild_env(t *testing.T) {
 	}
 }
 
+func TestCoreBuild_communicator(t *testing.T) {
+	config := TestCoreConfig(t)
+	testCoreTemplate(t, config, fixtureDir("build-communicator.json"))
+	b := TestBuilder(t, config, "test")
+	core := TestCore(t, config)
+
+	cases := []struct {
+		Schema   map[string]interface{}
+		Expected map[string]interface{}
+	}{
+		// The settings of the builder win over those of the template
+		{
+			map[string]interface{}{
+				"properties": map[string]interface{}{
+					"ssh_timeout":  map[string]interface{}{"type": "string"},
+					"ssh_username": map[string]interface{}{"type": "string"},
+				},
+			},
+			map[string]interface{}{
+				"ssh_username": "packer",
+				"ssh_timeout":  "5m",
+			},
+		},
+
+		// Keys the schema of the builder doesn't have aren't inherited
+		{
+			map[string]interface{}{
+				"properties": map[string]interface{}{
+					"ssh_timeout": map[string]interface{}{"type": "string"},
+				},
+			},
+			map[string]interface{}{
+				"ssh_timeout": "5m",
+			},
+		},
+
+		// Builders without a schema don't inherit anything
+		{
+			nil,
+			map[string]interface{}{
+				"ssh_timeout": "5m",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		b.SchemaResult = tc.Schema
+
+		build, err := core.Build("test")
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err := build.Prepare(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		actual := b.PrepareConfig[0].(map[string]interface{})
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("bad: %#v", actual)
+		}
+	}
+}
+
 func TestCoreBuild_nonExist(t *testing.T) {
 	config := TestCoreConfig(t)
 	testCoreTemplate(t, config, fixtureDir("build-basic.json"))
@@ -450,6 +513,44 @@ func TestCoreValidate(t *testing.T) {
 	}
 }
 
+func TestCoreValidate_minVersion(t *testing.T) {
+	cases := []struct {
+		MinVersion string
+		Version    string
+		Err        bool
+	}{
+		{"0.8.0", "0.7.5", true},
+		{"0.8.0", "0.8.0", false},
+		{"0.8.0", "0.8.0.dev (abc123)", false},
+		{"0.8.0", "0.10.0", false},
+		{"0.8.0", "", false},
+		{"0.8.1", "0.8.0.dev (abc123)", true},
+		{"latest", "0.8.0", true},
+	}
+
+	for _, tc := range cases {
+		f, err := os.Open(fixtureDir("validate-min-version.json"))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		tpl, err := template.Parse(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		tpl.MinVersion = tc.MinVersion
+
+		_, err = NewCore(&CoreConfig{
+			Template: tpl,
+			Version:  tc.Version,
+		})
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s %q: err: %s", tc.MinVersion, tc.Version, err)
+		}
+	}
+}
+
 func testComponentFinder() *ComponentFinder {
 	builderFactory := func(n string) (Builder, error) { return new(MockBuilder), nil }
 	ppFactory := func(n string) (PostProcessor, error) { return new(MockPostProcessor), nil }
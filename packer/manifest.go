@@ -0,0 +1,205 @@
+package packer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+)
+
+// Manifest records the inputs of a run of Packer, such as the version of
+// Packer, the template, the plugins and the resolved variables, together
+// with the artifacts that each build produced. It lets an artifact be
+// traced back to the exact inputs that created it.
+type Manifest struct {
+	PackerVersion string                   `json:"packer_version"`
+	GoVersion     string                   `json:"go_version"`
+	OS            string                   `json:"os"`
+	Arch          string                   `json:"arch"`
+	Template      ManifestFile             `json:"template"`
+	Variables     map[string]string        `json:"variables,omitempty"`
+	Plugins       map[string]*ManifestFile `json:"plugins,omitempty"`
+	Builds        []*ManifestBuild         `json:"builds"`
+}
+
+// ManifestFile is a file that was an input to the run, identified by
+// its path and the SHA256 checksum of its contents.
+type ManifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// ManifestBuild is the result of a single build.
+type ManifestBuild struct {
+	Name      string              `json:"name"`
+	Type      string              `json:"type"`
+	Artifacts []*ManifestArtifact `json:"artifacts,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// ManifestArtifact describes an artifact produced by a build.
+type ManifestArtifact struct {
+	BuilderId string   `json:"builder_id"`
+	Id        string   `json:"id"`
+	Files     []string `json:"files,omitempty"`
+}
+
+// manifestEnvRe matches defaults of variables that are read from the
+// environment, which is where secrets such as access keys usually come
+// from even if the variable isn't marked as sensitive.
+var manifestEnvRe = regexp.MustCompile(`\{\{\s*env\b`)
+
+// Manifest returns the manifest for running the given builds. The results
+// of the builds are added with SetBuildResult once they complete.
+//
+// The values of sensitive variables and of variables that default to an
+// environment variable are never recorded. If key isn't empty, they are
+// recorded as an HMAC-SHA256 of their value with it instead, so that two
+// manifests written with the same key can still be compared. Otherwise
+// only their names are recorded, with an empty value.
+func (c *Core) Manifest(names []string, key []byte) (*Manifest, error) {
+	result := &Manifest{
+		PackerVersion: c.version,
+		GoVersion:     runtime.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		Template: ManifestFile{
+			Path:   c.Template.Path,
+			SHA256: fmt.Sprintf("%x", sha256.Sum256(c.Template.RawContents)),
+		},
+	}
+
+	if len(c.variables) > 0 {
+		result.Variables = make(map[string]string, len(c.variables))
+	}
+	for k, v := range c.variables {
+		tv, ok := c.Template.Variables[k]
+		if ok && (tv.Sensitive || manifestEnvRe.MatchString(tv.Default)) {
+			v = ""
+			if len(key) > 0 {
+				h := hmac.New(sha256.New, key)
+				h.Write([]byte(c.variables[k]))
+				v = fmt.Sprintf("hmac-sha256:%x", h.Sum(nil))
+			}
+		}
+
+		result.Variables[k] = v
+	}
+
+	// Record the plugins that implement the components of the template
+	plugins := make(map[string]struct{})
+	for _, n := range names {
+		if b, ok := c.builds[n]; ok {
+			result.Builds = append(result.Builds, &ManifestBuild{
+				Name: n,
+				Type: b.Type,
+			})
+			plugins["builder."+b.Type] = struct{}{}
+		}
+	}
+	for _, p := range c.Template.Provisioners {
+		plugins["provisioner."+p.Type] = struct{}{}
+	}
+	for _, seq := range c.Template.PostProcessors {
+		for _, p := range seq {
+			plugins["post-processor."+p.Type] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(plugins))
+	for k := range plugins {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		path, ok := c.plugins[k]
+		if !ok {
+			continue
+		}
+
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading plugin %s: %s", k, err)
+		}
+
+		if result.Plugins == nil {
+			result.Plugins = make(map[string]*ManifestFile)
+		}
+		result.Plugins[k] = &ManifestFile{Path: path, SHA256: sum}
+	}
+
+	return result, nil
+}
+
+// SetBuildResult records the artifacts or the error of the named build.
+func (m *Manifest) SetBuildResult(name string, artifacts []Artifact, err error) {
+	for _, b := range m.Builds {
+		if b.Name != name {
+			continue
+		}
+
+		if err != nil {
+			b.Error = err.Error()
+		}
+
+		for _, a := range artifacts {
+			if a == nil {
+				continue
+			}
+
+			b.Artifacts = append(b.Artifacts, &ManifestArtifact{
+				BuilderId: a.BuilderId(),
+				Id:        a.Id(),
+				Files:     a.Files(),
+			})
+		}
+	}
+}
+
+// WriteFile writes the manifest as JSON to the given path.
+func (m *Manifest) WriteFile(path string) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(raw, '\n'), 0644)
+}
+
+// ReadManifest reads a manifest that was written with WriteFile.
+func ReadManifest(path string) (*Manifest, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("Error parsing manifest: %s", err)
+	}
+
+	return &m, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
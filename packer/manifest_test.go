@@ -0,0 +1,196 @@
+package packer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCoreManifest(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	pluginPath := filepath.Join(td, "packer-builder-test")
+	if err := ioutil.WriteFile(pluginPath, []byte("plugin"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config := TestCoreConfig(t)
+	testCoreTemplate(t, config, fixtureDir("manifest.json"))
+	config.Version = "1.2.3"
+	config.Plugins = map[string]string{
+		"builder.test":     pluginPath,
+		"builder.unused":   "/i/dont/exist",
+		"provisioner.test": "/i/dont/exist",
+	}
+	core := TestCore(t, config)
+
+	m, err := core.Manifest([]string{"test"}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if m.PackerVersion != "1.2.3" {
+		t.Fatalf("bad: %s", m.PackerVersion)
+	}
+	expected := fmt.Sprintf("%x", sha256.Sum256(config.Template.RawContents))
+	if m.Template.SHA256 != expected {
+		t.Fatalf("bad: %s", m.Template.SHA256)
+	}
+
+	// Only the names of sensitive variables and of variables from the
+	// environment are recorded
+	expectedVars := map[string]string{"foo": "bar", "home": "", "secret": ""}
+	if !reflect.DeepEqual(m.Variables, expectedVars) {
+		t.Fatalf("bad: %#v", m.Variables)
+	}
+
+	// Only the plugins the template uses are recorded
+	if len(m.Plugins) != 1 {
+		t.Fatalf("bad: %#v", m.Plugins)
+	}
+	expected = fmt.Sprintf("%x", sha256.Sum256([]byte("plugin")))
+	if p := m.Plugins["builder.test"]; p == nil || p.SHA256 != expected {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	if len(m.Builds) != 1 || m.Builds[0].Name != "test" || m.Builds[0].Type != "test" {
+		t.Fatalf("bad: %#v", m.Builds)
+	}
+}
+
+func TestCoreManifest_missingPlugin(t *testing.T) {
+	config := TestCoreConfig(t)
+	testCoreTemplate(t, config, fixtureDir("manifest.json"))
+	config.Plugins = map[string]string{
+		"post-processor.test": "/i/dont/exist",
+	}
+	core := TestCore(t, config)
+
+	if _, err := core.Manifest([]string{"test"}, nil); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestCoreManifest_key(t *testing.T) {
+	os.Setenv("PACKER_TEST_MANIFEST_HOME", "/home/foo")
+	defer os.Unsetenv("PACKER_TEST_MANIFEST_HOME")
+
+	config := TestCoreConfig(t)
+	testCoreTemplate(t, config, fixtureDir("manifest.json"))
+	config.Template.Variables["home"].Default = "{{env `PACKER_TEST_MANIFEST_HOME`}}"
+	core := TestCore(t, config)
+
+	key := []byte("key")
+	m, err := core.Manifest([]string{"test"}, key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	hash := func(v string) string {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(v))
+		return fmt.Sprintf("hmac-sha256:%x", h.Sum(nil))
+	}
+
+	expected := map[string]string{
+		"foo":    "bar",
+		"home":   hash("/home/foo"),
+		"secret": hash("hunter2"),
+	}
+	if !reflect.DeepEqual(m.Variables, expected) {
+		t.Fatalf("bad: %#v", m.Variables)
+	}
+}
+
+func TestManifest_WriteFile(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	m := &Manifest{
+		Builds: []*ManifestBuild{
+			&ManifestBuild{Name: "foo"},
+			&ManifestBuild{Name: "bar"},
+		},
+	}
+	m.SetBuildResult("foo", []Artifact{&MockArtifact{}, nil}, nil)
+	m.SetBuildResult("bar", nil, errors.New("failed"))
+
+	if err := m.WriteFile(tf.Name()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw, err := ioutil.ReadFile(tf.Name())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actual Manifest
+	if err := json.Unmarshal(raw, &actual); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	foo := actual.Builds[0]
+	if len(foo.Artifacts) != 1 || foo.Artifacts[0].BuilderId != "bid" || foo.Error != "" {
+		t.Fatalf("bad: %#v", foo)
+	}
+
+	bar := actual.Builds[1]
+	if len(bar.Artifacts) != 0 || bar.Error != "failed" {
+		t.Fatalf("bad: %#v", bar)
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	m := &Manifest{
+		PackerVersion: "1.2.3",
+		Builds: []*ManifestBuild{
+			&ManifestBuild{
+				Name: "foo",
+				Artifacts: []*ManifestArtifact{
+					&ManifestArtifact{BuilderId: "bid", Files: []string{"a", "b"}},
+				},
+			},
+		},
+	}
+	if err := m.WriteFile(tf.Name()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ReadManifest(tf.Name())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, m) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	if err := ioutil.WriteFile(tf.Name(), []byte("{"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ReadManifest(tf.Name()); err == nil {
+		t.Fatal("should error")
+	}
+}
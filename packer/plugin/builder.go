@@ -1,8 +1,10 @@
 package plugin
 
 import (
+	"errors"
 	"github.com/mitchellh/packer/packer"
 	"log"
+	"time"
 )
 
 type cmdBuilder struct {
@@ -37,6 +39,48 @@ func (b *cmdBuilder) Cancel() {
 	b.builder.Cancel()
 }
 
+func (b *cmdBuilder) PrepareVerify() error {
+	defer func() {
+		r := recover()
+		b.checkExit(r, nil)
+	}()
+
+	verifier, ok := b.builder.(packer.ArtifactVerifier)
+	if !ok {
+		return errors.New("the builder can't verify its artifacts")
+	}
+
+	return verifier.PrepareVerify()
+}
+
+func (b *cmdBuilder) VerifyArtifact(ui packer.Ui, artifact packer.Artifact, command string, timeout time.Duration) error {
+	defer func() {
+		r := recover()
+		b.checkExit(r, nil)
+	}()
+
+	verifier, ok := b.builder.(packer.ArtifactVerifier)
+	if !ok {
+		return errors.New("the builder can't verify its artifacts")
+	}
+
+	return verifier.VerifyArtifact(ui, artifact, command, timeout)
+}
+
+func (b *cmdBuilder) ConfigSchema() map[string]interface{} {
+	defer func() {
+		r := recover()
+		b.checkExit(r, nil)
+	}()
+
+	schemer, ok := b.builder.(packer.ConfigSchemer)
+	if !ok {
+		return nil
+	}
+
+	return schemer.ConfigSchema()
+}
+
 func (c *cmdBuilder) checkExit(p interface{}, cb func()) {
 	if c.client.Exited() && cb != nil {
 		cb()
@@ -1,8 +1,15 @@
 package plugin
 
 import (
+	"bytes"
 	"os/exec"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template"
 )
 
 func TestBuilder_NoExist(t *testing.T) {
@@ -24,3 +31,76 @@ func TestBuilder_Good(t *testing.T) {
 		t.Fatalf("should not have error: %s", err)
 	}
 }
+
+func TestBuilder_Verify(t *testing.T) {
+	c := NewClient(&ClientConfig{Cmd: helperProcess("builder")})
+	defer c.Kill()
+
+	b, err := c.Builder()
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	verifier, ok := b.(packer.ArtifactVerifier)
+	if !ok {
+		t.Fatal("should be an ArtifactVerifier")
+	}
+
+	if err := verifier.PrepareVerify(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := &packer.BasicUi{Writer: new(bytes.Buffer)}
+	artifact := &packer.MockArtifact{IdValue: "foo"}
+	if err := verifier.VerifyArtifact(ui, artifact, "true", time.Minute); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestBuilder_ConfigSchema(t *testing.T) {
+	c := NewClient(&ClientConfig{Cmd: helperProcess("builder-schema")})
+	defer c.Kill()
+
+	p, err := c.Builder()
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	schemer, ok := p.(packer.ConfigSchemer)
+	if !ok {
+		t.Fatal("should be a ConfigSchemer")
+	}
+
+	if schema := schemer.ConfigSchema(); !reflect.DeepEqual(schema, testSchema()) {
+		t.Fatalf("bad: %#v", schema)
+	}
+}
+
+func TestBuilder_communicatorDefaults(t *testing.T) {
+	c := NewClient(&ClientConfig{Cmd: helperProcess("builder-schema")})
+	defer c.Kill()
+
+	tpl, err := template.Parse(strings.NewReader(`{
+		"communicator": {"ssh_username": "packer"},
+		"builders": [{"type": "test", "foo": "bar"}]
+	}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config := packer.TestCoreConfig(t)
+	config.Template = tpl
+	config.Components.Builder = func(string) (packer.Builder, error) {
+		return c.Builder()
+	}
+	core := packer.TestCore(t, config)
+
+	// The builder has no ssh_username, so it must not be given one
+	build, err := core.Build("test")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
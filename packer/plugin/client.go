@@ -138,6 +138,17 @@ func (c *Client) Builder() (packer.Builder, error) {
 	return &cmdBuilder{client.Builder(), c}, nil
 }
 
+// Returns a communicator provider implementation that is communicating
+// over this client. If the client hasn't been started, this will start it.
+func (c *Client) CommunicatorProvider() (packer.CommunicatorProvider, error) {
+	client, err := c.packrpcClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cmdCommunicatorProvider{client.CommunicatorProvider(), c}, nil
+}
+
 // Returns a hook implementation that is communicating over this
 // client. If the client hasn't been started, this will start it.
 func (c *Client) Hook() (packer.Hook, error) {
@@ -171,6 +182,19 @@ func (c *Client) Provisioner() (packer.Provisioner, error) {
 	return &cmdProvisioner{client.Provisioner(), c}, nil
 }
 
+// Version returns the version that the plugin reports, which is empty
+// if it doesn't have one. If the client hasn't been started, this will
+// start it.
+func (c *Client) Version() (string, error) {
+	client, err := c.packrpcClient()
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	return client.PluginVersion()
+}
+
 // End the executing subprocess (if it is running) and perform any cleanup
 // tasks necessary such as capturing any remaining logs and so on.
 //
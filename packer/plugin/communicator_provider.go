@@ -0,0 +1,37 @@
+package plugin
+
+import (
+	"github.com/mitchellh/packer/packer"
+	"log"
+)
+
+type cmdCommunicatorProvider struct {
+	p      packer.CommunicatorProvider
+	client *Client
+}
+
+func (c *cmdCommunicatorProvider) Prepare(configs ...interface{}) error {
+	defer func() {
+		r := recover()
+		c.checkExit(r, nil)
+	}()
+
+	return c.p.Prepare(configs...)
+}
+
+func (c *cmdCommunicatorProvider) Connect(host string) (packer.Communicator, error) {
+	defer func() {
+		r := recover()
+		c.checkExit(r, nil)
+	}()
+
+	return c.p.Connect(host)
+}
+
+func (c *cmdCommunicatorProvider) checkExit(p interface{}, cb func()) {
+	if c.client.Exited() && cb != nil {
+		cb()
+	} else if p != nil && !Killed {
+		log.Panic(p)
+	}
+}
@@ -0,0 +1,26 @@
+package plugin
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestCommunicatorProvider_NoExist(t *testing.T) {
+	c := NewClient(&ClientConfig{Cmd: exec.Command("i-should-not-exist")})
+	defer c.Kill()
+
+	_, err := c.CommunicatorProvider()
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestCommunicatorProvider_Good(t *testing.T) {
+	c := NewClient(&ClientConfig{Cmd: helperProcess("communicator")})
+	defer c.Kill()
+
+	_, err := c.CommunicatorProvider()
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
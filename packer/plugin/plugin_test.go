@@ -6,6 +6,8 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -24,6 +26,42 @@ func helperProcess(s ...string) *exec.Cmd {
 	return cmd
 }
 
+// testSchema is the config schema of the components of the helper
+// processes that have one.
+func testSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"foo": map[string]interface{}{"type": "string"},
+		},
+		"additionalProperties": false,
+	}
+}
+
+// strictBuilder is a builder with a config schema that, like the real
+// builders, rejects the keys that aren't in it.
+type strictBuilder struct {
+	packer.MockBuilder
+}
+
+func (b *strictBuilder) Prepare(raws ...interface{}) ([]string, error) {
+	for _, raw := range raws {
+		// The maps may not have string keys after going through RPC
+		for _, key := range reflect.ValueOf(raw).MapKeys() {
+			k := fmt.Sprint(key.Interface())
+			if k != "foo" && !strings.HasPrefix(k, "packer_") {
+				return nil, fmt.Errorf("unknown configuration key: %s", k)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (b *strictBuilder) ConfigSchema() map[string]interface{} {
+	return testSchema()
+}
+
 // This is not a real test. This is just a helper process kicked off by
 // tests.
 func TestHelperProcess(*testing.T) {
@@ -61,6 +99,22 @@ func TestHelperProcess(*testing.T) {
 		}
 		server.RegisterBuilder(new(packer.MockBuilder))
 		server.Serve()
+	case "builder-schema":
+		server, err := Server()
+		if err != nil {
+			log.Printf("[ERR] %s", err)
+			os.Exit(1)
+		}
+		server.RegisterBuilder(new(strictBuilder))
+		server.Serve()
+	case "communicator":
+		server, err := Server()
+		if err != nil {
+			log.Printf("[ERR] %s", err)
+			os.Exit(1)
+		}
+		server.RegisterCommunicatorProvider(new(packer.MockCommunicatorProvider))
+		server.Serve()
 	case "hook":
 		server, err := Server()
 		if err != nil {
@@ -90,6 +144,14 @@ func TestHelperProcess(*testing.T) {
 		}
 		server.RegisterProvisioner(new(packer.MockProvisioner))
 		server.Serve()
+	case "provisioner-schema":
+		server, err := Server()
+		if err != nil {
+			log.Printf("[ERR] %s", err)
+			os.Exit(1)
+		}
+		server.RegisterProvisioner(&packer.MockProvisioner{SchemaResult: testSchema()})
+		server.Serve()
 	case "start-timeout":
 		time.Sleep(1 * time.Minute)
 		os.Exit(1)
@@ -28,6 +28,15 @@ func (c *cmdPostProcessor) PostProcess(ui packer.Ui, a packer.Artifact) (packer.
 	return c.p.PostProcess(ui, a)
 }
 
+func (c *cmdPostProcessor) PostProcessMulti(ui packer.Ui, a packer.Artifact) ([]packer.Artifact, bool, error) {
+	defer func() {
+		r := recover()
+		c.checkExit(r, nil)
+	}()
+
+	return packer.PostProcessArtifacts(c.p, ui, a)
+}
+
 func (c *cmdPostProcessor) checkExit(p interface{}, cb func()) {
 	if c.client.Exited() && cb != nil {
 		cb()
@@ -37,6 +37,20 @@ func (c *cmdProvisioner) Cancel() {
 	c.p.Cancel()
 }
 
+func (c *cmdProvisioner) ConfigSchema() map[string]interface{} {
+	defer func() {
+		r := recover()
+		c.checkExit(r, nil)
+	}()
+
+	schemer, ok := c.p.(packer.ConfigSchemer)
+	if !ok {
+		return nil
+	}
+
+	return schemer.ConfigSchema()
+}
+
 func (c *cmdProvisioner) checkExit(p interface{}, cb func()) {
 	if c.client.Exited() && cb != nil {
 		cb()
@@ -2,7 +2,10 @@ package plugin
 
 import (
 	"os/exec"
+	"reflect"
 	"testing"
+
+	"github.com/mitchellh/packer/packer"
 )
 
 func TestProvisioner_NoExist(t *testing.T) {
@@ -24,3 +27,22 @@ func TestProvisioner_Good(t *testing.T) {
 		t.Fatalf("should not have error: %s", err)
 	}
 }
+
+func TestProvisioner_ConfigSchema(t *testing.T) {
+	c := NewClient(&ClientConfig{Cmd: helperProcess("provisioner-schema")})
+	defer c.Kill()
+
+	p, err := c.Provisioner()
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	schemer, ok := p.(packer.ConfigSchemer)
+	if !ok {
+		t.Fatal("should be a ConfigSchemer")
+	}
+
+	if schema := schemer.ConfigSchema(); !reflect.DeepEqual(schema, testSchema()) {
+		t.Fatalf("bad: %#v", schema)
+	}
+}
@@ -34,6 +34,11 @@ const MagicCookieValue = "d602bf8f470bc67ca7faa0386276bbdd4330efaf76d1a219cb4d69
 // know how to speak it.
 const APIVersion = "4"
 
+// Version is the version of the plugin, which Packer asks for to check
+// for new releases of the plugins that are installed. Plugins that are
+// released on their own should set it before calling Server.
+var Version string
+
 // Server waits for a connection to this plugin and returns a Packer
 // RPC server that you can use to register components and serve them.
 func Server() (*packrpc.Server, error) {
@@ -97,7 +102,9 @@ func Server() (*packrpc.Server, error) {
 
 	// Serve a single connection
 	log.Println("Serving a plugin connection...")
-	return packrpc.NewServer(conn), nil
+	server := packrpc.NewServer(conn)
+	server.RegisterPluginVersion(Version)
+	return server, nil
 }
 
 func serverListener(minPort, maxPort int64) (net.Listener, error) {
@@ -16,3 +16,33 @@ type PostProcessor interface {
 	// is to true, then the previous artifact is forcibly kept.
 	PostProcess(Ui, Artifact) (a Artifact, keep bool, err error)
 }
+
+// A MultiPostProcessor is a PostProcessor that can produce more than one
+// artifact from a single artifact, such as a converter that creates an
+// image of each format. Each of the artifacts is passed on to the rest of
+// the post-processors of the sequence on its own.
+type MultiPostProcessor interface {
+	PostProcessor
+
+	// PostProcessMulti is like PostProcess, but returns any number of
+	// artifacts. If none are returned, the sequence of post-processors
+	// stops, like when PostProcess returns a nil artifact.
+	PostProcessMulti(Ui, Artifact) (as []Artifact, keep bool, err error)
+}
+
+// PostProcessArtifacts runs the post-processor on the artifact and returns
+// the artifacts that it produced. This is PostProcessMulti for a
+// MultiPostProcessor, and at most the one artifact of PostProcess for any
+// other post-processor.
+func PostProcessArtifacts(p PostProcessor, ui Ui, a Artifact) ([]Artifact, bool, error) {
+	if mp, ok := p.(MultiPostProcessor); ok {
+		return mp.PostProcessMulti(ui, a)
+	}
+
+	artifact, keep, err := p.PostProcess(ui, a)
+	if artifact == nil {
+		return nil, keep, err
+	}
+
+	return []Artifact{artifact}, keep, err
+}
@@ -31,3 +31,24 @@ func (t *MockPostProcessor) PostProcess(ui Ui, a Artifact) (Artifact, bool, erro
 		IdValue: t.ArtifactId,
 	}, t.Keep, t.Error
 }
+
+// MockMultiPostProcessor is an implementation of MultiPostProcessor that
+// can be used for tests. It produces an artifact for each of ArtifactIds.
+type MockMultiPostProcessor struct {
+	MockPostProcessor
+
+	ArtifactIds []string
+}
+
+func (t *MockMultiPostProcessor) PostProcessMulti(ui Ui, a Artifact) ([]Artifact, bool, error) {
+	t.PostProcessCalled = true
+	t.PostProcessArtifact = a
+	t.PostProcessUi = ui
+
+	artifacts := make([]Artifact, len(t.ArtifactIds))
+	for i, id := range t.ArtifactIds {
+		artifacts[i] = &MockArtifact{IdValue: id}
+	}
+
+	return artifacts, t.Keep, t.Error
+}
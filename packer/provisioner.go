@@ -2,8 +2,13 @@ package packer
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/mitchellh/packer/helper/tracing"
 )
 
 // A provisioner is responsible for installing and configuring software
@@ -26,6 +31,24 @@ type Provisioner interface {
 	Cancel()
 }
 
+// BuildData is data about a running build that is only known once the
+// machine is up, such as the host ports that are forwarded to it.
+// Builders pass it as the data of the provision hook.
+type BuildData map[string]string
+
+// A BuildDataProvisioner is a Provisioner that uses the BuildData of the
+// build, which is set before it runs.
+type BuildDataProvisioner interface {
+	SetBuildData(BuildData)
+}
+
+// setBuildData sets the build data of the provisioner if it uses it.
+func setBuildData(p Provisioner, data BuildData) {
+	if bp, ok := p.(BuildDataProvisioner); ok {
+		bp.SetBuildData(data)
+	}
+}
+
 // A Hook implementation that runs the given provisioners.
 type ProvisionHook struct {
 	// The provisioners to run as part of the hook. These should already
@@ -34,6 +57,7 @@ type ProvisionHook struct {
 
 	lock               sync.Mutex
 	runningProvisioner Provisioner
+	err                error
 }
 
 // Runs the provisioners in order.
@@ -45,12 +69,21 @@ func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interfac
 		h.runningProvisioner = nil
 	}()
 
+	buildData, _ := data.(BuildData)
 	for _, p := range h.Provisioners {
 		h.lock.Lock()
 		h.runningProvisioner = p
 		h.lock.Unlock()
 
+		if buildData != nil {
+			setBuildData(p, buildData)
+		}
+
 		if err := p.Provision(ui, comm); err != nil {
+			h.lock.Lock()
+			h.err = err
+			h.lock.Unlock()
+
 			return err
 		}
 	}
@@ -58,6 +91,14 @@ func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interfac
 	return nil
 }
 
+// failed returns true if one of the provisioners has failed.
+func (h *ProvisionHook) failed() bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.err != nil
+}
+
 // Cancels the privisioners that are still running.
 func (h *ProvisionHook) Cancel() {
 	h.lock.Lock()
@@ -83,6 +124,10 @@ func (p *PausedProvisioner) Prepare(raws ...interface{}) error {
 	return p.Provisioner.Prepare(raws...)
 }
 
+func (p *PausedProvisioner) SetBuildData(data BuildData) {
+	setBuildData(p.Provisioner, data)
+}
+
 func (p *PausedProvisioner) Provision(ui Ui, comm Communicator) error {
 	p.lock.Lock()
 	cancelCh := make(chan struct{})
@@ -144,3 +189,119 @@ func (p *PausedProvisioner) Cancel() {
 func (p *PausedProvisioner) provision(result chan<- error, ui Ui, comm Communicator) {
 	result <- p.Provisioner.Provision(ui, comm)
 }
+
+// CapturedProvisioner is a Provisioner implementation that writes the
+// output of the provisioner to files as it runs, in addition to showing
+// it on the UI.
+type CapturedProvisioner struct {
+	// Path is the path of the files without an extension. Messages are
+	// written to Path + ".stdout" and errors to Path + ".stderr".
+	Path        string
+	Provisioner Provisioner
+}
+
+func (p *CapturedProvisioner) Prepare(raws ...interface{}) error {
+	return p.Provisioner.Prepare(raws...)
+}
+
+func (p *CapturedProvisioner) SetBuildData(data BuildData) {
+	setBuildData(p.Provisioner, data)
+}
+
+func (p *CapturedProvisioner) Provision(ui Ui, comm Communicator) error {
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0755); err != nil {
+		return fmt.Errorf("Error creating provisioner output directory: %s", err)
+	}
+
+	stdout, err := os.Create(p.Path + ".stdout")
+	if err != nil {
+		return fmt.Errorf("Error creating provisioner output file: %s", err)
+	}
+	defer stdout.Close()
+
+	stderr, err := os.Create(p.Path + ".stderr")
+	if err != nil {
+		return fmt.Errorf("Error creating provisioner output file: %s", err)
+	}
+	defer stderr.Close()
+
+	return p.Provisioner.Provision(&captureUi{
+		Ui:     ui,
+		Stdout: stdout,
+		Stderr: stderr,
+	}, comm)
+}
+
+func (p *CapturedProvisioner) Cancel() {
+	p.Provisioner.Cancel()
+}
+
+// TracedProvisioner is a Provisioner implementation that traces the time
+// that the provisioner takes within the span of its build.
+type TracedProvisioner struct {
+	Build       string
+	Type        string
+	Tracer      *tracing.Tracer
+	Provisioner Provisioner
+}
+
+func (p *TracedProvisioner) Prepare(raws ...interface{}) error {
+	return p.Provisioner.Prepare(raws...)
+}
+
+func (p *TracedProvisioner) SetBuildData(data BuildData) {
+	setBuildData(p.Provisioner, data)
+}
+
+func (p *TracedProvisioner) Provision(ui Ui, comm Communicator) error {
+	span := p.Tracer.StartInBuild("provisioner "+p.Type, p.Build)
+	defer span.Finish()
+
+	err := p.Provisioner.Provision(ui, comm)
+	span.SetError(err)
+	return err
+}
+
+func (p *TracedProvisioner) Cancel() {
+	p.Provisioner.Cancel()
+}
+
+// captureUi is a Ui that copies everything said to Stdout and errors
+// to Stderr before passing them on.
+type captureUi struct {
+	Ui     Ui
+	Stdout io.Writer
+	Stderr io.Writer
+
+	l sync.Mutex
+}
+
+func (u *captureUi) Ask(query string) (string, error) {
+	return u.Ui.Ask(query)
+}
+
+func (u *captureUi) Say(message string) {
+	u.write(u.Stdout, message)
+	u.Ui.Say(message)
+}
+
+func (u *captureUi) Message(message string) {
+	u.write(u.Stdout, message)
+	u.Ui.Message(message)
+}
+
+func (u *captureUi) Error(message string) {
+	u.write(u.Stderr, message)
+	u.Ui.Error(message)
+}
+
+func (u *captureUi) Machine(t string, args ...string) {
+	u.Ui.Machine(t, args...)
+}
+
+func (u *captureUi) write(w io.Writer, message string) {
+	u.l.Lock()
+	defer u.l.Unlock()
+
+	fmt.Fprintln(w, message)
+}
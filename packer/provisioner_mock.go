@@ -7,16 +7,19 @@ type MockProvisioner struct {
 
 	PrepCalled       bool
 	PrepConfigs      []interface{}
+	PrepError        error
 	ProvCalled       bool
 	ProvCommunicator Communicator
 	ProvUi           Ui
 	CancelCalled     bool
+	BuildData        BuildData
+	SchemaResult     map[string]interface{}
 }
 
 func (t *MockProvisioner) Prepare(configs ...interface{}) error {
 	t.PrepCalled = true
 	t.PrepConfigs = configs
-	return nil
+	return t.PrepError
 }
 
 func (t *MockProvisioner) Provision(ui Ui, comm Communicator) error {
@@ -31,6 +34,14 @@ func (t *MockProvisioner) Provision(ui Ui, comm Communicator) error {
 	return t.ProvFunc()
 }
 
+func (t *MockProvisioner) SetBuildData(data BuildData) {
+	t.BuildData = data
+}
+
 func (t *MockProvisioner) Cancel() {
 	t.CancelCalled = true
 }
+
+func (t *MockProvisioner) ConfigSchema() map[string]interface{} {
+	return t.SchemaResult
+}
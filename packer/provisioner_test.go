@@ -1,9 +1,16 @@
 package packer
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/mitchellh/packer/helper/tracing"
 )
 
 func TestProvisionHook_Impl(t *testing.T) {
@@ -37,6 +44,36 @@ func TestProvisionHook(t *testing.T) {
 	}
 }
 
+func TestProvisionHook_buildData(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	pA := &MockProvisioner{}
+	pB := &MockProvisioner{}
+
+	// Build data goes through the provisioners that wrap others
+	hook := &ProvisionHook{
+		Provisioners: []Provisioner{
+			pA,
+			&CapturedProvisioner{
+				Path:        filepath.Join(td, "01-mock"),
+				Provisioner: &PausedProvisioner{Provisioner: pB},
+			},
+		},
+	}
+
+	data := BuildData{"foo": "bar"}
+	if err := hook.Run(HookProvision, testUi(), nil, data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(pA.BuildData, data) || !reflect.DeepEqual(pB.BuildData, data) {
+		t.Fatalf("bad: %#v %#v", pA.BuildData, pB.BuildData)
+	}
+}
+
 func TestProvisionHook_cancel(t *testing.T) {
 	var lock sync.Mutex
 	order := make([]string, 0, 2)
@@ -171,3 +208,77 @@ func TestPausedProvisionerCancel(t *testing.T) {
 		t.Fatal("cancel should be called")
 	}
 }
+
+func TestCapturedProvisioner_impl(t *testing.T) {
+	var _ Provisioner = new(CapturedProvisioner)
+}
+
+func TestCapturedProvisionerProvision(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	mock := new(MockProvisioner)
+	mock.ProvFunc = func() error {
+		mock.ProvUi.Say("foo")
+		mock.ProvUi.Message("bar")
+		mock.ProvUi.Error("baz")
+		return nil
+	}
+
+	path := filepath.Join(td, "build", "01-mock")
+	prov := &CapturedProvisioner{
+		Path:        path,
+		Provisioner: mock,
+	}
+
+	if err := prov.Provision(testUi(), new(MockCommunicator)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	stdout, err := ioutil.ReadFile(path + ".stdout")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(stdout) != "foo\nbar\n" {
+		t.Fatalf("bad: %q", stdout)
+	}
+
+	stderr, err := ioutil.ReadFile(path + ".stderr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(stderr) != "baz\n" {
+		t.Fatalf("bad: %q", stderr)
+	}
+}
+
+func TestTracedProvisioner_impl(t *testing.T) {
+	var _ Provisioner = new(TracedProvisioner)
+	var _ BuildDataProvisioner = new(TracedProvisioner)
+}
+
+func TestTracedProvisionerProvision(t *testing.T) {
+	mock := new(MockProvisioner)
+	mock.ProvFunc = func() error { return errors.New("failed") }
+	prov := &TracedProvisioner{
+		Build:       "foo",
+		Type:        "mock",
+		Tracer:      &tracing.Tracer{TraceID: "0123456789abcdef0123456789abcdef"},
+		Provisioner: mock,
+	}
+
+	prov.SetBuildData(BuildData{"foo": "bar"})
+	if mock.BuildData["foo"] != "bar" {
+		t.Fatalf("bad: %#v", mock.BuildData)
+	}
+
+	if err := prov.Provision(testUi(), new(MockCommunicator)); err == nil {
+		t.Fatal("should have error")
+	}
+	if !mock.ProvCalled {
+		t.Fatal("prov should be called")
+	}
+}
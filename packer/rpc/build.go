@@ -79,6 +79,24 @@ func (b *build) SetForce(val bool) {
 	}
 }
 
+func (b *build) SetCaptureOutput(dir string) {
+	if err := b.client.Call("Build.SetCaptureOutput", dir, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
+func (b *build) SetPostProcessorParallelism(n int) {
+	if err := b.client.Call("Build.SetPostProcessorParallelism", n, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
+func (b *build) SetKeepWorkDir(val bool) {
+	if err := b.client.Call("Build.SetKeepWorkDir", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
 func (b *build) Cancel() {
 	if err := b.client.Call("Build.Cancel", new(interface{}), new(interface{})); err != nil {
 		panic(err)
@@ -134,6 +152,21 @@ func (b *BuildServer) SetForce(val *bool, reply *interface{}) error {
 	return nil
 }
 
+func (b *BuildServer) SetCaptureOutput(dir *string, reply *interface{}) error {
+	b.build.SetCaptureOutput(*dir)
+	return nil
+}
+
+func (b *BuildServer) SetPostProcessorParallelism(n *int, reply *interface{}) error {
+	b.build.SetPostProcessorParallelism(*n)
+	return nil
+}
+
+func (b *BuildServer) SetKeepWorkDir(val *bool, reply *interface{}) error {
+	b.build.SetKeepWorkDir(*val)
+	return nil
+}
+
 func (b *BuildServer) Cancel(args *interface{}, reply *interface{}) error {
 	b.build.Cancel()
 	return nil
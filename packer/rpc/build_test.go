@@ -20,6 +20,10 @@ type testBuild struct {
 	setForceCalled  bool
 	cancelCalled    bool
 
+	setCaptureOutputDir string
+	setPPParallelism    int
+	setKeepWorkDir      bool
+
 	errRunResult bool
 }
 
@@ -53,6 +57,18 @@ func (b *testBuild) SetForce(bool) {
 	b.setForceCalled = true
 }
 
+func (b *testBuild) SetCaptureOutput(dir string) {
+	b.setCaptureOutputDir = dir
+}
+
+func (b *testBuild) SetPostProcessorParallelism(n int) {
+	b.setPPParallelism = n
+}
+
+func (b *testBuild) SetKeepWorkDir(val bool) {
+	b.setKeepWorkDir = val
+}
+
 func (b *testBuild) Cancel() {
 	b.cancelCalled = true
 }
@@ -116,6 +132,24 @@ func TestBuild(t *testing.T) {
 		t.Fatal("should be called")
 	}
 
+	// Test SetCaptureOutput
+	bClient.SetCaptureOutput("foo")
+	if b.setCaptureOutputDir != "foo" {
+		t.Fatalf("bad: %#v", b.setCaptureOutputDir)
+	}
+
+	// Test SetPostProcessorParallelism
+	bClient.SetPostProcessorParallelism(4)
+	if b.setPPParallelism != 4 {
+		t.Fatalf("bad: %#v", b.setPPParallelism)
+	}
+
+	// Test SetKeepWorkDir
+	bClient.SetKeepWorkDir(true)
+	if !b.setKeepWorkDir {
+		t.Fatal("should be called")
+	}
+
 	// Test Cancel
 	bClient.Cancel()
 	if !b.cancelCalled {
@@ -1,11 +1,19 @@
 package rpc
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/mitchellh/packer/packer"
 	"log"
 	"net/rpc"
+	"strings"
+	"time"
 )
 
+// errVerifyNotSupported is returned by builders that can't verify their
+// artifacts.
+var errVerifyNotSupported = errors.New("the builder can't verify its artifacts")
+
 // An implementation of packer.Builder where the builder is actually executed
 // over an RPC connection.
 type builder struct {
@@ -29,6 +37,16 @@ type BuilderPrepareResponse struct {
 	Error    *BasicError
 }
 
+type BuilderVerifyArtifactArgs struct {
+	StreamId uint32
+	Command  string
+	Timeout  time.Duration
+}
+
+type BuilderVerifyResponse struct {
+	Error *BasicError
+}
+
 func (b *builder) Prepare(config ...interface{}) ([]string, error) {
 	var resp BuilderPrepareResponse
 	cerr := b.client.Call("Builder.Prepare", &BuilderPrepareArgs{config}, &resp)
@@ -74,6 +92,60 @@ func (b *builder) Cancel() {
 	}
 }
 
+func (b *builder) ConfigSchema() map[string]interface{} {
+	var raw []byte
+	if err := b.client.Call("Builder.ConfigSchema", new(interface{}), &raw); err != nil {
+		log.Printf("Error getting builder config schema: %s", err)
+		return nil
+	}
+
+	return decodeConfigSchema(raw)
+}
+
+// PrepareVerify asks the builder whether it can verify its artifacts.
+// Plugins that were built before verification existed can't.
+func (b *builder) PrepareVerify() error {
+	var resp BuilderVerifyResponse
+	if err := b.client.Call("Builder.PrepareVerify", new(interface{}), &resp); err != nil {
+		if _, ok := err.(rpc.ServerError); ok && strings.Contains(err.Error(), "can't find method") {
+			return errVerifyNotSupported
+		}
+
+		return err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	return nil
+}
+
+func (b *builder) VerifyArtifact(ui packer.Ui, artifact packer.Artifact, command string, timeout time.Duration) error {
+	nextId := b.mux.NextId()
+	server := newServerWithMux(b.mux, nextId)
+	server.RegisterArtifact(artifact)
+	server.RegisterUi(ui)
+	go server.Serve()
+
+	args := &BuilderVerifyArtifactArgs{
+		StreamId: nextId,
+		Command:  command,
+		Timeout:  timeout,
+	}
+
+	var resp BuilderVerifyResponse
+	if err := b.client.Call("Builder.VerifyArtifact", args, &resp); err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	return nil
+}
+
 func (b *BuilderServer) Prepare(args *BuilderPrepareArgs, reply *BuilderPrepareResponse) error {
 	warnings, err := b.builder.Prepare(args.Configs...)
 	*reply = BuilderPrepareResponse{
@@ -111,3 +183,64 @@ func (b *BuilderServer) Cancel(args *interface{}, reply *interface{}) error {
 	b.builder.Cancel()
 	return nil
 }
+
+func (b *BuilderServer) PrepareVerify(args *interface{}, reply *BuilderVerifyResponse) error {
+	verifier, ok := b.builder.(packer.ArtifactVerifier)
+	if !ok {
+		*reply = BuilderVerifyResponse{Error: NewBasicError(errVerifyNotSupported)}
+		return nil
+	}
+
+	*reply = BuilderVerifyResponse{Error: NewBasicError(verifier.PrepareVerify())}
+	return nil
+}
+
+func (b *BuilderServer) VerifyArtifact(args *BuilderVerifyArtifactArgs, reply *BuilderVerifyResponse) error {
+	verifier, ok := b.builder.(packer.ArtifactVerifier)
+	if !ok {
+		*reply = BuilderVerifyResponse{Error: NewBasicError(errVerifyNotSupported)}
+		return nil
+	}
+
+	client, err := newClientWithMux(b.mux, args.StreamId)
+	if err != nil {
+		return NewBasicError(err)
+	}
+	defer client.Close()
+
+	err = verifier.VerifyArtifact(client.Ui(), client.Artifact(), args.Command, args.Timeout)
+	*reply = BuilderVerifyResponse{Error: NewBasicError(err)}
+	return nil
+}
+
+func (b *BuilderServer) ConfigSchema(args *interface{}, reply *[]byte) error {
+	return encodeConfigSchema(b.builder, reply)
+}
+
+// encodeConfigSchema encodes the config schema of the component as JSON,
+// since the nested maps of a schema don't survive the RPC encoding. If
+// the component doesn't implement packer.ConfigSchemer, it is null.
+func encodeConfigSchema(c interface{}, reply *[]byte) error {
+	var schema map[string]interface{}
+	if s, ok := c.(packer.ConfigSchemer); ok {
+		schema = s.ConfigSchema()
+	}
+
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return NewBasicError(err)
+	}
+
+	*reply = raw
+	return nil
+}
+
+func decodeConfigSchema(raw []byte) map[string]interface{} {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		log.Printf("Error decoding config schema: %s", err)
+		return nil
+	}
+
+	return schema
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/mitchellh/packer/packer"
 	"reflect"
 	"testing"
+	"time"
 )
 
 var testBuilderArtifact = &packer.MockArtifact{}
@@ -57,6 +58,31 @@ func TestBuilderPrepare_Warnings(t *testing.T) {
 	}
 }
 
+func TestBuilderConfigSchema(t *testing.T) {
+	b := new(packer.MockBuilder)
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+	server.RegisterBuilder(b)
+	bClient := client.Builder()
+
+	if schema := bClient.(packer.ConfigSchemer).ConfigSchema(); schema != nil {
+		t.Fatalf("bad: %#v", schema)
+	}
+
+	b.SchemaResult = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"foo": map[string]interface{}{"type": "string"},
+		},
+	}
+
+	schema := bClient.(packer.ConfigSchemer).ConfigSchema()
+	if !reflect.DeepEqual(schema, b.SchemaResult) {
+		t.Fatalf("bad: %#v", schema)
+	}
+}
+
 func TestBuilderRun(t *testing.T) {
 	b := new(packer.MockBuilder)
 	client, server := testClientServer(t)
@@ -127,6 +153,41 @@ func TestBuilderRun_ErrResult(t *testing.T) {
 	}
 }
 
+func TestBuilderVerify(t *testing.T) {
+	b := new(packer.MockBuilder)
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+	server.RegisterBuilder(b)
+	bClient := client.Builder().(packer.ArtifactVerifier)
+
+	if err := bClient.PrepareVerify(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !b.PrepareVerifyCalled {
+		t.Fatal("should be called")
+	}
+
+	ui := &testUi{}
+	if err := bClient.VerifyArtifact(ui, new(packer.MockArtifact), "true", time.Minute); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !b.VerifyCalled {
+		t.Fatal("should be called")
+	}
+	if b.VerifiedArtifactId != "id" {
+		t.Fatalf("bad: %s", b.VerifiedArtifactId)
+	}
+	if b.VerifyCommand != "true" || b.VerifyTimeout != time.Minute {
+		t.Fatalf("bad: %s %s", b.VerifyCommand, b.VerifyTimeout)
+	}
+
+	b.VerifyErrResult = true
+	if err := bClient.VerifyArtifact(ui, new(packer.MockArtifact), "true", time.Minute); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestBuilderCancel(t *testing.T) {
 	b := new(packer.MockBuilder)
 	client, server := testClientServer(t)
@@ -100,6 +100,13 @@ func (c *Client) Communicator() packer.Communicator {
 	}
 }
 
+func (c *Client) CommunicatorProvider() packer.CommunicatorProvider {
+	return &communicatorProvider{
+		client: c.client,
+		mux:    c.mux,
+	}
+}
+
 func (c *Client) Hook() packer.Hook {
 	return &hook{
 		client: c.client,
@@ -107,6 +114,14 @@ func (c *Client) Hook() packer.Hook {
 	}
 }
 
+// PluginVersion returns the version of the plugin. Plugins that were
+// built before the version was served return an error.
+func (c *Client) PluginVersion() (string, error) {
+	var result string
+	err := c.client.Call(DefaultPluginEndpoint+".Version", new(interface{}), &result)
+	return result, err
+}
+
 func (c *Client) PostProcessor() packer.PostProcessor {
 	return &postProcessor{
 		client: c.client,
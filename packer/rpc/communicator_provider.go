@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"github.com/mitchellh/packer/packer"
+	"net/rpc"
+)
+
+// An implementation of packer.CommunicatorProvider where the provider is
+// actually executed over an RPC connection.
+type communicatorProvider struct {
+	client *rpc.Client
+	mux    *muxBroker
+}
+
+// CommunicatorProviderServer wraps a packer.CommunicatorProvider
+// implementation and makes it exportable as part of a Golang RPC server.
+type CommunicatorProviderServer struct {
+	p   packer.CommunicatorProvider
+	mux *muxBroker
+}
+
+type CommunicatorProviderPrepareArgs struct {
+	Configs []interface{}
+}
+
+func (p *communicatorProvider) Prepare(configs ...interface{}) (err error) {
+	args := &CommunicatorProviderPrepareArgs{configs}
+	if cerr := p.client.Call("CommunicatorProvider.Prepare", args, new(interface{})); cerr != nil {
+		err = cerr
+	}
+
+	return
+}
+
+func (p *communicatorProvider) Connect(host string) (packer.Communicator, error) {
+	var streamId uint32
+	if err := p.client.Call("CommunicatorProvider.Connect", host, &streamId); err != nil {
+		return nil, err
+	}
+
+	client, err := newClientWithMux(p.mux, streamId)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Communicator(), nil
+}
+
+func (p *CommunicatorProviderServer) Prepare(args *CommunicatorProviderPrepareArgs, reply *interface{}) error {
+	if err := p.p.Prepare(args.Configs...); err != nil {
+		return NewBasicError(err)
+	}
+
+	return nil
+}
+
+func (p *CommunicatorProviderServer) Connect(host string, reply *uint32) error {
+	comm, err := p.p.Connect(host)
+	if err != nil {
+		return NewBasicError(err)
+	}
+
+	streamId := p.mux.NextId()
+	server := newServerWithMux(p.mux, streamId)
+	server.RegisterCommunicator(comm)
+	go server.Serve()
+
+	*reply = streamId
+	return nil
+}
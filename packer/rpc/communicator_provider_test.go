@@ -0,0 +1,59 @@
+package rpc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestCommunicatorProviderRPC(t *testing.T) {
+	// Create the interface to test
+	p := new(packer.MockCommunicatorProvider)
+
+	// Start the server
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+	server.RegisterCommunicatorProvider(p)
+	pClient := client.CommunicatorProvider()
+
+	// Test Prepare
+	config := 42
+	if err := pClient.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !p.PrepareCalled {
+		t.Fatal("should be called")
+	}
+	expected := []interface{}{int64(42)}
+	if !reflect.DeepEqual(p.PrepareConfigs, expected) {
+		t.Fatalf("bad: %#v", p.PrepareConfigs)
+	}
+
+	// Test Connect
+	mockComm := new(packer.MockCommunicator)
+	p.ConnectComm = mockComm
+	comm, err := pClient.Connect("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.ConnectHost != "foo" {
+		t.Fatalf("bad: %#v", p.ConnectHost)
+	}
+
+	// Verify the communicator works across the connection
+	var cmd packer.RemoteCmd
+	cmd.Command = "bar"
+	if err := comm.Start(&cmd); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd.Wait()
+	if !mockComm.StartCalled || mockComm.StartCmd.Command != "bar" {
+		t.Fatalf("bad: %#v", mockComm.StartCmd)
+	}
+}
+
+func TestCommunicatorProvider_Implements(t *testing.T) {
+	var _ packer.CommunicatorProvider = new(communicatorProvider)
+}
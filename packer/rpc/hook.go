@@ -24,6 +24,10 @@ type HookRunArgs struct {
 	Name     string
 	Data     interface{}
 	StreamId uint32
+
+	// BuildData is sent apart from Data so that it keeps its type, which
+	// the codec doesn't keep for the values of interfaces.
+	BuildData packer.BuildData
 }
 
 func (h *hook) Run(name string, ui packer.Ui, comm packer.Communicator, data interface{}) error {
@@ -38,6 +42,10 @@ func (h *hook) Run(name string, ui packer.Ui, comm packer.Communicator, data int
 		Data:     data,
 		StreamId: nextId,
 	}
+	if buildData, ok := data.(packer.BuildData); ok {
+		args.Data = nil
+		args.BuildData = buildData
+	}
 
 	return h.client.Call("Hook.Run", &args, new(interface{}))
 }
@@ -56,7 +64,12 @@ func (h *HookServer) Run(args *HookRunArgs, reply *interface{}) error {
 	}
 	defer client.Close()
 
-	if err := h.hook.Run(args.Name, client.Ui(), client.Communicator(), args.Data); err != nil {
+	data := args.Data
+	if args.BuildData != nil {
+		data = args.BuildData
+	}
+
+	if err := h.hook.Run(args.Name, client.Ui(), client.Communicator(), data); err != nil {
 		return NewBasicError(err)
 	}
 
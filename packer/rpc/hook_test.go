@@ -26,6 +26,13 @@ func TestHookRPC(t *testing.T) {
 		t.Fatal("should be called")
 	}
 
+	// Build data keeps its type
+	data := packer.BuildData{"foo": "bar"}
+	hClient.Run(packer.HookProvision, ui, nil, data)
+	if !reflect.DeepEqual(h.RunData, data) {
+		t.Fatalf("bad: %#v", h.RunData)
+	}
+
 	// Test Cancel
 	hClient.Cancel()
 	if !h.CancelCalled {
@@ -2,6 +2,7 @@ package rpc
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -55,15 +56,30 @@ func newMuxBrokerServer(rwc io.ReadWriteCloser) (*muxBroker, error) {
 	return newMuxBroker(s), nil
 }
 
+// errAcceptCancelled is returned by AcceptCancel when it is cancelled.
+var errAcceptCancelled = errors.New("accept cancelled")
+
 // Accept accepts a connection by ID.
 //
 // This should not be called multiple times with the same ID at one time.
 func (m *muxBroker) Accept(id uint32) (net.Conn, error) {
+	return m.AcceptCancel(id, nil)
+}
+
+// AcceptCancel accepts a connection by ID like Accept, but stops waiting
+// for it once cancel is closed.
+func (m *muxBroker) AcceptCancel(id uint32, cancel <-chan struct{}) (net.Conn, error) {
 	var c net.Conn
 	p := m.getStream(id)
 	select {
 	case c = <-p.ch:
 		close(p.doneCh)
+	case <-cancel:
+		m.Lock()
+		defer m.Unlock()
+		delete(m.streams, id)
+
+		return nil, errAcceptCancelled
 	case <-time.After(5 * time.Second):
 		m.Lock()
 		defer m.Unlock()
@@ -43,6 +43,27 @@ func TestMuxBroker(t *testing.T) {
 	}
 }
 
+func TestMuxBroker_acceptCancel(t *testing.T) {
+	c, s := testYamux(t)
+	defer c.Close()
+	defer s.Close()
+
+	bs := newMuxBroker(s)
+	go bs.Run()
+
+	cancel := make(chan struct{})
+	close(cancel)
+	if _, err := bs.AcceptCancel(5, cancel); err != errAcceptCancelled {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	bs.Lock()
+	defer bs.Unlock()
+	if len(bs.streams) != 0 {
+		t.Fatalf("bad: %#v", bs.streams)
+	}
+}
+
 func testYamux(t *testing.T) (client *yamux.Session, server *yamux.Session) {
 	l, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
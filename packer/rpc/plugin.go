@@ -0,0 +1,12 @@
+package rpc
+
+// PluginServer serves information about the plugin process itself, as
+// opposed to the component that it implements.
+type PluginServer struct {
+	version string
+}
+
+func (s *PluginServer) Version(args *interface{}, reply *string) error {
+	*reply = s.version
+	return nil
+}
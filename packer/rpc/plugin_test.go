@@ -0,0 +1,31 @@
+package rpc
+
+import (
+	"testing"
+)
+
+func TestPluginRPC(t *testing.T) {
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+	server.RegisterPluginVersion("1.2.3")
+
+	v, err := client.PluginVersion()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v != "1.2.3" {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestPluginRPC_unregistered(t *testing.T) {
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+
+	// Plugins built before the version was served return an error
+	if _, err := client.PluginVersion(); err == nil {
+		t.Fatal("should have error")
+	}
+}
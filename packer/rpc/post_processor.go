@@ -3,6 +3,7 @@ package rpc
 import (
 	"github.com/mitchellh/packer/packer"
 	"net/rpc"
+	"strings"
 )
 
 // An implementation of packer.PostProcessor where the PostProcessor is actually
@@ -30,13 +31,20 @@ type PostProcessorProcessResponse struct {
 	StreamId uint32
 }
 
+type PostProcessorProcessMultiResponse struct {
+	Err       *BasicError
+	Keep      bool
+	StreamIds []uint32
+}
+
 func (p *postProcessor) Configure(raw ...interface{}) (err error) {
 	args := &PostProcessorConfigureArgs{Configs: raw}
-	if cerr := p.client.Call("PostProcessor.Configure", args, new(interface{})); cerr != nil {
+	var warnings []string
+	if cerr := p.client.Call("PostProcessor.Configure", args, &warnings); cerr != nil {
 		err = cerr
 	}
 
-	return
+	return packer.WarningsOrError(warnings, err)
 }
 
 func (p *postProcessor) PostProcess(ui packer.Ui, a packer.Artifact) (packer.Artifact, bool, error) {
@@ -67,8 +75,55 @@ func (p *postProcessor) PostProcess(ui packer.Ui, a packer.Artifact) (packer.Art
 	return client.Artifact(), response.Keep, nil
 }
 
-func (p *PostProcessorServer) Configure(args *PostProcessorConfigureArgs, reply *interface{}) error {
-	err := p.p.Configure(args.Configs...)
+// PostProcessMulti runs the post-processor like PostProcess, but returns
+// all of the artifacts that it produced. Plugins that were built before
+// there was PostProcessMulti are asked with PostProcess.
+func (p *postProcessor) PostProcessMulti(ui packer.Ui, a packer.Artifact) ([]packer.Artifact, bool, error) {
+	nextId := p.mux.NextId()
+	server := newServerWithMux(p.mux, nextId)
+	server.RegisterArtifact(a)
+	server.RegisterUi(ui)
+	go server.Serve()
+
+	var response PostProcessorProcessMultiResponse
+	if err := p.client.Call("PostProcessor.PostProcessMulti", nextId, &response); err != nil {
+		if _, ok := err.(rpc.ServerError); ok && strings.Contains(err.Error(), "can't find method") {
+			// The plugin never connects to the server of this call, and
+			// PostProcess starts a server of its own.
+			server.Close()
+			return packer.PostProcessArtifacts(&postProcessorSingle{p}, ui, a)
+		}
+
+		return nil, false, err
+	}
+
+	if response.Err != nil {
+		return nil, false, response.Err
+	}
+
+	artifacts := make([]packer.Artifact, 0, len(response.StreamIds))
+	for _, streamId := range response.StreamIds {
+		client, err := newClientWithMux(p.mux, streamId)
+		if err != nil {
+			return nil, false, err
+		}
+
+		artifacts = append(artifacts, client.Artifact())
+	}
+
+	return artifacts, response.Keep, nil
+}
+
+// postProcessorSingle hides PostProcessMulti of a post-processor, so that
+// it is run with PostProcess.
+type postProcessorSingle struct {
+	packer.PostProcessor
+}
+
+func (p *PostProcessorServer) Configure(args *PostProcessorConfigureArgs, reply *[]string) error {
+	// Warnings are sent back as the reply, like for provisioners.
+	warnings, err := packer.SplitWarnings(p.p.Configure(args.Configs...))
+	*reply = warnings
 	return err
 }
 
@@ -96,3 +151,33 @@ func (p *PostProcessorServer) PostProcess(streamId uint32, reply *PostProcessorP
 
 	return nil
 }
+
+func (p *PostProcessorServer) PostProcessMulti(streamId uint32, reply *PostProcessorProcessMultiResponse) error {
+	client, err := newClientWithMux(p.mux, streamId)
+	if err != nil {
+		return NewBasicError(err)
+	}
+	defer client.Close()
+
+	artifacts, keep, err := packer.PostProcessArtifacts(p.p, client.Ui(), client.Artifact())
+
+	var streamIds []uint32
+	if err == nil {
+		for _, artifact := range artifacts {
+			streamId := p.mux.NextId()
+			server := newServerWithMux(p.mux, streamId)
+			server.RegisterArtifact(artifact)
+			go server.Serve()
+
+			streamIds = append(streamIds, streamId)
+		}
+	}
+
+	*reply = PostProcessorProcessMultiResponse{
+		Err:       NewBasicError(err),
+		Keep:      keep,
+		StreamIds: streamIds,
+	}
+
+	return nil
+}
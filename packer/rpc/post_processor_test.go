@@ -88,4 +88,75 @@ func TestPostProcessor_Implements(t *testing.T) {
 	if _, ok := raw.(packer.PostProcessor); !ok {
 		t.Fatal("not a postprocessor")
 	}
+	if _, ok := raw.(packer.MultiPostProcessor); !ok {
+		t.Fatal("not a multi postprocessor")
+	}
+}
+
+func TestPostProcessorRPC_multi(t *testing.T) {
+	p := &packer.MockMultiPostProcessor{ArtifactIds: []string{"vhd", "vmdk"}}
+
+	// Start the server
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+	server.RegisterPostProcessor(p)
+
+	ppClient := client.PostProcessor()
+	mp, ok := ppClient.(packer.MultiPostProcessor)
+	if !ok {
+		t.Fatal("should be a MultiPostProcessor")
+	}
+
+	artifacts, _, err := mp.PostProcessMulti(new(testUi), &packer.MockArtifact{IdValue: "ppTestId"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !p.PostProcessCalled {
+		t.Fatal("postprocess should be called")
+	}
+
+	if len(artifacts) != 2 || artifacts[0].Id() != "vhd" || artifacts[1].Id() != "vmdk" {
+		t.Fatalf("bad: %#v", artifacts)
+	}
+}
+
+// legacyPostProcessorServer is the PostProcessorServer of a plugin that
+// was built before there was PostProcessMulti.
+type legacyPostProcessorServer struct {
+	s *PostProcessorServer
+}
+
+func (l *legacyPostProcessorServer) Configure(args *PostProcessorConfigureArgs, reply *[]string) error {
+	return l.s.Configure(args, reply)
+}
+
+func (l *legacyPostProcessorServer) PostProcess(streamId uint32, reply *PostProcessorProcessResponse) error {
+	return l.s.PostProcess(streamId, reply)
+}
+
+func TestPostProcessorRPC_multiLegacy(t *testing.T) {
+	p := new(TestPostProcessor)
+
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+	server.server.RegisterName(DefaultPostProcessorEndpoint, &legacyPostProcessorServer{
+		s: &PostProcessorServer{mux: server.mux, p: p},
+	})
+
+	mp := client.PostProcessor().(packer.MultiPostProcessor)
+	artifacts, _, err := mp.PostProcessMulti(new(testUi), &packer.MockArtifact{IdValue: "ppTestId"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !p.ppCalled {
+		t.Fatal("postprocess should be called")
+	}
+
+	if len(artifacts) != 1 || artifacts[0].Id() != "id" {
+		t.Fatalf("bad: %#v", artifacts)
+	}
 }
@@ -26,11 +26,12 @@ type ProvisionerPrepareArgs struct {
 
 func (p *provisioner) Prepare(configs ...interface{}) (err error) {
 	args := &ProvisionerPrepareArgs{configs}
-	if cerr := p.client.Call("Provisioner.Prepare", args, new(interface{})); cerr != nil {
+	var warnings []string
+	if cerr := p.client.Call("Provisioner.Prepare", args, &warnings); cerr != nil {
 		err = cerr
 	}
 
-	return
+	return packer.WarningsOrError(warnings, err)
 }
 
 func (p *provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
@@ -43,6 +44,13 @@ func (p *provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	return p.client.Call("Provisioner.Provision", nextId, new(interface{}))
 }
 
+func (p *provisioner) SetBuildData(data packer.BuildData) {
+	err := p.client.Call("Provisioner.SetBuildData", data, new(interface{}))
+	if err != nil {
+		log.Printf("Provisioner.SetBuildData err: %s", err)
+	}
+}
+
 func (p *provisioner) Cancel() {
 	err := p.client.Call("Provisioner.Cancel", new(interface{}), new(interface{}))
 	if err != nil {
@@ -50,8 +58,22 @@ func (p *provisioner) Cancel() {
 	}
 }
 
-func (p *ProvisionerServer) Prepare(args *ProvisionerPrepareArgs, reply *interface{}) error {
-	return p.p.Prepare(args.Configs...)
+func (p *provisioner) ConfigSchema() map[string]interface{} {
+	var raw []byte
+	if err := p.client.Call("Provisioner.ConfigSchema", new(interface{}), &raw); err != nil {
+		log.Printf("Error getting provisioner config schema: %s", err)
+		return nil
+	}
+
+	return decodeConfigSchema(raw)
+}
+
+func (p *ProvisionerServer) Prepare(args *ProvisionerPrepareArgs, reply *[]string) error {
+	// Warnings are sent back as the reply, since the error of an RPC call
+	// only carries its message.
+	warnings, err := packer.SplitWarnings(p.p.Prepare(args.Configs...))
+	*reply = warnings
+	return err
 }
 
 func (p *ProvisionerServer) Provision(streamId uint32, reply *interface{}) error {
@@ -68,7 +90,19 @@ func (p *ProvisionerServer) Provision(streamId uint32, reply *interface{}) error
 	return nil
 }
 
+func (p *ProvisionerServer) SetBuildData(args packer.BuildData, reply *interface{}) error {
+	if bp, ok := p.p.(packer.BuildDataProvisioner); ok {
+		bp.SetBuildData(args)
+	}
+
+	return nil
+}
+
 func (p *ProvisionerServer) Cancel(args *interface{}, reply *interface{}) error {
 	p.p.Cancel()
 	return nil
 }
+
+func (p *ProvisionerServer) ConfigSchema(args *interface{}, reply *[]byte) error {
+	return encodeConfigSchema(p.p, reply)
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"errors"
 	"github.com/mitchellh/packer/packer"
 	"reflect"
 	"testing"
@@ -36,6 +37,13 @@ func TestProvisionerRPC(t *testing.T) {
 		t.Fatal("should be called")
 	}
 
+	// Test SetBuildData
+	data := packer.BuildData{"foo": "bar"}
+	pClient.(packer.BuildDataProvisioner).SetBuildData(data)
+	if !reflect.DeepEqual(p.BuildData, data) {
+		t.Fatalf("bad: %#v", p.BuildData)
+	}
+
 	// Test Cancel
 	pClient.Cancel()
 	if !p.CancelCalled {
@@ -43,6 +51,38 @@ func TestProvisionerRPC(t *testing.T) {
 	}
 }
 
+func TestProvisionerRPC_prepareWarnings(t *testing.T) {
+	p := &packer.MockProvisioner{
+		PrepError: packer.Warnings{"foo", "bar"},
+	}
+
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+	server.RegisterProvisioner(p)
+	pClient := client.Provisioner()
+
+	// Warnings must come back as warnings, not as a plain error
+	err := pClient.Prepare(42)
+	warns, ok := err.(packer.Warnings)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !reflect.DeepEqual(warns, packer.Warnings{"foo", "bar"}) {
+		t.Fatalf("bad: %#v", warns)
+	}
+
+	// Errors are still errors
+	p.PrepError = errors.New("nope")
+	err = pClient.Prepare(42)
+	if err == nil || err.Error() != "nope" {
+		t.Fatalf("bad: %#v", err)
+	}
+	if _, ok := err.(packer.Warnings); ok {
+		t.Fatal("should not be warnings")
+	}
+}
+
 func TestProvisioner_Implements(t *testing.T) {
 	var _ packer.Provisioner = new(provisioner)
 }
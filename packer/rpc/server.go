@@ -7,22 +7,25 @@ import (
 	"io"
 	"log"
 	"net/rpc"
+	"sync"
 	"sync/atomic"
 )
 
 var endpointId uint64
 
 const (
-	DefaultArtifactEndpoint      string = "Artifact"
-	DefaultBuildEndpoint                = "Build"
-	DefaultBuilderEndpoint              = "Builder"
-	DefaultCacheEndpoint                = "Cache"
-	DefaultCommandEndpoint              = "Command"
-	DefaultCommunicatorEndpoint         = "Communicator"
-	DefaultHookEndpoint                 = "Hook"
-	DefaultPostProcessorEndpoint        = "PostProcessor"
-	DefaultProvisionerEndpoint          = "Provisioner"
-	DefaultUiEndpoint                   = "Ui"
+	DefaultArtifactEndpoint             string = "Artifact"
+	DefaultBuildEndpoint                       = "Build"
+	DefaultBuilderEndpoint                     = "Builder"
+	DefaultCacheEndpoint                       = "Cache"
+	DefaultCommandEndpoint                     = "Command"
+	DefaultCommunicatorEndpoint                = "Communicator"
+	DefaultCommunicatorProviderEndpoint        = "CommunicatorProvider"
+	DefaultHookEndpoint                        = "Hook"
+	DefaultPluginEndpoint                      = "Plugin"
+	DefaultPostProcessorEndpoint               = "PostProcessor"
+	DefaultProvisionerEndpoint                 = "Provisioner"
+	DefaultUiEndpoint                          = "Ui"
 )
 
 // Server represents an RPC server for Packer. This must be paired on
@@ -32,6 +35,11 @@ type Server struct {
 	streamId uint32
 	server   *rpc.Server
 	closeMux bool
+
+	// closeCh is closed by Close, which stops waiting for the client if
+	// it hasn't connected yet.
+	closeCh   chan struct{}
+	closeOnce sync.Once
 }
 
 // NewServer returns a new Packer RPC server.
@@ -49,10 +57,15 @@ func newServerWithMux(mux *muxBroker, streamId uint32) *Server {
 		streamId: streamId,
 		server:   rpc.NewServer(),
 		closeMux: false,
+		closeCh:  make(chan struct{}),
 	}
 }
 
 func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+	})
+
 	if s.closeMux {
 		log.Printf("[WARN] Shutting down mux conn in Server")
 		return s.mux.Close()
@@ -94,6 +107,13 @@ func (s *Server) RegisterCommunicator(c packer.Communicator) {
 	})
 }
 
+func (s *Server) RegisterCommunicatorProvider(p packer.CommunicatorProvider) {
+	s.server.RegisterName(DefaultCommunicatorProviderEndpoint, &CommunicatorProviderServer{
+		p:   p,
+		mux: s.mux,
+	})
+}
+
 func (s *Server) RegisterHook(h packer.Hook) {
 	s.server.RegisterName(DefaultHookEndpoint, &HookServer{
 		hook: h,
@@ -101,6 +121,14 @@ func (s *Server) RegisterHook(h packer.Hook) {
 	})
 }
 
+// RegisterPluginVersion serves the version of the plugin, which is
+// empty if the plugin doesn't have one.
+func (s *Server) RegisterPluginVersion(v string) {
+	s.server.RegisterName(DefaultPluginEndpoint, &PluginServer{
+		version: v,
+	})
+}
+
 func (s *Server) RegisterPostProcessor(p packer.PostProcessor) {
 	s.server.RegisterName(DefaultPostProcessorEndpoint, &PostProcessorServer{
 		mux: s.mux,
@@ -126,7 +154,10 @@ func (s *Server) RegisterUi(ui packer.Ui) {
 func (s *Server) Serve() {
 	// Accept a connection on stream ID 0, which is always used for
 	// normal client to server connections.
-	stream, err := s.mux.Accept(s.streamId)
+	stream, err := s.mux.AcceptCancel(s.streamId, s.closeCh)
+	if err == errAcceptCancelled {
+		return
+	}
 	if err != nil {
 		log.Printf("[ERR] Error retrieving stream for serving: %s", err)
 		return
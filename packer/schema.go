@@ -0,0 +1,11 @@
+package packer
+
+// ConfigSchemer is implemented by builders and provisioners that can
+// describe the keys of their configuration as a JSON Schema. It is used
+// by `packer schema` and is optional, since components that don't
+// implement it are still valid.
+type ConfigSchemer interface {
+	// ConfigSchema returns the JSON Schema of the configuration, or nil
+	// if it isn't known.
+	ConfigSchema() map[string]interface{}
+}
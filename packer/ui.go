@@ -72,6 +72,13 @@ type MachineReadableUi struct {
 	Writer io.Writer
 }
 
+// QuietUi is a UI that wraps another UI implementation and only passes
+// through errors, questions, and machine-readable output. Say and
+// Message output is dropped.
+type QuietUi struct {
+	Ui Ui
+}
+
 func (u *ColoredUi) Ask(query string) (string, error) {
 	return u.Ui.Ask(u.colorize(query, u.Color, true))
 }
@@ -297,3 +304,23 @@ func (u *MachineReadableUi) Machine(category string, args ...string) {
 		}
 	}
 }
+
+func (u *QuietUi) Ask(query string) (string, error) {
+	return u.Ui.Ask(query)
+}
+
+func (u *QuietUi) Say(message string) {
+	log.Printf("ui: %s", message)
+}
+
+func (u *QuietUi) Message(message string) {
+	log.Printf("ui: %s", message)
+}
+
+func (u *QuietUi) Error(message string) {
+	u.Ui.Error(message)
+}
+
+func (u *QuietUi) Machine(t string, args ...string) {
+	u.Ui.Machine(t, args...)
+}
@@ -241,3 +241,27 @@ func TestMachineReadableUi(t *testing.T) {
 		t.Fatalf("bad: %#v", data)
 	}
 }
+
+func TestQuietUi_ImplUi(t *testing.T) {
+	var raw interface{}
+	raw = &QuietUi{}
+	if _, ok := raw.(Ui); !ok {
+		t.Fatalf("QuietUi must implement Ui")
+	}
+}
+
+func TestQuietUi(t *testing.T) {
+	bufferUi := testUi()
+	ui := &QuietUi{Ui: bufferUi}
+
+	ui.Say("foo")
+	ui.Message("bar")
+	if actual := readWriter(bufferUi); actual != "" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	ui.Error("baz")
+	if actual := readErrorWriter(bufferUi); actual != "baz\n" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -0,0 +1,28 @@
+package packer
+
+import (
+	"time"
+)
+
+// DefaultVerifyTimeout is how long the verification of an artifact may
+// take, including booting a machine from it, if the template doesn't set
+// a timeout.
+const DefaultVerifyTimeout = 10 * time.Minute
+
+// An ArtifactVerifier is implemented by builders that can verify their
+// artifacts before the post-processors run, by booting a machine from the
+// artifact and running a command in it. A build only verifies its
+// artifact if the template asks for it with "verify".
+type ArtifactVerifier interface {
+	// PrepareVerify is called after Prepare for builds that verify their
+	// artifact. It returns an error if the artifact can't be verified
+	// with the configuration of the builder.
+	PrepareVerify() error
+
+	// VerifyArtifact boots a machine from the artifact that Run built
+	// and runs the command in it. It returns an error if the machine
+	// can't be reached or the command doesn't exit with status zero
+	// within the timeout. The machine is discarded afterwards, leaving
+	// the artifact as it was.
+	VerifyArtifact(ui Ui, artifact Artifact, command string, timeout time.Duration) error
+}
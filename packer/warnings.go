@@ -0,0 +1,55 @@
+package packer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Warnings can be returned as the error of the Prepare method of a
+// Provisioner or the Configure method of a PostProcessor to report
+// problems with a configuration that is otherwise valid, such as the use
+// of a deprecated option. Unlike other errors, it doesn't fail the build:
+// the warnings are shown to the user together with those of the builder.
+type Warnings []string
+
+func (w Warnings) Error() string {
+	return strings.Join(w, "\n")
+}
+
+// WarningsOrError returns the warnings as the error to return from
+// Prepare or Configure, unless err is a real error, which takes
+// precedence.
+func WarningsOrError(warnings []string, err error) error {
+	if err != nil {
+		return err
+	}
+	if len(warnings) > 0 {
+		return Warnings(warnings)
+	}
+
+	return nil
+}
+
+// SplitWarnings separates the warnings from the result of Prepare or
+// Configure. The returned error is nil if there were only warnings.
+func SplitWarnings(err error) ([]string, error) {
+	if w, ok := err.(Warnings); ok {
+		return []string(w), nil
+	}
+
+	return nil, err
+}
+
+// DeprecatedOption returns the warning for an option that still works
+// but will be removed in a future version of Packer.
+func DeprecatedOption(option, replacement string) string {
+	if replacement == "" {
+		return fmt.Sprintf(
+			"The '%s' option is deprecated and will be removed in a future\n"+
+				"version of Packer.", option)
+	}
+
+	return fmt.Sprintf(
+		"The '%s' option is deprecated and will be removed in a future\n"+
+			"version of Packer. Use '%s' instead.", option, replacement)
+}
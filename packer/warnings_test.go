@@ -0,0 +1,46 @@
+package packer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWarnings_impl(t *testing.T) {
+	var _ error = Warnings{}
+}
+
+func TestSplitWarnings(t *testing.T) {
+	warns, err := SplitWarnings(Warnings{"foo", "bar"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(warns, []string{"foo", "bar"}) {
+		t.Fatalf("bad: %#v", warns)
+	}
+
+	expected := errors.New("nope")
+	warns, err = SplitWarnings(expected)
+	if err != expected {
+		t.Fatalf("bad: %#v", err)
+	}
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+}
+
+func TestWarningsOrError(t *testing.T) {
+	if err := WarningsOrError(nil, nil); err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	err := WarningsOrError([]string{"foo"}, nil)
+	if !reflect.DeepEqual(err, Warnings{"foo"}) {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	expected := errors.New("nope")
+	if err := WarningsOrError([]string{"foo"}, expected); err != expected {
+		t.Fatalf("bad: %#v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package azurevhd
+
+import (
+	"fmt"
+	"os"
+)
+
+const BuilderId = "packer.post-processor.azure-vhd"
+
+type Artifact struct {
+	// Path is the path to the VHD on disk.
+	Path string
+
+	// URL is the URL of the uploaded blob, if the VHD was uploaded.
+	URL string
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Id() string {
+	return a.URL
+}
+
+func (a *Artifact) Files() []string {
+	return []string{a.Path}
+}
+
+func (a *Artifact) String() string {
+	if a.URL != "" {
+		return fmt.Sprintf("Azure VHD: %s (uploaded to %s)", a.Path, a.URL)
+	}
+
+	return fmt.Sprintf("Azure VHD: %s", a.Path)
+}
+
+func (*Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	return os.Remove(a.Path)
+}
@@ -0,0 +1,195 @@
+package azurevhd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// blobVersion is the version of the Blob service API that is used.
+	blobVersion = "2015-04-05"
+
+	// blobPageSize is the most data that can be written to a page blob
+	// in one request.
+	blobPageSize = 4 * 1024 * 1024
+)
+
+// blobClient uploads page blobs to an Azure storage account,
+// authenticating with the shared key of the account.
+type blobClient struct {
+	account string
+	key     []byte
+	url     string
+	client  *http.Client
+}
+
+func newBlobClient(account, key, endpointSuffix string) (*blobClient, error) {
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding storage access key: %s", err)
+	}
+
+	return &blobClient{
+		account: account,
+		key:     rawKey,
+		url:     fmt.Sprintf("https://%s.blob.%s", account, endpointSuffix),
+		client:  http.DefaultClient,
+	}, nil
+}
+
+// URL returns the URL of the blob with the given name.
+func (c *blobClient) URL(container, name string) string {
+	return fmt.Sprintf("%s/%s/%s", c.url, container, name)
+}
+
+// UploadPageBlob uploads the file at path as a page blob. The size of
+// the file must be a multiple of 512 bytes. Pages that contain only
+// zeros aren't uploaded, since a new page blob reads as zeros.
+func (c *blobClient) UploadPageBlob(container, name, path string, progress func(int64, int64)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size()
+
+	blobURL := c.URL(container, name)
+	err = c.do("PUT", blobURL, nil, map[string]string{
+		"x-ms-blob-type":           "PageBlob",
+		"x-ms-blob-content-length": fmt.Sprintf("%d", size),
+	})
+	if err != nil {
+		return fmt.Errorf("Error creating blob: %s", err)
+	}
+
+	buf := make([]byte, blobPageSize)
+	for offset := int64(0); offset < size; {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		if !isZero(buf[:n]) {
+			err = c.do("PUT", blobURL+"?comp=page", buf[:n], map[string]string{
+				"x-ms-page-write": "update",
+				"x-ms-range":      fmt.Sprintf("bytes=%d-%d", offset, offset+int64(n)-1),
+			})
+			if err != nil {
+				return fmt.Errorf("Error uploading page at %d: %s", offset, err)
+			}
+		}
+
+		offset += int64(n)
+		if progress != nil {
+			progress(offset, size)
+		}
+	}
+
+	return nil
+}
+
+func (c *blobClient) do(method, rawurl string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(method, rawurl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = int64(len(body))
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", blobVersion)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"SharedKey %s:%s", c.account, c.sign(req)))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// sign returns the shared key signature of the request.
+func (c *blobClient) sign(req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = fmt.Sprintf("%d", req.ContentLength)
+	}
+
+	parts := []string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, which is sent as x-ms-date instead
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}
+
+	// Canonicalized headers
+	var names []string
+	for k := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-ms-") {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		parts = append(parts, fmt.Sprintf("%s:%s", k, req.Header.Get(k)))
+	}
+
+	// Canonicalized resource
+	resource := fmt.Sprintf("/%s%s", c.account, req.URL.Path)
+	query := req.URL.Query()
+	var params []string
+	for k := range query {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		resource += fmt.Sprintf("\n%s:%s", strings.ToLower(k), strings.Join(query[k], ","))
+	}
+	parts = append(parts, resource)
+
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,115 @@
+package azurevhd
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBlobClientUploadPageBlob(t *testing.T) {
+	var requests []string
+	var pages int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey account:") {
+			t.Errorf("bad auth: %s", r.Header.Get("Authorization"))
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Query().Get("comp") == "page" {
+			pages += len(body)
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("x-ms-range"))
+		} else {
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+
+				r.Header.Get("x-ms-blob-type")+" "+r.Header.Get("x-ms-blob-content-length"))
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	// The second page is all zeros, so it shouldn't be uploaded
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	data := make([]byte, blobPageSize*2+512)
+	data[0] = 1
+	data[len(data)-1] = 1
+	tf.Write(data)
+	tf.Close()
+
+	client, err := newBlobClient("account", base64.StdEncoding.EncodeToString([]byte("key")), "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client.url = ts.URL
+
+	if err := client.UploadPageBlob("vhds", "disk.vhd", tf.Name(), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"PUT /vhds/disk.vhd PageBlob 8389120",
+		"PUT /vhds/disk.vhd bytes=0-4194303",
+		"PUT /vhds/disk.vhd bytes=8388608-8389119",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("bad: %#v", requests)
+	}
+	if pages != blobPageSize+512 {
+		t.Fatalf("bad: %d", pages)
+	}
+}
+
+func TestBlobClientUploadPageBlob_error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AuthenticationFailed", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	client, err := newBlobClient("account", "", "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client.url = ts.URL
+
+	err = client.UploadPageBlob("vhds", "disk.vhd", tf.Name(), nil)
+	if err == nil || !strings.Contains(err.Error(), "AuthenticationFailed") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestBlobClientSign(t *testing.T) {
+	client := &blobClient{account: "account", key: []byte("key")}
+	req, err := http.NewRequest("PUT", "https://account.blob.core.windows.net/vhds/disk.vhd?comp=page", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.ContentLength = 512
+	req.Header.Set("x-ms-date", "Thu, 01 Jan 2015 00:00:00 GMT")
+	req.Header.Set("x-ms-version", blobVersion)
+
+	// Headers that aren't signed don't change the signature
+	expected := client.sign(req)
+	req.Header.Set("User-Agent", "packer")
+	if actual := client.sign(req); actual != expected {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	req.Header.Set("x-ms-range", "bytes=0-511")
+	if actual := client.sign(req); actual == expected {
+		t.Fatal("x-ms headers should be signed")
+	}
+}
@@ -0,0 +1,193 @@
+package azurevhd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/helper/config"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The path of the VHD to create. Defaults to the path of the disk
+	// image with a .vhd extension.
+	OutputPath string `mapstructure:"output"`
+
+	// The path to qemu-img, which is used to convert qcow2 images.
+	QemuImgPath string `mapstructure:"qemu_img_path"`
+
+	KeepInputArtifact bool `mapstructure:"keep_input_artifact"`
+
+	// The storage account to upload the VHD to. If these aren't set, the
+	// VHD isn't uploaded.
+	StorageAccount        string `mapstructure:"storage_account"`
+	StorageAccessKey      string `mapstructure:"storage_access_key"`
+	StorageContainer      string `mapstructure:"storage_container"`
+	StorageEndpointSuffix string `mapstructure:"storage_endpoint_suffix"`
+	BlobName              string `mapstructure:"blob_name"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate: true,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.QemuImgPath == "" {
+		p.config.QemuImgPath = "qemu-img"
+	}
+
+	if p.config.StorageEndpointSuffix == "" {
+		p.config.StorageEndpointSuffix = "core.windows.net"
+	}
+
+	errs := new(packer.MultiError)
+	if p.upload() {
+		required := map[string]string{
+			"storage_account":    p.config.StorageAccount,
+			"storage_access_key": p.config.StorageAccessKey,
+			"storage_container":  p.config.StorageContainer,
+		}
+		for key, value := range required {
+			if value == "" {
+				errs = packer.MultiErrorAppend(
+					errs, fmt.Errorf("%s must be set to upload the VHD", key))
+			}
+		}
+
+		if p.config.StorageAccessKey != "" {
+			if _, err := newBlobClient("", p.config.StorageAccessKey, ""); err != nil {
+				errs = packer.MultiErrorAppend(errs, err)
+			}
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	source, format, err := common.FindDiskImage(common.ArtifactImageFiles(artifact))
+	if err != nil {
+		return nil, false, err
+	}
+
+	output := p.config.OutputPath
+	if output == "" {
+		output = strings.TrimSuffix(source, filepath.Ext(source)) + ".vhd"
+	}
+	if output == source {
+		return nil, false, fmt.Errorf("output must be different from the disk image: %s", source)
+	}
+
+	ui.Say(fmt.Sprintf("Converting %s image %s to a fixed VHD: %s", format, source, output))
+	if format == "qcow2" {
+		err = p.convertQcow2(source, output)
+	} else {
+		err = copyFile(source, output)
+	}
+	if err != nil {
+		os.Remove(output)
+		return nil, false, fmt.Errorf("Error converting disk image: %s", err)
+	}
+
+	if err := finishVHD(output); err != nil {
+		os.Remove(output)
+		return nil, false, fmt.Errorf("Error writing VHD: %s", err)
+	}
+
+	result := &Artifact{Path: output}
+	if p.upload() {
+		client, err := newBlobClient(p.config.StorageAccount,
+			p.config.StorageAccessKey, p.config.StorageEndpointSuffix)
+		if err != nil {
+			return nil, false, err
+		}
+
+		name := p.config.BlobName
+		if name == "" {
+			name = filepath.Base(output)
+		}
+
+		result.URL = client.URL(p.config.StorageContainer, name)
+		ui.Say(fmt.Sprintf("Uploading VHD to %s", result.URL))
+		lastPercent := int64(-1)
+		progress := func(done, total int64) {
+			if percent := done * 100 / total; percent/10 != lastPercent/10 {
+				ui.Message(fmt.Sprintf("Uploaded %d%%", percent))
+				lastPercent = percent
+			}
+		}
+
+		err = client.UploadPageBlob(p.config.StorageContainer, name, output, progress)
+		if err != nil {
+			return nil, false, fmt.Errorf("Error uploading VHD: %s", err)
+		}
+	}
+
+	return result, p.config.KeepInputArtifact, nil
+}
+
+// upload returns true if the VHD should be uploaded to a storage account.
+func (p *PostProcessor) upload() bool {
+	return p.config.StorageAccount != "" ||
+		p.config.StorageAccessKey != "" ||
+		p.config.StorageContainer != ""
+}
+
+func (p *PostProcessor) convertQcow2(source, output string) error {
+	var stderr bytes.Buffer
+	args := []string{"convert", "-f", "qcow2", "-O", "raw", source, output}
+	log.Printf("Executing %s: %#v", p.config.QemuImgPath, args)
+	cmd := exec.Command(p.config.QemuImgPath, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s\nStderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
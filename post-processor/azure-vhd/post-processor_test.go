@@ -0,0 +1,93 @@
+package azurevhd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure_storage(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config := testConfig()
+	config["storage_account"] = "account"
+	p = PostProcessor{}
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["storage_container"] = "vhds"
+	config["storage_access_key"] = "not base64!"
+	p = PostProcessor{}
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["storage_access_key"] = "a2V5"
+	p = PostProcessor{}
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestPostProcessorPostProcess_raw(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	source := filepath.Join(td, "disk.raw")
+	if err := ioutil.WriteFile(source, []byte("disk"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{FilesValue: []string{source}}
+	result, keep, err := p.PostProcess(testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if keep {
+		t.Fatal("should not keep")
+	}
+
+	output := filepath.Join(td, "disk.vhd")
+	if files := result.Files(); len(files) != 1 || files[0] != output {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	fi, err := os.Stat(output)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if fi.Size() != vhdAlignment+vhdFooterSize {
+		t.Fatalf("bad: %d", fi.Size())
+	}
+}
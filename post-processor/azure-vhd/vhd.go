@@ -0,0 +1,108 @@
+package azurevhd
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"os"
+	"time"
+)
+
+// Azure requires fixed VHDs whose virtual size is a whole number of
+// megabytes.
+const vhdAlignment = 1024 * 1024
+
+const vhdFooterSize = 512
+
+// vhdEpoch is the time that VHD timestamps are relative to.
+var vhdEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// vhdFooter returns the footer of a fixed VHD with a virtual size of
+// size bytes, as described in the Virtual Hard Disk Image Format
+// Specification.
+func vhdFooter(size int64, now time.Time) []byte {
+	f := make([]byte, vhdFooterSize)
+	copy(f[0:8], "conectix")
+	binary.BigEndian.PutUint32(f[8:12], 2)           // Features: reserved
+	binary.BigEndian.PutUint32(f[12:16], 0x00010000) // File format version
+	binary.BigEndian.PutUint64(f[16:24], ^uint64(0)) // Data offset: none
+	binary.BigEndian.PutUint32(f[24:28], uint32(now.Sub(vhdEpoch)/time.Second))
+	copy(f[28:32], "pckr")                           // Creator application
+	binary.BigEndian.PutUint32(f[32:36], 0x00010000) // Creator version
+	copy(f[36:40], "Wi2k")                           // Creator host OS
+	binary.BigEndian.PutUint64(f[40:48], uint64(size))
+	binary.BigEndian.PutUint64(f[48:56], uint64(size))
+	binary.BigEndian.PutUint32(f[56:60], vhdGeometry(size))
+	binary.BigEndian.PutUint32(f[60:64], 2) // Disk type: fixed
+	rand.Read(f[68:84])                     // Unique ID
+
+	var sum uint32
+	for _, b := range f {
+		sum += uint32(b)
+	}
+	binary.BigEndian.PutUint32(f[64:68], ^sum)
+
+	return f
+}
+
+// vhdGeometry returns the CHS geometry for a disk of the given size,
+// encoded as it is stored in the footer.
+func vhdGeometry(size int64) uint32 {
+	totalSectors := size / 512
+	if totalSectors > 65535*16*255 {
+		totalSectors = 65535 * 16 * 255
+	}
+
+	var sectors, heads, cylinderTimesHeads int64
+	if totalSectors >= 65535*16*63 {
+		sectors = 255
+		heads = 16
+		cylinderTimesHeads = totalSectors / sectors
+	} else {
+		sectors = 17
+		cylinderTimesHeads = totalSectors / sectors
+		heads = (cylinderTimesHeads + 1023) / 1024
+		if heads < 4 {
+			heads = 4
+		}
+		if cylinderTimesHeads >= heads*1024 || heads > 16 {
+			sectors = 31
+			heads = 16
+			cylinderTimesHeads = totalSectors / sectors
+		}
+		if cylinderTimesHeads >= heads*1024 {
+			sectors = 63
+			heads = 16
+			cylinderTimesHeads = totalSectors / sectors
+		}
+	}
+
+	cylinders := cylinderTimesHeads / heads
+	return uint32(cylinders<<16 | heads<<8 | sectors)
+}
+
+// finishVHD turns the raw disk image at path into a fixed VHD in place.
+// The image is padded to a whole number of megabytes, sparsely if the
+// file system supports it, and the footer is appended.
+func finishVHD(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := (fi.Size() + vhdAlignment - 1) / vhdAlignment * vhdAlignment
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+
+	if _, err := f.WriteAt(vhdFooter(size, time.Now()), size); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
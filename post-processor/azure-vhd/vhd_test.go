@@ -0,0 +1,95 @@
+package azurevhd
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestVHDFooter(t *testing.T) {
+	now := vhdEpoch.Add(100 * time.Second)
+	f := vhdFooter(10*vhdAlignment, now)
+
+	if len(f) != vhdFooterSize {
+		t.Fatalf("bad: %d", len(f))
+	}
+	if string(f[0:8]) != "conectix" {
+		t.Fatalf("bad: %q", f[0:8])
+	}
+	if v := binary.BigEndian.Uint64(f[16:24]); v != ^uint64(0) {
+		t.Fatalf("bad: %x", v)
+	}
+	if v := binary.BigEndian.Uint32(f[24:28]); v != 100 {
+		t.Fatalf("bad: %d", v)
+	}
+	if v := binary.BigEndian.Uint64(f[48:56]); v != 10*vhdAlignment {
+		t.Fatalf("bad: %d", v)
+	}
+	if v := binary.BigEndian.Uint32(f[60:64]); v != 2 {
+		t.Fatalf("bad: %d", v)
+	}
+
+	// The checksum is the complement of the sum of the other bytes
+	checksum := binary.BigEndian.Uint32(f[64:68])
+	var sum uint32
+	for i, b := range f {
+		if i < 64 || i >= 68 {
+			sum += uint32(b)
+		}
+	}
+	if checksum != ^sum {
+		t.Fatalf("bad: %x != %x", checksum, ^sum)
+	}
+}
+
+func TestVHDGeometry(t *testing.T) {
+	cases := []struct {
+		Size     int64
+		Expected uint32
+	}{
+		// 10 MB: 20480 sectors, 17 sectors per track and 4 heads
+		{10 * vhdAlignment, 301<<16 | 4<<8 | 17},
+
+		// 30 GB, which needs 63 sectors per track and 16 heads
+		{30 * 1024 * vhdAlignment, 62415<<16 | 16<<8 | 63},
+
+		// Larger disks are capped
+		{200 * 1024 * 1024 * vhdAlignment, 65535<<16 | 16<<8 | 255},
+	}
+
+	for _, tc := range cases {
+		if actual := vhdGeometry(tc.Size); actual != tc.Expected {
+			t.Fatalf("%d: bad: %x, expected %x", tc.Size, actual, tc.Expected)
+		}
+	}
+}
+
+func TestFinishVHD(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Write([]byte("disk"))
+	tf.Close()
+
+	if err := finishVHD(tf.Name()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(tf.Name())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(data) != vhdAlignment+vhdFooterSize {
+		t.Fatalf("bad: %d", len(data))
+	}
+	if string(data[:4]) != "disk" {
+		t.Fatalf("bad: %q", data[:4])
+	}
+	if string(data[vhdAlignment:vhdAlignment+8]) != "conectix" {
+		t.Fatalf("bad: %q", data[vhdAlignment:vhdAlignment+8])
+	}
+}
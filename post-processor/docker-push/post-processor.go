@@ -22,6 +22,8 @@ type Config struct {
 	LoginPassword string `mapstructure:"login_password"`
 	LoginServer   string `mapstructure:"login_server"`
 
+	docker.RegistryAuthConfig `mapstructure:",squash"`
+
 	ctx interpolate.Context
 }
 
@@ -42,6 +44,15 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	var errs *packer.MultiError
+	for _, err := range p.config.RegistryAuthConfig.Prepare(p.config.LoginServer) {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
 	return nil
 }
 
@@ -60,13 +71,23 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
 	}
 
-	if p.config.Login {
+	if p.config.Login || p.config.RegistryAuthConfig.Enabled() {
 		ui.Message("Logging in...")
+		username, password := p.config.LoginUsername, p.config.LoginPassword
+		if p.config.RegistryAuthConfig.Enabled() {
+			var err error
+			username, password, err = p.config.RegistryAuthConfig.Credentials(p.config.LoginServer)
+			if err != nil {
+				return nil, false, fmt.Errorf(
+					"Error getting the credentials of the registry: %s", err)
+			}
+		}
+
 		err := driver.Login(
 			p.config.LoginServer,
 			p.config.LoginEmail,
-			p.config.LoginUsername,
-			p.config.LoginPassword)
+			username,
+			password)
 		if err != nil {
 			return nil, false, fmt.Errorf(
 				"Error logging in to Docker: %s", err)
@@ -32,6 +32,20 @@ func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
 	var _ packer.PostProcessor = new(PostProcessor)
 }
 
+func TestPostProcessorConfigure_registryAuth(t *testing.T) {
+	var p PostProcessor
+	config := testConfig()
+	config["ecr_login"] = true
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should have error without login_server")
+	}
+
+	config["login_server"] = "123456789012.dkr.ecr.us-east-1.amazonaws.com"
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
 func TestPostProcessor_PostProcess(t *testing.T) {
 	driver := &docker.MockDriver{}
 	p := &PostProcessor{Driver: driver}
@@ -0,0 +1,538 @@
+package dockersave
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// savedImage is an image that was saved with `docker save` and extracted
+// into a directory. Both the manifest.json format of Docker 1.10 and
+// later and the older format, in which each layer has its own json, are
+// understood.
+type savedImage struct {
+	// config is the configuration of the image, in the format used by
+	// manifest.json.
+	config map[string]interface{}
+
+	// layers are the paths of the layer tars, bottom first.
+	layers []string
+
+	// repoTags are the repository:tag names of the image.
+	repoTags []string
+}
+
+// readSavedImage extracts the output of `docker save` into dir and reads
+// the image from it.
+func readSavedImage(r io.Reader, dir string) (*savedImage, error) {
+	if err := extractTar(r, dir); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "manifest.json")); err == nil {
+		return readManifestImage(dir)
+	}
+
+	return readLegacyImage(dir)
+}
+
+func readManifestImage(dir string) (*savedImage, error) {
+	var manifest []struct {
+		Config   string
+		RepoTags []string
+		Layers   []string
+	}
+	if err := readJSON(filepath.Join(dir, "manifest.json"), &manifest); err != nil {
+		return nil, err
+	}
+	if len(manifest) != 1 {
+		return nil, fmt.Errorf("expected one image in manifest.json, found %d", len(manifest))
+	}
+
+	image := &savedImage{repoTags: manifest[0].RepoTags}
+	if err := readJSON(filepath.Join(dir, manifest[0].Config), &image.config); err != nil {
+		return nil, err
+	}
+	for _, layer := range manifest[0].Layers {
+		image.layers = append(image.layers, filepath.Join(dir, filepath.FromSlash(layer)))
+	}
+
+	return image, nil
+}
+
+func readLegacyImage(dir string) (*savedImage, error) {
+	image := new(savedImage)
+
+	// The top layer is the one that is tagged or, if the image was saved
+	// by ID, the one that no other layer has as its parent.
+	var top string
+	var repositories map[string]map[string]string
+	err := readJSON(filepath.Join(dir, "repositories"), &repositories)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for repo, tags := range repositories {
+		for tag, id := range tags {
+			image.repoTags = append(image.repoTags, repo+":"+tag)
+			top = id
+		}
+	}
+	sort.Strings(image.repoTags)
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	layers := make(map[string]map[string]interface{})
+	parents := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		var layer map[string]interface{}
+		if err := readJSON(filepath.Join(dir, entry.Name(), "json"), &layer); err != nil {
+			return nil, err
+		}
+		layers[entry.Name()] = layer
+		if parent, ok := layer["parent"].(string); ok {
+			parents[parent] = true
+		}
+	}
+
+	if top == "" {
+		for id := range layers {
+			if !parents[id] {
+				if top != "" {
+					return nil, fmt.Errorf("saved image has more than one top layer")
+				}
+				top = id
+			}
+		}
+	}
+
+	topLayer, ok := layers[top]
+	if !ok {
+		return nil, fmt.Errorf("top layer not found in saved image: %s", top)
+	}
+
+	for id := top; id != ""; {
+		layer, ok := layers[id]
+		if !ok {
+			return nil, fmt.Errorf("layer not found in saved image: %s", id)
+		}
+		image.layers = append([]string{filepath.Join(dir, id, "layer.tar")}, image.layers...)
+		id, _ = layer["parent"].(string)
+	}
+
+	image.config = make(map[string]interface{})
+	for _, key := range []string{
+		"architecture", "author", "config", "container", "container_config",
+		"created", "docker_version", "os",
+	} {
+		if v, ok := topLayer[key]; ok {
+			image.config[key] = v
+		}
+	}
+
+	return image, nil
+}
+
+// writeImage writes the image in the manifest.json format that `docker
+// load` reads. If mtime is set, the timestamps in the configuration and
+// of the files in the tar are set to it, and values that differ between
+// builds are removed. The layers must already be normalized.
+func writeImage(w io.Writer, image *savedImage, mtime *time.Time) error {
+	tw := tar.NewWriter(w)
+	now := time.Now()
+	if mtime != nil {
+		now = *mtime
+	}
+
+	config := image.config
+	if mtime != nil {
+		normalizeConfig(config, *mtime)
+	}
+
+	var diffIDs []interface{}
+	var layerPaths []string
+	for _, layer := range image.layers {
+		diffID, err := fileDigest(layer)
+		if err != nil {
+			return err
+		}
+		diffIDs = append(diffIDs, "sha256:"+diffID)
+
+		name := diffID + "/layer.tar"
+		if err := writeTarFile(tw, name, layer, now); err != nil {
+			return err
+		}
+		layerPaths = append(layerPaths, name)
+	}
+	config["rootfs"] = map[string]interface{}{
+		"type":     "layers",
+		"diff_ids": diffIDs,
+	}
+
+	rawConfig, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	configName := fmt.Sprintf("%x.json", sha256.Sum256(rawConfig))
+	if err := writeTarBytes(tw, configName, rawConfig, now); err != nil {
+		return err
+	}
+
+	repoTags := image.repoTags
+	if repoTags == nil {
+		repoTags = []string{}
+	}
+	manifest, err := json.Marshal([]map[string]interface{}{
+		{
+			"Config":   configName,
+			"RepoTags": repoTags,
+			"Layers":   layerPaths,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeTarBytes(tw, "manifest.json", manifest, now); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// normalizeConfig sets the timestamps in the image configuration to
+// mtime and removes the values that refer to the build container.
+func normalizeConfig(config map[string]interface{}, mtime time.Time) {
+	created := mtime.UTC().Format(time.RFC3339)
+	config["created"] = created
+	delete(config, "container")
+	delete(config, "container_config")
+
+	if c, ok := config["config"].(map[string]interface{}); ok {
+		c["Hostname"] = ""
+	}
+
+	if history, ok := config["history"].([]interface{}); ok {
+		for _, h := range history {
+			if h, ok := h.(map[string]interface{}); ok {
+				h["created"] = created
+			}
+		}
+	}
+}
+
+// normalizeLayer rewrites the layer tar at src into dst with all
+// timestamps set to mtime.
+func normalizeLayer(src, dst string, mtime time.Time) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	tr := tar.NewReader(in)
+	tw := tar.NewWriter(out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		normalizeHeader(hdr, mtime)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// squashLayers merges the layer tars, bottom first, into a single layer
+// tar at dst. Whiteouts are applied, so files that are deleted or
+// replaced by a higher layer aren't included.
+func squashLayers(layers []string, dst string, mtime *time.Time) error {
+	keep, err := squashedEntries(layers)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	tw := tar.NewWriter(out)
+	for i, layer := range layers {
+		err := eachTarEntry(layer, func(hdr *tar.Header, r io.Reader) error {
+			if !keep[i][cleanEntryName(hdr.Name)] {
+				return nil
+			}
+
+			if mtime != nil {
+				normalizeHeader(hdr, *mtime)
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err := io.Copy(tw, r)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// squashedEntries returns, for each layer, the names of the entries
+// that are visible in the squashed layer. The layers are walked from the
+// top, so that the first layer to have an entry wins.
+func squashedEntries(layers []string) ([]map[string]bool, error) {
+	keep := make([]map[string]bool, len(layers))
+	seen := make(map[string]bool)
+
+	// Entries below a removed path, or below a path that a higher layer
+	// replaced with something other than a directory, are hidden.
+	removed := make(map[string]bool)
+	// The contents of opaque directories in lower layers are hidden.
+	opaque := make(map[string]bool)
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		keep[i] = make(map[string]bool)
+		layerRemoved := make(map[string]bool)
+		layerOpaque := make(map[string]bool)
+
+		err := eachTarEntry(layers[i], func(hdr *tar.Header, r io.Reader) error {
+			name := cleanEntryName(hdr.Name)
+			dir, base := path.Split(name)
+			dir = strings.TrimSuffix(dir, "/")
+
+			switch {
+			case base == ".wh..wh..opq":
+				layerOpaque[dir] = true
+				return nil
+			case strings.HasPrefix(base, ".wh."):
+				layerRemoved[path.Join(dir, base[len(".wh."):])] = true
+				return nil
+			}
+
+			if seen[name] || removed[name] || isHidden(name, removed, opaque) {
+				return nil
+			}
+
+			seen[name] = true
+			keep[i][name] = true
+			if hdr.Typeflag != tar.TypeDir {
+				layerRemoved[name] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for name := range layerRemoved {
+			removed[name] = true
+		}
+		for name := range layerOpaque {
+			opaque[name] = true
+		}
+	}
+
+	return keep, nil
+}
+
+// isHidden returns true if a parent directory of name was removed or
+// is opaque.
+func isHidden(name string, removed, opaque map[string]bool) bool {
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if removed[dir] || opaque[dir] {
+			return true
+		}
+	}
+
+	// The root directory can be made opaque too
+	return opaque[""] || opaque["."]
+}
+
+func cleanEntryName(name string) string {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	return strings.TrimPrefix(name, "/")
+}
+
+func normalizeHeader(hdr *tar.Header, mtime time.Time) {
+	hdr.ModTime = mtime
+	hdr.AccessTime = time.Time{}
+	hdr.ChangeTime = time.Time{}
+}
+
+func eachTarEntry(p string, fn func(*tar.Header, io.Reader) error) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// extractTar extracts the regular files and directories in the tar
+// into dir.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := cleanEntryName(hdr.Name)
+		if name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid path in saved image: %s", hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeTarFile(tw *tar.Writer, name, p string, mtime time.Time) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     fi.Size(),
+		ModTime:  mtime,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func writeTarBytes(tw *tar.Writer, name string, data []byte, mtime time.Time) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  mtime,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(data)
+	return err
+}
+
+func fileDigest(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func readJSON(p string, v interface{}) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("Error reading %s: %s", filepath.Base(p), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package dockersave
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type testEntry struct {
+	Name     string
+	Contents string
+	Dir      bool
+}
+
+func testTar(t *testing.T, entries []testEntry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.Name,
+			Mode:     0644,
+			Size:     int64(len(e.Contents)),
+			ModTime:  time.Now(),
+			Typeflag: tar.TypeReg,
+		}
+		if e.Dir {
+			hdr.Mode = 0755
+			hdr.Typeflag = tar.TypeDir
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err := tw.Write([]byte(e.Contents)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return buf.Bytes()
+}
+
+func testJSON(t *testing.T, v interface{}) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return string(raw)
+}
+
+// testSavedImage returns the output of `docker save` for an image with
+// two layers, in the manifest.json format.
+func testSavedImage(t *testing.T) []byte {
+	bottom := testTar(t, []testEntry{
+		{Name: "etc/", Dir: true},
+		{Name: "etc/hostname", Contents: "old"},
+		{Name: "tmp/", Dir: true},
+		{Name: "tmp/cache", Contents: "cache"},
+		{Name: "var/", Dir: true},
+		{Name: "var/log/", Dir: true},
+		{Name: "var/log/build.log", Contents: "log"},
+	})
+	top := testTar(t, []testEntry{
+		{Name: "etc/", Dir: true},
+		{Name: "etc/hostname", Contents: "new"},
+		{Name: "tmp/.wh.cache"},
+		{Name: "var/log/", Dir: true},
+		{Name: "var/log/.wh..wh..opq"},
+		{Name: "var/log/app.log", Contents: "app"},
+	})
+
+	config := map[string]interface{}{
+		"created":   time.Now().UTC().Format(time.RFC3339Nano),
+		"container": "abc123",
+		"config":    map[string]interface{}{"Hostname": "abc123"},
+	}
+
+	return testTar(t, []testEntry{
+		{Name: "bottom/layer.tar", Contents: string(bottom)},
+		{Name: "top/layer.tar", Contents: string(top)},
+		{Name: "config.json", Contents: testJSON(t, config)},
+		{Name: "manifest.json", Contents: testJSON(t, []map[string]interface{}{
+			{
+				"Config":   "config.json",
+				"RepoTags": []string{"foo:latest"},
+				"Layers":   []string{"bottom/layer.tar", "top/layer.tar"},
+			},
+		})},
+	})
+}
+
+// testReadImage reads an image written by writeImage and returns its
+// manifest, configuration and the contents of its layers.
+func testReadImage(t *testing.T, data []byte) (map[string]interface{}, map[string]interface{}, []map[string]string) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	if err := extractTar(bytes.NewReader(data), td); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var manifest []map[string]interface{}
+	if err := readJSON(filepath.Join(td, "manifest.json"), &manifest); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var config map[string]interface{}
+	configPath := filepath.Join(td, manifest[0]["Config"].(string))
+	if err := readJSON(configPath, &config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var layers []map[string]string
+	for _, layer := range manifest[0]["Layers"].([]interface{}) {
+		contents := make(map[string]string)
+		err := eachTarEntry(filepath.Join(td, layer.(string)), func(hdr *tar.Header, r io.Reader) error {
+			data, err := ioutil.ReadAll(r)
+			contents[hdr.Name] = string(data)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		layers = append(layers, contents)
+	}
+
+	return manifest[0], config, layers
+}
+
+func TestSquashLayers(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	image, err := readSavedImage(bytes.NewReader(testSavedImage(t)), filepath.Join(td, "image"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	squashed := filepath.Join(td, "squashed.tar")
+	if err := squashLayers(image.layers, squashed, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var names []string
+	contents := make(map[string]string)
+	err = eachTarEntry(squashed, func(hdr *tar.Header, r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		names = append(names, hdr.Name)
+		contents[hdr.Name] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sort.Strings(names)
+	expected := []string{
+		"etc/", "etc/hostname", "tmp/", "var/", "var/log/", "var/log/app.log",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: %#v", names)
+	}
+	if contents["etc/hostname"] != "new" {
+		t.Fatalf("bad: %#v", contents)
+	}
+}
+
+func TestReadSavedImage_legacy(t *testing.T) {
+	data := testTar(t, []testEntry{
+		{Name: "repositories", Contents: `{"foo":{"latest":"top"}}`},
+		{Name: "bottom/json", Contents: `{"id":"bottom","os":"linux"}`},
+		{Name: "bottom/layer.tar", Contents: string(testTar(t, nil))},
+		{Name: "top/json", Contents: `{"id":"top","parent":"bottom","os":"linux","architecture":"amd64"}`},
+		{Name: "top/layer.tar", Contents: string(testTar(t, nil))},
+	})
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	image, err := readSavedImage(bytes.NewReader(data), td)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		filepath.Join(td, "bottom", "layer.tar"),
+		filepath.Join(td, "top", "layer.tar"),
+	}
+	if !reflect.DeepEqual(image.layers, expected) {
+		t.Fatalf("bad: %#v", image.layers)
+	}
+	if !reflect.DeepEqual(image.repoTags, []string{"foo:latest"}) {
+		t.Fatalf("bad: %#v", image.repoTags)
+	}
+	if image.config["architecture"] != "amd64" {
+		t.Fatalf("bad: %#v", image.config)
+	}
+}
+
+func TestExtractTar_invalidPath(t *testing.T) {
+	data := testTar(t, []testEntry{{Name: "../escape", Contents: "bad"}})
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	if err := extractTar(bytes.NewReader(data), td); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -2,7 +2,11 @@ package dockersave
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/mitchellh/packer/builder/docker"
 	"github.com/mitchellh/packer/common"
@@ -20,6 +24,13 @@ type Config struct {
 
 	Path string `mapstructure:"path"`
 
+	// If true, the layers of the image are squashed into one.
+	Squash bool `mapstructure:"squash"`
+
+	// If true, timestamps are normalized so that saving an image with
+	// the same contents always results in the same file.
+	Reproducible bool `mapstructure:"reproducible"`
+
 	ctx interpolate.Context
 }
 
@@ -70,7 +81,12 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 
 	ui.Message("Saving image: " + artifact.Id())
 
-	if err := driver.SaveImage(artifact.Id(), f); err != nil {
+	if p.config.Squash || p.config.Reproducible {
+		err = p.saveRewritten(ui, driver, artifact.Id(), f)
+	} else {
+		err = driver.SaveImage(artifact.Id(), f)
+	}
+	if err != nil {
 		f.Close()
 		os.Remove(f.Name())
 
@@ -82,3 +98,76 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 
 	return artifact, true, nil
 }
+
+// saveRewritten saves the image to a temporary directory and writes it
+// to dst with its layers squashed or normalized.
+func (p *PostProcessor) saveRewritten(ui packer.Ui, driver docker.Driver, id string, dst io.Writer) error {
+	td, err := ioutil.TempDir(p.config.PackerWorkDir, "packer-docker-save")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(td)
+
+	saved := filepath.Join(td, "saved.tar")
+	if err := saveImageFile(driver, id, saved); err != nil {
+		return err
+	}
+
+	f, err := os.Open(saved)
+	if err != nil {
+		return err
+	}
+	image, err := readSavedImage(f, filepath.Join(td, "image"))
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("Error reading saved image: %s", err)
+	}
+	os.Remove(saved)
+
+	var mtime *time.Time
+	if p.config.Reproducible {
+		epoch := time.Unix(0, 0).UTC()
+		mtime = &epoch
+	}
+
+	if p.config.Squash {
+		ui.Message(fmt.Sprintf("Squashing %d layers", len(image.layers)))
+		squashed := filepath.Join(td, "squashed.tar")
+		if err := squashLayers(image.layers, squashed, mtime); err != nil {
+			return fmt.Errorf("Error squashing layers: %s", err)
+		}
+
+		image.layers = []string{squashed}
+		image.config["history"] = []interface{}{
+			map[string]interface{}{
+				"created":    image.config["created"],
+				"created_by": "squashed by packer",
+			},
+		}
+	} else {
+		ui.Message("Normalizing layers")
+		for i, layer := range image.layers {
+			normalized := filepath.Join(td, fmt.Sprintf("layer-%d.tar", i))
+			if err := normalizeLayer(layer, normalized, *mtime); err != nil {
+				return fmt.Errorf("Error normalizing layer: %s", err)
+			}
+			image.layers[i] = normalized
+		}
+	}
+
+	return writeImage(dst, image, mtime)
+}
+
+func saveImageFile(driver docker.Driver, id, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := driver.SaveImage(id, f); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
@@ -2,8 +2,16 @@ package dockersave
 
 import (
 	"bytes"
-	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/mitchellh/packer/builder/docker"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/post-processor/docker-tag"
 )
 
 func testConfig() map[string]interface{} {
@@ -29,3 +37,71 @@ func testUi() *packer.BasicUi {
 func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
 	var _ packer.PostProcessor = new(PostProcessor)
 }
+
+func testSave(t *testing.T, config map[string]interface{}, saved []byte) []byte {
+	var p PostProcessor
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	p.config.Path = filepath.Join(td, "image.tar")
+	p.Driver = &docker.MockDriver{SaveImageReader: bytes.NewReader(saved)}
+	artifact := &packer.MockArtifact{BuilderIdValue: dockertag.BuilderId, IdValue: "foo"}
+	if _, _, err := p.PostProcess(testUi(), artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(p.config.Path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return data
+}
+
+func TestPostProcessor_squash(t *testing.T) {
+	config := map[string]interface{}{"squash": true}
+	manifest, imageConfig, layers := testReadImage(t, testSave(t, config, testSavedImage(t)))
+
+	if len(layers) != 1 {
+		t.Fatalf("bad: %#v", layers)
+	}
+	if layers[0]["etc/hostname"] != "new" {
+		t.Fatalf("bad: %#v", layers[0])
+	}
+	if !reflect.DeepEqual(manifest["RepoTags"], []interface{}{"foo:latest"}) {
+		t.Fatalf("bad: %#v", manifest)
+	}
+	if diffIDs := imageConfig["rootfs"].(map[string]interface{})["diff_ids"]; len(diffIDs.([]interface{})) != 1 {
+		t.Fatalf("bad: %#v", imageConfig)
+	}
+}
+
+func TestPostProcessor_reproducible(t *testing.T) {
+	config := map[string]interface{}{"reproducible": true}
+	first := testSave(t, config, testSavedImage(t))
+
+	// The saved image differs in its timestamps and container
+	time.Sleep(1100 * time.Millisecond)
+	second := testSave(t, config, testSavedImage(t))
+	if !bytes.Equal(first, second) {
+		t.Fatal("images should be identical")
+	}
+
+	_, imageConfig, layers := testReadImage(t, first)
+	if len(layers) != 2 {
+		t.Fatalf("bad: %#v", layers)
+	}
+	if imageConfig["created"] != "1970-01-01T00:00:00Z" {
+		t.Fatalf("bad: %#v", imageConfig)
+	}
+	if _, ok := imageConfig["container"]; ok {
+		t.Fatalf("bad: %#v", imageConfig)
+	}
+}
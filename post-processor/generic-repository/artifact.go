@@ -0,0 +1,68 @@
+package genericrepository
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mitchellh/packer/common"
+)
+
+const BuilderId = "packer.post-processor.generic-repository"
+
+// Artifact is a set of files that were uploaded to a repository.
+type Artifact struct {
+	// Paths are the paths of the files in the repository.
+	Paths []string
+
+	// Checksums are the SHA256 checksums of the files by their path.
+	Checksums map[string]string
+
+	client *repositoryClient
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	urls := make([]string, len(a.Paths))
+	for i, p := range a.Paths {
+		urls[i] = a.client.URL(p)
+	}
+
+	return strings.Join(urls, ",")
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Files were uploaded to the repository: %s",
+		strings.Join(a.Paths, ", "))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	if name == common.ArtifactStateChecksums {
+		return a.Checksums
+	}
+
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	var errs []string
+	for _, p := range a.Paths {
+		log.Printf("Deleting %s", a.client.URL(p))
+		if err := a.client.Delete(p); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Error deleting files: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
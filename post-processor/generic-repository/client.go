@@ -0,0 +1,252 @@
+package genericrepository
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// repositoryClient uploads files to a generic repository of Artifactory
+// or a raw repository of Nexus. Both take a file with a PUT to its path
+// under the URL of the repository.
+type repositoryClient struct {
+	// baseURL is the URL of the repository, without a trailing slash.
+	baseURL string
+	nexus   bool
+
+	username string
+	password string
+	token    string
+
+	// properties are set on the files in Artifactory.
+	properties map[string]string
+
+	retries   int
+	retryWait time.Duration
+	client    *http.Client
+}
+
+// fileChecksums are the checksums of a file as hex strings.
+type fileChecksums struct {
+	MD5    string
+	SHA1   string
+	SHA256 string
+}
+
+func computeChecksums(path string) (*fileChecksums, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	md5h, sha1h, sha256h := md5.New(), sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5h, sha1h, sha256h), f); err != nil {
+		return nil, err
+	}
+
+	return &fileChecksums{
+		MD5:    hex.EncodeToString(md5h.Sum(nil)),
+		SHA1:   hex.EncodeToString(sha1h.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256h.Sum(nil)),
+	}, nil
+}
+
+// Verify returns an error if the file doesn't match the checksum, which
+// has the form "type:value" of common.ArtifactStateChecksums. Checksums
+// of other types than MD5, SHA1 and SHA256 can't be verified and are
+// ignored, as is an empty checksum.
+func (s *fileChecksums) Verify(checksum string) error {
+	parts := strings.SplitN(checksum, ":", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	var actual string
+	switch strings.ToLower(parts[0]) {
+	case "md5":
+		actual = s.MD5
+	case "sha1":
+		actual = s.SHA1
+	case "sha256":
+		actual = s.SHA256
+	default:
+		return nil
+	}
+
+	if !strings.EqualFold(actual, parts[1]) {
+		return fmt.Errorf("%s checksum mismatch: expected %s, the file has %s",
+			parts[0], parts[1], actual)
+	}
+
+	return nil
+}
+
+// URL returns the URL of the file at path in the repository.
+func (c *repositoryClient) URL(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.QueryEscape(s)
+		segments[i] = strings.Replace(segments[i], "+", "%20", -1)
+	}
+
+	return c.baseURL + "/" + strings.Join(segments, "/")
+}
+
+// matrixParams returns the properties as matrix parameters, which
+// Artifactory sets as properties of a file when they follow its path.
+func (c *repositoryClient) matrixParams() string {
+	keys := make([]string, 0, len(c.properties))
+	for k := range c.properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var params string
+	for _, k := range keys {
+		params += ";" + url.QueryEscape(k) + "=" + url.QueryEscape(c.properties[k])
+	}
+
+	return params
+}
+
+// Upload uploads the local file to path in the repository, retrying if
+// the repository can't be reached or has an error of its own. The
+// checksums of the file are verified by the repository.
+func (c *repositoryClient) Upload(file, path string, sums *fileChecksums) error {
+	for attempt := 0; ; attempt++ {
+		retry, err := c.upload(file, path, sums)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= c.retries {
+			return err
+		}
+
+		log.Printf("Error uploading %s, retrying: %s", path, err)
+		time.Sleep(c.retryWait)
+	}
+}
+
+func (c *repositoryClient) upload(file, path string, sums *fileChecksums) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	u := c.URL(path)
+	if !c.nexus {
+		u += c.matrixParams()
+	}
+
+	req, err := http.NewRequest("PUT", u, f)
+	if err != nil {
+		return false, err
+	}
+	req.ContentLength = fi.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	// Artifactory rejects the upload if the data doesn't match these
+	if !c.nexus {
+		req.Header.Set("X-Checksum", sums.MD5)
+		req.Header.Set("X-Checksum-Sha1", sums.SHA1)
+		req.Header.Set("X-Checksum-Sha256", sums.SHA256)
+	}
+
+	if retry, err := c.do(req); err != nil {
+		return retry, err
+	}
+
+	// Nexus doesn't verify uploads, but serves the checksum of each file
+	if c.nexus {
+		return c.verifyNexus(path, sums)
+	}
+
+	return false, nil
+}
+
+func (c *repositoryClient) verifyNexus(path string, sums *fileChecksums) (bool, error) {
+	req, err := http.NewRequest("GET", c.URL(path)+".sha1", nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.client.Do(c.authorize(req))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+	if resp.StatusCode != 200 {
+		return false, fmt.Errorf("Error getting the checksum of %s: %s", path, resp.Status)
+	}
+
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 || fields[0] != sums.SHA1 {
+		return true, fmt.Errorf("SHA1 checksum mismatch: uploaded %s, Nexus has %s",
+			sums.SHA1, strings.TrimSpace(string(body)))
+	}
+
+	return false, nil
+}
+
+// Delete deletes the file at path in the repository.
+func (c *repositoryClient) Delete(path string) error {
+	req, err := http.NewRequest("DELETE", c.URL(path), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(req)
+	return err
+}
+
+func (c *repositoryClient) authorize(req *http.Request) *http.Request {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	return req
+}
+
+// do sends the request, returning whether it can be retried if it fails.
+func (c *repositoryClient) do(req *http.Request) (bool, error) {
+	resp, err := c.client.Do(c.authorize(req))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		retry := resp.StatusCode >= 500 || resp.StatusCode == 429
+		return retry, fmt.Errorf("%s %s: %s: %s",
+			req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	return false, nil
+}
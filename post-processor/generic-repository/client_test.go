@@ -0,0 +1,169 @@
+package genericrepository
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testRepository is a fake repository that stores files by their path.
+type testRepository struct {
+	files    map[string]string
+	requests []*http.Request
+
+	// failures is how many uploads fail before one succeeds.
+	failures int
+}
+
+func (r *testRepository) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.requests = append(r.requests, req)
+	if user, pass, _ := req.BasicAuth(); user != "user" || pass != "pass" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	path := req.URL.EscapedPath()
+	switch req.Method {
+	case "PUT":
+		if r.failures > 0 {
+			r.failures--
+			http.Error(w, "Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		data, _ := ioutil.ReadAll(req.Body)
+		r.files[path] = string(data)
+		w.WriteHeader(http.StatusCreated)
+	case "GET":
+		if strings.HasSuffix(path, ".sha1") {
+			data, ok := r.files[strings.TrimSuffix(path, ".sha1")]
+			if !ok {
+				http.NotFound(w, req)
+				return
+			}
+
+			sum := sha1.Sum([]byte(data))
+			w.Write([]byte(hex.EncodeToString(sum[:])))
+		}
+	case "DELETE":
+		delete(r.files, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func testClient(t *testing.T, r *testRepository) (*repositoryClient, string, func()) {
+	ts := httptest.NewServer(r)
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.WriteString("disk")
+	tf.Close()
+
+	client := &repositoryClient{
+		baseURL:  ts.URL + "/images",
+		username: "user",
+		password: "pass",
+		retries:  3,
+		client:   http.DefaultClient,
+	}
+
+	return client, tf.Name(), func() {
+		ts.Close()
+		os.Remove(tf.Name())
+	}
+}
+
+func TestRepositoryClient_artifactory(t *testing.T) {
+	r := &testRepository{files: make(map[string]string), failures: 2}
+	client, file, closeFn := testClient(t, r)
+	defer closeFn()
+	client.properties = map[string]string{"os": "ubuntu", "version": "1.0"}
+
+	if err := client.Upload(file, "ubuntu 1.0/disk.qcow2", testChecksums(t, file)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(r.requests) != 3 {
+		t.Fatalf("should retry: %d", len(r.requests))
+	}
+
+	req := r.requests[2]
+	if p := req.URL.EscapedPath(); p != "/images/ubuntu%201.0/disk.qcow2;os=ubuntu;version=1.0" {
+		t.Fatalf("bad: %s", p)
+	}
+	sum := sha1.Sum([]byte("disk"))
+	if v := req.Header.Get("X-Checksum-Sha1"); v != hex.EncodeToString(sum[:]) {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestRepositoryClient_nexus(t *testing.T) {
+	r := &testRepository{files: make(map[string]string)}
+	client, file, closeFn := testClient(t, r)
+	defer closeFn()
+	client.nexus = true
+
+	if err := client.Upload(file, "disk.qcow2", testChecksums(t, file)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if r.files["/images/disk.qcow2"] != "disk" {
+		t.Fatalf("bad: %#v", r.files)
+	}
+
+	if err := client.Delete("disk.qcow2"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(r.files) != 0 {
+		t.Fatalf("bad: %#v", r.files)
+	}
+}
+
+func TestRepositoryClient_noRetry(t *testing.T) {
+	r := &testRepository{files: make(map[string]string)}
+	client, file, closeFn := testClient(t, r)
+	defer closeFn()
+	client.password = "wrong"
+
+	if err := client.Upload(file, "disk.qcow2", testChecksums(t, file)); err == nil {
+		t.Fatal("should have error")
+	}
+	if len(r.requests) != 1 {
+		t.Fatalf("should not retry: %d", len(r.requests))
+	}
+}
+
+func testChecksums(t *testing.T, file string) *fileChecksums {
+	sums, err := computeChecksums(file)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return sums
+}
+
+func TestFileChecksums_Verify(t *testing.T) {
+	sum := sha256.Sum256([]byte("disk"))
+	sums := &fileChecksums{SHA256: hex.EncodeToString(sum[:])}
+
+	cases := map[string]bool{
+		"":                                       true,
+		"sha256:" + sums.SHA256:                  true,
+		"SHA256:" + strings.ToUpper(sums.SHA256): true,
+		"sha256:0000":                            false,
+		"sha512:0000":                            true,
+	}
+
+	for checksum, ok := range cases {
+		if err := sums.Verify(checksum); (err == nil) != ok {
+			t.Fatalf("%s: bad: %v", checksum, err)
+		}
+	}
+}
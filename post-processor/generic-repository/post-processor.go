@@ -0,0 +1,163 @@
+package genericrepository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/helper/config"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	URL               string            `mapstructure:"url"`
+	Path              string            `mapstructure:"path"`
+	RepositoryType    string            `mapstructure:"repository_type"`
+	Username          string            `mapstructure:"username"`
+	Password          string            `mapstructure:"password"`
+	Token             string            `mapstructure:"token"`
+	Properties        map[string]string `mapstructure:"properties"`
+	Retries           int               `mapstructure:"retries"`
+	KeepInputArtifact bool              `mapstructure:"keep_input_artifact"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate: true,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.RepositoryType == "" {
+		p.config.RepositoryType = "artifactory"
+	}
+
+	if p.config.Retries == 0 {
+		p.config.Retries = 3
+	}
+
+	p.config.URL = strings.TrimRight(p.config.URL, "/")
+	p.config.Path = strings.Trim(p.config.Path, "/")
+
+	var errs *packer.MultiError
+	if p.config.URL == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("url must be set"))
+	} else if u, err := url.Parse(p.config.URL); err != nil {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("url is invalid: %s", err))
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("url must be an http or https URL"))
+	}
+
+	switch p.config.RepositoryType {
+	case "artifactory":
+	case "nexus":
+		if len(p.config.Properties) > 0 {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("properties are only supported by Artifactory"))
+		}
+	default:
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("repository_type must be 'artifactory' or 'nexus'"))
+	}
+
+	if p.config.Token != "" && (p.config.Username != "" || p.config.Password != "") {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("token can't be used together with username and password"))
+	}
+
+	if p.config.Retries < 0 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("retries can't be negative"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	files := artifact.Files()
+	if len(files) == 0 {
+		return nil, false, fmt.Errorf("The artifact of %s has no files to upload", artifact.BuilderId())
+	}
+
+	// Files are uploaded by their name, so they must be unique
+	names := make(map[string]string)
+	for _, f := range files {
+		name := filepath.Base(f)
+		if other, ok := names[name]; ok {
+			return nil, false, fmt.Errorf(
+				"Files %s and %s would be uploaded to the same path", other, f)
+		}
+		names[name] = f
+	}
+
+	// Files with a checksum in the artifact are verified before they are
+	// uploaded, so that a corrupted image isn't published.
+	expected, _ := common.ArtifactChecksums(artifact)
+
+	client := p.client()
+	result := &Artifact{client: client, Checksums: make(map[string]string)}
+	for _, f := range files {
+		dest := filepath.Base(f)
+		if p.config.Path != "" {
+			dest = p.config.Path + "/" + dest
+		}
+
+		ui.Say(fmt.Sprintf("Uploading %s to %s", f, client.URL(dest)))
+		sums, err := computeChecksums(f)
+		if err == nil {
+			err = sums.Verify(expected[f])
+		}
+		if err == nil {
+			err = client.Upload(f, dest, sums)
+		}
+		if err != nil {
+			if len(result.Paths) > 0 {
+				ui.Say("Deleting the files that were uploaded...")
+				if err := result.Destroy(); err != nil {
+					ui.Error(fmt.Sprintf("Error deleting uploaded files: %s", err))
+				}
+			}
+
+			return nil, false, fmt.Errorf("Error uploading %s: %s", f, err)
+		}
+
+		result.Paths = append(result.Paths, dest)
+		result.Checksums[dest] = "sha256:" + sums.SHA256
+	}
+
+	return result, p.config.KeepInputArtifact, nil
+}
+
+func (p *PostProcessor) client() *repositoryClient {
+	return &repositoryClient{
+		baseURL:    p.config.URL,
+		nexus:      p.config.RepositoryType == "nexus",
+		username:   p.config.Username,
+		password:   p.config.Password,
+		token:      p.config.Token,
+		properties: p.config.Properties,
+		retries:    p.config.Retries,
+		retryWait:  5 * time.Second,
+		client:     http.DefaultClient,
+	}
+}
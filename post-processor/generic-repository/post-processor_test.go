@@ -0,0 +1,112 @@
+package genericrepository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"url": "https://artifactory.example.com/artifactory/images/",
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	config := testConfig()
+	config["path"] = "/ubuntu/1.0/"
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if p.config.URL != "https://artifactory.example.com/artifactory/images" {
+		t.Fatalf("bad: %s", p.config.URL)
+	}
+	if p.config.Path != "ubuntu/1.0" {
+		t.Fatalf("bad: %s", p.config.Path)
+	}
+	if p.config.RepositoryType != "artifactory" {
+		t.Fatalf("bad: %s", p.config.RepositoryType)
+	}
+	if p.config.Retries != 3 {
+		t.Fatalf("bad: %d", p.config.Retries)
+	}
+}
+
+func TestPostProcessorConfigure_invalid(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"url": ""},
+		{"url": "ftp://example.com/images"},
+		{"repository_type": "s3"},
+		{"repository_type": "nexus", "properties": map[string]string{"foo": "bar"}},
+		{"token": "foo", "username": "bar"},
+		{"retries": -1},
+	}
+
+	for _, tc := range cases {
+		config := testConfig()
+		for k, v := range tc {
+			config[k] = v
+		}
+
+		var p PostProcessor
+		if err := p.Configure(config); err == nil {
+			t.Fatalf("should have error: %#v", tc)
+		}
+	}
+}
+
+func TestPostProcessorPostProcess_checksums(t *testing.T) {
+	r := &testRepository{files: make(map[string]string)}
+	client, file, closeFn := testClient(t, r)
+	defer closeFn()
+
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"url":      client.baseURL,
+		"username": "user",
+		"password": "pass",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sum := sha256.Sum256([]byte("disk"))
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{file},
+		StateValues: map[string]interface{}{
+			common.ArtifactStateChecksums: map[string]string{file: checksum},
+		},
+	}
+
+	result, _, err := p.PostProcess(packer.TestUi(t), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	checksums, _ := common.ArtifactChecksums(result)
+	expected := map[string]string{result.(*Artifact).Paths[0]: checksum}
+	if !reflect.DeepEqual(checksums, expected) {
+		t.Fatalf("bad: %#v", checksums)
+	}
+
+	// A file that doesn't match its checksum isn't uploaded
+	r.files = make(map[string]string)
+	artifact.StateValues[common.ArtifactStateChecksums] = map[string]string{file: "sha256:0000"}
+	if _, _, err := p.PostProcess(packer.TestUi(t), artifact); err == nil {
+		t.Fatal("should have error")
+	}
+	if len(r.files) != 0 {
+		t.Fatalf("bad: %#v", r.files)
+	}
+}
@@ -0,0 +1,40 @@
+package glance
+
+import (
+	"fmt"
+	"log"
+)
+
+const BuilderId = "packer.post-processor.glance"
+
+// Artifact is an image that was uploaded to Glance.
+type Artifact struct {
+	ImageId string
+
+	client *glanceClient
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return a.ImageId
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("An image was uploaded to Glance: %s", a.ImageId)
+}
+
+func (*Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying image: %s", a.ImageId)
+	return a.client.Delete(a.ImageId)
+}
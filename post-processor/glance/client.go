@@ -0,0 +1,167 @@
+package glance
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// glanceClient is a client for the parts of version 2 of the Glance API
+// that are needed to upload an image.
+type glanceClient struct {
+	// endpoint is the URL of the v2 API, ending in a slash.
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+type glanceImage struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	Checksum   string `json:"checksum"`
+	OSHashAlgo string `json:"os_hash_algo"`
+	OSHash     string `json:"os_hash_value"`
+}
+
+// imageChecksums computes the checksums that Glance may report for an
+// image while it is uploaded.
+type imageChecksums struct {
+	md5    hash.Hash
+	sha512 hash.Hash
+}
+
+func newImageChecksums() *imageChecksums {
+	return &imageChecksums{md5: md5.New(), sha512: sha512.New()}
+}
+
+func (c *imageChecksums) Write(p []byte) (int, error) {
+	c.md5.Write(p)
+	return c.sha512.Write(p)
+}
+
+// Verify compares the checksums with the ones reported by Glance. Older
+// versions of Glance only report an MD5 checksum, newer versions also
+// report a multihash.
+func (c *imageChecksums) Verify(image *glanceImage) error {
+	if image.Checksum != "" {
+		if actual := hex.EncodeToString(c.md5.Sum(nil)); actual != image.Checksum {
+			return fmt.Errorf("MD5 checksum mismatch: uploaded %s, Glance has %s",
+				actual, image.Checksum)
+		}
+	}
+
+	if image.OSHashAlgo == "sha512" && image.OSHash != "" {
+		if actual := hex.EncodeToString(c.sha512.Sum(nil)); actual != image.OSHash {
+			return fmt.Errorf("SHA512 checksum mismatch: uploaded %s, Glance has %s",
+				actual, image.OSHash)
+		}
+	}
+
+	return nil
+}
+
+// CreateImage creates an image record with the given attributes. Any
+// attributes other than the ones known to Glance are stored as image
+// properties.
+func (c *glanceClient) CreateImage(attrs map[string]interface{}) (*glanceImage, error) {
+	body, err := json.Marshal(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	var image glanceImage
+	err = c.do("POST", "images", "application/json", bytes.NewReader(body), &image)
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// Upload uploads the data of the image.
+func (c *glanceClient) Upload(id string, r io.Reader) error {
+	return c.do("PUT", fmt.Sprintf("images/%s/file", id),
+		"application/octet-stream", r, nil)
+}
+
+// Get returns the image with the given ID.
+func (c *glanceClient) Get(id string) (*glanceImage, error) {
+	var image glanceImage
+	if err := c.do("GET", "images/"+id, "", nil, &image); err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// Delete deletes the image with the given ID.
+func (c *glanceClient) Delete(id string) error {
+	return c.do("DELETE", "images/"+id, "", nil, nil)
+}
+
+// WaitForActive waits for the image to become active after its data has
+// been uploaded.
+func (c *glanceClient) WaitForActive(id string, timeout time.Duration) (*glanceImage, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		image, err := c.Get(id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch image.Status {
+		case "active":
+			return image, nil
+		case "killed", "deleted", "deactivated":
+			return nil, fmt.Errorf("image is %s", image.Status)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout waiting for image, status is %s", image.Status)
+		}
+
+		log.Printf("Waiting for image to become active: %s", image.Status)
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func (c *glanceClient) do(method, path, contentType string, body io.Reader, result interface{}) error {
+	req, err := http.NewRequest(method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Auth-Token", c.token)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s: %s",
+			method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
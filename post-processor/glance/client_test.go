@@ -0,0 +1,128 @@
+package glance
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGlance is a fake Glance that stores a single image.
+type testGlance struct {
+	attrs    map[string]interface{}
+	data     string
+	checksum string
+}
+
+func (g *testGlance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Auth-Token") != "token" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	image := glanceImage{ID: "foo", Status: "queued"}
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/v2/images":
+		json.NewDecoder(r.Body).Decode(&g.attrs)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "PUT" && r.URL.Path == "/v2/images/foo/file":
+		data, _ := ioutil.ReadAll(r.Body)
+		g.data = string(data)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case r.Method == "GET" && r.URL.Path == "/v2/images/foo":
+		if g.data != "" {
+			image.Status = "active"
+			image.Checksum = g.checksum
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(&image)
+}
+
+func testClient(t *testing.T, g *testGlance) (*glanceClient, func()) {
+	ts := httptest.NewServer(g)
+	return &glanceClient{
+		endpoint: ts.URL + "/v2/",
+		token:    "token",
+		client:   http.DefaultClient,
+	}, ts.Close
+}
+
+func TestGlanceClient(t *testing.T) {
+	sum := md5.Sum([]byte("disk"))
+	g := &testGlance{checksum: hex.EncodeToString(sum[:])}
+	client, closeFn := testClient(t, g)
+	defer closeFn()
+
+	image, err := client.CreateImage(map[string]interface{}{
+		"name":        "packer",
+		"hw_disk_bus": "scsi",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if image.ID != "foo" {
+		t.Fatalf("bad: %#v", image)
+	}
+	if g.attrs["hw_disk_bus"] != "scsi" {
+		t.Fatalf("bad: %#v", g.attrs)
+	}
+
+	checksums := newImageChecksums()
+	r := io.TeeReader(strings.NewReader("disk"), checksums)
+	if err := client.Upload(image.ID, r); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if g.data != "disk" {
+		t.Fatalf("bad: %s", g.data)
+	}
+
+	image, err = client.WaitForActive(image.ID, time.Minute)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := checksums.Verify(image); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestGlanceClient_error(t *testing.T) {
+	client, closeFn := testClient(t, &testGlance{})
+	defer closeFn()
+	client.token = "bad"
+
+	_, err := client.CreateImage(map[string]interface{}{"name": "packer"})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestImageChecksumsVerify(t *testing.T) {
+	checksums := newImageChecksums()
+	checksums.Write([]byte("disk"))
+
+	image := &glanceImage{Checksum: "bad"}
+	if err := checksums.Verify(image); err == nil {
+		t.Fatal("should have error")
+	}
+
+	image = &glanceImage{OSHashAlgo: "sha512", OSHash: "bad"}
+	if err := checksums.Verify(image); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Without checksums there is nothing to verify
+	if err := checksums.Verify(&glanceImage{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,208 @@
+package glance
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mitchellh/packer/builder/openstack"
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/helper/config"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+var diskFormats = []string{
+	"aki", "ami", "ari", "iso", "ploop", "qcow2", "raw", "vdi", "vhd", "vhdx", "vmdk",
+}
+
+var visibilities = []string{"community", "private", "public", "shared"}
+
+// reservedAttributes are the attributes of an image that are set by
+// Packer or Glance, and so can't be used as properties.
+var reservedAttributes = []string{
+	"checksum", "container_format", "created_at", "disk_format", "file",
+	"id", "locations", "min_disk", "min_ram", "name", "os_hash_algo",
+	"os_hash_value", "owner", "protected", "schema", "self", "size",
+	"status", "tags", "updated_at", "virtual_size", "visibility",
+}
+
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	openstack.AccessConfig `mapstructure:",squash"`
+	openstack.ImageConfig  `mapstructure:",squash"`
+
+	DiskFormat        string            `mapstructure:"disk_format"`
+	ContainerFormat   string            `mapstructure:"container_format"`
+	Visibility        string            `mapstructure:"image_visibility"`
+	Properties        map[string]string `mapstructure:"image_properties"`
+	Tags              []string          `mapstructure:"image_tags"`
+	MinDisk           int               `mapstructure:"image_min_disk"`
+	MinRAM            int               `mapstructure:"image_min_ram"`
+	KeepInputArtifact bool              `mapstructure:"keep_input_artifact"`
+
+	// The amount of time to wait for the image to become active after
+	// it has been uploaded.
+	Timeout time.Duration `mapstructure:"image_timeout"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate: true,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.ContainerFormat == "" {
+		p.config.ContainerFormat = "bare"
+	}
+
+	if p.config.Timeout == 0 {
+		p.config.Timeout = 30 * time.Minute
+	}
+
+	var errs *packer.MultiError
+	errs = packer.MultiErrorAppend(errs, p.config.ImageConfig.Prepare(&p.config.ctx)...)
+
+	if p.config.DiskFormat != "" && !contains(diskFormats, p.config.DiskFormat) {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("Unknown disk_format: %s", p.config.DiskFormat))
+	}
+
+	if p.config.Visibility != "" && !contains(visibilities, p.config.Visibility) {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("Unknown image_visibility: %s", p.config.Visibility))
+	}
+
+	for key := range p.config.Properties {
+		if contains(reservedAttributes, key) {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("image_properties can't set the reserved attribute: %s", key))
+		}
+	}
+
+	// The access config authenticates, so only do it if everything else
+	// is valid.
+	if errs == nil || len(errs.Errors) == 0 {
+		errs = packer.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	source, format, err := common.FindDiskImage(common.ArtifactImageFiles(artifact))
+	if err != nil {
+		return nil, false, err
+	}
+	if p.config.DiskFormat != "" {
+		format = p.config.DiskFormat
+	}
+
+	sc, err := p.config.ImageV2Client()
+	if err != nil {
+		return nil, false, fmt.Errorf("Error initializing image client: %s", err)
+	}
+	client := &glanceClient{
+		endpoint: sc.Endpoint,
+		token:    sc.TokenID,
+		client:   &sc.HTTPClient,
+	}
+
+	ui.Say(fmt.Sprintf("Creating image: %s", p.config.ImageName))
+	image, err := client.CreateImage(p.imageAttributes(format))
+	if err != nil {
+		return nil, false, fmt.Errorf("Error creating image: %s", err)
+	}
+	ui.Message(fmt.Sprintf("Image: %s", image.ID))
+
+	if err := p.upload(ui, client, image.ID, source, format); err != nil {
+		if err := client.Delete(image.ID); err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error deleting image %s, delete it manually: %s", image.ID, err))
+		}
+
+		return nil, false, err
+	}
+
+	return &Artifact{ImageId: image.ID, client: client}, p.config.KeepInputArtifact, nil
+}
+
+// upload uploads the disk image and waits for Glance to verify it.
+func (p *PostProcessor) upload(ui packer.Ui, client *glanceClient, id, source, format string) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ui.Say(fmt.Sprintf("Uploading %s image: %s", format, source))
+	checksums := newImageChecksums()
+	if err := client.Upload(id, io.TeeReader(f, checksums)); err != nil {
+		return fmt.Errorf("Error uploading image: %s", err)
+	}
+
+	ui.Say("Waiting for image to become active...")
+	image, err := client.WaitForActive(id, p.config.Timeout)
+	if err != nil {
+		return fmt.Errorf("Error waiting for image: %s", err)
+	}
+
+	if err := checksums.Verify(image); err != nil {
+		return fmt.Errorf("Error verifying image: %s", err)
+	}
+
+	return nil
+}
+
+// imageAttributes returns the attributes to create the image with. In
+// Glance v2, properties are set alongside the core attributes.
+func (p *PostProcessor) imageAttributes(format string) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	for k, v := range p.config.Properties {
+		attrs[k] = v
+	}
+
+	attrs["name"] = p.config.ImageName
+	attrs["disk_format"] = format
+	attrs["container_format"] = p.config.ContainerFormat
+	if p.config.Visibility != "" {
+		attrs["visibility"] = p.config.Visibility
+	}
+	if len(p.config.Tags) > 0 {
+		attrs["tags"] = p.config.Tags
+	}
+	if p.config.MinDisk > 0 {
+		attrs["min_disk"] = p.config.MinDisk
+	}
+	if p.config.MinRAM > 0 {
+		attrs["min_ram"] = p.config.MinRAM
+	}
+
+	return attrs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
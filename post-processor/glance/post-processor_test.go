@@ -0,0 +1,65 @@
+package glance
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"image_name": "packer",
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure_invalid(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"image_name": ""},
+		{"disk_format": "floppy"},
+		{"image_visibility": "everyone"},
+		{"image_properties": map[string]string{"name": "foo"}},
+		{"image_timeout": "bad"},
+	}
+
+	for _, tc := range cases {
+		config := testConfig()
+		for k, v := range tc {
+			config[k] = v
+		}
+
+		var p PostProcessor
+		if err := p.Configure(config); err == nil {
+			t.Fatalf("should have error: %#v", tc)
+		}
+	}
+}
+
+func TestPostProcessorImageAttributes(t *testing.T) {
+	var p PostProcessor
+	p.config.ImageName = "packer"
+	p.config.ContainerFormat = "bare"
+	p.config.Visibility = "private"
+	p.config.MinDisk = 10
+	p.config.Properties = map[string]string{
+		"hw_disk_bus": "scsi",
+		"os_type":     "linux",
+	}
+
+	expected := map[string]interface{}{
+		"name":             "packer",
+		"disk_format":      "qcow2",
+		"container_format": "bare",
+		"visibility":       "private",
+		"min_disk":         10,
+		"hw_disk_bus":      "scsi",
+		"os_type":          "linux",
+	}
+	if actual := p.imageAttributes("qcow2"); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -0,0 +1,58 @@
+package libvirt
+
+import (
+	"fmt"
+	"os"
+)
+
+const BuilderId = "packer.post-processor.libvirt"
+
+// Artifact is a volume that was uploaded to a libvirt storage pool and,
+// optionally, the domain XML that was generated for it.
+type Artifact struct {
+	Pool       string
+	Volume     string
+	VolumePath string
+	DomainXML  string
+
+	virsh *virsh
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	if a.DomainXML == "" {
+		return nil
+	}
+
+	return []string{a.DomainXML}
+}
+
+func (a *Artifact) Id() string {
+	return fmt.Sprintf("%s/%s", a.Pool, a.Volume)
+}
+
+func (a *Artifact) String() string {
+	if a.DomainXML != "" {
+		return fmt.Sprintf("libvirt volume %s (domain XML: %s)", a.VolumePath, a.DomainXML)
+	}
+
+	return fmt.Sprintf("libvirt volume %s", a.VolumePath)
+}
+
+func (*Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	if a.DomainXML != "" {
+		if err := os.Remove(a.DomainXML); err != nil {
+			return err
+		}
+	}
+
+	_, err := a.virsh.Run("vol-delete", "--pool", a.Pool, a.Volume)
+	return err
+}
@@ -0,0 +1,258 @@
+package libvirt
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/helper/config"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The libvirt connection to use, and the path to virsh.
+	URI       string `mapstructure:"libvirt_uri"`
+	VirshPath string `mapstructure:"virsh_path"`
+
+	// The storage pool and the name of the volume to upload the disk
+	// image as.
+	Pool       string `mapstructure:"pool"`
+	VolumeName string `mapstructure:"volume_name"`
+
+	// If DomainXML is set, a domain XML file is written there that boots
+	// from the uploaded volume. It is rendered from DomainTemplate, or a
+	// built in template if that isn't set.
+	DomainXML      string `mapstructure:"domain_xml"`
+	DomainTemplate string `mapstructure:"domain_template"`
+	DomainName     string `mapstructure:"domain_name"`
+	Memory         int    `mapstructure:"memory"`
+	CPUs           int    `mapstructure:"cpus"`
+	DiskBus        string `mapstructure:"disk_bus"`
+	Network        string `mapstructure:"network"`
+
+	KeepInputArtifact bool `mapstructure:"keep_input_artifact"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+// domainTemplate is the data available to the domain template.
+type domainTemplate struct {
+	Name       string
+	Memory     int
+	CPUs       int
+	Pool       string
+	Volume     string
+	VolumePath string
+	Format     string
+	DiskBus    string
+	Network    string
+}
+
+const defaultDomainTemplate = `<domain type='kvm'>
+  <name>{{.Name}}</name>
+  <memory unit='MiB'>{{.Memory}}</memory>
+  <vcpu>{{.CPUs}}</vcpu>
+  <os>
+    <type>hvm</type>
+    <boot dev='hd'/>
+  </os>
+  <features>
+    <acpi/>
+    <apic/>
+  </features>
+  <devices>
+    <disk type='volume' device='disk'>
+      <driver name='qemu' type='{{.Format}}'/>
+      <source pool='{{.Pool}}' volume='{{.Volume}}'/>
+      <target dev='{{if eq .DiskBus "virtio"}}vda{{else}}sda{{end}}' bus='{{.DiskBus}}'/>
+    </disk>
+    <interface type='network'>
+      <source network='{{.Network}}'/>
+      <model type='virtio'/>
+    </interface>
+    <serial type='pty'/>
+    <console type='pty'/>
+    <graphics type='vnc' autoport='yes'/>
+  </devices>
+</domain>
+`
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate: true,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.URI == "" {
+		p.config.URI = "qemu:///system"
+	}
+	if p.config.VirshPath == "" {
+		p.config.VirshPath = "virsh"
+	}
+	if p.config.Pool == "" {
+		p.config.Pool = "default"
+	}
+	if p.config.Memory == 0 {
+		p.config.Memory = 512
+	}
+	if p.config.CPUs == 0 {
+		p.config.CPUs = 1
+	}
+	if p.config.DiskBus == "" {
+		p.config.DiskBus = "virtio"
+	}
+	if p.config.Network == "" {
+		p.config.Network = "default"
+	}
+
+	var errs *packer.MultiError
+	switch p.config.DiskBus {
+	case "ide", "sata", "scsi", "virtio":
+	default:
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("Unrecognized disk_bus: %s", p.config.DiskBus))
+	}
+
+	if p.config.DomainTemplate != "" {
+		if p.config.DomainXML == "" {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("domain_xml must be set to use domain_template"))
+		}
+		if _, err := os.Stat(p.config.DomainTemplate); err != nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("domain_template is invalid: %s", err))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	source, format, err := common.FindDiskImage(common.ArtifactImageFiles(artifact))
+	if err != nil {
+		return nil, false, err
+	}
+
+	fi, err := os.Stat(source)
+	if err != nil {
+		return nil, false, err
+	}
+
+	name := p.config.VolumeName
+	if name == "" {
+		name = filepath.Base(source)
+	}
+
+	v := &virsh{path: p.config.VirshPath, uri: p.config.URI}
+	result := &Artifact{Pool: p.config.Pool, Volume: name, virsh: v}
+
+	ui.Say(fmt.Sprintf("Creating volume %s in pool %s", name, p.config.Pool))
+	_, err = v.Run("vol-create-as", p.config.Pool, name,
+		strconv.FormatInt(fi.Size(), 10), "--format", format)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error creating volume: %s", err)
+	}
+
+	if err := p.upload(ui, result, source, format); err != nil {
+		if _, err := v.Run("vol-delete", "--pool", p.config.Pool, name); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting volume, delete it manually: %s", err))
+		}
+
+		return nil, false, err
+	}
+
+	return result, p.config.KeepInputArtifact, nil
+}
+
+// upload uploads the disk image into the volume and writes the domain
+// XML, if it is wanted.
+func (p *PostProcessor) upload(ui packer.Ui, a *Artifact, source, format string) error {
+	ui.Say(fmt.Sprintf("Uploading %s image: %s", format, source))
+	if _, err := a.virsh.Run("vol-upload", "--pool", a.Pool, a.Volume, source); err != nil {
+		return fmt.Errorf("Error uploading volume: %s", err)
+	}
+
+	// Refreshing the pool makes libvirt read the capacity of the image
+	if _, err := a.virsh.Run("pool-refresh", a.Pool); err != nil {
+		return fmt.Errorf("Error refreshing pool: %s", err)
+	}
+
+	path, err := a.virsh.Run("vol-path", "--pool", a.Pool, a.Volume)
+	if err != nil {
+		return fmt.Errorf("Error finding volume path: %s", err)
+	}
+	a.VolumePath = path
+
+	if p.config.DomainXML == "" {
+		return nil
+	}
+
+	ui.Say(fmt.Sprintf("Writing domain XML: %s", p.config.DomainXML))
+	name := p.config.DomainName
+	if name == "" {
+		name = strings.TrimSuffix(a.Volume, filepath.Ext(a.Volume))
+	}
+
+	err = p.writeDomainXML(&domainTemplate{
+		Name:       name,
+		Memory:     p.config.Memory,
+		CPUs:       p.config.CPUs,
+		Pool:       a.Pool,
+		Volume:     a.Volume,
+		VolumePath: a.VolumePath,
+		Format:     format,
+		DiskBus:    p.config.DiskBus,
+		Network:    p.config.Network,
+	})
+	if err != nil {
+		return fmt.Errorf("Error writing domain XML: %s", err)
+	}
+	a.DomainXML = p.config.DomainXML
+
+	return nil
+}
+
+func (p *PostProcessor) writeDomainXML(data *domainTemplate) error {
+	contents := defaultDomainTemplate
+	if p.config.DomainTemplate != "" {
+		raw, err := ioutil.ReadFile(p.config.DomainTemplate)
+		if err != nil {
+			return err
+		}
+		contents = string(raw)
+	}
+
+	t, err := template.New("domain").Parse(contents)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(p.config.DomainXML, buf.Bytes(), 0644)
+}
@@ -0,0 +1,165 @@
+package libvirt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+// testVirsh writes a fake virsh to dir that logs its arguments and
+// prints a path for vol-path.
+const testVirsh = `#!/bin/sh
+echo "$@" >> "$(dirname "$0")/virsh.log"
+case "$3" in
+vol-path) echo /var/lib/libvirt/images/disk.qcow2 ;;
+vol-upload) [ -z "$VIRSH_FAIL" ] || exit 1 ;;
+esac
+`
+
+func testConfig(dir string) map[string]interface{} {
+	return map[string]interface{}{
+		"virsh_path": filepath.Join(dir, "virsh"),
+	}
+}
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
+
+func testDir(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(td, "virsh"), []byte(testVirsh), 0755)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	disk := append([]byte{'Q', 'F', 'I', 0xfb}, 0, 0, 0, 3)
+	if err := ioutil.WriteFile(filepath.Join(td, "disk.qcow2"), disk, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return td
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.URI != "qemu:///system" || p.config.Pool != "default" {
+		t.Fatalf("bad: %#v", p.config)
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"disk_bus": "floppy"}); err == nil {
+		t.Fatal("should have error")
+	}
+
+	p = PostProcessor{}
+	err := p.Configure(map[string]interface{}{"domain_template": "i-should-not-exist"})
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestPostProcessorPostProcess(t *testing.T) {
+	td := testDir(t)
+	defer os.RemoveAll(td)
+
+	config := testConfig(td)
+	config["domain_xml"] = filepath.Join(td, "domain.xml")
+	var p PostProcessor
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{filepath.Join(td, "disk.qcow2")},
+	}
+	result, _, err := p.PostProcess(testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result.Id() != "default/disk.qcow2" {
+		t.Fatalf("bad: %s", result.Id())
+	}
+
+	log, err := ioutil.ReadFile(filepath.Join(td, "virsh.log"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := strings.Join([]string{
+		"--connect qemu:///system vol-create-as default disk.qcow2 8 --format qcow2",
+		"--connect qemu:///system vol-upload --pool default disk.qcow2 " + filepath.Join(td, "disk.qcow2"),
+		"--connect qemu:///system pool-refresh default",
+		"--connect qemu:///system vol-path --pool default disk.qcow2",
+	}, "\n") + "\n"
+	if string(log) != expected {
+		t.Fatalf("bad: %s", log)
+	}
+
+	domain, err := ioutil.ReadFile(filepath.Join(td, "domain.xml"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, s := range []string{
+		"<name>disk</name>",
+		"<source pool='default' volume='disk.qcow2'/>",
+		"<driver name='qemu' type='qcow2'/>",
+		"<target dev='vda' bus='virtio'/>",
+	} {
+		if !strings.Contains(string(domain), s) {
+			t.Fatalf("bad: %s", domain)
+		}
+	}
+}
+
+func TestPostProcessorPostProcess_uploadError(t *testing.T) {
+	td := testDir(t)
+	defer os.RemoveAll(td)
+
+	os.Setenv("VIRSH_FAIL", "1")
+	defer os.Setenv("VIRSH_FAIL", "")
+
+	var p PostProcessor
+	if err := p.Configure(testConfig(td)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{filepath.Join(td, "disk.qcow2")},
+	}
+	if _, _, err := p.PostProcess(testUi(), artifact); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// The volume is deleted again
+	log, err := ioutil.ReadFile(filepath.Join(td, "virsh.log"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(string(log), "vol-delete --pool default disk.qcow2") {
+		t.Fatalf("bad: %s", log)
+	}
+}
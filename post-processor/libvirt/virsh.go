@@ -0,0 +1,35 @@
+package libvirt
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// virsh runs virsh against a single libvirt connection.
+type virsh struct {
+	path string
+	uri  string
+}
+
+// Run runs virsh with the given arguments and returns its output.
+func (v *virsh) Run(args ...string) (string, error) {
+	command := args[0]
+	if v.uri != "" {
+		args = append([]string{"--connect", v.uri}, args...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	log.Printf("Executing virsh: %#v", args)
+	cmd := exec.Command(v.path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("virsh %s failed: %s\nStderr: %s",
+			command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -35,6 +35,7 @@ type Config struct {
 }
 
 type boxDownloadUrlTemplate struct {
+	Artifact   *common.ArtifactData
 	ArtifactId string
 	Provider   string
 }
@@ -107,6 +108,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	providerName := providerFromBuilderName(artifact.Id())
 
 	p.config.ctx.Data = &boxDownloadUrlTemplate{
+		Artifact:   common.NewArtifactData(artifact),
 		ArtifactId: artifact.Id(),
 		Provider:   providerName,
 	}
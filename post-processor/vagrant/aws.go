@@ -6,6 +6,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -20,18 +21,17 @@ func (p *AWSProvider) Process(ui packer.Ui, artifact packer.Artifact, dir string
 	metadata = map[string]interface{}{"provider": "aws"}
 
 	// Build up the template data to build our Vagrantfile
-	tplData := &awsVagrantfileTemplate{
-		Images: make(map[string]string),
-	}
+	tplData := new(awsVagrantfileTemplate)
 
-	for _, regions := range strings.Split(artifact.Id(), ",") {
-		parts := strings.Split(regions, ":")
-		if len(parts) != 2 {
-			err = fmt.Errorf("Poorly formatted artifact ID: %s", artifact.Id())
+	// Prefer the regions the artifact reports, falling back to parsing
+	// the "region:ami,..." ID for artifacts that don't report them.
+	if regions, ok := common.ArtifactRegions(artifact); ok {
+		tplData.Images = regions
+	} else {
+		tplData.Images, err = parseAWSArtifactId(artifact.Id())
+		if err != nil {
 			return
 		}
-
-		tplData.Images[parts[0]] = parts[1]
 	}
 
 	// Build up the contents
@@ -42,6 +42,22 @@ func (p *AWSProvider) Process(ui packer.Ui, artifact packer.Artifact, dir string
 	return
 }
 
+// parseAWSArtifactId parses an artifact ID of the form "region:ami,..."
+// into a map of regions to AMI IDs.
+func parseAWSArtifactId(id string) (map[string]string, error) {
+	images := make(map[string]string)
+	for _, regions := range strings.Split(id, ",") {
+		parts := strings.Split(regions, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Poorly formatted artifact ID: %s", id)
+		}
+
+		images[parts[0]] = parts[1]
+	}
+
+	return images, nil
+}
+
 type awsVagrantfileTemplate struct {
 	Images map[string]string
 }
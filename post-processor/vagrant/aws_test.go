@@ -1,7 +1,11 @@
 package vagrant
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/packer"
 )
 
 func TestAWSProvider_impl(t *testing.T) {
@@ -15,3 +19,37 @@ func TestAWSProvider_KeepInputArtifact(t *testing.T) {
 		t.Fatal("should keep input artifact")
 	}
 }
+
+func TestAWSProvider_Process(t *testing.T) {
+	p := new(AWSProvider)
+
+	// The regions that the artifact reports are used
+	a := &packer.MockArtifact{
+		IdValue: "bad",
+		StateValues: map[string]interface{}{
+			common.ArtifactStateRegions: map[string]string{"us-east-1": "ami-1234"},
+		},
+	}
+	vagrantfile, _, err := p.Process(nil, a, "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(vagrantfile, `"us-east-1", ami: "ami-1234"`) {
+		t.Fatalf("bad: %s", vagrantfile)
+	}
+
+	// Otherwise the ID is parsed
+	a = &packer.MockArtifact{IdValue: "us-west-1:ami-5678"}
+	vagrantfile, _, err = p.Process(nil, a, "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(vagrantfile, `"us-west-1", ami: "ami-5678"`) {
+		t.Fatalf("bad: %s", vagrantfile)
+	}
+
+	a = &packer.MockArtifact{IdValue: "bad"}
+	if _, _, err := p.Process(nil, a, ""); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -3,6 +3,7 @@ package vagrant
 import (
 	"bytes"
 	"fmt"
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 	"strings"
 	"text/template"
@@ -26,13 +27,22 @@ func (p *DigitalOceanProvider) Process(ui packer.Ui, artifact packer.Artifact, d
 	// Determine the image and region...
 	tplData := &digitalOceanVagrantfileTemplate{}
 
-	parts := strings.Split(artifact.Id(), ":")
-	if len(parts) != 2 {
-		err = fmt.Errorf("Poorly formatted artifact ID: %s", artifact.Id())
-		return
+	// Prefer the region the artifact reports, falling back to parsing a
+	// "region:image" ID for artifacts that don't report it.
+	if regions, ok := common.ArtifactRegions(artifact); ok && len(regions) == 1 {
+		for region, image := range regions {
+			tplData.Region = region
+			tplData.Image = image
+		}
+	} else {
+		parts := strings.Split(artifact.Id(), ":")
+		if len(parts) != 2 {
+			err = fmt.Errorf("Poorly formatted artifact ID: %s", artifact.Id())
+			return
+		}
+		tplData.Region = parts[0]
+		tplData.Image = parts[1]
 	}
-	tplData.Region = parts[0]
-	tplData.Image = parts[1]
 
 	// Build up the Vagrantfile
 	var contents bytes.Buffer
@@ -2,6 +2,7 @@ package vagrant
 
 import (
 	"fmt"
+	"github.com/mitchellh/packer/common"
 	"github.com/mitchellh/packer/packer"
 	"path/filepath"
 	"strings"
@@ -16,7 +17,7 @@ func (p *LibVirtProvider) Process(ui packer.Ui, artifact packer.Artifact, dir st
 	diskName := artifact.State("diskName").(string)
 
 	// Copy the disk image into the temporary directory (as box.img)
-	for _, path := range artifact.Files() {
+	for _, path := range common.ArtifactImageFiles(artifact) {
 		if strings.HasSuffix(path, "/"+diskName) {
 			ui.Message(fmt.Sprintf("Copying from artifact: %s", path))
 			dstPath := filepath.Join(dir, "box.img")
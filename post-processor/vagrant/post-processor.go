@@ -78,6 +78,7 @@ func (p *PostProcessor) PostProcessProvider(name string, provider Provider, ui p
 
 	outputPath, err := interpolate.Render(config.OutputPath, &interpolate.Context{
 		Data: &outputPathTemplate{
+			Artifact:   common.NewArtifactData(artifact),
 			ArtifactId: artifact.Id(),
 			BuildName:  config.PackerBuildName,
 			Provider:   name,
@@ -88,7 +89,7 @@ func (p *PostProcessor) PostProcessProvider(name string, provider Provider, ui p
 	}
 
 	// Create a temporary directory for us to build the contents of the box in
-	dir, err := ioutil.TempDir("", "packer")
+	dir, err := ioutil.TempDir(config.PackerWorkDir, "packer")
 	if err != nil {
 		return nil, false, err
 	}
@@ -239,6 +240,7 @@ func providerForName(name string) Provider {
 // OutputPathTemplate is the structure that is availalable within the
 // OutputPath variables.
 type outputPathTemplate struct {
+	Artifact   *common.ArtifactData
 	ArtifactId string
 	BuildName  string
 	Provider   string
@@ -77,6 +77,9 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 
 	// Validation
 	var errs *packer.MultiError
+	if err := p.config.RequireUnixGuest("ansible-local provisioner"); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
 	err = validateFileConfig(p.config.PlaybookFile, "playbook_file", true)
 	if err != nil {
 		errs = packer.MultiErrorAppend(errs, err)
@@ -152,7 +155,7 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	}
 
 	if len(p.config.InventoryFile) == 0 {
-		tf, err := ioutil.TempFile("", "packer-provisioner-ansible-local")
+		tf, err := ioutil.TempFile(p.config.PackerWorkDir, "packer-provisioner-ansible-local")
 		if err != nil {
 			return fmt.Errorf("Error preparing inventory file: %s", err)
 		}
@@ -224,6 +227,10 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	return nil
 }
 
+func (p *Provisioner) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (p *Provisioner) Cancel() {
 	// Just hard quit. It isn't a big deal if what we're doing keeps
 	// running on the other side.
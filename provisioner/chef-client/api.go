@@ -0,0 +1,189 @@
+package chefclient
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// chefVersion is the Chef version reported to the server in requests.
+const chefVersion = "11.12.0"
+
+// apiClient is a minimal client for the Chef server API. It only
+// supports what is needed to clean up after a build, so that knife
+// doesn't need to be installed on the machine running Packer when a
+// client key is configured.
+type apiClient struct {
+	url    *url.URL
+	name   string
+	key    *rsa.PrivateKey
+	client *http.Client
+
+	// now returns the current time, and is replaceable for tests.
+	now func() time.Time
+}
+
+// newAPIClient returns a client for the Chef server at serverUrl that
+// authenticates as the client name using the given PEM encoded key.
+func newAPIClient(serverUrl, name string, keyPem []byte, insecure bool) (*apiClient, error) {
+	u, err := url.Parse(serverUrl)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing server_url: %s", err)
+	}
+
+	key, err := parsePrivateKey(keyPem)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &apiClient{
+		url:    u,
+		name:   name,
+		key:    key,
+		client: &http.Client{Transport: transport},
+		now:    time.Now,
+	}, nil
+}
+
+// Delete deletes the object at the path made of the given segments,
+// such as "nodes" and "foo", relative to the server URL. Objects that
+// don't exist are ignored.
+func (c *apiClient) Delete(segments ...string) error {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = url.PathEscape(s)
+	}
+
+	u := *c.url
+	u.Path = path.Join(append([]string{"/", c.url.Path}, segments...)...)
+	u.RawPath = path.Join(append([]string{"/", c.url.EscapedPath()}, escaped...)...)
+
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if err := c.sign(req, nil); err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Chef server returned %s: %s",
+			resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// sign adds the headers for version 1.0 of the Chef authentication
+// protocol to the request.
+func (c *apiClient) sign(req *http.Request, body []byte) error {
+	timestamp := c.now().UTC().Format("2006-01-02T15:04:05Z")
+	contentHash := hashBase64(body)
+
+	canonical := fmt.Sprintf(
+		"Method:%s\nHashed Path:%s\nX-Ops-Content-Hash:%s\n"+
+			"X-Ops-Timestamp:%s\nX-Ops-UserId:%s",
+		req.Method,
+		hashBase64([]byte(canonicalPath(req.URL.EscapedPath()))),
+		contentHash,
+		timestamp,
+		c.name)
+
+	// Chef signs the canonical request itself rather than a digest of it
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.Hash(0), []byte(canonical))
+	if err != nil {
+		return fmt.Errorf("Error signing request: %s", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Chef-Version", chefVersion)
+	req.Header.Set("X-Ops-Sign", "algorithm=sha1;version=1.0")
+	req.Header.Set("X-Ops-Userid", c.name)
+	req.Header.Set("X-Ops-Timestamp", timestamp)
+	req.Header.Set("X-Ops-Content-Hash", contentHash)
+
+	// The signature is split into numbered headers of 60 characters
+	encoded := base64.StdEncoding.EncodeToString(sig)
+	for i := 0; len(encoded) > 0; i++ {
+		n := 60
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+
+		req.Header.Set(fmt.Sprintf("X-Ops-Authorization-%d", i+1), encoded[:n])
+		encoded = encoded[n:]
+	}
+
+	return nil
+}
+
+// canonicalPath returns the path as the Chef server sees it when
+// verifying signatures: repeated slashes are collapsed and any trailing
+// slash is removed.
+func canonicalPath(p string) string {
+	for strings.Contains(p, "//") {
+		p = strings.Replace(p, "//", "/", -1)
+	}
+	if len(p) > 1 {
+		p = strings.TrimSuffix(p, "/")
+	}
+
+	return p
+}
+
+func hashBase64(data []byte) string {
+	sum := sha1.Sum(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func parsePrivateKey(keyPem []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(keyPem))
+	if block == nil {
+		return nil, errors.New("Error parsing key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	raw, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing key: %s", err)
+	}
+
+	key, ok := raw.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("Error parsing key: not an RSA key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,197 @@
+package chefclient
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	keyPem := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	return key, keyPem
+}
+
+func TestAPIClientDelete(t *testing.T) {
+	key, keyPem := testKey(t)
+
+	var req *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+	}))
+	defer ts.Close()
+
+	c, err := newAPIClient(ts.URL+"/organizations/foo", "packer", keyPem, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c.now = func() time.Time {
+		return time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	if err := c.Delete("nodes", "bar"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if req.Method != "DELETE" {
+		t.Fatalf("bad: %s", req.Method)
+	}
+	if req.URL.Path != "/organizations/foo/nodes/bar" {
+		t.Fatalf("bad: %s", req.URL.Path)
+	}
+
+	expected := map[string]string{
+		"X-Ops-Sign":         "algorithm=sha1;version=1.0",
+		"X-Ops-Userid":       "packer",
+		"X-Ops-Timestamp":    "2015-06-01T12:00:00Z",
+		"X-Ops-Content-Hash": "2jmj7l5rSw0yVb/vlWAYkK/YBwk=",
+	}
+	for k, v := range expected {
+		if actual := req.Header.Get(k); actual != v {
+			t.Fatalf("bad %s: %s", k, actual)
+		}
+	}
+
+	// Put the signature back together and verify it
+	var sig string
+	for i := 1; ; i++ {
+		part := req.Header.Get(fmt.Sprintf("X-Ops-Authorization-%d", i))
+		if part == "" {
+			break
+		}
+		if len(part) > 60 {
+			t.Fatalf("bad: %s", part)
+		}
+
+		sig += part
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	canonical := "Method:DELETE\n" +
+		"Hashed Path:" + hashBase64([]byte("/organizations/foo/nodes/bar")) + "\n" +
+		"X-Ops-Content-Hash:2jmj7l5rSw0yVb/vlWAYkK/YBwk=\n" +
+		"X-Ops-Timestamp:2015-06-01T12:00:00Z\n" +
+		"X-Ops-UserId:packer"
+	err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.Hash(0), []byte(canonical), raw)
+	if err != nil {
+		t.Fatalf("bad signature: %s", err)
+	}
+}
+
+func TestAPIClientDelete_status(t *testing.T) {
+	_, keyPem := testKey(t)
+
+	status := http.StatusNotFound
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte("nope"))
+	}))
+	defer ts.Close()
+
+	c, err := newAPIClient(ts.URL, "packer", keyPem, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Objects that are already gone are fine
+	if err := c.Delete("nodes", "bar"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	status = http.StatusForbidden
+	err = c.Delete("nodes", "bar")
+	if err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestAPIClientDelete_escape(t *testing.T) {
+	key, keyPem := testKey(t)
+
+	var req *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+	}))
+	defer ts.Close()
+
+	c, err := newAPIClient(ts.URL, "packer", keyPem, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := c.Delete("nodes", "foo bar+baz/qux"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := "/nodes/foo%20bar+baz%2Fqux"
+	if req.RequestURI != expected {
+		t.Fatalf("bad: %s", req.RequestURI)
+	}
+
+	// The signature covers the path as it was sent
+	var sig string
+	for i := 1; ; i++ {
+		part := req.Header.Get(fmt.Sprintf("X-Ops-Authorization-%d", i))
+		if part == "" {
+			break
+		}
+
+		sig += part
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	canonical := "Method:DELETE\n" +
+		"Hashed Path:" + hashBase64([]byte(expected)) + "\n" +
+		"X-Ops-Content-Hash:" + req.Header.Get("X-Ops-Content-Hash") + "\n" +
+		"X-Ops-Timestamp:" + req.Header.Get("X-Ops-Timestamp") + "\n" +
+		"X-Ops-UserId:packer"
+	err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.Hash(0), []byte(canonical), raw)
+	if err != nil {
+		t.Fatalf("bad signature: %s", err)
+	}
+}
+
+func TestNewAPIClient_badKey(t *testing.T) {
+	if _, err := newAPIClient("http://foo", "packer", []byte("bad"), false); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestCanonicalPath(t *testing.T) {
+	cases := map[string]string{
+		"/":                   "/",
+		"/nodes/foo":          "/nodes/foo",
+		"//organizations//x/": "/organizations/x",
+	}
+
+	for input, expected := range cases {
+		if actual := canonicalPath(input); actual != expected {
+			t.Fatalf("%s: bad: %s", input, actual)
+		}
+	}
+}
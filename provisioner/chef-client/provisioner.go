@@ -24,6 +24,8 @@ type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
 	ChefEnvironment      string `mapstructure:"chef_environment"`
+	ClientKey            string `mapstructure:"client_key"`
+	ClientName           string `mapstructure:"client_name"`
 	SslVerifyMode        string `mapstructure:"ssl_verify_mode"`
 	ConfigTemplate       string `mapstructure:"config_template"`
 	ExecuteCommand       string `mapstructure:"execute_command"`
@@ -100,6 +102,9 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 	}
 
 	var errs *packer.MultiError
+	if err := p.config.RequireUnixGuest("chef-client provisioner"); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
 	if p.config.ConfigTemplate != "" {
 		fi, err := os.Stat(p.config.ConfigTemplate)
 		if err != nil {
@@ -116,6 +121,18 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 			errs, fmt.Errorf("server_url must be set"))
 	}
 
+	if p.config.ClientKey != "" {
+		if _, err := os.Stat(p.config.ClientKey); err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("Bad client key path: %s", err))
+		}
+
+		if p.config.ClientName == "" {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("client_name must be set with client_key"))
+		}
+	}
+
 	jsonValid := true
 	for k, v := range p.config.Json {
 		p.config.Json[k], err = p.deepJsonFix(k, v)
@@ -182,13 +199,13 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 
 	err = p.executeChef(ui, comm, configPath, jsonPath)
 	if !p.config.SkipCleanNode {
-		if err2 := p.cleanNode(ui, comm, nodeName); err2 != nil {
+		if err2 := p.cleanNode(ui, nodeName); err2 != nil {
 			return fmt.Errorf("Error cleaning up chef node: %s", err2)
 		}
 	}
 
 	if !p.config.SkipCleanClient {
-		if err2 := p.cleanClient(ui, comm, nodeName); err2 != nil {
+		if err2 := p.cleanClient(ui, nodeName); err2 != nil {
 			return fmt.Errorf("Error cleaning up chef client: %s", err2)
 		}
 	}
@@ -204,6 +221,10 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	return nil
 }
 
+func (p *Provisioner) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (p *Provisioner) Cancel() {
 	// Just hard quit. It isn't a big deal if what we're doing keeps
 	// running on the other side.
@@ -318,36 +339,42 @@ func (p *Provisioner) createDir(ui packer.Ui, comm packer.Communicator, dir stri
 	return nil
 }
 
-func (p *Provisioner) cleanNode(ui packer.Ui, comm packer.Communicator, node string) error {
+func (p *Provisioner) cleanNode(ui packer.Ui, node string) error {
 	ui.Say("Cleaning up chef node...")
-	app := fmt.Sprintf("knife node delete %s -y", node)
+	return p.deleteObject(ui, "node", node)
+}
 
-	cmd := exec.Command("sh", "-c", app)
-	out, err := cmd.Output()
+func (p *Provisioner) cleanClient(ui packer.Ui, node string) error {
+	ui.Say("Cleaning up chef client...")
+	return p.deleteObject(ui, "client", node)
+}
 
-	ui.Message(fmt.Sprintf("%s", out))
+// deleteObject deletes a node or client from the Chef server. If
+// client_key is set, the Chef server API is called directly with that
+// key. Otherwise knife is run, which must be on the path and configured
+// for the Chef server on the machine running Packer.
+func (p *Provisioner) deleteObject(ui packer.Ui, kind string, name string) error {
+	if p.config.ClientKey == "" {
+		cmd := exec.Command("knife", kind, "delete", name, "-y")
+		out, err := cmd.Output()
+
+		ui.Message(fmt.Sprintf("%s", out))
 
-	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (p *Provisioner) cleanClient(ui packer.Ui, comm packer.Communicator, node string) error {
-	ui.Say("Cleaning up chef client...")
-	app := fmt.Sprintf("knife client delete %s -y", node)
-
-	cmd := exec.Command("sh", "-c", app)
-	out, err := cmd.Output()
-
-	ui.Message(fmt.Sprintf("%s", out))
+	key, err := ioutil.ReadFile(p.config.ClientKey)
+	if err != nil {
+		return err
+	}
 
+	api, err := newAPIClient(p.config.ServerUrl, p.config.ClientName, key,
+		p.config.SslVerifyMode == "verify_none")
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return api.Delete(kind+"s", name)
 }
 
 func (p *Provisioner) removeDir(ui packer.Ui, comm packer.Communicator, dir string) error {
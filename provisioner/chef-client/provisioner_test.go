@@ -40,6 +40,40 @@ func TestProvisionerPrepare_chefEnvironment(t *testing.T) {
 	}
 }
 
+func TestProvisionerPrepare_clientKey(t *testing.T) {
+	var p Provisioner
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	// Test a client key without a client name
+	config := testConfig()
+	config["client_key"] = tf.Name()
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should error")
+	}
+
+	// Test a missing client key
+	config = testConfig()
+	config["client_key"] = tf.Name() + ".missing"
+	config["client_name"] = "packer"
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should error")
+	}
+
+	// Test good
+	config = testConfig()
+	config["client_key"] = tf.Name()
+	config["client_name"] = "packer"
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
 func TestProvisionerPrepare_configTemplate(t *testing.T) {
 	var err error
 	var p Provisioner
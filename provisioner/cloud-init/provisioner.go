@@ -0,0 +1,177 @@
+// This package implements a provisioner for Packer that waits for the
+// first boot initialization of the machine, such as cloud-init or
+// EC2Launch, to complete before other provisioners run.
+package cloudinit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf16"
+
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/helper/config"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+// unixCommand waits for cloud-init. Versions of cloud-init without the
+// status subcommand create boot-finished once they're done.
+const unixCommand = `if ! command -v cloud-init >/dev/null 2>&1; then ` +
+	`echo "cloud-init is not installed, not waiting"; ` +
+	`elif cloud-init status --help >/dev/null 2>&1; then ` +
+	`cloud-init status --wait; ` +
+	`else ` +
+	`while [ ! -f /var/lib/cloud/instance/boot-finished ]; do sleep 1; done; ` +
+	`fi`
+
+// windowsScript waits for EC2Launch v2 with its status command, and for
+// EC2Launch v1 and EC2Config by watching their logs for the message that
+// is written once the instance is ready.
+const windowsScript = `$ErrorActionPreference = 'Stop'
+$ec2launch = Join-Path $env:ProgramFiles 'Amazon\EC2Launch\EC2Launch.exe'
+if (Test-Path $ec2launch) {
+    & $ec2launch status --block
+    exit $LASTEXITCODE
+}
+
+$logs = @(
+    (Join-Path $env:ProgramData 'Amazon\EC2-Windows\Launch\Log\Ec2Launch.log'),
+    (Join-Path $env:ProgramFiles 'Amazon\Ec2ConfigService\Logs\Ec2ConfigLog.txt')
+)
+foreach ($log in $logs) {
+    if (Test-Path $log) {
+        while (-not (Select-String -Path $log -Pattern 'Windows is Ready to use' -SimpleMatch -Quiet)) {
+            Start-Sleep -Seconds 1
+        }
+        exit 0
+    }
+}
+
+Write-Output 'EC2Launch is not installed, not waiting'
+`
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The operating system of the machine, either "unix", to wait for
+	// cloud-init, or "windows", to wait for EC2Launch.
+	GuestOSType string `mapstructure:"guest_os_type"`
+
+	// If true, errors reported by cloud-init don't fail the build.
+	IgnoreErrors bool `mapstructure:"ignore_errors"`
+
+	// The amount of time to wait for initialization to complete.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	ctx interpolate.Context
+}
+
+type Provisioner struct {
+	config Config
+	cancel chan struct{}
+}
+
+func (p *Provisioner) Prepare(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate: true,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.GuestOSType == "" {
+		p.config.GuestOSType = "unix"
+		if p.config.PackerGuestOS == "windows" {
+			p.config.GuestOSType = "windows"
+		}
+	}
+
+	if p.config.Timeout == 0 {
+		p.config.Timeout = 30 * time.Minute
+	}
+
+	var errs *packer.MultiError
+	if p.config.GuestOSType != "unix" && p.config.GuestOSType != "windows" {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("guest_os_type must be 'unix' or 'windows'"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	p.cancel = make(chan struct{})
+	return nil
+}
+
+func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
+	name := "cloud-init"
+	command := unixCommand
+	if p.config.GuestOSType == "windows" {
+		name = "EC2Launch"
+		command = powershellCommand(windowsScript)
+	}
+
+	ui.Say(fmt.Sprintf("Waiting for %s to complete...", name))
+	cmd := &packer.RemoteCmd{Command: command}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cmd.StartWithUi(comm, ui)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return err
+		}
+	case <-time.After(p.config.Timeout):
+		return fmt.Errorf("Timeout waiting for %s to complete", name)
+	case <-p.cancel:
+		return fmt.Errorf("Cancelled waiting for %s", name)
+	}
+
+	switch cmd.ExitStatus {
+	case 0:
+		ui.Message(fmt.Sprintf("%s is complete", name))
+	case 2:
+		// cloud-init reports recoverable errors with an exit status of 2
+		ui.Message(fmt.Sprintf("%s completed with recoverable errors", name))
+	default:
+		if !p.config.IgnoreErrors {
+			return fmt.Errorf(
+				"%s failed with a non-zero exit status: %d", name, cmd.ExitStatus)
+		}
+		ui.Message(fmt.Sprintf(
+			"%s failed with exit status %d, ignoring", name, cmd.ExitStatus))
+	}
+
+	return nil
+}
+
+func (p *Provisioner) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
+func (p *Provisioner) Cancel() {
+	close(p.cancel)
+}
+
+// powershellCommand returns a command line that runs the script with
+// PowerShell. The script is encoded so that it doesn't need quoting.
+func powershellCommand(script string) string {
+	var buf bytes.Buffer
+	for _, c := range utf16.Encode([]rune(script)) {
+		binary.Write(&buf, binary.LittleEndian, c)
+	}
+
+	return fmt.Sprintf(
+		"powershell.exe -NoProfile -NonInteractive -EncodedCommand %s",
+		base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
@@ -0,0 +1,170 @@
+package cloudinit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func TestProvisioner_Impl(t *testing.T) {
+	var raw interface{}
+	raw = &Provisioner{}
+	if _, ok := raw.(packer.Provisioner); !ok {
+		t.Fatalf("must be a Provisioner")
+	}
+}
+
+func TestProvisionerPrepare_defaults(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if p.config.GuestOSType != "unix" {
+		t.Fatalf("bad: %s", p.config.GuestOSType)
+	}
+	if p.config.Timeout != 30*time.Minute {
+		t.Fatalf("bad: %s", p.config.Timeout)
+	}
+}
+
+func TestProvisionerPrepare_guestOSType(t *testing.T) {
+	config := testConfig()
+	config["guest_os_type"] = "windows"
+
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Windows builds default to it
+	delete(config, "guest_os_type")
+	config[packer.GuestOSConfigKey] = "windows"
+	p = Provisioner{}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.GuestOSType != "windows" {
+		t.Fatalf("bad: %s", p.config.GuestOSType)
+	}
+
+	config["guest_os_type"] = "plan9"
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_timeout(t *testing.T) {
+	config := testConfig()
+	config["timeout"] = "bad"
+
+	var p Provisioner
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerProvision(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := new(packer.MockCommunicator)
+	ui := testUi()
+	if err := p.Provision(ui, comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(comm.StartCmd.Command, "cloud-init status --wait") {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+
+	// Recoverable errors are only reported
+	comm.StartExitStatus = 2
+	if err := p.Provision(ui, comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm.StartExitStatus = 1
+	if err := p.Provision(ui, comm); err == nil {
+		t.Fatal("should have error")
+	}
+
+	p.config.IgnoreErrors = true
+	if err := p.Provision(ui, comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestProvisionerProvision_windows(t *testing.T) {
+	config := testConfig()
+	config["guest_os_type"] = "windows"
+
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := new(packer.MockCommunicator)
+	if err := p.Provision(testUi(), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	prefix := "powershell.exe -NoProfile -NonInteractive -EncodedCommand "
+	if !strings.HasPrefix(comm.StartCmd.Command, prefix) {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(comm.StartCmd.Command[len(prefix):])
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	chars := make([]uint16, len(raw)/2)
+	for i := range chars {
+		chars[i] = uint16(raw[2*i]) | uint16(raw[2*i+1])<<8
+	}
+	if string(utf16.Decode(chars)) != windowsScript {
+		t.Fatalf("bad: %s", string(utf16.Decode(chars)))
+	}
+}
+
+func TestProvisionerProvision_timeout(t *testing.T) {
+	config := testConfig()
+	config["timeout"] = "10ms"
+
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := &hangingCommunicator{new(packer.MockCommunicator)}
+	if err := p.Provision(testUi(), comm); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+// hangingCommunicator starts commands that never exit.
+type hangingCommunicator struct {
+	*packer.MockCommunicator
+}
+
+func (c *hangingCommunicator) Start(rc *packer.RemoteCmd) error {
+	return nil
+}
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
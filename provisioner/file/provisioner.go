@@ -87,6 +87,10 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	return err
 }
 
+func (p *Provisioner) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (p *Provisioner) Cancel() {
 	// Just hard quit. It isn't a big deal if what we're doing keeps
 	// running on the other side.
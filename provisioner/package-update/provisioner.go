@@ -0,0 +1,429 @@
+// This package implements a provisioner for Packer that updates the
+// packages of the machine with its package manager, and reboots it if the
+// update requires it.
+package packageupdate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/common/uuid"
+	"github.com/mitchellh/packer/helper/config"
+	"github.com/mitchellh/packer/packer"
+	"github.com/mitchellh/packer/template/interpolate"
+)
+
+// DefaultExecuteCommand runs the scripts as root, with sudo if the user
+// isn't root.
+const DefaultExecuteCommand = `if [ "$(id -u)" = 0 ]; then sh -e {{.Path}}; ` +
+	`else sudo -n sh -e {{.Path}}; fi`
+
+// packageManager is how the packages are updated on a distribution.
+type packageManager struct {
+	// Update updates the package lists and upgrades the packages.
+	Update string
+
+	// RebootRequired exits with a zero exit status if the machine must be
+	// rebooted to finish the update.
+	RebootRequired string
+}
+
+// kernelRemoved is true if the modules of the running kernel were removed
+// because it was replaced.
+const kernelRemoved = `[ ! -d "/lib/modules/$(uname -r)" ]`
+
+var packageManagers = map[string]packageManager{
+	"apk": {
+		Update: "apk update\n" +
+			"apk upgrade\n",
+		RebootRequired: kernelRemoved,
+	},
+	"apt": {
+		Update: "export DEBIAN_FRONTEND=noninteractive\n" +
+			"apt-get -q -o DPkg::Lock::Timeout=300 update\n" +
+			"apt-get -q -y -o DPkg::Lock::Timeout=300 " +
+			"-o Dpkg::Options::=--force-confdef -o Dpkg::Options::=--force-confold " +
+			"dist-upgrade\n",
+		RebootRequired: "[ -f /var/run/reboot-required ]",
+	},
+	"dnf": {
+		Update: "dnf -y upgrade --refresh\n",
+		RebootRequired: "if dnf needs-restarting --help >/dev/null 2>&1; then\n" +
+			"  ! dnf needs-restarting -r >/dev/null\n" +
+			"else\n" +
+			"  " + kernelRemoved + "\n" +
+			"fi",
+	},
+	"yum": {
+		Update: "yum -y update\n",
+		RebootRequired: "if command -v needs-restarting >/dev/null 2>&1; then\n" +
+			"  ! needs-restarting -r >/dev/null\n" +
+			"else\n" +
+			"  " + kernelRemoved + "\n" +
+			"fi",
+	},
+	"zypper": {
+		Update: "zypper --non-interactive --gpg-auto-import-keys refresh\n" +
+			"zypper --non-interactive update\n",
+		// zypper exits with 102 if a reboot is needed
+		RebootRequired: "[ -f /var/run/reboot-needed ] && exit 0\n" +
+			"zypper needs-rebooting >/dev/null 2>&1 && exit 1\n" +
+			"[ $? -eq 102 ]",
+	},
+}
+
+// detectCommand prints the package manager of the machine.
+const detectCommand = `for pm in apt-get dnf yum zypper apk; do ` +
+	`if command -v $pm >/dev/null 2>&1; then echo $pm; exit 0; fi; ` +
+	`done; exit 1`
+
+// bootIDCommand prints a value that changes every time the machine boots.
+const bootIDCommand = "cat /proc/sys/kernel/random/boot_id"
+
+var (
+	// rebootPollInterval is the time between checks of whether the
+	// machine is back up after a reboot.
+	rebootPollInterval = 5 * time.Second
+
+	// outputTimeout is the time that quick commands, such as reading the
+	// boot ID, are given before trying them again.
+	outputTimeout = 30 * time.Second
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The command that runs the scripts of the provisioner as root. The
+	// '{{ .Path }}' variable is the path of the script.
+	ExecuteCommand string `mapstructure:"execute_command"`
+
+	// The package manager to update the packages with, or "auto" to use
+	// the one that is installed.
+	PackageManager string `mapstructure:"package_manager"`
+
+	// The command that reboots the machine.
+	RebootCommand string `mapstructure:"reboot_command"`
+
+	// The amount of time to wait for the machine to come back up after
+	// rebooting.
+	RebootTimeout time.Duration `mapstructure:"reboot_timeout"`
+
+	// The number of times to retry a failed update, and how long to wait
+	// before each retry. Retries is a pointer so that an explicit zero
+	// can be told apart from the default.
+	Retries    *int          `mapstructure:"retries"`
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+
+	// If true, the machine isn't rebooted even if the update requires it.
+	SkipReboot bool `mapstructure:"skip_reboot"`
+
+	ctx interpolate.Context
+}
+
+type Provisioner struct {
+	config     Config
+	cancel     chan struct{}
+	cancelOnce sync.Once
+}
+
+type ExecuteCommandTemplate struct {
+	Path string
+}
+
+func (p *Provisioner) Prepare(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate: true,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"execute_command",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.ExecuteCommand == "" {
+		p.config.ExecuteCommand = DefaultExecuteCommand
+	}
+
+	if p.config.PackageManager == "" {
+		p.config.PackageManager = "auto"
+	}
+
+	if p.config.RebootCommand == "" {
+		p.config.RebootCommand = "shutdown -r now"
+	}
+
+	if p.config.RebootTimeout == 0 {
+		p.config.RebootTimeout = 10 * time.Minute
+	}
+
+	if p.config.Retries == nil {
+		retries := 3
+		p.config.Retries = &retries
+	}
+
+	if p.config.RetryDelay == 0 {
+		p.config.RetryDelay = 10 * time.Second
+	}
+
+	var errs *packer.MultiError
+	if err := p.config.RequireUnixGuest("package-update provisioner"); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+	if *p.config.Retries < 0 {
+		errs = packer.MultiErrorAppend(errs, errors.New("retries can't be negative"))
+	}
+	if _, ok := packageManagers[p.config.PackageManager]; !ok && p.config.PackageManager != "auto" {
+		names := make([]string, 0, len(packageManagers))
+		for name := range packageManagers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"package_manager must be 'auto' or one of: %s", strings.Join(names, ", ")))
+	}
+
+	p.config.ctx.Data = &ExecuteCommandTemplate{}
+	if _, err := interpolate.Render(p.config.ExecuteCommand, &p.config.ctx); err != nil {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("Error parsing execute_command: %s", err))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	p.cancel = make(chan struct{})
+	return nil
+}
+
+func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
+	name := p.config.PackageManager
+	if name == "auto" {
+		output, err := p.output(comm, detectCommand)
+		if err != nil {
+			return fmt.Errorf("Error detecting the package manager: %s", err)
+		}
+
+		name = strings.TrimSuffix(output, "-get")
+		if _, ok := packageManagers[name]; !ok {
+			return fmt.Errorf("Unsupported package manager: %s", output)
+		}
+	}
+	pm := packageManagers[name]
+
+	ui.Say(fmt.Sprintf("Updating packages with %s...", name))
+	for attempt := 0; ; attempt++ {
+		status, err := p.runScript(ui, comm, pm.Update)
+		if err != nil {
+			return err
+		}
+		if status == 0 {
+			break
+		}
+
+		// Mirrors that are being synced or a lock held by automatic
+		// updates cause transient failures, so try again a few times.
+		if attempt >= *p.config.Retries {
+			return fmt.Errorf(
+				"Updating packages failed with exit status %d", status)
+		}
+
+		ui.Message(fmt.Sprintf(
+			"Updating packages failed with exit status %d, retrying in %s...",
+			status, p.config.RetryDelay))
+		select {
+		case <-time.After(p.config.RetryDelay):
+		case <-p.cancel:
+			return errors.New("Cancelled updating packages")
+		}
+	}
+
+	if p.config.SkipReboot {
+		return nil
+	}
+
+	status, err := p.runScript(nil, comm, pm.RebootRequired)
+	if err != nil {
+		return err
+	}
+	if status != 0 {
+		ui.Message("No reboot is required")
+		return nil
+	}
+
+	return p.reboot(ui, comm)
+}
+
+func (p *Provisioner) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
+func (p *Provisioner) Cancel() {
+	// Cancel can be called more than once, such as by a second interrupt
+	p.cancelOnce.Do(func() {
+		close(p.cancel)
+	})
+}
+
+// reboot reboots the machine and waits until it is back up, which is
+// when its boot ID has changed.
+func (p *Provisioner) reboot(ui packer.Ui, comm packer.Communicator) error {
+	bootID, err := p.output(comm, bootIDCommand)
+	if err != nil {
+		return fmt.Errorf("Error reading the boot ID: %s", err)
+	}
+
+	ui.Say("Rebooting to finish updating...")
+	cmd, err := p.startScript(comm, p.config.RebootCommand)
+	if err != nil {
+		return fmt.Errorf("Error rebooting: %s", err)
+	}
+
+	// The connection usually drops before the reboot command exits, so
+	// its exit status only matters if it exits while the machine is
+	// still up.
+	timeout := time.After(p.config.RebootTimeout)
+	for {
+		select {
+		case <-time.After(rebootPollInterval):
+		case <-timeout:
+			return errors.New("Timeout waiting for the machine to reboot")
+		case <-p.cancel:
+			return errors.New("Cancelled waiting for the machine to reboot")
+		}
+
+		current, err := p.output(comm, bootIDCommand)
+		if err != nil {
+			log.Printf("Machine isn't back up yet: %s", err)
+			continue
+		}
+		if current != bootID {
+			ui.Message("The machine is back up")
+			return nil
+		}
+
+		cmd.Lock()
+		exited, status := cmd.Exited, cmd.ExitStatus
+		cmd.Unlock()
+		if exited && status != 0 {
+			return fmt.Errorf("Reboot command failed with exit status %d", status)
+		}
+	}
+}
+
+// runScript runs the script as root and returns its exit status. The
+// output is shown on the ui unless it is nil.
+func (p *Provisioner) runScript(ui packer.Ui, comm packer.Communicator, script string) (int, error) {
+	cmd, path, err := p.scriptCommand(comm, script)
+	if err != nil {
+		return 0, err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if ui != nil {
+			errCh <- cmd.StartWithUi(comm, ui)
+			return
+		}
+
+		if err := comm.Start(cmd); err != nil {
+			errCh <- err
+			return
+		}
+		cmd.Wait()
+		errCh <- nil
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return 0, err
+		}
+	case <-p.cancel:
+		return 0, errors.New("Cancelled updating packages")
+	}
+
+	rm := &packer.RemoteCmd{Command: fmt.Sprintf("rm -f %s", path)}
+	if err := comm.Start(rm); err != nil {
+		log.Printf("Error removing script %s: %s", path, err)
+	} else {
+		rm.Wait()
+	}
+
+	return cmd.ExitStatus, nil
+}
+
+// startScript starts the script as root without waiting for it.
+func (p *Provisioner) startScript(comm packer.Communicator, script string) (*packer.RemoteCmd, error) {
+	cmd, _, err := p.scriptCommand(comm, script)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := comm.Start(cmd); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+// scriptCommand uploads the script and returns the command that runs it
+// as root, and the path it was uploaded to.
+func (p *Provisioner) scriptCommand(comm packer.Communicator, script string) (*packer.RemoteCmd, string, error) {
+	path := fmt.Sprintf("/tmp/packer-package-update-%s.sh", uuid.TimeOrderedUUID())
+	if err := comm.Upload(path, strings.NewReader(script+"\n"), nil); err != nil {
+		return nil, "", fmt.Errorf("Error uploading script: %s", err)
+	}
+
+	p.config.ctx.Data = &ExecuteCommandTemplate{Path: path}
+	command, err := interpolate.Render(p.config.ExecuteCommand, &p.config.ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("Error processing command: %s", err)
+	}
+
+	return &packer.RemoteCmd{Command: command}, path, nil
+}
+
+// output runs a quick command and returns its output. It gives up after
+// outputTimeout, such as when the machine went down while it ran.
+func (p *Provisioner) output(comm packer.Communicator, command string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := &packer.RemoteCmd{Command: command, Stdout: &stdout}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := comm.Start(cmd); err != nil {
+			errCh <- err
+			return
+		}
+		cmd.Wait()
+		errCh <- nil
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return "", err
+		}
+	case <-time.After(outputTimeout):
+		return "", fmt.Errorf("Timeout running command: %s", command)
+	}
+
+	if cmd.ExitStatus != 0 {
+		return "", fmt.Errorf(
+			"Command exited with non-zero exit status %d: %s", cmd.ExitStatus, command)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,286 @@
+package packageupdate
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"retry_delay": "1ms",
+	}
+}
+
+// testComm runs scripts by asking Respond for their output and exit
+// status, and records the scripts and commands that were run.
+type testComm struct {
+	Respond func(script string) (string, int)
+
+	lock    sync.Mutex
+	scripts map[string]string
+	run     []string
+}
+
+func (c *testComm) Start(cmd *packer.RemoteCmd) error {
+	c.lock.Lock()
+	script := cmd.Command
+	for path, contents := range c.scripts {
+		if strings.Contains(cmd.Command, path) {
+			script = contents
+		}
+	}
+	if strings.HasPrefix(cmd.Command, "rm -f ") {
+		c.lock.Unlock()
+		cmd.SetExited(0)
+		return nil
+	}
+	c.run = append(c.run, script)
+	c.lock.Unlock()
+
+	stdout, status := c.Respond(script)
+	go func() {
+		if cmd.Stdout != nil {
+			cmd.Stdout.Write([]byte(stdout))
+		}
+		cmd.SetExited(status)
+	}()
+
+	return nil
+}
+
+func (c *testComm) Upload(path string, r io.Reader, fi *os.FileInfo) error {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.scripts == nil {
+		c.scripts = make(map[string]string)
+	}
+	c.scripts[path] = strings.TrimSpace(string(contents))
+	return nil
+}
+
+func (c *testComm) UploadDir(string, string, []string) error { return nil }
+func (c *testComm) Download(string, io.Writer) error         { return nil }
+
+func TestProvisioner_Impl(t *testing.T) {
+	var raw interface{}
+	raw = &Provisioner{}
+	if _, ok := raw.(packer.Provisioner); !ok {
+		t.Fatalf("must be a Provisioner")
+	}
+}
+
+func TestProvisionerPrepare_defaults(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if p.config.PackageManager != "auto" {
+		t.Fatalf("bad: %s", p.config.PackageManager)
+	}
+	if p.config.ExecuteCommand != DefaultExecuteCommand {
+		t.Fatalf("bad: %s", p.config.ExecuteCommand)
+	}
+	if *p.config.Retries != 3 || p.config.RetryDelay != 10*time.Second {
+		t.Fatalf("bad: %#v", p.config)
+	}
+	if p.config.RebootTimeout != 10*time.Minute {
+		t.Fatalf("bad: %s", p.config.RebootTimeout)
+	}
+}
+
+func TestProvisionerPrepare_packageManager(t *testing.T) {
+	config := testConfig()
+	config["package_manager"] = "dnf"
+
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config["package_manager"] = "pacman"
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_retries(t *testing.T) {
+	config := testConfig()
+	config["retries"] = 0
+
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if *p.config.Retries != 0 {
+		t.Fatalf("bad: %d", *p.config.Retries)
+	}
+
+	config["retries"] = -1
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_executeCommand(t *testing.T) {
+	config := testConfig()
+	config["execute_command"] = "sh {{.Path"
+
+	var p Provisioner
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerProvision_retries(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	yum := packageManagers["yum"]
+	failures := 2
+	comm := &testComm{Respond: func(script string) (string, int) {
+		switch script {
+		case detectCommand:
+			return "yum\n", 0
+		case strings.TrimSpace(yum.Update):
+			if failures > 0 {
+				failures--
+				return "", 1
+			}
+			return "", 0
+		case yum.RebootRequired:
+			return "", 1
+		}
+
+		t.Errorf("unexpected script: %s", script)
+		return "", 255
+	}}
+
+	if err := p.Provision(packer.TestUi(t), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(comm.run) != 5 {
+		t.Fatalf("bad: %#v", comm.run)
+	}
+}
+
+func TestProvisionerProvision_fails(t *testing.T) {
+	config := testConfig()
+	config["package_manager"] = "apt"
+	config["retries"] = 1
+
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := &testComm{Respond: func(string) (string, int) { return "", 100 }}
+	if err := p.Provision(packer.TestUi(t), comm); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// The first attempt and one retry
+	if len(comm.run) != 2 {
+		t.Fatalf("bad: %#v", comm.run)
+	}
+}
+
+func TestProvisionerCancel(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Cancelling twice must not panic
+	p.Cancel()
+	p.Cancel()
+}
+
+func TestProvisionerProvision_reboot(t *testing.T) {
+	old := rebootPollInterval
+	defer func() { rebootPollInterval = old }()
+	rebootPollInterval = time.Millisecond
+
+	config := testConfig()
+	config["package_manager"] = "apt"
+	config["reboot_command"] = "reboot"
+
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	apt := packageManagers["apt"]
+	var lock sync.Mutex
+	rebooted := false
+	polls := 0
+	comm := &testComm{Respond: func(script string) (string, int) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch script {
+		case strings.TrimSpace(apt.Update), apt.RebootRequired:
+			return "", 0
+		case "reboot":
+			rebooted = true
+			return "", 0
+		case bootIDCommand:
+			if !rebooted {
+				return "old\n", 0
+			}
+
+			// The machine is unreachable for a while
+			polls++
+			if polls < 3 {
+				return "", 255
+			}
+			return "new\n", 0
+		}
+
+		t.Errorf("unexpected script: %s", script)
+		return "", 255
+	}}
+
+	if err := p.Provision(packer.TestUi(t), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !rebooted || polls != 3 {
+		t.Fatalf("bad: %v %d", rebooted, polls)
+	}
+}
+
+func TestProvisionerProvision_skipReboot(t *testing.T) {
+	config := testConfig()
+	config["package_manager"] = "apk"
+	config["skip_reboot"] = true
+
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := &testComm{Respond: func(string) (string, int) { return "", 0 }}
+	if err := p.Provision(packer.TestUi(t), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(comm.run) != 1 {
+		t.Fatalf("bad: %#v", comm.run)
+	}
+}
@@ -0,0 +1,163 @@
+package puppetserver
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// caClient is a client for the certificate_status endpoint of the
+// Puppet Server CA API. The certificate it authenticates with must be
+// allowed to use the endpoint, which is configured in auth.conf on the
+// CA server.
+type caClient struct {
+	url    string
+	client *http.Client
+}
+
+// certificateStatus is the part of a certificate status that we use.
+type certificateStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// newCAClient returns a client for the CA at the given host and port,
+// authenticating with the client certificate and key and verifying the
+// server against the CA certificate.
+func newCAClient(host string, port int, caCertPath, certPath, keyPath string) (*caClient, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading CA client certificate: %s", err)
+	}
+
+	caCert, err := ioutil.ReadFile(caCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading CA certificate: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("Error reading CA certificate: no certificates found")
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      pool,
+		},
+	}
+
+	return &caClient{
+		url:    fmt.Sprintf("https://%s:%d/puppet-ca/v1", host, port),
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+// Status returns the status of the certificate for the given name, or
+// nil if neither a certificate nor a request exists.
+func (c *caClient) Status(name string) (*certificateStatus, error) {
+	resp, err := c.do("GET", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var status certificateStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("Error decoding certificate status: %s", err)
+	}
+
+	return &status, nil
+}
+
+// Sign signs the pending certificate request for the given name.
+func (c *caClient) Sign(name string) error {
+	return c.setState(name, "signed")
+}
+
+// Clean revokes the certificate for the given name and removes it,
+// along with any pending request, from the CA. Certificates that don't
+// exist are ignored.
+func (c *caClient) Clean(name string) error {
+	status, err := c.Status(name)
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		return nil
+	}
+
+	if status.State == "signed" {
+		if err := c.setState(name, "revoked"); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.do("DELETE", name, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return checkResponse(resp)
+}
+
+func (c *caClient) setState(name, state string) error {
+	body, err := json.Marshal(map[string]string{"desired_state": state})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do("PUT", name, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+func (c *caClient) do(method, name string, body []byte) (*http.Response, error) {
+	u := fmt.Sprintf("%s/certificate_status/%s?environment=production",
+		c.url, url.QueryEscape(name))
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.client.Do(req)
+}
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	return fmt.Errorf("Puppet CA returned %s: %s",
+		resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,118 @@
+package puppetserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// testCA is a fake Puppet CA that keeps the state of its certificates
+// and records the requests made to it.
+type testCA struct {
+	states   map[string]string
+	requests []string
+}
+
+func (ca *testCA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Path[len("/puppet-ca/v1/certificate_status/"):]
+	ca.requests = append(ca.requests, r.Method+" "+name)
+
+	state, ok := ca.states[name]
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(&certificateStatus{Name: name, State: state})
+	case "PUT":
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ca.states[name] = body["desired_state"]
+		w.WriteHeader(http.StatusNoContent)
+	case "DELETE":
+		delete(ca.states, name)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func testCAClient(t *testing.T, states map[string]string) (*caClient, *testCA, func()) {
+	ca := &testCA{states: states}
+	ts := httptest.NewServer(ca)
+	c := &caClient{
+		url:    ts.URL + "/puppet-ca/v1",
+		client: http.DefaultClient,
+	}
+
+	return c, ca, ts.Close
+}
+
+func TestCAClientStatus(t *testing.T) {
+	c, _, closeFn := testCAClient(t, map[string]string{"foo": "requested"})
+	defer closeFn()
+
+	status, err := c.Status("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if status == nil || status.State != "requested" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	status, err = c.Status("bar")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if status != nil {
+		t.Fatalf("bad: %#v", status)
+	}
+}
+
+func TestCAClientSign(t *testing.T) {
+	c, ca, closeFn := testCAClient(t, map[string]string{"foo": "requested"})
+	defer closeFn()
+
+	if err := c.Sign("foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ca.states["foo"] != "signed" {
+		t.Fatalf("bad: %#v", ca.states)
+	}
+
+	if err := c.Sign("bar"); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestCAClientClean(t *testing.T) {
+	c, ca, closeFn := testCAClient(t, map[string]string{
+		"foo": "signed",
+		"bar": "requested",
+	})
+	defer closeFn()
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		if err := c.Clean(name); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if len(ca.states) > 0 {
+		t.Fatalf("bad: %#v", ca.states)
+	}
+
+	expected := []string{
+		"GET foo", "PUT foo", "DELETE foo",
+		"GET bar", "DELETE bar",
+		"GET baz",
+	}
+	if !reflect.DeepEqual(ca.requests, expected) {
+		t.Fatalf("bad: %#v", ca.requests)
+	}
+}
@@ -3,6 +3,7 @@
 package puppetserver
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"strings"
@@ -41,6 +42,26 @@ type Config struct {
 	// The directory where files will be uploaded. Packer requires write
 	// permissions in this directory.
 	StagingDir string `mapstructure:"staging_dir"`
+
+	// The number of times to run Puppet. Puppet is run again as long as
+	// it reports changes, up to this many times, so that it converges.
+	MaxRuns int `mapstructure:"max_runs"`
+
+	// The Puppet CA, which is used to sign the certificate of the node
+	// and to clean it up afterwards. The client certificate must be
+	// allowed to use the certificate_status endpoint of the CA.
+	CAServer         string `mapstructure:"ca_server"`
+	CAPort           int    `mapstructure:"ca_port"`
+	CACertPath       string `mapstructure:"ca_cert_path"`
+	CAClientCertPath string `mapstructure:"ca_client_cert_path"`
+	CAClientKeyPath  string `mapstructure:"ca_client_key_path"`
+
+	// If true, the certificate request of the node is signed with the CA.
+	SignCert bool `mapstructure:"sign_cert"`
+
+	// If true, the certificate of the node is removed from the machine
+	// and from the CA after Puppet has run.
+	CleanCert bool `mapstructure:"clean_cert"`
 }
 
 type Provisioner struct {
@@ -55,6 +76,7 @@ type ExecuteTemplate struct {
 	PuppetServer         string
 	Options              string
 	Sudo                 bool
+	RequestCert          bool
 }
 
 func (p *Provisioner) Prepare(raws ...interface{}) error {
@@ -72,7 +94,22 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		p.config.StagingDir = "/tmp/packer-puppet-server"
 	}
 
+	if p.config.MaxRuns == 0 {
+		p.config.MaxRuns = 1
+	}
+
+	if p.config.CAServer == "" {
+		p.config.CAServer = p.config.PuppetServer
+	}
+
+	if p.config.CAPort == 0 {
+		p.config.CAPort = 8140
+	}
+
 	var errs *packer.MultiError
+	if err := p.config.RequireUnixGuest("puppet-server provisioner"); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
 	if p.config.ClientCertPath != "" {
 		info, err := os.Stat(p.config.ClientCertPath)
 		if err != nil {
@@ -95,6 +132,41 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		}
 	}
 
+	if p.config.MaxRuns < 1 {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("max_runs must be at least 1"))
+	}
+
+	caPaths := map[string]string{
+		"ca_cert_path":        p.config.CACertPath,
+		"ca_client_cert_path": p.config.CAClientCertPath,
+		"ca_client_key_path":  p.config.CAClientKeyPath,
+	}
+	for key, path := range caPaths {
+		if path == "" {
+			if p.caEnabled() {
+				errs = packer.MultiErrorAppend(errs,
+					fmt.Errorf("%s must be set to use the CA", key))
+			}
+			continue
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("%s is invalid: %s", key, err))
+		}
+	}
+
+	if p.caEnabled() && p.config.CAServer == "" {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("ca_server or puppet_server must be set to use the CA"))
+	}
+
+	if p.config.SignCert && !p.caEnabled() {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("sign_cert requires ca_cert_path, ca_client_cert_path and ca_client_key_path"))
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
 		return errs
 	}
@@ -139,8 +211,25 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 		facterVars = append(facterVars, fmt.Sprintf("FACTER_%s='%s'", k, v))
 	}
 
-	// Execute Puppet
-	p.config.ctx.Data = &ExecuteTemplate{
+	// Connect to the CA, which needs the name of the certificate
+	var ca *caClient
+	certname := p.config.PuppetNode
+	if p.caEnabled() {
+		var err error
+		ca, err = newCAClient(p.config.CAServer, p.config.CAPort,
+			p.config.CACertPath, p.config.CAClientCertPath, p.config.CAClientKeyPath)
+		if err != nil {
+			return err
+		}
+
+		if certname == "" {
+			if certname, err = p.certname(comm); err != nil {
+				return fmt.Errorf("Error determining Puppet certname: %s", err)
+			}
+		}
+	}
+
+	data := &ExecuteTemplate{
 		FacterVars:           strings.Join(facterVars, " "),
 		ClientCertPath:       remoteClientCertPath,
 		ClientPrivateKeyPath: remoteClientPrivateKeyPath,
@@ -149,25 +238,165 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 		Options:              p.config.Options,
 		Sudo:                 !p.config.PreventSudo,
 	}
+
+	if p.config.SignCert {
+		if err := p.signCert(ui, comm, ca, certname, data); err != nil {
+			return fmt.Errorf("Error signing certificate: %s", err)
+		}
+	}
+
+	err := p.runPuppet(ui, comm, data)
+	if p.config.CleanCert {
+		if err2 := p.cleanCert(ui, comm, ca, certname); err2 != nil {
+			return fmt.Errorf("Error cleaning up Puppet certificate: %s", err2)
+		}
+	}
+
+	return err
+}
+
+// runPuppet runs Puppet until it makes no more changes or it has run
+// max_runs times.
+func (p *Provisioner) runPuppet(ui packer.Ui, comm packer.Communicator, data *ExecuteTemplate) error {
+	p.config.ctx.Data = data
 	command, err := interpolate.Render(p.commandTemplate(), &p.config.ctx)
 	if err != nil {
 		return err
 	}
 
-	cmd := &packer.RemoteCmd{
-		Command: command,
+	for run := 1; ; run++ {
+		cmd := &packer.RemoteCmd{
+			Command: command,
+		}
+
+		ui.Message(fmt.Sprintf("Running Puppet: %s", command))
+		if err := cmd.StartWithUi(comm, ui); err != nil {
+			return err
+		}
+
+		// With --detailed-exitcodes, 2 means that changes were made
+		switch {
+		case cmd.ExitStatus == 0:
+			return nil
+		case cmd.ExitStatus == 2 && run < p.config.MaxRuns:
+			ui.Message(fmt.Sprintf(
+				"Puppet made changes, running again (%d/%d)...", run+1, p.config.MaxRuns))
+		case cmd.ExitStatus == 2:
+			return nil
+		default:
+			return fmt.Errorf("Puppet exited with a non-zero exit status: %d", cmd.ExitStatus)
+		}
+	}
+}
+
+// signCert makes sure that the node has a signed certificate. If there
+// is no certificate yet, Puppet is run once to submit a certificate
+// request, which is then signed with the CA.
+func (p *Provisioner) signCert(ui packer.Ui, comm packer.Communicator, ca *caClient, certname string, data *ExecuteTemplate) error {
+	status, err := ca.Status(certname)
+	if err != nil {
+		return err
 	}
 
-	ui.Message(fmt.Sprintf("Running Puppet: %s", command))
+	if status == nil {
+		ui.Message(fmt.Sprintf("Requesting certificate for %s...", certname))
+		request := *data
+		request.RequestCert = true
+		p.config.ctx.Data = &request
+		command, err := interpolate.Render(p.commandTemplate(), &p.config.ctx)
+		if err != nil {
+			return err
+		}
+
+		// Without a signed certificate Puppet exits with an error once
+		// the request is submitted, so the exit status is ignored.
+		cmd := &packer.RemoteCmd{Command: command}
+		if err := cmd.StartWithUi(comm, ui); err != nil {
+			return err
+		}
+
+		if status, err = ca.Status(certname); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case status == nil:
+		return fmt.Errorf("no certificate request was made for %s", certname)
+	case status.State == "signed":
+		return nil
+	case status.State == "requested":
+		ui.Message(fmt.Sprintf("Signing certificate for %s...", certname))
+		return ca.Sign(certname)
+	default:
+		return fmt.Errorf("certificate for %s is %s", certname, status.State)
+	}
+}
+
+// cleanCert removes the certificate of the node from the machine and,
+// if the CA is configured, revokes and removes it from the CA.
+func (p *Provisioner) cleanCert(ui packer.Ui, comm packer.Communicator, ca *caClient, certname string) error {
+	ui.Message("Removing Puppet certificates from the machine...")
+	rmCmd := "rm -rf \"$(puppet agent --configprint ssldir)\""
+	if !p.config.PreventSudo {
+		rmCmd = fmt.Sprintf("sudo sh -c '%s'", rmCmd)
+	}
+
+	cmd := &packer.RemoteCmd{Command: rmCmd}
 	if err := cmd.StartWithUi(comm, ui); err != nil {
 		return err
 	}
+	if cmd.ExitStatus != 0 {
+		return fmt.Errorf("Non-zero exit status: %d", cmd.ExitStatus)
+	}
 
-	if cmd.ExitStatus != 0 && cmd.ExitStatus != 2 {
-		return fmt.Errorf("Puppet exited with a non-zero exit status: %d", cmd.ExitStatus)
+	// Certificates that were uploaded are shared, so they're not revoked
+	if p.config.ClientCertPath != "" {
+		return nil
 	}
 
-	return nil
+	if ca == nil {
+		ui.Message("No CA is configured, the certificate must be cleaned from the Puppet CA manually.")
+		return nil
+	}
+
+	ui.Message(fmt.Sprintf("Revoking certificate for %s...", certname))
+	return ca.Clean(certname)
+}
+
+// certname asks Puppet on the machine for the name of its certificate.
+func (p *Provisioner) certname(comm packer.Communicator) (string, error) {
+	command := "puppet agent --configprint certname"
+	if !p.config.PreventSudo {
+		command = "sudo " + command
+	}
+
+	var stdout bytes.Buffer
+	cmd := &packer.RemoteCmd{
+		Command: command,
+		Stdout:  &stdout,
+	}
+	if err := comm.Start(cmd); err != nil {
+		return "", err
+	}
+	cmd.Wait()
+
+	if cmd.ExitStatus != 0 {
+		return "", fmt.Errorf("Non-zero exit status: %d", cmd.ExitStatus)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// caEnabled returns true if any of the CA settings are set.
+func (p *Provisioner) caEnabled() bool {
+	return p.config.CACertPath != "" ||
+		p.config.CAClientCertPath != "" ||
+		p.config.CAClientKeyPath != ""
+}
+
+func (p *Provisioner) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
 }
 
 func (p *Provisioner) Cancel() {
@@ -214,5 +443,6 @@ func (p *Provisioner) commandTemplate() string {
 		"{{if ne .PuppetNode \"\"}}--certname={{.PuppetNode}} {{end}}" +
 		"{{if ne .ClientCertPath \"\"}}--certdir='{{.ClientCertPath}}' {{end}}" +
 		"{{if ne .ClientPrivateKeyPath \"\"}}--privatekeydir='{{.ClientPrivateKeyPath}}' {{end}}" +
+		"{{if .RequestCert}}--waitforcert=0 --noop {{end}}" +
 		"--detailed-exitcodes"
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/mitchellh/packer/packer"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -91,3 +92,97 @@ func TestProvisionerPrepare_clientCertPath(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 }
+
+func TestProvisionerPrepare_maxRuns(t *testing.T) {
+	config := testConfig()
+
+	p := new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.MaxRuns != 1 {
+		t.Fatalf("bad: %d", p.config.MaxRuns)
+	}
+
+	config["max_runs"] = -1
+	p = new(Provisioner)
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_ca(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+
+	config := testConfig()
+	config["puppet_server"] = "puppet.example.com"
+
+	// Signing requires the CA
+	config["sign_cert"] = true
+	p := new(Provisioner)
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// All of the paths must be set
+	config["ca_cert_path"] = tf.Name()
+	p = new(Provisioner)
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["ca_client_cert_path"] = tf.Name()
+	config["ca_client_key_path"] = tf.Name()
+	p = new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.CAServer != "puppet.example.com" {
+		t.Fatalf("bad: %s", p.config.CAServer)
+	}
+	if p.config.CAPort != 8140 {
+		t.Fatalf("bad: %d", p.config.CAPort)
+	}
+
+	// The paths must exist
+	config["ca_client_key_path"] = "i-should-not-exist"
+	p = new(Provisioner)
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerProvision_cleanCert(t *testing.T) {
+	config := testConfig()
+	ui := packer.TestUi(t)
+
+	// The certificates are left alone by default
+	comm := new(packer.MockCommunicator)
+	p := new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := p.Provision(ui, comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if strings.Contains(comm.StartCmd.Command, "ssldir") {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+
+	config["clean_cert"] = true
+	comm = new(packer.MockCommunicator)
+	p = new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := p.Provision(ui, comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(comm.StartCmd.Command, "ssldir") {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+}
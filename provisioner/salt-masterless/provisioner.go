@@ -58,6 +58,9 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 	}
 
 	var errs *packer.MultiError
+	if err := p.config.RequireUnixGuest("salt-masterless provisioner"); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
 
 	// require a salt state tree
 	if p.config.LocalStateTree == "" {
@@ -177,6 +180,10 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	return nil
 }
 
+func (p *Provisioner) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
 func (p *Provisioner) Cancel() {
 	// Just hard quit. It isn't a big deal if what we're doing keeps
 	// running on the other side.
@@ -4,22 +4,28 @@ package shell
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/common/uuid"
 	"github.com/mitchellh/packer/helper/config"
 	"github.com/mitchellh/packer/packer"
 	"github.com/mitchellh/packer/template/interpolate"
 )
 
-const DefaultRemotePath = "/tmp/script.sh"
+const (
+	DefaultRemotePath = "/tmp/script_{{.Unique}}.sh"
+	DefaultTracePath  = "/tmp/script_{{.Unique}}.trace"
+)
 
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
@@ -47,8 +53,23 @@ type Config struct {
 
 	// The remote path where the local shell script will be uploaded to.
 	// This should be set to a writable file that is in a pre-existing directory.
+	// It is a template that is rendered for each script.
 	RemotePath string `mapstructure:"remote_path"`
 
+	// If true, the environment variables are written to a file that is
+	// uploaded alongside the script, rather than being passed on the
+	// command line.
+	UseEnvVarFile bool `mapstructure:"use_env_var_file"`
+
+	// If true, the uploaded scripts are not removed from the remote
+	// machine after they run.
+	SkipClean bool `mapstructure:"skip_clean"`
+
+	// If true, scripts are run with "set -x" and the trace is written
+	// to the remote file at TracePath, which is shown if the script fails.
+	Trace     bool   `mapstructure:"trace"`
+	TracePath string `mapstructure:"trace_path"`
+
 	// The command used to execute the script. The '{{ .Path }}' variable
 	// should be used to specify where the script goes, {{ .Vars }}
 	// can be used to inject the environment_vars into the environment.
@@ -57,19 +78,36 @@ type Config struct {
 	// The timeout for retrying to start the process. Until this timeout
 	// is reached, if the provisioner can't start a process, it retries.
 	// This can be set high to allow for reboots.
-	RawStartRetryTimeout string `mapstructure:"start_retry_timeout"`
+	StartRetryTimeout time.Duration `mapstructure:"start_retry_timeout"`
 
-	startRetryTimeout time.Duration
-	ctx               interpolate.Context
+	ctx interpolate.Context
 }
 
 type Provisioner struct {
-	config Config
+	config    Config
+	buildData packer.BuildData
 }
 
 type ExecuteCommandTemplate struct {
-	Vars string
-	Path string
+	Vars       string
+	Path       string
+	EnvVarFile string
+
+	// Build is the data that the builder knows once the machine is up,
+	// such as the host ports that are forwarded to it.
+	Build packer.BuildData
+}
+
+// RemotePathTemplate is the data available to remote_path and trace_path.
+type RemotePathTemplate struct {
+	// Index is the position of the script being run, starting at zero.
+	Index int
+
+	// Name is the file name of the script, or "inline" for inline scripts.
+	Name string
+
+	// Unique is a value that is different for every script that is run.
+	Unique string
 }
 
 func (p *Provisioner) Prepare(raws ...interface{}) error {
@@ -78,6 +116,8 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"execute_command",
+				"remote_path",
+				"trace_path",
 			},
 		},
 	}, raws...)
@@ -87,6 +127,9 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 
 	if p.config.ExecuteCommand == "" {
 		p.config.ExecuteCommand = "chmod +x {{.Path}}; {{.Vars}} {{.Path}}"
+		if p.config.UseEnvVarFile {
+			p.config.ExecuteCommand = "chmod +x {{.Path}}; . {{.EnvVarFile}} && {{.Path}}"
+		}
 	}
 
 	if p.config.Inline != nil && len(p.config.Inline) == 0 {
@@ -97,14 +140,18 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		p.config.InlineShebang = "/bin/sh -e"
 	}
 
-	if p.config.RawStartRetryTimeout == "" {
-		p.config.RawStartRetryTimeout = "5m"
+	if p.config.StartRetryTimeout == 0 {
+		p.config.StartRetryTimeout = 5 * time.Minute
 	}
 
 	if p.config.RemotePath == "" {
 		p.config.RemotePath = DefaultRemotePath
 	}
 
+	if p.config.TracePath == "" {
+		p.config.TracePath = DefaultTracePath
+	}
+
 	if p.config.Scripts == nil {
 		p.config.Scripts = make([]string, 0)
 	}
@@ -114,6 +161,9 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 	}
 
 	var errs *packer.MultiError
+	if err := p.config.RequireUnixGuest("shell provisioner"); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
 	if p.config.Script != "" && len(p.config.Scripts) > 0 {
 		errs = packer.MultiErrorAppend(errs,
 			errors.New("Only one of script or scripts can be specified."))
@@ -131,6 +181,23 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 			errors.New("Only a script file or an inline script can be specified, not both."))
 	}
 
+	if p.config.Trace && p.config.Binary {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("trace can't be used with binary scripts."))
+	}
+
+	templates := map[string]string{
+		"remote_path": p.config.RemotePath,
+		"trace_path":  p.config.TracePath,
+	}
+	for key, tpl := range templates {
+		p.config.ctx.Data = &RemotePathTemplate{}
+		if _, err := interpolate.Render(tpl, &p.config.ctx); err != nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Error parsing %s: %s", key, err))
+		}
+	}
+
 	for _, path := range p.config.Scripts {
 		if _, err := os.Stat(path); err != nil {
 			errs = packer.MultiErrorAppend(errs,
@@ -150,14 +217,6 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		}
 	}
 
-	if p.config.RawStartRetryTimeout != "" {
-		p.config.startRetryTimeout, err = time.ParseDuration(p.config.RawStartRetryTimeout)
-		if err != nil {
-			errs = packer.MultiErrorAppend(
-				errs, fmt.Errorf("Failed parsing start_retry_timeout: %s", err))
-		}
-	}
-
 	if errs != nil && len(errs.Errors) > 0 {
 		return errs
 	}
@@ -172,7 +231,7 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	// If we have an inline script, then turn that into a temporary
 	// shell script and use that.
 	if p.config.Inline != nil {
-		tf, err := ioutil.TempFile("", "packer-shell")
+		tf, err := ioutil.TempFile(p.config.PackerWorkDir, "packer-shell")
 		if err != nil {
 			return fmt.Errorf("Error preparing shell script: %s", err)
 		}
@@ -203,7 +262,7 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	envVars[1] = fmt.Sprintf("PACKER_BUILDER_TYPE='%s'", p.config.PackerBuilderType)
 	copy(envVars[2:], p.config.Vars)
 
-	for _, path := range scripts {
+	for i, path := range scripts {
 		ui.Say(fmt.Sprintf("Provisioning with shell script: %s", path))
 
 		log.Printf("Opening %s for reading", path)
@@ -213,13 +272,44 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 		}
 		defer f.Close()
 
+		// Determine where the files for this script go on the remote side
+		pathData := &RemotePathTemplate{
+			Index:  i,
+			Name:   filepath.Base(path),
+			Unique: uuid.TimeOrderedUUID(),
+		}
+		if p.config.Inline != nil {
+			pathData.Name = "inline"
+		}
+
+		remotePath, err := p.renderPath(p.config.RemotePath, pathData)
+		if err != nil {
+			return fmt.Errorf("Error processing remote_path: %s", err)
+		}
+
+		tracePath := ""
+		if p.config.Trace {
+			tracePath, err = p.renderPath(p.config.TracePath, pathData)
+			if err != nil {
+				return fmt.Errorf("Error processing trace_path: %s", err)
+			}
+		}
+
+		envVarFile := ""
+		if p.config.UseEnvVarFile {
+			envVarFile = fmt.Sprintf("%s/varfile_%s.sh",
+				filepath.ToSlash(filepath.Dir(remotePath)), pathData.Unique)
+		}
+
 		// Flatten the environment variables
 		flattendVars := strings.Join(envVars, " ")
 
 		// Compile the command
 		p.config.ctx.Data = &ExecuteCommandTemplate{
-			Vars: flattendVars,
-			Path: p.config.RemotePath,
+			Vars:       flattendVars,
+			Path:       remotePath,
+			EnvVarFile: envVarFile,
+			Build:      p.buildData,
 		}
 		command, err := interpolate.Render(p.config.ExecuteCommand, &p.config.ctx)
 		if err != nil {
@@ -241,13 +331,25 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 			if !p.config.Binary {
 				r = &UnixReader{Reader: r}
 			}
+			if p.config.Trace {
+				var err error
+				if r, err = traceScript(r, tracePath); err != nil {
+					return err
+				}
+			}
+
+			if envVarFile != "" {
+				if err := comm.Upload(envVarFile, envVarFileReader(envVars), nil); err != nil {
+					return fmt.Errorf("Error uploading environment variables: %s", err)
+				}
+			}
 
-			if err := comm.Upload(p.config.RemotePath, r, nil); err != nil {
+			if err := comm.Upload(remotePath, r, nil); err != nil {
 				return fmt.Errorf("Error uploading script: %s", err)
 			}
 
 			cmd = &packer.RemoteCmd{
-				Command: fmt.Sprintf("chmod 0755 %s", p.config.RemotePath),
+				Command: fmt.Sprintf("chmod 0755 %s", remotePath),
 			}
 			if err := comm.Start(cmd); err != nil {
 				return fmt.Errorf(
@@ -266,6 +368,16 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 		// Close the original file since we copied it
 		f.Close()
 
+		if cmd.ExitStatus != 0 && p.config.Trace {
+			p.showTrace(ui, comm, tracePath)
+		}
+
+		if !p.config.SkipClean {
+			if err := p.cleanup(comm, remotePath, envVarFile, tracePath); err != nil {
+				return err
+			}
+		}
+
 		if cmd.ExitStatus != 0 {
 			return fmt.Errorf("Script exited with non-zero exit status: %d", cmd.ExitStatus)
 		}
@@ -274,6 +386,14 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	return nil
 }
 
+func (p *Provisioner) ConfigSchema() map[string]interface{} {
+	return config.Schema(new(Config))
+}
+
+func (p *Provisioner) SetBuildData(data packer.BuildData) {
+	p.buildData = data
+}
+
 func (p *Provisioner) Cancel() {
 	// Just hard quit. It isn't a big deal if what we're doing keeps
 	// running on the other side.
@@ -283,7 +403,7 @@ func (p *Provisioner) Cancel() {
 // retryable will retry the given function over and over until a
 // non-error is returned.
 func (p *Provisioner) retryable(f func() error) error {
-	startTimeout := time.After(p.config.startRetryTimeout)
+	startTimeout := time.After(p.config.StartRetryTimeout)
 	for {
 		var err error
 		if err = f(); err == nil {
@@ -305,3 +425,95 @@ func (p *Provisioner) retryable(f func() error) error {
 		}
 	}
 }
+
+// renderPath renders a remote_path or trace_path template.
+func (p *Provisioner) renderPath(tpl string, data *RemotePathTemplate) (string, error) {
+	p.config.ctx.Data = data
+	return interpolate.Render(tpl, &p.config.ctx)
+}
+
+// showTrace shows the trace of a failed script. The trace is read with
+// cat rather than downloaded, since not every communicator can download.
+func (p *Provisioner) showTrace(ui packer.Ui, comm packer.Communicator, path string) {
+	var stdout bytes.Buffer
+	cmd := &packer.RemoteCmd{
+		Command: fmt.Sprintf("cat '%s'", path),
+		Stdout:  &stdout,
+	}
+	if err := comm.Start(cmd); err != nil {
+		log.Printf("Error reading trace %s: %s", path, err)
+		return
+	}
+	cmd.Wait()
+	if cmd.ExitStatus != 0 {
+		log.Printf("Error reading trace %s: exit status %d", path, cmd.ExitStatus)
+		return
+	}
+
+	ui.Error(fmt.Sprintf("Script failed, trace from %s:", path))
+	ui.Message(strings.TrimSpace(stdout.String()))
+}
+
+// cleanup removes the files that were uploaded for a script, retrying
+// in case the script restarted the machine.
+func (p *Provisioner) cleanup(comm packer.Communicator, paths ...string) error {
+	var files []string
+	for _, path := range paths {
+		if path != "" {
+			files = append(files, fmt.Sprintf("'%s'", path))
+		}
+	}
+
+	return p.retryable(func() error {
+		cmd := &packer.RemoteCmd{
+			Command: fmt.Sprintf("rm -f %s", strings.Join(files, " ")),
+		}
+		if err := comm.Start(cmd); err != nil {
+			return fmt.Errorf("Error removing script from remote machine: %s", err)
+		}
+		cmd.Wait()
+
+		return nil
+	})
+}
+
+// traceScript returns the script with commands added after the shebang
+// that turn on tracing and write the trace to the file at path. With bash
+// only the trace goes to the file, otherwise all of stderr does.
+func traceScript(r io.Reader, path string) (io.Reader, error) {
+	script, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var header []byte
+	if bytes.HasPrefix(script, []byte("#!")) {
+		idx := bytes.IndexByte(script, '\n')
+		if idx < 0 {
+			script = append(script, '\n')
+			idx = len(script) - 1
+		}
+
+		header = script[:idx+1]
+		script = script[idx+1:]
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	fmt.Fprintf(&buf,
+		"if [ -n \"$BASH_VERSION\" ]; then exec 9>>'%s'; BASH_XTRACEFD=9; "+
+			"else exec 2>>'%s'; fi\nset -x\n", path, path)
+	buf.Write(script)
+	return &buf, nil
+}
+
+// envVarFileReader returns the contents of the file that exports the
+// environment variables for use_env_var_file.
+func envVarFileReader(vars []string) io.Reader {
+	var buf bytes.Buffer
+	for _, kv := range vars {
+		fmt.Fprintf(&buf, "export %s\n", kv)
+	}
+
+	return &buf
+}
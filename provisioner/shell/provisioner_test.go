@@ -1,9 +1,11 @@
 package shell
 
 import (
+	"bytes"
 	"github.com/mitchellh/packer/packer"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -19,6 +21,9 @@ func TestProvisioner_Impl(t *testing.T) {
 	if _, ok := raw.(packer.Provisioner); !ok {
 		t.Fatalf("must be a Provisioner")
 	}
+	if _, ok := raw.(packer.BuildDataProvisioner); !ok {
+		t.Fatalf("must be a BuildDataProvisioner")
+	}
 }
 
 func TestProvisionerPrepare_Defaults(t *testing.T) {
@@ -62,6 +67,22 @@ func TestProvisionerPrepare_InlineShebang(t *testing.T) {
 	}
 }
 
+func TestProvisionerPrepare_WindowsGuest(t *testing.T) {
+	config := testConfig()
+	config[packer.GuestOSConfigKey] = "windows"
+
+	var p Provisioner
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config[packer.GuestOSConfigKey] = ""
+	p = Provisioner{}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
 func TestProvisionerPrepare_InvalidKey(t *testing.T) {
 	var p Provisioner
 	config := testConfig()
@@ -217,3 +238,160 @@ func TestProvisionerQuote_EnvironmentVars(t *testing.T) {
 		t.Fatalf("%s should be equal to %s", p.config.Vars[1], expectedValue)
 	}
 }
+
+func TestProvisionerPrepare_RemotePath(t *testing.T) {
+	config := testConfig()
+	config["remote_path"] = "/tmp/{{.Name}}_{{.Index}}.sh"
+	p := new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	actual, err := p.renderPath(p.config.RemotePath, &RemotePathTemplate{
+		Index: 2,
+		Name:  "inline",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual != "/tmp/inline_2.sh" {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	// Test with a bad template
+	config["remote_path"] = "/tmp/{{.Nope}}.sh"
+	p = new(Provisioner)
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_Trace(t *testing.T) {
+	config := testConfig()
+	config["trace"] = true
+	p := new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if p.config.TracePath != DefaultTracePath {
+		t.Fatalf("bad: %s", p.config.TracePath)
+	}
+
+	// Not with binary scripts
+	config["binary"] = true
+	p = new(Provisioner)
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_UseEnvVarFile(t *testing.T) {
+	config := testConfig()
+	config["use_env_var_file"] = true
+	p := new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	expected := "chmod +x {{.Path}}; . {{.EnvVarFile}} && {{.Path}}"
+	if p.config.ExecuteCommand != expected {
+		t.Fatalf("bad: %s", p.config.ExecuteCommand)
+	}
+}
+
+func TestProvisionerProvision_Clean(t *testing.T) {
+	config := testConfig()
+	config["remote_path"] = "/tmp/{{.Name}}.sh"
+	p := new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := new(packer.MockCommunicator)
+	if err := p.Provision(testUi(), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if comm.UploadPath != "/tmp/inline.sh" {
+		t.Fatalf("bad: %s", comm.UploadPath)
+	}
+	if comm.StartCmd.Command != "rm -f '/tmp/inline.sh'" {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+
+	// With skip_clean the script is the last command run
+	config["skip_clean"] = true
+	p = new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm = new(packer.MockCommunicator)
+	if err := p.Provision(testUi(), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !strings.HasSuffix(comm.StartCmd.Command, " /tmp/inline.sh") {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+}
+
+func TestProvisionerProvision_BuildData(t *testing.T) {
+	config := testConfig()
+	config["execute_command"] = "PORT={{.Build.HostPort_http}} {{.Path}}"
+	config["skip_clean"] = true
+	p := new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	p.SetBuildData(packer.BuildData{"HostPort_http": "8080"})
+
+	comm := new(packer.MockCommunicator)
+	if err := p.Provision(testUi(), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !strings.HasPrefix(comm.StartCmd.Command, "PORT=8080 ") {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+}
+
+func TestTraceScript(t *testing.T) {
+	r, err := traceScript(strings.NewReader("#!/bin/sh -e\necho foo\n"), "/tmp/trace")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	actual, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := "#!/bin/sh -e\n" +
+		"if [ -n \"$BASH_VERSION\" ]; then exec 9>>'/tmp/trace'; BASH_XTRACEFD=9; " +
+		"else exec 2>>'/tmp/trace'; fi\nset -x\n" +
+		"echo foo\n"
+	if string(actual) != expected {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestEnvVarFileReader(t *testing.T) {
+	r := envVarFileReader([]string{"FOO='bar'", "BAZ=''"})
+	actual, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := "export FOO='bar'\nexport BAZ=''\n"
+	if string(actual) != expected {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
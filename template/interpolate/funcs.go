@@ -1,8 +1,10 @@
 package interpolate
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -11,6 +13,7 @@ import (
 	"time"
 
 	"github.com/mitchellh/packer/common/uuid"
+	"github.com/mitchellh/packer/common/yaml"
 )
 
 // InitTime is the UTC time when this package was initialized. It is
@@ -25,12 +28,15 @@ func init() {
 // Funcs are the interpolation funcs that are available within interpolations.
 var FuncGens = map[string]FuncGenerator{
 	"env":          funcGenEnv,
+	"file":         funcGenFile,
 	"isotime":      funcGenIsotime,
+	"jsondecode":   funcGenJSONDecode,
 	"pwd":          funcGenPwd,
 	"template_dir": funcGenTemplateDir,
 	"timestamp":    funcGenTimestamp,
 	"uuid":         funcGenUuid,
 	"user":         funcGenUser,
+	"yamldecode":   funcGenYAMLDecode,
 
 	"upper": funcGenPrimitive(strings.ToUpper),
 	"lower": funcGenPrimitive(strings.ToLower),
@@ -68,6 +74,17 @@ func funcGenEnv(ctx *Context) interface{} {
 	}
 }
 
+func funcGenFile(ctx *Context) interface{} {
+	return func(path string) (string, error) {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		return string(contents), nil
+	}
+}
+
 func funcGenIsotime(ctx *Context) interface{} {
 	return func(format ...string) (string, error) {
 		if len(format) == 0 {
@@ -82,6 +99,17 @@ func funcGenIsotime(ctx *Context) interface{} {
 	}
 }
 
+func funcGenJSONDecode(ctx *Context) interface{} {
+	return func(data string) (interface{}, error) {
+		var result interface{}
+		if err := json.Unmarshal([]byte(data), &result); err != nil {
+			return nil, fmt.Errorf("error decoding JSON: %s", err)
+		}
+
+		return result, nil
+	}
+}
+
 func funcGenPrimitive(value interface{}) FuncGenerator {
 	return func(ctx *Context) interface{} {
 		return value
@@ -125,6 +153,17 @@ func funcGenUser(ctx *Context) interface{} {
 	}
 }
 
+func funcGenYAMLDecode(ctx *Context) interface{} {
+	return func(data string) (interface{}, error) {
+		result, err := yaml.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding YAML: %s", err)
+		}
+
+		return result, nil
+	}
+}
+
 func funcGenUuid(ctx *Context) interface{} {
 	return func() string {
 		return uuid.TimeOrderedUUID()
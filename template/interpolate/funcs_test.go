@@ -1,6 +1,8 @@
 package interpolate
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -68,6 +70,31 @@ func TestFuncEnv_disable(t *testing.T) {
 	}
 }
 
+func TestFuncFile(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Write([]byte("hello\n"))
+	tf.Close()
+
+	ctx := &Context{}
+	i := &I{Value: fmt.Sprintf(`{{file %q}}`, tf.Name())}
+	result, err := i.Render(ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result != "hello\n" {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	i = &I{Value: `{{file "/i/dont/exist"}}`}
+	if _, err := i.Render(ctx); err == nil {
+		t.Fatal("should error")
+	}
+}
+
 func TestFuncIsotime(t *testing.T) {
 	ctx := &Context{}
 	i := &I{Value: "{{isotime}}"}
@@ -207,3 +234,63 @@ func TestFuncUser(t *testing.T) {
 		}
 	}
 }
+
+func TestFuncJSONDecode(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Output string
+		Error  bool
+	}{
+		{
+			`{{(jsondecode (user "json")).name}}`,
+			`foo`,
+			false,
+		},
+
+		{
+			`{{index (jsondecode (user "json")).list 1}}`,
+			`2`,
+			false,
+		},
+
+		{
+			`{{jsondecode "{"}}`,
+			``,
+			true,
+		},
+	}
+
+	ctx := &Context{
+		UserVariables: map[string]string{
+			"json": `{"name": "foo", "list": [1, 2]}`,
+		},
+	}
+	for _, tc := range cases {
+		i := &I{Value: tc.Input}
+		result, err := i.Render(ctx)
+		if (err != nil) != tc.Error {
+			t.Fatalf("Input: %s\n\nerr: %s", tc.Input, err)
+		}
+
+		if result != tc.Output {
+			t.Fatalf("Input: %s\n\nGot: %s", tc.Input, result)
+		}
+	}
+}
+
+func TestFuncYAMLDecode(t *testing.T) {
+	ctx := &Context{
+		UserVariables: map[string]string{
+			"yaml": "name: foo\nlist:\n  - 1\n  - 2\n",
+		},
+	}
+
+	i := &I{Value: `{{(yamldecode (user "yaml")).name}} {{index (yamldecode (user "yaml")).list 1}}`}
+	result, err := i.Render(ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result != "foo 2" {
+		t.Fatalf("bad: %#v", result)
+	}
+}
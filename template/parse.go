@@ -7,12 +7,18 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/mitchellh/mapstructure"
 )
 
+// communicatorKeyRe matches the keys that can be set in the communicator
+// block: those of helper/communicator.Config that builders share.
+var communicatorKeyRe = regexp.MustCompile(
+	`^(communicator(_.+)?|ssh_.+|winrm_.+|upload_.+)$`)
+
 // rawTemplate is the direct JSON document format of the template file.
 // This is what is decoded directly from the file, and then it is turned
 // into a Template object thereafter.
@@ -21,11 +27,14 @@ type rawTemplate struct {
 	Description string
 
 	Builders       []map[string]interface{}
+	Communicator   map[string]interface{}
 	Push           map[string]interface{}
 	PostProcessors []interface{} `mapstructure:"post-processors"`
 	Provisioners   []map[string]interface{}
 	Variables      map[string]interface{}
 
+	SensitiveVariables []string `mapstructure:"sensitive-variables"`
+
 	RawContents []byte
 }
 
@@ -60,6 +69,18 @@ func (r *rawTemplate) Template() (*Template, error) {
 		result.Variables[k] = &v
 	}
 
+	// Mark the sensitive variables
+	for _, k := range r.SensitiveVariables {
+		v, ok := result.Variables[k]
+		if !ok {
+			errs = multierror.Append(errs, fmt.Errorf(
+				"sensitive variable %s is not defined", k))
+			continue
+		}
+
+		v.Sensitive = true
+	}
+
 	// Let's start by gathering all the builders
 	if len(r.Builders) > 0 {
 		result.Builders = make(map[string]*Builder, len(r.Builders))
@@ -72,10 +93,29 @@ func (r *rawTemplate) Template() (*Template, error) {
 			continue
 		}
 
+		// The verification is done by the core rather than the builder
+		if rawV, ok := rawB["verify"]; ok {
+			var v Verify
+			if err := r.decoder(&v, nil).Decode(rawV); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"builder %d: verify: %s", i+1, err))
+				continue
+			}
+
+			if v.Command == "" {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"builder %d: verify: command is required", i+1))
+				continue
+			}
+
+			b.Verify = &v
+		}
+
 		// Set the raw configuration and delete any special keys
 		b.Config = rawB
 		delete(b.Config, "name")
 		delete(b.Config, "type")
+		delete(b.Config, "verify")
 		if len(b.Config) == 0 {
 			b.Config = nil
 		}
@@ -104,6 +144,25 @@ func (r *rawTemplate) Template() (*Template, error) {
 		result.Builders[b.Name] = &b
 	}
 
+	// The communicator settings are only defaults, which the core merges
+	// into the configuration of each builder
+	if len(r.Communicator) > 0 {
+		keys := make([]string, 0, len(r.Communicator))
+		for k := range r.Communicator {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if !communicatorKeyRe.MatchString(k) {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"communicator: unknown key '%s'", k))
+			}
+		}
+
+		result.Communicator = r.Communicator
+	}
+
 	// Gather all the post-processors
 	if len(r.PostProcessors) > 0 {
 		result.PostProcessors = make([][]*PostProcessor, 0, len(r.PostProcessors))
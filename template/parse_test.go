@@ -39,6 +39,30 @@ func TestParse(t *testing.T) {
 			nil,
 			true,
 		},
+		{
+			"parse-builder-verify.json",
+			&Template{
+				Builders: map[string]*Builder{
+					"something": &Builder{
+						Name: "something",
+						Type: "something",
+						Config: map[string]interface{}{
+							"foo": "bar",
+						},
+						Verify: &Verify{
+							Command: "systemctl is-system-running",
+							Timeout: 5 * time.Minute,
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"parse-builder-verify-no-command.json",
+			nil,
+			true,
+		},
 
 		/*
 		 * Provisioners
@@ -143,6 +167,28 @@ func TestParse(t *testing.T) {
 			false,
 		},
 
+		{
+			"parse-variable-sensitive.json",
+			&Template{
+				Variables: map[string]*Variable{
+					"foo": &Variable{
+						Default: "bar",
+					},
+					"secret": &Variable{
+						Required:  true,
+						Sensitive: true,
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"parse-variable-sensitive-undefined.json",
+			nil,
+			true,
+		},
+
 		{
 			"parse-pp-basic.json",
 			&Template{
@@ -295,6 +341,23 @@ func TestParse(t *testing.T) {
 			false,
 		},
 
+		{
+			"parse-communicator.json",
+			&Template{
+				Communicator: map[string]interface{}{
+					"ssh_username": "packer",
+					"ssh_timeout":  "20m",
+				},
+			},
+			false,
+		},
+
+		{
+			"parse-communicator-bad-key.json",
+			nil,
+			true,
+		},
+
 		{
 			"parse-push.json",
 			&Template{
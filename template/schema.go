@@ -0,0 +1,177 @@
+package template
+
+import (
+	"sort"
+)
+
+// SchemaURI is the version of JSON Schema that Schema follows.
+const SchemaURI = "http://json-schema.org/draft-04/schema#"
+
+// Schema returns a JSON Schema that describes the format of templates.
+//
+// The builders and provisioners map the type of each known component to
+// the JSON Schema of its configuration, which is nil if the component
+// can't describe it. The configuration of components with a schema is
+// validated against it, while any keys are allowed for the others.
+func Schema(builders, provisioners map[string]map[string]interface{}) map[string]interface{} {
+	stringArray := map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "string"},
+	}
+
+	onlyExcept := map[string]interface{}{
+		"only":   stringArray,
+		"except": stringArray,
+	}
+
+	builderKeys := map[string]interface{}{
+		"name": map[string]interface{}{"type": "string"},
+		"verify": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"command": map[string]interface{}{"type": "string"},
+				"timeout": map[string]interface{}{"type": "string"},
+			},
+			"required":             []interface{}{"command"},
+			"additionalProperties": false,
+		},
+	}
+
+	provisionerKeys := map[string]interface{}{
+		"override":     map[string]interface{}{"type": "object"},
+		"pause_before": map[string]interface{}{"type": "string"},
+	}
+	for k, v := range onlyExcept {
+		provisionerKeys[k] = v
+	}
+
+	postProcessorKeys := map[string]interface{}{
+		"keep_input_artifact": map[string]interface{}{"type": "boolean"},
+	}
+	for k, v := range onlyExcept {
+		postProcessorKeys[k] = v
+	}
+	postProcessor := map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			componentSchema("", nil, postProcessorKeys),
+		},
+	}
+
+	return map[string]interface{}{
+		"$schema": SchemaURI,
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"description":        map[string]interface{}{"type": "string"},
+			"min_packer_version": map[string]interface{}{"type": "string"},
+			"variables": map[string]interface{}{
+				"type": "object",
+				"additionalProperties": map[string]interface{}{
+					"type": []interface{}{"string", "null"},
+				},
+			},
+			"sensitive-variables": stringArray,
+			"builders": map[string]interface{}{
+				"type":     "array",
+				"minItems": 1,
+				"items":    componentsSchema(builders, builderKeys),
+			},
+			"provisioners": map[string]interface{}{
+				"type":  "array",
+				"items": componentsSchema(provisioners, provisionerKeys),
+			},
+			"post-processors": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"oneOf": []interface{}{
+						postProcessor,
+						map[string]interface{}{
+							"type":  "array",
+							"items": postProcessor,
+						},
+					},
+				},
+			},
+			"communicator": map[string]interface{}{
+				"type": "object",
+				"patternProperties": map[string]interface{}{
+					communicatorKeyRe.String(): map[string]interface{}{},
+				},
+				"additionalProperties": false,
+			},
+			"push": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":     map[string]interface{}{"type": "string"},
+					"address":  map[string]interface{}{"type": "string"},
+					"base_dir": map[string]interface{}{"type": "string"},
+					"include":  stringArray,
+					"exclude":  stringArray,
+					"token":    map[string]interface{}{"type": "string"},
+					"vcs":      map[string]interface{}{"type": "boolean"},
+				},
+				"additionalProperties": false,
+			},
+		},
+		"required":             []interface{}{"builders"},
+		"additionalProperties": false,
+	}
+}
+
+// componentsSchema returns the schema of an entry in a list of builders or
+// provisioners, which is one of the known components, or any component if
+// none are known.
+func componentsSchema(components map[string]map[string]interface{}, keys map[string]interface{}) map[string]interface{} {
+	if len(components) == 0 {
+		return componentSchema("", nil, keys)
+	}
+
+	types := make([]string, 0, len(components))
+	for t := range components {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	schemas := make([]interface{}, len(types))
+	for i, t := range types {
+		schemas[i] = componentSchema(t, components[t], keys)
+	}
+
+	return map[string]interface{}{"oneOf": schemas}
+}
+
+// componentSchema returns the schema of a single component of the given
+// type, made of the schema of its configuration and the keys that Packer
+// itself reads. An empty type matches any component.
+func componentSchema(t string, config map[string]interface{}, keys map[string]interface{}) map[string]interface{} {
+	typeSchema := map[string]interface{}{"type": "string"}
+	if t != "" {
+		typeSchema = map[string]interface{}{"enum": []interface{}{t}}
+	}
+
+	properties := map[string]interface{}{"type": typeSchema}
+	for k, v := range keys {
+		properties[k] = v
+	}
+
+	result := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"type"},
+	}
+
+	if config != nil {
+		if ps, ok := config["properties"].(map[string]interface{}); ok {
+			for k, v := range ps {
+				if _, ok := properties[k]; !ok {
+					properties[k] = v
+				}
+			}
+		}
+		if v, ok := config["additionalProperties"]; ok {
+			result["additionalProperties"] = v
+		}
+	}
+
+	result["properties"] = properties
+	return result
+}
@@ -0,0 +1,67 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	builders := map[string]map[string]interface{}{
+		"foo": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"bar": map[string]interface{}{"type": "string"},
+			},
+			"additionalProperties": false,
+		},
+		"baz": nil,
+	}
+
+	schema := Schema(builders, nil)
+	if schema["$schema"] != SchemaURI {
+		t.Fatalf("bad: %#v", schema["$schema"])
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+	items := properties["builders"].(map[string]interface{})["items"].(map[string]interface{})
+	oneOf := items["oneOf"].([]interface{})
+	if len(oneOf) != 2 {
+		t.Fatalf("bad: %#v", oneOf)
+	}
+
+	// The schemas are sorted by type, so "baz" comes first and allows
+	// any keys since its schema isn't known.
+	baz := oneOf[0].(map[string]interface{})
+	if _, ok := baz["additionalProperties"]; ok {
+		t.Fatalf("bad: %#v", baz)
+	}
+	bazType := baz["properties"].(map[string]interface{})["type"]
+	if !reflect.DeepEqual(bazType, map[string]interface{}{"enum": []interface{}{"baz"}}) {
+		t.Fatalf("bad: %#v", bazType)
+	}
+
+	foo := oneOf[1].(map[string]interface{})
+	if foo["additionalProperties"] != false {
+		t.Fatalf("bad: %#v", foo)
+	}
+	fooProps := foo["properties"].(map[string]interface{})
+	for _, k := range []string{"bar", "name", "type"} {
+		if _, ok := fooProps[k]; !ok {
+			t.Fatalf("should have %s: %#v", k, fooProps)
+		}
+	}
+
+	// The schema of the component isn't modified
+	if _, ok := builders["foo"]["properties"].(map[string]interface{})["name"]; ok {
+		t.Fatal("should not modify the builder schema")
+	}
+
+	// Without known provisioners any provisioner is allowed
+	provItems := properties["provisioners"].(map[string]interface{})["items"].(map[string]interface{})
+	if _, ok := provItems["oneOf"]; ok {
+		t.Fatalf("bad: %#v", provItems)
+	}
+	if _, ok := provItems["properties"].(map[string]interface{})["pause_before"]; !ok {
+		t.Fatalf("bad: %#v", provItems)
+	}
+}
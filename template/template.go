@@ -24,6 +24,10 @@ type Template struct {
 	PostProcessors [][]*PostProcessor
 	Push           Push
 
+	// Communicator is the configuration of the communicator that every
+	// builder inherits, unless the builder sets the same key itself.
+	Communicator map[string]interface{}
+
 	// RawContents is just the raw data for this template
 	RawContents []byte
 }
@@ -33,6 +37,17 @@ type Builder struct {
 	Name   string
 	Type   string
 	Config map[string]interface{}
+
+	// Verify is how the artifact of the builder is verified before the
+	// post-processors run. It is nil if the artifact isn't verified.
+	Verify *Verify `mapstructure:"-"`
+}
+
+// Verify represents the verification of the artifact of a builder: a
+// machine is booted from the artifact and the command must succeed in it.
+type Verify struct {
+	Command string
+	Timeout time.Duration
 }
 
 // PostProcessor represents a post-processor within the template.
@@ -69,6 +84,10 @@ type Push struct {
 type Variable struct {
 	Default  string
 	Required bool
+
+	// Sensitive variables are never written out in plain text, for
+	// example to a build manifest.
+	Sensitive bool
 }
 
 // OnlyExcept is a struct that is meant to be embedded that contains the
@@ -10,3 +10,18 @@ const Version = "0.8.0"
 // then it means that it is a final release. Otherwise, this is a pre-release
 // such as "dev" (in development), "beta", "rc1", etc.
 const VersionPrerelease = "dev"
+
+// versionString returns the full version, including the pre-release
+// marker and the git commit for pre-releases.
+func versionString() string {
+	result := Version
+	if VersionPrerelease != "" {
+		result += "." + VersionPrerelease
+
+		if GitCommit != "" {
+			result += " (" + GitCommit + ")"
+		}
+	}
+
+	return result
+}